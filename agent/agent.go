@@ -5,6 +5,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -15,8 +16,12 @@ import (
 	"github.com/hashicorp/nomad-autoscaler/agent/config"
 	"github.com/hashicorp/nomad-autoscaler/plugins/manager"
 	"github.com/hashicorp/nomad-autoscaler/policy"
+	apiPolicy "github.com/hashicorp/nomad-autoscaler/policy/api"
 	filePolicy "github.com/hashicorp/nomad-autoscaler/policy/file"
 	nomadPolicy "github.com/hashicorp/nomad-autoscaler/policy/nomad"
+	s3Policy "github.com/hashicorp/nomad-autoscaler/policy/s3"
+	variablesPolicy "github.com/hashicorp/nomad-autoscaler/policy/variables"
+	vaultPolicy "github.com/hashicorp/nomad-autoscaler/policy/vault"
 	"github.com/hashicorp/nomad-autoscaler/policyeval"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
 	nomadHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
@@ -30,9 +35,16 @@ type Agent struct {
 	nomadClient   *api.Client
 	pluginManager *manager.PluginManager
 	policySources map[policy.SourceName]policy.Source
+	policyProc    *policy.Processor
 	policyManager *policy.Manager
 	inMemSink     *metrics.InmemSink
 	evalBroker    *policyeval.Broker
+	guardrail     *policyeval.Guardrail
+	apmCache      *policyeval.APMCache
+	lastMetrics   *policyeval.LastMetrics
+	activities    *policyeval.TargetActivities
+	desiredCounts *policyeval.DesiredCounts
+	provisioner   *policyeval.Provisioner
 
 	// nomadCfg is the merged Nomad API configuration that should be used when
 	// setting up all clients. It is the result of the Nomad api.DefaultConfig
@@ -84,9 +96,34 @@ func (a *Agent) Run(ctx context.Context) error {
 	a.evalBroker = policyeval.NewBroker(
 		a.logger.ResetNamed("policy_eval"),
 		a.config.PolicyEval.AckTimeout,
-		a.config.PolicyEval.DeliveryLimit)
+		a.config.PolicyEval.DeliveryLimit,
+		a.config.PolicyEval.DependencyWindow)
+
+	// Setup the scaling action guardrail.
+	if g := a.config.PolicyEval.Guardrail; g != nil {
+		a.guardrail, err = policyeval.NewGuardrail(ctx, a.logger.ResetNamed("policy_eval"), g.Paths, g.Query)
+		if err != nil {
+			return fmt.Errorf("failed to setup guardrail: %v", err)
+		}
+	}
+
+	// Setup the APM query result cache.
+	if c := a.config.PolicyEval.APMCache; c != nil {
+		a.apmCache = policyeval.NewAPMCache(c.TTL, c.SourceTTLs)
+	}
+	a.lastMetrics = policyeval.NewLastMetrics()
+	a.activities = policyeval.NewTargetActivities()
+	a.desiredCounts = policyeval.NewDesiredCounts()
 	a.initWorkers(ctx)
 
+	// Launch the blocked evaluations provisioner.
+	if c := a.config.PolicyEval.Provisioner; c != nil {
+		if err := a.setupProvisioner(c); err != nil {
+			return fmt.Errorf("failed to setup provisioner: %v", err)
+		}
+		go a.provisioner.Run(ctx)
+	}
+
 	a.initEnt(ctx)
 
 	// Launch the eval handler.
@@ -118,17 +155,49 @@ func (a *Agent) initWorkers(ctx context.Context) {
 	}
 	policyEvalLogger.Info("starting workers", workersCount...)
 
-	for i := 0; i < a.config.PolicyEval.Workers["horizontal"]; i++ {
-		w := policyeval.NewBaseWorker(
-			policyEvalLogger, a.pluginManager, a.policyManager, a.evalBroker, "horizontal")
-		go w.Run(ctx)
+	for _, queue := range []string{"horizontal", "cluster"} {
+		for i := 0; i < a.config.PolicyEval.Workers[queue]; i++ {
+			w := policyeval.NewBaseWorker(&policyeval.BaseWorkerConfig{
+				Logger:        policyEvalLogger,
+				PluginManager: a.pluginManager,
+				PolicyManager: a.policyManager,
+				Broker:        a.evalBroker,
+				Guardrail:     a.guardrail,
+				APMCache:      a.apmCache,
+				LastMetrics:   a.lastMetrics,
+				Activities:    a.activities,
+				DesiredCounts: a.desiredCounts,
+				Queue:         queue,
+			})
+			go w.Run(ctx)
+		}
 	}
+}
 
-	for i := 0; i < a.config.PolicyEval.Workers["cluster"]; i++ {
-		w := policyeval.NewBaseWorker(
-			policyEvalLogger, a.pluginManager, a.policyManager, a.evalBroker, "cluster")
-		go w.Run(ctx)
+// setupProvisioner creates the blocked evaluations provisioner, which uses
+// the configured Nomad APM plugin or the first one available.
+func (a *Agent) setupProvisioner(c *config.Provisioner) error {
+	names := a.getNomadAPMNames()
+
+	if len(names) == 0 {
+		return errors.New("no Nomad APM plugin configured")
 	}
+
+	apmName := names[0]
+	if c.APM != "" {
+		found := false
+		for _, name := range names {
+			found = found || name == c.APM
+		}
+		if !found {
+			return fmt.Errorf("%q is not a Nomad APM plugin", c.APM)
+		}
+		apmName = c.APM
+	}
+
+	a.provisioner = policyeval.NewProvisioner(
+		a.logger.ResetNamed("policy_eval"), a.nomadClient, a.policyManager, a.pluginManager, apmName, c.BatchWindow)
+	return nil
 }
 
 func (a *Agent) setupPolicyManager() (chan *sdk.ScalingEvaluation, error) {
@@ -139,7 +208,19 @@ func (a *Agent) setupPolicyManager() (chan *sdk.ScalingEvaluation, error) {
 		DefaultEvaluationInterval: a.config.Policy.DefaultEvaluationInterval,
 		DefaultCooldown:           a.config.Policy.DefaultCooldown,
 	}
+	if pd := a.config.PolicyDefaults; pd != nil {
+		if pd.EvaluationInterval != 0 {
+			cfgDefaults.DefaultEvaluationInterval = pd.EvaluationInterval
+		}
+		if pd.Cooldown != 0 {
+			cfgDefaults.DefaultCooldown = pd.Cooldown
+		}
+		cfgDefaults.DefaultOnCheckError = pd.OnCheckError
+		cfgDefaults.DefaultMin = pd.Min
+		cfgDefaults.DefaultMax = pd.Max
+	}
 	policyProcessor := policy.NewProcessor(&cfgDefaults, a.getNomadAPMNames())
+	a.policyProc = policyProcessor
 
 	// Setup our initial default policy source which is Nomad.
 	sources := map[policy.SourceName]policy.Source{}
@@ -150,13 +231,52 @@ func (a *Agent) setupPolicyManager() (chan *sdk.ScalingEvaluation, error) {
 
 		switch policy.SourceName(s.Name) {
 		case policy.SourceNameNomad:
-			sources[policy.SourceNameNomad] = nomadPolicy.NewNomadSource(a.logger, a.nomadClient, policyProcessor)
+			nomadSource := nomadPolicy.NewNomadSource(a.logger, a.nomadClient, policyProcessor)
+			if err := nomadSource.SetNamespaceFilter(s.Config); err != nil {
+				return nil, fmt.Errorf("failed to setup nomad policy source: %v", err)
+			}
+			sources[policy.SourceNameNomad] = nomadSource
 		case policy.SourceNameFile:
 			// Only setup the file source if operators have configured a
 			// scaling policy directory to read from.
 			if a.config.Policy.Dir != "" {
-				sources[policy.SourceNameFile] = filePolicy.NewFileSource(a.logger, a.config.Policy.Dir, policyProcessor)
+				verifier, err := policy.NewSignatureVerifier(s.Config)
+				if err != nil {
+					return nil, fmt.Errorf("failed to setup file policy source: %v", err)
+				}
+				decrypter, err := policy.NewDecrypter(s.Config)
+				if err != nil {
+					return nil, fmt.Errorf("failed to setup file policy source: %v", err)
+				}
+				fileSource := filePolicy.NewFileSource(a.logger, a.config.Policy.Dir, policyProcessor).(*filePolicy.Source)
+				if err := fileSource.SetScanConfig(s.Config); err != nil {
+					return nil, fmt.Errorf("failed to setup file policy source: %v", err)
+				}
+				fileSource.SetSignatureVerifier(verifier)
+				fileSource.SetDecrypter(decrypter)
+				fileSource.SetNomadClient(a.nomadClient)
+				sources[policy.SourceNameFile] = fileSource
+			}
+		case policy.SourceNameS3:
+			s3Source, err := s3Policy.NewS3Source(a.logger, s.Config, policyProcessor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to setup s3 policy source: %v", err)
 			}
+			sources[policy.SourceNameS3] = s3Source
+		case policy.SourceNameVault:
+			vaultSource, err := vaultPolicy.NewVaultSource(a.logger, s.Config, policyProcessor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to setup vault policy source: %v", err)
+			}
+			sources[policy.SourceNameVault] = vaultSource
+		case policy.SourceNameNomadVariables:
+			sources[policy.SourceNameNomadVariables] = variablesPolicy.NewVariablesSource(a.logger, a.nomadClient, s.Config, policyProcessor)
+		case policy.SourceNameAPI:
+			apiSource, err := apiPolicy.NewAPISource(a.logger, a.nomadClient, s.Config, policyProcessor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to setup api policy source: %v", err)
+			}
+			sources[policy.SourceNameAPI] = apiSource
 		}
 	}
 
@@ -166,8 +286,33 @@ func (a *Agent) setupPolicyManager() (chan *sdk.ScalingEvaluation, error) {
 		return nil, fmt.Errorf("no policy source available")
 	}
 
+	priority := make([]policy.SourceName, len(a.config.Policy.SourcePriority))
+	for i, name := range a.config.Policy.SourcePriority {
+		priority[i] = policy.SourceName(name)
+	}
+	cfg := &policy.ManagerConfig{
+		Logger:          a.logger,
+		Sources:         sources,
+		PluginManager:   a.pluginManager,
+		Conflicts:       policy.NewConflictResolver(a.logger, a.config.Policy.ConflictStrategy, priority),
+		Versions:        policy.NewVersionStore(a.config.Policy.VersionHistoryLimit),
+		MetricsInterval: a.config.Telemetry.CollectionInterval,
+	}
+
+	if w := a.config.Policy.ChangeWebhook; w != nil {
+		cfg.Notifier = policy.NewChangeNotifier(a.logger, w.Address, w.Headers, w.Timeout)
+	}
+
+	if w := a.config.Policy.AdmissionWebhook; w != nil {
+		cfg.Admission = policy.NewAdmissionController(a.logger, w.Address, w.Headers, w.Timeout)
+	}
+
+	if f := a.config.PolicyFilter; f != nil {
+		cfg.Selector = f.Labels
+	}
+
 	a.policySources = sources
-	a.policyManager = policy.NewManager(a.logger, a.policySources, a.pluginManager, a.config.Telemetry.CollectionInterval)
+	a.policyManager = policy.NewManager(cfg)
 
 	return make(chan *sdk.ScalingEvaluation, 10), nil
 }
@@ -218,10 +363,33 @@ func (a *Agent) reload() {
 	// Set new Nomad client in the Nomad policy source.
 	ps, ok := a.policySources[policy.SourceNameNomad]
 	if ok {
-		ps.(*nomadPolicy.Source).SetNomadClient(a.nomadClient)
+		nomadSource := ps.(*nomadPolicy.Source)
+		nomadSource.SetNomadClient(a.nomadClient)
+
+		for _, s := range a.config.Policy.Sources {
+			if s.Name != string(policy.SourceNameNomad) {
+				continue
+			}
+			if err := nomadSource.SetNamespaceFilter(s.Config); err != nil {
+				a.logger.Error("failed to reload nomad policy source namespaces", "error", err)
+			}
+		}
+	}
+	if ps, ok := a.policySources[policy.SourceNameNomadVariables]; ok {
+		ps.(*variablesPolicy.Source).SetNomadClient(a.nomadClient)
+	}
+	if ps, ok := a.policySources[policy.SourceNameFile]; ok {
+		ps.(*filePolicy.Source).SetNomadClient(a.nomadClient)
+	}
+	if ps, ok := a.policySources[policy.SourceNameAPI]; ok {
+		ps.(*apiPolicy.Source).SetNomadClient(a.nomadClient)
 	}
 	a.policyManager.ReloadSources()
 
+	if a.provisioner != nil {
+		a.provisioner.SetNomadClient(a.nomadClient)
+	}
+
 	a.logger.Debug("reloading plugins")
 	if err := a.pluginManager.Reload(a.setupPluginsConfig()); err != nil {
 		a.logger.Error("failed to reload plugins", "error", err)
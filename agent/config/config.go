@@ -6,6 +6,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
@@ -56,6 +57,14 @@ type Agent struct {
 	// Policy is the configuration used to setup the policy manager.
 	Policy *Policy `hcl:"policy,block"`
 
+	// PolicyDefaults holds values which are merged into every parsed policy
+	// that does not explicitly configure them.
+	PolicyDefaults *PolicyDefaults `hcl:"policy_defaults,block"`
+
+	// PolicyFilter restricts the policies evaluated by the agent, allowing
+	// multiple agents to split a shared set of policies.
+	PolicyFilter *PolicyFilter `hcl:"policy_filter,block"`
+
 	// PolicyWorkers is the configuration used to define the number of workers
 	// to start for each policy type.
 	PolicyEval *PolicyEval `hcl:"policy_eval,block"`
@@ -298,6 +307,81 @@ type Policy struct {
 
 	// Sources store configuration for policy sources.
 	Sources []*PolicySource `hcl:"source,block"`
+
+	// ConflictStrategy controls how policies from multiple sources which
+	// target the same resource are handled. The default "none" strategy
+	// evaluates all of them and only reports the conflict, while the opt-in
+	// "override" strategy only evaluates the policies from the highest
+	// priority source.
+	ConflictStrategy string `hcl:"conflict_strategy,optional"`
+
+	// SourcePriority lists policy source names, from highest to lowest
+	// priority, used to resolve policy conflicts. Sources which are not
+	// listed have a lower priority than all listed sources.
+	SourcePriority []string `hcl:"source_priority,optional"`
+
+	// VersionHistoryLimit is the number of versions of each policy kept by
+	// the agent, which can be inspected and pinned via the HTTP API.
+	VersionHistoryLimit int `hcl:"version_history_limit,optional"`
+
+	// ChangeWebhook configures a webhook which is notified whenever a policy
+	// is added, updated or deleted.
+	ChangeWebhook *PolicyWebhook `hcl:"change_webhook,block"`
+
+	// AdmissionWebhook configures a webhook which must approve each new or
+	// updated policy before it becomes active, and may mutate it.
+	AdmissionWebhook *PolicyWebhook `hcl:"admission_webhook,block"`
+}
+
+// PolicyWebhook is the configuration of a webhook called by the policy
+// manager.
+type PolicyWebhook struct {
+
+	// Address is the URL the webhook payload is sent to using HTTP POST.
+	Address string `hcl:"address"`
+
+	// Headers are additional HTTP headers added to each request, which can
+	// be used for authentication.
+	Headers map[string]string `hcl:"headers,optional"`
+
+	// Timeout is the maximum time to wait for the webhook to respond.
+	Timeout    time.Duration
+	TimeoutHCL string `hcl:"timeout,optional" json:"-"`
+}
+
+// PolicyDefaults holds default values which are merged into every policy,
+// regardless of source, unless the policy overrides them. Cooldown and
+// EvaluationInterval take precedence over the Policy default_* parameters
+// when set.
+type PolicyDefaults struct {
+
+	// Cooldown is the cooldown applied to policies which do not configure
+	// one.
+	Cooldown    time.Duration
+	CooldownHCL string `hcl:"cooldown,optional" json:"-"`
+
+	// EvaluationInterval is the evaluation interval applied to policies which
+	// do not configure one.
+	EvaluationInterval    time.Duration
+	EvaluationIntervalHCL string `hcl:"evaluation_interval,optional" json:"-"`
+
+	// OnCheckError is the on_check_error value applied to policies which do
+	// not configure one.
+	OnCheckError string `hcl:"on_check_error,optional"`
+
+	// Min and Max are applied to policies which do not set their min and max
+	// values. Policies must set max if no default is configured.
+	Min int64 `hcl:"min,optional"`
+	Max int64 `hcl:"max,optional"`
+}
+
+// PolicyFilter restricts the policies evaluated by the agent to those which
+// match all of its selectors.
+type PolicyFilter struct {
+
+	// Labels is the set of key/value pairs a policy must have within its
+	// labels to be evaluated by the agent.
+	Labels map[string]string `hcl:"labels,optional"`
 }
 
 // PolicyEval holds the configuration related to the policy evaluation process.
@@ -312,14 +396,99 @@ type PolicyEval struct {
 	AckTimeout    time.Duration
 	AckTimeoutHCL string `hcl:"ack_timeout,optional" json:"-"`
 
+	// DependencyWindow is the maximum time an eval for a policy with
+	// depends_on is held back while the policies it depends on are being
+	// evaluated. A value of zero disables the ordering.
+	DependencyWindow    time.Duration
+	DependencyWindowHCL string `hcl:"dependency_window,optional" json:"-"`
+
+	// Guardrail optionally configures an OPA Rego policy which is evaluated
+	// against every computed scaling action before it is taken.
+	Guardrail *Guardrail `hcl:"guardrail,block"`
+
+	// APMCache optionally configures caching of APM query results, so that
+	// identical queries from different policies reuse the same result.
+	APMCache *APMCache `hcl:"apm_cache,block"`
+
+	// TargetStatusCache optionally configures caching of target status
+	// results, so that policies with the same target reuse the same status.
+	TargetStatusCache *TargetStatusCache `hcl:"target_status_cache,block"`
+
+	// Provisioner optionally enables watching the Nomad blocked evaluations,
+	// so cluster policies are evaluated as soon as allocations fail to be
+	// placed rather than at their next evaluation interval.
+	Provisioner *Provisioner `hcl:"provisioner,block"`
+
 	// Workers hold the number of workers to initialize for each queue.
 	Workers map[string]int `hcl:"workers,optional"`
 }
 
+// Guardrail is the configuration of the embedded OPA guardrail, which can
+// deny or clamp scaling actions regardless of the policy that computed them.
+type Guardrail struct {
+
+	// Paths is the list of Rego files, or directories containing them, which
+	// make up the guardrail policy.
+	Paths []string `hcl:"paths"`
+
+	// Query is the Rego query evaluated for each action. It must produce an
+	// object with optional allow, count and reason fields.
+	Query string `hcl:"query,optional"`
+}
+
+// APMCache is the configuration of the APM query result cache. Results are
+// cached per source, query and query window.
+type APMCache struct {
+
+	// TTL is the time a query result is cached for when the source does not
+	// have its own TTL. A value of zero disables caching for those sources.
+	TTL    time.Duration
+	TTLHCL string `hcl:"ttl,optional" json:"-"`
+
+	// SourceTTLs overrides TTL for individual APM sources, keyed by the name
+	// of the apm block.
+	SourceTTLs    map[string]time.Duration
+	SourceTTLsHCL map[string]string `hcl:"source_ttl,optional" json:"-"`
+}
+
+// TargetStatusCache is the configuration of the target status cache. Results
+// are cached per target plugin and target config.
+type TargetStatusCache struct {
+
+	// TTL is the time a status is cached for when the target plugin does not
+	// have its own TTL. A value of zero disables caching for those plugins.
+	TTL    time.Duration
+	TTLHCL string `hcl:"ttl,optional" json:"-"`
+
+	// TargetTTLs overrides TTL for individual target plugins, keyed by the
+	// name of the target block.
+	TargetTTLs    map[string]time.Duration
+	TargetTTLsHCL map[string]string `hcl:"target_ttl,optional" json:"-"`
+}
+
+// Provisioner is the configuration of the blocked evaluations provisioner,
+// which scales cluster targets out just in time to place blocked allocations.
+type Provisioner struct {
+
+	// APM is the name of the Nomad APM plugin used to calculate the number of
+	// nodes required. It defaults to the first Nomad APM plugin configured.
+	APM string `hcl:"apm,optional"`
+
+	// BatchWindow is the time waited after a new blocked evaluation is
+	// detected, so evaluations blocked together are placed by a single
+	// scaling action.
+	BatchWindow    time.Duration
+	BatchWindowHCL string `hcl:"batch_window,optional" json:"-"`
+}
+
 // PolicySource is an individual configured policy source.
 type PolicySource struct {
 	Name    string `hcl:"name,label"`
 	Enabled *bool  `hcl:"enabled,optional"`
+
+	// Config is the mapping of source specific config values. Not all
+	// sources require additional configuration.
+	Config map[string]string `hcl:"config,optional"`
 }
 
 const (
@@ -348,6 +517,10 @@ const (
 	// collection interval.
 	defaultTelemetryCollectionInterval = 1 * time.Second
 
+	// defaultPolicyVersionHistoryLimit is the default number of versions kept
+	// for each policy.
+	defaultPolicyVersionHistoryLimit = 10
+
 	// defaultPolicyWorkerDeliveryLimit is the default value for the delivery
 	// limit count for the policy eval broker.
 	defaultPolicyEvalDeliveryLimit = 1
@@ -355,6 +528,11 @@ const (
 	// defaultPolicyWorkerAckTimeout is the default time limit that a policy
 	// eval must be ACK'd.
 	defaultPolicyEvalAckTimeout = 5 * time.Minute
+
+	// defaultPolicyEvalDependencyWindow is the default time limit that a
+	// policy eval is held back by its dependencies. It matches the ack
+	// timeout so evals wait for in-progress dependencies to complete.
+	defaultPolicyEvalDependencyWindow = 5 * time.Minute
 )
 
 // TODO: there's an unexpected import cycle that prevents us from using the
@@ -366,6 +544,27 @@ const (
 	// policySourceNomad is the source for policies that originate from the
 	// Nomad scaling policies API.
 	policySourceNomad = "nomad"
+
+	// policySourceS3 is the source for policies that are loaded from an
+	// S3-compatible object storage bucket.
+	policySourceS3 = "s3"
+
+	// policySourceVault is the source for policies that are loaded from a
+	// Vault KV v2 secrets engine.
+	policySourceVault = "vault"
+
+	// policySourceNomadVariables is the source for policies that are loaded
+	// from Nomad Variables.
+	policySourceNomadVariables = "nomad-variables"
+
+	// policySourceAPI is the source for policies that are pushed to the agent
+	// through its HTTP API.
+	policySourceAPI = "api"
+
+	// policyConflictStrategyOverride and policyConflictStrategyNone are the
+	// valid values for the policy conflict_strategy parameter.
+	policyConflictStrategyOverride = "override"
+	policyConflictStrategyNone     = "none"
 )
 
 var defaultPolicyEvalWorkers = map[string]int{
@@ -402,11 +601,15 @@ func Default() (*Agent, error) {
 				{Name: policySourceFile, Enabled: ptr.BoolToPtr(true)},
 				{Name: policySourceNomad, Enabled: ptr.BoolToPtr(true)},
 			},
+			ConflictStrategy:    policyConflictStrategyNone,
+			SourcePriority:      []string{policySourceNomad, policySourceFile},
+			VersionHistoryLimit: defaultPolicyVersionHistoryLimit,
 		},
 		PolicyEval: &PolicyEval{
-			DeliveryLimit: defaultPolicyEvalDeliveryLimit,
-			AckTimeout:    defaultPolicyEvalAckTimeout,
-			Workers:       defaultPolicyEvalWorkers,
+			DeliveryLimit:    defaultPolicyEvalDeliveryLimit,
+			AckTimeout:       defaultPolicyEvalAckTimeout,
+			DependencyWindow: defaultPolicyEvalDependencyWindow,
+			Workers:          defaultPolicyEvalWorkers,
 		},
 		APMs: []*Plugin{
 			{Name: plugins.InternalAPMNomad, Driver: plugins.InternalAPMNomad},
@@ -468,6 +671,14 @@ func (a *Agent) Merge(b *Agent) *Agent {
 		result.PolicyEval = result.PolicyEval.merge(b.PolicyEval)
 	}
 
+	if b.PolicyDefaults != nil {
+		result.PolicyDefaults = result.PolicyDefaults.merge(b.PolicyDefaults)
+	}
+
+	if b.PolicyFilter != nil {
+		result.PolicyFilter = result.PolicyFilter.merge(b.PolicyFilter)
+	}
+
 	if len(result.APMs) == 0 && len(b.APMs) != 0 {
 		apmCopy := make([]*Plugin, len(b.APMs))
 		for i, v := range b.APMs {
@@ -512,11 +723,20 @@ func (a *Agent) Validate() error {
 	}
 
 	if a.Policy != nil {
+		result = multierror.Append(result, a.Policy.validate())
 		for _, s := range a.Policy.Sources {
 			result = multierror.Append(result, s.validate())
 		}
 	}
 
+	if a.PolicyDefaults != nil {
+		result = multierror.Append(result, a.PolicyDefaults.validate())
+	}
+
+	if a.PolicyFilter != nil {
+		result = multierror.Append(result, a.PolicyFilter.validate())
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -750,10 +970,207 @@ func (p *Policy) merge(b *Policy) *Policy {
 	} else if len(b.Sources) != 0 {
 		result.Sources = policySourceConfigSetMerge(result.Sources, b.Sources)
 	}
+	if b.ConflictStrategy != "" {
+		result.ConflictStrategy = b.ConflictStrategy
+	}
+	if len(b.SourcePriority) != 0 {
+		result.SourcePriority = make([]string, len(b.SourcePriority))
+		copy(result.SourcePriority, b.SourcePriority)
+	}
+	if b.VersionHistoryLimit != 0 {
+		result.VersionHistoryLimit = b.VersionHistoryLimit
+	}
+	if b.ChangeWebhook != nil {
+		result.ChangeWebhook = result.ChangeWebhook.merge(b.ChangeWebhook)
+	}
+	if b.AdmissionWebhook != nil {
+		result.AdmissionWebhook = result.AdmissionWebhook.merge(b.AdmissionWebhook)
+	}
+
+	return &result
+}
+
+func (p *Policy) validate() *multierror.Error {
+	var result *multierror.Error
+	prefix := "policy ->"
+
+	switch p.ConflictStrategy {
+	case "", policyConflictStrategyOverride, policyConflictStrategyNone:
+	default:
+		result = multierror.Append(result, fmt.Errorf("invalid conflict_strategy %q, must be one of %q or %q",
+			p.ConflictStrategy, policyConflictStrategyOverride, policyConflictStrategyNone))
+	}
+
+	seen := make(map[string]bool, len(p.SourcePriority))
+	for _, name := range p.SourcePriority {
+		if seen[name] {
+			result = multierror.Append(result, fmt.Errorf("duplicate source %q in source_priority", name))
+		}
+		seen[name] = true
+	}
+
+	if p.VersionHistoryLimit < 0 {
+		result = multierror.Append(result, errors.New("version_history_limit must not be negative"))
+	}
+
+	if p.DefaultEvaluationInterval < 0 {
+		result = multierror.Append(result, errors.New("default_evaluation_interval must not be negative"))
+	}
+
+	if p.ChangeWebhook != nil {
+		for _, err := range p.ChangeWebhook.validate().WrappedErrors() {
+			result = multierror.Append(result, multierror.Prefix(err, "change_webhook ->"))
+		}
+	}
+
+	if p.AdmissionWebhook != nil {
+		for _, err := range p.AdmissionWebhook.validate().WrappedErrors() {
+			result = multierror.Append(result, multierror.Prefix(err, "admission_webhook ->"))
+		}
+	}
+
+	// Prefix all errors.
+	if result != nil {
+		for i, err := range result.Errors {
+			result.Errors[i] = multierror.Prefix(err, prefix)
+		}
+	}
+	return result
+}
+
+func (w *PolicyWebhook) merge(b *PolicyWebhook) *PolicyWebhook {
+	if w == nil {
+		return b
+	}
+
+	result := *w
+
+	if b.Address != "" {
+		result.Address = b.Address
+	}
+	if len(b.Headers) != 0 {
+		result.Headers = make(map[string]string, len(b.Headers))
+		for k, v := range b.Headers {
+			result.Headers[k] = v
+		}
+	}
+	if b.Timeout != 0 {
+		result.Timeout = b.Timeout
+	}
+
+	return &result
+}
+
+func (w *PolicyWebhook) validate() *multierror.Error {
+	var result *multierror.Error
+
+	u, err := url.Parse(w.Address)
+	if err != nil {
+		result = multierror.Append(result, fmt.Errorf("invalid address: %v", err))
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		result = multierror.Append(result, fmt.Errorf("address %q must use http or https", w.Address))
+	}
+
+	if w.Timeout < 0 {
+		result = multierror.Append(result, errors.New("timeout must not be negative"))
+	}
+	return result
+}
+
+func (pd *PolicyDefaults) merge(b *PolicyDefaults) *PolicyDefaults {
+	if pd == nil {
+		return b
+	}
+
+	result := *pd
+
+	if b.Cooldown != 0 {
+		result.Cooldown = b.Cooldown
+	}
+	if b.EvaluationInterval != 0 {
+		result.EvaluationInterval = b.EvaluationInterval
+	}
+	if b.OnCheckError != "" {
+		result.OnCheckError = b.OnCheckError
+	}
+	if b.Min != 0 {
+		result.Min = b.Min
+	}
+	if b.Max != 0 {
+		result.Max = b.Max
+	}
+
+	return &result
+}
+
+func (pd *PolicyDefaults) validate() *multierror.Error {
+	var result *multierror.Error
+	prefix := "policy_defaults ->"
+
+	switch pd.OnCheckError {
+	case "", "fail", "ignore":
+	default:
+		result = multierror.Append(result, errors.New("on_check_error must be one of fail or ignore"))
+	}
+
+	if pd.Min < 0 {
+		result = multierror.Append(result, errors.New("min can't be negative"))
+	}
+	if pd.Max < 0 {
+		result = multierror.Append(result, errors.New("max can't be negative"))
+	}
+	if pd.Max != 0 && pd.Min > pd.Max {
+		result = multierror.Append(result, errors.New("min must not be greater than max"))
+	}
+
+	// Prefix all errors.
+	if result != nil {
+		for i, err := range result.Errors {
+			result.Errors[i] = multierror.Prefix(err, prefix)
+		}
+	}
+	return result
+}
+
+func (pf *PolicyFilter) merge(b *PolicyFilter) *PolicyFilter {
+	if pf == nil {
+		return b
+	}
+
+	result := *pf
+
+	if len(b.Labels) != 0 {
+		result.Labels = make(map[string]string, len(pf.Labels)+len(b.Labels))
+		for k, v := range pf.Labels {
+			result.Labels[k] = v
+		}
+		for k, v := range b.Labels {
+			result.Labels[k] = v
+		}
+	}
 
 	return &result
 }
 
+func (pf *PolicyFilter) validate() *multierror.Error {
+	var result *multierror.Error
+	prefix := "policy_filter ->"
+
+	for k := range pf.Labels {
+		if k == "" {
+			result = multierror.Append(result, errors.New("labels must not contain an empty key"))
+		}
+	}
+
+	// Prefix all errors.
+	if result != nil {
+		for i, err := range result.Errors {
+			result.Errors[i] = multierror.Prefix(err, prefix)
+		}
+	}
+	return result
+}
+
 func (pw *PolicyEval) merge(in *PolicyEval) *PolicyEval {
 	if pw == nil {
 		return in
@@ -770,6 +1187,27 @@ func (pw *PolicyEval) merge(in *PolicyEval) *PolicyEval {
 		result.DeliveryLimit = in.DeliveryLimit
 	}
 
+	if in.DependencyWindowHCL != "" {
+		result.DependencyWindowHCL = in.DependencyWindowHCL
+		result.DependencyWindow = in.DependencyWindow
+	}
+
+	if in.Guardrail != nil {
+		result.Guardrail = in.Guardrail
+	}
+
+	if in.APMCache != nil {
+		result.APMCache = in.APMCache
+	}
+
+	if in.TargetStatusCache != nil {
+		result.TargetStatusCache = in.TargetStatusCache
+	}
+
+	if in.Provisioner != nil {
+		result.Provisioner = in.Provisioner
+	}
+
 	for k, v := range in.Workers {
 		result.Workers[k] = v
 	}
@@ -785,6 +1223,40 @@ func (pw *PolicyEval) validate() *multierror.Error {
 		result = multierror.Append(result, errors.New("delivery_limit must be bigger than 0"))
 	}
 
+	if pw.DependencyWindow < 0 {
+		result = multierror.Append(result, errors.New("dependency_window must not be negative"))
+	}
+
+	if pw.Guardrail != nil && len(pw.Guardrail.Paths) == 0 {
+		result = multierror.Append(result, errors.New("guardrail -> paths must not be empty"))
+	}
+
+	if c := pw.APMCache; c != nil {
+		if c.TTL < 0 {
+			result = multierror.Append(result, errors.New("apm_cache -> ttl must not be negative"))
+		}
+		for k, v := range c.SourceTTLs {
+			if v < 0 {
+				result = multierror.Append(result, fmt.Errorf("apm_cache -> source_ttl for %q must not be negative", k))
+			}
+		}
+	}
+
+	if c := pw.TargetStatusCache; c != nil {
+		if c.TTL < 0 {
+			result = multierror.Append(result, errors.New("target_status_cache -> ttl must not be negative"))
+		}
+		for k, v := range c.TargetTTLs {
+			if v < 0 {
+				result = multierror.Append(result, fmt.Errorf("target_status_cache -> target_ttl for %q must not be negative", k))
+			}
+		}
+	}
+
+	if c := pw.Provisioner; c != nil && c.BatchWindow < 0 {
+		result = multierror.Append(result, errors.New("provisioner -> batch_window must not be negative"))
+	}
+
 	for k, v := range pw.Workers {
 		if v < 0 {
 			result = multierror.Append(result, fmt.Errorf("number of workers for %q must be positive", k))
@@ -809,9 +1281,19 @@ func (s *PolicySource) copy() *PolicySource {
 		enabled = ptr.BoolToPtr(*s.Enabled)
 	}
 
+	var cfg map[string]string
+	if s.Config != nil {
+		if i, err := copystructure.Copy(s.Config); err != nil {
+			panic(err.Error())
+		} else {
+			cfg = i.(map[string]string)
+		}
+	}
+
 	return &PolicySource{
 		Name:    s.Name,
 		Enabled: enabled,
+		Config:  cfg,
 	}
 }
 
@@ -828,6 +1310,9 @@ func (s *PolicySource) merge(b *PolicySource) *PolicySource {
 	if b.Enabled != nil {
 		result.Enabled = b.Enabled
 	}
+	if len(b.Config) != 0 {
+		result.Config = b.Config
+	}
 
 	return &result
 }
@@ -837,13 +1322,23 @@ func (s *PolicySource) validate() *multierror.Error {
 	prefix := fmt.Sprintf("source[%s] ->", s.Name)
 
 	validSources := map[string]bool{
-		policySourceNomad: true,
-		policySourceFile:  true,
+		policySourceNomad:          true,
+		policySourceFile:           true,
+		policySourceS3:             true,
+		policySourceVault:          true,
+		policySourceNomadVariables: true,
+		policySourceAPI:            true,
 	}
 	if _, ok := validSources[s.Name]; !ok {
 		result = multierror.Append(result, fmt.Errorf("invalid source %q", s.Name))
 	}
 
+	// The API source accepts policy writes, so it must not be enabled without
+	// a token to authenticate requests.
+	if s.Name == policySourceAPI && s.Enabled != nil && *s.Enabled && s.Config["token"] == "" {
+		result = multierror.Append(result, errors.New("token must be set"))
+	}
+
 	// Prefix all errors.
 	if result != nil {
 		for i, err := range result.Errors {
@@ -951,6 +1446,17 @@ func parseFile(file string, cfg *Agent) error {
 			cfg.Policy.DefaultEvaluationInterval = d
 		}
 
+		for _, w := range []*PolicyWebhook{cfg.Policy.ChangeWebhook, cfg.Policy.AdmissionWebhook} {
+			if w == nil || w.TimeoutHCL == "" {
+				continue
+			}
+			d, err := time.ParseDuration(w.TimeoutHCL)
+			if err != nil {
+				return err
+			}
+			w.Timeout = d
+		}
+
 		for _, source := range cfg.Policy.Sources {
 			if source.Enabled == nil {
 				// Default to true if source block is defined.
@@ -976,6 +1482,24 @@ func parseFile(file string, cfg *Agent) error {
 		}
 	}
 
+	if cfg.PolicyDefaults != nil {
+		if cfg.PolicyDefaults.CooldownHCL != "" {
+			d, err := time.ParseDuration(cfg.PolicyDefaults.CooldownHCL)
+			if err != nil {
+				return err
+			}
+			cfg.PolicyDefaults.Cooldown = d
+		}
+
+		if cfg.PolicyDefaults.EvaluationIntervalHCL != "" {
+			d, err := time.ParseDuration(cfg.PolicyDefaults.EvaluationIntervalHCL)
+			if err != nil {
+				return err
+			}
+			cfg.PolicyDefaults.EvaluationInterval = d
+		}
+	}
+
 	if cfg.PolicyEval != nil {
 		if cfg.PolicyEval.AckTimeoutHCL != "" {
 			t, err := time.ParseDuration(cfg.PolicyEval.AckTimeoutHCL)
@@ -985,9 +1509,67 @@ func parseFile(file string, cfg *Agent) error {
 			cfg.PolicyEval.AckTimeout = t
 		}
 
+		if cfg.PolicyEval.DependencyWindowHCL != "" {
+			t, err := time.ParseDuration(cfg.PolicyEval.DependencyWindowHCL)
+			if err != nil {
+				return err
+			}
+			cfg.PolicyEval.DependencyWindow = t
+		}
+
 		if cfg.PolicyEval.DeliveryLimitPtr != nil {
 			cfg.PolicyEval.DeliveryLimit = *cfg.PolicyEval.DeliveryLimitPtr
 		}
+
+		if c := cfg.PolicyEval.APMCache; c != nil {
+			if c.TTLHCL != "" {
+				t, err := time.ParseDuration(c.TTLHCL)
+				if err != nil {
+					return err
+				}
+				c.TTL = t
+			}
+
+			if len(c.SourceTTLsHCL) > 0 {
+				c.SourceTTLs = make(map[string]time.Duration, len(c.SourceTTLsHCL))
+				for k, v := range c.SourceTTLsHCL {
+					t, err := time.ParseDuration(v)
+					if err != nil {
+						return err
+					}
+					c.SourceTTLs[k] = t
+				}
+			}
+		}
+
+		if c := cfg.PolicyEval.TargetStatusCache; c != nil {
+			if c.TTLHCL != "" {
+				t, err := time.ParseDuration(c.TTLHCL)
+				if err != nil {
+					return err
+				}
+				c.TTL = t
+			}
+
+			if len(c.TargetTTLsHCL) > 0 {
+				c.TargetTTLs = make(map[string]time.Duration, len(c.TargetTTLsHCL))
+				for k, v := range c.TargetTTLsHCL {
+					t, err := time.ParseDuration(v)
+					if err != nil {
+						return err
+					}
+					c.TargetTTLs[k] = t
+				}
+			}
+		}
+
+		if c := cfg.PolicyEval.Provisioner; c != nil && c.BatchWindowHCL != "" {
+			t, err := time.ParseDuration(c.BatchWindowHCL)
+			if err != nil {
+				return err
+			}
+			c.BatchWindow = t
+		}
 	}
 
 	if cfg.DynamicApplicationSizing != nil {
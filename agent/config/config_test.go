@@ -30,6 +30,7 @@ func Test_Default(t *testing.T) {
 	assert.Len(t, def.Policy.Sources, 2)
 	assert.Equal(t, defaultPolicyEvalDeliveryLimit, def.PolicyEval.DeliveryLimit)
 	assert.Equal(t, defaultPolicyEvalAckTimeout, def.PolicyEval.AckTimeout)
+	assert.Equal(t, defaultPolicyEvalDependencyWindow, def.PolicyEval.DependencyWindow)
 	assert.Equal(t, defaultPolicyEvalWorkers, def.PolicyEval.Workers)
 	assert.Len(t, def.APMs, 1)
 	assert.Len(t, def.Targets, 1)
@@ -220,11 +221,15 @@ func TestAgent_Merge(t *testing.T) {
 					Enabled: ptr.BoolToPtr(true),
 				},
 			},
+			ConflictStrategy:    "none",
+			SourcePriority:      []string{"nomad", "file"},
+			VersionHistoryLimit: 10,
 		},
 		PolicyEval: &PolicyEval{
 			DeliveryLimitPtr: ptr.IntToPtr(10),
 			DeliveryLimit:    10,
 			AckTimeout:       3 * time.Minute,
+			DependencyWindow: defaultPolicyEvalDependencyWindow,
 			Workers: map[string]int{
 				"cluster":    8,
 				"horizontal": 7,
@@ -524,3 +529,371 @@ policy {
 	}
 	assert.ElementsMatch(t, expected, result.Policy.Sources)
 }
+
+func TestAgent_policySourceConfig(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	s3SourceCfg := `
+policy {
+  source "s3" {
+    config = {
+      bucket = "policies"
+      prefix = "prod/"
+    }
+  }
+}`
+
+	_, err = fh.WriteString(s3SourceCfg)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	result := defaultConfig.Merge(cfg)
+	require.NoError(t, result.Validate())
+
+	expected := []*PolicySource{
+		{
+			Name:    "file",
+			Enabled: ptr.BoolToPtr(true),
+		},
+		{
+			Name:    "nomad",
+			Enabled: ptr.BoolToPtr(true),
+		},
+		{
+			Name:    "s3",
+			Enabled: ptr.BoolToPtr(true),
+			Config:  map[string]string{"bucket": "policies", "prefix": "prod/"},
+		},
+	}
+	assert.ElementsMatch(t, expected, result.Policy.Sources)
+}
+
+func TestAgent_policySourceAPI(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	_, err = fh.WriteString(`
+policy {
+  source "api" {
+    config = {
+      persistence = "disk"
+      path        = "/var/lib/nomad-autoscaler/policies"
+    }
+  }
+}`)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	// The api source must not be enabled without a token.
+	result := defaultConfig.Merge(cfg)
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "source[api] -> token must be set")
+
+	for _, s := range result.Policy.Sources {
+		if s.Name == "api" {
+			s.Config["token"] = "secret"
+		}
+	}
+	require.NoError(t, result.Validate())
+}
+
+func TestAgent_policyDefaults(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	_, err = fh.WriteString(`
+policy_defaults {
+  cooldown            = "2m"
+  evaluation_interval = "30s"
+  on_check_error      = "ignore"
+  min                 = 1
+  max                 = 10
+}`)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	result := defaultConfig.Merge(cfg)
+	require.NoError(t, result.Validate())
+	assert.Equal(t, &PolicyDefaults{
+		Cooldown:              2 * time.Minute,
+		CooldownHCL:           "2m",
+		EvaluationInterval:    30 * time.Second,
+		EvaluationIntervalHCL: "30s",
+		OnCheckError:          "ignore",
+		Min:                   1,
+		Max:                   10,
+	}, result.PolicyDefaults)
+
+	// Invalid values should be rejected.
+	result.PolicyDefaults = &PolicyDefaults{OnCheckError: "maybe", Min: 5, Max: 2}
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "on_check_error must be one of fail or ignore")
+	assert.Contains(t, err.Error(), "min must not be greater than max")
+}
+
+func TestAgent_policyFilter(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+	assert.Nil(t, defaultConfig.PolicyFilter)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	_, err = fh.WriteString(`
+policy_filter {
+  labels = {
+    team = "payments"
+    tier = "web"
+  }
+}`)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	result := defaultConfig.Merge(cfg)
+	require.NoError(t, result.Validate())
+	assert.Equal(t, map[string]string{"team": "payments", "tier": "web"}, result.PolicyFilter.Labels)
+
+	// Merging another filter should combine the labels.
+	result = result.Merge(&Agent{PolicyFilter: &PolicyFilter{Labels: map[string]string{"tier": "api"}}})
+	assert.Equal(t, map[string]string{"team": "payments", "tier": "api"}, result.PolicyFilter.Labels)
+
+	// Empty keys should be rejected.
+	result.PolicyFilter = &PolicyFilter{Labels: map[string]string{"": "payments"}}
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "policy_filter -> labels must not contain an empty key")
+}
+
+func TestAgent_policyConflicts(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+	assert.Equal(t, "none", defaultConfig.Policy.ConflictStrategy)
+	assert.Equal(t, []string{"nomad", "file"}, defaultConfig.Policy.SourcePriority)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	_, err = fh.WriteString(`
+policy {
+  conflict_strategy = "override"
+  source_priority   = ["file", "s3", "nomad"]
+}`)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	result := defaultConfig.Merge(cfg)
+	require.NoError(t, result.Validate())
+	assert.Equal(t, "override", result.Policy.ConflictStrategy)
+	assert.Equal(t, []string{"file", "s3", "nomad"}, result.Policy.SourcePriority)
+
+	// Invalid values should be rejected.
+	result.Policy.ConflictStrategy = "merge"
+	result.Policy.SourcePriority = []string{"file", "file"}
+	result.Policy.DefaultEvaluationInterval = -time.Second
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid conflict_strategy "merge"`)
+	assert.Contains(t, err.Error(), `duplicate source "file" in source_priority`)
+	assert.Contains(t, err.Error(), "default_evaluation_interval must not be negative")
+}
+
+func TestAgent_policyChangeWebhook(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+	assert.Nil(t, defaultConfig.Policy.ChangeWebhook)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	_, err = fh.WriteString(`
+policy {
+  change_webhook {
+    address = "https://example.com/hook"
+    timeout = "5s"
+    headers = {
+      Authorization = "Bearer secret"
+    }
+  }
+
+  admission_webhook {
+    address = "https://example.com/admit"
+    timeout = "2s"
+  }
+}`)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	result := defaultConfig.Merge(cfg)
+	require.NoError(t, result.Validate())
+	require.NotNil(t, result.Policy.ChangeWebhook)
+	assert.Equal(t, "https://example.com/hook", result.Policy.ChangeWebhook.Address)
+	assert.Equal(t, 5*time.Second, result.Policy.ChangeWebhook.Timeout)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer secret"}, result.Policy.ChangeWebhook.Headers)
+	require.NotNil(t, result.Policy.AdmissionWebhook)
+	assert.Equal(t, "https://example.com/admit", result.Policy.AdmissionWebhook.Address)
+	assert.Equal(t, 2*time.Second, result.Policy.AdmissionWebhook.Timeout)
+
+	// Invalid values should be rejected.
+	result.Policy.ChangeWebhook.Address = "ftp://example.com"
+	result.Policy.ChangeWebhook.Timeout = -time.Second
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `change_webhook -> address "ftp://example.com" must use http or https`)
+	assert.Contains(t, err.Error(), "change_webhook -> timeout must not be negative")
+
+	result.Policy.AdmissionWebhook.Address = "admit"
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `admission_webhook -> address "admit" must use http or https`)
+}
+
+func TestAgent_policyEvalAPMCache(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+	assert.Nil(t, defaultConfig.PolicyEval.APMCache)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	_, err = fh.WriteString(`
+policy_eval {
+  apm_cache {
+    ttl = "15s"
+
+    source_ttl = {
+      prometheus = "1m"
+      nomad-apm  = "0s"
+    }
+  }
+}`)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	result := defaultConfig.Merge(cfg)
+	require.NoError(t, result.Validate())
+	assert.Equal(t, 15*time.Second, result.PolicyEval.APMCache.TTL)
+	assert.Equal(t, map[string]time.Duration{
+		"prometheus": time.Minute,
+		"nomad-apm":  0,
+	}, result.PolicyEval.APMCache.SourceTTLs)
+
+	// Negative TTLs should be rejected.
+	result.PolicyEval.APMCache = &APMCache{
+		TTL:        -time.Second,
+		SourceTTLs: map[string]time.Duration{"prometheus": -time.Second},
+	}
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "apm_cache -> ttl must not be negative")
+	assert.Contains(t, err.Error(), `apm_cache -> source_ttl for "prometheus" must not be negative`)
+}
+
+func TestAgent_policyEvalTargetStatusCache(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+	assert.Nil(t, defaultConfig.PolicyEval.TargetStatusCache)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	_, err = fh.WriteString(`
+policy_eval {
+  target_status_cache {
+    ttl = "10s"
+
+    target_ttl = {
+      aws-asg      = "30s"
+      nomad-target = "0s"
+    }
+  }
+}`)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	result := defaultConfig.Merge(cfg)
+	require.NoError(t, result.Validate())
+	assert.Equal(t, 10*time.Second, result.PolicyEval.TargetStatusCache.TTL)
+	assert.Equal(t, map[string]time.Duration{
+		"aws-asg":      30 * time.Second,
+		"nomad-target": 0,
+	}, result.PolicyEval.TargetStatusCache.TargetTTLs)
+
+	// Negative TTLs should be rejected.
+	result.PolicyEval.TargetStatusCache = &TargetStatusCache{
+		TTL:        -time.Second,
+		TargetTTLs: map[string]time.Duration{"aws-asg": -time.Second},
+	}
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "target_status_cache -> ttl must not be negative")
+	assert.Contains(t, err.Error(), `target_status_cache -> target_ttl for "aws-asg" must not be negative`)
+}
+
+func TestAgent_policyEvalProvisioner(t *testing.T) {
+	defaultConfig, err := Default()
+	require.NoError(t, err)
+	assert.Nil(t, defaultConfig.PolicyEval.Provisioner)
+
+	fh, err := os.CreateTemp("", "nomad-autoscaler*.hcl")
+	require.NoError(t, err)
+	defer os.RemoveAll(fh.Name())
+
+	_, err = fh.WriteString(`
+policy_eval {
+  provisioner {
+    apm          = "nomad-apm"
+    batch_window = "5s"
+  }
+}`)
+	require.NoError(t, err)
+
+	cfg := &Agent{}
+	require.NoError(t, parseFile(fh.Name(), cfg))
+
+	result := defaultConfig.Merge(cfg)
+	require.NoError(t, result.Validate())
+	assert.Equal(t, "nomad-apm", result.PolicyEval.Provisioner.APM)
+	assert.Equal(t, 5*time.Second, result.PolicyEval.Provisioner.BatchWindow)
+
+	// Negative batch windows should be rejected.
+	result.PolicyEval.Provisioner = &Provisioner{BatchWindow: -time.Second}
+	err = result.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "provisioner -> batch_window must not be negative")
+}
@@ -9,9 +9,10 @@ import (
 )
 
 // getHealth is the HTTP handler used to respond when a request is made to the
-// health endpoint. The response is based on the aliveness parameter within the
-// httpServer struct.
-func (s *Server) getHealth(_ http.ResponseWriter, r *http.Request) (interface{}, error) {
+// health endpoint. The response code is based on the aliveness parameter
+// within the httpServer struct, while the body details the health of the
+// agent components.
+func (s *Server) getHealth(w http.ResponseWriter, r *http.Request) (interface{}, error) {
 
 	// Only allow GET requests on this endpoint.
 	if r.Method != http.MethodGet {
@@ -22,5 +23,5 @@ func (s *Server) getHealth(_ http.ResponseWriter, r *http.Request) (interface{},
 		return nil, newCodedError(http.StatusServiceUnavailable, "Service unavailable")
 
 	}
-	return nil, nil
+	return s.agent.GetHealth(w, r)
 }
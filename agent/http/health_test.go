@@ -18,6 +18,7 @@ func TestServer_getHealth(t *testing.T) {
 		inputWriter       *httptest.ResponseRecorder
 		inputSetAliveness int32
 		expectedRespCode  int
+		expectedRespBody  string
 		name              string
 	}{
 		{
@@ -25,6 +26,7 @@ func TestServer_getHealth(t *testing.T) {
 			inputWriter:       httptest.NewRecorder(),
 			inputSetAliveness: healthAlivenessReady,
 			expectedRespCode:  200,
+			expectedRespBody:  `{"Sources":[{"LastError":"","LastErrorTime":null,"LastSync":null,"Name":"nomad","PolicyCount":1}]}`,
 			name:              "agent alive and ready",
 		},
 		{
@@ -52,6 +54,9 @@ func TestServer_getHealth(t *testing.T) {
 			atomic.StoreInt32(&srv.aliveness, tc.inputSetAliveness)
 			srv.mux.ServeHTTP(tc.inputWriter, tc.inputReq)
 			assert.Equal(t, tc.expectedRespCode, tc.inputWriter.Code, tc.name)
+			if tc.expectedRespBody != "" {
+				assert.JSONEq(t, tc.expectedRespBody, tc.inputWriter.Body.String(), tc.name)
+			}
 		})
 	}
 }
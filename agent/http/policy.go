@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	apiPolicy "github.com/hashicorp/nomad-autoscaler/policy/api"
+)
+
+// validatePolicy handles requests to the `/v1/policies/validate` endpoint.
+func (s *Server) validatePolicy(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		return nil, newCodedError(http.StatusMethodNotAllowed, errInvalidMethod)
+	}
+
+	obj, err := s.agent.ValidatePolicy(w, r)
+	return obj, policyError(err)
+}
+
+// listPolicies handles requests to the `/v1/policies` endpoint.
+func (s *Server) listPolicies(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	if r.Method != http.MethodGet {
+		return nil, newCodedError(http.StatusMethodNotAllowed, errInvalidMethod)
+	}
+
+	obj, err := s.agent.ListPolicies(w, r)
+	return obj, policyError(err)
+}
+
+// policySpecificRequest handles the requests for the `/v1/policies/` endpoint
+// and sub-paths. The supported paths are:
+//
+//	GET             /v1/policies/:name
+//	PUT|POST        /v1/policies/:name
+//	DELETE          /v1/policies/:name
+//	GET      /v1/policies/:id/versions
+//	PUT|POST /v1/policies/:id/versions/:version/pin
+//	PUT|POST /v1/policies/:id/unpin
+func (s *Server) policySpecificRequest(w http.ResponseWriter, r *http.Request) (interface{}, error) {
+	path := strings.TrimPrefix(r.URL.Path, policyRoutePattern)
+	parts := strings.Split(path, "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return s.policyCRUD(w, r, parts[0])
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "versions":
+		return s.policyVersions(w, r, parts[0])
+	case len(parts) == 4 && parts[0] != "" && parts[1] == "versions" && parts[3] == "pin":
+		return s.policyPin(w, r, parts[0], parts[2])
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "unpin":
+		return s.policyUnpin(w, r, parts[0])
+	default:
+		return nil, newCodedError(http.StatusNotFound, "")
+	}
+}
+
+func (s *Server) policyCRUD(w http.ResponseWriter, r *http.Request, name string) (interface{}, error) {
+	var (
+		obj interface{}
+		err error
+	)
+
+	switch r.Method {
+	case http.MethodGet:
+		obj, err = s.agent.GetPolicy(w, r, name)
+	case http.MethodPut, http.MethodPost:
+		obj, err = s.agent.PutPolicy(w, r, name)
+	case http.MethodDelete:
+		obj, err = s.agent.DeletePolicy(w, r, name)
+	default:
+		return nil, newCodedError(http.StatusMethodNotAllowed, errInvalidMethod)
+	}
+	return obj, policyError(err)
+}
+
+func (s *Server) policyVersions(w http.ResponseWriter, r *http.Request, id string) (interface{}, error) {
+	if r.Method != http.MethodGet {
+		return nil, newCodedError(http.StatusMethodNotAllowed, errInvalidMethod)
+	}
+
+	obj, err := s.agent.GetPolicyVersions(w, r, id)
+	return obj, policyError(err)
+}
+
+func (s *Server) policyPin(w http.ResponseWriter, r *http.Request, id, version string) (interface{}, error) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		return nil, newCodedError(http.StatusMethodNotAllowed, errInvalidMethod)
+	}
+
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil || v == 0 {
+		return nil, newCodedError(http.StatusBadRequest, "invalid policy version")
+	}
+
+	obj, err := s.agent.PinPolicyVersion(w, r, id, v)
+	return obj, policyError(err)
+}
+
+func (s *Server) policyUnpin(w http.ResponseWriter, r *http.Request, id string) (interface{}, error) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		return nil, newCodedError(http.StatusMethodNotAllowed, errInvalidMethod)
+	}
+
+	obj, err := s.agent.UnpinPolicy(w, r, id)
+	return obj, policyError(err)
+}
+
+// policyError converts known policy errors into coded errors so the correct
+// response code is returned.
+func policyError(err error) error {
+	switch {
+	case errors.Is(err, policy.ErrPolicyNotFound),
+		errors.Is(err, policy.ErrPolicyVersionNotFound),
+		errors.Is(err, apiPolicy.ErrSourceNotEnabled):
+		return newCodedError(http.StatusNotFound, err.Error())
+	case errors.Is(err, apiPolicy.ErrPermissionDenied):
+		return newCodedError(http.StatusForbidden, err.Error())
+	case errors.Is(err, apiPolicy.ErrInvalidPolicy):
+		return newCodedError(http.StatusBadRequest, err.Error())
+	}
+	return err
+}
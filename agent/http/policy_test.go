@@ -0,0 +1,225 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_validatePolicy(t *testing.T) {
+	testCases := []struct {
+		inputReq         *http.Request
+		expectedRespCode int
+		name             string
+	}{
+		{
+			inputReq:         httptest.NewRequest("POST", "/v1/policies/validate", strings.NewReader(`scaling "a" {}`)),
+			expectedRespCode: 200,
+			name:             "successfully validate",
+		},
+		{
+			inputReq:         httptest.NewRequest("GET", "/v1/policies/validate", nil),
+			expectedRespCode: 405,
+			name:             "incorrect request method",
+		},
+	}
+
+	srv, stopSrv := TestServer(t, false)
+	defer stopSrv()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, tc.inputReq)
+			assert.Equal(tc.expectedRespCode, w.Code)
+		})
+	}
+}
+
+func TestServer_policySpecificRequest(t *testing.T) {
+	newReq := func(method, target, token string) *http.Request {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("X-Nomad-Autoscaler-Token", token)
+		}
+		return req
+	}
+
+	testCases := []struct {
+		inputReq         *http.Request
+		expectedRespCode int
+		name             string
+	}{
+		{
+			inputReq:         newReq("GET", "/v1/policies/test/versions", "secret"),
+			expectedRespCode: 200,
+			name:             "successfully list versions",
+		},
+		{
+			inputReq:         newReq("GET", "/v1/policies/test/versions", ""),
+			expectedRespCode: 403,
+			name:             "list versions missing token",
+		},
+		{
+			inputReq:         newReq("GET", "/v1/policies/test/versions", "wrong"),
+			expectedRespCode: 403,
+			name:             "list versions incorrect token",
+		},
+		{
+			inputReq:         newReq("GET", "/v1/policies/missing/versions", "secret"),
+			expectedRespCode: 404,
+			name:             "list versions of unknown policy",
+		},
+		{
+			inputReq:         httptest.NewRequest("POST", "/v1/policies/test/versions", nil),
+			expectedRespCode: 405,
+			name:             "list versions incorrect request method",
+		},
+		{
+			inputReq:         newReq("PUT", "/v1/policies/test/versions/1/pin", "secret"),
+			expectedRespCode: 200,
+			name:             "successfully pin version",
+		},
+		{
+			inputReq:         newReq("PUT", "/v1/policies/test/versions/2/pin", "secret"),
+			expectedRespCode: 404,
+			name:             "pin unknown version",
+		},
+		{
+			inputReq:         httptest.NewRequest("PUT", "/v1/policies/test/versions/latest/pin", nil),
+			expectedRespCode: 400,
+			name:             "pin invalid version",
+		},
+		{
+			inputReq:         httptest.NewRequest("GET", "/v1/policies/test/versions/1/pin", nil),
+			expectedRespCode: 405,
+			name:             "pin incorrect request method",
+		},
+		{
+			inputReq:         newReq("PUT", "/v1/policies/test/versions/1/pin", ""),
+			expectedRespCode: 403,
+			name:             "pin missing token",
+		},
+		{
+			inputReq:         newReq("POST", "/v1/policies/test/unpin", "secret"),
+			expectedRespCode: 200,
+			name:             "successfully unpin",
+		},
+		{
+			inputReq:         newReq("POST", "/v1/policies/test/unpin", "wrong"),
+			expectedRespCode: 403,
+			name:             "unpin incorrect token",
+		},
+		{
+			inputReq:         httptest.NewRequest("GET", "/v1/policies/test/unknown", nil),
+			expectedRespCode: 404,
+			name:             "unknown path",
+		},
+	}
+
+	srv, stopSrv := TestServer(t, false)
+	defer stopSrv()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, tc.inputReq)
+			assert.Equal(tc.expectedRespCode, w.Code)
+		})
+	}
+}
+
+func TestServer_policyCRUD(t *testing.T) {
+	newReq := func(method, target, token string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(`scaling "test" {}`))
+		if token != "" {
+			req.Header.Set("X-Nomad-Autoscaler-Token", token)
+		}
+		return req
+	}
+
+	testCases := []struct {
+		inputReq         *http.Request
+		expectedRespCode int
+		name             string
+	}{
+		{
+			inputReq:         newReq("GET", "/v1/policies", "secret"),
+			expectedRespCode: 200,
+			name:             "successfully list policies",
+		},
+		{
+			inputReq:         newReq("GET", "/v1/policies", ""),
+			expectedRespCode: 403,
+			name:             "list policies without token",
+		},
+		{
+			inputReq:         newReq("POST", "/v1/policies", "secret"),
+			expectedRespCode: 405,
+			name:             "list policies incorrect request method",
+		},
+		{
+			inputReq:         newReq("GET", "/v1/policies/test", "secret"),
+			expectedRespCode: 200,
+			name:             "successfully get policy",
+		},
+		{
+			inputReq:         newReq("GET", "/v1/policies/missing", "secret"),
+			expectedRespCode: 404,
+			name:             "get unknown policy",
+		},
+		{
+			inputReq:         newReq("PUT", "/v1/policies/test", "secret"),
+			expectedRespCode: 200,
+			name:             "successfully put policy",
+		},
+		{
+			inputReq:         newReq("PUT", "/v1/policies/other", "secret"),
+			expectedRespCode: 400,
+			name:             "put invalid policy",
+		},
+		{
+			inputReq:         newReq("PUT", "/v1/policies/test", "wrong"),
+			expectedRespCode: 403,
+			name:             "put policy with wrong token",
+		},
+		{
+			inputReq:         newReq("DELETE", "/v1/policies/test", "secret"),
+			expectedRespCode: 200,
+			name:             "successfully delete policy",
+		},
+		{
+			inputReq:         newReq("DELETE", "/v1/policies/missing", "secret"),
+			expectedRespCode: 404,
+			name:             "delete unknown policy",
+		},
+		{
+			inputReq:         newReq("PATCH", "/v1/policies/test", "secret"),
+			expectedRespCode: 405,
+			name:             "incorrect request method",
+		},
+	}
+
+	srv, stopSrv := TestServer(t, false)
+	defer stopSrv()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, tc.inputReq)
+			assert.Equal(tc.expectedRespCode, w.Code)
+		})
+	}
+}
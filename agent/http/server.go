@@ -31,6 +31,18 @@ const (
 	// register endpoints related to the agent.
 	agentRoutePattern = "/v1/agent/"
 
+	// policiesRoutePattern is the Autoscaler HTTP router pattern which is used
+	// to register the endpoint listing the policies of the api policy source.
+	policiesRoutePattern = "/v1/policies"
+
+	// policyRoutePattern is the Autoscaler HTTP router pattern which is used
+	// to register endpoints related to individual policies.
+	policyRoutePattern = "/v1/policies/"
+
+	// policyValidateRoutePattern is the Autoscaler HTTP router pattern which
+	// is used to register the policy validation endpoint.
+	policyValidateRoutePattern = "/v1/policies/validate"
+
 	// healthAliveness is used to define the health of the Autoscaler agent. It
 	// currently can only be in two states; ready or unavailable and depends
 	// entirely on whether the server is serving or not.
@@ -41,11 +53,40 @@ const (
 // AgentHTTP is the interface that defines the HTTP handlers that an Agent
 // must implement in order to be accessible through the HTTP API.
 type AgentHTTP interface {
+	// GetHealth returns the health of the agent components, such as the
+	// policy sources.
+	GetHealth(resp http.ResponseWriter, req *http.Request) (interface{}, error)
+
 	// DisplayMetrics returns a summary of metrics collected by the agent.
 	DisplayMetrics(resp http.ResponseWriter, req *http.Request) (interface{}, error)
 
 	// ReloadAgent triggers the agent to reload policies and configuration.
 	ReloadAgent(resp http.ResponseWriter, req *http.Request) (interface{}, error)
+
+	// ValidatePolicy parses and validates a submitted policy document.
+	ValidatePolicy(resp http.ResponseWriter, req *http.Request) (interface{}, error)
+
+	// GetPolicyVersions returns the stored versions of a policy.
+	GetPolicyVersions(resp http.ResponseWriter, req *http.Request, id string) (interface{}, error)
+
+	// PinPolicyVersion pins a policy to one of its stored versions.
+	PinPolicyVersion(resp http.ResponseWriter, req *http.Request, id string, version uint64) (interface{}, error)
+
+	// UnpinPolicy removes the pinned version of a policy.
+	UnpinPolicy(resp http.ResponseWriter, req *http.Request, id string) (interface{}, error)
+
+	// ListPolicies returns the policies held by the api policy source.
+	ListPolicies(resp http.ResponseWriter, req *http.Request) (interface{}, error)
+
+	// GetPolicy returns the named policy from the api policy source.
+	GetPolicy(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error)
+
+	// PutPolicy creates or updates the named policy within the api policy
+	// source using the submitted policy document.
+	PutPolicy(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error)
+
+	// DeletePolicy removes the named policy from the api policy source.
+	DeletePolicy(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error)
 }
 
 type Server struct {
@@ -82,6 +123,9 @@ func NewHTTPServer(debug, prom bool, cfg *config.HTTP, log hclog.Logger, agent A
 	srv.mux.HandleFunc(healthRoutePattern, srv.wrap(srv.getHealth))
 	srv.mux.HandleFunc(metricsRoutePattern, srv.wrap(srv.getMetrics))
 	srv.mux.HandleFunc(agentRoutePattern, srv.wrap(srv.agentSpecificRequest))
+	srv.mux.HandleFunc(policyValidateRoutePattern, srv.wrap(srv.validatePolicy))
+	srv.mux.HandleFunc(policiesRoutePattern, srv.wrap(srv.listPolicies))
+	srv.mux.HandleFunc(policyRoutePattern, srv.wrap(srv.policySpecificRequest))
 
 	// Setup the debugging endpoints.
 	if debug {
@@ -3,10 +3,43 @@
 
 package agent
 
-import "net/http"
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	apiPolicy "github.com/hashicorp/nomad-autoscaler/policy/api"
+)
+
+const (
+	// maxPolicyValidationBodySize is the maximum size of a policy document
+	// that can be submitted for validation or to the api policy source.
+	maxPolicyValidationBodySize = 1 << 20
+
+	// policyTokenHeader is the request header used to present the token of
+	// the api policy source. The token can also be sent as a bearer token in
+	// the Authorization header.
+	policyTokenHeader = "X-Nomad-Autoscaler-Token"
+)
+
+// HealthResponse is the response returned by the agent health endpoint.
+type HealthResponse struct {
+
+	// Sources details the health of each configured policy source.
+	Sources []*policy.SourceStatus
+}
 
 // The methods in this file implement in the http.AgentHTTP interface.
 
+func (a *Agent) GetHealth(_ http.ResponseWriter, _ *http.Request) (interface{}, error) {
+	resp := &HealthResponse{Sources: []*policy.SourceStatus{}}
+	if a.policyManager != nil {
+		resp.Sources = a.policyManager.SourceStatuses()
+	}
+	return resp, nil
+}
+
 func (a *Agent) DisplayMetrics(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	return a.inMemSink.DisplayMetrics(resp, req)
 }
@@ -15,3 +48,111 @@ func (a *Agent) ReloadAgent(_ http.ResponseWriter, _ *http.Request) (interface{}
 	a.reload()
 	return nil, nil
 }
+
+func (a *Agent) ValidatePolicy(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	filename, src, err := readPolicyDocument(resp, req, "policy")
+	if err != nil {
+		return nil, err
+	}
+
+	return validatePolicyDocument(filename, src, a.policyProc, a.pluginManager), nil
+}
+
+func (a *Agent) GetPolicyVersions(_ http.ResponseWriter, req *http.Request, id string) (interface{}, error) {
+	if _, err := a.apiPolicySource(req); err != nil {
+		return nil, err
+	}
+	return a.policyManager.PolicyVersions(policy.PolicyID(id))
+}
+
+func (a *Agent) PinPolicyVersion(_ http.ResponseWriter, req *http.Request, id string, version uint64) (interface{}, error) {
+	if _, err := a.apiPolicySource(req); err != nil {
+		return nil, err
+	}
+	if err := a.policyManager.PinPolicyVersion(policy.PolicyID(id), version); err != nil {
+		return nil, err
+	}
+	return a.policyManager.PolicyVersions(policy.PolicyID(id))
+}
+
+func (a *Agent) UnpinPolicy(_ http.ResponseWriter, req *http.Request, id string) (interface{}, error) {
+	if _, err := a.apiPolicySource(req); err != nil {
+		return nil, err
+	}
+	if err := a.policyManager.UnpinPolicy(policy.PolicyID(id)); err != nil {
+		return nil, err
+	}
+	return a.policyManager.PolicyVersions(policy.PolicyID(id))
+}
+
+func (a *Agent) ListPolicies(_ http.ResponseWriter, req *http.Request) (interface{}, error) {
+	src, err := a.apiPolicySource(req)
+	if err != nil {
+		return nil, err
+	}
+	return src.List(), nil
+}
+
+func (a *Agent) GetPolicy(_ http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	src, err := a.apiPolicySource(req)
+	if err != nil {
+		return nil, err
+	}
+	return src.Get(name)
+}
+
+func (a *Agent) PutPolicy(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	src, err := a.apiPolicySource(req)
+	if err != nil {
+		return nil, err
+	}
+
+	filename, doc, err := readPolicyDocument(resp, req, name)
+	if err != nil {
+		return nil, err
+	}
+	return src.Put(req.Context(), name, filename, doc)
+}
+
+func (a *Agent) DeletePolicy(_ http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	src, err := a.apiPolicySource(req)
+	if err != nil {
+		return nil, err
+	}
+	return nil, src.Delete(req.Context(), name)
+}
+
+// apiPolicySource returns the api policy source once the request has been
+// authorized against it.
+func (a *Agent) apiPolicySource(req *http.Request) (*apiPolicy.Source, error) {
+	ps, ok := a.policySources[policy.SourceNameAPI]
+	if !ok {
+		return nil, apiPolicy.ErrSourceNotEnabled
+	}
+	src := ps.(*apiPolicy.Source)
+
+	token := req.Header.Get(policyTokenHeader)
+	if token == "" {
+		token = strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	}
+	if err := src.Authorize(token); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// readPolicyDocument reads the policy document from the request body. The
+// document format is detected from the filename suffix, so the request
+// content type is mapped onto the returned filename.
+func readPolicyDocument(resp http.ResponseWriter, req *http.Request, name string) (string, []byte, error) {
+	src, err := io.ReadAll(http.MaxBytesReader(resp, req.Body, maxPolicyValidationBodySize))
+	if err != nil {
+		return "", nil, err
+	}
+
+	filename := name + ".hcl"
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") || req.URL.Query().Get("format") == "json" {
+		filename = name + ".json"
+	}
+	return filename, src, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	apiPolicy "github.com/hashicorp/nomad-autoscaler/policy/api"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_GetPolicyVersions(t *testing.T) {
+	src, err := apiPolicy.NewAPISource(hclog.NewNullLogger(), nil, map[string]string{"token": "secret"}, nil)
+	require.NoError(t, err)
+
+	versions := policy.NewVersionStore(5)
+	versions.Record("test", policy.SourceNameAPI, &sdk.ScalingPolicy{ID: "test"}, nil)
+
+	a := &Agent{
+		policySources: map[policy.SourceName]policy.Source{policy.SourceNameAPI: src},
+		policyManager: policy.NewManager(&policy.ManagerConfig{
+			Logger:   hclog.NewNullLogger(),
+			Versions: versions,
+		}),
+	}
+
+	testCases := []struct {
+		inputToken    string
+		expectedError error
+		name          string
+	}{
+		{
+			inputToken: "secret",
+			name:       "authorized",
+		},
+		{
+			expectedError: apiPolicy.ErrPermissionDenied,
+			name:          "missing token",
+		},
+		{
+			inputToken:    "wrong",
+			expectedError: apiPolicy.ErrPermissionDenied,
+			name:          "incorrect token",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/policies/test/versions", nil)
+			if tc.inputToken != "" {
+				req.Header.Set(policyTokenHeader, tc.inputToken)
+			}
+
+			obj, err := a.GetPolicyVersions(httptest.NewRecorder(), req, "test")
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError, tc.name)
+				assert.Nil(t, obj, tc.name)
+				return
+			}
+			require.NoError(t, err, tc.name)
+			assert.Equal(t, policy.PolicyID("test"), obj.(*policy.PolicyVersions).ID, tc.name)
+		})
+	}
+
+	// Policy versions cannot be read when the api source is not enabled.
+	a.policySources = map[policy.SourceName]policy.Source{}
+	req := httptest.NewRequest("GET", "/v1/policies/test/versions", nil)
+	req.Header.Set(policyTokenHeader, "secret")
+	_, err = a.GetPolicyVersions(httptest.NewRecorder(), req, "test")
+	assert.ErrorIs(t, err, apiPolicy.ErrSourceNotEnabled)
+}
@@ -19,6 +19,11 @@ func (a *Agent) setupPlugins() error {
 
 	a.pluginManager = manager.NewPluginManager(a.logger, a.config.PluginDir, a.setupPluginsConfig())
 
+	// Setup the target status cache.
+	if c := a.config.PolicyEval.TargetStatusCache; c != nil {
+		a.pluginManager.SetTargetStatusCache(manager.NewTargetStatusCache(c.TTL, c.TargetTTLs))
+	}
+
 	// Trigger the loading of the plugins which will be available to the agent.
 	// Any errors here will cause the agent to fail, but will include wrapped
 	// errors so the user can fix any problems in a single iteration.
@@ -0,0 +1,170 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/nomad-autoscaler/plugins/manager"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	filePolicy "github.com/hashicorp/nomad-autoscaler/policy/file"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// PolicyValidationResponse is the response returned when validating a policy
+// document.
+type PolicyValidationResponse struct {
+
+	// Valid indicates whether the document, and all the policies within it,
+	// passed validation.
+	Valid bool
+
+	// Policies lists the names of the policies found within the document.
+	Policies []string
+
+	// Errors contains all the validation errors found.
+	Errors []*PolicyValidationError
+}
+
+// PolicyValidationError describes a single problem found when validating a
+// policy document.
+type PolicyValidationError struct {
+
+	// Policy is the name of the policy the error relates to. It is empty if
+	// the error relates to the document as a whole, such as a parse error.
+	Policy string
+
+	// Message is the human readable description of the error.
+	Message string
+
+	// Range is the location of the error within the document, in the form
+	// <filename>:<line>,<column>-<line>,<column>, if known.
+	Range string `json:",omitempty"`
+}
+
+// validatePolicyDocument runs the submitted policy document through the same
+// parsing and processing steps as policies loaded by the agent, and checks
+// that the plugins it references are available. The filename is used to
+// determine the document format. Documents are submitted by remote clients,
+// so are decoded without the functions which read from the agent host.
+func validatePolicyDocument(filename string, src []byte, pr *policy.Processor, pm *manager.PluginManager) *PolicyValidationResponse {
+	resp := &PolicyValidationResponse{Policies: []string{}, Errors: []*PolicyValidationError{}}
+
+	policies, err := filePolicy.DecodeUntrusted(filename, src)
+	if err != nil {
+		resp.Errors = append(resp.Errors, validationErrors("", err)...)
+		return resp
+	}
+
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resp.Policies = names
+
+	for _, name := range names {
+		resp.Errors = append(resp.Errors, validationErrors(name, validatePolicy(name, policies[name], pr, pm))...)
+	}
+
+	resp.Valid = len(resp.Errors) == 0
+	return resp
+}
+
+// validatePolicy performs the processing and validation steps on a single
+// decoded policy.
+func validatePolicy(name string, p *sdk.ScalingPolicy, pr *policy.Processor, pm *manager.PluginManager) error {
+	var mErr *multierror.Error
+
+	// Policies decoded from documents do not have an ID until a source
+	// assigns one, so use the name to satisfy the processor validation.
+	p.ID = name
+
+	if p.Target == nil || p.Target.Name == "" {
+		return errors.New("policy target is required")
+	}
+
+	if pr != nil {
+		pr.ApplyPolicyDefaults(p)
+		for _, c := range p.Checks {
+			pr.CanonicalizeCheck(c, p.Target)
+		}
+		if err := pr.ValidatePolicy(p); err != nil {
+			mErr = multierror.Append(mErr, err)
+		}
+	}
+
+	if err := p.Validate(); err != nil {
+		mErr = multierror.Append(mErr, err)
+	}
+
+	for _, c := range p.Checks {
+		if c.Strategy == nil || c.Strategy.Name == "" {
+			mErr = multierror.Append(mErr, fmt.Errorf("check %s: strategy is required", c.Name))
+			continue
+		}
+		if pm == nil {
+			continue
+		}
+		if _, err := pm.GetStrategy(c.Strategy.Name); err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("check %s: %v", c.Name, err))
+		}
+		if _, err := pm.GetAPM(c.Source); err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("check %s: %v", c.Name, err))
+		}
+	}
+
+	if pm != nil {
+		if _, err := pm.GetTarget(p.Target); err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("target plugin %q not initialized: %v", p.Target.Name, err))
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// validationErrors flattens err into a list of validation errors. HCL
+// diagnostics are expanded so each one retains its source range.
+func validationErrors(name string, err error) []*PolicyValidationError {
+	if err == nil {
+		return nil
+	}
+
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) {
+		out := make([]*PolicyValidationError, 0, len(diags))
+		for _, d := range diags {
+			if d.Severity != hcl.DiagError {
+				continue
+			}
+
+			msg := d.Summary
+			if d.Detail != "" {
+				msg = fmt.Sprintf("%s: %s", d.Summary, d.Detail)
+			}
+
+			vErr := &PolicyValidationError{Policy: name, Message: msg}
+			if d.Subject != nil {
+				vErr.Range = d.Subject.String()
+			}
+			out = append(out, vErr)
+		}
+		return out
+	}
+
+	var mErr *multierror.Error
+	if errors.As(err, &mErr) {
+		var out []*PolicyValidationError
+		for _, e := range mErr.Errors {
+			out = append(out, validationErrors(name, e)...)
+		}
+		return out
+	}
+
+	return []*PolicyValidationError{{Policy: name, Message: err.Error()}}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package agent
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/agent/config"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/manager"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validatePolicyDocument(t *testing.T) {
+	pm := manager.NewPluginManager(hclog.NewNullLogger(), "", map[string][]*config.Plugin{
+		sdk.PluginTypeAPM: {
+			{Name: plugins.InternalAPMNomad, Driver: plugins.InternalAPMNomad, Config: map[string]string{}},
+		},
+		sdk.PluginTypeStrategy: {
+			{Name: plugins.InternalStrategyTargetValue, Driver: plugins.InternalStrategyTargetValue, Config: map[string]string{}},
+		},
+		sdk.PluginTypeTarget: {
+			{Name: plugins.InternalTargetNomad, Driver: plugins.InternalTargetNomad, Config: map[string]string{}},
+		},
+	})
+	require.NoError(t, pm.Load())
+	defer pm.KillPlugins()
+
+	pr := policy.NewProcessor(&policy.ConfigDefaults{
+		DefaultEvaluationInterval: 10 * time.Second,
+		DefaultCooldown:           time.Minute,
+	}, []string{plugins.InternalAPMNomad})
+
+	testCases := []struct {
+		name           string
+		filename       string
+		input          string
+		expectedResp   *PolicyValidationResponse
+		expectedErrMsg []string
+	}{
+		{
+			name:     "valid policy",
+			filename: "policy.hcl",
+			input: `
+scaling "cache" {
+  min = 1
+  max = 5
+
+  policy {
+    check "cpu" {
+      query = "avg_cpu"
+
+      strategy "target-value" {
+        target = 70
+      }
+    }
+
+    target "nomad-target" {
+      Job   = "example"
+      Group = "cache"
+    }
+  }
+}`,
+			expectedResp: &PolicyValidationResponse{
+				Valid:    true,
+				Policies: []string{"cache"},
+				Errors:   []*PolicyValidationError{},
+			},
+		},
+		{
+			name:     "parse error",
+			filename: "policy.hcl",
+			input:    `scaling "cache" {`,
+			expectedResp: &PolicyValidationResponse{
+				Valid:    false,
+				Policies: []string{},
+				Errors: []*PolicyValidationError{
+					{
+						Message: "Argument or block definition required: An argument or block definition is required here.",
+						Range:   "policy.hcl:1,18-18",
+					},
+				},
+			},
+		},
+		{
+			name:     "host functions",
+			filename: "policy.hcl",
+			input:    `scaling "cache" { max = env("HOME") }`,
+			expectedResp: &PolicyValidationResponse{
+				Valid:    false,
+				Policies: []string{},
+				Errors: []*PolicyValidationError{
+					{
+						Message: `Call to unknown function: There is no function named "env".`,
+						Range:   "policy.hcl:1,25-28",
+					},
+					{
+						Message: "Unsuitable value type: Unsuitable value: value must be known",
+						Range:   "policy.hcl:1,25-29",
+					},
+				},
+			},
+		},
+		{
+			name:     "invalid policy and missing plugins",
+			filename: "policy.hcl",
+			input: `
+scaling "cluster" {
+  min = 5
+  max = 1
+
+  policy {
+    on_check_error = "maybe"
+
+    check "cpu" {
+      source = "prometheus"
+      query  = "avg(cpu)"
+
+      strategy "threshold" {}
+    }
+
+    target "aws-asg" {}
+  }
+}`,
+			expectedErrMsg: []string{
+				"policy Min must not be greater Max",
+				"invalid value for on_check_error",
+				`strategy plugin "threshold" not initialized`,
+				`apm plugin "prometheus" not initialized`,
+				`target plugin "aws-asg" not initialized`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := validatePolicyDocument(tc.filename, []byte(tc.input), pr, pm)
+
+			if tc.expectedResp != nil {
+				assert.Equal(t, tc.expectedResp, resp)
+				return
+			}
+
+			assert.False(t, resp.Valid)
+			require.Len(t, resp.Errors, len(tc.expectedErrMsg))
+			for i, msg := range tc.expectedErrMsg {
+				assert.Contains(t, resp.Errors[i].Message, msg)
+				assert.Equal(t, "cluster", resp.Errors[i].Policy)
+			}
+		})
+	}
+}
+
+func TestAgent_ValidatePolicy(t *testing.T) {
+	// Validation does not depend on the api policy source, so it is available
+	// without the source enabled and without a token.
+	a := &Agent{
+		policySources: map[policy.SourceName]policy.Source{},
+		policyProc:    policy.NewProcessor(&policy.ConfigDefaults{}, nil),
+	}
+
+	req := httptest.NewRequest("POST", "/v1/policies/validate", strings.NewReader(`scaling "cache" {
+  min = 1
+  max = 5
+
+  policy {
+    check "cpu" {
+      query = "avg_cpu"
+
+      strategy "target-value" {
+        target = 70
+      }
+    }
+
+    target "nomad-target" {
+      Job   = "example"
+      Group = "cache"
+    }
+  }
+}`))
+
+	obj, err := a.ValidatePolicy(httptest.NewRecorder(), req)
+	require.NoError(t, err)
+	assert.Equal(t, &PolicyValidationResponse{
+		Valid:    true,
+		Policies: []string{"cache"},
+		Errors:   []*PolicyValidationError{},
+	}, obj)
+}
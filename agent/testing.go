@@ -4,13 +4,20 @@
 package agent
 
 import (
+	"fmt"
 	"net/http"
 
 	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	apiPolicy "github.com/hashicorp/nomad-autoscaler/policy/api"
 )
 
 type MockAgentHTTP struct{}
 
+func (m *MockAgentHTTP) GetHealth(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	return &HealthResponse{Sources: []*policy.SourceStatus{{Name: policy.SourceNameNomad, PolicyCount: 1}}}, nil
+}
+
 func (m *MockAgentHTTP) DisplayMetrics(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	return metrics.MetricsSummary{
 		Timestamp: "2020-11-17 00:17:50 +0000 UTC",
@@ -23,3 +30,77 @@ func (m *MockAgentHTTP) DisplayMetrics(resp http.ResponseWriter, req *http.Reque
 func (m *MockAgentHTTP) ReloadAgent(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	return nil, nil
 }
+
+func (m *MockAgentHTTP) ValidatePolicy(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	return &PolicyValidationResponse{Valid: true}, nil
+}
+
+func (m *MockAgentHTTP) GetPolicyVersions(resp http.ResponseWriter, req *http.Request, id string) (interface{}, error) {
+	if req.Header.Get("X-Nomad-Autoscaler-Token") != "secret" {
+		return nil, apiPolicy.ErrPermissionDenied
+	}
+	if id != "test" {
+		return nil, policy.ErrPolicyNotFound
+	}
+	return &policy.PolicyVersions{ID: policy.PolicyID(id)}, nil
+}
+
+func (m *MockAgentHTTP) PinPolicyVersion(resp http.ResponseWriter, req *http.Request, id string, version uint64) (interface{}, error) {
+	if req.Header.Get("X-Nomad-Autoscaler-Token") != "secret" {
+		return nil, apiPolicy.ErrPermissionDenied
+	}
+	if id != "test" {
+		return nil, policy.ErrPolicyNotFound
+	}
+	if version != 1 {
+		return nil, policy.ErrPolicyVersionNotFound
+	}
+	return &policy.PolicyVersions{ID: policy.PolicyID(id), PinnedVersion: version}, nil
+}
+
+func (m *MockAgentHTTP) UnpinPolicy(resp http.ResponseWriter, req *http.Request, id string) (interface{}, error) {
+	if req.Header.Get("X-Nomad-Autoscaler-Token") != "secret" {
+		return nil, apiPolicy.ErrPermissionDenied
+	}
+	if id != "test" {
+		return nil, policy.ErrPolicyNotFound
+	}
+	return &policy.PolicyVersions{ID: policy.PolicyID(id)}, nil
+}
+
+func (m *MockAgentHTTP) ListPolicies(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Header.Get("X-Nomad-Autoscaler-Token") != "secret" {
+		return nil, apiPolicy.ErrPermissionDenied
+	}
+	return []*apiPolicy.StoredPolicy{{Name: "test", ID: "test-id"}}, nil
+}
+
+func (m *MockAgentHTTP) GetPolicy(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	if req.Header.Get("X-Nomad-Autoscaler-Token") != "secret" {
+		return nil, apiPolicy.ErrPermissionDenied
+	}
+	if name != "test" {
+		return nil, policy.ErrPolicyNotFound
+	}
+	return &apiPolicy.StoredPolicy{Name: name, ID: "test-id"}, nil
+}
+
+func (m *MockAgentHTTP) PutPolicy(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	if req.Header.Get("X-Nomad-Autoscaler-Token") != "secret" {
+		return nil, apiPolicy.ErrPermissionDenied
+	}
+	if name != "test" {
+		return nil, fmt.Errorf("%w: document does not contain policy %q", apiPolicy.ErrInvalidPolicy, name)
+	}
+	return &apiPolicy.StoredPolicy{Name: name, ID: "test-id"}, nil
+}
+
+func (m *MockAgentHTTP) DeletePolicy(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
+	if req.Header.Get("X-Nomad-Autoscaler-Token") != "secret" {
+		return nil, apiPolicy.ErrPermissionDenied
+	}
+	if name != "test" {
+		return nil, policy.ErrPolicyNotFound
+	}
+	return nil, nil
+}
@@ -224,9 +224,15 @@ func TestCommandAgent_readConfig(t *testing.T) {
 					},
 				},
 				PolicyEval: &config.PolicyEval{
-					DeliveryLimit:    10,
-					DeliveryLimitPtr: ptr.IntToPtr(10),
-					AckTimeout:       3 * time.Minute,
+					DeliveryLimit:       10,
+					DeliveryLimitPtr:    ptr.IntToPtr(10),
+					AckTimeout:          3 * time.Minute,
+					DependencyWindow:    time.Minute,
+					DependencyWindowHCL: "1m",
+					Guardrail: &config.Guardrail{
+						Paths: []string{"/etc/nomad-autoscaler/guardrails"},
+						Query: "data.platform.guardrail",
+					},
 					Workers: map[string]int{
 						"cluster":    3,
 						"horizontal": 1,
@@ -303,9 +309,15 @@ func TestCommandAgent_readConfig(t *testing.T) {
 					},
 				},
 				PolicyEval: &config.PolicyEval{
-					DeliveryLimit:    10,
-					DeliveryLimitPtr: ptr.IntToPtr(10),
-					AckTimeout:       3 * time.Minute,
+					DeliveryLimit:       10,
+					DeliveryLimitPtr:    ptr.IntToPtr(10),
+					AckTimeout:          3 * time.Minute,
+					DependencyWindow:    time.Minute,
+					DependencyWindowHCL: "1m",
+					Guardrail: &config.Guardrail{
+						Paths: []string{"/etc/nomad-autoscaler/guardrails"},
+						Query: "data.platform.guardrail",
+					},
 					Workers: map[string]int{
 						"cluster":    3,
 						"horizontal": 1,
@@ -0,0 +1,397 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/nomad-autoscaler/agent/config"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	filePolicy "github.com/hashicorp/nomad-autoscaler/policy/file"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	flaghelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/flag"
+)
+
+const (
+	// lintSeverityError and lintSeverityWarning are the severities of the
+	// issues found when linting. Only errors cause a non-zero exit code.
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+
+	// lintFormatText and lintFormatJSON are the supported output formats.
+	lintFormatText = "text"
+	lintFormatJSON = "json"
+)
+
+type PolicyLintCommand struct {
+	args []string
+}
+
+// LintResult is the machine-readable output of the policy lint command.
+type LintResult struct {
+	Files    []*LintFile `json:"files"`
+	Errors   int         `json:"errors"`
+	Warnings int         `json:"warnings"`
+}
+
+// LintFile details the policies and issues found within a single file.
+type LintFile struct {
+	File     string       `json:"file"`
+	Policies []string     `json:"policies"`
+	Issues   []*LintIssue `json:"issues"`
+}
+
+// LintIssue is a single problem found when linting a policy file.
+type LintIssue struct {
+	Policy   string `json:"policy,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Range    string `json:"range,omitempty"`
+}
+
+// Help should return long-form help text that includes the command-line
+// usage, a brief few sentences explaining the function of the command,
+// and the complete list of flags the command accepts.
+func (c *PolicyLintCommand) Help() string {
+	helpText := `
+Usage: nomad-autoscaler policy lint [options] <path> [<path>...]
+
+  Lints scaling policy files, or directories of policy files, in the format
+  used by the file policy source. Policies are parsed and validated as the
+  agent would, the plugins they reference are checked against the agent
+  configuration, and suspicious values are reported as warnings.
+
+  The command exits with status 1 if any errors are found.
+
+Options:
+
+  -config=<path>
+    The path to either a single agent config file or a directory of config
+    files. The plugins and policy defaults are read from the config. If not
+    specified, the agent defaults are used.
+
+  -format=<format>
+    The output format. Valid values are text and json. The default is text.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis should return a one-line, short synopsis of the command.
+// This should be less than 50 characters ideally.
+func (c *PolicyLintCommand) Synopsis() string {
+	return "Lints scaling policy files"
+}
+
+// Run should run the actual command with the given CLI instance and
+// command-line arguments. It should return the exit status when it is
+// finished.
+func (c *PolicyLintCommand) Run(args []string) int {
+	c.args = args
+
+	var (
+		configPath []string
+		format     string
+	)
+
+	flags := flag.NewFlagSet("policy lint", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Println(c.Help()) }
+	flags.Var((*flaghelper.StringFlag)(&configPath), "config", "")
+	flags.StringVar(&format, "format", lintFormatText, "")
+
+	if err := flags.Parse(c.args); err != nil {
+		return 1
+	}
+
+	if format != lintFormatText && format != lintFormatJSON {
+		fmt.Printf("Invalid format %q, must be one of %q or %q\n", format, lintFormatText, lintFormatJSON)
+		return 1
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		fmt.Println("At least one policy file or directory must be specified")
+		fmt.Println("Run 'nomad-autoscaler policy lint --help' for more information.")
+		return 1
+	}
+
+	cfg, err := config.LoadPaths(configPath)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	files, err := lintFileList(paths)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	result := newPolicyLinter(cfg).lint(files)
+
+	if format == lintFormatJSON {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Printf("Failed to encode result: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(out))
+	} else {
+		fmt.Print(result.text())
+	}
+
+	if result.Errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// lintFileList expands the passed paths into the list of policy files to
+// lint. Directories are not read recursively.
+func lintFileList(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		for _, ext := range []string{"*.hcl", "*.json"} {
+			matches, err := filepath.Glob(filepath.Join(p, ext))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// policyLinter lints policy files against an agent config.
+type policyLinter struct {
+	processor  *policy.Processor
+	apms       map[string]bool
+	strategies map[string]bool
+	targets    map[string]bool
+}
+
+func newPolicyLinter(cfg *config.Agent) *policyLinter {
+	defaults := policy.ConfigDefaults{
+		DefaultEvaluationInterval: cfg.Policy.DefaultEvaluationInterval,
+		DefaultCooldown:           cfg.Policy.DefaultCooldown,
+	}
+	if pd := cfg.PolicyDefaults; pd != nil {
+		if pd.EvaluationInterval != 0 {
+			defaults.DefaultEvaluationInterval = pd.EvaluationInterval
+		}
+		if pd.Cooldown != 0 {
+			defaults.DefaultCooldown = pd.Cooldown
+		}
+		defaults.DefaultOnCheckError = pd.OnCheckError
+		defaults.DefaultMin = pd.Min
+		defaults.DefaultMax = pd.Max
+	}
+
+	var nomadAPMs []string
+	for _, apm := range cfg.APMs {
+		if apm.Driver == plugins.InternalAPMNomad {
+			nomadAPMs = append(nomadAPMs, apm.Name)
+		}
+	}
+
+	return &policyLinter{
+		processor:  policy.NewProcessor(&defaults, nomadAPMs),
+		apms:       pluginNames(cfg.APMs),
+		strategies: pluginNames(cfg.Strategies),
+		targets:    pluginNames(cfg.Targets),
+	}
+}
+
+func pluginNames(cfgs []*config.Plugin) map[string]bool {
+	names := make(map[string]bool, len(cfgs))
+	for _, p := range cfgs {
+		names[p.Name] = true
+	}
+	return names
+}
+
+// lint lints each of the passed files.
+func (l *policyLinter) lint(files []string) *LintResult {
+	result := &LintResult{Files: []*LintFile{}}
+
+	for _, file := range files {
+		lf := l.lintFile(file)
+		for _, issue := range lf.Issues {
+			if issue.Severity == lintSeverityError {
+				result.Errors++
+			} else {
+				result.Warnings++
+			}
+		}
+		result.Files = append(result.Files, lf)
+	}
+	return result
+}
+
+// lintFile decodes and lints the policies within a single file.
+func (l *policyLinter) lintFile(file string) *LintFile {
+	lf := &LintFile{File: file, Policies: []string{}, Issues: []*LintIssue{}}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		lf.Issues = append(lf.Issues, &LintIssue{Severity: lintSeverityError, Message: err.Error()})
+		return lf
+	}
+
+	policies, err := filePolicy.Decode(file, src)
+	if err != nil {
+		lf.Issues = append(lf.Issues, lintErrors("", err)...)
+		return lf
+	}
+
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lf.Policies = names
+
+	for _, name := range names {
+		lf.Issues = append(lf.Issues, l.lintPolicy(name, policies[name])...)
+	}
+	return lf
+}
+
+// lintPolicy runs the agent processing and validation steps on a single
+// policy, then checks the plugins it references and its values.
+func (l *policyLinter) lintPolicy(name string, p *sdk.ScalingPolicy) []*LintIssue {
+	var issues []*LintIssue
+
+	addError := func(format string, a ...interface{}) {
+		issues = append(issues, &LintIssue{Policy: name, Severity: lintSeverityError, Message: fmt.Sprintf(format, a...)})
+	}
+	addWarning := func(format string, a ...interface{}) {
+		issues = append(issues, &LintIssue{Policy: name, Severity: lintSeverityWarning, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if p.Target == nil || p.Target.Name == "" {
+		addError("policy target is required")
+		return issues
+	}
+
+	// Policies decoded from files do not have an ID until the source assigns
+	// one, so use the name to satisfy the processor validation.
+	p.ID = name
+	l.processor.ApplyPolicyDefaults(p)
+	for _, c := range p.Checks {
+		l.processor.CanonicalizeCheck(c, p.Target)
+	}
+
+	issues = append(issues, lintErrors(name, l.processor.ValidatePolicy(p))...)
+	issues = append(issues, lintErrors(name, p.Validate())...)
+
+	if !l.targets[p.Target.Name] {
+		addError("target plugin %q is not configured", p.Target.Name)
+	}
+
+	for _, c := range p.Checks {
+		if c.Strategy == nil || c.Strategy.Name == "" {
+			addError("check %s: strategy is required", c.Name)
+		} else if !l.strategies[c.Strategy.Name] {
+			addError("check %s: strategy plugin %q is not configured", c.Name, c.Strategy.Name)
+		}
+		if !l.apms[c.Source] {
+			addError("check %s: apm plugin %q is not configured", c.Name, c.Source)
+		}
+	}
+
+	if p.Enabled && len(p.Checks) == 0 {
+		addWarning("policy is enabled but has no checks")
+	}
+	if p.Cooldown < p.EvaluationInterval {
+		addWarning("cooldown %s is shorter than evaluation_interval %s", p.Cooldown, p.EvaluationInterval)
+	}
+	if p.Min == p.Max {
+		addWarning("min and max are both %d so the target will never be scaled", p.Min)
+	}
+
+	return issues
+}
+
+// lintErrors flattens err into a list of error issues. HCL diagnostics are
+// expanded so each one retains its source range.
+func lintErrors(name string, err error) []*LintIssue {
+	if err == nil {
+		return nil
+	}
+
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) {
+		var out []*LintIssue
+		for _, d := range diags {
+			if d.Severity != hcl.DiagError {
+				continue
+			}
+
+			msg := d.Summary
+			if d.Detail != "" {
+				msg = fmt.Sprintf("%s: %s", d.Summary, d.Detail)
+			}
+
+			issue := &LintIssue{Policy: name, Severity: lintSeverityError, Message: msg}
+			if d.Subject != nil {
+				issue.Range = d.Subject.String()
+			}
+			out = append(out, issue)
+		}
+		return out
+	}
+
+	var mErr *multierror.Error
+	if errors.As(err, &mErr) {
+		var out []*LintIssue
+		for _, e := range mErr.Errors {
+			out = append(out, lintErrors(name, e)...)
+		}
+		return out
+	}
+
+	return []*LintIssue{{Policy: name, Severity: lintSeverityError, Message: err.Error()}}
+}
+
+// text returns the human readable form of the lint result.
+func (r *LintResult) text() string {
+	var b strings.Builder
+
+	for _, f := range r.Files {
+		for _, issue := range f.Issues {
+			location := f.File
+			if issue.Range != "" {
+				location = issue.Range
+			}
+			if issue.Policy != "" {
+				fmt.Fprintf(&b, "%s: %s: policy %s: %s\n", location, issue.Severity, issue.Policy, issue.Message)
+			} else {
+				fmt.Fprintf(&b, "%s: %s: %s\n", location, issue.Severity, issue.Message)
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "%d file(s) linted, %d error(s), %d warning(s)\n", len(r.Files), r.Errors, r.Warnings)
+	return b.String()
+}
@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package command
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad-autoscaler/agent/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyLint_lint(t *testing.T) {
+	cfg, err := config.LoadPaths(nil)
+	require.NoError(t, err)
+
+	files, err := lintFileList([]string{"./test-fixtures/policy-lint"})
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	result := newPolicyLinter(cfg).lint(files)
+	require.Len(t, result.Files, 3)
+
+	issues := map[string][]*LintIssue{}
+	for _, f := range result.Files {
+		issues[f.File] = f.Issues
+	}
+
+	// The valid policy should not report any issues.
+	assert.Empty(t, issues["test-fixtures/policy-lint/valid.hcl"])
+
+	// The invalid policy should report each of the problems.
+	var msgs []string
+	for _, i := range issues["test-fixtures/policy-lint/invalid.hcl"] {
+		assert.Equal(t, "invalid", i.Policy)
+		msgs = append(msgs, i.Severity+": "+i.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"error: policy Min must not be greater Max",
+		`error: target plugin "aws-asg" is not configured`,
+		`error: check latency: strategy plugin "unknown-strategy" is not configured`,
+		`error: check latency: apm plugin "datadog" is not configured`,
+		"warning: cooldown 10s is shorter than evaluation_interval 1m0s",
+	}, msgs)
+
+	// Syntax errors should retain the source range.
+	syntax := issues["test-fixtures/policy-lint/syntax.hcl"]
+	require.NotEmpty(t, syntax)
+	assert.Equal(t, lintSeverityError, syntax[0].Severity)
+	assert.Contains(t, syntax[0].Range, "syntax.hcl:")
+
+	assert.Equal(t, 5, result.Errors)
+	assert.Equal(t, 1, result.Warnings)
+}
+
+func TestPolicyLintCommand_Run(t *testing.T) {
+	cmd := &PolicyLintCommand{}
+	assert.Equal(t, 0, cmd.Run([]string{"-format=json", "./test-fixtures/policy-lint/valid.hcl"}))
+	assert.Equal(t, 1, cmd.Run([]string{"./test-fixtures/policy-lint/invalid.hcl"}))
+	assert.Equal(t, 1, cmd.Run([]string{"-format=yaml", "./test-fixtures/policy-lint/valid.hcl"}))
+	assert.Equal(t, 1, cmd.Run([]string{}))
+}
@@ -40,8 +40,14 @@ policy {
 }
 
 policy_eval {
-  delivery_limit = 10
-  ack_timeout    = "3m"
+  delivery_limit    = 10
+  ack_timeout       = "3m"
+  dependency_window = "1m"
+
+  guardrail {
+    paths = ["/etc/nomad-autoscaler/guardrails"]
+    query = "data.platform.guardrail"
+  }
 
   workers = {
     cluster    = 3
@@ -0,0 +1,23 @@
+scaling "invalid" {
+  enabled = true
+  min     = 10
+  max     = 5
+
+  policy {
+    cooldown            = "10s"
+    evaluation_interval = "1m"
+
+    check "latency" {
+      source = "datadog"
+      query  = "avg:latency"
+
+      strategy "unknown-strategy" {
+        target = 70
+      }
+    }
+
+    target "aws-asg" {
+      aws_asg_name = "my-asg"
+    }
+  }
+}
@@ -0,0 +1,4 @@
+scaling "syntax" {
+  enabled = true
+  min     = 
+}
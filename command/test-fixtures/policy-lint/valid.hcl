@@ -0,0 +1,24 @@
+scaling "valid" {
+  enabled = true
+  min     = 1
+  max     = 10
+
+  policy {
+    cooldown            = "2m"
+    evaluation_interval = "1m"
+
+    check "cpu" {
+      source = "nomad-apm"
+      query  = "percentage-allocated_cpu"
+
+      strategy "target-value" {
+        target = 70
+      }
+    }
+
+    target "nomad-target" {
+      Job   = "example"
+      Group = "cache"
+    }
+  }
+}
@@ -21,6 +21,9 @@ func main() {
 		"agent": func() (cli.Command, error) {
 			return &command.AgentCommand{}, nil
 		},
+		"policy lint": func() (cli.Command, error) {
+			return &command.PolicyLintCommand{}, nil
+		},
 		"version": func() (cli.Command, error) {
 			return &command.VersionCommand{Version: versionString}, nil
 		},
@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oauthTokenPath = "/controller/api/oauth/access_token"
+
+	// tokenExpiryMargin is how long before expiring access tokens are
+	// renewed, so they don't expire while a query is in flight.
+	tokenExpiryMargin = 30 * time.Second
+)
+
+// tokenSource obtains OAuth access tokens for an API client, reusing them
+// until they are about to expire.
+type tokenSource struct {
+	client  *http.Client
+	address string
+	id      string
+	secret  string
+
+	lock    sync.Mutex
+	current string
+	expires time.Time
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+func newTokenSource(client *http.Client, address, id, secret string) *tokenSource {
+	return &tokenSource{
+		client:  client,
+		address: address,
+		id:      id,
+		secret:  secret,
+		now:     time.Now,
+	}
+}
+
+// tokenResponse is the body of an access token response.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// token returns a valid access token, requesting a new one if needed.
+func (t *tokenSource) token(ctx context.Context) (string, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.current != "" && t.now().Add(tokenExpiryMargin).Before(t.expires) {
+		return t.current, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {t.id},
+		"client_secret": {t.secret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.address+oauthTokenPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %v", err)
+	}
+
+	// The Controller requires this content type for the form encoded body.
+	req.Header.Set("Content-Type", "application/vnd.appd.cntrl+protobuf;v=1")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return "", fmt.Errorf("failed to request access token: unexpected response code %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", fmt.Errorf("failed to decode access token response: %v", err)
+	}
+	if tr.AccessToken == "" {
+		return "", errors.New("access token response is missing the token")
+	}
+
+	t.current = tr.AccessToken
+	t.expires = t.now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	return t.current, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_Query_apiClient(t *testing.T) {
+	var tokenRequests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case oauthTokenPath:
+			tokenRequests++
+			// The Controller expects a form body despite the content type.
+			b, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			form, err := url.ParseQuery(string(b))
+			assert.NoError(t, err)
+			assert.Equal(t, "client_credentials", form.Get("grant_type"))
+			assert.Equal(t, "autoscaler@customer1", form.Get("client_id"))
+			assert.Equal(t, "secret", form.Get("client_secret"))
+			_, _ = fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 300}`, tokenRequests)
+		default:
+			assert.Equal(t, fmt.Sprintf("Bearer token-%d", tokenRequests), r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`[{"metricValues": [{"startTimeInMillis": 1704067260000, "value": 1}]}]`))
+		}
+	}))
+	defer srv.Close()
+
+	plugin := NewAppDynamicsPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address":       srv.URL,
+		"account":       "customer1",
+		"client_name":   "autoscaler",
+		"client_secret": "secret",
+	}))
+
+	now := time.Now()
+	plugin.(*APMPlugin).tokens.now = func() time.Time { return now }
+
+	q := "shop:Overall Application Performance|Calls per Minute"
+	r := sdk.TimeRange{From: now.Add(-time.Minute), To: now}
+
+	// The access token is reused while it is valid.
+	for i := 0; i < 2; i++ {
+		_, err := plugin.Query(q, r)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, tokenRequests)
+
+	// Tokens are renewed before they expire.
+	now = now.Add(280 * time.Second)
+	_, err := plugin.Query(q, r)
+	require.NoError(t, err)
+	assert.Equal(t, 2, tokenRequests)
+}
+
+func TestAPMPlugin_Query_apiClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("Invalid client secret\n"))
+	}))
+	defer srv.Close()
+
+	plugin := NewAppDynamicsPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address":       srv.URL,
+		"account":       "customer1",
+		"client_name":   "autoscaler",
+		"client_secret": "invalid",
+	}))
+
+	_, err := plugin.Query("shop:Overall Application Performance|Calls per Minute", sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()})
+	assert.EqualError(t, err, "failed to authenticate: failed to request access token: unexpected response code 401: Invalid client secret")
+}
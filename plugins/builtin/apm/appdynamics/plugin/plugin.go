@@ -0,0 +1,301 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "appdynamics"
+
+	// configKeyAddress is the address of the AppDynamics Controller, such as
+	// "https://example.saas.appdynamics.com".
+	configKeyAddress = "address"
+
+	// configKeyAccount is the Controller account name.
+	configKeyAccount = "account"
+
+	// configKeyUsername and configKeyPassword configure basic auth using a
+	// Controller user.
+	configKeyUsername = "username"
+	configKeyPassword = "password"
+
+	// configKeyClientName and configKeyClientSecret configure an API client,
+	// which is used to obtain OAuth access tokens.
+	configKeyClientName   = "client_name"
+	configKeyClientSecret = "client_secret"
+
+	// configKeyValue is the field of the metric values to return.
+	configKeyValue          = "value"
+	configValueValueDefault = "value"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewAppDynamicsPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the AppDynamics implementation of the apm.APM interface, which
+// queries metric paths using the Controller REST API.
+type APMPlugin struct {
+	client  *http.Client
+	logger  hclog.Logger
+	address string
+	value   string
+	timeout time.Duration
+
+	// username and password are set when using basic auth, otherwise tokens
+	// is used to obtain access tokens for the configured API client.
+	username string
+	password string
+	tokens   *tokenSource
+}
+
+func NewAppDynamicsPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	addr := config[configKeyAddress]
+	if addr == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("%q must be an absolute URL", configKeyAddress)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	account := config[configKeyAccount]
+	if account == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAccount)
+	}
+
+	a.value = configValueValueDefault
+	if v := config[configKeyValue]; v != "" {
+		if _, ok := valueFields[v]; !ok {
+			return fmt.Errorf("invalid %q %q, allowed values are value, current, min, max, sum or count", configKeyValue, v)
+		}
+		a.value = v
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	// Controller users and API clients are identified as <name>@<account>.
+	user, client := config[configKeyUsername], config[configKeyClientName]
+	switch {
+	case user != "" && client != "":
+		return fmt.Errorf("only one of %q and %q can be set", configKeyUsername, configKeyClientName)
+	case user != "":
+		a.username = user + "@" + account
+		a.password = config[configKeyPassword]
+		a.tokens = nil
+	case client != "":
+		secret := config[configKeyClientSecret]
+		if secret == "" {
+			return fmt.Errorf("%q config value cannot be empty", configKeyClientSecret)
+		}
+		a.username, a.password = "", ""
+		a.tokens = newTokenSource(a.client, a.address, client+"@"+account, secret)
+	default:
+		return fmt.Errorf("one of %q or %q must be set", configKeyUsername, configKeyClientName)
+	}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple queries the metric path, returning a metric stream for each
+// metric matched by the path. Queries are in the format
+// <application>:<metric path>, such as
+// "shop:Business Transaction Performance|Business Transactions|web|/checkout|Calls per Minute".
+// Metric paths may use wildcards to match multiple tiers or nodes.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	app, path, ok := strings.Cut(q, ":")
+	if !ok || app == "" || path == "" {
+		return nil, errors.New("failed to parse query: expected query in the format <application>:<metric path>")
+	}
+
+	a.logger.Debug("querying AppDynamics", "application", app, "path", path, "range", r)
+
+	params := url.Values{
+		"metric-path":     {path},
+		"time-range-type": {"BETWEEN_TIMES"},
+		"start-time":      {strconv.FormatInt(r.From.UnixMilli(), 10)},
+		"end-time":        {strconv.FormatInt(r.To.UnixMilli(), 10)},
+		"rollup":          {"false"},
+		"output":          {"JSON"},
+	}
+	reqURL := fmt.Sprintf("%s/controller/rest/applications/%s/metric-data?%s",
+		a.address, url.PathEscape(app), params.Encode())
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	if err := a.authenticate(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %v", err)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from appdynamics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("error querying metrics from appdynamics: unexpected response code %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	var data []metricData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode appdynamics response: %v", err)
+	}
+
+	var results []sdk.TimestampedMetrics
+	for _, d := range data {
+		var result sdk.TimestampedMetrics
+		for _, v := range d.MetricValues {
+			result = append(result, sdk.TimestampedMetric{
+				Timestamp: time.UnixMilli(v.StartTimeInMillis),
+				Value:     valueFields[a.value](v),
+			})
+		}
+
+		if len(result) > 0 {
+			sort.Stable(result)
+			results = append(results, result)
+		}
+	}
+
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from appdynamics, try a wider query window")
+	}
+	return results, nil
+}
+
+// authenticate sets the credentials of the request.
+func (a *APMPlugin) authenticate(ctx context.Context, req *http.Request) error {
+	if a.tokens == nil {
+		req.SetBasicAuth(a.username, a.password)
+		return nil
+	}
+
+	token, err := a.tokens.token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// metricData is a metric returned by the metric-data API.
+type metricData struct {
+	MetricID     int64         `json:"metricId"`
+	MetricName   string        `json:"metricName"`
+	MetricPath   string        `json:"metricPath"`
+	Frequency    string        `json:"frequency"`
+	MetricValues []metricValue `json:"metricValues"`
+}
+
+// metricValue is the value of a metric over an interval starting at
+// StartTimeInMillis.
+type metricValue struct {
+	StartTimeInMillis int64   `json:"startTimeInMillis"`
+	Value             float64 `json:"value"`
+	Current           float64 `json:"current"`
+	Min               float64 `json:"min"`
+	Max               float64 `json:"max"`
+	Sum               float64 `json:"sum"`
+	Count             float64 `json:"count"`
+}
+
+// valueFields are the metric value fields which can be returned.
+var valueFields = map[string]func(metricValue) float64{
+	"value":   func(v metricValue) float64 { return v.Value },
+	"current": func(v metricValue) float64 { return v.Current },
+	"min":     func(v metricValue) float64 { return v.Min },
+	"max":     func(v metricValue) float64 { return v.Max },
+	"sum":     func(v metricValue) float64 { return v.Sum },
+	"count":   func(v metricValue) float64 { return v.Count },
+}
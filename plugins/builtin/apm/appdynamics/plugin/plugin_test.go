@@ -0,0 +1,233 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing address",
+			config:        map[string]string{"account": "customer1", "username": "user"},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "relative address",
+			config:        map[string]string{"address": "example.saas.appdynamics.com", "account": "customer1", "username": "user"},
+			expectedError: `"address" must be an absolute URL`,
+		},
+		{
+			name:          "missing account",
+			config:        map[string]string{"address": "https://example.saas.appdynamics.com", "username": "user"},
+			expectedError: `"account" config value cannot be empty`,
+		},
+		{
+			name:          "missing credentials",
+			config:        map[string]string{"address": "https://example.saas.appdynamics.com", "account": "customer1"},
+			expectedError: `one of "username" or "client_name" must be set`,
+		},
+		{
+			name: "user and client",
+			config: map[string]string{
+				"address":     "https://example.saas.appdynamics.com",
+				"account":     "customer1",
+				"username":    "user",
+				"client_name": "autoscaler",
+			},
+			expectedError: `only one of "username" and "client_name" can be set`,
+		},
+		{
+			name: "missing client secret",
+			config: map[string]string{
+				"address":     "https://example.saas.appdynamics.com",
+				"account":     "customer1",
+				"client_name": "autoscaler",
+			},
+			expectedError: `"client_secret" config value cannot be empty`,
+		},
+		{
+			name: "invalid value",
+			config: map[string]string{
+				"address":  "https://example.saas.appdynamics.com",
+				"account":  "customer1",
+				"username": "user",
+				"value":    "avg",
+			},
+			expectedError: `invalid "value" "avg", allowed values are value, current, min, max, sum or count`,
+		},
+		{
+			name: "invalid skip_verify",
+			config: map[string]string{
+				"address":     "https://example.saas.appdynamics.com",
+				"account":     "customer1",
+				"username":    "user",
+				"skip_verify": "maybe",
+			},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name: "valid basic auth",
+			config: map[string]string{
+				"address":  "https://example.saas.appdynamics.com/",
+				"account":  "customer1",
+				"username": "user",
+				"password": "pass",
+				"value":    "max",
+				"timeout":  "30s",
+			},
+		},
+		{
+			name: "valid api client",
+			config: map[string]string{
+				"address":       "https://example.saas.appdynamics.com",
+				"account":       "customer1",
+				"client_name":   "autoscaler",
+				"client_secret": "secret",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+			assert.Equal(t, "https://example.saas.appdynamics.com", apmPlugin.address)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected sdk.TimestampedMetrics
+	}{
+		{
+			name: "default value",
+			// Metrics should be sorted by timestamp.
+			expected: sdk.TimestampedMetrics{
+				{Timestamp: time.UnixMilli(1704067260000), Value: 118},
+				{Timestamp: time.UnixMilli(1704067320000), Value: 140},
+			},
+		},
+		{
+			name:  "max value",
+			value: "max",
+			expected: sdk.TimestampedMetrics{
+				{Timestamp: time.UnixMilli(1704067260000), Value: 131},
+				{Timestamp: time.UnixMilli(1704067320000), Value: 160},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/controller/rest/applications/online shop/metric-data", r.URL.Path)
+
+				qp := r.URL.Query()
+				assert.Equal(t, "Business Transaction Performance|Business Transactions|web|/checkout|Calls per Minute", qp.Get("metric-path"))
+				assert.Equal(t, "BETWEEN_TIMES", qp.Get("time-range-type"))
+				assert.Equal(t, "1704067200000", qp.Get("start-time"))
+				assert.Equal(t, "1704067500000", qp.Get("end-time"))
+				assert.Equal(t, "false", qp.Get("rollup"))
+				assert.Equal(t, "JSON", qp.Get("output"))
+
+				user, pass, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, "user@customer1", user)
+				assert.Equal(t, "pass", pass)
+
+				http.ServeFile(w, r, path.Join("./test-fixtures", "metric_data_200.json"))
+			}))
+			defer srv.Close()
+
+			plugin := NewAppDynamicsPlugin(hclog.NewNullLogger())
+			require.NoError(t, plugin.SetConfig(map[string]string{
+				"address":  srv.URL,
+				"account":  "customer1",
+				"username": "user",
+				"password": "pass",
+				"value":    tc.value,
+			}))
+
+			metrics, err := plugin.Query(
+				"online shop:Business Transaction Performance|Business Transactions|web|/checkout|Calls per Minute",
+				sdk.TimeRange{From: time.Unix(1704067200, 0), To: time.Unix(1704067500, 0)},
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, metrics)
+		})
+	}
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+  {"metricPath": "Application Infrastructure Performance|web|Individual Nodes|web-1|Hardware Resources|CPU|%Busy",
+   "metricValues": [{"startTimeInMillis": 1704067260000, "value": 40}]},
+  {"metricName": "METRIC DATA NOT FOUND", "metricValues": []},
+  {"metricPath": "Application Infrastructure Performance|web|Individual Nodes|web-2|Hardware Resources|CPU|%Busy",
+   "metricValues": [{"startTimeInMillis": 1704067260000, "value": 60}]}
+]`))
+	}))
+	defer srv.Close()
+
+	plugin := NewAppDynamicsPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "account": "customer1", "username": "user"}))
+
+	q := "shop:Application Infrastructure Performance|web|Individual Nodes|*|Hardware Resources|CPU|%Busy"
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	// Metrics without any values should be skipped.
+	metrics, err := plugin.QueryMultiple(q, r)
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{{Timestamp: time.UnixMilli(1704067260000), Value: 40}},
+		{{Timestamp: time.UnixMilli(1704067260000), Value: 60}},
+	}, metrics)
+
+	_, err = plugin.Query(q, r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Invalid application id unknown is specified\n"))
+	}))
+	defer srv.Close()
+
+	plugin := NewAppDynamicsPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "account": "customer1", "username": "user"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	_, err := plugin.Query("unknown:Overall Application Performance|Calls per Minute", r)
+	assert.EqualError(t, err, "error querying metrics from appdynamics: unexpected response code 400: Invalid application id unknown is specified")
+
+	_, err = plugin.Query("Overall Application Performance|Calls per Minute", r)
+	assert.EqualError(t, err, "failed to parse query: expected query in the format <application>:<metric path>")
+}
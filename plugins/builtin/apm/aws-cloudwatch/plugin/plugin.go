@@ -0,0 +1,295 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "aws-cloudwatch"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle.
+	configKeyRegion          = "aws_region"
+	configKeyAccessID        = "aws_access_key_id"
+	configKeySecretKey       = "aws_secret_access_key"
+	configKeySessionToken    = "aws_session_token"
+	configKeyRoleARN         = "aws_role_arn"
+	configKeyRoleExternalID  = "aws_role_external_id"
+	configKeyRoleSessionName = "aws_role_session_name"
+	configKeyPeriod          = "period"
+	configKeyStatistic       = "statistic"
+	configKeyTimeout         = "timeout"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueRegionDefault          = "us-east-1"
+	configValueRoleSessionNameDefault = "nomad-autoscaler"
+	configValuePeriodDefault          = time.Minute
+	configValueStatisticDefault       = "Average"
+	configValueTimeoutDefault         = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewCloudWatchPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// cloudWatchAPI is the subset of the CloudWatch client used by the plugin.
+type cloudWatchAPI interface {
+	GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error)
+}
+
+// Assert that APMPlugin meets the apm.APM interface.
+var _ apm.APM = (*APMPlugin)(nil)
+
+// APMPlugin is the Amazon CloudWatch implementation of the apm.APM
+// interface.
+type APMPlugin struct {
+	client cloudWatchAPI
+	config map[string]string
+	logger hclog.Logger
+
+	// period and statistic are used for metric queries which do not specify
+	// their own.
+	period    time.Duration
+	statistic string
+	timeout   time.Duration
+}
+
+// NewCloudWatchPlugin returns the Amazon CloudWatch implementation of the
+// apm.APM interface.
+func NewCloudWatchPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	a.period = configValuePeriodDefault
+	if v := config[configKeyPeriod]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyPeriod, err)
+		}
+		if err := validatePeriod(d); err != nil {
+			return fmt.Errorf("invalid %q: %v", configKeyPeriod, err)
+		}
+		a.period = d
+	}
+
+	a.statistic = configValueStatisticDefault
+	if v := config[configKeyStatistic]; v != "" {
+		a.statistic = v
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	// Allow tests to replace the client.
+	if a.client != nil {
+		return nil
+	}
+
+	client, err := newClient(a.logger, config)
+	if err != nil {
+		return err
+	}
+	a.client = client
+	return nil
+}
+
+// newClient builds the CloudWatch client from the default AWS config,
+// overridden by the values from the passed config mapping.
+func newClient(log hclog.Logger, config map[string]string) (*cloudwatch.Client, error) {
+
+	// Load our default AWS config. This handles pulling configuration from
+	// default profiles and environment variables.
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default AWS config: %v", err)
+	}
+
+	if region, ok := config[configKeyRegion]; ok {
+		log.Debug("setting AWS region for client", "region", region)
+		cfg.Region = region
+	}
+	if cfg.Region == "" {
+		cfg.Region = configValueRegionDefault
+	}
+
+	// Static credentials require both the access key and secret key; the
+	// session token is optional.
+	keyID := config[configKeyAccessID]
+	secretKey := config[configKeySecretKey]
+	if keyID != "" && secretKey != "" {
+		log.Trace("setting AWS access credentials from config map")
+		cfg.Credentials = credentials.NewStaticCredentialsProvider(keyID, secretKey, config[configKeySessionToken])
+	}
+
+	// Assuming a role allows metrics to be read from another account, using
+	// the credentials configured above to call STS.
+	if roleARN := config[configKeyRoleARN]; roleARN != "" {
+		log.Trace("assuming AWS role for client", "role_arn", roleARN)
+
+		sessionName := config[configKeyRoleSessionName]
+		if sessionName == "" {
+			sessionName = configValueRoleSessionNameDefault
+		}
+
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if externalID := config[configKeyRoleExternalID]; externalID != "" {
+				o.ExternalID = aws.String(externalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return cloudwatch.NewFromConfig(cfg), nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Query satisfies the Query function on the apm.APM interface.
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple satisfies the QueryMultiple function on the apm.APM
+// interface. Each query which returns data produces a metric stream, in the
+// order the queries were defined.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	queries, err := parseQuery(q, a.period, a.statistic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	input := &cloudwatch.GetMetricDataInput{
+		StartTime:         aws.Time(r.From),
+		EndTime:           aws.Time(r.To),
+		MetricDataQueries: queries,
+		ScanBy:            types.ScanByTimestampAscending,
+	}
+
+	// Results for a single query can be split across pages, so they are
+	// collected by ID before being converted.
+	results := make(map[string]sdk.TimestampedMetrics)
+	for {
+		out, err := a.client.GetMetricData(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("error querying metrics from cloudwatch: %v", err)
+		}
+
+		for _, res := range out.MetricDataResults {
+			id := aws.ToString(res.Id)
+			if res.StatusCode == types.StatusCodeForbidden || res.StatusCode == types.StatusCodeInternalError {
+				return nil, fmt.Errorf("cloudwatch query %q returned status %s", id, res.StatusCode)
+			}
+			if len(res.Timestamps) != len(res.Values) {
+				return nil, fmt.Errorf("cloudwatch query %q returned %d timestamps and %d values",
+					id, len(res.Timestamps), len(res.Values))
+			}
+			for i := range res.Values {
+				results[id] = append(results[id], sdk.TimestampedMetric{
+					Timestamp: res.Timestamps[i],
+					Value:     res.Values[i],
+				})
+			}
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	var metrics []sdk.TimestampedMetrics
+	for _, query := range queries {
+		if query.ReturnData != nil && !*query.ReturnData {
+			continue
+		}
+		if m, ok := results[aws.ToString(query.Id)]; ok {
+			metrics = append(metrics, m)
+		}
+	}
+
+	if len(metrics) == 0 {
+		a.logger.Warn("empty time series response from cloudwatch, try a wider query window")
+	}
+	return metrics, nil
+}
+
+// validatePeriod checks d is a valid CloudWatch period. Periods must be a
+// multiple of 60 seconds, or 1, 5, 10 or 30 seconds for high resolution
+// metrics.
+func validatePeriod(d time.Duration) error {
+	if d%time.Second != 0 {
+		return fmt.Errorf("%s must be a whole number of seconds", d)
+	}
+
+	switch s := int64(d.Seconds()); {
+	case s <= 0:
+		return fmt.Errorf("%s must be greater than zero", d)
+	case s == 1, s == 5, s == 10, s == 30, s%60 == 0:
+		return nil
+	default:
+		return fmt.Errorf("%s must be 1s, 5s, 10s, 30s or a multiple of 60s", d)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCloudWatch returns each of its pages in turn, recording the inputs it
+// was called with.
+type mockCloudWatch struct {
+	pages  []*cloudwatch.GetMetricDataOutput
+	err    error
+	inputs []cloudwatch.GetMetricDataInput
+}
+
+func (m *mockCloudWatch) GetMetricData(_ context.Context, params *cloudwatch.GetMetricDataInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	m.inputs = append(m.inputs, *params)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.pages[len(m.inputs)-1], nil
+}
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:   "defaults",
+			config: map[string]string{},
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"period": "5m", "statistic": "Sum", "timeout": "30s"},
+		},
+		{
+			name:          "invalid period",
+			config:        map[string]string{"period": "90s"},
+			expectedError: `invalid "period"`,
+		},
+		{
+			name:          "unparsable timeout",
+			config:        map[string]string{"timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), client: &mockCloudWatch{}}
+
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	// The client is created from the plugin config.
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{
+		"aws_region":           "eu-west-1",
+		"aws_role_arn":         "arn:aws:iam::123456789012:role/metrics",
+		"aws_role_external_id": "external",
+	}))
+	assert.IsType(t, &cloudwatch.Client{}, apmPlugin.client)
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	r := sdk.TimeRange{From: now.Add(-3 * time.Minute), To: now}
+
+	client := &mockCloudWatch{
+		pages: []*cloudwatch.GetMetricDataOutput{
+			{
+				MetricDataResults: []types.MetricDataResult{
+					{
+						Id:         aws.String("per_instance"),
+						StatusCode: types.StatusCodePartialData,
+						Timestamps: []time.Time{now.Add(-2 * time.Minute)},
+						Values:     []float64{10},
+					},
+					{
+						Id:         aws.String("latency"),
+						StatusCode: types.StatusCodeComplete,
+						Timestamps: []time.Time{now.Add(-2 * time.Minute), now.Add(-time.Minute)},
+						Values:     []float64{0.2, 0.3},
+					},
+				},
+				NextToken: aws.String("next"),
+			},
+			{
+				MetricDataResults: []types.MetricDataResult{
+					{
+						Id:         aws.String("per_instance"),
+						StatusCode: types.StatusCodeComplete,
+						Timestamps: []time.Time{now.Add(-time.Minute)},
+						Values:     []float64{20},
+					},
+				},
+			},
+		},
+	}
+
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), client: client}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{}))
+
+	metrics, err := apmPlugin.QueryMultiple(`[
+  {"Id": "requests", "ReturnData": false, "MetricStat": {"Metric": {"Namespace": "AWS/ApplicationELB", "MetricName": "RequestCount"}, "Stat": "Sum"}},
+  {"Id": "per_instance", "Expression": "requests / 4"},
+  {"Id": "latency", "MetricStat": {"Metric": {"Namespace": "AWS/ApplicationELB", "MetricName": "TargetResponseTime"}}}
+]`, r)
+	require.NoError(t, err)
+
+	// Pages should be merged and returned in query order.
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{
+			{Timestamp: now.Add(-2 * time.Minute), Value: 10},
+			{Timestamp: now.Add(-time.Minute), Value: 20},
+		},
+		{
+			{Timestamp: now.Add(-2 * time.Minute), Value: 0.2},
+			{Timestamp: now.Add(-time.Minute), Value: 0.3},
+		},
+	}, metrics)
+
+	require.Len(t, client.inputs, 2)
+	assert.Equal(t, r.From, *client.inputs[0].StartTime)
+	assert.Equal(t, r.To, *client.inputs[0].EndTime)
+	assert.Equal(t, types.ScanByTimestampAscending, client.inputs[0].ScanBy)
+	assert.Nil(t, client.inputs[0].NextToken)
+	assert.Equal(t, "next", *client.inputs[1].NextToken)
+
+	// A single stream is required by Query.
+	client.inputs = nil
+	client.pages = []*cloudwatch.GetMetricDataOutput{{
+		MetricDataResults: []types.MetricDataResult{
+			{Id: aws.String("a"), Timestamps: []time.Time{now}, Values: []float64{1}},
+			{Id: aws.String("b"), Timestamps: []time.Time{now}, Values: []float64{2}},
+		},
+	}}
+	_, err = apmPlugin.Query(`[{"Id": "a", "Expression": "1"}, {"Id": "b", "Expression": "2"}]`, r)
+	assert.ErrorContains(t, err, "query returned 2 metric streams")
+}
+
+func TestAPMPlugin_Query_errors(t *testing.T) {
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), client: &mockCloudWatch{err: errors.New("access denied")}}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{}))
+
+	_, err := apmPlugin.Query("AWS/SQS:ApproximateNumberOfMessagesVisible{QueueName=jobs}", r)
+	assert.EqualError(t, err, "error querying metrics from cloudwatch: access denied")
+
+	_, err = apmPlugin.Query("AWS/SQS:ApproximateNumberOfMessagesVisible{QueueName}", r)
+	assert.ErrorContains(t, err, "failed to parse query")
+
+	apmPlugin.client = &mockCloudWatch{pages: []*cloudwatch.GetMetricDataOutput{{
+		MetricDataResults: []types.MetricDataResult{{Id: aws.String("q0"), StatusCode: types.StatusCodeForbidden}},
+	}}}
+	_, err = apmPlugin.Query("AWS/SQS:ApproximateNumberOfMessagesVisible{QueueName=jobs}", r)
+	assert.EqualError(t, err, `cloudwatch query "q0" returned status Forbidden`)
+
+	// No data is not an error.
+	apmPlugin.client = &mockCloudWatch{pages: []*cloudwatch.GetMetricDataOutput{{}}}
+	m, err := apmPlugin.Query("AWS/SQS:ApproximateNumberOfMessagesVisible{QueueName=jobs}", r)
+	require.NoError(t, err)
+	assert.Empty(t, m)
+}
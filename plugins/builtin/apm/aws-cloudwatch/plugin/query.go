@@ -0,0 +1,135 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// metricQueryRe matches the short metric query form:
+//
+//	<namespace>:<metric_name>[{<dimension>=<value>,...}][:<statistic>[:<period>]]
+var metricQueryRe = regexp.MustCompile(`^([^:(){}\s]+):([^:(){}\s]+)(\{[^{}]*\})?((?::[^:(){}\s]+){0,2})$`)
+
+// parseQuery converts the check query into the CloudWatch metric data
+// queries. Three forms are supported:
+//
+//   - A single metric, such as
+//     "AWS/ApplicationELB:RequestCount{LoadBalancer=app/web/1234}:Sum:1m".
+//     The statistic and period are optional.
+//
+//   - A JSON array of MetricDataQuery objects, as accepted by the
+//     GetMetricData API, which allows metric math expressions to combine
+//     multiple metrics. Queries with ReturnData set to false are only used
+//     as inputs to other queries.
+//
+//   - Any other value is used as a single expression, such as a SEARCH
+//     expression or a Metrics Insights query.
+//
+// The period and statistic are used for queries which do not set their own.
+func parseQuery(q string, period time.Duration, statistic string) ([]types.MetricDataQuery, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, errors.New("query is empty")
+	}
+
+	var queries []types.MetricDataQuery
+
+	switch {
+	case strings.HasPrefix(q, "["):
+		if err := json.Unmarshal([]byte(q), &queries); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON query: %v", err)
+		}
+		if len(queries) == 0 {
+			return nil, errors.New("JSON query must contain at least one query")
+		}
+	case metricQueryRe.MatchString(q):
+		query, err := parseMetricQuery(q)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	default:
+		queries = append(queries, types.MetricDataQuery{Expression: aws.String(q)})
+	}
+
+	seconds := int32(period.Seconds())
+
+	for i := range queries {
+		query := &queries[i]
+
+		if query.Id == nil {
+			query.Id = aws.String(fmt.Sprintf("q%d", i))
+		}
+		if (query.Expression == nil) == (query.MetricStat == nil) {
+			return nil, fmt.Errorf("query %q must set exactly one of Expression or MetricStat", *query.Id)
+		}
+
+		if query.MetricStat != nil {
+			if query.MetricStat.Period == nil {
+				query.MetricStat.Period = aws.Int32(seconds)
+			}
+			if query.MetricStat.Stat == nil {
+				query.MetricStat.Stat = aws.String(statistic)
+			}
+		} else if query.Period == nil {
+			query.Period = aws.Int32(seconds)
+		}
+	}
+
+	return queries, nil
+}
+
+// parseMetricQuery parses the short metric query form matched by
+// metricQueryRe.
+func parseMetricQuery(q string) (types.MetricDataQuery, error) {
+	m := metricQueryRe.FindStringSubmatch(q)
+
+	stat := &types.MetricStat{
+		Metric: &types.Metric{
+			Namespace:  aws.String(m[1]),
+			MetricName: aws.String(m[2]),
+		},
+	}
+
+	if dims := strings.Trim(m[3], "{}"); dims != "" {
+		for _, d := range strings.Split(dims, ",") {
+			name, value, ok := strings.Cut(d, "=")
+			name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+			if !ok || name == "" || value == "" {
+				return types.MetricDataQuery{}, fmt.Errorf("invalid dimension %q, must be in the form name=value", d)
+			}
+			stat.Metric.Dimensions = append(stat.Metric.Dimensions, types.Dimension{
+				Name:  aws.String(name),
+				Value: aws.String(value),
+			})
+		}
+	}
+
+	if opts := strings.TrimPrefix(m[4], ":"); opts != "" {
+		parts := strings.Split(opts, ":")
+		stat.Stat = aws.String(parts[0])
+
+		if len(parts) > 1 {
+			d, err := time.ParseDuration(parts[1])
+			if err != nil {
+				return types.MetricDataQuery{}, fmt.Errorf("failed to parse period: %v", err)
+			}
+			if err := validatePeriod(d); err != nil {
+				return types.MetricDataQuery{}, fmt.Errorf("invalid period: %v", err)
+			}
+			stat.Period = aws.Int32(int32(d.Seconds()))
+		}
+	}
+
+	return types.MetricDataQuery{MetricStat: stat}, nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseQuery(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		expected      []types.MetricDataQuery
+		expectedError string
+	}{
+		{
+			name:  "metric",
+			query: "AWS/SQS:ApproximateNumberOfMessagesVisible{QueueName=jobs}",
+			expected: []types.MetricDataQuery{{
+				Id: aws.String("q0"),
+				MetricStat: &types.MetricStat{
+					Metric: &types.Metric{
+						Namespace:  aws.String("AWS/SQS"),
+						MetricName: aws.String("ApproximateNumberOfMessagesVisible"),
+						Dimensions: []types.Dimension{{Name: aws.String("QueueName"), Value: aws.String("jobs")}},
+					},
+					Period: aws.Int32(60),
+					Stat:   aws.String("Average"),
+				},
+			}},
+		},
+		{
+			name:  "metric with statistic and period",
+			query: "AWS/ApplicationELB:RequestCount{LoadBalancer=app/web/1234, TargetGroup=targetgroup/web/5678}:Sum:5m",
+			expected: []types.MetricDataQuery{{
+				Id: aws.String("q0"),
+				MetricStat: &types.MetricStat{
+					Metric: &types.Metric{
+						Namespace:  aws.String("AWS/ApplicationELB"),
+						MetricName: aws.String("RequestCount"),
+						Dimensions: []types.Dimension{
+							{Name: aws.String("LoadBalancer"), Value: aws.String("app/web/1234")},
+							{Name: aws.String("TargetGroup"), Value: aws.String("targetgroup/web/5678")},
+						},
+					},
+					Period: aws.Int32(300),
+					Stat:   aws.String("Sum"),
+				},
+			}},
+		},
+		{
+			name:  "metric without dimensions",
+			query: "Custom:QueueDepth:p90",
+			expected: []types.MetricDataQuery{{
+				Id: aws.String("q0"),
+				MetricStat: &types.MetricStat{
+					Metric: &types.Metric{
+						Namespace:  aws.String("Custom"),
+						MetricName: aws.String("QueueDepth"),
+					},
+					Period: aws.Int32(60),
+					Stat:   aws.String("p90"),
+				},
+			}},
+		},
+		{
+			name:  "expression",
+			query: `SEARCH('{AWS/SQS,QueueName} MetricName="ApproximateNumberOfMessagesVisible"', 'Sum', 60)`,
+			expected: []types.MetricDataQuery{{
+				Id:         aws.String("q0"),
+				Expression: aws.String(`SEARCH('{AWS/SQS,QueueName} MetricName="ApproximateNumberOfMessagesVisible"', 'Sum', 60)`),
+				Period:     aws.Int32(60),
+			}},
+		},
+		{
+			name: "json metric math",
+			query: `[
+  {"Id": "requests", "ReturnData": false, "MetricStat": {"Metric": {"Namespace": "AWS/ApplicationELB", "MetricName": "RequestCount"}, "Stat": "Sum"}},
+  {"Id": "per_instance", "Expression": "requests / 4"}
+]`,
+			expected: []types.MetricDataQuery{
+				{
+					Id:         aws.String("requests"),
+					ReturnData: aws.Bool(false),
+					MetricStat: &types.MetricStat{
+						Metric: &types.Metric{
+							Namespace:  aws.String("AWS/ApplicationELB"),
+							MetricName: aws.String("RequestCount"),
+						},
+						Period: aws.Int32(60),
+						Stat:   aws.String("Sum"),
+					},
+				},
+				{
+					Id:         aws.String("per_instance"),
+					Expression: aws.String("requests / 4"),
+					Period:     aws.Int32(60),
+				},
+			},
+		},
+		{
+			name:          "empty",
+			query:         " ",
+			expectedError: "query is empty",
+		},
+		{
+			name:          "invalid json",
+			query:         `[{"Id": }]`,
+			expectedError: "failed to decode JSON query",
+		},
+		{
+			name:          "json without metric or expression",
+			query:         `[{"Id": "m1"}]`,
+			expectedError: `query "m1" must set exactly one of Expression or MetricStat`,
+		},
+		{
+			name:          "invalid dimension",
+			query:         "AWS/SQS:ApproximateNumberOfMessagesVisible{QueueName}",
+			expectedError: `invalid dimension "QueueName"`,
+		},
+		{
+			name:          "invalid period",
+			query:         "AWS/SQS:ApproximateNumberOfMessagesVisible:Sum:90s",
+			expectedError: "invalid period: 1m30s must be 1s, 5s, 10s, 30s or a multiple of 60s",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			queries, err := parseQuery(tc.query, time.Minute, "Average")
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, queries)
+		})
+	}
+}
+
+func Test_validatePeriod(t *testing.T) {
+	for _, d := range []time.Duration{time.Second, 5 * time.Second, 30 * time.Second, time.Minute, time.Hour} {
+		assert.NoError(t, validatePeriod(d), d.String())
+	}
+	for _, d := range []time.Duration{0, -time.Minute, 1500 * time.Millisecond, 45 * time.Second} {
+		assert.Error(t, validatePeriod(d), d.String())
+	}
+}
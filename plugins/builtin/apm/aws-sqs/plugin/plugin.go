@@ -0,0 +1,276 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "aws-sqs"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle.
+	configKeyRegion          = "aws_region"
+	configKeyAccessID        = "aws_access_key_id"
+	configKeySecretKey       = "aws_secret_access_key"
+	configKeySessionToken    = "aws_session_token"
+	configKeyRoleARN         = "aws_role_arn"
+	configKeyRoleExternalID  = "aws_role_external_id"
+	configKeyRoleSessionName = "aws_role_session_name"
+	configKeyIncludeInFlight = "include_in_flight"
+	configKeyIncludeDelayed  = "include_delayed"
+	configKeyTimeout         = "timeout"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueRegionDefault          = "us-east-1"
+	configValueRoleSessionNameDefault = "nomad-autoscaler"
+	configValueIncludeInFlightDefault = true
+	configValueIncludeDelayedDefault  = false
+	configValueTimeoutDefault         = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewSQSPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// sqsAPI is the subset of the SQS client used by the plugin.
+type sqsAPI interface {
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+}
+
+// Assert that APMPlugin meets the apm.APM interface.
+var _ apm.APM = (*APMPlugin)(nil)
+
+// APMPlugin is the Amazon SQS implementation of the apm.APM interface, which
+// reads the approximate queue depth directly from SQS.
+type APMPlugin struct {
+	client sqsAPI
+	config map[string]string
+	logger hclog.Logger
+
+	// attributes are the queue attributes summed to produce the queue depth.
+	attributes []types.QueueAttributeName
+	timeout    time.Duration
+}
+
+// NewSQSPlugin returns the Amazon SQS implementation of the apm.APM
+// interface.
+func NewSQSPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	inFlight, err := parseBool(config, configKeyIncludeInFlight, configValueIncludeInFlightDefault)
+	if err != nil {
+		return err
+	}
+	delayed, err := parseBool(config, configKeyIncludeDelayed, configValueIncludeDelayedDefault)
+	if err != nil {
+		return err
+	}
+
+	a.attributes = []types.QueueAttributeName{types.QueueAttributeNameApproximateNumberOfMessages}
+	if inFlight {
+		a.attributes = append(a.attributes, types.QueueAttributeNameApproximateNumberOfMessagesNotVisible)
+	}
+	if delayed {
+		a.attributes = append(a.attributes, types.QueueAttributeNameApproximateNumberOfMessagesDelayed)
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	// Allow tests to replace the client.
+	if a.client != nil {
+		return nil
+	}
+
+	client, err := newClient(a.logger, config)
+	if err != nil {
+		return err
+	}
+	a.client = client
+	return nil
+}
+
+func parseBool(config map[string]string, key string, def bool) (bool, error) {
+	v := config[key]
+	if v == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %q: %v", key, err)
+	}
+	return b, nil
+}
+
+// newClient builds the SQS client from the default AWS config, overridden by
+// the values from the passed config mapping.
+func newClient(log hclog.Logger, config map[string]string) (*sqs.Client, error) {
+
+	// Load our default AWS config. This handles pulling configuration from
+	// default profiles and environment variables.
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default AWS config: %v", err)
+	}
+
+	if region, ok := config[configKeyRegion]; ok {
+		log.Debug("setting AWS region for client", "region", region)
+		cfg.Region = region
+	}
+	if cfg.Region == "" {
+		cfg.Region = configValueRegionDefault
+	}
+
+	// Static credentials require both the access key and secret key; the
+	// session token is optional.
+	keyID := config[configKeyAccessID]
+	secretKey := config[configKeySecretKey]
+	if keyID != "" && secretKey != "" {
+		log.Trace("setting AWS access credentials from config map")
+		cfg.Credentials = credentials.NewStaticCredentialsProvider(keyID, secretKey, config[configKeySessionToken])
+	}
+
+	// Assuming a role allows queues to be read from another account, using
+	// the credentials configured above to call STS.
+	if roleARN := config[configKeyRoleARN]; roleARN != "" {
+		log.Trace("assuming AWS role for client", "role_arn", roleARN)
+
+		sessionName := config[configKeyRoleSessionName]
+		if sessionName == "" {
+			sessionName = configValueRoleSessionNameDefault
+		}
+
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if externalID := config[configKeyRoleExternalID]; externalID != "" {
+				o.ExternalID = aws.String(externalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return sqs.NewFromConfig(cfg), nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Query satisfies the Query function on the apm.APM interface.
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple satisfies the QueryMultiple function on the apm.APM
+// interface. The query is the URL of the queue, and the result is the sum of
+// the configured approximate message counts. SQS reports point in time
+// values, so the metric stream contains a single point and the time range is
+// not used.
+func (a *APMPlugin) QueryMultiple(q string, _ sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	queueURL, err := parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	out, err := a.client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: a.attributes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error querying attributes from sqs: %v", err)
+	}
+
+	var value float64
+	for _, attr := range a.attributes {
+		v, ok := out.Attributes[string(attr)]
+		if !ok {
+			return nil, fmt.Errorf("sqs queue %q did not return attribute %s", queueURL, attr)
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse attribute %s value %q: %v", attr, v, err)
+		}
+		value += n
+	}
+
+	return []sdk.TimestampedMetrics{{{Timestamp: time.Now(), Value: value}}}, nil
+}
+
+// parseQuery validates the query is an absolute queue URL.
+func parseQuery(q string) (string, error) {
+	q = strings.TrimSpace(q)
+
+	u, err := url.Parse(q)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return "", fmt.Errorf("query %q must be a queue URL", q)
+	}
+	return q, nil
+}
@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testQueueURL = "https://sqs.eu-west-1.amazonaws.com/123456789012/jobs"
+
+// mockSQS returns the configured attributes, recording the inputs it was
+// called with.
+type mockSQS struct {
+	attributes map[string]string
+	err        error
+	inputs     []sqs.GetQueueAttributesInput
+}
+
+func (m *mockSQS) GetQueueAttributes(_ context.Context, params *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	m.inputs = append(m.inputs, *params)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: m.attributes}, nil
+}
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name               string
+		config             map[string]string
+		expectedAttributes []types.QueueAttributeName
+		expectedError      string
+	}{
+		{
+			name:   "defaults",
+			config: map[string]string{},
+			expectedAttributes: []types.QueueAttributeName{
+				types.QueueAttributeNameApproximateNumberOfMessages,
+				types.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+			},
+		},
+		{
+			name:   "visible only",
+			config: map[string]string{"include_in_flight": "false"},
+			expectedAttributes: []types.QueueAttributeName{
+				types.QueueAttributeNameApproximateNumberOfMessages,
+			},
+		},
+		{
+			name:   "include delayed",
+			config: map[string]string{"include_delayed": "true", "timeout": "30s"},
+			expectedAttributes: []types.QueueAttributeName{
+				types.QueueAttributeNameApproximateNumberOfMessages,
+				types.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+				types.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+			},
+		},
+		{
+			name:          "unparsable include_in_flight",
+			config:        map[string]string{"include_in_flight": "sometimes"},
+			expectedError: `failed to parse "include_in_flight"`,
+		},
+		{
+			name:          "unparsable timeout",
+			config:        map[string]string{"timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), client: &mockSQS{}}
+
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedAttributes, apmPlugin.attributes)
+		})
+	}
+
+	// The client is created from the plugin config.
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{
+		"aws_region":           "eu-west-1",
+		"aws_role_arn":         "arn:aws:iam::123456789012:role/queues",
+		"aws_role_external_id": "external",
+	}))
+	assert.IsType(t, &sqs.Client{}, apmPlugin.client)
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	client := &mockSQS{attributes: map[string]string{
+		"ApproximateNumberOfMessages":           "12",
+		"ApproximateNumberOfMessagesNotVisible": "3",
+		"ApproximateNumberOfMessagesDelayed":    "7",
+	}}
+
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), client: client}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{}))
+
+	before := time.Now()
+	m, err := apmPlugin.Query(" "+testQueueURL+" ", r)
+	require.NoError(t, err)
+	require.Len(t, m, 1)
+	assert.Equal(t, float64(15), m[0].Value)
+	assert.False(t, m[0].Timestamp.Before(before))
+
+	require.Len(t, client.inputs, 1)
+	assert.Equal(t, testQueueURL, *client.inputs[0].QueueUrl)
+	assert.Equal(t, apmPlugin.attributes, client.inputs[0].AttributeNames)
+
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{"include_in_flight": "false", "include_delayed": "true"}))
+	m, err = apmPlugin.Query(testQueueURL, r)
+	require.NoError(t, err)
+	assert.Equal(t, float64(19), m[0].Value)
+}
+
+func TestAPMPlugin_Query_errors(t *testing.T) {
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), client: &mockSQS{err: errors.New("access denied")}}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{}))
+
+	_, err := apmPlugin.Query(testQueueURL, r)
+	assert.EqualError(t, err, "error querying attributes from sqs: access denied")
+
+	_, err = apmPlugin.Query("jobs", r)
+	assert.EqualError(t, err, `failed to parse query: query "jobs" must be a queue URL`)
+
+	apmPlugin.client = &mockSQS{attributes: map[string]string{"ApproximateNumberOfMessages": "12"}}
+	_, err = apmPlugin.Query(testQueueURL, r)
+	assert.EqualError(t, err, `sqs queue "`+testQueueURL+`" did not return attribute ApproximateNumberOfMessagesNotVisible`)
+
+	apmPlugin.client = &mockSQS{attributes: map[string]string{
+		"ApproximateNumberOfMessages":           "many",
+		"ApproximateNumberOfMessagesNotVisible": "3",
+	}}
+	_, err = apmPlugin.Query(testQueueURL, r)
+	assert.ErrorContains(t, err, `failed to parse attribute ApproximateNumberOfMessages value "many"`)
+}
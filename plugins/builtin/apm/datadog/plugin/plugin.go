@@ -35,7 +35,16 @@ const (
 	datadogAuthAPIKey = "apiKeyAuth"
 	datadogAuthAPPKey = "appKeyAuth"
 
-	ratelimitResetHdr = "X-Ratelimit-Reset"
+	// configKeyRatelimitMaxWait is the maximum duration a query is delayed
+	// in order to spread queries over the rate limit period.
+	configKeyRatelimitMaxWait          = "ratelimit_max_wait"
+	configValueRatelimitMaxWaitDefault = 5 * time.Second
+
+	// configKeyRatelimitMaxStale is the maximum age of the cached result
+	// returned for a query when the rate limit budget is exhausted. Setting
+	// it to zero disables the cache.
+	configKeyRatelimitMaxStale          = "ratelimit_max_stale"
+	configValueRatelimitMaxStaleDefault = 5 * time.Minute
 )
 
 var (
@@ -60,6 +69,9 @@ type APMPlugin struct {
 	config    map[string]string
 	logger    hclog.Logger
 
+	limiter *rateLimiter
+	cache   *resultCache
+
 	// ddConfigCallback is used to customize the Datadog client for testing.
 	ddConfigCallback func(*datadog.Configuration)
 }
@@ -107,6 +119,17 @@ func (a *APMPlugin) SetConfig(config map[string]string) error {
 			})
 	}
 
+	maxWait, err := parseDurationConfig(a.config, configKeyRatelimitMaxWait, configValueRatelimitMaxWaitDefault)
+	if err != nil {
+		return err
+	}
+	maxStale, err := parseDurationConfig(a.config, configKeyRatelimitMaxStale, configValueRatelimitMaxStaleDefault)
+	if err != nil {
+		return err
+	}
+	a.limiter = newRateLimiter(maxWait)
+	a.cache = newResultCache(maxStale)
+
 	a.clientCtx = ctx
 
 	// configure the Datadog API client.
@@ -123,6 +146,24 @@ func (a *APMPlugin) SetConfig(config map[string]string) error {
 	return nil
 }
 
+// parseDurationConfig parses the optional, non-negative duration config
+// value, returning def if it is not set.
+func parseDurationConfig(config map[string]string, key string, def time.Duration) (time.Duration, error) {
+	v := config[key]
+	if v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %q: %v", key, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q cannot be negative", key)
+	}
+	return d, nil
+}
+
 func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
 	return pluginInfo, nil
 }
@@ -144,23 +185,61 @@ func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, er
 }
 
 func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+
+	// Wait for our turn within the rate limit budget. If the budget is
+	// exhausted, fallback to the last result of the query.
+	wait, err := a.limiter.reserve()
+	if err != nil {
+		return a.cachedResult(q, err)
+	}
+	if wait > 0 {
+		a.logger.Debug("delaying query to spread rate limit budget", "query", q, "wait", wait)
+		time.Sleep(wait)
+	}
+
 	ctx, cancel := context.WithTimeout(a.clientCtx, 10*time.Second)
 	defer cancel()
 
 	queryResult, res, err := a.client.MetricsApi.QueryMetrics(ctx, r.From.Unix(), r.To.Unix(), q)
 	if err != nil {
 		if res != nil && res.StatusCode == http.StatusTooManyRequests {
-			return nil,
+			a.limiter.exhaust(res.Header)
+			return a.cachedResult(q,
 				fmt.Errorf("metric queries are ratelimited in current time period by datadog, resets in %s sec",
-					res.Header.Get(ratelimitResetHdr))
+					res.Header.Get(ratelimitResetHdr)))
 		}
 		return nil, fmt.Errorf("error querying metrics from datadog: %v", err)
 	}
+	if res != nil {
+		a.limiter.update(res.Header)
+	}
+
+	results := a.parseQueryResult(queryResult)
+	if len(results) > 0 {
+		a.cache.set(q, results)
+	}
+	return results, nil
+}
 
+// cachedResult returns the last result of the query when it cannot be
+// performed due to rate limiting, or the input error if there is none.
+func (a *APMPlugin) cachedResult(q string, err error) ([]sdk.TimestampedMetrics, error) {
+	results, age, ok := a.cache.get(q)
+	if !ok {
+		return nil, err
+	}
+
+	a.logger.Warn("datadog rate limit reached, using cached query result",
+		"query", q, "age", age, "reason", err)
+	return results, nil
+}
+
+// parseQueryResult converts the Datadog series into metrics.
+func (a *APMPlugin) parseQueryResult(queryResult datadog.MetricsQueryResponse) []sdk.TimestampedMetrics {
 	series := queryResult.GetSeries()
 	if len(series) == 0 {
 		a.logger.Warn("empty time series response from datadog, try a wider query window")
-		return nil, nil
+		return nil
 	}
 
 	var results []sdk.TimestampedMetrics
@@ -183,10 +262,10 @@ func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedM
 				continue
 			}
 
-			ts := int64(*p[0]) / 1e3
+			// Datadog returns timestamps in milliseconds.
 			value := *p[1]
 			tm := sdk.TimestampedMetric{
-				Timestamp: time.Unix(ts, 0),
+				Timestamp: time.UnixMilli(int64(*p[0])),
 				Value:     value,
 			}
 			result = append(result, tm)
@@ -199,5 +278,5 @@ func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedM
 		a.logger.Warn("no data points found in time series response from datadog, try a wider query window")
 	}
 
-	return results, nil
+	return results
 }
@@ -124,6 +124,26 @@ func TestAPMPlugin_SetConfig(t *testing.T) {
 			},
 			name: "site set by config map",
 		},
+		{
+			inputConfig: map[string]string{
+				"dd_api_key":         "fake-api-key",
+				"dd_app_key":         "some-app",
+				"ratelimit_max_wait": "soon",
+			},
+			expectOutput:         errors.New(`failed to parse "ratelimit_max_wait": time: invalid duration "soon"`),
+			expectedContextValue: nil,
+			name:                 "invalid rate limit max wait",
+		},
+		{
+			inputConfig: map[string]string{
+				"dd_api_key":          "fake-api-key",
+				"dd_app_key":          "some-app",
+				"ratelimit_max_stale": "-1m",
+			},
+			expectOutput:         errors.New(`"ratelimit_max_stale" cannot be negative`),
+			expectedContextValue: nil,
+			name:                 "negative rate limit max stale",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -211,6 +231,28 @@ func TestAPMPlugin_Query(t *testing.T) {
 				require.Len(t, m, 20)
 			},
 		},
+		{
+			name:    "sub-second resolution",
+			fixture: "query_subsecond.json",
+			pluginConfig: map[string]string{
+				configKeyClientAPPKey: "app",
+				configKeyClientAPIKey: "key",
+			},
+			query: "avg:nomad.client.allocated.memory",
+			timeRange: sdk.TimeRange{
+				From: time.Unix(1700000000, 0),
+				To:   time.Unix(1700000002, 0),
+			},
+			validateMetrics: func(t *testing.T, m sdk.TimestampedMetrics, err error) {
+				require.NoError(t, err)
+				require.Equal(t, sdk.TimestampedMetrics{
+					{Timestamp: time.UnixMilli(1700000000000), Value: 1},
+					{Timestamp: time.UnixMilli(1700000000500), Value: 2},
+					{Timestamp: time.UnixMilli(1700000001000), Value: 3},
+					{Timestamp: time.UnixMilli(1700000001500), Value: 4},
+				}, m)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -244,3 +286,60 @@ func TestAPMPlugin_Query(t *testing.T) {
 		})
 	}
 }
+
+func TestAPMPlugin_Query_rateLimited(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Ratelimit-Reset", "30")
+
+		// Only the first request of each query succeeds.
+		if requests > 1 {
+			w.Header().Set("X-Ratelimit-Remaining", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("X-Ratelimit-Remaining", "100")
+		http.ServeFile(w, r, path.Join("./test-fixtures", "query_200.json"))
+	}))
+	defer srv.Close()
+
+	srvURL, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	plugin := NewDatadogPlugin(hclog.NewNullLogger()).(*APMPlugin)
+	plugin.ddConfigCallback = func(config *datadog.Configuration) {
+		config.Host = srvURL.Host
+		config.Scheme = srvURL.Scheme
+	}
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		configKeyClientAPPKey:     "app",
+		configKeyClientAPIKey:     "key",
+		configKeyRatelimitMaxWait: "1ms",
+	}))
+
+	timeRange := sdk.TimeRange{From: time.Unix(1600000000, 0), To: time.Unix(1610000000, 0)}
+
+	metrics, err := plugin.Query("avg:nomad.client.allocated.memory", timeRange)
+	require.NoError(t, err)
+	require.Len(t, metrics, 63)
+
+	// The budget allows the next query to be performed, but it is rejected
+	// by Datadog, so the cached result is returned.
+	plugin.limiter.next = time.Time{}
+	metrics, err = plugin.Query("avg:nomad.client.allocated.memory", timeRange)
+	require.NoError(t, err)
+	require.Len(t, metrics, 63)
+	assert.Equal(t, 2, requests)
+
+	// The budget is now exhausted, so queries are not sent to Datadog.
+	metrics, err = plugin.Query("avg:nomad.client.allocated.memory", timeRange)
+	require.NoError(t, err)
+	require.Len(t, metrics, 63)
+	assert.Equal(t, 2, requests)
+
+	// Queries without a cached result fail.
+	_, err = plugin.Query("avg:nomad.client.allocated.cpu", timeRange)
+	assert.ErrorIs(t, err, errBudgetExhausted)
+	assert.Equal(t, 2, requests)
+}
@@ -0,0 +1,198 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// ratelimitRemainingHdr is the number of requests left in the current
+	// rate limit period, and ratelimitResetHdr the number of seconds until
+	// the period resets.
+	ratelimitRemainingHdr = "X-Ratelimit-Remaining"
+	ratelimitResetHdr     = "X-Ratelimit-Reset"
+)
+
+// errBudgetExhausted is returned when a query cannot be performed within the
+// current rate limit period.
+var errBudgetExhausted = errors.New("rate limit budget exhausted")
+
+// rateLimiter tracks the Datadog rate limit headers, spreading queries evenly
+// over the remaining rate limit period rather than allowing bursts of
+// queries to exhaust the budget.
+type rateLimiter struct {
+	// maxWait is the maximum duration a query is delayed in order to spread
+	// queries. Queries which would need to wait longer fail with
+	// errBudgetExhausted.
+	maxWait time.Duration
+
+	lock sync.Mutex
+
+	// known is true once the rate limit headers have been received.
+	known     bool
+	remaining int
+	resetAt   time.Time
+
+	// next is the earliest time the next query may start.
+	next time.Time
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+func newRateLimiter(maxWait time.Duration) *rateLimiter {
+	return &rateLimiter{
+		maxWait: maxWait,
+		now:     time.Now,
+	}
+}
+
+// reserve reserves a query from the budget, returning the duration the caller
+// must wait before performing it.
+func (r *rateLimiter) reserve() (time.Duration, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.now()
+
+	// Without rate limit information, or once the period has reset, queries
+	// are not limited until new headers are received.
+	if !r.known || !now.Before(r.resetAt) {
+		r.known = false
+		return 0, nil
+	}
+
+	if r.remaining <= 0 {
+		return 0, errBudgetExhausted
+	}
+
+	start := now
+	if r.next.After(start) {
+		start = r.next
+	}
+
+	wait := start.Sub(now)
+	if wait > r.maxWait {
+		return 0, errBudgetExhausted
+	}
+
+	// Spread the remaining queries evenly until the reset. The remaining
+	// count is decremented so concurrent queries are accounted for before
+	// their responses update it.
+	r.next = start.Add(r.resetAt.Sub(start) / time.Duration(r.remaining))
+	r.remaining--
+
+	return wait, nil
+}
+
+// update records the rate limit headers of a response.
+func (r *rateLimiter) update(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get(ratelimitRemainingHdr))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.Atoi(h.Get(ratelimitResetHdr))
+	if err != nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.known = true
+	r.remaining = remaining
+	r.resetAt = r.now().Add(time.Duration(reset) * time.Second)
+}
+
+// exhaust marks the budget as exhausted until the reset, which is used when
+// Datadog rejects a query.
+func (r *rateLimiter) exhaust(h http.Header) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	reset, err := strconv.Atoi(h.Get(ratelimitResetHdr))
+	if err != nil {
+		reset = 0
+	}
+
+	r.known = true
+	r.remaining = 0
+	r.resetAt = r.now().Add(time.Duration(reset) * time.Second)
+}
+
+// resultCache stores the last successful result of each query, so they can
+// be returned when the rate limit budget is exhausted.
+type resultCache struct {
+	maxAge time.Duration
+
+	lock    sync.Mutex
+	entries map[string]cachedResult
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+type cachedResult struct {
+	timestamp time.Time
+	metrics   []sdk.TimestampedMetrics
+}
+
+func newResultCache(maxAge time.Duration) *resultCache {
+	return &resultCache{
+		maxAge:  maxAge,
+		entries: make(map[string]cachedResult),
+		now:     time.Now,
+	}
+}
+
+func (c *resultCache) set(q string, m []sdk.TimestampedMetrics) {
+	if c.maxAge <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.now()
+	for k, e := range c.entries {
+		if now.Sub(e.timestamp) > c.maxAge {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[q] = cachedResult{timestamp: now, metrics: copyResults(m)}
+}
+
+// get returns the last result of the query, if it is not older than maxAge.
+func (c *resultCache) get(q string) ([]sdk.TimestampedMetrics, time.Duration, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[q]
+	if !ok {
+		return nil, 0, false
+	}
+
+	age := c.now().Sub(e.timestamp)
+	if age > c.maxAge {
+		return nil, 0, false
+	}
+	return copyResults(e.metrics), age, true
+}
+
+// copyResults returns a copy of the input, so that callers sorting their
+// result do not modify the cached value.
+func copyResults(in []sdk.TimestampedMetrics) []sdk.TimestampedMetrics {
+	out := make([]sdk.TimestampedMetrics, len(in))
+	for i, m := range in {
+		out[i] = append(sdk.TimestampedMetrics(nil), m...)
+	}
+	return out
+}
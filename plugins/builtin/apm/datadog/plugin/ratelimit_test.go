@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rateLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := newRateLimiter(5 * time.Second)
+	limiter.now = func() time.Time { return now }
+
+	// Queries are not limited before any headers are received.
+	wait, err := limiter.reserve()
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// 4 queries remain over 20 seconds, so they are spread 5 seconds apart.
+	limiter.update(http.Header{
+		"X-Ratelimit-Remaining": []string{"4"},
+		"X-Ratelimit-Reset":     []string{"20"},
+	})
+
+	wait, err = limiter.reserve()
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	wait, err = limiter.reserve()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, wait)
+
+	// The next query would need to wait longer than the maximum.
+	_, err = limiter.reserve()
+	assert.ErrorIs(t, err, errBudgetExhausted)
+
+	// Once time has passed, the query can be performed.
+	now = now.Add(6 * time.Second)
+	wait, err = limiter.reserve()
+	require.NoError(t, err)
+	assert.Equal(t, 4*time.Second, wait)
+
+	// The budget is used up until the period resets.
+	now = now.Add(10 * time.Second)
+	wait, err = limiter.reserve()
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+	_, err = limiter.reserve()
+	assert.ErrorIs(t, err, errBudgetExhausted)
+
+	now = now.Add(5 * time.Second)
+	wait, err = limiter.reserve()
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func Test_rateLimiter_exhaust(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := newRateLimiter(5 * time.Second)
+	limiter.now = func() time.Time { return now }
+
+	limiter.exhaust(http.Header{"X-Ratelimit-Reset": []string{"10"}})
+	_, err := limiter.reserve()
+	assert.ErrorIs(t, err, errBudgetExhausted)
+
+	now = now.Add(10 * time.Second)
+	_, err = limiter.reserve()
+	assert.NoError(t, err)
+
+	// Invalid headers are ignored.
+	limiter.update(http.Header{"X-Ratelimit-Remaining": []string{"0"}})
+	_, err = limiter.reserve()
+	assert.NoError(t, err)
+}
+
+func Test_resultCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := newResultCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	_, _, ok := cache.get("query")
+	assert.False(t, ok)
+
+	m := []sdk.TimestampedMetrics{{{Timestamp: now, Value: 1}, {Timestamp: now.Add(-time.Second), Value: 2}}}
+	cache.set("query", m)
+
+	// Results are copied in and out of the cache.
+	m[0][0].Value = 10
+	now = now.Add(30 * time.Second)
+	result, age, ok := cache.get("query")
+	require.True(t, ok)
+	assert.Equal(t, 30*time.Second, age)
+	assert.Equal(t, 1.0, result[0][0].Value)
+
+	result[0][0].Value = 20
+	result, _, _ = cache.get("query")
+	assert.Equal(t, 1.0, result[0][0].Value)
+
+	// Stale results are not returned.
+	now = now.Add(time.Minute)
+	_, _, ok = cache.get("query")
+	assert.False(t, ok)
+
+	// A zero max age disables the cache.
+	cache = newResultCache(0)
+	cache.set("query", m)
+	_, _, ok = cache.get("query")
+	assert.False(t, ok)
+}
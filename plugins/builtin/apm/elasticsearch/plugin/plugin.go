@@ -0,0 +1,215 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "elasticsearch"
+
+	// configKeyAddress is the address of the Elasticsearch or OpenSearch
+	// cluster, such as "https://127.0.0.1:9200".
+	configKeyAddress = "address"
+
+	// configKeyUsername and configKeyPassword configure basic auth, while
+	// configKeyAPIKey configures an Elasticsearch API key. The API key takes
+	// precedence if both are set.
+	configKeyUsername = "username"
+	configKeyPassword = "password"
+	configKeyAPIKey   = "api_key"
+
+	// configKeyTimeField is the default date field used to filter and
+	// bucket documents, when not set by the query.
+	configKeyTimeField          = "time_field"
+	configValueTimeFieldDefault = "@timestamp"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewElasticsearchPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the Elasticsearch implementation of the apm.APM interface.
+// It runs date histogram aggregations using the search API, which is shared
+// by Elasticsearch and OpenSearch.
+type APMPlugin struct {
+	client    *http.Client
+	config    map[string]string
+	logger    hclog.Logger
+	address   string
+	timeField string
+	timeout   time.Duration
+}
+
+func NewElasticsearchPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	addr := config[configKeyAddress]
+	if addr == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+	if _, err := url.Parse(addr); err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	a.timeField = configValueTimeFieldDefault
+	if v := config[configKeyTimeField]; v != "" {
+		a.timeField = v
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple runs the query as a date histogram search, returning a
+// metric stream for each group_by bucket, or a single stream if the query is
+// not grouped.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying Elasticsearch", "query", q, "range", r)
+
+	query, err := parseQuery(q, a.timeField)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	body, err := json.Marshal(query.searchBody(r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	u := fmt.Sprintf("%s/%s/_search", a.address, url.PathEscape(query.Index))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if key := a.config[configKeyAPIKey]; key != "" {
+		req.Header.Set("Authorization", "ApiKey "+key)
+	} else if user := a.config[configKeyUsername]; user != "" {
+		req.SetBasicAuth(user, a.config[configKeyPassword])
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from elasticsearch: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error querying metrics from elasticsearch: %v", responseError(resp))
+	}
+
+	var result searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode elasticsearch response: %v", err)
+	}
+
+	metrics, err := query.metrics(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse elasticsearch response: %v", err)
+	}
+
+	if len(metrics) == 0 {
+		a.logger.Warn("empty time series response from elasticsearch, try a wider query window")
+	}
+	return metrics, nil
+}
+
+// responseError converts an Elasticsearch error response into an error,
+// using the root cause reason if possible.
+func responseError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var body struct {
+		Error struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(b, &body); err == nil && body.Error.Reason != "" {
+		return fmt.Errorf("%s (%d): %s", body.Error.Type, resp.StatusCode, body.Error.Reason)
+	}
+	return fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+}
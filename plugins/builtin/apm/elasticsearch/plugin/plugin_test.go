@@ -0,0 +1,226 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing address",
+			config:        map[string]string{},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"address": "http://127.0.0.1:9200", "timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+		{
+			name:          "missing ca cert",
+			config:        map[string]string{"address": "http://127.0.0.1:9200", "ca_cert": "./test-fixtures/missing.crt"},
+			expectedError: "failed to read CA cert",
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"address": "http://127.0.0.1:9200", "api_key": "key", "time_field": "timestamp"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	timeRange := sdk.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC),
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/access-logs-*/_search", r.URL.Path)
+
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "elastic", user)
+		assert.Equal(t, "changeme", pass)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		expected := map[string]interface{}{
+			"size": float64(0),
+			"query": map[string]interface{}{"bool": map[string]interface{}{"filter": []interface{}{
+				map[string]interface{}{"range": map[string]interface{}{"@timestamp": map[string]interface{}{
+					"gte": float64(1704067200000), "lte": float64(1704067500000), "format": "epoch_millis",
+				}}},
+				map[string]interface{}{"term": map[string]interface{}{"service": "web"}},
+			}}},
+			"aggs": map[string]interface{}{"histogram": map[string]interface{}{
+				"date_histogram": map[string]interface{}{"field": "@timestamp", "fixed_interval": "1m"},
+				"aggs":           map[string]interface{}{"value": map[string]interface{}{"rate": map[string]interface{}{"unit": "second"}}},
+			}},
+		}
+		assert.Equal(t, expected, body)
+
+		http.ServeFile(w, r, path.Join("./test-fixtures", "search_200.json"))
+	}))
+	defer srv.Close()
+
+	plugin := NewElasticsearchPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address":  srv.URL,
+		"username": "elastic",
+		"password": "changeme",
+	}))
+
+	metrics, err := plugin.Query(`{
+  "index": "access-logs-*",
+  "query": {"term": {"service": "web"}},
+  "metric": {"rate": {"unit": "second"}}
+}`, timeRange)
+	require.NoError(t, err)
+
+	// The empty bucket should be skipped.
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: time.UnixMilli(1704067260000), Value: 1},
+		{Timestamp: time.UnixMilli(1704067380000), Value: 2},
+	}, metrics)
+}
+
+func TestAPMPlugin_QueryMultiple_groupBy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ApiKey secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{
+  "aggregations": {
+    "groups": {
+      "buckets": [
+        {"key": "web", "doc_count": 3, "histogram": {"buckets": [{"key": 1704067260000, "doc_count": 3}]}},
+        {"key": "api", "doc_count": 0, "histogram": {"buckets": []}},
+        {"key": "db", "doc_count": 5, "histogram": {"buckets": [{"key": 1704067260000, "doc_count": 2}, {"key": 1704067320000, "doc_count": 3}]}}
+      ]
+    }
+  }
+}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewElasticsearchPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "api_key": "secret"}))
+
+	metrics, err := plugin.QueryMultiple(`{"index": "logs", "group_by": "service"}`, sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()})
+	require.NoError(t, err)
+
+	// Document counts are used without a metric, and empty groups are
+	// skipped.
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{{Timestamp: time.UnixMilli(1704067260000), Value: 3}},
+		{{Timestamp: time.UnixMilli(1704067260000), Value: 2}, {Timestamp: time.UnixMilli(1704067320000), Value: 3}},
+	}, metrics)
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": {"type": "index_not_found_exception", "reason": "no such index [logs]"}, "status": 404}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewElasticsearchPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	_, err := plugin.Query(`{"index": "logs"}`, r)
+	assert.EqualError(t, err, "error querying metrics from elasticsearch: index_not_found_exception (404): no such index [logs]")
+
+	_, err = plugin.Query(`{"indx": "logs"}`, r)
+	assert.ErrorContains(t, err, "failed to parse query")
+}
+
+func Test_parseQuery(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		expected      *query
+		expectedError string
+	}{
+		{
+			name:     "defaults",
+			query:    `{"index": "logs"}`,
+			expected: &query{Index: "logs", TimeField: "@timestamp", Interval: "1m", GroupSize: 10},
+		},
+		{
+			name:          "missing index",
+			query:         `{"interval": "5m"}`,
+			expectedError: "index must be set",
+		},
+		{
+			name:          "multiple metrics",
+			query:         `{"index": "logs", "metric": {"avg": {"field": "a"}, "max": {"field": "a"}}}`,
+			expectedError: "metric must contain a single aggregation",
+		},
+		{
+			name:          "negative group size",
+			query:         `{"index": "logs", "group_by": "service", "group_size": -1}`,
+			expectedError: "group_size must be greater than zero",
+		},
+		{
+			name:          "not json",
+			query:         `logs`,
+			expectedError: "invalid character",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := parseQuery(tc.query, "@timestamp")
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, q)
+		})
+	}
+}
+
+func Test_metricValue(t *testing.T) {
+	var m metricValue
+	require.NoError(t, json.Unmarshal([]byte(`{"values": {"95.0": 12.5}}`), &m))
+	v, err := m.get()
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, *v)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"values": {"50.0": 1, "95.0": 2}}`), &m))
+	_, err = m.get()
+	assert.EqualError(t, err, "metric returned 2 values, only 1 is expected")
+}
@@ -0,0 +1,247 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// defaultInterval is the date histogram interval used when the query does
+	// not set one.
+	defaultInterval = "1m"
+
+	// defaultGroupSize is the number of group_by buckets returned when the
+	// query does not set group_size.
+	defaultGroupSize = 10
+)
+
+// query is the JSON document used as the check query, for example:
+//
+//	{
+//	  "index": "access-logs-*",
+//	  "query": {"term": {"service": "web"}},
+//	  "metric": {"rate": {"unit": "second"}},
+//	  "interval": "1m"
+//	}
+//
+// Documents within the check query window are bucketed into a date
+// histogram. Each bucket is converted to a metric using the value of the
+// metric aggregation, or the number of documents if no metric is set.
+type query struct {
+
+	// Index is the index, alias or index pattern to search.
+	Index string `json:"index"`
+
+	// TimeField is the date field used to filter and bucket documents. It
+	// defaults to the time_field plugin config value.
+	TimeField string `json:"time_field"`
+
+	// Interval is the fixed interval of the date histogram.
+	Interval string `json:"interval"`
+
+	// Query is an optional query DSL object used to filter documents.
+	Query json.RawMessage `json:"query"`
+
+	// Metric is an optional single value metric aggregation, such as
+	// {"avg": {"field": "duration"}}, calculated for each bucket.
+	Metric json.RawMessage `json:"metric"`
+
+	// GroupBy is an optional field which splits the documents into multiple
+	// metric streams, using a terms aggregation of GroupSize buckets.
+	GroupBy   string `json:"group_by"`
+	GroupSize int    `json:"group_size"`
+}
+
+// parseQuery decodes and validates the check query, applying defaults.
+func parseQuery(q string, timeField string) (*query, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(q)))
+	dec.DisallowUnknownFields()
+
+	var parsed query
+	if err := dec.Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	if parsed.Index == "" {
+		return nil, errors.New("index must be set")
+	}
+	if parsed.TimeField == "" {
+		parsed.TimeField = timeField
+	}
+	if parsed.Interval == "" {
+		parsed.Interval = defaultInterval
+	}
+	if parsed.GroupSize == 0 {
+		parsed.GroupSize = defaultGroupSize
+	}
+	if parsed.GroupSize < 0 {
+		return nil, errors.New("group_size must be greater than zero")
+	}
+
+	if len(parsed.Metric) > 0 {
+		var metric map[string]json.RawMessage
+		if err := json.Unmarshal(parsed.Metric, &metric); err != nil {
+			return nil, fmt.Errorf("invalid metric: %v", err)
+		}
+		if len(metric) != 1 {
+			return nil, errors.New("metric must contain a single aggregation")
+		}
+	}
+
+	return &parsed, nil
+}
+
+// searchBody returns the search API request body for the query over the
+// passed time range.
+func (q *query) searchBody(r sdk.TimeRange) map[string]interface{} {
+	filters := []interface{}{
+		map[string]interface{}{
+			"range": map[string]interface{}{
+				q.TimeField: map[string]interface{}{
+					"gte":    r.From.UnixMilli(),
+					"lte":    r.To.UnixMilli(),
+					"format": "epoch_millis",
+				},
+			},
+		},
+	}
+	if len(q.Query) > 0 {
+		filters = append(filters, q.Query)
+	}
+
+	histogram := map[string]interface{}{
+		"date_histogram": map[string]interface{}{
+			"field":          q.TimeField,
+			"fixed_interval": q.Interval,
+		},
+	}
+	if len(q.Metric) > 0 {
+		histogram["aggs"] = map[string]interface{}{"value": q.Metric}
+	}
+
+	aggs := map[string]interface{}{"histogram": histogram}
+	if q.GroupBy != "" {
+		aggs = map[string]interface{}{
+			"groups": map[string]interface{}{
+				"terms": map[string]interface{}{"field": q.GroupBy, "size": q.GroupSize},
+				"aggs":  aggs,
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filters}},
+		"aggs":  aggs,
+	}
+}
+
+// searchResponse is the subset of the search API response used by the
+// plugin.
+type searchResponse struct {
+	Aggregations struct {
+		Histogram *histogramAggregation `json:"histogram"`
+		Groups    *struct {
+			Buckets []struct {
+				Histogram *histogramAggregation `json:"histogram"`
+			} `json:"buckets"`
+		} `json:"groups"`
+	} `json:"aggregations"`
+}
+
+type histogramAggregation struct {
+	Buckets []struct {
+		Key      int64        `json:"key"`
+		DocCount int64        `json:"doc_count"`
+		Value    *metricValue `json:"value"`
+	} `json:"buckets"`
+}
+
+// metricValue is the result of a metric aggregation. Single value metrics
+// such as avg set Value, while metrics such as percentiles set a single
+// entry within Values.
+type metricValue struct {
+	Value  *float64            `json:"value"`
+	Values map[string]*float64 `json:"values"`
+}
+
+// get returns the value of the metric, or nil if the bucket has no value.
+func (m *metricValue) get() (*float64, error) {
+	if m.Values == nil {
+		return m.Value, nil
+	}
+	if len(m.Values) != 1 {
+		return nil, fmt.Errorf("metric returned %d values, only 1 is expected", len(m.Values))
+	}
+	for _, v := range m.Values {
+		return v, nil
+	}
+	return nil, nil
+}
+
+// metrics converts the search response into metric streams.
+func (q *query) metrics(resp *searchResponse) ([]sdk.TimestampedMetrics, error) {
+	var histograms []*histogramAggregation
+
+	if q.GroupBy != "" {
+		if resp.Aggregations.Groups == nil {
+			return nil, errors.New("response does not contain the groups aggregation")
+		}
+		for _, b := range resp.Aggregations.Groups.Buckets {
+			histograms = append(histograms, b.Histogram)
+		}
+	} else {
+		if resp.Aggregations.Histogram == nil {
+			return nil, errors.New("response does not contain the histogram aggregation")
+		}
+		histograms = append(histograms, resp.Aggregations.Histogram)
+	}
+
+	var results []sdk.TimestampedMetrics
+	for _, h := range histograms {
+		if h == nil {
+			continue
+		}
+
+		var result sdk.TimestampedMetrics
+		for _, b := range h.Buckets {
+			value := float64(b.DocCount)
+
+			if len(q.Metric) > 0 {
+				if b.Value == nil {
+					return nil, errors.New("bucket does not contain the metric value")
+				}
+				v, err := b.Value.get()
+				if err != nil {
+					return nil, err
+				}
+
+				// Buckets without documents have a null value for most
+				// metrics, so they are skipped.
+				if v == nil {
+					continue
+				}
+				value = *v
+			}
+
+			result = append(result, sdk.TimestampedMetric{
+				Timestamp: time.UnixMilli(b.Key),
+				Value:     value,
+			})
+		}
+
+		if len(result) > 0 {
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
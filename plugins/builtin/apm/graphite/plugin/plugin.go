@@ -0,0 +1,226 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "graphite"
+
+	// configKeyAddress is the address of the Graphite web or
+	// graphite-api server, such as "http://127.0.0.1:8080".
+	configKeyAddress = "address"
+
+	// configKeyUsername and configKeyPassword configure basic auth.
+	configKeyUsername = "username"
+	configKeyPassword = "password"
+
+	// configKeyHeadersPrefix is the prefix used to indicate that a
+	// configuration value should be set as an HTTP header.
+	configKeyHeadersPrefix = "header_"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewGraphitePlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the Graphite implementation of the apm.APM interface, which
+// runs queries using the render API.
+type APMPlugin struct {
+	client  *http.Client
+	config  map[string]string
+	logger  hclog.Logger
+	address string
+	timeout time.Duration
+}
+
+func NewGraphitePlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	addr := config[configKeyAddress]
+	if addr == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+	if _, err := url.Parse(addr); err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple runs the query as the render API target, returning a metric
+// stream for each series. Null datapoints are skipped.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying Graphite", "query", q, "range", r)
+
+	params := url.Values{
+		"target": {q},
+		"from":   {strconv.FormatInt(r.From.Unix(), 10)},
+		"until":  {strconv.FormatInt(r.To.Unix(), 10)},
+		"format": {"json"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.address+"/render?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	if user := a.config[configKeyUsername]; user != "" {
+		req.SetBasicAuth(user, a.config[configKeyPassword])
+	}
+	for k, v := range a.config {
+		if strings.HasPrefix(k, configKeyHeadersPrefix) {
+			req.Header.Set(strings.TrimPrefix(k, configKeyHeadersPrefix), v)
+		}
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from graphite: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("error querying metrics from graphite: unexpected response code %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	var series []renderSeries
+	if err := json.NewDecoder(resp.Body).Decode(&series); err != nil {
+		return nil, fmt.Errorf("failed to decode graphite response: %v", err)
+	}
+
+	var results []sdk.TimestampedMetrics
+	for _, s := range series {
+		var result sdk.TimestampedMetrics
+
+		for _, dp := range s.Datapoints {
+			if dp.Value == nil {
+				continue
+			}
+			result = append(result, sdk.TimestampedMetric{
+				Timestamp: time.Unix(dp.Timestamp, 0),
+				Value:     *dp.Value,
+			})
+		}
+
+		if len(result) > 0 {
+			results = append(results, result)
+		}
+	}
+
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from graphite, try a wider query window")
+	}
+	return results, nil
+}
+
+// renderSeries is a single series returned by the render API.
+type renderSeries struct {
+	Target     string      `json:"target"`
+	Datapoints []datapoint `json:"datapoints"`
+}
+
+// datapoint is a render API datapoint, which is encoded as a
+// [value, timestamp] array where the value may be null.
+type datapoint struct {
+	Value     *float64
+	Timestamp int64
+}
+
+func (d *datapoint) UnmarshalJSON(b []byte) error {
+	var raw []*float64
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 2 || raw[1] == nil {
+		return fmt.Errorf("invalid datapoint %s", b)
+	}
+	d.Value = raw[0]
+	d.Timestamp = int64(*raw[1])
+	return nil
+}
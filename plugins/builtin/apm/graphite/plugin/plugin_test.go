@@ -0,0 +1,147 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing address",
+			config:        map[string]string{},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "invalid address",
+			config:        map[string]string{"address": "\n\n"},
+			expectedError: `failed to parse "address"`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"address": "http://127.0.0.1:8080", "skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"address": "http://127.0.0.1:8080", "timeout": "30s"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/render", r.URL.Path)
+		assert.Equal(t, "sumSeries(stats.web.*.requests)", r.URL.Query().Get("target"))
+		assert.Equal(t, "1704067200", r.URL.Query().Get("from"))
+		assert.Equal(t, "1704067500", r.URL.Query().Get("until"))
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		assert.Equal(t, "true", r.Header.Get("test"))
+
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+
+		http.ServeFile(w, r, path.Join("./test-fixtures", "render_200.json"))
+	}))
+	defer srv.Close()
+
+	plugin := NewGraphitePlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address":     srv.URL,
+		"username":    "user",
+		"password":    "pass",
+		"header_test": "true",
+	}))
+
+	metrics, err := plugin.Query("sumSeries(stats.web.*.requests)", sdk.TimeRange{
+		From: time.Unix(1704067200, 0),
+		To:   time.Unix(1704067500, 0),
+	})
+	require.NoError(t, err)
+
+	// Null datapoints should be skipped.
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: time.Unix(1704067260, 0), Value: 12},
+		{Timestamp: time.Unix(1704067380, 0), Value: 15.5},
+	}, metrics)
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+  {"target": "stats.web.a.requests", "datapoints": [[1, 1704067260]]},
+  {"target": "stats.web.b.requests", "datapoints": [[null, 1704067260]]},
+  {"target": "stats.web.c.requests", "datapoints": [[3, 1704067260]]}
+]`))
+	}))
+	defer srv.Close()
+
+	plugin := NewGraphitePlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	// Series without any datapoints should be skipped.
+	metrics, err := plugin.QueryMultiple("stats.web.*.requests", r)
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{{Timestamp: time.Unix(1704067260, 0), Value: 1}},
+		{{Timestamp: time.Unix(1704067260, 0), Value: 3}},
+	}, metrics)
+
+	_, err = plugin.Query("stats.web.*.requests", r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("target") == "invalid" {
+			_, _ = w.Write([]byte(`[{"target": "invalid", "datapoints": [[1]]}]`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Unknown function 'sumSerie'\n"))
+	}))
+	defer srv.Close()
+
+	plugin := NewGraphitePlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	_, err := plugin.Query("sumSerie(stats.web.*.requests)", r)
+	assert.EqualError(t, err, "error querying metrics from graphite: unexpected response code 400: Unknown function 'sumSerie'")
+
+	_, err = plugin.Query("invalid", r)
+	assert.ErrorContains(t, err, "invalid datapoint [1]")
+}
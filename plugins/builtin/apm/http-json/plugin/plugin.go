@@ -0,0 +1,336 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+	"github.com/jmespath/go-jmespath"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "http-json"
+
+	// configKeyAddress is the optional base URL which relative query URLs are
+	// resolved against.
+	configKeyAddress = "address"
+
+	// configKeyBasicAuthUser and configKeyBasicAuthPassword configure basic
+	// auth.
+	configKeyBasicAuthUser     = "basic_auth_user"
+	configKeyBasicAuthPassword = "basic_auth_password"
+
+	// configKeyHeadersPrefix is the prefix used to indicate that a
+	// configuration value should be set as an HTTP header.
+	configKeyHeadersPrefix = "header_"
+
+	// configKeyTimeout is the maximum duration of a request.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+
+	// maxResponseSize limits the size of response bodies read by the plugin.
+	maxResponseSize = 16 * 1024 * 1024
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewHTTPJSONPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the HTTP JSON implementation of the apm.APM interface, which
+// extracts metrics from arbitrary JSON endpoints using JMESPath expressions.
+type APMPlugin struct {
+	client  *http.Client
+	config  map[string]string
+	logger  hclog.Logger
+	address *url.URL
+	timeout time.Duration
+}
+
+func NewHTTPJSONPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	a.address = nil
+	if addr := config[configKeyAddress]; addr != "" {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+		}
+		if !u.IsAbs() {
+			return fmt.Errorf("%q must be an absolute URL", configKeyAddress)
+		}
+		a.address = u
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple fetches the query URL and evaluates the JMESPath expression
+// against the JSON response.
+//
+// Queries are in the form <url>#<expression>, where the URL may be relative
+// to the configured address. An expression which evaluates to a number, or a
+// numeric string, produces a single point at the current time. An expression
+// which evaluates to an array of [<timestamp>, <value>] pairs produces a
+// point for each pair within the time range, where timestamps are Unix
+// seconds or RFC3339 strings.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying HTTP JSON endpoint", "query", q, "range", r)
+
+	target, expr, err := a.parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	body, err := a.get(target)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from %s: %v", target.Redacted(), err)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	result, err := expr.Search(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %v", err)
+	}
+
+	metrics, err := parseResult(result, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) == 0 {
+		a.logger.Warn("empty time series response from http endpoint, try a wider query window")
+		return nil, nil
+	}
+	return []sdk.TimestampedMetrics{metrics}, nil
+}
+
+// parseQuery splits the query into the URL and compiled expression.
+func (a *APMPlugin) parseQuery(q string) (*url.URL, *jmespath.JMESPath, error) {
+	rawURL, rawExpr, ok := strings.Cut(strings.TrimSpace(q), "#")
+	if !ok || strings.TrimSpace(rawExpr) == "" {
+		return nil, nil, fmt.Errorf("query %q must be in the form <url>#<expression>", q)
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.address != nil {
+		target = a.address.ResolveReference(target)
+	}
+	if !target.IsAbs() {
+		return nil, nil, fmt.Errorf("url %q must be absolute when %q is not set", rawURL, configKeyAddress)
+	}
+
+	expr, err := jmespath.Compile(strings.TrimSpace(rawExpr))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid expression: %v", err)
+	}
+
+	return target, expr, nil
+}
+
+// get performs a request to the URL, returning the response body.
+func (a *APMPlugin) get(target *url.URL) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	for k, v := range a.config {
+		if name, ok := strings.CutPrefix(k, configKeyHeadersPrefix); ok {
+			req.Header.Set(name, v)
+		}
+	}
+	if user := a.config[configKeyBasicAuthUser]; user != "" {
+		req.SetBasicAuth(user, a.config[configKeyBasicAuthPassword])
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return nil, fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, msg)
+	}
+	return body, nil
+}
+
+// parseResult converts the expression result into metrics.
+func parseResult(result interface{}, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	switch v := result.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		return parseSeries(v, r)
+	default:
+		value, err := parseValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return sdk.TimestampedMetrics{{Timestamp: time.Now(), Value: value}}, nil
+	}
+}
+
+// parseSeries converts an array of [<timestamp>, <value>] pairs into metrics
+// within the time range, sorted by timestamp.
+func parseSeries(pairs []interface{}, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	var metrics sdk.TimestampedMetrics
+
+	for i, p := range pairs {
+		pair, ok := p.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("result element %d must be a [<timestamp>, <value>] pair", i)
+		}
+		ts, err := parseTimestamp(pair[0])
+		if err != nil {
+			return nil, fmt.Errorf("result element %d: %v", i, err)
+		}
+		if (!r.From.IsZero() && ts.Before(r.From)) || (!r.To.IsZero() && ts.After(r.To)) {
+			continue
+		}
+		value, err := parseValue(pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("result element %d: %v", i, err)
+		}
+		metrics = append(metrics, sdk.TimestampedMetric{Timestamp: ts, Value: value})
+	}
+
+	sort.Stable(metrics)
+	return metrics, nil
+}
+
+func parseValue(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not numeric", v)
+		}
+		return f, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("value of type %T is not numeric", v)
+	}
+}
+
+func parseTimestamp(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case float64:
+		return unixSeconds(v), nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return unixSeconds(f), nil
+		}
+		ts, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timestamp %q must be Unix seconds or RFC3339", v)
+		}
+		return ts, nil
+	default:
+		return time.Time{}, fmt.Errorf("timestamp of type %T is not supported", v)
+	}
+}
+
+func unixSeconds(f float64) time.Time {
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9))
+}
@@ -0,0 +1,204 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "invalid address",
+			config:        map[string]string{"address": "\n\n"},
+			expectedError: `failed to parse "address"`,
+		},
+		{
+			name:          "relative address",
+			config:        map[string]string{"address": "/api"},
+			expectedError: `"address" must be an absolute URL`,
+		},
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:   "defaults",
+			config: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+			assert.Equal(t, configValueTimeoutDefault, apmPlugin.timeout)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "missing api key"}`))
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "autoscaler" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/status" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+			return
+		}
+		http.ServeFile(w, r, "test-fixtures/status_200.json")
+	}))
+	defer ts.Close()
+
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{
+		"address":             ts.URL + "/api/",
+		"header_X-Api-Key":    "secret",
+		"basic_auth_user":     "autoscaler",
+		"basic_auth_password": "pass",
+	}))
+
+	r := sdk.TimeRange{From: time.Unix(1700000000, 0), To: time.Unix(1700000100, 0)}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected []float64
+	}{
+		{name: "number", query: "status#queues[?name=='default'].depth | [0]", expected: []float64{12}},
+		{name: "numeric string", query: "status#queues[?name=='priority'].depth | [0]", expected: []float64{4}},
+		{name: "function", query: "status#sum(queues[].workers)", expected: []float64{4}},
+		{name: "bool", query: "status#healthy", expected: []float64{1}},
+		{name: "absolute url", query: ts.URL + "/api/status#length(queues)", expected: []float64{2}},
+		{name: "series", query: "status#history.points[].[time, value]", expected: []float64{0.5, 0.25}},
+		{name: "missing", query: "status#queues[?name=='bulk'].depth | [0]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics, err := apmPlugin.Query(tc.query, r)
+			require.NoError(t, err)
+
+			var values []float64
+			for _, m := range metrics {
+				values = append(values, m.Value)
+			}
+			assert.Equal(t, tc.expected, values)
+		})
+	}
+
+	// Series points are sorted and use the returned timestamps.
+	metrics, err := apmPlugin.Query("status#history.points[].[time, value]", r)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: time.Unix(1700000000, 0), Value: 0.5},
+		{Timestamp: time.Date(2023, 11, 14, 22, 15, 0, 0, time.UTC), Value: 0.25},
+	}, metrics)
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			http.ServeFile(w, r, "test-fixtures/status_200.json")
+		case "/text":
+			_, _ = w.Write([]byte("OK"))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("maintenance\n"))
+		}
+	}))
+	defer ts.Close()
+
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{"address": ts.URL}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	testCases := []struct {
+		name          string
+		query         string
+		expectedError string
+	}{
+		{
+			name:          "missing expression",
+			query:         "/status",
+			expectedError: `failed to parse query: query "/status" must be in the form <url>#<expression>`,
+		},
+		{
+			name:          "invalid expression",
+			query:         "/status#queues[",
+			expectedError: "failed to parse query: invalid expression",
+		},
+		{
+			name:          "response code",
+			query:         "/down#status",
+			expectedError: "error querying metrics from " + ts.URL + "/down: unexpected response code 503: maintenance",
+		},
+		{
+			name:          "invalid json",
+			query:         "/text#status",
+			expectedError: "failed to decode response",
+		},
+		{
+			name:          "non numeric",
+			query:         "/status#status",
+			expectedError: `value "ok" is not numeric`,
+		},
+		{
+			name:          "object",
+			query:         "/status#history",
+			expectedError: "value of type map[string]interface {} is not numeric",
+		},
+		{
+			name:          "invalid pair",
+			query:         "/status#queues[].depth",
+			expectedError: "result element 0 must be a [<timestamp>, <value>] pair",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := apmPlugin.Query(tc.query, r)
+			assert.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+
+	// Relative URLs require the address.
+	apmPlugin = APMPlugin{logger: hclog.NewNullLogger()}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{}))
+	_, err := apmPlugin.Query("/status#status", r)
+	assert.EqualError(t, err, `failed to parse query: url "/status" must be absolute when "address" is not set`)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	columnResult = "result"
+	columnTable  = "table"
+	columnTime   = "_time"
+	columnValue  = "_value"
+	columnError  = "error"
+)
+
+// parseFluxCSV converts an annotated CSV Flux response into metric streams,
+// returning one stream per table in the order the tables were received.
+// Tables must contain the _time and _value columns, and rows with a null
+// _value are skipped.
+func parseFluxCSV(r io.Reader) ([]sdk.TimestampedMetrics, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	var (
+		results []sdk.TimestampedMetrics
+		index   = make(map[string]int)
+
+		// header maps the name of each column to its index, and defaults
+		// holds the default values from the #default annotation.
+		header       map[string]int
+		defaults     []string
+		expectHeader = true
+	)
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Annotations start a new result set, so the next row is its header.
+		if strings.HasPrefix(record[0], "#") {
+			if record[0] == "#default" {
+				defaults = append([]string(nil), record...)
+			}
+			expectHeader = true
+			continue
+		}
+
+		if expectHeader {
+			header = make(map[string]int, len(record))
+			for i, name := range record {
+				header[name] = i
+			}
+			expectHeader = false
+
+			if _, ok := header[columnError]; ok {
+				continue
+			}
+			if _, ok := header[columnTime]; !ok {
+				return nil, fmt.Errorf("result does not contain the %q column", columnTime)
+			}
+			if _, ok := header[columnValue]; !ok {
+				return nil, fmt.Errorf("result does not contain the %q column", columnValue)
+			}
+			continue
+		}
+
+		get := func(column string) string {
+			i, ok := header[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			if record[i] == "" && i < len(defaults) {
+				return defaults[i]
+			}
+			return record[i]
+		}
+
+		// Errors which occur after the response has started are returned as
+		// a table with an error column.
+		if _, ok := header[columnError]; ok {
+			return nil, errors.New(get(columnError))
+		}
+
+		value := get(columnValue)
+		if value == "" {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s %q: %v", columnValue, value, err)
+		}
+		ts, err := time.Parse(time.RFC3339Nano, get(columnTime))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", columnTime, err)
+		}
+
+		key := get(columnResult) + "/" + get(columnTable)
+		i, ok := index[key]
+		if !ok {
+			i = len(results)
+			index[key] = i
+			results = append(results, nil)
+		}
+		results[i] = append(results[i], sdk.TimestampedMetric{Timestamp: ts, Value: v})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,250 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "influxdb"
+
+	// configKeyAddress is the address of the InfluxDB API, such as
+	// "http://127.0.0.1:8086".
+	configKeyAddress = "address"
+
+	// configKeyToken and configKeyOrg are the API token and organization
+	// used to run queries. They default to the values of the environment
+	// variables used by the influx CLI.
+	configKeyToken = "token"
+	configKeyOrg   = "org"
+	envKeyToken    = "INFLUX_TOKEN"
+	envKeyOrg      = "INFLUX_ORG"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewInfluxDBPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the InfluxDB 2.x implementation of the apm.APM interface,
+// which runs Flux queries using the InfluxDB v2 query API. This supports
+// both InfluxDB OSS 2.x and InfluxDB Cloud.
+type APMPlugin struct {
+	client  *http.Client
+	config  map[string]string
+	logger  hclog.Logger
+	address string
+	token   string
+	org     string
+	timeout time.Duration
+}
+
+func NewInfluxDBPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	addr := config[configKeyAddress]
+	if addr == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+	if _, err := url.Parse(addr); err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	// config keys override env keys
+	a.token = config[configKeyToken]
+	if a.token == "" {
+		a.token = os.Getenv(envKeyToken)
+	}
+	if a.token == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyToken)
+	}
+
+	a.org = config[configKeyOrg]
+	if a.org == "" {
+		a.org = os.Getenv(envKeyOrg)
+	}
+	if a.org == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyOrg)
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple runs the Flux query, returning a metric stream for each
+// table within the result. The query range is available to the query as
+// v.timeRangeStart and v.timeRangeStop, matching InfluxDB dashboards.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying InfluxDB", "query", q, "range", r)
+
+	body, err := json.Marshal(&queryRequest{
+		Query: withTimeRange(q, r),
+		Type:  "flux",
+		Dialect: queryDialect{
+			Header:      true,
+			Annotations: []string{"datatype", "group", "default"},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	u := a.address + "/api/v2/query?" + url.Values{"org": {a.org}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Token "+a.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from influxdb: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error querying metrics from influxdb: %v", responseError(resp))
+	}
+
+	results, err := parseFluxCSV(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse influxdb response: %v", err)
+	}
+
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from influxdb, try a wider query window")
+	}
+	return results, nil
+}
+
+type queryRequest struct {
+	Query   string       `json:"query"`
+	Type    string       `json:"type"`
+	Dialect queryDialect `json:"dialect"`
+}
+
+type queryDialect struct {
+	Header      bool     `json:"header"`
+	Annotations []string `json:"annotations"`
+}
+
+// withTimeRange defines the v.timeRangeStart and v.timeRangeStop options
+// within the Flux query. Flux requires imports to come first, so the option
+// is added after any leading import statements.
+func withTimeRange(q string, r sdk.TimeRange) string {
+	option := fmt.Sprintf("option v = {timeRangeStart: %s, timeRangeStop: %s}\n",
+		r.From.UTC().Format(time.RFC3339Nano), r.To.UTC().Format(time.RFC3339Nano))
+
+	lines := strings.SplitAfter(q, "\n")
+	i := 0
+	for ; i < len(lines); i++ {
+		l := strings.TrimSpace(lines[i])
+		if l != "" && !strings.HasPrefix(l, "//") && !strings.HasPrefix(l, "import ") {
+			break
+		}
+	}
+
+	head := strings.Join(lines[:i], "")
+	if head != "" && !strings.HasSuffix(head, "\n") {
+		head += "\n"
+	}
+	return head + option + strings.Join(lines[i:], "")
+}
+
+// responseError converts an InfluxDB error response into an error, using
+// the message of the JSON body if possible.
+func responseError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var body struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(b, &body); err == nil && body.Message != "" {
+		return fmt.Errorf("%s (%d): %s", body.Code, resp.StatusCode, body.Message)
+	}
+	return fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+}
@@ -0,0 +1,222 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		env           map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing address",
+			config:        map[string]string{},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "missing token",
+			config:        map[string]string{"address": "http://127.0.0.1:8086", "org": "ops"},
+			expectedError: `"token" config value cannot be empty`,
+		},
+		{
+			name:          "missing org",
+			config:        map[string]string{"address": "http://127.0.0.1:8086", "token": "secret"},
+			expectedError: `"org" config value cannot be empty`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"address": "http://127.0.0.1:8086", "token": "secret", "org": "ops", "skip_verify": "maybe"},
+			expectedError: `failed to parse TLS configuration`,
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"address": "http://127.0.0.1:8086", "token": "secret", "org": "ops", "timeout": "30s"},
+		},
+		{
+			name:   "token and org from env",
+			config: map[string]string{"address": "http://127.0.0.1:8086"},
+			env:    map[string]string{"INFLUX_TOKEN": "secret", "INFLUX_ORG": "ops"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("INFLUX_TOKEN", "")
+			t.Setenv("INFLUX_ORG", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+			assert.Equal(t, "secret", apmPlugin.token)
+			assert.Equal(t, "ops", apmPlugin.org)
+		})
+	}
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	query := `from(bucket: "telegraf")
+  |> range(start: v.timeRangeStart, stop: v.timeRangeStop)
+  |> filter(fn: (r) => r._measurement == "cpu")`
+
+	timeRange := sdk.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC),
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/query", r.URL.Path)
+		assert.Equal(t, "ops", r.URL.Query().Get("org"))
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+
+		var req queryRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "flux", req.Type)
+		assert.Equal(t, "option v = {timeRangeStart: 2024-01-01T00:00:00Z, timeRangeStop: 2024-01-01T00:05:00Z}\n"+query, req.Query)
+
+		http.ServeFile(w, r, path.Join("./test-fixtures", "query_200.csv"))
+	}))
+	defer srv.Close()
+
+	plugin := NewInfluxDBPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL + "/", "token": "secret", "org": "ops"}))
+
+	metrics, err := plugin.QueryMultiple(query, timeRange)
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{
+			{Timestamp: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), Value: 41.5},
+			{Timestamp: time.Date(2024, 1, 1, 0, 3, 0, 0, time.UTC), Value: 43.25},
+		},
+		{
+			{Timestamp: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), Value: 12},
+		},
+	}, metrics)
+
+	_, err = plugin.Query(query, timeRange)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"invalid","message":"compilation failed: error at @1:1-1:5: undefined identifier frum"}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewInfluxDBPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "token": "secret", "org": "ops"}))
+
+	_, err := plugin.Query(`frum(bucket: "telegraf")`, sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()})
+	assert.EqualError(t, err, "error querying metrics from influxdb: invalid (400): compilation failed: error at @1:1-1:5: undefined identifier frum")
+}
+
+func Test_withTimeRange(t *testing.T) {
+	r := sdk.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC),
+	}
+	option := "option v = {timeRangeStart: 2024-01-01T00:00:00Z, timeRangeStop: 2024-01-01T00:05:00Z}\n"
+
+	assert.Equal(t, option+`from(bucket: "a")`, withTimeRange(`from(bucket: "a")`, r))
+	assert.Equal(t,
+		"// CPU usage\nimport \"strings\"\n\n"+option+`from(bucket: "a")`,
+		withTimeRange("// CPU usage\nimport \"strings\"\n\nfrom(bucket: \"a\")", r))
+	assert.Equal(t, "import \"strings\"\n"+option, withTimeRange(`import "strings"`, r))
+}
+
+func Test_parseFluxCSV(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		expected      []sdk.TimestampedMetrics
+		expectedError string
+	}{
+		{
+			name: "multiple results",
+			input: `#datatype,string,long,dateTime:RFC3339,long
+#group,false,false,false,false
+#default,a,,,
+,result,table,_time,_value
+,,0,2024-01-01T00:01:00Z,1
+
+#datatype,string,long,dateTime:RFC3339,long
+#group,false,false,false,false
+#default,b,,,
+,result,table,_time,_value
+,,0,2024-01-01T00:01:00Z,2
+`,
+			expected: []sdk.TimestampedMetrics{
+				{{Timestamp: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), Value: 1}},
+				{{Timestamp: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), Value: 2}},
+			},
+		},
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name: "missing value column",
+			input: `#datatype,string,long,dateTime:RFC3339
+,result,table,_time
+,,0,2024-01-01T00:01:00Z
+`,
+			expectedError: `result does not contain the "_value" column`,
+		},
+		{
+			name: "invalid value",
+			input: `#datatype,string,long,dateTime:RFC3339,boolean
+,result,table,_time,_value
+,,0,2024-01-01T00:01:00Z,true
+`,
+			expectedError: `failed to parse _value "true"`,
+		},
+		{
+			name: "error",
+			input: `#datatype,string,string
+#group,true,true
+#default,,
+,error,reference
+,panic: runtime error,
+`,
+			expectedError: "panic: runtime error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics, err := parseFluxCSV(strings.NewReader(tc.input))
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, metrics)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "instana"
+
+	// configKeyAddress is the address of the Instana tenant unit, such as
+	// "https://unit-tenant.instana.io".
+	configKeyAddress = "address"
+
+	// configKeyAPIToken is the Instana API token used to authenticate
+	// queries. It requires access to the infrastructure and application
+	// monitoring APIs.
+	configKeyAPIToken = "api_token"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewInstanaPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the Instana implementation of the apm.APM interface, which
+// runs queries using the infrastructure and application monitoring REST
+// APIs.
+type APMPlugin struct {
+	client   *http.Client
+	logger   hclog.Logger
+	address  string
+	apiToken string
+	timeout  time.Duration
+}
+
+func NewInstanaPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	addr := config[configKeyAddress]
+	if addr == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("%q must be an absolute URL", configKeyAddress)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	a.apiToken = config[configKeyAPIToken]
+	if a.apiToken == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAPIToken)
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple runs the query, returning a metric stream for each snapshot
+// or application in the response.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	parsed, err := parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	a.logger.Debug("querying Instana", "query", q, "range", r)
+
+	path, body := parsed.request(r)
+	var resp metricsResponse
+	if err := a.post(path, body, &resp); err != nil {
+		return nil, fmt.Errorf("error querying metrics from instana: %v", err)
+	}
+
+	results := resp.metrics(parsed)
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from instana, try a wider query window")
+	}
+	return results, nil
+}
+
+// post sends the request body as JSON to the API path, decoding the response
+// into out.
+func (a *APMPlugin) post(path string, body interface{}, out interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.address+path, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "apiToken "+a.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
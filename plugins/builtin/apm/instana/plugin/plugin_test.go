@@ -0,0 +1,259 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing address",
+			config:        map[string]string{"api_token": "token"},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "relative address",
+			config:        map[string]string{"address": "unit-tenant.instana.io", "api_token": "token"},
+			expectedError: `"address" must be an absolute URL`,
+		},
+		{
+			name:          "missing api token",
+			config:        map[string]string{"address": "https://unit-tenant.instana.io"},
+			expectedError: `"api_token" config value cannot be empty`,
+		},
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"address": "https://unit-tenant.instana.io", "api_token": "token", "timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"address": "https://unit-tenant.instana.io", "api_token": "token", "skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"address": "https://unit-tenant.instana.io/", "api_token": "token", "timeout": "30s"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+			assert.Equal(t, "https://unit-tenant.instana.io", apmPlugin.address)
+		})
+	}
+}
+
+func Test_parseQuery(t *testing.T) {
+	testCases := []struct {
+		input         string
+		expected      *query
+		expectedError string
+	}{
+		{
+			input:    "infrastructure:host:cpu.used",
+			expected: &query{kind: "infrastructure", plugin: "host", metric: "cpu.used"},
+		},
+		{
+			input: "infrastructure:nomadClient:memory.used:entity.nomad.client.datacenter:dc1",
+			expected: &query{
+				kind:   "infrastructure",
+				plugin: "nomadClient",
+				metric: "memory.used",
+				focus:  "entity.nomad.client.datacenter:dc1",
+			},
+		},
+		{
+			input: "application:3Bf8xXayQ3G8E2dSWYJ1UQ:latency:p90",
+			expected: &query{
+				kind:          "application",
+				applicationID: "3Bf8xXayQ3G8E2dSWYJ1UQ",
+				metric:        "latency",
+				aggregation:   "P90",
+			},
+		},
+		{
+			input:         "cpu.used",
+			expectedError: "expected query in the format <type>:<parameters>",
+		},
+		{
+			input:         "infrastructure:host",
+			expectedError: "expected infrastructure query in the format infrastructure:<plugin>:<metric>[:<query>]",
+		},
+		{
+			input:         "application:3Bf8xXayQ3G8E2dSWYJ1UQ:latency",
+			expectedError: "expected application query in the format application:<application id>:<metric>:<aggregation>",
+		},
+		{
+			input:         "website:abc:pageLoads:sum",
+			expectedError: `invalid query type "website", must be "infrastructure" or "application"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			q, err := parseQuery(tc.input)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, q)
+		})
+	}
+}
+
+func Test_selectRollup(t *testing.T) {
+	assert.Equal(t, 1, selectRollup(30*time.Second))
+	assert.Equal(t, 1, selectRollup(10*time.Minute))
+	assert.Equal(t, 5, selectRollup(30*time.Minute))
+	assert.Equal(t, 60, selectRollup(time.Hour))
+	assert.Equal(t, 3600, selectRollup(30*24*time.Hour))
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	r := sdk.TimeRange{From: time.Unix(1704067200, 0), To: time.Unix(1704067500, 0)}
+
+	testCases := []struct {
+		name            string
+		query           string
+		fixture         string
+		expectedPath    string
+		expectedBody    string
+		expectedMetrics sdk.TimestampedMetrics
+	}{
+		{
+			name:         "infrastructure",
+			query:        "infrastructure:host:cpu.used:entity.tag:nomad",
+			fixture:      "infrastructure_200.json",
+			expectedPath: "/api/infrastructure-monitoring/metrics",
+			expectedBody: `{
+  "timeFrame": {"to": 1704067500000, "windowSize": 300000},
+  "rollup": 1,
+  "plugin": "host",
+  "query": "entity.tag:nomad",
+  "metrics": ["cpu.used"]
+}`,
+			// Null values should be skipped.
+			expectedMetrics: sdk.TimestampedMetrics{
+				{Timestamp: time.UnixMilli(1704067260000), Value: 0.42},
+				{Timestamp: time.UnixMilli(1704067380000), Value: 0.55},
+			},
+		},
+		{
+			name:         "application",
+			query:        "application:3Bf8xXayQ3G8E2dSWYJ1UQ:latency:p90",
+			fixture:      "application_200.json",
+			expectedPath: "/api/application-monitoring/metrics/applications",
+			expectedBody: `{
+  "timeFrame": {"to": 1704067500000, "windowSize": 300000},
+  "applicationId": "3Bf8xXayQ3G8E2dSWYJ1UQ",
+  "metrics": [{"metric": "latency", "aggregation": "P90", "granularity": 1}]
+}`,
+			// Metrics should be sorted by timestamp.
+			expectedMetrics: sdk.TimestampedMetrics{
+				{Timestamp: time.UnixMilli(1704067260000), Value: 125.5},
+				{Timestamp: time.UnixMilli(1704067320000), Value: 180},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, tc.expectedPath, r.URL.Path)
+				assert.Equal(t, "apiToken secret", r.Header.Get("Authorization"))
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+				var body json.RawMessage
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.JSONEq(t, tc.expectedBody, string(body))
+
+				http.ServeFile(w, r, path.Join("./test-fixtures", tc.fixture))
+			}))
+			defer srv.Close()
+
+			plugin := NewInstanaPlugin(hclog.NewNullLogger())
+			require.NoError(t, plugin.SetConfig(map[string]string{
+				"address":   srv.URL,
+				"api_token": "secret",
+			}))
+
+			metrics, err := plugin.Query(tc.query, r)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedMetrics, metrics)
+		})
+	}
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"items": [
+  {"snapshotId": "a", "metrics": {"cpu.used": [[1704067260000, 0.1]]}},
+  {"snapshotId": "b", "metrics": {"cpu.used": [[1704067260000, null]]}},
+  {"snapshotId": "c", "metrics": {"cpu.used": [[1704067260000, 0.3]]}}
+]}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewInstanaPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "api_token": "secret"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	// Snapshots without any values should be skipped.
+	metrics, err := plugin.QueryMultiple("infrastructure:host:cpu.used", r)
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{{Timestamp: time.UnixMilli(1704067260000), Value: 0.1}},
+		{{Timestamp: time.UnixMilli(1704067260000), Value: 0.3}},
+	}, metrics)
+
+	_, err = plugin.Query("infrastructure:host:cpu.used", r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"errors":["Unauthorized"]}` + "\n"))
+	}))
+	defer srv.Close()
+
+	plugin := NewInstanaPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "api_token": "invalid"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	_, err := plugin.Query("infrastructure:host:cpu.used", r)
+	assert.EqualError(t, err, `error querying metrics from instana: unexpected response code 401: {"errors":["Unauthorized"]}`)
+
+	_, err = plugin.Query("cpu.used", r)
+	assert.EqualError(t, err, "failed to parse query: expected query in the format <type>:<parameters>")
+}
@@ -0,0 +1,192 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	queryTypeInfrastructure = "infrastructure"
+	queryTypeApplication    = "application"
+
+	infrastructureMetricsPath = "/api/infrastructure-monitoring/metrics"
+	applicationMetricsPath    = "/api/application-monitoring/metrics/applications"
+
+	// maxDatapoints is the maximum number of datapoints Instana returns for
+	// each metric, which limits the rollup that can be used for a window.
+	maxDatapoints = 600
+)
+
+// rollups are the metric resolutions, in seconds, supported by Instana.
+var rollups = []int{1, 5, 60, 300, 3600}
+
+// query is a parsed Instana query. Queries use one of the formats:
+//
+//	infrastructure:<plugin>:<metric>[:<dynamic focus query>]
+//	application:<application id>:<metric>:<aggregation>
+//
+// such as "infrastructure:host:cpu.used:entity.zone:prod" or
+// "application:3Bf8xXayQ3G8E2dSWYJ1UQ:latency:p90".
+type query struct {
+	kind string
+
+	// plugin and focus are the infrastructure entity type and the dynamic
+	// focus query used to select snapshots.
+	plugin string
+	focus  string
+
+	// applicationID and aggregation select the application metric.
+	applicationID string
+	aggregation   string
+
+	metric string
+}
+
+func parseQuery(q string) (*query, error) {
+	kind, rest, ok := strings.Cut(q, ":")
+	if !ok {
+		return nil, errors.New("expected query in the format <type>:<parameters>")
+	}
+
+	switch kind {
+	case queryTypeInfrastructure:
+		parts := strings.SplitN(rest, ":", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.New("expected infrastructure query in the format infrastructure:<plugin>:<metric>[:<query>]")
+		}
+		parsed := &query{kind: kind, plugin: parts[0], metric: parts[1]}
+		if len(parts) == 3 {
+			parsed.focus = parts[2]
+		}
+		return parsed, nil
+
+	case queryTypeApplication:
+		parts := strings.Split(rest, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, errors.New("expected application query in the format application:<application id>:<metric>:<aggregation>")
+		}
+		return &query{
+			kind:          kind,
+			applicationID: parts[0],
+			metric:        parts[1],
+			aggregation:   strings.ToUpper(parts[2]),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid query type %q, must be %q or %q", kind, queryTypeInfrastructure, queryTypeApplication)
+	}
+}
+
+// timeFrame is the Instana representation of a query time range.
+type timeFrame struct {
+	To         int64 `json:"to"`
+	WindowSize int64 `json:"windowSize"`
+}
+
+type infrastructureRequest struct {
+	TimeFrame timeFrame `json:"timeFrame"`
+	Rollup    int       `json:"rollup"`
+	Plugin    string    `json:"plugin"`
+	Query     string    `json:"query,omitempty"`
+	Metrics   []string  `json:"metrics"`
+}
+
+type applicationRequest struct {
+	TimeFrame     timeFrame           `json:"timeFrame"`
+	ApplicationID string              `json:"applicationId"`
+	Metrics       []applicationMetric `json:"metrics"`
+}
+
+type applicationMetric struct {
+	Metric      string `json:"metric"`
+	Aggregation string `json:"aggregation"`
+	Granularity int    `json:"granularity"`
+}
+
+// request returns the API path and body used to run the query over the
+// time range.
+func (q *query) request(r sdk.TimeRange) (string, interface{}) {
+	window := r.To.Sub(r.From)
+	tf := timeFrame{To: r.To.UnixMilli(), WindowSize: window.Milliseconds()}
+	rollup := selectRollup(window)
+
+	if q.kind == queryTypeApplication {
+		return applicationMetricsPath, applicationRequest{
+			TimeFrame:     tf,
+			ApplicationID: q.applicationID,
+			Metrics: []applicationMetric{{
+				Metric:      q.metric,
+				Aggregation: q.aggregation,
+				Granularity: rollup,
+			}},
+		}
+	}
+
+	return infrastructureMetricsPath, infrastructureRequest{
+		TimeFrame: tf,
+		Rollup:    rollup,
+		Plugin:    q.plugin,
+		Query:     q.focus,
+		Metrics:   []string{q.metric},
+	}
+}
+
+// selectRollup returns the finest rollup which returns the whole window
+// within the datapoint limit.
+func selectRollup(window time.Duration) int {
+	for _, r := range rollups {
+		if window <= time.Duration(r*maxDatapoints)*time.Second {
+			return r
+		}
+	}
+	return rollups[len(rollups)-1]
+}
+
+// metricsResponse is the body of both the infrastructure and application
+// metrics responses. Each item is a snapshot or application, and its metrics
+// are keyed by name, with application metric names including the
+// aggregation, such as "latency.p90".
+type metricsResponse struct {
+	Items []struct {
+		Metrics map[string][][]*float64 `json:"metrics"`
+	} `json:"items"`
+}
+
+// metrics converts the response into metrics, one stream per item. Null
+// values are skipped.
+func (resp *metricsResponse) metrics(q *query) []sdk.TimestampedMetrics {
+	var results []sdk.TimestampedMetrics
+
+	for _, item := range resp.Items {
+		points, ok := item.Metrics[q.metric]
+		if !ok && q.kind == queryTypeApplication {
+			points = item.Metrics[q.metric+"."+strings.ToLower(q.aggregation)]
+		}
+
+		var result sdk.TimestampedMetrics
+		for _, p := range points {
+			if len(p) != 2 || p[0] == nil || p[1] == nil {
+				continue
+			}
+			result = append(result, sdk.TimestampedMetric{
+				Timestamp: time.UnixMilli(int64(*p[0])),
+				Value:     *p[1],
+			})
+		}
+
+		if len(result) > 0 {
+			sort.Stable(result)
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
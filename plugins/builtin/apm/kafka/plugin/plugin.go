@@ -0,0 +1,255 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "kafka"
+
+	// configKeyBrokers is a comma separated list of seed broker addresses.
+	configKeyBrokers = "brokers"
+
+	// configKeyClientID is the client ID sent to the brokers.
+	configKeyClientID          = "client_id"
+	configValueClientIDDefault = "nomad-autoscaler"
+
+	// configKeySASLMechanism configures SASL authentication, using one of
+	// PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512 along with the username and
+	// password.
+	configKeySASLMechanism = "sasl_mechanism"
+	configKeySASLUsername  = "sasl_username"
+	configKeySASLPassword  = "sasl_password"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewKafkaPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// lagClient is the subset of the Kafka admin client used by the plugin, which
+// allows it to be mocked in tests.
+type lagClient interface {
+	Lag(ctx context.Context, groups ...string) (kadm.DescribedGroupLags, error)
+}
+
+// APMPlugin is the Kafka implementation of the apm.APM interface, which
+// calculates consumer group lag directly from the brokers.
+type APMPlugin struct {
+	logger hclog.Logger
+
+	// lock protects the fields below, which are updated by SetConfig.
+	lock    sync.Mutex
+	kgo     *kgo.Client
+	client  lagClient
+	timeout time.Duration
+}
+
+func NewKafkaPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	var brokers []string
+	for _, b := range strings.Split(config[configKeyBrokers], ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	if len(brokers) == 0 {
+		return fmt.Errorf("%q config value cannot be empty", configKeyBrokers)
+	}
+
+	timeout := configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		timeout = d
+	}
+
+	clientID := config[configKeyClientID]
+	if clientID == "" {
+		clientID = configValueClientIDDefault
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(brokers...),
+		kgo.ClientID(clientID),
+	}
+
+	saslOpt, err := saslOption(config)
+	if err != nil {
+		return err
+	}
+	if saslOpt != nil {
+		opts = append(opts, saslOpt)
+	}
+
+	tlsConfig, err := tlsutil.NewOptionalClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+	if tlsConfig != nil {
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create Kafka client: %v", err)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.kgo != nil {
+		a.kgo.Close()
+	}
+	a.kgo = cl
+	a.client = kadm.NewClient(cl)
+	a.timeout = timeout
+
+	return nil
+}
+
+// saslOption returns the client SASL option, or nil if SASL has not been
+// configured.
+func saslOption(config map[string]string) (kgo.Opt, error) {
+	mechanism := strings.ToUpper(config[configKeySASLMechanism])
+	if mechanism == "" {
+		return nil, nil
+	}
+
+	user, pass := config[configKeySASLUsername], config[configKeySASLPassword]
+	if user == "" {
+		return nil, fmt.Errorf("%q must be set when using SASL", configKeySASLUsername)
+	}
+
+	switch mechanism {
+	case "PLAIN":
+		return kgo.SASL(plain.Auth{User: user, Pass: pass}.AsMechanism()), nil
+	case "SCRAM-SHA-256":
+		return kgo.SASL(scram.Auth{User: user, Pass: pass}.AsSha256Mechanism()), nil
+	case "SCRAM-SHA-512":
+		return kgo.SASL(scram.Auth{User: user, Pass: pass}.AsSha512Mechanism()), nil
+	default:
+		return nil, fmt.Errorf("unsupported %q %q", configKeySASLMechanism, config[configKeySASLMechanism])
+	}
+}
+
+// Close closes the Kafka client. It implements io.Closer so the plugin
+// manager closes the client when the plugin is removed.
+func (a *APMPlugin) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.kgo != nil {
+		a.kgo.Close()
+		a.kgo, a.client = nil, nil
+	}
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple calculates the current lag of the consumer group. Lag is a
+// point in time value, so each metric stream contains a single point and the
+// time range is not used.
+func (a *APMPlugin) QueryMultiple(q string, _ sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying Kafka consumer group lag", "query", q)
+
+	parsed, err := parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	a.lock.Lock()
+	client, timeout := a.client, a.timeout
+	a.lock.Unlock()
+
+	if client == nil {
+		return nil, errors.New("plugin has not been configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	lags, err := client.Lag(ctx, parsed.group)
+	if err != nil {
+		return nil, fmt.Errorf("error querying lag from kafka: %v", err)
+	}
+
+	groupLag, ok := lags[parsed.group]
+	if !ok {
+		return nil, fmt.Errorf("error querying lag from kafka: group %q not returned", parsed.group)
+	}
+	if err := groupLag.Error(); err != nil {
+		return nil, fmt.Errorf("error querying lag from kafka: %v", err)
+	}
+
+	results, err := parsed.metrics(groupLag.Lag, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		a.logger.Warn("no partitions found for consumer group", "group", parsed.group, "state", groupLag.State)
+	}
+	return results, nil
+}
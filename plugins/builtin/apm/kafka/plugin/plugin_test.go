@@ -0,0 +1,239 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kadm"
+)
+
+type mockLagClient struct {
+	lags kadm.DescribedGroupLags
+	err  error
+}
+
+func (m *mockLagClient) Lag(_ context.Context, _ ...string) (kadm.DescribedGroupLags, error) {
+	return m.lags, m.err
+}
+
+func testGroupLag(group string) kadm.DescribedGroupLag {
+	return kadm.DescribedGroupLag{
+		Group: group,
+		State: "Stable",
+		Lag: kadm.GroupLag{
+			"orders": {
+				0: {Topic: "orders", Partition: 0, Lag: 10},
+				1: {Topic: "orders", Partition: 1, Lag: 30},
+			},
+			"payments": {
+				0: {Topic: "payments", Partition: 0, Lag: 5},
+			},
+		},
+	}
+}
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing brokers",
+			config:        map[string]string{"brokers": " , "},
+			expectedError: `"brokers" config value cannot be empty`,
+		},
+		{
+			name:          "unsupported sasl mechanism",
+			config:        map[string]string{"brokers": "127.0.0.1:9092", "sasl_mechanism": "GSSAPI", "sasl_username": "user"},
+			expectedError: `unsupported "sasl_mechanism" "GSSAPI"`,
+		},
+		{
+			name:          "missing sasl username",
+			config:        map[string]string{"brokers": "127.0.0.1:9092", "sasl_mechanism": "plain"},
+			expectedError: `"sasl_username" must be set when using SASL`,
+		},
+		{
+			name:          "invalid tls_enabled",
+			config:        map[string]string{"brokers": "127.0.0.1:9092", "tls_enabled": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name: "valid",
+			config: map[string]string{
+				"brokers":        "127.0.0.1:9092, 127.0.0.1:9093",
+				"sasl_mechanism": "SCRAM-SHA-512",
+				"sasl_username":  "user",
+				"sasl_password":  "pass",
+				"tls_enabled":    "true",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			defer apmPlugin.Close()
+
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+		})
+	}
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	apmPlugin := APMPlugin{
+		logger:  hclog.NewNullLogger(),
+		timeout: time.Second,
+		client:  &mockLagClient{lags: kadm.DescribedGroupLags{"billing": testGroupLag("billing")}},
+	}
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	testCases := []struct {
+		query    string
+		expected []float64
+	}{
+		{query: "billing", expected: []float64{45}},
+		{query: "sum:billing/orders", expected: []float64{40}},
+		{query: "max:billing", expected: []float64{30}},
+		{query: "min:billing", expected: []float64{5}},
+		{query: "avg:billing/orders", expected: []float64{20}},
+		{query: "billing/orders/1", expected: []float64{30}},
+		{query: "each:billing", expected: []float64{10, 30, 5}},
+		{query: "billing/inventory", expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			metrics, err := apmPlugin.QueryMultiple(tc.query, r)
+			require.NoError(t, err)
+
+			var values []float64
+			for _, m := range metrics {
+				require.Len(t, m, 1)
+				values = append(values, m[0].Value)
+			}
+			assert.Equal(t, tc.expected, values)
+		})
+	}
+
+	_, err := apmPlugin.Query("each:billing", r)
+	assert.EqualError(t, err, "query returned 3 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	groupLag := testGroupLag("billing")
+	groupLag.Lag["payments"][0] = kadm.GroupMemberLag{Topic: "payments", Lag: -1, Err: errors.New("not leader")}
+
+	testCases := []struct {
+		name          string
+		client        *mockLagClient
+		query         string
+		expectedError string
+	}{
+		{
+			name:          "request error",
+			client:        &mockLagClient{err: errors.New("connection refused")},
+			query:         "billing",
+			expectedError: "error querying lag from kafka: connection refused",
+		},
+		{
+			name:          "group error",
+			client:        &mockLagClient{lags: kadm.DescribedGroupLags{"billing": {Group: "billing", FetchErr: errors.New("not authorized")}}},
+			query:         "billing",
+			expectedError: "error querying lag from kafka: not authorized",
+		},
+		{
+			name:          "partition error",
+			client:        &mockLagClient{lags: kadm.DescribedGroupLags{"billing": groupLag}},
+			query:         "billing",
+			expectedError: `failed to calculate lag of topic "payments" partition 0: not leader`,
+		},
+		{
+			name:   "partition error filtered",
+			client: &mockLagClient{lags: kadm.DescribedGroupLags{"billing": groupLag}},
+			query:  "billing/orders",
+		},
+		{
+			name:          "invalid query",
+			client:        &mockLagClient{},
+			query:         "billing/orders/first",
+			expectedError: `failed to parse query: invalid partition "first"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), timeout: time.Second, client: tc.client}
+
+			_, err := apmPlugin.Query(tc.query, r)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_parseQuery(t *testing.T) {
+	testCases := []struct {
+		query         string
+		expected      *query
+		expectedError string
+	}{
+		{
+			query:    "billing",
+			expected: &query{aggregation: "sum", group: "billing", partition: -1},
+		},
+		{
+			query:    "max:billing/orders/3",
+			expected: &query{aggregation: "max", group: "billing", topic: "orders", partition: 3},
+		},
+		{
+			query:    "app:billing/orders",
+			expected: &query{aggregation: "sum", group: "app:billing", topic: "orders", partition: -1},
+		},
+		{
+			query:         "sum:",
+			expectedError: "consumer group cannot be empty",
+		},
+		{
+			query:         "billing//1",
+			expectedError: "topic cannot be empty",
+		},
+		{
+			query:         "billing/orders/1/2",
+			expectedError: `invalid query "billing/orders/1/2"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := parseQuery(tc.query)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, q)
+		})
+	}
+}
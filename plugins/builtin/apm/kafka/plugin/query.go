@@ -0,0 +1,135 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/twmb/franz-go/pkg/kadm"
+)
+
+const (
+	aggregationSum  = "sum"
+	aggregationMax  = "max"
+	aggregationMin  = "min"
+	aggregationAvg  = "avg"
+	aggregationEach = "each"
+)
+
+// query is a parsed check query.
+type query struct {
+	aggregation string
+	group       string
+	topic       string
+	partition   int32
+}
+
+// parseQuery parses the check query, in the form:
+//
+//	[<aggregation>:]<group>[/<topic>[/<partition>]]
+//
+// The lag of the consumer group partitions, optionally filtered by topic and
+// partition, is combined using sum (the default), max, min or avg. The each
+// aggregation returns a metric stream for each partition instead.
+func parseQuery(q string) (*query, error) {
+	q = strings.TrimSpace(q)
+
+	parsed := &query{aggregation: aggregationSum, partition: -1}
+
+	// Consumer group names may contain colons, so the prefix is only used as
+	// the aggregation if it is supported.
+	if agg, rest, ok := strings.Cut(q, ":"); ok {
+		switch agg {
+		case aggregationSum, aggregationMax, aggregationMin, aggregationAvg, aggregationEach:
+			parsed.aggregation = agg
+			q = rest
+		}
+	}
+
+	parts := strings.Split(q, "/")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid query %q", q)
+	}
+
+	parsed.group = parts[0]
+	if parsed.group == "" {
+		return nil, errors.New("consumer group cannot be empty")
+	}
+
+	if len(parts) > 1 {
+		parsed.topic = parts[1]
+		if parsed.topic == "" {
+			return nil, errors.New("topic cannot be empty")
+		}
+	}
+
+	if len(parts) > 2 {
+		p, err := strconv.ParseInt(parts[2], 10, 32)
+		if err != nil || p < 0 {
+			return nil, fmt.Errorf("invalid partition %q", parts[2])
+		}
+		parsed.partition = int32(p)
+	}
+
+	return parsed, nil
+}
+
+// metrics converts the group lag of the matching partitions into metric
+// streams, using the passed timestamp. An error is returned if the lag of any
+// matching partition could not be calculated, rather than under reporting
+// the lag.
+func (q *query) metrics(lag kadm.GroupLag, ts time.Time) ([]sdk.TimestampedMetrics, error) {
+	var values []float64
+
+	for _, l := range lag.Sorted() {
+		if q.topic != "" && l.Topic != q.topic {
+			continue
+		}
+		if q.partition >= 0 && l.Partition != q.partition {
+			continue
+		}
+		if l.Err != nil {
+			return nil, fmt.Errorf("failed to calculate lag of topic %q partition %d: %v", l.Topic, l.Partition, l.Err)
+		}
+		values = append(values, float64(l.Lag))
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	if q.aggregation == aggregationEach {
+		results := make([]sdk.TimestampedMetrics, len(values))
+		for i, v := range values {
+			results[i] = sdk.TimestampedMetrics{{Timestamp: ts, Value: v}}
+		}
+		return results, nil
+	}
+
+	result := values[0]
+	for _, v := range values[1:] {
+		switch q.aggregation {
+		case aggregationSum, aggregationAvg:
+			result += v
+		case aggregationMax:
+			if v > result {
+				result = v
+			}
+		case aggregationMin:
+			if v < result {
+				result = v
+			}
+		}
+	}
+	if q.aggregation == aggregationAvg {
+		result /= float64(len(values))
+	}
+
+	return []sdk.TimestampedMetrics{{{Timestamp: ts, Value: result}}}, nil
+}
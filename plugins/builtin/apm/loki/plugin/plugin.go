@@ -0,0 +1,244 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "loki"
+
+	// configKeyAddress is the address of the Loki HTTP API.
+	configKeyAddress = "address"
+
+	// configKeyTenantID sets the X-Scope-OrgID header used to select the
+	// tenant in multi-tenant Loki deployments.
+	configKeyTenantID = "tenant_id"
+
+	// configKeyBasicAuthUser and configKeyBasicAuthPassword configure basic
+	// auth.
+	configKeyBasicAuthUser     = "basic_auth_user"
+	configKeyBasicAuthPassword = "basic_auth_password"
+
+	// configKeyHeadersPrefix is the prefix used to indicate that a
+	// configuration value should be set as an HTTP header.
+	configKeyHeadersPrefix = "header_"
+
+	// configKeyStep is the query resolution step. If not set, Loki picks a
+	// step based on the query window.
+	configKeyStep = "step"
+
+	// configKeyTimeout is the maximum duration of a request.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+
+	// queryRangePath is the Loki API endpoint used for metric queries.
+	queryRangePath = "/loki/api/v1/query_range"
+
+	// maxResponseSize limits the size of response bodies read by the plugin.
+	maxResponseSize = 16 * 1024 * 1024
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewLokiPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the Grafana Loki implementation of the apm.APM interface,
+// which runs LogQL metric queries so scaling can be driven by signals
+// derived from logs.
+type APMPlugin struct {
+	client   *http.Client
+	config   map[string]string
+	logger   hclog.Logger
+	queryURL *url.URL
+	step     time.Duration
+	timeout  time.Duration
+}
+
+func NewLokiPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	addr := config[configKeyAddress]
+	if addr == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("%q must be an absolute URL", configKeyAddress)
+	}
+	a.queryURL = u.JoinPath(queryRangePath)
+
+	a.step = 0
+	if v := config[configKeyStep]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyStep, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("%q must be greater than zero", configKeyStep)
+		}
+		a.step = d
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple runs the LogQL metric query over the time range, such as
+// sum(rate({app="web"} |= "error" [1m])). Log queries which return log lines
+// rather than samples are not supported.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying Loki", "query", q, "range", r)
+
+	body, err := a.queryRange(q, r)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from loki: %v", err)
+	}
+
+	var resp queryResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	results, err := parseResponse(&resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from loki, try a wider query window")
+		return nil, nil
+	}
+	return results, nil
+}
+
+// queryRange performs the query_range request, returning the response body.
+func (a *APMPlugin) queryRange(q string, r sdk.TimeRange) ([]byte, error) {
+	params := url.Values{}
+	params.Set("query", q)
+	params.Set("start", strconv.FormatInt(r.From.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(r.To.UnixNano(), 10))
+	if a.step > 0 {
+		params.Set("step", strconv.FormatFloat(a.step.Seconds(), 'f', -1, 64))
+	}
+
+	u := *a.queryURL
+	u.RawQuery = params.Encode()
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	for k, v := range a.config {
+		if name, ok := strings.CutPrefix(k, configKeyHeadersPrefix); ok {
+			req.Header.Set(name, v)
+		}
+	}
+	if tenant := a.config[configKeyTenantID]; tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+	if user := a.config[configKeyBasicAuthUser]; user != "" {
+		req.SetBasicAuth(user, a.config[configKeyBasicAuthPassword])
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return nil, fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, msg)
+	}
+	return body, nil
+}
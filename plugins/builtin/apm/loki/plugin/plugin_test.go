@@ -0,0 +1,230 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing address",
+			config:        map[string]string{},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "invalid address",
+			config:        map[string]string{"address": "\n\n"},
+			expectedError: `failed to parse "address"`,
+		},
+		{
+			name:          "relative address",
+			config:        map[string]string{"address": "/loki"},
+			expectedError: `"address" must be an absolute URL`,
+		},
+		{
+			name:          "invalid step",
+			config:        map[string]string{"address": "http://127.0.0.1:3100", "step": "often"},
+			expectedError: `failed to parse "step"`,
+		},
+		{
+			name:          "zero step",
+			config:        map[string]string{"address": "http://127.0.0.1:3100", "step": "0s"},
+			expectedError: `"step" must be greater than zero`,
+		},
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"address": "http://127.0.0.1:3100", "timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"address": "http://127.0.0.1:3100", "skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"address": "http://127.0.0.1:3100/base"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+			assert.Equal(t, "http://127.0.0.1:3100/base/loki/api/v1/query_range", apmPlugin.queryURL.String())
+			assert.Equal(t, configValueTimeoutDefault, apmPlugin.timeout)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	r := sdk.TimeRange{From: time.Unix(1700000000, 0), To: time.Unix(1700000300, 0)}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/loki/api/v1/query_range", req.URL.Path)
+		assert.Equal(t, "team-a", req.Header.Get("X-Scope-OrgID"))
+		assert.Equal(t, "value", req.Header.Get("X-Custom"))
+
+		user, pass, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "autoscaler", user)
+		assert.Equal(t, "pass", pass)
+
+		qp := req.URL.Query()
+		assert.Equal(t, "1700000000000000000", qp.Get("start"))
+		assert.Equal(t, "1700000300000000000", qp.Get("end"))
+		assert.Equal(t, "60", qp.Get("step"))
+
+		switch qp.Get("query") {
+		case `sum(rate({app="web"} |= "error" [1m]))`:
+			http.ServeFile(w, req, path.Join("test-fixtures", "query_range_200.json"))
+		case `sum by (level) (rate({app="web"}[1m]))`:
+			http.ServeFile(w, req, path.Join("test-fixtures", "query_range_multiple.json"))
+		case `{app="web"}`:
+			http.ServeFile(w, req, path.Join("test-fixtures", "query_range_streams.json"))
+		case `sum(rate({app="none"}[1m]))`:
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "matrix", "result": []}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("parse error at line 1, col 1: syntax error\n"))
+		}
+	}))
+	defer ts.Close()
+
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{
+		"address":             ts.URL,
+		"tenant_id":           "team-a",
+		"header_X-Custom":     "value",
+		"basic_auth_user":     "autoscaler",
+		"basic_auth_password": "pass",
+		"step":                "1m",
+	}))
+
+	// Metrics are sorted by timestamp.
+	metrics, err := apmPlugin.Query(`sum(rate({app="web"} |= "error" [1m]))`, r)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: time.Unix(1700000000, 0), Value: 0.5},
+		{Timestamp: time.Unix(1700000060, 0), Value: 0.75},
+		{Timestamp: time.Unix(1700000120, 5e8), Value: 1.25},
+	}, metrics)
+
+	multiple, err := apmPlugin.QueryMultiple(`sum by (level) (rate({app="web"}[1m]))`, r)
+	require.NoError(t, err)
+	assert.Len(t, multiple, 2)
+
+	_, err = apmPlugin.Query(`sum by (level) (rate({app="web"}[1m]))`, r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+
+	metrics, err = apmPlugin.Query(`sum(rate({app="none"}[1m]))`, r)
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	_, err = apmPlugin.Query(`{app="web"}`, r)
+	assert.ErrorContains(t, err, "query returned log lines")
+
+	_, err = apmPlugin.Query(`sum(rate(`, r)
+	assert.EqualError(t, err, "error querying metrics from loki: unexpected response code 400: parse error at line 1, col 1: syntax error")
+}
+
+func Test_parseResponse(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         *queryResponse
+		expected      []sdk.TimestampedMetrics
+		expectedError string
+	}{
+		{
+			name: "vector",
+			input: &queryResponse{
+				Status: "success",
+				Data: queryData{
+					ResultType: "vector",
+					Result:     []byte(`[{"metric": {}, "value": [1700000000, "3"]}]`),
+				},
+			},
+			expected: []sdk.TimestampedMetrics{{{Timestamp: time.Unix(1700000000, 0), Value: 3}}},
+		},
+		{
+			name:          "error status",
+			input:         &queryResponse{Status: "error", Error: "max query length exceeded"},
+			expectedError: "query failed: max query length exceeded",
+		},
+		{
+			name: "unsupported result type",
+			input: &queryResponse{
+				Status: "success",
+				Data:   queryData{ResultType: "scalar", Result: []byte(`[1700000000, "1"]`)},
+			},
+			expectedError: `result type "scalar" is not supported`,
+		},
+		{
+			name: "invalid value",
+			input: &queryResponse{
+				Status: "success",
+				Data: queryData{
+					ResultType: "vector",
+					Result:     []byte(`[{"metric": {}, "value": [1700000000, "many"]}]`),
+				},
+			},
+			expectedError: `invalid sample value "many"`,
+		},
+		{
+			name: "not a number",
+			input: &queryResponse{
+				Status: "success",
+				Data: queryData{
+					ResultType: "matrix",
+					Result:     []byte(`[{"metric": {}, "values": [[1700000000, "NaN"]]}]`),
+				},
+			},
+			expectedError: "query result value is not-a-number",
+		},
+		{
+			name: "invalid timestamp",
+			input: &queryResponse{
+				Status: "success",
+				Data: queryData{
+					ResultType: "matrix",
+					Result:     []byte(`[{"metric": {}, "values": [["now", "1"]]}]`),
+				},
+			},
+			expectedError: "invalid sample timestamp now",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseResponse(tc.input)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
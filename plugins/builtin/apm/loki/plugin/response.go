@@ -0,0 +1,126 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	resultTypeMatrix  = "matrix"
+	resultTypeVector  = "vector"
+	resultTypeStreams = "streams"
+)
+
+// queryResponse is the body of a Loki query response.
+type queryResponse struct {
+	Status string    `json:"status"`
+	Error  string    `json:"error"`
+	Data   queryData `json:"data"`
+}
+
+type queryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+// matrixResult is a series of a matrix result.
+type matrixResult struct {
+	Metric map[string]string `json:"metric"`
+	Values []samplePair      `json:"values"`
+}
+
+// vectorResult is a single sample of a vector result.
+type vectorResult struct {
+	Metric map[string]string `json:"metric"`
+	Value  samplePair        `json:"value"`
+}
+
+// samplePair is a [<unix seconds>, "<value>"] pair.
+type samplePair [2]interface{}
+
+// parseResponse converts the response into metrics, one stream per series.
+func parseResponse(resp *queryResponse) ([]sdk.TimestampedMetrics, error) {
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", resp.Error)
+	}
+
+	switch t := resp.Data.ResultType; t {
+	case resultTypeMatrix:
+		var series []matrixResult
+		if err := json.Unmarshal(resp.Data.Result, &series); err != nil {
+			return nil, fmt.Errorf("failed to decode matrix result: %v", err)
+		}
+
+		results := make([]sdk.TimestampedMetrics, 0, len(series))
+		for _, s := range series {
+			var metrics sdk.TimestampedMetrics
+			for _, v := range s.Values {
+				m, err := v.toMetric()
+				if err != nil {
+					return nil, err
+				}
+				metrics = append(metrics, m)
+			}
+			sort.Stable(metrics)
+			results = append(results, metrics)
+		}
+		return results, nil
+
+	case resultTypeVector:
+		var samples []vectorResult
+		if err := json.Unmarshal(resp.Data.Result, &samples); err != nil {
+			return nil, fmt.Errorf("failed to decode vector result: %v", err)
+		}
+
+		results := make([]sdk.TimestampedMetrics, 0, len(samples))
+		for _, s := range samples {
+			m, err := s.Value.toMetric()
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, sdk.TimestampedMetrics{m})
+		}
+		return results, nil
+
+	case resultTypeStreams:
+		return nil, errors.New(`query returned log lines, only metric queries such as rate({app="web"}[1m]) are supported`)
+
+	default:
+		return nil, fmt.Errorf("result type %q is not supported", t)
+	}
+}
+
+func (p samplePair) toMetric() (sdk.TimestampedMetric, error) {
+	ts, ok := p[0].(float64)
+	if !ok {
+		return sdk.TimestampedMetric{}, fmt.Errorf("invalid sample timestamp %v", p[0])
+	}
+
+	s, ok := p[1].(string)
+	if !ok {
+		return sdk.TimestampedMetric{}, fmt.Errorf("invalid sample value %v", p[1])
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return sdk.TimestampedMetric{}, fmt.Errorf("invalid sample value %q", s)
+	}
+	if math.IsNaN(value) {
+		return sdk.TimestampedMetric{}, errors.New("query result value is not-a-number")
+	}
+
+	sec, frac := math.Modf(ts)
+	return sdk.TimestampedMetric{
+		Timestamp: time.Unix(int64(sec), int64(frac*1e9)),
+		Value:     value,
+	}, nil
+}
@@ -0,0 +1,352 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "nats"
+
+	// configKeyAddress is a comma separated list of NATS server URLs.
+	configKeyAddress          = "address"
+	configValueAddressDefault = nats.DefaultURL
+
+	// configKeyCredsFile is the path to a user credentials file, while
+	// configKeyToken and configKeyUsername and configKeyPassword configure
+	// token or password authentication.
+	configKeyCredsFile = "creds_file"
+	configKeyToken     = "token"
+	configKeyUsername  = "username"
+	configKeyPassword  = "password"
+
+	// configKeyCACert is the path to the CA certificate the client should
+	// use, and configKeySkipVerify disables TLS certificate verification.
+	configKeyCACert     = "ca_cert"
+	configKeySkipVerify = "skip_verify"
+
+	// configKeyDomain is the JetStream domain, used when querying a
+	// JetStream deployment through a leaf node.
+	configKeyDomain = "domain"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+
+	// allConsumers is the query consumer name used to sum the metric across
+	// all consumers of the stream.
+	allConsumers = "*"
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewNATSPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// streamMetrics and consumerMetrics map the query metric names to the value
+// of the stream or consumer.
+var (
+	streamMetrics = map[string]func(s *jetstream.StreamInfo) float64{
+		"messages":  func(s *jetstream.StreamInfo) float64 { return float64(s.State.Msgs) },
+		"bytes":     func(s *jetstream.StreamInfo) float64 { return float64(s.State.Bytes) },
+		"consumers": func(s *jetstream.StreamInfo) float64 { return float64(s.State.Consumers) },
+	}
+
+	consumerMetrics = map[string]func(c *jetstream.ConsumerInfo) float64{
+		"num_pending":     func(c *jetstream.ConsumerInfo) float64 { return float64(c.NumPending) },
+		"num_ack_pending": func(c *jetstream.ConsumerInfo) float64 { return float64(c.NumAckPending) },
+		"num_redelivered": func(c *jetstream.ConsumerInfo) float64 { return float64(c.NumRedelivered) },
+		"num_waiting":     func(c *jetstream.ConsumerInfo) float64 { return float64(c.NumWaiting) },
+		"backlog": func(c *jetstream.ConsumerInfo) float64 {
+			return float64(c.NumPending) + float64(c.NumAckPending)
+		},
+	}
+)
+
+// jsClient is the subset of the JetStream API used by the plugin, which
+// allows it to be mocked in tests.
+type jsClient interface {
+	streamInfo(ctx context.Context, stream string) (*jetstream.StreamInfo, error)
+
+	// consumerInfos returns the info of the named consumer, or all consumers
+	// of the stream if the name is allConsumers.
+	consumerInfos(ctx context.Context, stream, consumer string) ([]*jetstream.ConsumerInfo, error)
+}
+
+// APMPlugin is the NATS implementation of the apm.APM interface, which reads
+// JetStream stream and consumer state.
+type APMPlugin struct {
+	logger hclog.Logger
+
+	// lock protects the fields below, which are updated by SetConfig.
+	lock    sync.Mutex
+	conn    *nats.Conn
+	client  jsClient
+	timeout time.Duration
+}
+
+func NewNATSPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig configures the plugin and connects to NATS. The connection is
+// retried in the background, so an unavailable server does not prevent the
+// plugin from loading.
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	addr := config[configKeyAddress]
+	if addr == "" {
+		addr = configValueAddressDefault
+	}
+
+	timeout := configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		timeout = d
+	}
+
+	opts := []nats.Option{
+		nats.Name("nomad-autoscaler"),
+		nats.Timeout(timeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	}
+
+	switch {
+	case config[configKeyCredsFile] != "":
+		opts = append(opts, nats.UserCredentials(config[configKeyCredsFile]))
+	case config[configKeyToken] != "":
+		opts = append(opts, nats.Token(config[configKeyToken]))
+	case config[configKeyUsername] != "":
+		opts = append(opts, nats.UserInfo(config[configKeyUsername], config[configKeyPassword]))
+	}
+
+	if caCert := config[configKeyCACert]; caCert != "" {
+		opts = append(opts, nats.RootCAs(caCert))
+	}
+	if v := config[configKeySkipVerify]; v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeySkipVerify, err)
+		}
+		if skip {
+			opts = append(opts, nats.Secure(&tls.Config{InsecureSkipVerify: true}))
+		}
+	}
+
+	conn, err := nats.Connect(addr, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %v", err)
+	}
+
+	var js jetstream.JetStream
+	if domain := config[configKeyDomain]; domain != "" {
+		js, err = jetstream.NewWithDomain(conn, domain)
+	} else {
+		js, err = jetstream.New(conn)
+	}
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create JetStream client: %v", err)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.conn != nil {
+		a.conn.Close()
+	}
+	a.conn = conn
+	a.client = &natsClient{js: js}
+	a.timeout = timeout
+
+	return nil
+}
+
+// Close closes the NATS connection. It implements io.Closer so the plugin
+// manager closes the connection when the plugin is removed.
+func (a *APMPlugin) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn, a.client = nil, nil
+	}
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple reads the current value of the stream or consumer metric.
+// JetStream reports point in time state, so the metric stream contains a
+// single point and the time range is not used.
+//
+// Queries are in the form <metric>:<stream>[/<consumer>], such as
+// "num_pending:ORDERS/fulfilment". A consumer of "*" sums the metric across
+// all consumers of the stream.
+func (a *APMPlugin) QueryMultiple(q string, _ sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying NATS JetStream", "query", q)
+
+	metric, stream, consumer, err := parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	a.lock.Lock()
+	client, timeout := a.client, a.timeout
+	a.lock.Unlock()
+
+	if client == nil {
+		return nil, errors.New("plugin has not been configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var value float64
+
+	if consumer == "" {
+		info, err := client.streamInfo(ctx, stream)
+		if err != nil {
+			return nil, fmt.Errorf("error querying stream %q from nats: %v", stream, err)
+		}
+		value = streamMetrics[metric](info)
+	} else {
+		infos, err := client.consumerInfos(ctx, stream, consumer)
+		if err != nil {
+			return nil, fmt.Errorf("error querying consumer %q of stream %q from nats: %v", consumer, stream, err)
+		}
+		for _, info := range infos {
+			value += consumerMetrics[metric](info)
+		}
+	}
+
+	return []sdk.TimestampedMetrics{{{Timestamp: time.Now(), Value: value}}}, nil
+}
+
+// parseQuery splits the query into the metric, stream and consumer. The
+// consumer is empty for stream metrics.
+func parseQuery(q string) (string, string, string, error) {
+	metric, target, ok := strings.Cut(strings.TrimSpace(q), ":")
+	if !ok || target == "" {
+		return "", "", "", fmt.Errorf("query %q must be in the form <metric>:<stream>[/<consumer>]", q)
+	}
+	stream, consumer, hasConsumer := strings.Cut(target, "/")
+	if stream == "" || (hasConsumer && consumer == "") {
+		return "", "", "", fmt.Errorf("query %q must be in the form <metric>:<stream>[/<consumer>]", q)
+	}
+
+	if hasConsumer {
+		if _, ok := consumerMetrics[metric]; !ok {
+			return "", "", "", fmt.Errorf("unsupported consumer metric %q, must be one of %s", metric, metricNames(consumerMetrics))
+		}
+	} else if _, ok := streamMetrics[metric]; !ok {
+		return "", "", "", fmt.Errorf("unsupported stream metric %q, must be one of %s", metric, metricNames(streamMetrics))
+	}
+
+	return metric, stream, consumer, nil
+}
+
+func metricNames[T any](metrics map[string]T) string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// natsClient implements jsClient using a JetStream connection.
+type natsClient struct {
+	js jetstream.JetStream
+}
+
+func (n *natsClient) streamInfo(ctx context.Context, stream string) (*jetstream.StreamInfo, error) {
+	s, err := n.js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	return s.Info(ctx)
+}
+
+func (n *natsClient) consumerInfos(ctx context.Context, stream, consumer string) ([]*jetstream.ConsumerInfo, error) {
+	if consumer != allConsumers {
+		c, err := n.js.Consumer(ctx, stream, consumer)
+		if err != nil {
+			return nil, err
+		}
+		info, err := c.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []*jetstream.ConsumerInfo{info}, nil
+	}
+
+	s, err := n.js.Stream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []*jetstream.ConsumerInfo
+	lister := s.ListConsumers(ctx)
+	for info := range lister.Info() {
+		infos = append(infos, info)
+	}
+	if err := lister.Err(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
@@ -0,0 +1,251 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockJSClient struct {
+	streams   map[string]*jetstream.StreamInfo
+	consumers map[string][]*jetstream.ConsumerInfo
+	err       error
+}
+
+func (m *mockJSClient) streamInfo(_ context.Context, stream string) (*jetstream.StreamInfo, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	info, ok := m.streams[stream]
+	if !ok {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	return info, nil
+}
+
+func (m *mockJSClient) consumerInfos(_ context.Context, stream, consumer string) ([]*jetstream.ConsumerInfo, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	infos, ok := m.consumers[stream]
+	if !ok {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	if consumer == allConsumers {
+		return infos, nil
+	}
+	for _, info := range infos {
+		if info.Name == consumer {
+			return []*jetstream.ConsumerInfo{info}, nil
+		}
+	}
+	return nil, jetstream.ErrConsumerNotFound
+}
+
+func testJSClient() *mockJSClient {
+	return &mockJSClient{
+		streams: map[string]*jetstream.StreamInfo{
+			"ORDERS": {State: jetstream.StreamState{Msgs: 120, Bytes: 4096, Consumers: 2}},
+		},
+		consumers: map[string][]*jetstream.ConsumerInfo{
+			"ORDERS": {
+				{Name: "fulfilment", NumPending: 40, NumAckPending: 5, NumRedelivered: 2, NumWaiting: 1},
+				{Name: "billing", NumPending: 10, NumAckPending: 0, NumRedelivered: 0, NumWaiting: 3},
+			},
+		},
+	}
+}
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"skip_verify": "maybe"},
+			expectedError: `failed to parse "skip_verify"`,
+		},
+		{
+			name:          "missing ca cert",
+			config:        map[string]string{"ca_cert": "does-not-exist.pem"},
+			expectedError: "failed to connect to NATS",
+		},
+		{
+			name: "server unavailable",
+			config: map[string]string{
+				"address":  "nats://127.0.0.1:1, nats://127.0.0.1:2",
+				"username": "user",
+				"password": "pass",
+				"domain":   "hub",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			defer apmPlugin.Close()
+
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	apmPlugin := APMPlugin{
+		logger:  hclog.NewNullLogger(),
+		timeout: time.Second,
+		client:  testJSClient(),
+	}
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	testCases := []struct {
+		query    string
+		expected float64
+	}{
+		{query: "messages:ORDERS", expected: 120},
+		{query: "bytes:ORDERS", expected: 4096},
+		{query: "consumers:ORDERS", expected: 2},
+		{query: "num_pending:ORDERS/fulfilment", expected: 40},
+		{query: "num_ack_pending:ORDERS/fulfilment", expected: 5},
+		{query: "num_redelivered:ORDERS/fulfilment", expected: 2},
+		{query: "num_waiting:ORDERS/billing", expected: 3},
+		{query: "backlog:ORDERS/fulfilment", expected: 45},
+		{query: "backlog:ORDERS/*", expected: 55},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			metrics, err := apmPlugin.Query(tc.query, r)
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			assert.Equal(t, tc.expected, metrics[0].Value)
+		})
+	}
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	testCases := []struct {
+		name          string
+		client        jsClient
+		query         string
+		expectedError string
+	}{
+		{
+			name:          "not configured",
+			query:         "messages:ORDERS",
+			expectedError: "plugin has not been configured",
+		},
+		{
+			name:          "request error",
+			client:        &mockJSClient{err: errors.New("nats: timeout")},
+			query:         "messages:ORDERS",
+			expectedError: `error querying stream "ORDERS" from nats: nats: timeout`,
+		},
+		{
+			name:          "stream not found",
+			client:        testJSClient(),
+			query:         "messages:PAYMENTS",
+			expectedError: `error querying stream "PAYMENTS" from nats: nats: API error: code=404 err_code=10059 description=stream not found`,
+		},
+		{
+			name:          "consumer not found",
+			client:        testJSClient(),
+			query:         "num_pending:ORDERS/shipping",
+			expectedError: `error querying consumer "shipping" of stream "ORDERS" from nats: nats: API error: code=404 err_code=10014 description=consumer not found`,
+		},
+		{
+			name:          "invalid query",
+			client:        testJSClient(),
+			query:         "ORDERS",
+			expectedError: `failed to parse query: query "ORDERS" must be in the form <metric>:<stream>[/<consumer>]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), timeout: time.Second, client: tc.client}
+
+			_, err := apmPlugin.Query(tc.query, r)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func Test_parseQuery(t *testing.T) {
+	testCases := []struct {
+		query            string
+		expectedMetric   string
+		expectedStream   string
+		expectedConsumer string
+		expectedError    string
+	}{
+		{
+			query:          "messages:ORDERS",
+			expectedMetric: "messages",
+			expectedStream: "ORDERS",
+		},
+		{
+			query:            " backlog:ORDERS/* ",
+			expectedMetric:   "backlog",
+			expectedStream:   "ORDERS",
+			expectedConsumer: "*",
+		},
+		{
+			query:         "num_pending:ORDERS",
+			expectedError: `unsupported stream metric "num_pending", must be one of bytes, consumers, messages`,
+		},
+		{
+			query:         "bytes:ORDERS/fulfilment",
+			expectedError: `unsupported consumer metric "bytes", must be one of backlog, num_ack_pending, num_pending, num_redelivered, num_waiting`,
+		},
+		{
+			query:         "backlog:ORDERS/",
+			expectedError: `query "backlog:ORDERS/" must be in the form <metric>:<stream>[/<consumer>]`,
+		},
+		{
+			query:         "messages:",
+			expectedError: `query "messages:" must be in the form <metric>:<stream>[/<consumer>]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			metric, stream, consumer, err := parseQuery(tc.query)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedMetric, metric)
+			assert.Equal(t, tc.expectedStream, stream)
+			assert.Equal(t, tc.expectedConsumer, consumer)
+		})
+	}
+}
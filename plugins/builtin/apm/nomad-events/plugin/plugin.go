@@ -0,0 +1,161 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	nomadHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "nomad-events"
+
+	// allNamespaces is the namespace the plugin watches if the Nomad config
+	// does not set one.
+	allNamespaces = "*"
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewNomadEventsPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	// errNotSynced is returned by queries while the state is not synchronized
+	// with the cluster.
+	errNotSynced = errors.New("state is not synchronized with the Nomad event stream")
+)
+
+// APMPlugin is the Nomad event stream implementation of the apm.APM
+// interface. It maintains counts of allocations and blocked evaluations from
+// the event stream, so checks observe pending placements as soon as they
+// happen rather than on the next poll.
+type APMPlugin struct {
+	logger hclog.Logger
+
+	// lock protects the fields below. Each watcher has its own state, so a
+	// stopping watcher cannot modify the state of its replacement.
+	lock   sync.Mutex
+	state  *state
+	cancel context.CancelFunc
+}
+
+func NewNomadEventsPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+		state:  newState(),
+	}
+}
+
+// SetConfig configures the plugin and starts watching the event stream,
+// restarting the watcher if it is already running. The namespace of the
+// Nomad config limits the events watched, otherwise all namespaces are
+// watched.
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	cfg := nomadHelper.ConfigFromNamespacedMap(config)
+
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate Nomad client: %v", err)
+	}
+
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = allNamespaces
+	}
+
+	a.start(&apiClient{client: client}, namespace)
+	return nil
+}
+
+func (a *APMPlugin) start(client nomadClient, namespace string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.cancel != nil {
+		a.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.state, a.cancel = newState(), cancel
+
+	go a.watch(ctx, client, namespace, a.state)
+}
+
+// Close stops watching the event stream. It implements io.Closer so the
+// plugin manager stops the watcher when the plugin is removed.
+func (a *APMPlugin) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.cancel != nil {
+		a.cancel()
+		a.cancel = nil
+	}
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple returns the current value of the metric. The state only
+// reflects the present, so the metric stream contains a single point and the
+// time range is not used.
+func (a *APMPlugin) QueryMultiple(q string, _ sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying Nomad event stream state", "query", q)
+
+	parsed, err := parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	a.lock.Lock()
+	st := a.state
+	a.lock.Unlock()
+
+	if !st.isSynced() {
+		return nil, errNotSynced
+	}
+
+	value := st.count(parsed)
+	return []sdk.TimestampedMetrics{{{Timestamp: time.Now(), Value: value}}}, nil
+}
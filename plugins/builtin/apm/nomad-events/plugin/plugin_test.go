@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockNomadClient returns the snapshot, recording the index each stream was
+// started from. Events sent on eventsCh are delivered to the current stream.
+type mockNomadClient struct {
+	lock      sync.Mutex
+	snap      *snapshot
+	index     uint64
+	snapshots int
+	indexes   []uint64
+
+	eventsCh chan *api.Events
+}
+
+func (m *mockNomadClient) snapshot(_ string) (*snapshot, uint64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.snapshots++
+	return m.snap, m.index, nil
+}
+
+func (m *mockNomadClient) stream(_ context.Context, _ string, index uint64) (<-chan *api.Events, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.indexes = append(m.indexes, index)
+	return m.eventsCh, nil
+}
+
+func (m *mockNomadClient) snapshotCount() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.snapshots
+}
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	apmPlugin := NewNomadEventsPlugin(hclog.NewNullLogger()).(*APMPlugin)
+	defer apmPlugin.Close()
+
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{"nomad_address": "http://127.0.0.1:1"}))
+	assert.NotNil(t, apmPlugin.cancel)
+
+	// The state is not synchronized while Nomad is unavailable.
+	_, err := apmPlugin.Query("queued_allocs", sdk.TimeRange{})
+	assert.Equal(t, errNotSynced, err)
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	oldRetryWait := retryWait
+	retryWait = 10 * time.Millisecond
+	defer func() { retryWait = oldRetryWait }()
+
+	client := &mockNomadClient{
+		snap: &snapshot{
+			evals: []*api.Evaluation{
+				{ID: "e1", Namespace: "default", JobID: "web", Status: api.EvalStatusBlocked,
+					QueuedAllocations: map[string]int{"frontend": 2}},
+			},
+		},
+		index:    42,
+		eventsCh: make(chan *api.Events),
+	}
+
+	apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), state: newState()}
+	defer apmPlugin.Close()
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	_, err := apmPlugin.Query("queued_allocs", r)
+	assert.Equal(t, errNotSynced, err)
+
+	apmPlugin.start(client, allNamespaces)
+
+	query := func() float64 {
+		m, err := apmPlugin.Query("queued_allocs:job=web", r)
+		if err != nil {
+			return -1
+		}
+		return m[0].Value
+	}
+
+	assert.Eventually(t, func() bool { return query() == 2 }, time.Second, 10*time.Millisecond)
+
+	// Events are applied to the snapshot.
+	e := evalEvent("e2", "web", api.EvalStatusBlocked, map[string]interface{}{"frontend": 3}, nil)
+	client.eventsCh <- &api.Events{Index: 43, Events: []api.Event{e}}
+	assert.Eventually(t, func() bool { return query() == 5 }, time.Second, 10*time.Millisecond)
+
+	// A stream error resynchronizes the state from a new snapshot.
+	client.eventsCh <- &api.Events{Err: errors.New("unexpected EOF")}
+	assert.Eventually(t, func() bool { return client.snapshotCount() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return query() == 2 }, time.Second, 10*time.Millisecond)
+
+	client.lock.Lock()
+	assert.Equal(t, []uint64{42, 42}, client.indexes)
+	client.lock.Unlock()
+
+	_, err = apmPlugin.Query("queued_allocs:group=web", r)
+	assert.ErrorContains(t, err, "failed to parse query")
+}
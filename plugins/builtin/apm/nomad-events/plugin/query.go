@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// metricPendingAllocs is the number of allocations which have been placed
+	// but are not yet running.
+	metricPendingAllocs = "pending_allocs"
+
+	// metricRunningAllocs is the number of running allocations.
+	metricRunningAllocs = "running_allocs"
+
+	// metricBlockedEvals is the number of blocked evaluations, which are
+	// waiting for cluster capacity.
+	metricBlockedEvals = "blocked_evals"
+
+	// metricQueuedAllocs is the number of allocations of blocked evaluations
+	// which could not be placed.
+	metricQueuedAllocs = "queued_allocs"
+
+	filterJob       = "job"
+	filterGroup     = "group"
+	filterNodeClass = "node_class"
+
+	defaultNamespace = "default"
+)
+
+// query is a parsed check query.
+type query struct {
+	metric    string
+	namespace string
+	job       string
+	group     string
+	nodeClass string
+
+	// hasNodeClass is set when filtering by node class, as the empty class
+	// is valid.
+	hasNodeClass bool
+}
+
+// parseQuery parses the check query, in the form:
+//
+//	<metric>[:<filter>=<value>]
+//
+// The filter restricts the metric to a job (job=[<namespace>/]<job>), a task
+// group (group=[<namespace>/]<job>/<group>) or a node class
+// (node_class=<class>). Without a filter the metric covers the cluster.
+func parseQuery(q string) (*query, error) {
+	metric, filter, hasFilter := strings.Cut(strings.TrimSpace(q), ":")
+
+	switch metric {
+	case metricPendingAllocs, metricRunningAllocs, metricBlockedEvals, metricQueuedAllocs:
+	default:
+		return nil, fmt.Errorf("unsupported metric %q, must be one of %s, %s, %s or %s",
+			metric, metricPendingAllocs, metricRunningAllocs, metricBlockedEvals, metricQueuedAllocs)
+	}
+
+	parsed := &query{metric: metric}
+	if !hasFilter {
+		return parsed, nil
+	}
+
+	key, value, ok := strings.Cut(filter, "=")
+	if !ok {
+		return nil, fmt.Errorf("filter %q must be in the form <filter>=<value>", filter)
+	}
+
+	switch key {
+	case filterJob:
+		parts := strings.Split(value, "/")
+		if len(parts) == 1 {
+			parts = []string{defaultNamespace, parts[0]}
+		}
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("job filter %q must be in the form [<namespace>/]<job>", value)
+		}
+		parsed.namespace, parsed.job = parts[0], parts[1]
+
+	case filterGroup:
+		parts := strings.Split(value, "/")
+		if len(parts) == 2 {
+			parts = []string{defaultNamespace, parts[0], parts[1]}
+		}
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("group filter %q must be in the form [<namespace>/]<job>/<group>", value)
+		}
+		parsed.namespace, parsed.job, parsed.group = parts[0], parts[1], parts[2]
+
+	case filterNodeClass:
+		parsed.nodeClass, parsed.hasNodeClass = value, true
+
+	default:
+		return nil, fmt.Errorf("unsupported filter %q, must be one of %s, %s or %s",
+			key, filterJob, filterGroup, filterNodeClass)
+	}
+
+	return parsed, nil
+}
+
+// matchAlloc returns whether the allocation, placed on a node of the passed
+// class, matches the query filter.
+func (q *query) matchAlloc(a *allocState, nodeClass string) bool {
+	if q.job != "" && (a.namespace != q.namespace || a.jobID != q.job) {
+		return false
+	}
+	if q.group != "" && a.taskGroup != q.group {
+		return false
+	}
+	if q.hasNodeClass && nodeClass != q.nodeClass {
+		return false
+	}
+	return true
+}
+
+// matchEval returns whether the blocked evaluation matches the query filter.
+// An evaluation matches a node class if it is eligible for placement on
+// nodes of the class, or if its constraints prevented eligibility being
+// computed by class.
+func (q *query) matchEval(e *evalState) bool {
+	if q.job != "" && (e.namespace != q.namespace || e.jobID != q.job) {
+		return false
+	}
+	if q.group != "" && e.queued[q.group] == 0 {
+		return false
+	}
+	if q.hasNodeClass && !e.escapedClass && !e.classEligibility[q.nodeClass] {
+		return false
+	}
+	return true
+}
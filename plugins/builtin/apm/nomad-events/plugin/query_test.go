@@ -0,0 +1,76 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseQuery(t *testing.T) {
+	testCases := []struct {
+		query         string
+		expected      *query
+		expectedError string
+	}{
+		{
+			query:    "queued_allocs",
+			expected: &query{metric: "queued_allocs"},
+		},
+		{
+			query:    "pending_allocs:job=web",
+			expected: &query{metric: "pending_allocs", namespace: "default", job: "web"},
+		},
+		{
+			query:    "running_allocs:job=platform/web",
+			expected: &query{metric: "running_allocs", namespace: "platform", job: "web"},
+		},
+		{
+			query:    "queued_allocs:group=web/frontend",
+			expected: &query{metric: "queued_allocs", namespace: "default", job: "web", group: "frontend"},
+		},
+		{
+			query:    "blocked_evals:group=platform/web/frontend",
+			expected: &query{metric: "blocked_evals", namespace: "platform", job: "web", group: "frontend"},
+		},
+		{
+			query:    " queued_allocs:node_class= ",
+			expected: &query{metric: "queued_allocs", hasNodeClass: true},
+		},
+		{
+			query:         "failed_allocs",
+			expectedError: `unsupported metric "failed_allocs", must be one of pending_allocs, running_allocs, blocked_evals or queued_allocs`,
+		},
+		{
+			query:         "queued_allocs:web",
+			expectedError: `filter "web" must be in the form <filter>=<value>`,
+		},
+		{
+			query:         "queued_allocs:job=a/b/c",
+			expectedError: `job filter "a/b/c" must be in the form [<namespace>/]<job>`,
+		},
+		{
+			query:         "queued_allocs:group=web",
+			expectedError: `group filter "web" must be in the form [<namespace>/]<job>/<group>`,
+		},
+		{
+			query:         "queued_allocs:datacenter=dc1",
+			expectedError: `unsupported filter "datacenter", must be one of job, group or node_class`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := parseQuery(tc.query)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, q)
+		})
+	}
+}
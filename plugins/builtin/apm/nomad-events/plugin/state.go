@@ -0,0 +1,214 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"sync"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// allocState is the subset of an allocation tracked by the plugin.
+type allocState struct {
+	namespace     string
+	jobID         string
+	taskGroup     string
+	nodeID        string
+	clientStatus  string
+	desiredStatus string
+}
+
+// evalState is the subset of a blocked evaluation tracked by the plugin.
+type evalState struct {
+	namespace        string
+	jobID            string
+	queued           map[string]int
+	classEligibility map[string]bool
+	escapedClass     bool
+}
+
+// state is the view of the cluster built from the event stream. Only
+// allocations which are pending or running, and evaluations which are
+// blocked, are kept.
+type state struct {
+	lock   sync.RWMutex
+	synced bool
+	allocs map[string]*allocState
+	evals  map[string]*evalState
+
+	// nodeClasses maps node IDs to their node class, so allocations can be
+	// filtered by class.
+	nodeClasses map[string]string
+}
+
+func newState() *state {
+	return &state{
+		allocs:      make(map[string]*allocState),
+		evals:       make(map[string]*evalState),
+		nodeClasses: make(map[string]string),
+	}
+}
+
+// reset replaces the state with the snapshot, marking it as synchronized.
+func (s *state) reset(snap *snapshot) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.allocs = make(map[string]*allocState, len(snap.allocs))
+	for _, a := range snap.allocs {
+		s.upsertAlloc(a.ID, &allocState{
+			namespace:     a.Namespace,
+			jobID:         a.JobID,
+			taskGroup:     a.TaskGroup,
+			nodeID:        a.NodeID,
+			clientStatus:  a.ClientStatus,
+			desiredStatus: a.DesiredStatus,
+		})
+	}
+
+	s.evals = make(map[string]*evalState, len(snap.evals))
+	for _, e := range snap.evals {
+		s.upsertEval(e)
+	}
+
+	s.nodeClasses = make(map[string]string, len(snap.nodes))
+	for _, n := range snap.nodes {
+		s.nodeClasses[n.ID] = n.NodeClass
+	}
+
+	s.synced = true
+}
+
+// setSynced marks whether the state reflects the cluster. It is cleared when
+// the event stream is interrupted, so queries do not return stale values.
+func (s *state) setSynced(synced bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.synced = synced
+}
+
+func (s *state) isSynced() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.synced
+}
+
+// apply updates the state from the event. Events for other topics, or
+// without a payload the plugin understands, are ignored.
+func (s *state) apply(e *api.Event) error {
+	switch e.Topic {
+	case api.TopicAllocation:
+		alloc, err := e.Allocation()
+		if err != nil || alloc == nil {
+			return err
+		}
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.upsertAlloc(alloc.ID, &allocState{
+			namespace:     alloc.Namespace,
+			jobID:         alloc.JobID,
+			taskGroup:     alloc.TaskGroup,
+			nodeID:        alloc.NodeID,
+			clientStatus:  alloc.ClientStatus,
+			desiredStatus: alloc.DesiredStatus,
+		})
+
+	case api.TopicEvaluation:
+		eval, err := e.Evaluation()
+		if err != nil || eval == nil {
+			return err
+		}
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.upsertEval(eval)
+
+	case api.TopicNode:
+		node, err := e.Node()
+		if err != nil || node == nil {
+			return err
+		}
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if e.Type == "NodeDeregistration" {
+			delete(s.nodeClasses, node.ID)
+		} else {
+			s.nodeClasses[node.ID] = node.NodeClass
+		}
+	}
+
+	return nil
+}
+
+// upsertAlloc stores the allocation if it is pending or running, otherwise
+// removing it. The lock must be held by the caller.
+func (s *state) upsertAlloc(id string, alloc *allocState) {
+	switch alloc.clientStatus {
+	case api.AllocClientStatusPending, api.AllocClientStatusRunning:
+		s.allocs[id] = alloc
+	default:
+		delete(s.allocs, id)
+	}
+}
+
+// upsertEval stores the evaluation if it is blocked, otherwise removing it.
+// The lock must be held by the caller.
+func (s *state) upsertEval(eval *api.Evaluation) {
+	if eval.Status != api.EvalStatusBlocked {
+		delete(s.evals, eval.ID)
+		return
+	}
+
+	s.evals[eval.ID] = &evalState{
+		namespace:        eval.Namespace,
+		jobID:            eval.JobID,
+		queued:           eval.QueuedAllocations,
+		classEligibility: eval.ClassEligibility,
+		escapedClass:     eval.EscapedComputedClass,
+	}
+}
+
+// count returns the value of the query metric.
+func (s *state) count(q *query) float64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var total int
+
+	switch q.metric {
+	case metricPendingAllocs, metricRunningAllocs:
+		for _, a := range s.allocs {
+			if !q.matchAlloc(a, s.nodeClasses[a.nodeID]) {
+				continue
+			}
+			switch {
+			case q.metric == metricPendingAllocs &&
+				a.clientStatus == api.AllocClientStatusPending && a.desiredStatus == api.AllocDesiredStatusRun:
+				total++
+			case q.metric == metricRunningAllocs && a.clientStatus == api.AllocClientStatusRunning:
+				total++
+			}
+		}
+
+	case metricBlockedEvals, metricQueuedAllocs:
+		for _, e := range s.evals {
+			if !q.matchEval(e) {
+				continue
+			}
+			if q.metric == metricBlockedEvals {
+				total++
+				continue
+			}
+			for group, n := range e.queued {
+				if q.group == "" || q.group == group {
+					total += n
+				}
+			}
+		}
+	}
+
+	return float64(total)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allocEvent(id, job, group, node, clientStatus string) api.Event {
+	return api.Event{
+		Topic: api.TopicAllocation,
+		Type:  "AllocationUpdated",
+		Payload: map[string]interface{}{
+			"Allocation": map[string]interface{}{
+				"ID":            id,
+				"Namespace":     "default",
+				"JobID":         job,
+				"TaskGroup":     group,
+				"NodeID":        node,
+				"ClientStatus":  clientStatus,
+				"DesiredStatus": api.AllocDesiredStatusRun,
+			},
+		},
+	}
+}
+
+func evalEvent(id, job, status string, queued map[string]interface{}, classes map[string]interface{}) api.Event {
+	return api.Event{
+		Topic: api.TopicEvaluation,
+		Type:  "EvaluationUpdated",
+		Payload: map[string]interface{}{
+			"Evaluation": map[string]interface{}{
+				"ID":                id,
+				"Namespace":         "default",
+				"JobID":             job,
+				"Status":            status,
+				"QueuedAllocations": queued,
+				"ClassEligibility":  classes,
+			},
+		},
+	}
+}
+
+func nodeEvent(id, class, eventType string) api.Event {
+	return api.Event{
+		Topic: api.TopicNode,
+		Type:  eventType,
+		Payload: map[string]interface{}{
+			"Node": map[string]interface{}{"ID": id, "NodeClass": class},
+		},
+	}
+}
+
+func testState(t *testing.T) *state {
+	s := newState()
+	s.reset(&snapshot{
+		allocs: []*api.AllocationListStub{
+			{ID: "a1", Namespace: "default", JobID: "web", TaskGroup: "frontend", NodeID: "n1",
+				ClientStatus: api.AllocClientStatusRunning, DesiredStatus: api.AllocDesiredStatusRun},
+			{ID: "a2", Namespace: "default", JobID: "web", TaskGroup: "frontend", NodeID: "n2",
+				ClientStatus: api.AllocClientStatusPending, DesiredStatus: api.AllocDesiredStatusRun},
+			{ID: "a3", Namespace: "batch", JobID: "report", TaskGroup: "main", NodeID: "n3",
+				ClientStatus: api.AllocClientStatusRunning, DesiredStatus: api.AllocDesiredStatusRun},
+		},
+		evals: []*api.Evaluation{
+			{ID: "e1", Namespace: "default", JobID: "web", Status: api.EvalStatusBlocked,
+				QueuedAllocations: map[string]int{"frontend": 2, "backend": 1},
+				ClassEligibility:  map[string]bool{"general": true, "gpu": false}},
+			{ID: "e2", Namespace: "batch", JobID: "report", Status: api.EvalStatusBlocked,
+				QueuedAllocations: map[string]int{"main": 4}, EscapedComputedClass: true},
+		},
+		nodes: []*api.NodeListStub{
+			{ID: "n1", NodeClass: "general"},
+			{ID: "n2", NodeClass: "general"},
+			{ID: "n3", NodeClass: "gpu"},
+		},
+	})
+	require.True(t, s.isSynced())
+	return s
+}
+
+func TestState_count(t *testing.T) {
+	s := testState(t)
+
+	testCases := []struct {
+		query    string
+		expected float64
+	}{
+		{query: "running_allocs", expected: 2},
+		{query: "pending_allocs", expected: 1},
+		{query: "running_allocs:job=web", expected: 1},
+		{query: "running_allocs:job=batch/report", expected: 1},
+		{query: "running_allocs:node_class=gpu", expected: 1},
+		{query: "pending_allocs:node_class=gpu", expected: 0},
+		{query: "blocked_evals", expected: 2},
+		{query: "blocked_evals:group=web/backend", expected: 1},
+		{query: "blocked_evals:group=web/sidecar", expected: 0},
+		{query: "queued_allocs", expected: 7},
+		{query: "queued_allocs:group=web/frontend", expected: 2},
+		{query: "queued_allocs:node_class=general", expected: 7},
+		{query: "queued_allocs:node_class=gpu", expected: 4},
+		{query: "queued_allocs:node_class=arm", expected: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := parseQuery(tc.query)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, s.count(q))
+		})
+	}
+}
+
+func TestState_apply(t *testing.T) {
+	s := testState(t)
+
+	count := func(query string) float64 {
+		q, err := parseQuery(query)
+		require.NoError(t, err)
+		return s.count(q)
+	}
+
+	// The pending allocation starts running, and a new one is placed on a
+	// new node.
+	for _, e := range []api.Event{
+		allocEvent("a2", "web", "frontend", "n2", api.AllocClientStatusRunning),
+		nodeEvent("n4", "gpu", "NodeRegistration"),
+		allocEvent("a4", "web", "frontend", "n4", api.AllocClientStatusPending),
+	} {
+		require.NoError(t, s.apply(&e))
+	}
+	assert.Equal(t, float64(2), count("running_allocs:job=web"))
+	assert.Equal(t, float64(1), count("pending_allocs:node_class=gpu"))
+
+	// Terminal allocations are removed.
+	e := allocEvent("a1", "web", "frontend", "n1", api.AllocClientStatusComplete)
+	require.NoError(t, s.apply(&e))
+	assert.Equal(t, float64(1), count("running_allocs:job=web"))
+
+	// Evaluations are removed once they are no longer blocked, and new
+	// blocked evaluations are added.
+	e = evalEvent("e1", "web", api.EvalStatusComplete, nil, nil)
+	require.NoError(t, s.apply(&e))
+	e = evalEvent("e3", "web", api.EvalStatusBlocked,
+		map[string]interface{}{"frontend": 5}, map[string]interface{}{"gpu": true})
+	require.NoError(t, s.apply(&e))
+	assert.Equal(t, float64(5), count("queued_allocs:job=web"))
+	assert.Equal(t, float64(9), count("queued_allocs:node_class=gpu"))
+	assert.Equal(t, float64(4), count("queued_allocs:node_class=general"))
+
+	// Deregistered nodes no longer have a class.
+	e = nodeEvent("n4", "gpu", "NodeDeregistration")
+	require.NoError(t, s.apply(&e))
+	assert.Equal(t, float64(0), count("pending_allocs:node_class=gpu"))
+
+	// Other topics are ignored.
+	require.NoError(t, s.apply(&api.Event{Topic: api.TopicJob, Type: "JobRegistered"}))
+}
@@ -0,0 +1,147 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// retryWait is the duration waited before resynchronizing the state after the
+// event stream fails.
+var retryWait = 10 * time.Second
+
+// snapshot is the initial state of the cluster, which the event stream is
+// applied to.
+type snapshot struct {
+	allocs []*api.AllocationListStub
+	evals  []*api.Evaluation
+	nodes  []*api.NodeListStub
+}
+
+// nomadClient is the subset of the Nomad API used by the plugin, which allows
+// it to be mocked in tests.
+type nomadClient interface {
+	// snapshot lists the current allocations, blocked evaluations and nodes,
+	// returning the index the event stream should start from.
+	snapshot(namespace string) (*snapshot, uint64, error)
+
+	// stream subscribes to the allocation, evaluation and node events after
+	// the index.
+	stream(ctx context.Context, namespace string, index uint64) (<-chan *api.Events, error)
+}
+
+// apiClient implements nomadClient using the Nomad API client.
+type apiClient struct {
+	client *api.Client
+}
+
+func (c *apiClient) snapshot(namespace string) (*snapshot, uint64, error) {
+	// Only the allocations and evaluations which are tracked are listed, to
+	// reduce the size of the responses.
+	allocs, allocMeta, err := c.client.Allocations().List(&api.QueryOptions{
+		Namespace: namespace,
+		Filter:    `ClientStatus == "pending" or ClientStatus == "running"`,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list allocations: %v", err)
+	}
+	evals, evalMeta, err := c.client.Evaluations().List(&api.QueryOptions{
+		Namespace: namespace,
+		Filter:    `Status == "blocked"`,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list evaluations: %v", err)
+	}
+	nodes, nodeMeta, err := c.client.Nodes().List(nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	// Starting from the lowest index replays any events which happened
+	// between the list calls, so none are missed.
+	index := allocMeta.LastIndex
+	if evalMeta.LastIndex < index {
+		index = evalMeta.LastIndex
+	}
+	if nodeMeta.LastIndex < index {
+		index = nodeMeta.LastIndex
+	}
+
+	return &snapshot{allocs: allocs, evals: evals, nodes: nodes}, index, nil
+}
+
+func (c *apiClient) stream(ctx context.Context, namespace string, index uint64) (<-chan *api.Events, error) {
+	topics := map[api.Topic][]string{
+		api.TopicAllocation: {"*"},
+		api.TopicEvaluation: {"*"},
+		api.TopicNode:       {"*"},
+	}
+	return c.client.EventStream().Stream(ctx, topics, index, &api.QueryOptions{Namespace: namespace})
+}
+
+// watch keeps the state synchronized with the cluster until the context is
+// cancelled, resynchronizing after any event stream failure.
+func (a *APMPlugin) watch(ctx context.Context, client nomadClient, namespace string, st *state) {
+	for {
+		err := a.sync(ctx, client, namespace, st)
+		st.setSynced(false)
+
+		if ctx.Err() != nil {
+			return
+		}
+		a.logger.Error("Nomad event stream failed, retrying", "error", err, "wait", retryWait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryWait):
+		}
+	}
+}
+
+// sync resets st from a snapshot of the cluster and applies events
+// from the stream until it fails.
+func (a *APMPlugin) sync(ctx context.Context, client nomadClient, namespace string, st *state) error {
+	snap, index, err := client.snapshot(namespace)
+	if err != nil {
+		return err
+	}
+	st.reset(snap)
+	a.logger.Debug("synchronized state from Nomad", "index", index,
+		"allocs", len(snap.allocs), "blocked_evals", len(snap.evals), "nodes", len(snap.nodes))
+
+	// The stream is cancelled on return so its goroutine exits.
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	eventsCh, err := client.stream(streamCtx, namespace, index)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to event stream: %v", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case events, ok := <-eventsCh:
+			if !ok {
+				return errors.New("event stream closed")
+			}
+			if events.Err != nil {
+				return events.Err
+			}
+			for i := range events.Events {
+				if err := st.apply(&events.Events[i]); err != nil {
+					a.logger.Warn("failed to apply event", "topic", events.Events[i].Topic,
+						"type", events.Events[i].Type, "error", err)
+				}
+			}
+		}
+	}
+}
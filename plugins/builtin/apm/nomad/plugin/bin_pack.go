@@ -0,0 +1,394 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils/nodepool"
+	"github.com/hashicorp/nomad/api"
+)
+
+// constraintTargetNodeClass is the constraint attribute of the node class,
+// which is the only constraint checked when identifying whether a blocked
+// allocation could be placed on the nodes of the pool.
+const constraintTargetNodeClass = "${node.class}"
+
+// queryBinPackedCount returns the number of nodes required to place the
+// allocations running within the node pool, along with the allocations
+// blocked waiting for capacity the pool could provide. The allocations are
+// packed onto nodes of the smallest shape found within the pool, regardless
+// of where they currently run, so the result is suitable for use with the
+// pass-through strategy.
+//
+// If blockedOnly is set, only the blocked allocations are packed, returning
+// the number of nodes to add to the pool in order to place them.
+func (a *APMPlugin) queryBinPackedCount(id nodepool.ClusterNodePoolIdentifier, blockedOnly bool) (sdk.TimestampedMetrics, error) {
+
+	nodes, _, err := a.client.Nodes().List(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Nomad nodes: %v", err)
+	}
+
+	nodePoolList, err := scaleutils.FilterNodes(nodes, id.IsPoolMember)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify nodes within pool: %v", err)
+	}
+	if len(nodePoolList) == 0 {
+		return nil, errors.New("no nodes identified within pool")
+	}
+
+	var (
+		shape poolResources
+		items []poolResources
+	)
+
+	for i, node := range nodePoolList {
+		nodeInfo, _, err := a.client.Nodes().Info(node.ID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Nomad node info on node %s: %v", node.ID, err)
+		}
+
+		// Use the smallest resources found within the pool as the shape of
+		// the node, so heterogeneous pools do not report too few nodes.
+		res := nodeAllocatableResources(nodeInfo)
+		if i == 0 {
+			shape = res
+		} else {
+			shape = minResources(shape, res)
+		}
+
+		if blockedOnly {
+			continue
+		}
+
+		nodeAllocs, _, err := a.client.Nodes().Allocations(node.ID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Nomad node allocs on node %s: %v", node.ID, err)
+		}
+		for _, alloc := range nodeAllocs {
+			if isServerTerminalStatus(alloc) || isClientTerminalStatus(alloc) {
+				continue
+			}
+			items = append(items, allocResources(alloc))
+		}
+	}
+
+	if shape.cpu <= 0 || shape.mem <= 0 {
+		return nil, errors.New("zero allocatable cpu or memory found on node shape")
+	}
+
+	blocked, err := a.getBlockedAllocResources(nodePoolList)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, blocked...)
+
+	count, unplaceable := binPack(shape, items)
+	if unplaceable > 0 {
+		a.logger.Warn("allocations do not fit on the node shape and are not counted",
+			"count", unplaceable, "node_cpu", shape.cpu, "node_memory", shape.mem, "node_gpu", shape.gpu)
+	}
+	a.logger.Debug("calculated bin packed node count", "node_count", count, "blocked_only", blockedOnly,
+		"allocations", len(items), "blocked_allocations", len(blocked),
+		"node_cpu", shape.cpu, "node_memory", shape.mem, "node_gpu", shape.gpu)
+
+	tm := sdk.TimestampedMetric{
+		Timestamp: time.Now(),
+		Value:     float64(count),
+	}
+	return sdk.TimestampedMetrics{tm}, nil
+}
+
+// getBlockedAllocResources returns the resources of each allocation blocked
+// waiting for capacity which could be placed on the nodes of the pool.
+func (a *APMPlugin) getBlockedAllocResources(nodes []*api.NodeListStub) ([]poolResources, error) {
+
+	q := &api.QueryOptions{
+		Namespace: "*",
+		Filter:    fmt.Sprintf("Status == %q", api.EvalStatusBlocked),
+	}
+
+	evals, _, err := a.client.Evaluations().List(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Nomad evaluations: %v", err)
+	}
+
+	jobs := make(map[string]*api.Job)
+	var out []poolResources
+
+	for _, eval := range evals {
+		if eval.Status != api.EvalStatusBlocked || len(eval.FailedTGAllocs) == 0 {
+			continue
+		}
+
+		key := eval.Namespace + "/" + eval.JobID
+		job, ok := jobs[key]
+		if !ok {
+			job, _, err = a.client.Jobs().Info(eval.JobID, &api.QueryOptions{Namespace: eval.Namespace})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read job %s: %v", eval.JobID, err)
+			}
+			jobs[key] = job
+		}
+
+		for tgName, metric := range eval.FailedTGAllocs {
+			tg := lookupTaskGroup(job, tgName)
+			if tg == nil || !isFeasibleOnPool(job, tg, nodes) {
+				continue
+			}
+
+			res := taskGroupResources(tg)
+			for i := 0; i < blockedAllocCount(eval, tgName, metric); i++ {
+				out = append(out, res)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// blockedAllocCount returns the number of allocations of the task group
+// blocked by the evaluation. Failures of the same task group are coalesced,
+// so the queued allocations are used when available.
+func blockedAllocCount(eval *api.Evaluation, tgName string, metric *api.AllocationMetric) int {
+	count := 1
+	if metric != nil {
+		count += metric.CoalescedFailures
+	}
+	if queued := eval.QueuedAllocations[tgName]; queued > count {
+		count = queued
+	}
+	return count
+}
+
+// lookupTaskGroup returns the named task group of the job, or nil if the job
+// does not contain it.
+func lookupTaskGroup(job *api.Job, name string) *api.TaskGroup {
+	for _, tg := range job.TaskGroups {
+		if tg.Name != nil && *tg.Name == name {
+			return tg
+		}
+	}
+	return nil
+}
+
+// taskGroupResources returns the resources requested by the tasks of the
+// task group.
+func taskGroupResources(tg *api.TaskGroup) poolResources {
+	var res poolResources
+
+	for _, task := range tg.Tasks {
+		if task == nil || task.Resources == nil {
+			continue
+		}
+		if task.Resources.CPU != nil {
+			res.cpu += int64(*task.Resources.CPU)
+		}
+		if task.Resources.MemoryMB != nil {
+			res.mem += int64(*task.Resources.MemoryMB)
+		}
+		res.memMax += int64(memoryMaxMB(task.Resources))
+
+		for _, device := range task.Resources.Devices {
+			if device == nil || deviceRequestType(device.Name) != deviceTypeGPU {
+				continue
+			}
+			if device.Count != nil {
+				res.gpu += int64(*device.Count)
+			} else {
+				res.gpu++
+			}
+		}
+	}
+	return res
+}
+
+// deviceRequestType returns the device type of a device request name in the
+// <type>, <vendor>/<type> or <vendor>/<type>/<name> format.
+func deviceRequestType(name string) string {
+	parts := strings.Split(name, "/")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[1]
+}
+
+// isFeasibleOnPool returns whether an allocation of the task group could be
+// placed on any of the nodes within the pool.
+func isFeasibleOnPool(job *api.Job, tg *api.TaskGroup, nodes []*api.NodeListStub) bool {
+	for _, node := range nodes {
+		if isFeasibleOnNode(job, tg, node) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFeasibleOnNode returns whether an allocation of the task group could be
+// placed on the node, based on the node pool, datacenters and node class
+// constraints of the job. Other constraints are not checked.
+func isFeasibleOnNode(job *api.Job, tg *api.TaskGroup, node *api.NodeListStub) bool {
+
+	pool := api.NodePoolDefault
+	if job.NodePool != nil && *job.NodePool != "" {
+		pool = *job.NodePool
+	}
+	if pool != api.NodePoolAll && pool != node.NodePool {
+		return false
+	}
+
+	if !matchesDatacenter(job.Datacenters, node.Datacenter) {
+		return false
+	}
+
+	constraints := append([]*api.Constraint{}, job.Constraints...)
+	constraints = append(constraints, tg.Constraints...)
+	for _, task := range tg.Tasks {
+		if task != nil {
+			constraints = append(constraints, task.Constraints...)
+		}
+	}
+
+	for _, c := range constraints {
+		if c != nil && !nodeClassConstraintMet(c, node.NodeClass) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesDatacenter returns whether the datacenter matches any of the job
+// datacenters, which may contain glob patterns. Jobs without datacenters may
+// be placed in any datacenter.
+func matchesDatacenter(datacenters []string, dc string) bool {
+	if len(datacenters) == 0 {
+		return true
+	}
+	for _, pattern := range datacenters {
+		if ok, _ := path.Match(pattern, dc); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeClassConstraintMet returns whether the node class satisfies the
+// constraint. Constraints which do not target the node class are always met.
+func nodeClassConstraintMet(c *api.Constraint, class string) bool {
+	if c.LTarget != constraintTargetNodeClass {
+		return true
+	}
+
+	switch c.Operand {
+	case "", "=", "==", "is":
+		return c.RTarget == class
+	case "!=", "not":
+		return c.RTarget != class
+	default:
+		return true
+	}
+}
+
+// binPack packs the allocation resources onto nodes of the shape using the
+// first fit decreasing algorithm, ordering allocations by their dominant
+// share of the node resources. It returns the number of nodes required, and
+// the number of allocations too large to fit on a node of the shape, which
+// are not placed.
+func binPack(shape poolResources, items []poolResources) (int, int) {
+
+	sorted := make([]poolResources, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return dominantShare(sorted[i], shape) > dominantShare(sorted[j], shape)
+	})
+
+	var (
+		bins        []poolResources
+		unplaceable int
+	)
+
+	for _, item := range sorted {
+		if !fitsResources(item, shape) {
+			unplaceable++
+			continue
+		}
+
+		placed := false
+		for i := range bins {
+			if fitsResources(item, bins[i]) {
+				bins[i] = subtractResources(bins[i], item)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			bins = append(bins, subtractResources(shape, item))
+		}
+	}
+
+	return len(bins), unplaceable
+}
+
+// dominantShare returns the largest fraction of any node resource used by
+// the allocation.
+func dominantShare(item, shape poolResources) float64 {
+	var share float64
+	if shape.cpu > 0 {
+		share = float64(item.cpu) / float64(shape.cpu)
+	}
+	if shape.mem > 0 {
+		if s := float64(item.mem) / float64(shape.mem); s > share {
+			share = s
+		}
+	}
+	if shape.gpu > 0 {
+		if s := float64(item.gpu) / float64(shape.gpu); s > share {
+			share = s
+		}
+	}
+	return share
+}
+
+// fitsResources returns whether the allocation fits within the available
+// resources. Memory oversubscription is not accounted for, as the scheduler
+// places allocations based on their memory and not their memory max.
+func fitsResources(item, available poolResources) bool {
+	return item.cpu <= available.cpu && item.mem <= available.mem && item.gpu <= available.gpu
+}
+
+// subtractResources returns the available resources remaining once the
+// allocation is placed.
+func subtractResources(available, item poolResources) poolResources {
+	return poolResources{
+		cpu:    available.cpu - item.cpu,
+		mem:    available.mem - item.mem,
+		memMax: available.memMax - item.memMax,
+		gpu:    available.gpu - item.gpu,
+	}
+}
+
+// minResources returns the smallest value of each resource.
+func minResources(a, b poolResources) poolResources {
+	return poolResources{
+		cpu:    minInt64(a.cpu, b.cpu),
+		mem:    minInt64(a.mem, b.mem),
+		memMax: minInt64(a.memMax, b.memMax),
+		gpu:    minInt64(a.gpu, b.gpu),
+	}
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,194 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_binPack(t *testing.T) {
+	shape := poolResources{cpu: 4000, mem: 8192, gpu: 1}
+
+	testCases := []struct {
+		inputItems          []poolResources
+		expectedCount       int
+		expectedUnplaceable int
+		name                string
+	}{
+		{
+			inputItems:    nil,
+			expectedCount: 0,
+			name:          "no allocations",
+		},
+		{
+			inputItems: []poolResources{
+				{cpu: 1000, mem: 1024},
+				{cpu: 1000, mem: 1024},
+				{cpu: 1000, mem: 1024},
+			},
+			expectedCount: 1,
+			name:          "small allocations share a node",
+		},
+		{
+			inputItems: []poolResources{
+				{cpu: 500, mem: 6144},
+				{cpu: 3000, mem: 1024},
+				{cpu: 500, mem: 2048},
+				{cpu: 1000, mem: 1024},
+			},
+			expectedCount: 2,
+			name:          "heterogeneous allocations",
+		},
+		{
+			inputItems: []poolResources{
+				{cpu: 100, mem: 128, gpu: 1},
+				{cpu: 100, mem: 128, gpu: 1},
+			},
+			expectedCount: 2,
+			name:          "gpu bound allocations",
+		},
+		{
+			inputItems: []poolResources{
+				{cpu: 1000, mem: 1024},
+				{cpu: 8000, mem: 1024},
+				{cpu: 100, mem: 128, gpu: 2},
+			},
+			expectedCount:       1,
+			expectedUnplaceable: 2,
+			name:                "allocations larger than the node shape",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualCount, actualUnplaceable := binPack(shape, tc.inputItems)
+			assert.Equal(t, tc.expectedCount, actualCount, tc.name)
+			assert.Equal(t, tc.expectedUnplaceable, actualUnplaceable, tc.name)
+		})
+	}
+}
+
+func Test_taskGroupResources(t *testing.T) {
+	gpus := uint64(2)
+	tg := &api.TaskGroup{
+		Tasks: []*api.Task{
+			{
+				Resources: &api.Resources{
+					CPU:      ptr.IntToPtr(500),
+					MemoryMB: ptr.IntToPtr(256),
+					Devices: []*api.RequestedDevice{
+						{Name: "nvidia/gpu", Count: &gpus},
+						{Name: "fpga"},
+					},
+				},
+			},
+			{
+				Resources: &api.Resources{
+					CPU:         ptr.IntToPtr(100),
+					MemoryMB:    ptr.IntToPtr(128),
+					MemoryMaxMB: ptr.IntToPtr(512),
+					Devices:     []*api.RequestedDevice{{Name: "gpu"}},
+				},
+			},
+		},
+	}
+
+	expected := poolResources{cpu: 600, mem: 384, memMax: 768, gpu: 3}
+	assert.Equal(t, expected, taskGroupResources(tg))
+}
+
+func Test_blockedAllocCount(t *testing.T) {
+	eval := &api.Evaluation{QueuedAllocations: map[string]int{"cache": 5}}
+
+	assert.Equal(t, 5, blockedAllocCount(eval, "cache", &api.AllocationMetric{CoalescedFailures: 2}))
+	assert.Equal(t, 3, blockedAllocCount(eval, "web", &api.AllocationMetric{CoalescedFailures: 2}))
+	assert.Equal(t, 1, blockedAllocCount(eval, "web", nil))
+}
+
+func Test_isFeasibleOnNode(t *testing.T) {
+	node := &api.NodeListStub{Datacenter: "dc1", NodeClass: "high-memory", NodePool: "default"}
+
+	testCases := []struct {
+		inputJob       *api.Job
+		inputGroup     *api.TaskGroup
+		expectedOutput bool
+		name           string
+	}{
+		{
+			inputJob:       &api.Job{},
+			inputGroup:     &api.TaskGroup{},
+			expectedOutput: true,
+			name:           "no placement requirements",
+		},
+		{
+			inputJob:       &api.Job{NodePool: ptr.StringToPtr("gpu")},
+			inputGroup:     &api.TaskGroup{},
+			expectedOutput: false,
+			name:           "different node pool",
+		},
+		{
+			inputJob:       &api.Job{NodePool: ptr.StringToPtr(api.NodePoolAll)},
+			inputGroup:     &api.TaskGroup{},
+			expectedOutput: true,
+			name:           "all node pool",
+		},
+		{
+			inputJob:       &api.Job{Datacenters: []string{"dc2", "dc*"}},
+			inputGroup:     &api.TaskGroup{},
+			expectedOutput: true,
+			name:           "datacenter glob",
+		},
+		{
+			inputJob:       &api.Job{Datacenters: []string{"dc2"}},
+			inputGroup:     &api.TaskGroup{},
+			expectedOutput: false,
+			name:           "different datacenter",
+		},
+		{
+			inputJob: &api.Job{},
+			inputGroup: &api.TaskGroup{
+				Constraints: []*api.Constraint{{LTarget: "${node.class}", Operand: "=", RTarget: "high-memory"}},
+			},
+			expectedOutput: true,
+			name:           "group node class constraint met",
+		},
+		{
+			inputJob: &api.Job{},
+			inputGroup: &api.TaskGroup{
+				Tasks: []*api.Task{{
+					Constraints: []*api.Constraint{{LTarget: "${node.class}", Operand: "=", RTarget: "gpu"}},
+				}},
+			},
+			expectedOutput: false,
+			name:           "task node class constraint not met",
+		},
+		{
+			inputJob: &api.Job{
+				Constraints: []*api.Constraint{{LTarget: "${node.class}", Operand: "!=", RTarget: "high-memory"}},
+			},
+			inputGroup:     &api.TaskGroup{},
+			expectedOutput: false,
+			name:           "job node class inequality not met",
+		},
+		{
+			inputJob: &api.Job{
+				Constraints: []*api.Constraint{{LTarget: "${attr.kernel.name}", Operand: "=", RTarget: "windows"}},
+			},
+			inputGroup:     &api.TaskGroup{},
+			expectedOutput: true,
+			name:           "other constraints not checked",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput := isFeasibleOnNode(tc.inputJob, tc.inputGroup, node)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+		})
+	}
+}
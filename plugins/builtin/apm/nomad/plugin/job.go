@@ -107,6 +107,29 @@ func (a *APMPlugin) getTaskGroupResourceUsage(query *taskGroupQuery) ([]float64,
 			usageMiB := ru.MemoryStats.Usage / 1024 / 1024
 			*m = append(*m, (float64(usageMiB)/float64(allocatedMem))*100)
 		}
+	case queryMetricMemMaxAlloc:
+
+		// The memory max is the limit up to which tasks may use memory when
+		// oversubscription is enabled, so it is the value usage is compared
+		// against.
+		allocatedMemMax, err := a.getAllocatedMemMaxForTaskGroup(query.job, query.group)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get total allocated memory max for taskgroup: %v", err)
+		}
+
+		metricFunc = func(m *[]float64, ru *api.ResourceUsage) {
+			usageMiB := ru.MemoryStats.Usage / 1024 / 1024
+			*m = append(*m, (float64(usageMiB)/float64(allocatedMemMax))*100)
+		}
+	case queryMetricGPU:
+
+		// Allocations without GPU statistics are skipped, rather than being
+		// counted as idle.
+		metricFunc = func(m *[]float64, ru *api.ResourceUsage) {
+			if util, ok := gpuUtilization(ru.DeviceStats); ok {
+				*m = append(*m, util)
+			}
+		}
 	}
 
 	for _, alloc := range allocs {
@@ -176,6 +199,94 @@ func (a *APMPlugin) getAllocatedMemForTaskGroup(job, taskgroup string) (int, err
 	return taskGroupAllocatedMem, nil
 }
 
+// getAllocatedMemMaxForTaskGroup calculates the total allocated memory max in
+// MiB for a taskgroup. Tasks which do not set a memory max are limited to
+// their allocated memory.
+func (a *APMPlugin) getAllocatedMemMaxForTaskGroup(job, taskgroup string) (int, error) {
+	taskGroupConfig, err := a.getTaskGroup(job, taskgroup)
+	if err != nil {
+		return -1, err
+	}
+
+	taskGroupAllocatedMemMax := 0
+	for _, task := range taskGroupConfig.Tasks {
+		taskGroupAllocatedMemMax += memoryMaxMB(task.Resources)
+	}
+	return taskGroupAllocatedMemMax, nil
+}
+
+// memoryMaxMB returns the memory max of the resources, falling back to the
+// allocated memory when no memory max is set.
+func memoryMaxMB(r *api.Resources) int {
+	switch {
+	case r == nil:
+		return 0
+	case r.MemoryMaxMB != nil && *r.MemoryMaxMB > 0:
+		return *r.MemoryMaxMB
+	case r.MemoryMB != nil:
+		return *r.MemoryMB
+	default:
+		return 0
+	}
+}
+
+// gpuUtilization returns the average utilization percentage of the GPU
+// devices within the device stats. False is returned if no GPU reports its
+// utilization.
+func gpuUtilization(stats []*api.DeviceGroupStats) (float64, bool) {
+	var sum float64
+	var count int
+
+	for _, group := range stats {
+		if group == nil || group.Type != deviceTypeGPU {
+			continue
+		}
+		for _, instance := range group.InstanceStats {
+			if instance == nil || instance.Stats == nil {
+				continue
+			}
+			if v, ok := statValueFloat(instance.Stats.Attributes[gpuUtilizationStat]); ok {
+				sum += v
+				count++
+			}
+		}
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+// statValueFloat converts a numeric device statistic to a float. Fractional
+// values are converted to a percentage.
+func statValueFloat(v *api.StatValue) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+
+	switch {
+	case v.FloatNumeratorVal != nil:
+		if v.FloatDenominatorVal != nil {
+			if *v.FloatDenominatorVal == 0 {
+				return 0, false
+			}
+			return *v.FloatNumeratorVal / *v.FloatDenominatorVal * 100, true
+		}
+		return *v.FloatNumeratorVal, true
+	case v.IntNumeratorVal != nil:
+		if v.IntDenominatorVal != nil {
+			if *v.IntDenominatorVal == 0 {
+				return 0, false
+			}
+			return float64(*v.IntNumeratorVal) / float64(*v.IntDenominatorVal) * 100, true
+		}
+		return float64(*v.IntNumeratorVal), true
+	default:
+		return 0, false
+	}
+}
+
 // getTaskGroup returns a task group configuration from a job.
 func (a *APMPlugin) getTaskGroup(job, taskgroup string) (*api.TaskGroup, error) {
 	jobInfo, _, err := a.client.Jobs().Info(job, nil)
@@ -270,5 +381,8 @@ func parseTaskGroupQuery(q string) (*taskGroupQuery, error) {
 }
 
 func validateMetricTaskGroupQuery(metric string) error {
-	return validateMetric(metric, []string{queryMetricCPU, queryMetricCPUAllocated, queryMetricMem, queryMetricMemAllocated})
+	return validateMetric(metric, []string{
+		queryMetricCPU, queryMetricCPUAllocated, queryMetricMem, queryMetricMemAllocated,
+		queryMetricMemMaxAlloc, queryMetricGPU,
+	})
 }
@@ -6,6 +6,7 @@ package plugin
 import (
 	"testing"
 
+	"github.com/hashicorp/nomad/api"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -101,6 +102,28 @@ func Test_parseTaskGroupQuery(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name:  "avg_memory-max-allocated",
+			input: "taskgroup_avg_memory-max-allocated/group/job",
+			expected: &taskGroupQuery{
+				metric:    "memory-max-allocated",
+				job:       "job",
+				group:     "group",
+				operation: "avg",
+			},
+			expectError: false,
+		},
+		{
+			name:  "max_gpu",
+			input: "taskgroup_max_gpu/group/job",
+			expected: &taskGroupQuery{
+				metric:    "gpu",
+				job:       "job",
+				group:     "group",
+				operation: "max",
+			},
+			expectError: false,
+		},
 		{
 			name:  "job with fwd slashes",
 			input: "taskgroup_avg_cpu/group/my/super/job//",
@@ -158,3 +181,129 @@ func Test_parseTaskGroupQuery(t *testing.T) {
 		})
 	}
 }
+
+func Test_memoryMaxMB(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    *api.Resources
+		expected int
+	}{
+		{
+			name:     "nil resources",
+			input:    nil,
+			expected: 0,
+		},
+		{
+			name:     "memory max set",
+			input:    &api.Resources{MemoryMB: pointerOf(256), MemoryMaxMB: pointerOf(1024)},
+			expected: 1024,
+		},
+		{
+			name:     "memory max not set",
+			input:    &api.Resources{MemoryMB: pointerOf(256)},
+			expected: 256,
+		},
+		{
+			name:     "memory max zero",
+			input:    &api.Resources{MemoryMB: pointerOf(256), MemoryMaxMB: pointerOf(0)},
+			expected: 256,
+		},
+		{
+			name:     "no memory set",
+			input:    &api.Resources{},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, memoryMaxMB(tc.input))
+		})
+	}
+}
+
+func Test_gpuUtilization(t *testing.T) {
+	gpuStats := func(values ...*api.StatValue) *api.DeviceGroupStats {
+		group := &api.DeviceGroupStats{
+			Vendor:        "nvidia",
+			Type:          deviceTypeGPU,
+			Name:          "T4",
+			InstanceStats: map[string]*api.DeviceStats{},
+		}
+		for i, v := range values {
+			group.InstanceStats[string(rune('a'+i))] = &api.DeviceStats{
+				Stats: &api.StatObject{Attributes: map[string]*api.StatValue{gpuUtilizationStat: v}},
+			}
+		}
+		return group
+	}
+
+	testCases := []struct {
+		name          string
+		input         []*api.DeviceGroupStats
+		expected      float64
+		expectedFound bool
+	}{
+		{
+			name:          "no devices",
+			input:         nil,
+			expectedFound: false,
+		},
+		{
+			name: "non gpu devices",
+			input: []*api.DeviceGroupStats{{
+				Type: "fpga",
+				InstanceStats: map[string]*api.DeviceStats{"a": {
+					Stats: &api.StatObject{Attributes: map[string]*api.StatValue{
+						gpuUtilizationStat: {IntNumeratorVal: pointerOf(int64(50))},
+					}},
+				}},
+			}},
+			expectedFound: false,
+		},
+		{
+			name:          "single gpu",
+			input:         []*api.DeviceGroupStats{gpuStats(&api.StatValue{IntNumeratorVal: pointerOf(int64(40))})},
+			expected:      40,
+			expectedFound: true,
+		},
+		{
+			name: "multiple gpus",
+			input: []*api.DeviceGroupStats{
+				gpuStats(
+					&api.StatValue{IntNumeratorVal: pointerOf(int64(40))},
+					&api.StatValue{FloatNumeratorVal: pointerOf(80.0)},
+				),
+				gpuStats(&api.StatValue{IntNumeratorVal: pointerOf(int64(1)), IntDenominatorVal: pointerOf(int64(4))}),
+			},
+			expected:      (40 + 80 + 25) / 3.0,
+			expectedFound: true,
+		},
+		{
+			name:          "missing utilization stat",
+			input:         []*api.DeviceGroupStats{gpuStats(nil, &api.StatValue{IntNumeratorVal: pointerOf(int64(60))})},
+			expected:      60,
+			expectedFound: true,
+		},
+		{
+			name:          "non numeric stat",
+			input:         []*api.DeviceGroupStats{gpuStats(&api.StatValue{StringVal: pointerOf("busy")})},
+			expectedFound: false,
+		},
+		{
+			name:          "zero denominator",
+			input:         []*api.DeviceGroupStats{gpuStats(&api.StatValue{FloatNumeratorVal: pointerOf(1.0), FloatDenominatorVal: pointerOf(0.0)})},
+			expectedFound: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, found := gpuUtilization(tc.input)
+			assert.Equal(t, tc.expectedFound, found)
+			assert.InDelta(t, tc.expected, actual, 0.0001)
+		})
+	}
+}
+
+func pointerOf[T any](v T) *T { return &v }
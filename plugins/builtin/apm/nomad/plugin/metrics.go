@@ -25,12 +25,29 @@ const (
 
 	// queryOps below are the supported operators for node pool queries.
 	queryOpPercentageAllocated = "percentage-allocated"
+	queryOpBinPacked           = "bin-packed"
+
+	// queryPoolKeys are the supported pool identifier keys for node pool
+	// queries. The node_class target config key is also accepted for class.
+	queryPoolKeyClass      = "class"
+	queryPoolKeyDatacenter = "datacenter"
+	queryPoolKeyNodePool   = "node_pool"
 
 	// queryMetrics are the supported resources for querying.
 	queryMetricCPU          = "cpu"
 	queryMetricCPUAllocated = "cpu-allocated"
 	queryMetricMem          = "memory"
 	queryMetricMemAllocated = "memory-allocated"
+	queryMetricMemMax       = "memory-max"
+	queryMetricMemMaxAlloc  = "memory-max-allocated"
+	queryMetricGPU          = "gpu"
+	queryMetricCount        = "count"
+	queryMetricBlocked      = "blocked"
+
+	// deviceTypeGPU is the Nomad device type of GPUs, and gpuUtilizationStat
+	// is the device statistic reporting their utilization percentage.
+	deviceTypeGPU      = "gpu"
+	gpuUtilizationStat = "GPU utilization"
 )
 
 // Query satisfies the Query function on the apm.APM interface.
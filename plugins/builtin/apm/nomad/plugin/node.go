@@ -29,8 +29,10 @@ type nodePoolResources struct {
 }
 
 type poolResources struct {
-	cpu int64
-	mem int64
+	cpu    int64
+	mem    int64
+	memMax int64
+	gpu    int64
 }
 
 // queryNodePool is the main entry point when performing a Nomad node pool APM
@@ -45,6 +47,10 @@ func (a *APMPlugin) queryNodePool(q string) (sdk.TimestampedMetrics, error) {
 	}
 	a.logger.Debug("performing node pool APM query", "query", q)
 
+	if query.operation == queryOpBinPacked {
+		return a.queryBinPackedCount(query.poolIdentifier, query.metric == queryMetricBlocked)
+	}
+
 	// Identify the resource available and consumed within the target pool.
 	resources, err := a.getPoolResources(query.poolIdentifier)
 	if err != nil {
@@ -52,7 +58,9 @@ func (a *APMPlugin) queryNodePool(q string) (sdk.TimestampedMetrics, error) {
 	}
 	a.logger.Debug("collected node pool resource data",
 		"allocated_cpu", resources.allocated.cpu, "allocated_memory", resources.allocated.mem,
-		"allocatable_cpu", resources.allocatable.cpu, "allocatable_memory", resources.allocatable.mem)
+		"allocated_memory_max", resources.allocated.memMax, "allocated_gpu", resources.allocated.gpu,
+		"allocatable_cpu", resources.allocatable.cpu, "allocatable_memory", resources.allocatable.mem,
+		"allocatable_gpu", resources.allocatable.gpu)
 
 	var result float64
 
@@ -69,6 +77,18 @@ func (a *APMPlugin) queryNodePool(q string) (sdk.TimestampedMetrics, error) {
 			return nil, errors.New("zero allocatable cpu found in pool")
 		}
 		result = calculateNodePoolResult(float64(resources.allocated.cpu), float64(resources.allocatable.cpu))
+	case queryMetricMemMax:
+		// The memory max of allocations is compared against the allocatable
+		// memory, so oversubscribed pools report over 100%.
+		if resources.allocatable.mem == 0 {
+			return nil, errors.New("zero allocatable memory found in pool")
+		}
+		result = calculateNodePoolResult(float64(resources.allocated.memMax), float64(resources.allocatable.mem))
+	case queryMetricGPU:
+		if resources.allocatable.gpu == 0 {
+			return nil, errors.New("zero allocatable gpu found in pool")
+		}
+		result = calculateNodePoolResult(float64(resources.allocated.gpu), float64(resources.allocatable.gpu))
 	}
 
 	tm := sdk.TimestampedMetric{
@@ -132,12 +152,24 @@ func (a *APMPlugin) getNodeAllocatableResources(nodeID string, pool *poolResourc
 
 	// Update our tracking, making sure to account for reserved resources
 	// on the node.
-	pool.cpu += nodeInfo.NodeResources.Cpu.CpuShares - int64(nodeInfo.ReservedResources.Cpu.CpuShares)
-	pool.mem += nodeInfo.NodeResources.Memory.MemoryMB - int64(nodeInfo.ReservedResources.Memory.MemoryMB)
+	res := nodeAllocatableResources(nodeInfo)
+	pool.cpu += res.cpu
+	pool.mem += res.mem
+	pool.gpu += res.gpu
 
 	return nil
 }
 
+// nodeAllocatableResources returns the resources of the node available for
+// scheduling, accounting for the resources reserved on the node.
+func nodeAllocatableResources(node *api.Node) poolResources {
+	return poolResources{
+		cpu: node.NodeResources.Cpu.CpuShares - int64(node.ReservedResources.Cpu.CpuShares),
+		mem: node.NodeResources.Memory.MemoryMB - int64(node.ReservedResources.Memory.MemoryMB),
+		gpu: countHealthyGPUs(node.NodeResources.Devices),
+	}
+}
+
 // getNodeAllocatedResources updates the poolResources tracking with the
 // allocated resources on the node.
 func (a *APMPlugin) getNodeAllocatedResources(nodeID string, pool *poolResources) error {
@@ -155,46 +187,135 @@ func (a *APMPlugin) getNodeAllocatedResources(nodeID string, pool *poolResources
 		}
 
 		// Update our tracking with the resources of the allocation.
-		pool.cpu += int64(*alloc.Resources.CPU)
-		pool.mem += int64(*alloc.Resources.MemoryMB)
+		res := allocResources(alloc)
+		pool.cpu += res.cpu
+		pool.mem += res.mem
+		pool.memMax += res.memMax
+		pool.gpu += res.gpu
 	}
 
 	return nil
 }
 
+// allocResources returns the resources used by the allocation.
+func allocResources(alloc *api.Allocation) poolResources {
+	return poolResources{
+		cpu:    int64(*alloc.Resources.CPU),
+		mem:    int64(*alloc.Resources.MemoryMB),
+		memMax: int64(memoryMaxMB(alloc.Resources)),
+		gpu:    countAllocatedGPUs(alloc.AllocatedResources),
+	}
+}
+
+// countHealthyGPUs returns the number of healthy GPU device instances, which
+// are the GPUs available for scheduling.
+func countHealthyGPUs(devices []*api.NodeDeviceResource) int64 {
+	var count int64
+	for _, device := range devices {
+		if device == nil || device.Type != deviceTypeGPU {
+			continue
+		}
+		for _, instance := range device.Instances {
+			if instance != nil && instance.Healthy {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// countAllocatedGPUs returns the number of GPU device instances assigned to
+// the tasks of an allocation.
+func countAllocatedGPUs(resources *api.AllocatedResources) int64 {
+	if resources == nil {
+		return 0
+	}
+
+	var count int64
+	for _, task := range resources.Tasks {
+		if task == nil {
+			continue
+		}
+		for _, device := range task.Devices {
+			if device != nil && device.Type == deviceTypeGPU {
+				count += int64(len(device.DeviceIDs))
+			}
+		}
+	}
+	return count
+}
+
+// parseNodePoolQuery parses a node pool query in the
+// <query>/<pool_identifier_value>/<pool_identifier_key> format. Further value
+// and key pairs may be appended, in which case nodes must match all of them.
 func parseNodePoolQuery(q string) (*nodePoolQuery, error) {
 
-	mainParts := strings.SplitN(q, "/", 3)
-	if len(mainParts) != 3 {
+	mainParts := strings.Split(q, "/")
+	if len(mainParts) < 3 || len(mainParts)%2 != 1 {
 		return nil, fmt.Errorf("expected <query>/<pool_identifier_value>/<pool_identifier_key>, received %s", q)
 	}
 
-	query := nodePoolQuery{
-		poolIdentifier: nodepool.NewNodeClassPoolIdentifier(mainParts[1]),
-	}
+	query := nodePoolQuery{}
 
 	opMetricParts := strings.SplitN(mainParts[0], "_", 3)
 	if len(opMetricParts) != 3 {
 		return nil, fmt.Errorf("expected node_<operation>_<metric>, received %s", mainParts[0])
 	}
 
-	if err := validateMetricNodeQuery(opMetricParts[2]); err != nil {
+	// The metrics available depend on the operation, so the operation is
+	// validated first.
+	var err error
+	switch opMetricParts[1] {
+	case queryOpPercentageAllocated:
+		err = validateMetricNodeQuery(opMetricParts[2])
+	case queryOpBinPacked:
+		err = validateMetric(opMetricParts[2], []string{queryMetricCount, queryMetricBlocked})
+	default:
+		return nil, fmt.Errorf("invalid operation %q, allowed values are: %s, %s",
+			opMetricParts[1], queryOpPercentageAllocated, queryOpBinPacked)
+	}
+	if err != nil {
 		return nil, err
 	}
+	query.operation = opMetricParts[1]
 	query.metric = opMetricParts[2]
 
-	switch opMetricParts[1] {
-	case queryOpPercentageAllocated:
-		query.operation = opMetricParts[1]
-	default:
-		return nil, fmt.Errorf("invalid operation %q, allowed value is %s",
-			opMetricParts[1], queryOpPercentageAllocated)
+	var ids []nodepool.ClusterNodePoolIdentifier
+
+	for i := 1; i < len(mainParts); i += 2 {
+		id, err := parseNodePoolIdentifier(mainParts[i], mainParts[i+1])
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 1 {
+		query.poolIdentifier = ids[0]
+	} else {
+		query.poolIdentifier = nodepool.NewCombinedClusterPoolIdentifier(ids, nodepool.CombinedClusterPoolIdentifierAnd)
 	}
 	return &query, nil
 }
 
+// parseNodePoolIdentifier returns the pool identifier for the value and key
+// pair of a node pool query.
+func parseNodePoolIdentifier(value, key string) (nodepool.ClusterNodePoolIdentifier, error) {
+	switch key {
+	case queryPoolKeyClass, sdk.TargetConfigKeyClass:
+		return nodepool.NewNodeClassPoolIdentifier(value), nil
+	case queryPoolKeyDatacenter:
+		return nodepool.NewNodeDatacenterPoolIdentifier(value), nil
+	case queryPoolKeyNodePool:
+		return nodepool.NewNodePoolIdentifier(value), nil
+	default:
+		return nil, fmt.Errorf("invalid pool identifier key %q, allowed values are: %s, %s, %s",
+			key, queryPoolKeyClass, queryPoolKeyDatacenter, queryPoolKeyNodePool)
+	}
+}
+
 func validateMetricNodeQuery(metric string) error {
-	return validateMetric(metric, []string{queryMetricCPU, queryMetricMem})
+	return validateMetric(metric, []string{queryMetricCPU, queryMetricMem, queryMetricMemMax, queryMetricGPU})
 }
 
 // calculateNodePoolResult returns the current usage percentage of the node
@@ -39,6 +39,82 @@ func Test_parseNodePoolQuery(t *testing.T) {
 			expectError: nil,
 			name:        "node percentage-allocated cpu",
 		},
+		{
+			inputQuery: "node_percentage-allocated_memory-max/high-memory/class",
+			expectedOutputQuery: &nodePoolQuery{
+				metric:         "memory-max",
+				poolIdentifier: nodepool.NewNodeClassPoolIdentifier("high-memory"),
+				operation:      "percentage-allocated",
+			},
+			expectError: nil,
+			name:        "node percentage-allocated memory-max",
+		},
+		{
+			inputQuery: "node_percentage-allocated_gpu/gpu/class",
+			expectedOutputQuery: &nodePoolQuery{
+				metric:         "gpu",
+				poolIdentifier: nodepool.NewNodeClassPoolIdentifier("gpu"),
+				operation:      "percentage-allocated",
+			},
+			expectError: nil,
+			name:        "node percentage-allocated gpu",
+		},
+		{
+			inputQuery: "node_percentage-allocated_cpu/gpu/node_pool",
+			expectedOutputQuery: &nodePoolQuery{
+				metric:         "cpu",
+				poolIdentifier: nodepool.NewNodePoolIdentifier("gpu"),
+				operation:      "percentage-allocated",
+			},
+			expectError: nil,
+			name:        "node pool identifier",
+		},
+		{
+			inputQuery: "node_bin-packed_count/high-compute/class",
+			expectedOutputQuery: &nodePoolQuery{
+				metric:         "count",
+				poolIdentifier: nodepool.NewNodeClassPoolIdentifier("high-compute"),
+				operation:      "bin-packed",
+			},
+			expectError: nil,
+			name:        "node bin-packed count",
+		},
+		{
+			inputQuery: "node_bin-packed_blocked/high-compute/node_class",
+			expectedOutputQuery: &nodePoolQuery{
+				metric:         "blocked",
+				poolIdentifier: nodepool.NewNodeClassPoolIdentifier("high-compute"),
+				operation:      "bin-packed",
+			},
+			expectError: nil,
+			name:        "node bin-packed blocked",
+		},
+		{
+			inputQuery: "node_percentage-allocated_cpu/dc1/datacenter",
+			expectedOutputQuery: &nodePoolQuery{
+				metric:         "cpu",
+				poolIdentifier: nodepool.NewNodeDatacenterPoolIdentifier("dc1"),
+				operation:      "percentage-allocated",
+			},
+			expectError: nil,
+			name:        "datacenter identifier",
+		},
+		{
+			inputQuery: "node_percentage-allocated_memory/high-memory/class/gpu/node_pool",
+			expectedOutputQuery: &nodePoolQuery{
+				metric: "memory",
+				poolIdentifier: nodepool.NewCombinedClusterPoolIdentifier(
+					[]nodepool.ClusterNodePoolIdentifier{
+						nodepool.NewNodeClassPoolIdentifier("high-memory"),
+						nodepool.NewNodePoolIdentifier("gpu"),
+					},
+					nodepool.CombinedClusterPoolIdentifierAnd,
+				),
+				operation: "percentage-allocated",
+			},
+			expectError: nil,
+			name:        "multiple identifiers",
+		},
 
 		{
 			inputQuery:          "",
@@ -58,22 +134,46 @@ func Test_parseNodePoolQuery(t *testing.T) {
 			expectError:         errors.New("expected <query>/<pool_identifier_value>/<pool_identifier_key>, received node_percentage-allocated_cpu/class"),
 			name:                "missing node pool identifier value",
 		},
+		{
+			inputQuery:          "node_percentage-allocated_cpu/gpu/node_pool/dc1",
+			expectedOutputQuery: nil,
+			expectError:         errors.New("expected <query>/<pool_identifier_value>/<pool_identifier_key>, received node_percentage-allocated_cpu/gpu/node_pool/dc1"),
+			name:                "missing additional identifier key",
+		},
+		{
+			inputQuery:          "node_percentage-allocated_cpu/gpu/invalid",
+			expectedOutputQuery: nil,
+			expectError:         errors.New("invalid pool identifier key \"invalid\", allowed values are: class, datacenter, node_pool"),
+			name:                "invalid identifier key",
+		},
 		{
 			inputQuery:          "node_percentage-allocated_invalid/class/high-compute",
 			expectedOutputQuery: nil,
-			expectError:         errors.New("invalid metric \"invalid\", allowed values are: cpu, memory"),
+			expectError:         errors.New("invalid metric \"invalid\", allowed values are: cpu, memory, memory-max, gpu"),
 			name:                "invalid metric",
 		},
 		{
 			inputQuery:          "node_percentage-allocated_cpu-allocated/class/high-compute",
 			expectedOutputQuery: nil,
-			expectError:         errors.New("invalid metric \"cpu-allocated\", allowed values are: cpu, memory"),
+			expectError:         errors.New("invalid metric \"cpu-allocated\", allowed values are: cpu, memory, memory-max, gpu"),
 			name:                "metric for task group queries only",
 		},
+		{
+			inputQuery:          "node_bin-packed_cpu/class/high-compute",
+			expectedOutputQuery: nil,
+			expectError:         errors.New("invalid metric \"cpu\", allowed values are: count, blocked"),
+			name:                "metric for percentage-allocated queries only",
+		},
+		{
+			inputQuery:          "node_percentage-allocated_count/class/high-compute",
+			expectedOutputQuery: nil,
+			expectError:         errors.New("invalid metric \"count\", allowed values are: cpu, memory, memory-max, gpu"),
+			name:                "metric for bin-packed queries only",
+		},
 		{
 			inputQuery:          "node_invalid_cpu/class/high-compute",
 			expectedOutputQuery: nil,
-			expectError:         errors.New("invalid operation \"invalid\", allowed value is percentage-allocated"),
+			expectError:         errors.New("invalid operation \"invalid\", allowed values are: percentage-allocated, bin-packed"),
 			name:                "invalid operation",
 		},
 	}
@@ -122,6 +222,52 @@ func Test_calculateNodePoolResult(t *testing.T) {
 	}
 }
 
+func Test_countHealthyGPUs(t *testing.T) {
+	devices := []*api.NodeDeviceResource{
+		{
+			Type: deviceTypeGPU,
+			Instances: []*api.NodeDevice{
+				{ID: "a", Healthy: true},
+				{ID: "b", Healthy: false},
+				{ID: "c", Healthy: true},
+			},
+		},
+		{
+			Type:      "fpga",
+			Instances: []*api.NodeDevice{{ID: "d", Healthy: true}},
+		},
+		{
+			Type:      deviceTypeGPU,
+			Instances: []*api.NodeDevice{{ID: "e", Healthy: true}},
+		},
+	}
+
+	assert.Equal(t, int64(3), countHealthyGPUs(devices))
+	assert.Equal(t, int64(0), countHealthyGPUs(nil))
+}
+
+func Test_countAllocatedGPUs(t *testing.T) {
+	resources := &api.AllocatedResources{
+		Tasks: map[string]*api.AllocatedTaskResources{
+			"train": {
+				Devices: []*api.AllocatedDeviceResource{
+					{Type: deviceTypeGPU, DeviceIDs: []string{"a", "c"}},
+					{Type: "fpga", DeviceIDs: []string{"d"}},
+				},
+			},
+			"sidecar": {},
+			"infer": {
+				Devices: []*api.AllocatedDeviceResource{
+					{Type: deviceTypeGPU, DeviceIDs: []string{"e"}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, int64(3), countAllocatedGPUs(resources))
+	assert.Equal(t, int64(0), countAllocatedGPUs(nil))
+}
+
 func Test_isServerTerminalStatus(t *testing.T) {
 	testCases := []struct {
 		inputAlloc     *api.Allocation
@@ -0,0 +1,302 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "otel"
+
+	// configKeyGRPCAddress and configKeyHTTPAddress are the addresses the
+	// OTLP/gRPC and OTLP/HTTP receivers listen on. They default to the
+	// standard OTLP ports, and setting either to an empty value disables the
+	// receiver.
+	configKeyGRPCAddress          = "grpc_address"
+	configValueGRPCAddressDefault = "0.0.0.0:4317"
+	configKeyHTTPAddress          = "http_address"
+	configValueHTTPAddressDefault = "0.0.0.0:4318"
+
+	// configKeyRetention is the duration received points are buffered for,
+	// which limits the query window of checks.
+	configKeyRetention          = "retention"
+	configValueRetentionDefault = 15 * time.Minute
+
+	// configKeyAggregationInterval is the bucket size used when queries
+	// aggregate multiple series.
+	configKeyAggregationInterval          = "aggregation_interval"
+	configValueAggregationIntervalDefault = 10 * time.Second
+
+	// pruneInterval is how often expired points are removed from the buffer.
+	pruneInterval = 30 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewOTelPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the OpenTelemetry implementation of the apm.APM interface. It
+// runs OTLP receivers which buffer recently pushed metrics in memory, so
+// applications can provide scaling metrics without an external backend.
+type APMPlugin struct {
+	logger hclog.Logger
+	store  *store
+
+	// lock protects the fields below, which are updated by SetConfig.
+	lock                sync.Mutex
+	aggregationInterval time.Duration
+	grpcAddr            string
+	grpcServer          *grpc.Server
+	grpcListener        net.Listener
+	httpAddr            string
+	httpServer          *http.Server
+	httpListener        net.Listener
+	stopCh              chan struct{}
+}
+
+func NewOTelPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+		store:  newStore(configValueRetentionDefault),
+	}
+}
+
+// SetConfig configures the plugin and starts the receivers. When called
+// again, receivers are only restarted if their address has changed, and
+// buffered metrics are kept.
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	retention := configValueRetentionDefault
+	if v := config[configKeyRetention]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyRetention, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("%q must be greater than zero", configKeyRetention)
+		}
+		retention = d
+	}
+
+	interval := configValueAggregationIntervalDefault
+	if v := config[configKeyAggregationInterval]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyAggregationInterval, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("%q must be greater than zero", configKeyAggregationInterval)
+		}
+		interval = d
+	}
+
+	grpcAddr, ok := config[configKeyGRPCAddress]
+	if !ok {
+		grpcAddr = configValueGRPCAddressDefault
+	}
+	httpAddr, ok := config[configKeyHTTPAddress]
+	if !ok {
+		httpAddr = configValueHTTPAddressDefault
+	}
+	if grpcAddr == "" && httpAddr == "" {
+		return fmt.Errorf("at least one of %q or %q must be set", configKeyGRPCAddress, configKeyHTTPAddress)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.store.setRetention(retention)
+	a.aggregationInterval = interval
+
+	if grpcAddr != a.grpcAddr {
+		a.stopGRPCLocked()
+		if err := a.startGRPCLocked(grpcAddr); err != nil {
+			return err
+		}
+	}
+	if httpAddr != a.httpAddr {
+		a.stopHTTPLocked()
+		if err := a.startHTTPLocked(httpAddr); err != nil {
+			return err
+		}
+	}
+
+	if a.stopCh == nil {
+		a.stopCh = make(chan struct{})
+		go a.pruneLoop(a.stopCh)
+	}
+
+	return nil
+}
+
+func (a *APMPlugin) startGRPCLocked(addr string) error {
+	if addr == "" {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start OTLP/gRPC receiver: %v", err)
+	}
+
+	srv := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(srv, &grpcReceiver{store: a.store})
+
+	a.grpcAddr = addr
+	a.grpcServer = srv
+	a.grpcListener = ln
+
+	a.logger.Info("starting OTLP/gRPC receiver", "address", ln.Addr().String())
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			a.logger.Error("OTLP/gRPC receiver stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+func (a *APMPlugin) stopGRPCLocked() {
+	if a.grpcServer == nil {
+		return
+	}
+	a.grpcServer.Stop()
+	a.grpcAddr, a.grpcServer, a.grpcListener = "", nil, nil
+}
+
+func (a *APMPlugin) startHTTPLocked(addr string) error {
+	if addr == "" {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start OTLP/HTTP receiver: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(otlpMetricsPath, &httpReceiver{store: a.store})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	a.httpAddr = addr
+	a.httpServer = srv
+	a.httpListener = ln
+
+	a.logger.Info("starting OTLP/HTTP receiver", "address", ln.Addr().String())
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Error("OTLP/HTTP receiver stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+func (a *APMPlugin) stopHTTPLocked() {
+	if a.httpServer == nil {
+		return
+	}
+	_ = a.httpServer.Close()
+	a.httpAddr, a.httpServer, a.httpListener = "", nil, nil
+}
+
+func (a *APMPlugin) pruneLoop(stopCh chan struct{}) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case now := <-ticker.C:
+			a.store.prune(now)
+		}
+	}
+}
+
+// Close stops the receivers. It implements io.Closer so the plugin manager
+// stops the receivers when the plugin is removed.
+func (a *APMPlugin) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.stopGRPCLocked()
+	a.stopHTTPLocked()
+
+	if a.stopCh != nil {
+		close(a.stopCh)
+		a.stopCh = nil
+	}
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple returns the buffered points of the series matching the query
+// within the time range.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying OTLP metrics", "query", q, "range", r)
+
+	parsed, err := parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	results := a.store.query(parsed.name, parsed.matchers, r)
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from otel, try a wider query window")
+		return nil, nil
+	}
+
+	if parsed.aggregation != "" {
+		a.lock.Lock()
+		interval := a.aggregationInterval
+		a.lock.Unlock()
+
+		results = []sdk.TimestampedMetrics{parsed.aggregate(results, interval)}
+	}
+	return results, nil
+}
@@ -0,0 +1,237 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "invalid retention",
+			config:        map[string]string{"retention": "forever"},
+			expectedError: `failed to parse "retention"`,
+		},
+		{
+			name:          "negative aggregation interval",
+			config:        map[string]string{"aggregation_interval": "-1s"},
+			expectedError: `"aggregation_interval" must be greater than zero`,
+		},
+		{
+			name:          "receivers disabled",
+			config:        map[string]string{"grpc_address": "", "http_address": ""},
+			expectedError: `at least one of "grpc_address" or "http_address" must be set`,
+		},
+		{
+			name:          "invalid address",
+			config:        map[string]string{"grpc_address": "127.0.0.1:-1", "http_address": ""},
+			expectedError: "failed to start OTLP/gRPC receiver",
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"grpc_address": "127.0.0.1:0", "http_address": "127.0.0.1:0", "retention": "5m"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := NewOTelPlugin(hclog.NewNullLogger()).(*APMPlugin)
+			defer apmPlugin.Close()
+
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.grpcServer)
+			assert.NotNil(t, apmPlugin.httpServer)
+		})
+	}
+}
+
+func TestAPMPlugin_SetConfig_reload(t *testing.T) {
+	apmPlugin := NewOTelPlugin(hclog.NewNullLogger()).(*APMPlugin)
+	defer apmPlugin.Close()
+
+	config := map[string]string{"grpc_address": "127.0.0.1:0", "http_address": "127.0.0.1:0"}
+	require.NoError(t, apmPlugin.SetConfig(config))
+	grpcServer, httpServer := apmPlugin.grpcServer, apmPlugin.httpServer
+
+	// Receivers which keep their address should not be restarted.
+	config["http_address"] = ""
+	require.NoError(t, apmPlugin.SetConfig(config))
+	assert.Same(t, grpcServer, apmPlugin.grpcServer)
+	assert.Nil(t, apmPlugin.httpServer)
+	assert.NotNil(t, httpServer)
+}
+
+func TestAPMPlugin_grpcReceiver(t *testing.T) {
+	apmPlugin := NewOTelPlugin(hclog.NewNullLogger()).(*APMPlugin)
+	defer apmPlugin.Close()
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{"grpc_address": "127.0.0.1:0", "http_address": ""}))
+
+	conn, err := grpc.Dial(apmPlugin.grpcListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	now := time.Now().Truncate(time.Second)
+	req := testExportRequest(now)
+
+	resp, err := colmetricspb.NewMetricsServiceClient(conn).Export(context.Background(), req)
+	require.NoError(t, err)
+	assert.Nil(t, resp.GetPartialSuccess())
+
+	r := sdk.TimeRange{From: now.Add(-time.Minute), To: now}
+
+	metrics, err := apmPlugin.QueryMultiple(`http.server.active_requests{service.name="web"}`, r)
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{{Timestamp: now.Add(-20 * time.Second), Value: 2}, {Timestamp: now, Value: 4}},
+		{{Timestamp: now, Value: 6}},
+	}, metrics)
+
+	metric, err := apmPlugin.Query(`sum:http.server.active_requests{service.name=web}`, r)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: now.Add(-20 * time.Second).Truncate(10 * time.Second), Value: 2},
+		{Timestamp: now.Truncate(10 * time.Second), Value: 10},
+	}, metric)
+
+	metric, err = apmPlugin.Query(`http.server.duration_count{http.route="/"}`, r)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{{Timestamp: now, Value: 12}}, metric)
+
+	_, err = apmPlugin.Query(`http.server.active_requests`, r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_httpReceiver(t *testing.T) {
+	apmPlugin := NewOTelPlugin(hclog.NewNullLogger()).(*APMPlugin)
+	defer apmPlugin.Close()
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{"grpc_address": "", "http_address": "127.0.0.1:0"}))
+
+	url := "http://" + apmPlugin.httpListener.Addr().String() + "/v1/metrics"
+	now := time.Now().Truncate(time.Second)
+
+	body, err := proto.Marshal(testExportRequest(now))
+	require.NoError(t, err)
+
+	resp, err := http.Post(url, "application/x-protobuf", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-protobuf", resp.Header.Get("Content-Type"))
+
+	// The JSON encoding uses string encoded 64 bit integers. The data point
+	// without a timestamp should be rejected.
+	jsonBody := `{"resourceMetrics": [{"scopeMetrics": [{"metrics": [{
+  "name": "queue.depth",
+  "gauge": {"dataPoints": [{"asInt": "42", "timeUnixNano": "` + strconv.FormatInt(now.UnixNano(), 10) + `"}, {"asInt": "1"}]}
+}]}]}]}`
+
+	resp, err = http.Post(url, "application/json", strings.NewReader(jsonBody))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var respBody bytes.Buffer
+	_, err = respBody.ReadFrom(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, respBody.String(), `"rejectedDataPoints":"1"`)
+
+	resp, err = http.Post(url, "text/plain", strings.NewReader("queue.depth 42"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	r := sdk.TimeRange{From: now.Add(-time.Minute), To: now}
+
+	metric, err := apmPlugin.Query("queue.depth", r)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{{Timestamp: now, Value: 42}}, metric)
+
+	metric, err = apmPlugin.Query("max:http.server.active_requests", r)
+	require.NoError(t, err)
+	assert.Len(t, metric, 2)
+	assert.Equal(t, float64(6), metric[1].Value)
+}
+
+// testExportRequest returns an export request containing a gauge reported
+// by two instances of the web service and a histogram.
+func testExportRequest(now time.Time) *colmetricspb.ExportMetricsServiceRequest {
+	instance := func(id string, points ...*metricspb.NumberDataPoint) *metricspb.ResourceMetrics {
+		return &metricspb.ResourceMetrics{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				stringAttr("service.name", "web"),
+				stringAttr("service.instance.id", id),
+			}},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{{
+					Name: "http.server.active_requests",
+					Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}},
+				}},
+			}},
+		}
+	}
+
+	point := func(ts time.Time, v int64) *metricspb.NumberDataPoint {
+		return &metricspb.NumberDataPoint{
+			TimeUnixNano: uint64(ts.UnixNano()),
+			Value:        &metricspb.NumberDataPoint_AsInt{AsInt: v},
+		}
+	}
+
+	histogram := instance("a")
+	sum := 1.5
+	histogram.ScopeMetrics[0].Metrics = []*metricspb.Metric{{
+		Name: "http.server.duration",
+		Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints: []*metricspb.HistogramDataPoint{{
+				Attributes:   []*commonpb.KeyValue{stringAttr("http.route", "/")},
+				TimeUnixNano: uint64(now.UnixNano()),
+				Count:        12,
+				Sum:          &sum,
+			}},
+		}},
+	}}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			instance("a", point(now.Add(-20*time.Second), 2), point(now, 4)),
+			instance("b", point(now, 6)),
+			histogram,
+		},
+	}
+}
+
+func stringAttr(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   k,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}},
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// queryRe matches the check query form:
+//
+//	[<aggregation>:]<metric_name>[{<attribute>=<value>,...}]
+var queryRe = regexp.MustCompile(`^(?:([a-z0-9]+):)?([^:{}\s]+)(?:\{([^{}]*)\})?$`)
+
+// query is a parsed check query.
+type query struct {
+	aggregation string
+	name        string
+	matchers    map[string]string
+}
+
+// parseQuery parses the check query, such as
+// `sum:http.server.active_requests{service.name="web"}`.
+//
+// Series are selected by the metric name and the optional attribute values,
+// which may be quoted. Without an aggregation a metric stream is returned for
+// each matching series. Otherwise the series are combined into a single
+// stream using sum, avg, min or max.
+func parseQuery(q string) (*query, error) {
+	match := queryRe.FindStringSubmatch(strings.TrimSpace(q))
+	if match == nil {
+		return nil, fmt.Errorf("invalid query %q", q)
+	}
+
+	parsed := &query{
+		aggregation: match[1],
+		name:        match[2],
+		matchers:    make(map[string]string),
+	}
+
+	switch parsed.aggregation {
+	case "", "sum", "avg", "min", "max":
+	default:
+		return nil, fmt.Errorf("unsupported aggregation %q", parsed.aggregation)
+	}
+
+	if strings.TrimSpace(match[3]) == "" {
+		return parsed, nil
+	}
+
+	for _, m := range strings.Split(match[3], ",") {
+		k, v, ok := strings.Cut(m, "=")
+		k = strings.TrimSpace(k)
+		v = strings.TrimSpace(v)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid attribute matcher %q", strings.TrimSpace(m))
+		}
+
+		if strings.HasPrefix(v, `"`) {
+			unquoted, err := strconv.Unquote(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid attribute value %s: %v", v, err)
+			}
+			v = unquoted
+		}
+		parsed.matchers[k] = v
+	}
+
+	return parsed, nil
+}
+
+// aggregate combines the series into a single metric stream. Points are
+// grouped into buckets of the passed interval, using the latest point of
+// each series within the bucket, and the bucket values are combined using
+// the query aggregation.
+func (q *query) aggregate(series []sdk.TimestampedMetrics, interval time.Duration) sdk.TimestampedMetrics {
+	buckets := make(map[int64][]float64)
+
+	for _, s := range series {
+		latest := make(map[int64]float64)
+		for _, p := range s {
+			latest[p.Timestamp.Truncate(interval).UnixNano()] = p.Value
+		}
+		for b, v := range latest {
+			buckets[b] = append(buckets[b], v)
+		}
+	}
+
+	keys := make([]int64, 0, len(buckets))
+	for k := range buckets {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	result := make(sdk.TimestampedMetrics, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, sdk.TimestampedMetric{
+			Timestamp: time.Unix(0, k),
+			Value:     q.combine(buckets[k]),
+		})
+	}
+	return result
+}
+
+func (q *query) combine(values []float64) float64 {
+	var result float64
+
+	switch q.aggregation {
+	case "sum", "avg":
+		for _, v := range values {
+			result += v
+		}
+		if q.aggregation == "avg" {
+			result /= float64(len(values))
+		}
+	case "min":
+		result = math.Inf(1)
+		for _, v := range values {
+			result = math.Min(result, v)
+		}
+	case "max":
+		result = math.Inf(-1)
+		for _, v := range values {
+			result = math.Max(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseQuery(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		expected      *query
+		expectedError string
+	}{
+		{
+			name:     "name only",
+			query:    "queue.depth",
+			expected: &query{name: "queue.depth", matchers: map[string]string{}},
+		},
+		{
+			name:  "aggregation and matchers",
+			query: `avg:http.server.active_requests{service.name="web", http.route=/api}`,
+			expected: &query{
+				aggregation: "avg",
+				name:        "http.server.active_requests",
+				matchers:    map[string]string{"service.name": "web", "http.route": "/api"},
+			},
+		},
+		{
+			name:          "unsupported aggregation",
+			query:         "p95:http.server.duration",
+			expectedError: `unsupported aggregation "p95"`,
+		},
+		{
+			name:          "invalid matcher",
+			query:         "queue.depth{service.name}",
+			expectedError: `invalid attribute matcher "service.name"`,
+		},
+		{
+			name:          "invalid query",
+			query:         "queue depth",
+			expectedError: `invalid query "queue depth"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := parseQuery(tc.query)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, q)
+		})
+	}
+}
+
+func Test_query_aggregate(t *testing.T) {
+	base := time.Unix(1704067200, 0)
+	series := []sdk.TimestampedMetrics{
+		{{Timestamp: base, Value: 1}, {Timestamp: base.Add(5 * time.Second), Value: 3}},
+		{{Timestamp: base.Add(2 * time.Second), Value: 5}, {Timestamp: base.Add(10 * time.Second), Value: 7}},
+	}
+
+	testCases := []struct {
+		aggregation string
+		expected    []float64
+	}{
+		{aggregation: "sum", expected: []float64{8, 7}},
+		{aggregation: "avg", expected: []float64{4, 7}},
+		{aggregation: "min", expected: []float64{3, 7}},
+		{aggregation: "max", expected: []float64{5, 7}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.aggregation, func(t *testing.T) {
+			// The latest point of each series within a bucket is used.
+			q := &query{aggregation: tc.aggregation}
+			assert.Equal(t, sdk.TimestampedMetrics{
+				{Timestamp: base, Value: tc.expected[0]},
+				{Timestamp: base.Add(10 * time.Second), Value: tc.expected[1]},
+			}, q.aggregate(series, 10*time.Second))
+		})
+	}
+}
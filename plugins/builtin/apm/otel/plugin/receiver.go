@@ -0,0 +1,252 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// otlpMetricsPath is the OTLP/HTTP metrics endpoint path.
+	otlpMetricsPath = "/v1/metrics"
+
+	// maxRequestSize is the maximum size of an OTLP/HTTP request body, after
+	// decompression.
+	maxRequestSize = 16 << 20
+
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// ingest records the data points of the export request within the store,
+// returning the number of points which could not be recorded.
+//
+// Resource and data point attributes are merged into the series labels, with
+// data point attributes taking precedence. Gauges and sums are recorded
+// using the metric name, while histograms and summaries are recorded as
+// <name>_count and <name>_sum series.
+func (s *store) ingest(req *colmetricspb.ExportMetricsServiceRequest) int64 {
+	var rejected int64
+
+	for _, rm := range req.GetResourceMetrics() {
+		resourceLabels := attributesToLabels(nil, rm.GetResource().GetAttributes())
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				switch data := m.GetData().(type) {
+				case *metricspb.Metric_Gauge:
+					rejected += s.ingestNumberPoints(m.GetName(), resourceLabels, data.Gauge.GetDataPoints())
+				case *metricspb.Metric_Sum:
+					rejected += s.ingestNumberPoints(m.GetName(), resourceLabels, data.Sum.GetDataPoints())
+				case *metricspb.Metric_Histogram:
+					for _, dp := range data.Histogram.GetDataPoints() {
+						rejected += s.ingestCountSum(m.GetName(), resourceLabels, dp.GetAttributes(),
+							dp.GetTimeUnixNano(), dp.GetFlags(), dp.GetCount(), dp.Sum)
+					}
+				case *metricspb.Metric_ExponentialHistogram:
+					for _, dp := range data.ExponentialHistogram.GetDataPoints() {
+						rejected += s.ingestCountSum(m.GetName(), resourceLabels, dp.GetAttributes(),
+							dp.GetTimeUnixNano(), dp.GetFlags(), dp.GetCount(), dp.Sum)
+					}
+				case *metricspb.Metric_Summary:
+					for _, dp := range data.Summary.GetDataPoints() {
+						sum := dp.GetSum()
+						rejected += s.ingestCountSum(m.GetName(), resourceLabels, dp.GetAttributes(),
+							dp.GetTimeUnixNano(), dp.GetFlags(), dp.GetCount(), &sum)
+					}
+				}
+			}
+		}
+	}
+
+	return rejected
+}
+
+func (s *store) ingestNumberPoints(name string, resourceLabels map[string]string, points []*metricspb.NumberDataPoint) int64 {
+	var rejected int64
+
+	for _, dp := range points {
+		if noRecordedValue(dp.GetFlags()) {
+			continue
+		}
+		if dp.GetTimeUnixNano() == 0 {
+			rejected++
+			continue
+		}
+
+		var value float64
+		switch v := dp.GetValue().(type) {
+		case *metricspb.NumberDataPoint_AsDouble:
+			value = v.AsDouble
+		case *metricspb.NumberDataPoint_AsInt:
+			value = float64(v.AsInt)
+		default:
+			rejected++
+			continue
+		}
+
+		labels := attributesToLabels(resourceLabels, dp.GetAttributes())
+		s.add(name, labels, time.Unix(0, int64(dp.GetTimeUnixNano())), value)
+	}
+
+	return rejected
+}
+
+func (s *store) ingestCountSum(name string, resourceLabels map[string]string, attrs []*commonpb.KeyValue,
+	tsNano uint64, flags uint32, count uint64, sum *float64) int64 {
+
+	if noRecordedValue(flags) {
+		return 0
+	}
+	if tsNano == 0 {
+		return 1
+	}
+
+	ts := time.Unix(0, int64(tsNano))
+	labels := attributesToLabels(resourceLabels, attrs)
+
+	s.add(name+"_count", labels, ts, float64(count))
+	if sum != nil {
+		s.add(name+"_sum", labels, ts, *sum)
+	}
+	return 0
+}
+
+func noRecordedValue(flags uint32) bool {
+	mask := uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK)
+	return flags&mask == mask
+}
+
+// attributesToLabels returns a copy of the base labels, updated with the
+// passed attributes. Attributes which do not have a scalar value are
+// ignored.
+func attributesToLabels(base map[string]string, attrs []*commonpb.KeyValue) map[string]string {
+	labels := make(map[string]string, len(base)+len(attrs))
+	for k, v := range base {
+		labels[k] = v
+	}
+
+	for _, kv := range attrs {
+		switch v := kv.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			labels[kv.GetKey()] = v.StringValue
+		case *commonpb.AnyValue_BoolValue:
+			labels[kv.GetKey()] = strconv.FormatBool(v.BoolValue)
+		case *commonpb.AnyValue_IntValue:
+			labels[kv.GetKey()] = strconv.FormatInt(v.IntValue, 10)
+		case *commonpb.AnyValue_DoubleValue:
+			labels[kv.GetKey()] = strconv.FormatFloat(v.DoubleValue, 'f', -1, 64)
+		}
+	}
+	return labels
+}
+
+// exportResponse returns the export response, reporting a partial success
+// if any data points were rejected.
+func exportResponse(rejected int64) *colmetricspb.ExportMetricsServiceResponse {
+	resp := &colmetricspb.ExportMetricsServiceResponse{}
+	if rejected > 0 {
+		resp.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: rejected,
+			ErrorMessage:       "data points must have a timestamp and value",
+		}
+	}
+	return resp
+}
+
+// grpcReceiver implements the OTLP/gRPC metrics service.
+type grpcReceiver struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	store *store
+}
+
+func (g *grpcReceiver) Export(_ context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	return exportResponse(g.store.ingest(req)), nil
+}
+
+// httpReceiver implements the OTLP/HTTP metrics endpoint, supporting both
+// the binary protobuf and JSON encodings.
+type httpReceiver struct {
+	store *store
+}
+
+func (h *httpReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to decompress request: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		http.Error(w, "unsupported content encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	b, err := io.ReadAll(io.LimitReader(body, maxRequestSize+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(b) > maxRequestSize {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	req := &colmetricspb.ExportMetricsServiceRequest{}
+	if contentType == contentTypeJSON {
+		err = protojson.Unmarshal(b, req)
+	} else {
+		err = proto.Unmarshal(b, req)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	resp := exportResponse(h.store.ingest(req))
+
+	var out []byte
+	if contentType == contentTypeJSON {
+		out, err = protojson.Marshal(resp)
+	} else {
+		out, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(out)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// store is an in-memory buffer of the recently received metric points,
+// keyed by the metric name and attributes of each series.
+type store struct {
+	lock      sync.RWMutex
+	retention time.Duration
+	series    map[string]*series
+}
+
+// series is a single metric stream, with points kept in timestamp order.
+type series struct {
+	name   string
+	labels map[string]string
+	points sdk.TimestampedMetrics
+}
+
+func newStore(retention time.Duration) *store {
+	return &store{
+		retention: retention,
+		series:    make(map[string]*series),
+	}
+}
+
+// setRetention updates the duration points are kept for.
+func (s *store) setRetention(d time.Duration) {
+	s.lock.Lock()
+	s.retention = d
+	s.lock.Unlock()
+}
+
+// add records a point for the series identified by the name and labels.
+// Points older than the retention period are dropped, and a point with the
+// same timestamp as an existing point replaces it.
+func (s *store) add(name string, labels map[string]string, ts time.Time, value float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if ts.Before(time.Now().Add(-s.retention)) {
+		return
+	}
+
+	key := seriesKey(name, labels)
+	ser, ok := s.series[key]
+	if !ok {
+		ser = &series{name: name, labels: labels}
+		s.series[key] = ser
+	}
+
+	point := sdk.TimestampedMetric{Timestamp: ts, Value: value}
+
+	// Points are usually received in order, so the common case is an append.
+	i := sort.Search(len(ser.points), func(i int) bool { return !ser.points[i].Timestamp.Before(ts) })
+	switch {
+	case i == len(ser.points):
+		ser.points = append(ser.points, point)
+	case ser.points[i].Timestamp.Equal(ts):
+		ser.points[i] = point
+	default:
+		ser.points = append(ser.points, sdk.TimestampedMetric{})
+		copy(ser.points[i+1:], ser.points[i:])
+		ser.points[i] = point
+	}
+}
+
+// prune removes points older than the retention period, along with series
+// which no longer have any points.
+func (s *store) prune(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cutoff := now.Add(-s.retention)
+
+	for key, ser := range s.series {
+		i := sort.Search(len(ser.points), func(i int) bool { return !ser.points[i].Timestamp.Before(cutoff) })
+		if i == len(ser.points) {
+			delete(s.series, key)
+			continue
+		}
+		ser.points = append(ser.points[:0], ser.points[i:]...)
+	}
+}
+
+// query returns the points within the time range of each series with the
+// passed name and matching labels, in a stable order.
+func (s *store) query(name string, matchers map[string]string, r sdk.TimeRange) []sdk.TimestampedMetrics {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var keys []string
+	for key, ser := range s.series {
+		if ser.name == name && ser.matches(matchers) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var results []sdk.TimestampedMetrics
+	for _, key := range keys {
+		var result sdk.TimestampedMetrics
+		for _, p := range s.series[key].points {
+			if p.Timestamp.Before(r.From) || p.Timestamp.After(r.To) {
+				continue
+			}
+			result = append(result, p)
+		}
+		if len(result) > 0 {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func (s *series) matches(matchers map[string]string) bool {
+	for k, v := range matchers {
+		if s.labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// seriesKey returns the unique key of a series.
+func seriesKey(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteString("\x00")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
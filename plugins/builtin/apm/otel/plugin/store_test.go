@@ -0,0 +1,43 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_store(t *testing.T) {
+	s := newStore(time.Minute)
+	now := time.Now().Truncate(time.Second)
+	labels := map[string]string{"service.name": "web"}
+
+	// Out of order and duplicate points should be kept in order, and points
+	// outside the retention period dropped.
+	s.add("queue.depth", labels, now, 3)
+	s.add("queue.depth", labels, now.Add(-20*time.Second), 1)
+	s.add("queue.depth", labels, now.Add(-10*time.Second), 0)
+	s.add("queue.depth", labels, now.Add(-10*time.Second), 2)
+	s.add("queue.depth", labels, now.Add(-2*time.Minute), 9)
+	s.add("queue.depth", map[string]string{"service.name": "api"}, now.Add(-50*time.Second), 5)
+
+	r := sdk.TimeRange{From: now.Add(-time.Minute), To: now}
+
+	assert.Equal(t, []sdk.TimestampedMetrics{{
+		{Timestamp: now.Add(-20 * time.Second), Value: 1},
+		{Timestamp: now.Add(-10 * time.Second), Value: 2},
+		{Timestamp: now, Value: 3},
+	}}, s.query("queue.depth", map[string]string{"service.name": "web"}, r))
+
+	assert.Len(t, s.query("queue.depth", nil, r), 2)
+	assert.Empty(t, s.query("queue.size", nil, r))
+
+	// Pruning should remove expired points and series without any points.
+	s.prune(now.Add(55 * time.Second))
+	assert.Len(t, s.series, 1)
+	assert.Equal(t, []sdk.TimestampedMetrics{{{Timestamp: now, Value: 3}}}, s.query("queue.depth", nil, r))
+}
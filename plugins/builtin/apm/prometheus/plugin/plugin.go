@@ -12,7 +12,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
@@ -42,15 +44,67 @@ const (
 	// configuration value should be set as an HTTP header.
 	configKeyHeadersPrefix = "header_"
 
+	// configKeyQueryParamsPrefix is the prefix used to indicate that a
+	// configuration value should be added to the query parameters of each
+	// request, such as "dedup" and "partial_response" for Thanos.
+	configKeyQueryParamsPrefix = "query_param_"
+
 	// configKeyCACert is the path to the CA certificate the Prometheus client
 	// should use.
 	configKeyCACert = "ca_cert"
 
+	// configKeyClientCert and configKeyClientKey are the paths to the client
+	// certificate and key used for mutual TLS.
+	configKeyClientCert = "client_cert"
+	configKeyClientKey  = "client_key"
+
+	// configKeyBearerToken and configKeyBearerTokenFile set the bearer token
+	// sent in the Authorization header of each request. The token file is
+	// read on every request, so rotated tokens are picked up.
+	configKeyBearerToken     = "bearer_token"
+	configKeyBearerTokenFile = "bearer_token_file"
+
 	// configKeySkipVerify indicates that the Prometheus client should not
 	// verify TLS certificates.
 	configKeySkipVerify = "skip_verify"
+
+	// configKeyFlavor is the Prometheus compatible server being queried,
+	// which changes the defaults of the plugin to suit it.
+	configKeyFlavor             = "flavor"
+	configValueFlavorPrometheus = "prometheus"
+	configValueFlavorVM         = "victoriametrics"
+
+	// configKeyStep is the query resolution step, either a duration or
+	// "auto" to derive it from the query window. It defaults to 1s, or auto
+	// for VictoriaMetrics since MetricsQL uses the step as the lookbehind
+	// window of functions such as rate() when it is omitted.
+	configKeyStep          = "step"
+	configValueStepAuto    = "auto"
+	configValueStepDefault = time.Second
+
+	// autoStepPoints is the number of points an automatic step returns over
+	// the query window.
+	autoStepPoints = 60
+
+	// configKeyTenantID is the VictoriaMetrics cluster tenant, in the form
+	// <accountID>[:<projectID>], which is queried through the vmselect
+	// multitenant URL layout.
+	configKeyTenantID = "tenant_id"
+
+	// configKeyAPI is the API used to run queries. The query_range API runs
+	// PromQL queries, while remote_read reads the raw samples of the series
+	// matched by a series selector, for backends which only implement the
+	// remote read protocol.
+	configKeyAPI              = "api"
+	configValueAPIQueryRange  = "query_range"
+	configValueAPIRemoteRead  = "remote_read"
+	configKeyRemoteReadPath   = "remote_read_path"
+	configValueRemoteReadPath = "/api/v1/read"
 )
 
+// vmTenantIDRegex matches valid VictoriaMetrics tenant IDs.
+var vmTenantIDRegex = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
 var (
 	PluginID = plugins.PluginID{
 		Name:       pluginName,
@@ -71,6 +125,14 @@ type APMPlugin struct {
 	client api.Client
 	config map[string]string
 	logger hclog.Logger
+
+	// step is the query resolution step, where zero means the step is
+	// derived from the query window.
+	step time.Duration
+
+	// remoteReadPath is the path of the remote read endpoint, and is only
+	// set when queries use the remote read API.
+	remoteReadPath string
 }
 
 func NewPrometheusPlugin(log hclog.Logger) apm.APM {
@@ -91,11 +153,52 @@ func (a *APMPlugin) SetConfig(config map[string]string) error {
 		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
 	}
 
+	flavor := a.config[configKeyFlavor]
+	switch flavor {
+	case "", configValueFlavorPrometheus, configValueFlavorVM:
+	default:
+		return fmt.Errorf("invalid %q value %q, allowed values are %s or %s",
+			configKeyFlavor, flavor, configValueFlavorPrometheus, configValueFlavorVM)
+	}
+
+	step, err := parseStep(a.config[configKeyStep], flavor)
+	if err != nil {
+		return err
+	}
+	a.step = step
+
+	if tenant := a.config[configKeyTenantID]; tenant != "" {
+		if flavor != configValueFlavorVM {
+			return fmt.Errorf("%q can only be set when %q is %s", configKeyTenantID, configKeyFlavor, configValueFlavorVM)
+		}
+		if !vmTenantIDRegex.MatchString(tenant) {
+			return fmt.Errorf("invalid %q value %q, must be in the form <accountID>[:<projectID>]", configKeyTenantID, tenant)
+		}
+		addr = strings.TrimSuffix(addr, "/") + "/select/" + tenant + "/prometheus"
+	}
+
+	a.remoteReadPath = ""
+	switch queryAPI := a.config[configKeyAPI]; queryAPI {
+	case "", configValueAPIQueryRange:
+	case configValueAPIRemoteRead:
+		a.remoteReadPath = configValueRemoteReadPath
+		if p := a.config[configKeyRemoteReadPath]; p != "" {
+			a.remoteReadPath = p
+		}
+	default:
+		return fmt.Errorf("invalid %q value %q, allowed values are %s or %s",
+			configKeyAPI, queryAPI, configValueAPIQueryRange, configValueAPIRemoteRead)
+	}
+
 	tlsConfig, err := generateTLSConfig(config)
 	if err != nil {
 		return fmt.Errorf("failed to parse TLS configuration: %v", err)
 	}
 
+	if err := validateAuthConfig(config); err != nil {
+		return fmt.Errorf("failed to parse auth configuration: %v", err)
+	}
+
 	promCfg := api.Config{
 		Address:      addr,
 		RoundTripper: newPluginRoudTripper(a.config, tlsConfig),
@@ -113,6 +216,42 @@ func (a *APMPlugin) SetConfig(config map[string]string) error {
 	return nil
 }
 
+// parseStep parses the step config value. Zero is returned for an automatic
+// step.
+func parseStep(v, flavor string) (time.Duration, error) {
+	switch v {
+	case "":
+		if flavor == configValueFlavorVM {
+			return 0, nil
+		}
+		return configValueStepDefault, nil
+	case configValueStepAuto:
+		return 0, nil
+	}
+
+	step, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %q: %v", configKeyStep, err)
+	}
+	if step < time.Second {
+		return 0, fmt.Errorf("%q must be at least 1s", configKeyStep)
+	}
+	return step, nil
+}
+
+// queryStep returns the step used to query the time range.
+func (a *APMPlugin) queryStep(r sdk.TimeRange) time.Duration {
+	if a.step > 0 {
+		return a.step
+	}
+
+	step := (r.To.Sub(r.From) / autoStepPoints).Round(time.Second)
+	if step < time.Second {
+		return time.Second
+	}
+	return step
+}
+
 func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
 	return pluginInfo, nil
 }
@@ -136,11 +275,16 @@ func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, er
 func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
 	a.logger.Debug("querying Prometheus", "query", q, "range", r)
 
-	v1api := v1.NewAPI(a.client)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	promRange := v1.Range{Start: r.From, End: r.To, Step: time.Second}
+	if a.remoteReadPath != "" {
+		return a.queryRemoteRead(ctx, q, r)
+	}
+
+	v1api := v1.NewAPI(a.client)
+
+	promRange := v1.Range{Start: r.From, End: r.To, Step: a.queryStep(r)}
 	result, warnings, err := v1api.QueryRange(ctx, q, promRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query: %v", err)
@@ -187,6 +331,21 @@ func generateTLSConfig(config map[string]string) (*tls.Config, error) {
 		tlsConfig.RootCAs = caCertPool
 	}
 
+	// Load the client certificate if present, which must be accompanied by
+	// its key.
+	clientCertPath := config[configKeyClientCert]
+	clientKeyPath := config[configKeyClientKey]
+	switch {
+	case clientCertPath != "" && clientKeyPath != "":
+		cert, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %s: %v", clientCertPath, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case clientCertPath != "" || clientKeyPath != "":
+		return nil, fmt.Errorf("%s and %s must be set together", configKeyClientCert, configKeyClientKey)
+	}
+
 	skipVerify := config[configKeySkipVerify]
 	if skipVerify != "" {
 		skipVerifyBool, err := strconv.ParseBool(skipVerify)
@@ -273,10 +432,10 @@ func parseSample(s interface{}) (sdk.TimestampedMetric, error) {
 		return result, errors.New("query result value is not-a-number")
 	}
 
-	tsTime := time.Unix(int64(ts)/1e3, 0)
-
+	// Keep the millisecond precision of the sample, so high resolution
+	// metrics with multiple points per second remain distinct.
 	return sdk.TimestampedMetric{
-		Timestamp: tsTime,
+		Timestamp: time.UnixMilli(int64(ts)),
 		Value:     valFloat,
 	}, nil
 }
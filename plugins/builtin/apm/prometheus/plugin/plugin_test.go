@@ -5,6 +5,7 @@ package plugin
 
 import (
 	"errors"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"path"
@@ -13,6 +14,7 @@ import (
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -38,6 +40,45 @@ func TestAPMPlugin_SetConfig(t *testing.T) {
 			expectOutput: nil,
 			name:         "required and valid config parameters set",
 		},
+		{
+			inputConfig: map[string]string{
+				"address":             "http://127.0.0.1:9090",
+				"bearer_token":        "secret",
+				"basic_auth_password": "secret",
+			},
+			expectOutput: errors.New("failed to parse auth configuration: bearer token and basic auth cannot both be set"),
+			name:         "multiple auth methods set",
+		},
+		{
+			inputConfig:  map[string]string{"address": "http://127.0.0.1:9090", "flavor": "thanos"},
+			expectOutput: errors.New(`invalid "flavor" value "thanos", allowed values are prometheus or victoriametrics`),
+			name:         "invalid flavor",
+		},
+		{
+			inputConfig:  map[string]string{"address": "http://127.0.0.1:9090", "step": "500ms"},
+			expectOutput: errors.New(`"step" must be at least 1s`),
+			name:         "step too small",
+		},
+		{
+			inputConfig:  map[string]string{"address": "http://127.0.0.1:9090", "tenant_id": "42"},
+			expectOutput: errors.New(`"tenant_id" can only be set when "flavor" is victoriametrics`),
+			name:         "tenant without victoriametrics flavor",
+		},
+		{
+			inputConfig:  map[string]string{"address": "http://127.0.0.1:8481", "flavor": "victoriametrics", "tenant_id": "team-a"},
+			expectOutput: errors.New(`invalid "tenant_id" value "team-a", must be in the form <accountID>[:<projectID>]`),
+			name:         "invalid victoriametrics tenant",
+		},
+		{
+			inputConfig:  map[string]string{"address": "http://127.0.0.1:9090", "api": "graphql"},
+			expectOutput: errors.New(`invalid "api" value "graphql", allowed values are query_range or remote_read`),
+			name:         "invalid api",
+		},
+		{
+			inputConfig:  map[string]string{"address": "http://127.0.0.1:9090", "api": "remote_read", "remote_read_path": "/read"},
+			expectOutput: nil,
+			name:         "remote read api",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -56,6 +97,58 @@ func TestAPMPlugin_SetConfig(t *testing.T) {
 	}
 }
 
+func TestAPMPlugin_queryStep(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   map[string]string
+		window   time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "prometheus default",
+			config:   map[string]string{},
+			window:   time.Hour,
+			expected: time.Second,
+		},
+		{
+			name:     "victoriametrics default",
+			config:   map[string]string{"flavor": "victoriametrics"},
+			window:   time.Hour,
+			expected: time.Minute,
+		},
+		{
+			name:     "auto",
+			config:   map[string]string{"step": "auto"},
+			window:   10 * time.Minute,
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "auto small window",
+			config:   map[string]string{"step": "auto"},
+			window:   30 * time.Second,
+			expected: time.Second,
+		},
+		{
+			name:     "fixed",
+			config:   map[string]string{"flavor": "victoriametrics", "step": "15s"},
+			window:   time.Hour,
+			expected: 15 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config[configKeyAddress] = "http://127.0.0.1:9090"
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			require.NoError(t, apmPlugin.SetConfig(tc.config))
+
+			now := time.Now()
+			r := sdk.TimeRange{From: now.Add(-tc.window), To: now}
+			assert.Equal(t, tc.expected, apmPlugin.queryStep(r))
+		})
+	}
+}
+
 func TestAPMPlugin_Query(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -102,6 +195,49 @@ func TestAPMPlugin_Query(t *testing.T) {
 				require.Len(t, m, 31)
 			},
 		},
+		{
+			name:    "victoriametrics tenant",
+			fixture: "query_range_200.json",
+			pluginConfig: map[string]string{
+				configKeyFlavor:   "victoriametrics",
+				configKeyTenantID: "42:7",
+			},
+			query: "rate(http_requests_total)",
+			timeRange: sdk.TimeRange{
+				From: time.Unix(1600000000, 0),
+				To:   time.Unix(1600000300, 0),
+			},
+			validateRequest: func(t *testing.T, r *http.Request) {
+				require.Equal(t, "/select/42:7/prometheus/api/v1/query_range", r.URL.Path)
+
+				// The step is derived from the query window.
+				r.ParseForm()
+				require.Equal(t, "rate(http_requests_total)", r.FormValue("query"))
+				require.Equal(t, "5", r.FormValue("step"))
+			},
+			validateMetrics: func(t *testing.T, m sdk.TimestampedMetrics, err error) {
+				require.NoError(t, err)
+				require.Len(t, m, 31)
+			},
+		},
+		{
+			name:    "custom step",
+			fixture: "query_range_200.json",
+			pluginConfig: map[string]string{
+				configKeyStep: "30s",
+			},
+			query: "up",
+			timeRange: sdk.TimeRange{
+				From: time.Unix(1600000000, 0),
+				To:   time.Unix(1600000300, 0),
+			},
+			validateRequest: func(t *testing.T, r *http.Request) {
+				require.Equal(t, "/api/v1/query_range", r.URL.Path)
+
+				r.ParseForm()
+				require.Equal(t, "30", r.FormValue("step"))
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -128,3 +264,13 @@ func TestAPMPlugin_Query(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseSample(t *testing.T) {
+	// Millisecond precision is kept for high resolution metrics.
+	m, err := parseSample(model.SamplePair{Timestamp: 1700000000250, Value: 3})
+	require.NoError(t, err)
+	require.Equal(t, sdk.TimestampedMetric{Timestamp: time.UnixMilli(1700000000250), Value: 3}, m)
+
+	_, err = parseSample(model.SamplePair{Timestamp: 1700000000250, Value: model.SampleValue(math.NaN())})
+	require.EqualError(t, err, "query result value is not-a-number")
+}
@@ -5,7 +5,11 @@ package plugin
 
 import (
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"os"
 	"strings"
 
 	"github.com/prometheus/client_golang/api"
@@ -14,8 +18,11 @@ import (
 // pluginRoundTripper is used to configure the Prometheus HTTP client.
 type pluginRoundTripper struct {
 	headers           map[string]string
+	queryParams       url.Values
 	basicAuthUser     string
 	basicAuthPassword string
+	bearerToken       string
+	bearerTokenFile   string
 
 	rt http.RoundTripper
 }
@@ -27,10 +34,15 @@ func newPluginRoudTripper(config map[string]string, tlsConfig *tls.Config) *plug
 	password := config[configKeyBasicAuthPassword]
 
 	headers := make(map[string]string)
+	queryParams := make(url.Values)
 	for k, v := range config {
-		if strings.HasPrefix(k, configKeyHeadersPrefix) {
+		switch {
+		case strings.HasPrefix(k, configKeyHeadersPrefix):
 			header := strings.TrimPrefix(k, configKeyHeadersPrefix)
 			headers[header] = v
+		case strings.HasPrefix(k, configKeyQueryParamsPrefix):
+			param := strings.TrimPrefix(k, configKeyQueryParamsPrefix)
+			queryParams.Set(param, v)
 		}
 	}
 
@@ -39,8 +51,11 @@ func newPluginRoudTripper(config map[string]string, tlsConfig *tls.Config) *plug
 
 	return &pluginRoundTripper{
 		headers:           headers,
+		queryParams:       queryParams,
 		basicAuthUser:     username,
 		basicAuthPassword: password,
+		bearerToken:       config[configKeyBearerToken],
+		bearerTokenFile:   config[configKeyBearerTokenFile],
 		rt:                defaultRoudTripper,
 	}
 }
@@ -50,10 +65,75 @@ func (rt *pluginRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 		req.Header.Add(header, value)
 	}
 
-	setAuth := (rt.basicAuthUser != "" || rt.basicAuthPassword != "") && req.Header.Get("Authorization") == ""
-	if setAuth {
-		req.SetBasicAuth(rt.basicAuthUser, rt.basicAuthPassword)
+	// Prometheus reads parameters from both the URL and the form body, so
+	// the extra parameters can be added to the URL of POST requests too.
+	if len(rt.queryParams) > 0 {
+		u := *req.URL
+		q := u.Query()
+		for param, values := range rt.queryParams {
+			q[param] = values
+		}
+		u.RawQuery = q.Encode()
+		req.URL = &u
+	}
+
+	// Auth set via headers takes precedence over the auth config.
+	if req.Header.Get("Authorization") == "" {
+		switch {
+		case rt.bearerToken != "" || rt.bearerTokenFile != "":
+			token, err := rt.token()
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		case rt.basicAuthUser != "" || rt.basicAuthPassword != "":
+			req.SetBasicAuth(rt.basicAuthUser, rt.basicAuthPassword)
+		}
 	}
 
 	return rt.rt.RoundTrip(req)
 }
+
+// token returns the bearer token, reading it from the token file if one is
+// configured.
+func (rt *pluginRoundTripper) token() (string, error) {
+	if rt.bearerTokenFile == "" {
+		return rt.bearerToken, nil
+	}
+	return readBearerTokenFile(rt.bearerTokenFile)
+}
+
+func readBearerTokenFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read bearer token file %s: %v", path, err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("bearer token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// validateAuthConfig ensures at most one authentication method is configured
+// and that the bearer token file can be read.
+func validateAuthConfig(config map[string]string) error {
+	token := config[configKeyBearerToken]
+	tokenFile := config[configKeyBearerTokenFile]
+	basicAuth := config[configKeyBasicAuthUser] != "" || config[configKeyBasicAuthPassword] != ""
+
+	switch {
+	case token != "" && tokenFile != "":
+		return fmt.Errorf("only one of %s and %s can be set", configKeyBearerToken, configKeyBearerTokenFile)
+	case (token != "" || tokenFile != "") && basicAuth:
+		return errors.New("bearer token and basic auth cannot both be set")
+	}
+
+	if tokenFile != "" {
+		if _, err := readBearerTokenFile(tokenFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,12 +4,21 @@
 package plugin
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,6 +30,8 @@ func TestAPMPlugin_roundTripper(t *testing.T) {
 		"basic_auth_password":    "secret",
 		"header_X-Scope-OrgID":   "my-org",
 		"header_X-Custom-Header": "header value",
+		"query_param_dedup":      "true",
+		"query_param_tenant":     "team a",
 	}
 
 	// Run tests inside the handler where we can check the values actually
@@ -32,6 +43,11 @@ func TestAPMPlugin_roundTripper(t *testing.T) {
 
 		assert.Equal(t, r.Header.Get("X-Scope-OrgID"), "my-org")
 		assert.Equal(t, r.Header.Get("X-Custom-Header"), "header value")
+
+		// Extra query parameters should be merged with those of the request.
+		assert.Equal(t, "true", r.URL.Query().Get("dedup"))
+		assert.Equal(t, "team a", r.URL.Query().Get("tenant"))
+		assert.Equal(t, "up", r.URL.Query().Get("query"))
 	}
 
 	// Setup round tripper and an HTTP client to use for testing.
@@ -41,7 +57,7 @@ func TestAPMPlugin_roundTripper(t *testing.T) {
 	defer server.Close()
 
 	// Make a request to run the tests.
-	_, err := client.Get(server.URL)
+	_, err := client.Get(server.URL + "?query=up")
 	require.NoError(t, err)
 }
 
@@ -142,3 +158,225 @@ func TestAPMPlugin_roundTripperTLS(t *testing.T) {
 		})
 	}
 }
+
+func TestAPMPlugin_roundTripperBearerToken(t *testing.T) {
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("first\n"), 0o600))
+
+	testCases := []struct {
+		name     string
+		cfg      map[string]string
+		expected string
+	}{
+		{
+			name:     "bearer token",
+			cfg:      map[string]string{"bearer_token": "secret"},
+			expected: "Bearer secret",
+		},
+		{
+			name:     "bearer token file",
+			cfg:      map[string]string{"bearer_token_file": tokenFile},
+			expected: "Bearer first",
+		},
+		{
+			name: "header takes precedence",
+			cfg: map[string]string{
+				"bearer_token":         "secret",
+				"header_Authorization": "Custom value",
+			},
+			expected: "Custom value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &http.Client{Transport: newPluginRoudTripper(tc.cfg, nil)}
+			_, err := client.Get(server.URL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, authHeader)
+		})
+	}
+
+	// The token file is read on every request, so rotated tokens are used.
+	client := &http.Client{Transport: newPluginRoudTripper(map[string]string{"bearer_token_file": tokenFile}, nil)}
+	require.NoError(t, os.WriteFile(tokenFile, []byte("second"), 0o600))
+	_, err := client.Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer second", authHeader)
+
+	// Requests fail if the token file can no longer be read.
+	require.NoError(t, os.Remove(tokenFile))
+	_, err = client.Get(server.URL)
+	assert.ErrorContains(t, err, "failed to read bearer token file")
+}
+
+func Test_validateAuthConfig(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret"), 0o600))
+
+	emptyTokenFile := filepath.Join(t.TempDir(), "empty")
+	require.NoError(t, os.WriteFile(emptyTokenFile, []byte("\n"), 0o600))
+
+	testCases := []struct {
+		name          string
+		cfg           map[string]string
+		expectedError string
+	}{
+		{
+			name: "no auth",
+			cfg:  map[string]string{},
+		},
+		{
+			name: "basic auth",
+			cfg:  map[string]string{"basic_auth_user": "user", "basic_auth_password": "secret"},
+		},
+		{
+			name: "bearer token file",
+			cfg:  map[string]string{"bearer_token_file": tokenFile},
+		},
+		{
+			name:          "bearer token and file",
+			cfg:           map[string]string{"bearer_token": "secret", "bearer_token_file": tokenFile},
+			expectedError: "only one of bearer_token and bearer_token_file can be set",
+		},
+		{
+			name:          "bearer token and basic auth",
+			cfg:           map[string]string{"bearer_token": "secret", "basic_auth_user": "user"},
+			expectedError: "bearer token and basic auth cannot both be set",
+		},
+		{
+			name:          "missing token file",
+			cfg:           map[string]string{"bearer_token_file": filepath.Join(t.TempDir(), "missing")},
+			expectedError: "failed to read bearer token file",
+		},
+		{
+			name:          "empty token file",
+			cfg:           map[string]string{"bearer_token_file": emptyTokenFile},
+			expectedError: "is empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateAuthConfig(tc.cfg)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestAPMPlugin_roundTripperMTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeClientCert(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	// Setup test HTTPS server which requires a client certificate.
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	testCases := []struct {
+		name                  string
+		cfg                   map[string]string
+		expectConnError       bool
+		expectValidationError string
+	}{
+		{
+			name:            "no client cert",
+			cfg:             map[string]string{"skip_verify": "true"},
+			expectConnError: true,
+		},
+		{
+			name: "client cert",
+			cfg: map[string]string{
+				"skip_verify": "true",
+				"client_cert": certFile,
+				"client_key":  keyFile,
+			},
+		},
+		{
+			name:                  "missing client key",
+			cfg:                   map[string]string{"client_cert": certFile},
+			expectValidationError: "client_cert and client_key must be set together",
+		},
+		{
+			name: "invalid client key",
+			cfg: map[string]string{
+				"client_cert": certFile,
+				"client_key":  certFile,
+			},
+			expectValidationError: "failed to load client certificate",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tlsConfig, err := generateTLSConfig(tc.cfg)
+			if tc.expectValidationError != "" {
+				require.ErrorContains(t, err, tc.expectValidationError)
+				return
+			}
+			require.NoError(t, err)
+
+			client := &http.Client{Transport: &pluginRoundTripper{
+				rt: &http.Transport{TLSClientConfig: tlsConfig},
+			}}
+
+			_, err = client.Get(server.URL)
+			if tc.expectConnError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// writeClientCert generates a self-signed client certificate and key, writing
+// them to PEM files within dir.
+func writeClientCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nomad-autoscaler"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile, cert
+}
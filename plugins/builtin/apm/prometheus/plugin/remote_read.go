@@ -0,0 +1,344 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// matchType is the type of a remote read label matcher, matching the values
+// of the prompb.LabelMatcher_Type enum.
+type matchType uint64
+
+const (
+	matchEqual matchType = iota
+	matchNotEqual
+	matchRegexp
+	matchNotRegexp
+)
+
+// labelMatcher selects series by the value of a label.
+type labelMatcher struct {
+	typ   matchType
+	name  string
+	value string
+}
+
+// queryRemoteRead reads the raw samples of the series selected by the query
+// using the remote read protocol. Each series is returned as a metric
+// stream.
+func (a *APMPlugin) queryRemoteRead(ctx context.Context, q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	matchers, err := parseSelector(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse series selector: %v", err)
+	}
+
+	body := snappy.Encode(nil, encodeReadRequest(matchers, r, a.queryStep(r)))
+
+	u := a.client.URL(a.remoteReadPath, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Read-Version", "0.1.0")
+
+	resp, b, err := a.client.Do(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query: unexpected response code %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	decoded, err := snappy.Decode(nil, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress response: %v", err)
+	}
+	result, err := decodeReadResponse(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return result, nil
+}
+
+// parseSelector parses a series selector, such as
+// `http_requests_total{job="web",code=~"5.."}`, into label matchers.
+func parseSelector(s string) ([]labelMatcher, error) {
+	s = strings.TrimSpace(s)
+
+	var matchers []labelMatcher
+
+	name := s
+	if i := strings.IndexByte(s, '{'); i >= 0 {
+		name = strings.TrimSpace(s[:i])
+		if !strings.HasSuffix(s, "}") {
+			return nil, errors.New(`expected "}" at the end of the selector`)
+		}
+
+		m, err := parseMatchers(s[i+1 : len(s)-1])
+		if err != nil {
+			return nil, err
+		}
+		matchers = m
+	}
+
+	if name != "" {
+		if !isValidName(name, true) {
+			return nil, fmt.Errorf("invalid metric name %q", name)
+		}
+		matchers = append([]labelMatcher{{typ: matchEqual, name: "__name__", value: name}}, matchers...)
+	}
+
+	if len(matchers) == 0 {
+		return nil, errors.New("selector must contain at least one matcher")
+	}
+	return matchers, nil
+}
+
+// parseMatchers parses the comma separated label matchers within the braces
+// of a selector.
+func parseMatchers(s string) ([]labelMatcher, error) {
+	var matchers []labelMatcher
+
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return matchers, nil
+		}
+
+		i := strings.IndexAny(s, "=!")
+		if i < 0 {
+			return nil, fmt.Errorf("expected label matcher operator in %q", s)
+		}
+		name := strings.TrimSpace(s[:i])
+		if !isValidName(name, false) {
+			return nil, fmt.Errorf("invalid label name %q", name)
+		}
+		s = s[i:]
+
+		var m labelMatcher
+		switch {
+		case strings.HasPrefix(s, "=~"):
+			m.typ, s = matchRegexp, s[2:]
+		case strings.HasPrefix(s, "!~"):
+			m.typ, s = matchNotRegexp, s[2:]
+		case strings.HasPrefix(s, "!="):
+			m.typ, s = matchNotEqual, s[2:]
+		case strings.HasPrefix(s, "="):
+			m.typ, s = matchEqual, s[1:]
+		default:
+			return nil, fmt.Errorf("invalid label matcher operator for label %q", name)
+		}
+		m.name = name
+
+		value, rest, err := parseQuoted(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for label %q: %v", name, err)
+		}
+		m.value = value
+		matchers = append(matchers, m)
+
+		s = strings.TrimSpace(rest)
+		if s == "" {
+			return matchers, nil
+		}
+		if s[0] != ',' {
+			return nil, fmt.Errorf("expected \",\" after label %q", name)
+		}
+		s = s[1:]
+	}
+}
+
+// parseQuoted parses the double-quoted or backtick-quoted string at the start
+// of s, returning its value and the rest of s.
+func parseQuoted(s string) (string, string, error) {
+	if s == "" || (s[0] != '"' && s[0] != '`') {
+		return "", "", errors.New("expected quoted string")
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[0] == '"' && s[i] == '\\':
+			i++
+		case s[i] == s[0]:
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", err
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return "", "", errors.New("unterminated quoted string")
+}
+
+// isValidName reports whether s is a valid label name, or metric name which
+// may also contain colons.
+func isValidName(s string, metric bool) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(i > 0 && c >= '0' && c <= '9') || (metric && c == ':')
+		if !valid {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeReadRequest encodes a prompb.ReadRequest with a single query for the
+// matchers over the time range.
+func encodeReadRequest(matchers []labelMatcher, r sdk.TimeRange, step time.Duration) []byte {
+	start, end := r.From.UnixMilli(), r.To.UnixMilli()
+
+	var query []byte
+	query = appendVarintField(query, 1, uint64(start))
+	query = appendVarintField(query, 2, uint64(end))
+	for _, m := range matchers {
+		var mb []byte
+		mb = appendVarintField(mb, 1, uint64(m.typ))
+		mb = appendBytesField(mb, 2, []byte(m.name))
+		mb = appendBytesField(mb, 3, []byte(m.value))
+		query = appendBytesField(query, 3, mb)
+	}
+
+	// The hints allow the server to downsample or avoid reading data outside
+	// of the range.
+	var hints []byte
+	hints = appendVarintField(hints, 1, uint64(step.Milliseconds()))
+	hints = appendVarintField(hints, 3, uint64(start))
+	hints = appendVarintField(hints, 4, uint64(end))
+	query = appendBytesField(query, 4, hints)
+
+	return appendBytesField(nil, 1, query)
+}
+
+// decodeReadResponse decodes the series of a prompb.ReadResponse into
+// metrics. Stale markers and other NaN samples are skipped.
+func decodeReadResponse(b []byte) ([]sdk.TimestampedMetrics, error) {
+	results := []sdk.TimestampedMetrics{}
+
+	err := readFields(b, func(num protowire.Number, queryResult []byte, _ uint64) error {
+		if num != 1 {
+			return nil
+		}
+		return readFields(queryResult, func(num protowire.Number, series []byte, _ uint64) error {
+			if num != 1 {
+				return nil
+			}
+			metrics, err := decodeSeries(series)
+			if err != nil {
+				return err
+			}
+			if len(metrics) > 0 {
+				results = append(results, metrics)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// decodeSeries decodes the samples of a prompb.TimeSeries.
+func decodeSeries(b []byte) (sdk.TimestampedMetrics, error) {
+	var metrics sdk.TimestampedMetrics
+
+	err := readFields(b, func(num protowire.Number, sample []byte, _ uint64) error {
+		if num != 2 {
+			return nil
+		}
+
+		var value float64
+		var ts int64
+		err := readFields(sample, func(num protowire.Number, _ []byte, v uint64) error {
+			switch num {
+			case 1:
+				value = math.Float64frombits(v)
+			case 2:
+				ts = int64(v)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if !math.IsNaN(value) {
+			metrics = append(metrics, sdk.TimestampedMetric{Timestamp: time.UnixMilli(ts), Value: value})
+		}
+		return nil
+	})
+
+	sort.Stable(metrics)
+	return metrics, err
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// readFields calls fn for each field of the encoded protobuf message, with
+// the value of length-delimited fields as bytes and the value of varint and
+// fixed size fields as an integer.
+func readFields(b []byte, fn func(num protowire.Number, bytes []byte, scalar uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var bytesValue []byte
+		var scalar uint64
+		switch typ {
+		case protowire.BytesType:
+			bytesValue, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			scalar, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			scalar, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			scalar = uint64(v)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, bytesValue, scalar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
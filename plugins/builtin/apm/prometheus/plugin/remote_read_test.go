@@ -0,0 +1,176 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func Test_parseSelector(t *testing.T) {
+	testCases := []struct {
+		input         string
+		expected      []labelMatcher
+		expectedError string
+	}{
+		{
+			input:    "up",
+			expected: []labelMatcher{{typ: matchEqual, name: "__name__", value: "up"}},
+		},
+		{
+			input: `http_requests_total{job="web", code=~"5..", method!="GET", path!~` + "`/health.*`" + `,}`,
+			expected: []labelMatcher{
+				{typ: matchEqual, name: "__name__", value: "http_requests_total"},
+				{typ: matchEqual, name: "job", value: "web"},
+				{typ: matchRegexp, name: "code", value: "5.."},
+				{typ: matchNotEqual, name: "method", value: "GET"},
+				{typ: matchNotRegexp, name: "path", value: "/health.*"},
+			},
+		},
+		{
+			input: `{__name__=~"nomad:.*", task_group="a,b}\"c"}`,
+			expected: []labelMatcher{
+				{typ: matchRegexp, name: "__name__", value: "nomad:.*"},
+				{typ: matchEqual, name: "task_group", value: `a,b}"c`},
+			},
+		},
+		{
+			input:         "{}",
+			expectedError: "selector must contain at least one matcher",
+		},
+		{
+			input:         "rate(up[5m])",
+			expectedError: `invalid metric name "rate(up[5m])"`,
+		},
+		{
+			input:         `up{job="web"`,
+			expectedError: `expected "}" at the end of the selector`,
+		},
+		{
+			input:         `up{job}`,
+			expectedError: `expected label matcher operator in "job"`,
+		},
+		{
+			input:         `up{job=web}`,
+			expectedError: `invalid value for label "job": expected quoted string`,
+		},
+		{
+			input:         `up{job="web" instance="a"}`,
+			expectedError: `expected "," after label "job"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			m, err := parseSelector(tc.input)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, m)
+		})
+	}
+}
+
+func TestAPMPlugin_queryRemoteRead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/read", r.URL.Path)
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Read-Version"))
+
+		compressed, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		body, err := snappy.Decode(nil, compressed)
+		assert.NoError(t, err)
+
+		expected := encodeReadRequest([]labelMatcher{
+			{typ: matchEqual, name: "__name__", value: "nomad_client_allocated_memory"},
+			{typ: matchEqual, name: "node_class", value: "batch"},
+		}, sdk.TimeRange{From: time.Unix(1600000000, 0), To: time.Unix(1600000300, 0)}, time.Second)
+		assert.Equal(t, expected, body)
+
+		// Respond with two series, one of which only has a stale marker.
+		series := [][]byte{
+			encodeTestSeries(map[int64]float64{1600000060000: 2, 1600000000500: 1}),
+			encodeTestSeries(map[int64]float64{1600000060000: math.Float64frombits(0x7ff0000000000002)}),
+		}
+		var queryResult []byte
+		for _, s := range series {
+			queryResult = appendBytesField(queryResult, 1, s)
+		}
+		_, _ = w.Write(snappy.Encode(nil, appendBytesField(nil, 1, queryResult)))
+	}))
+	defer srv.Close()
+
+	plugin := NewPrometheusPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address": srv.URL,
+		"api":     "remote_read",
+	}))
+
+	metrics, err := plugin.Query(`nomad_client_allocated_memory{node_class="batch"}`, sdk.TimeRange{
+		From: time.Unix(1600000000, 0),
+		To:   time.Unix(1600000300, 0),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: time.UnixMilli(1600000000500), Value: 1},
+		{Timestamp: time.UnixMilli(1600000060000), Value: 2},
+	}, metrics)
+}
+
+func TestAPMPlugin_queryRemoteRead_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/read":
+			_, _ = w.Write([]byte("not snappy"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("404 page not found\n"))
+		}
+	}))
+	defer srv.Close()
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	plugin := NewPrometheusPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "api": "remote_read"}))
+
+	_, err := plugin.Query("up", r)
+	assert.EqualError(t, err, "failed to query: unexpected response code 404: 404 page not found")
+
+	_, err = plugin.Query("sum(up)", r)
+	assert.EqualError(t, err, `failed to parse series selector: invalid metric name "sum(up)"`)
+
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "api": "remote_read", "remote_read_path": "/read"}))
+	_, err = plugin.Query("up", r)
+	assert.ErrorContains(t, err, "failed to decompress response")
+}
+
+// encodeTestSeries encodes a prompb.TimeSeries with the input samples.
+func encodeTestSeries(samples map[int64]float64) []byte {
+	var series []byte
+	for ts, v := range samples {
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(v))
+		sample = appendVarintField(sample, 2, uint64(ts))
+		series = appendBytesField(series, 2, sample)
+	}
+	return series
+}
@@ -0,0 +1,280 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "rabbitmq"
+
+	// configKeyAddress is the address of the RabbitMQ management API.
+	configKeyAddress          = "address"
+	configValueAddressDefault = "http://127.0.0.1:15672"
+
+	// configKeyUsername and configKeyPassword configure basic auth.
+	configKeyUsername = "username"
+	configKeyPassword = "password"
+
+	// configKeyVHost is the virtual host used by queries which do not set
+	// one.
+	configKeyVHost          = "vhost"
+	configValueVHostDefault = "/"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+
+	// allQueues is the query queue name used to sum the metric across all
+	// queues of the virtual host.
+	allQueues = "*"
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewRabbitMQPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// queueMetrics maps the query metric names to the value of the queue.
+var queueMetrics = map[string]func(q *queueInfo) float64{
+	"messages":                func(q *queueInfo) float64 { return q.Messages },
+	"messages_ready":          func(q *queueInfo) float64 { return q.MessagesReady },
+	"messages_unacknowledged": func(q *queueInfo) float64 { return q.MessagesUnacknowledged },
+	"consumers":               func(q *queueInfo) float64 { return q.Consumers },
+	"publish_rate":            func(q *queueInfo) float64 { return q.MessageStats.Publish.Rate },
+	"ack_rate":                func(q *queueInfo) float64 { return q.MessageStats.Ack.Rate },
+	"deliver_rate":            func(q *queueInfo) float64 { return q.MessageStats.DeliverGet.Rate },
+	"redeliver_rate":          func(q *queueInfo) float64 { return q.MessageStats.Redeliver.Rate },
+}
+
+// APMPlugin is the RabbitMQ implementation of the apm.APM interface, which
+// reads queue metrics from the management API.
+type APMPlugin struct {
+	client  *http.Client
+	config  map[string]string
+	logger  hclog.Logger
+	address string
+	vhost   string
+	timeout time.Duration
+}
+
+func NewRabbitMQPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	addr := config[configKeyAddress]
+	if addr == "" {
+		addr = configValueAddressDefault
+	}
+	if _, err := url.Parse(addr); err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	a.vhost = config[configKeyVHost]
+	if a.vhost == "" {
+		a.vhost = configValueVHostDefault
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple reads the current value of the queue metric. The management
+// API reports point in time values and rates, so the metric stream contains a
+// single point and the time range is not used.
+//
+// Queries are in the form <metric>:<queue>[@<vhost>], such as
+// "messages_ready:orders@production". A queue of "*" sums the metric across
+// all queues of the virtual host.
+func (a *APMPlugin) QueryMultiple(q string, _ sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying RabbitMQ", "query", q)
+
+	metric, queue, vhost, err := a.parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	path := "/api/queues/" + url.PathEscape(vhost)
+	if queue != allQueues {
+		path += "/" + url.PathEscape(queue)
+	}
+
+	var queues []queueInfo
+	if queue == allQueues {
+		err = a.get(path, &queues)
+	} else {
+		var info queueInfo
+		err = a.get(path, &info)
+		queues = append(queues, info)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from rabbitmq: %v", err)
+	}
+
+	extract := queueMetrics[metric]
+
+	var value float64
+	for i := range queues {
+		value += extract(&queues[i])
+	}
+
+	return []sdk.TimestampedMetrics{{{Timestamp: time.Now(), Value: value}}}, nil
+}
+
+// parseQuery splits the query into the metric, queue and virtual host.
+func (a *APMPlugin) parseQuery(q string) (string, string, string, error) {
+	metric, target, ok := strings.Cut(strings.TrimSpace(q), ":")
+	if !ok || target == "" {
+		return "", "", "", fmt.Errorf("query %q must be in the form <metric>:<queue>[@<vhost>]", q)
+	}
+	if _, ok := queueMetrics[metric]; !ok {
+		names := make([]string, 0, len(queueMetrics))
+		for name := range queueMetrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", "", "", fmt.Errorf("unsupported metric %q, must be one of %s", metric, strings.Join(names, ", "))
+	}
+
+	queue, vhost := target, a.vhost
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		queue, vhost = target[:i], target[i+1:]
+	}
+	if queue == "" || vhost == "" {
+		return "", "", "", fmt.Errorf("query %q must be in the form <metric>:<queue>[@<vhost>]", q)
+	}
+
+	return metric, queue, vhost, nil
+}
+
+// get performs a management API request, decoding the JSON response into
+// out.
+func (a *APMPlugin) get(path string, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.address+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if user := a.config[configKeyUsername]; user != "" {
+		req.SetBasicAuth(user, a.config[configKeyPassword])
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+		var apiErr struct {
+			Error  string `json:"error"`
+			Reason string `json:"reason"`
+		}
+		if json.Unmarshal(b, &apiErr) == nil && apiErr.Reason != "" {
+			return fmt.Errorf("unexpected response code %d: %s: %s", resp.StatusCode, apiErr.Error, apiErr.Reason)
+		}
+		return fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
+// queueInfo is the subset of the management API queue object used by the
+// plugin. Message stats are omitted by the API until the queue has activity,
+// so rates default to zero.
+type queueInfo struct {
+	Name                   string  `json:"name"`
+	Consumers              float64 `json:"consumers"`
+	Messages               float64 `json:"messages"`
+	MessagesReady          float64 `json:"messages_ready"`
+	MessagesUnacknowledged float64 `json:"messages_unacknowledged"`
+	MessageStats           struct {
+		Publish    rateDetails `json:"publish_details"`
+		Ack        rateDetails `json:"ack_details"`
+		DeliverGet rateDetails `json:"deliver_get_details"`
+		Redeliver  rateDetails `json:"redeliver_details"`
+	} `json:"message_stats"`
+}
+
+type rateDetails struct {
+	Rate float64 `json:"rate"`
+}
@@ -0,0 +1,176 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "invalid address",
+			config:        map[string]string{"address": "\n\n"},
+			expectedError: `failed to parse "address"`,
+		},
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:   "defaults",
+			config: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+			assert.Equal(t, "http://127.0.0.1:15672", apmPlugin.address)
+			assert.Equal(t, "/", apmPlugin.vhost)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/queues/%2F/orders", r.URL.EscapedPath())
+
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "guest", user)
+		assert.Equal(t, "secret", pass)
+
+		http.ServeFile(w, r, path.Join("./test-fixtures", "queue_200.json"))
+	}))
+	defer srv.Close()
+
+	plugin := NewRabbitMQPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address":  srv.URL,
+		"username": "guest",
+		"password": "secret",
+	}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	testCases := []struct {
+		query    string
+		expected float64
+	}{
+		{query: "messages:orders", expected: 120},
+		{query: "messages_ready:orders", expected: 100},
+		{query: "messages_unacknowledged:orders", expected: 20},
+		{query: "consumers:orders", expected: 3},
+		{query: "publish_rate:orders", expected: 12.5},
+		{query: "ack_rate:orders", expected: 10.2},
+		{query: "deliver_rate:orders", expected: 10.4},
+		{query: "redeliver_rate:orders@/", expected: 0.2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			metrics, err := plugin.Query(tc.query, r)
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			assert.Equal(t, tc.expected, metrics[0].Value)
+		})
+	}
+}
+
+func TestAPMPlugin_Query_allQueues(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/queues/production", r.URL.EscapedPath())
+
+		// Queues without activity do not have message stats.
+		_, _ = w.Write([]byte(`[
+  {"name": "orders", "messages_ready": 7, "message_stats": {"publish_details": {"rate": 1.5}}},
+  {"name": "emails", "messages_ready": 3}
+]`))
+	}))
+	defer srv.Close()
+
+	plugin := NewRabbitMQPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "vhost": "production"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	metrics, err := plugin.Query("messages_ready:*", r)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, float64(10), metrics[0].Value)
+
+	metrics, err = plugin.Query("publish_rate:*", r)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, 1.5, metrics[0].Value)
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": "Object Not Found", "reason": "Not Found"}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewRabbitMQPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	testCases := []struct {
+		query         string
+		expectedError string
+	}{
+		{
+			query:         "messages:missing@production",
+			expectedError: "error querying metrics from rabbitmq: unexpected response code 404: Object Not Found: Not Found",
+		},
+		{
+			query:         "messages",
+			expectedError: `failed to parse query: query "messages" must be in the form <metric>:<queue>[@<vhost>]`,
+		},
+		{
+			query:         "messages:orders@",
+			expectedError: `failed to parse query: query "messages:orders@" must be in the form <metric>:<queue>[@<vhost>]`,
+		},
+		{
+			query:         "depth:orders",
+			expectedError: `failed to parse query: unsupported metric "depth", must be one of ack_rate, consumers, deliver_rate, messages, messages_ready, messages_unacknowledged, publish_rate, redeliver_rate`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := plugin.Query(tc.query, r)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
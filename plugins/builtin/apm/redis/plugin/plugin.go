@@ -0,0 +1,298 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "redis"
+
+	// configKeyAddress is a comma separated list of Redis addresses. Multiple
+	// addresses are treated as cluster nodes, unless configKeyMasterName is
+	// set, in which case they are sentinel addresses.
+	configKeyAddress          = "address"
+	configValueAddressDefault = "127.0.0.1:6379"
+
+	// configKeyMasterName is the name of the sentinel monitored master, and
+	// configKeySentinelUsername and configKeySentinelPassword authenticate
+	// with the sentinels.
+	configKeyMasterName       = "master_name"
+	configKeySentinelUsername = "sentinel_username"
+	configKeySentinelPassword = "sentinel_password"
+
+	// configKeyCluster forces the use of the cluster client when a single
+	// address is configured.
+	configKeyCluster = "cluster"
+
+	// configKeyUsername and configKeyPassword authenticate with Redis.
+	configKeyUsername = "username"
+	configKeyPassword = "password"
+
+	// configKeyDB is the database selected by the client. It is not
+	// supported by clusters.
+	configKeyDB = "db"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+)
+
+const (
+	commandLLen     = "llen"
+	commandXLen     = "xlen"
+	commandXPending = "xpending"
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewRedisPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// redisClient is the subset of the Redis client used by the plugin, which
+// allows it to be mocked in tests.
+type redisClient interface {
+	LLen(ctx context.Context, key string) *redis.IntCmd
+	XLen(ctx context.Context, stream string) *redis.IntCmd
+	XPending(ctx context.Context, stream, group string) *redis.XPendingCmd
+}
+
+// APMPlugin is the Redis implementation of the apm.APM interface, which reads
+// the length of lists and streams used as job queues.
+type APMPlugin struct {
+	logger hclog.Logger
+
+	// lock protects the fields below, which are updated by SetConfig.
+	lock    sync.Mutex
+	redis   redis.UniversalClient
+	client  redisClient
+	timeout time.Duration
+}
+
+func NewRedisPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	addr := config[configKeyAddress]
+	if addr == "" {
+		addr = configValueAddressDefault
+	}
+
+	var addrs []string
+	for _, s := range strings.Split(addr, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			addrs = append(addrs, s)
+		}
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+
+	timeout := configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		timeout = d
+	}
+
+	var db int
+	if v := config[configKeyDB]; v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return fmt.Errorf("failed to parse %q: must be a non-negative integer", configKeyDB)
+		}
+		db = i
+	}
+
+	var cluster bool
+	if v := config[configKeyCluster]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyCluster, err)
+		}
+		cluster = b
+	}
+
+	masterName := config[configKeyMasterName]
+	if cluster && masterName != "" {
+		return fmt.Errorf("%q and %q cannot both be set", configKeyCluster, configKeyMasterName)
+	}
+	if (cluster || (len(addrs) > 1 && masterName == "")) && db != 0 {
+		return fmt.Errorf("%q is not supported by Redis clusters", configKeyDB)
+	}
+
+	tlsConfig, err := tlsutil.NewOptionalClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            addrs,
+		DB:               db,
+		Username:         config[configKeyUsername],
+		Password:         config[configKeyPassword],
+		MasterName:       masterName,
+		SentinelUsername: config[configKeySentinelUsername],
+		SentinelPassword: config[configKeySentinelPassword],
+		DialTimeout:      timeout,
+		ReadTimeout:      timeout,
+		WriteTimeout:     timeout,
+		TLSConfig:        tlsConfig,
+	}
+
+	var cl redis.UniversalClient
+	if cluster {
+		cl = redis.NewClusterClient(opts.Cluster())
+	} else {
+		cl = redis.NewUniversalClient(opts)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.redis != nil {
+		_ = a.redis.Close()
+	}
+	a.redis = cl
+	a.client = cl
+	a.timeout = timeout
+
+	return nil
+}
+
+// Close closes the Redis client. It implements io.Closer so the plugin
+// manager closes the client when the plugin is removed.
+func (a *APMPlugin) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.redis != nil {
+		_ = a.redis.Close()
+		a.redis, a.client = nil, nil
+	}
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple reads the current length of the list or stream. Lengths are
+// point in time values, so the metric stream contains a single point and the
+// time range is not used.
+//
+// Queries are in the form <command>:<key>, where the command is llen or xlen,
+// or xpending:<stream>/<group> for the number of entries delivered to the
+// consumer group which have not been acknowledged.
+func (a *APMPlugin) QueryMultiple(q string, _ sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying Redis", "query", q)
+
+	command, key, group, err := parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	a.lock.Lock()
+	client, timeout := a.client, a.timeout
+	a.lock.Unlock()
+
+	if client == nil {
+		return nil, errors.New("plugin has not been configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var value int64
+
+	switch command {
+	case commandLLen:
+		value, err = client.LLen(ctx, key).Result()
+	case commandXLen:
+		value, err = client.XLen(ctx, key).Result()
+	case commandXPending:
+		var pending *redis.XPending
+		pending, err = client.XPending(ctx, key, group).Result()
+		if err == nil {
+			value = pending.Count
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s from redis: %v", command, err)
+	}
+
+	return []sdk.TimestampedMetrics{{{Timestamp: time.Now(), Value: float64(value)}}}, nil
+}
+
+// parseQuery splits the query into the command, key and consumer group. The
+// group is only set for xpending queries.
+func parseQuery(q string) (string, string, string, error) {
+	command, key, ok := strings.Cut(strings.TrimSpace(q), ":")
+	if !ok || key == "" {
+		return "", "", "", fmt.Errorf("query %q must be in the form <command>:<key>", q)
+	}
+
+	switch command {
+	case commandLLen, commandXLen:
+		return command, key, "", nil
+	case commandXPending:
+		// Keys commonly contain slashes, so the group follows the last one.
+		i := strings.LastIndex(key, "/")
+		if i <= 0 || i == len(key)-1 {
+			return "", "", "", fmt.Errorf("query %q must be in the form xpending:<stream>/<group>", q)
+		}
+		return command, key[:i], key[i+1:], nil
+	default:
+		return "", "", "", fmt.Errorf("unsupported command %q, must be one of %s, %s or %s",
+			command, commandLLen, commandXLen, commandXPending)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRedisClient struct {
+	lists   map[string]int64
+	streams map[string]int64
+	pending map[string]int64
+	err     error
+}
+
+func (m *mockRedisClient) LLen(_ context.Context, key string) *redis.IntCmd {
+	return redis.NewIntResult(m.lists[key], m.err)
+}
+
+func (m *mockRedisClient) XLen(_ context.Context, stream string) *redis.IntCmd {
+	return redis.NewIntResult(m.streams[stream], m.err)
+}
+
+func (m *mockRedisClient) XPending(_ context.Context, stream, group string) *redis.XPendingCmd {
+	if m.err != nil {
+		return redis.NewXPendingResult(nil, m.err)
+	}
+	count, ok := m.pending[stream+"/"+group]
+	if !ok {
+		return redis.NewXPendingResult(nil, errors.New("NOGROUP No such key or consumer group"))
+	}
+	return redis.NewXPendingResult(&redis.XPending{Count: count}, nil)
+}
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         map[string]string
+		expectedClient interface{}
+		expectedError  string
+	}{
+		{
+			name:           "defaults",
+			config:         map[string]string{},
+			expectedClient: &redis.Client{},
+		},
+		{
+			name:           "cluster",
+			config:         map[string]string{"address": "10.0.0.1:6379, 10.0.0.2:6379"},
+			expectedClient: &redis.ClusterClient{},
+		},
+		{
+			name:           "forced cluster",
+			config:         map[string]string{"address": "10.0.0.1:6379", "cluster": "true"},
+			expectedClient: &redis.ClusterClient{},
+		},
+		{
+			name: "sentinel",
+			config: map[string]string{
+				"address":           "10.0.0.1:26379,10.0.0.2:26379",
+				"master_name":       "jobs",
+				"sentinel_password": "secret",
+				"db":                "2",
+				"tls_enabled":       "true",
+			},
+			expectedClient: &redis.Client{},
+		},
+		{
+			name:          "empty address",
+			config:        map[string]string{"address": " , "},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "invalid db",
+			config:        map[string]string{"db": "-1"},
+			expectedError: `failed to parse "db"`,
+		},
+		{
+			name:          "cluster db",
+			config:        map[string]string{"address": "10.0.0.1:6379,10.0.0.2:6379", "db": "1"},
+			expectedError: `"db" is not supported by Redis clusters`,
+		},
+		{
+			name:          "cluster and sentinel",
+			config:        map[string]string{"cluster": "true", "master_name": "jobs"},
+			expectedError: `"cluster" and "master_name" cannot both be set`,
+		},
+		{
+			name:          "invalid tls_enabled",
+			config:        map[string]string{"tls_enabled": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			defer apmPlugin.Close()
+
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tc.expectedClient, apmPlugin.client)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	apmPlugin := APMPlugin{
+		logger:  hclog.NewNullLogger(),
+		timeout: time.Second,
+		client: &mockRedisClient{
+			lists:   map[string]int64{"queue:default": 42},
+			streams: map[string]int64{"events/orders": 120},
+			pending: map[string]int64{"events/orders/workers": 7},
+		},
+	}
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	testCases := []struct {
+		query    string
+		expected float64
+	}{
+		{query: "llen:queue:default", expected: 42},
+		{query: "llen:queue:empty", expected: 0},
+		{query: "xlen:events/orders", expected: 120},
+		{query: "xpending:events/orders/workers", expected: 7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			metrics, err := apmPlugin.Query(tc.query, r)
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			assert.Equal(t, tc.expected, metrics[0].Value)
+		})
+	}
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	testCases := []struct {
+		name          string
+		client        redisClient
+		query         string
+		expectedError string
+	}{
+		{
+			name:          "not configured",
+			query:         "llen:jobs",
+			expectedError: "plugin has not been configured",
+		},
+		{
+			name:          "request error",
+			client:        &mockRedisClient{err: errors.New("connection refused")},
+			query:         "llen:jobs",
+			expectedError: "error querying llen from redis: connection refused",
+		},
+		{
+			name:          "missing group",
+			client:        &mockRedisClient{},
+			query:         "xpending:events/workers",
+			expectedError: "error querying xpending from redis: NOGROUP No such key or consumer group",
+		},
+		{
+			name:          "invalid query",
+			client:        &mockRedisClient{},
+			query:         "jobs",
+			expectedError: `failed to parse query: query "jobs" must be in the form <command>:<key>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger(), timeout: time.Second, client: tc.client}
+
+			_, err := apmPlugin.Query(tc.query, r)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func Test_parseQuery(t *testing.T) {
+	testCases := []struct {
+		query           string
+		expectedCommand string
+		expectedKey     string
+		expectedGroup   string
+		expectedError   string
+	}{
+		{
+			query:           "llen:queue:default",
+			expectedCommand: "llen",
+			expectedKey:     "queue:default",
+		},
+		{
+			query:           " xlen:events ",
+			expectedCommand: "xlen",
+			expectedKey:     "events",
+		},
+		{
+			query:           "xpending:app/events/workers",
+			expectedCommand: "xpending",
+			expectedKey:     "app/events",
+			expectedGroup:   "workers",
+		},
+		{
+			query:         "xpending:events",
+			expectedError: `query "xpending:events" must be in the form xpending:<stream>/<group>`,
+		},
+		{
+			query:         "xpending:events/",
+			expectedError: `query "xpending:events/" must be in the form xpending:<stream>/<group>`,
+		},
+		{
+			query:         "scard:members",
+			expectedError: `unsupported command "scard", must be one of llen, xlen or xpending`,
+		},
+		{
+			query:         "llen:",
+			expectedError: `query "llen:" must be in the form <command>:<key>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			command, key, group, err := parseQuery(tc.query)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedCommand, command)
+			assert.Equal(t, tc.expectedKey, key)
+			assert.Equal(t, tc.expectedGroup, group)
+		})
+	}
+}
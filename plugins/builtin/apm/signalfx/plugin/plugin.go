@@ -0,0 +1,212 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "signalfx"
+
+	// configKeyRealm is the Splunk Observability realm of the organization,
+	// such as "us1", which is used to build the default stream address.
+	configKeyRealm = "realm"
+
+	// configKeyAddress overrides the address of the SignalFlow API, such as
+	// "https://stream.us1.signalfx.com".
+	configKeyAddress = "address"
+
+	// configKeyAccessToken is the access token used to authenticate
+	// queries. It requires the API scope.
+	configKeyAccessToken = "access_token"
+
+	// configKeyResolution is the minimum resolution of the computed values.
+	// When unset SignalFlow selects the resolution based on the window.
+	configKeyResolution = "resolution"
+
+	// configKeyTimeout is the maximum duration of a query, including
+	// streaming all of the computed values.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 30 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewSignalFxPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the SignalFx implementation of the apm.APM interface, which
+// executes SignalFlow programs and collects the values they compute.
+type APMPlugin struct {
+	client      *http.Client
+	logger      hclog.Logger
+	address     string
+	accessToken string
+	resolution  time.Duration
+	timeout     time.Duration
+}
+
+func NewSignalFxPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	addr, realm := config[configKeyAddress], config[configKeyRealm]
+	switch {
+	case addr != "" && realm != "":
+		return fmt.Errorf("only one of %q and %q can be set", configKeyAddress, configKeyRealm)
+	case realm != "":
+		addr = fmt.Sprintf("https://stream.%s.signalfx.com", realm)
+	case addr == "":
+		return fmt.Errorf("one of %q or %q must be set", configKeyRealm, configKeyAddress)
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("%q must be an absolute URL", configKeyAddress)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	a.accessToken = config[configKeyAccessToken]
+	if a.accessToken == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAccessToken)
+	}
+
+	a.resolution = 0
+	if v := config[configKeyResolution]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyResolution, err)
+		}
+		if d < time.Second {
+			return fmt.Errorf("%q must be at least 1s", configKeyResolution)
+		}
+		a.resolution = d
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple executes the query as a SignalFlow program over the time
+// range, such as "data('cpu.utilization', filter=filter('job', 'web')).mean().publish()",
+// returning a metric stream for each published timeseries.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying SignalFx", "query", q, "range", r)
+
+	// The program is run in immediate mode, so the stream ends once the
+	// values of the whole range have been computed instead of waiting for
+	// delayed data.
+	params := url.Values{
+		"start":     {strconv.FormatInt(r.From.UnixMilli(), 10)},
+		"stop":      {strconv.FormatInt(r.To.UnixMilli(), 10)},
+		"immediate": {"true"},
+	}
+	if a.resolution > 0 {
+		params.Set("resolution", strconv.FormatInt(a.resolution.Milliseconds(), 10))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		a.address+"/v2/signalflow/execute?"+params.Encode(), strings.NewReader(q))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("X-SF-Token", a.accessToken)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from signalfx: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("error querying metrics from signalfx: unexpected response code %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	results, err := readStream(resp.Body, a.logger)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from signalfx: %v", err)
+	}
+
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from signalfx, try a wider query window")
+	}
+	return results, nil
+}
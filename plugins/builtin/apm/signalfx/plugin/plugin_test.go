@@ -0,0 +1,194 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name            string
+		config          map[string]string
+		expectedAddress string
+		expectedError   string
+	}{
+		{
+			name:          "missing address and realm",
+			config:        map[string]string{"access_token": "token"},
+			expectedError: `one of "realm" or "address" must be set`,
+		},
+		{
+			name:          "address and realm",
+			config:        map[string]string{"address": "https://stream.us1.signalfx.com", "realm": "us1", "access_token": "token"},
+			expectedError: `only one of "address" and "realm" can be set`,
+		},
+		{
+			name:          "relative address",
+			config:        map[string]string{"address": "stream.us1.signalfx.com", "access_token": "token"},
+			expectedError: `"address" must be an absolute URL`,
+		},
+		{
+			name:          "missing access token",
+			config:        map[string]string{"realm": "us1"},
+			expectedError: `"access_token" config value cannot be empty`,
+		},
+		{
+			name:          "invalid resolution",
+			config:        map[string]string{"realm": "us1", "access_token": "token", "resolution": "500ms"},
+			expectedError: `"resolution" must be at least 1s`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"realm": "us1", "access_token": "token", "skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:            "valid realm",
+			config:          map[string]string{"realm": "eu0", "access_token": "token", "resolution": "10s"},
+			expectedAddress: "https://stream.eu0.signalfx.com",
+		},
+		{
+			name:            "valid address",
+			config:          map[string]string{"address": "https://signalflow.example.com/", "access_token": "token", "timeout": "1m"},
+			expectedAddress: "https://signalflow.example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+			assert.Equal(t, tc.expectedAddress, apmPlugin.address)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	program := "data('cpu.utilization', filter=filter('task_group', 'web')).mean().publish()"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v2/signalflow/execute", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-SF-Token"))
+		assert.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+
+		qp := r.URL.Query()
+		assert.Equal(t, "1704067200000", qp.Get("start"))
+		assert.Equal(t, "1704067500000", qp.Get("stop"))
+		assert.Equal(t, "10000", qp.Get("resolution"))
+		assert.Equal(t, "true", qp.Get("immediate"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, program, string(body))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		http.ServeFile(w, r, path.Join("./test-fixtures", "execute_200.txt"))
+	}))
+	defer srv.Close()
+
+	plugin := NewSignalFxPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address":      srv.URL,
+		"access_token": "secret",
+		"resolution":   "10s",
+	}))
+
+	metrics, err := plugin.Query(program, sdk.TimeRange{
+		From: time.Unix(1704067200, 0),
+		To:   time.Unix(1704067500, 0),
+	})
+	require.NoError(t, err)
+
+	// Null values should be skipped.
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: time.UnixMilli(1704067260000), Value: 42.5},
+		{Timestamp: time.UnixMilli(1704067380000), Value: 48},
+	}, metrics)
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`event: data
+data: {"data": [{"tsId": "b", "value": 2}, {"tsId": "a", "value": 1}], "logicalTimestampMs": 1704067260000}
+
+event: data
+data: {"data": [{"tsId": "a", "value": 3}], "logicalTimestampMs": 1704067320000}
+
+event: control-message
+data: {"event": "END_OF_CHANNEL"}
+
+`))
+	}))
+	defer srv.Close()
+
+	plugin := NewSignalFxPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "access_token": "secret"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	// Timeseries are returned in the order they were first seen.
+	metrics, err := plugin.QueryMultiple("data('cpu.utilization').publish()", r)
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{{Timestamp: time.UnixMilli(1704067260000), Value: 2}},
+		{{Timestamp: time.UnixMilli(1704067260000), Value: 1}, {Timestamp: time.UnixMilli(1704067320000), Value: 3}},
+	}, metrics)
+
+	_, err = plugin.Query("data('cpu.utilization').publish()", r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		switch string(body) {
+		case "unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("Unauthorized\n"))
+		case "invalid":
+			_, _ = w.Write([]byte("event: error\ndata: {\"error\": 400, \"errors\": [{\"code\": \"ANALYTICS_PROGRAM_NAME_ERROR\", \"message\": \"data is not defined\"}]}\n\n"))
+		case "aborted":
+			_, _ = w.Write([]byte("event: control-message\ndata: {\"event\": \"CHANNEL_ABORT\", \"abortInfo\": {\"sf_job_abortReason\": \"Too many timeseries\"}}\n\n"))
+		default:
+			_, _ = w.Write([]byte("event: data\ndata: {\"data\": [], \"logicalTimestampMs\": 1704067260000}\n\n"))
+		}
+	}))
+	defer srv.Close()
+
+	plugin := NewSignalFxPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "access_token": "secret"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	_, err := plugin.Query("unauthorized", r)
+	assert.EqualError(t, err, "error querying metrics from signalfx: unexpected response code 401: Unauthorized")
+
+	_, err = plugin.Query("invalid", r)
+	assert.EqualError(t, err, "error querying metrics from signalfx: program failed: data is not defined")
+
+	_, err = plugin.Query("aborted", r)
+	assert.EqualError(t, err, "error querying metrics from signalfx: computation aborted: Too many timeseries")
+
+	_, err = plugin.Query("truncated", r)
+	assert.EqualError(t, err, "error querying metrics from signalfx: stream ended before the end of the channel")
+}
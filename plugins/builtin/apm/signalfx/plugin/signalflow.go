@@ -0,0 +1,181 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	eventTypeControl = "control-message"
+	eventTypeData    = "data"
+	eventTypeError   = "error"
+
+	controlEndOfChannel = "END_OF_CHANNEL"
+	controlChannelAbort = "CHANNEL_ABORT"
+)
+
+// streamEvent is a server-sent event of the SignalFlow stream.
+type streamEvent struct {
+	name string
+	data string
+}
+
+// controlMessage reports the state of the SignalFlow computation.
+type controlMessage struct {
+	Event     string `json:"event"`
+	AbortInfo struct {
+		SFJobAbortReason string `json:"sf_job_abortReason"`
+	} `json:"abortInfo"`
+}
+
+// dataMessage holds the values computed for each timeseries at a point in
+// time.
+type dataMessage struct {
+	LogicalTimestampMs int64 `json:"logicalTimestampMs"`
+	Data               []struct {
+		TSID  string   `json:"tsId"`
+		Value *float64 `json:"value"`
+	} `json:"data"`
+}
+
+// errorMessage reports a failure to start or run the SignalFlow program.
+type errorMessage struct {
+	Message string `json:"message"`
+	Errors  []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (m *errorMessage) String() string {
+	if m.Message != "" {
+		return m.Message
+	}
+	msgs := make([]string, 0, len(m.Errors))
+	for _, e := range m.Errors {
+		msgs = append(msgs, e.Message)
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// readStream reads the SignalFlow event stream until the end of the channel,
+// returning a metric stream for each timeseries in the order they were first
+// seen. Events other than data, errors and control messages are ignored.
+func readStream(r io.Reader, logger hclog.Logger) ([]sdk.TimestampedMetrics, error) {
+	series := make(map[string]sdk.TimestampedMetrics)
+	var order []string
+
+	err := readEvents(r, func(e streamEvent) (bool, error) {
+		switch e.name {
+		case eventTypeControl:
+			var msg controlMessage
+			if err := json.Unmarshal([]byte(e.data), &msg); err != nil {
+				return false, fmt.Errorf("failed to decode control message: %v", err)
+			}
+			switch msg.Event {
+			case controlEndOfChannel:
+				return true, nil
+			case controlChannelAbort:
+				return false, fmt.Errorf("computation aborted: %s", msg.AbortInfo.SFJobAbortReason)
+			}
+
+		case eventTypeData:
+			var msg dataMessage
+			if err := json.Unmarshal([]byte(e.data), &msg); err != nil {
+				return false, fmt.Errorf("failed to decode data message: %v", err)
+			}
+			ts := time.UnixMilli(msg.LogicalTimestampMs)
+			for _, d := range msg.Data {
+				if d.Value == nil {
+					continue
+				}
+				if _, ok := series[d.TSID]; !ok {
+					order = append(order, d.TSID)
+				}
+				series[d.TSID] = append(series[d.TSID], sdk.TimestampedMetric{Timestamp: ts, Value: *d.Value})
+			}
+
+		case eventTypeError:
+			var msg errorMessage
+			if err := json.Unmarshal([]byte(e.data), &msg); err != nil {
+				return false, fmt.Errorf("failed to decode error message: %v", err)
+			}
+			return false, fmt.Errorf("program failed: %s", msg.String())
+
+		default:
+			logger.Trace("ignoring signalflow event", "event", e.name)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]sdk.TimestampedMetrics, 0, len(order))
+	for _, id := range order {
+		m := series[id]
+		sort.Stable(m)
+		results = append(results, m)
+	}
+	return results, nil
+}
+
+// readEvents parses the server-sent events of r, calling fn for each one
+// until it returns true or an error. An error is returned if the stream ends
+// before fn is done.
+func readEvents(r io.Reader, fn func(streamEvent) (bool, error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var e streamEvent
+	var data []string
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the event.
+		if line == "" {
+			if e.name == "" && len(data) == 0 {
+				continue
+			}
+			e.data = strings.Join(data, "\n")
+			done, err := fn(e)
+			if err != nil || done {
+				return err
+			}
+			e, data = streamEvent{}, nil
+			continue
+		}
+
+		// Lines starting with a colon are comments, such as keepalives.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			e.name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %v", err)
+	}
+	return errors.New("stream ended before the end of the channel")
+}
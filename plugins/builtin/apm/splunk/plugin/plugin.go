@@ -0,0 +1,312 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "splunk"
+
+	// configKeyAddress is the address of the Splunk management port, such as
+	// "https://127.0.0.1:8089".
+	configKeyAddress = "address"
+
+	// configKeyToken configures a Splunk authentication token, while
+	// configKeyUsername and configKeyPassword configure basic auth. The
+	// token takes precedence if both are set.
+	configKeyToken    = "token"
+	configKeyUsername = "username"
+	configKeyPassword = "password"
+
+	// configKeyApp and configKeyOwner set the namespace searches are run
+	// within, which is required to find saved searches that are not shared
+	// globally.
+	configKeyApp            = "app"
+	configKeyOwner          = "owner"
+	configValueOwnerDefault = "-"
+
+	// configKeyTimeout is the maximum duration of a search.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 30 * time.Second
+
+	// savedSearchPrefix is the query prefix used to run a saved search
+	// rather than an ad-hoc search.
+	savedSearchPrefix = "saved:"
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewSplunkPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the Splunk implementation of the apm.APM interface, which
+// runs blocking oneshot searches using the Splunk REST API.
+type APMPlugin struct {
+	client  *http.Client
+	config  map[string]string
+	logger  hclog.Logger
+	address string
+	timeout time.Duration
+}
+
+func NewSplunkPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	a.config = config
+
+	addr := config[configKeyAddress]
+	if addr == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+	if _, err := url.Parse(addr); err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	if config[configKeyToken] == "" && config[configKeyUsername] == "" {
+		return fmt.Errorf("one of %q or %q must be set", configKeyToken, configKeyUsername)
+	}
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple runs the search over the query range, returning a metric
+// stream for each numeric result field, such as each series of a timechart.
+// Queries prefixed with "saved:" run the named saved search.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying Splunk", "query", q, "range", r)
+
+	form := url.Values{
+		"search":        {searchString(q)},
+		"exec_mode":     {"oneshot"},
+		"output_mode":   {"json"},
+		"earliest_time": {strconv.FormatInt(r.From.Unix(), 10)},
+		"latest_time":   {strconv.FormatInt(r.To.Unix(), 10)},
+		"count":         {"0"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.searchURL(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if token := a.config[configKeyToken]; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.SetBasicAuth(a.config[configKeyUsername], a.config[configKeyPassword])
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from splunk: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error querying metrics from splunk: %v", responseError(resp))
+	}
+
+	var result searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode splunk response: %v", err)
+	}
+
+	for _, m := range result.Messages {
+		if m.Type == "WARN" {
+			a.logger.Warn("splunk search returned warning", "warning", m.Text)
+		}
+	}
+
+	metrics, err := result.metrics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse splunk response: %v", err)
+	}
+
+	if len(metrics) == 0 {
+		a.logger.Warn("empty time series response from splunk, try a wider query window")
+	}
+	return metrics, nil
+}
+
+// searchURL returns the URL of the search jobs endpoint, within the app
+// namespace if one is configured.
+func (a *APMPlugin) searchURL() string {
+	app := a.config[configKeyApp]
+	if app == "" {
+		return a.address + "/services/search/jobs"
+	}
+
+	owner := a.config[configKeyOwner]
+	if owner == "" {
+		owner = configValueOwnerDefault
+	}
+	return fmt.Sprintf("%s/servicesNS/%s/%s/search/jobs", a.address, url.PathEscape(owner), url.PathEscape(app))
+}
+
+// searchString converts the check query into the SPL search string. Ad-hoc
+// searches must start with a search command, so "search" is added if the
+// query does not start with one or a generating command.
+func searchString(q string) string {
+	q = strings.TrimSpace(q)
+
+	if name, ok := strings.CutPrefix(q, savedSearchPrefix); ok {
+		return fmt.Sprintf("| savedsearch %q", strings.TrimSpace(name))
+	}
+	if strings.HasPrefix(q, "|") || strings.HasPrefix(q, "search ") {
+		return q
+	}
+	return "search " + q
+}
+
+// responseError converts a Splunk error response into an error, using the
+// messages of the JSON body if possible.
+func responseError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var body searchResponse
+	if err := json.Unmarshal(b, &body); err == nil && len(body.Messages) > 0 {
+		msgs := make([]string, len(body.Messages))
+		for i, m := range body.Messages {
+			msgs[i] = m.Text
+		}
+		return fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, strings.Join(msgs, "; "))
+	}
+	return fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+}
+
+// searchResponse is the subset of the JSON search results used by the
+// plugin.
+type searchResponse struct {
+	Messages []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"messages"`
+	Fields []struct {
+		Name string `json:"name"`
+	} `json:"fields"`
+	Results []map[string]interface{} `json:"results"`
+}
+
+// metrics converts the search results into metric streams. Each result must
+// contain the _time field, and each field which does not start with an
+// underscore is converted into a stream, in the order of the result fields.
+// Empty values, such as gaps within a timechart, are skipped.
+func (s *searchResponse) metrics() ([]sdk.TimestampedMetrics, error) {
+	var fields []string
+	for _, f := range s.Fields {
+		if !strings.HasPrefix(f.Name, "_") {
+			fields = append(fields, f.Name)
+		}
+	}
+
+	streams := make([]sdk.TimestampedMetrics, len(fields))
+
+	for _, res := range s.Results {
+		rawTime, ok := res["_time"].(string)
+		if !ok {
+			return nil, errors.New("result does not contain the _time field")
+		}
+		ts, err := time.Parse(time.RFC3339Nano, rawTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse _time: %v", err)
+		}
+		ts = ts.Local()
+
+		for i, field := range fields {
+			raw, ok := res[field].(string)
+			if !ok || raw == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("field %q is not numeric: %q", field, raw)
+			}
+			streams[i] = append(streams[i], sdk.TimestampedMetric{Timestamp: ts, Value: v})
+		}
+	}
+
+	var results []sdk.TimestampedMetrics
+	for _, stream := range streams {
+		if len(stream) > 0 {
+			results = append(results, stream)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,183 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing address",
+			config:        map[string]string{},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "missing credentials",
+			config:        map[string]string{"address": "https://127.0.0.1:8089"},
+			expectedError: `one of "token" or "username" must be set`,
+		},
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"address": "https://127.0.0.1:8089", "token": "t", "timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"address": "https://127.0.0.1:8089", "token": "t", "skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"address": "https://127.0.0.1:8089", "username": "admin", "skip_verify": "true"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+		})
+	}
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/services/search/jobs", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "search index=web | timechart span=1m count by service", r.PostForm.Get("search"))
+		assert.Equal(t, "oneshot", r.PostForm.Get("exec_mode"))
+		assert.Equal(t, "json", r.PostForm.Get("output_mode"))
+		assert.Equal(t, "1704067200", r.PostForm.Get("earliest_time"))
+		assert.Equal(t, "1704067500", r.PostForm.Get("latest_time"))
+
+		http.ServeFile(w, r, path.Join("./test-fixtures", "search_200.json"))
+	}))
+	defer srv.Close()
+
+	plugin := NewSplunkPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "token": "secret"}))
+
+	r := sdk.TimeRange{From: time.Unix(1704067200, 0), To: time.Unix(1704067500, 0)}
+
+	metrics, err := plugin.QueryMultiple("index=web | timechart span=1m count by service", r)
+	require.NoError(t, err)
+
+	// Internal fields are skipped, as are the empty and missing values.
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{
+			{Timestamp: time.Unix(1704067260, 0), Value: 4},
+			{Timestamp: time.Unix(1704067320, 0), Value: 6},
+		},
+		{
+			{Timestamp: time.Unix(1704067260, 0), Value: 12},
+			{Timestamp: time.Unix(1704067380, 0), Value: 15.5},
+		},
+	}, metrics)
+
+	_, err = plugin.Query("index=web | timechart span=1m count by service", r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_Query_savedSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/servicesNS/-/ops/search/jobs", r.URL.Path)
+
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "admin", user)
+		assert.Equal(t, "changeme", pass)
+
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, `| savedsearch "web requests"`, r.PostForm.Get("search"))
+
+		_, _ = w.Write([]byte(`{
+  "fields": [{"name": "_time"}, {"name": "count"}],
+  "results": [{"_time": "2024-01-01T00:01:00.000+00:00", "count": "7"}]
+}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewSplunkPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address":  srv.URL,
+		"username": "admin",
+		"password": "changeme",
+		"app":      "ops",
+	}))
+
+	metrics, err := plugin.Query("saved:web requests", sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()})
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: time.Unix(1704067260, 0), Value: 7},
+	}, metrics)
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("search") == "search index=web | table _time host" {
+			_, _ = w.Write([]byte(`{
+  "fields": [{"name": "_time"}, {"name": "host"}],
+  "results": [{"_time": "2024-01-01T00:01:00.000+00:00", "host": "web-1"}]
+}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"messages": [{"type": "FATAL", "text": "Unknown search command 'timechat'."}]}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewSplunkPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "token": "secret"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	_, err := plugin.Query("index=web | timechat count", r)
+	assert.EqualError(t, err, "error querying metrics from splunk: unexpected response code 400: Unknown search command 'timechat'.")
+
+	_, err = plugin.Query("index=web | table _time host", r)
+	assert.EqualError(t, err, `failed to parse splunk response: field "host" is not numeric: "web-1"`)
+}
+
+func Test_searchString(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{query: "index=web | timechart count", expected: "search index=web | timechart count"},
+		{query: "search index=web | timechart count", expected: "search index=web | timechart count"},
+		{query: "| mstats avg(cpu) WHERE index=metrics span=1m", expected: "| mstats avg(cpu) WHERE index=metrics span=1m"},
+		{query: "saved: web requests ", expected: `| savedsearch "web requests"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			assert.Equal(t, tc.expected, searchString(tc.query))
+		})
+	}
+}
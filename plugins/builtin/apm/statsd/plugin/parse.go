@@ -0,0 +1,133 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// metricKind is the type of a StatsD metric.
+type metricKind string
+
+const (
+	kindCounter metricKind = "counter"
+	kindGauge   metricKind = "gauge"
+	kindTimer   metricKind = "timer"
+	kindSet     metricKind = "set"
+)
+
+// sample is a single value received for a metric.
+type sample struct {
+	name string
+	kind metricKind
+	tags map[string]string
+
+	// value is the numeric value of counters, gauges and timers, while
+	// setValue is the member added to sets.
+	value    float64
+	setValue string
+
+	// relative is set for gauge values prefixed with a sign, which modify
+	// the current value rather than replacing it.
+	relative bool
+
+	// rate is the client side sample rate, used to scale counters and
+	// timer counts.
+	rate float64
+}
+
+// parseLine parses a single StatsD line, in the form:
+//
+//	<name>:<value>[:<value>...]|<type>[|@<sample_rate>][|#<tag>[:<value>],...]
+//
+// Types c, g, ms, h, d and s are supported. The DogStatsD tags and multiple
+// value extensions are supported, while other extension sections are
+// ignored. DogStatsD events and service checks return no samples.
+func parseLine(line string) ([]sample, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "_e{") || strings.HasPrefix(line, "_sc|") {
+		return nil, nil
+	}
+
+	sections := strings.Split(line, "|")
+	if len(sections) < 2 {
+		return nil, fmt.Errorf("invalid line %q: missing type", line)
+	}
+
+	name, rawValues, ok := strings.Cut(sections[0], ":")
+	if !ok || name == "" || rawValues == "" {
+		return nil, fmt.Errorf("invalid line %q: missing name or value", line)
+	}
+
+	var kind metricKind
+	switch sections[1] {
+	case "c":
+		kind = kindCounter
+	case "g":
+		kind = kindGauge
+	case "ms", "h", "d":
+		kind = kindTimer
+	case "s":
+		kind = kindSet
+	default:
+		return nil, fmt.Errorf("invalid line %q: unsupported type %q", line, sections[1])
+	}
+
+	rate := 1.0
+	var tags map[string]string
+
+	for _, s := range sections[2:] {
+		switch {
+		case strings.HasPrefix(s, "@"):
+			r, err := strconv.ParseFloat(s[1:], 64)
+			if err != nil || r <= 0 || r > 1 {
+				return nil, fmt.Errorf("invalid line %q: invalid sample rate %q", line, s[1:])
+			}
+			rate = r
+		case strings.HasPrefix(s, "#"):
+			tags = parseTags(s[1:])
+		}
+	}
+
+	var samples []sample
+	for _, raw := range strings.Split(rawValues, ":") {
+		smp := sample{name: name, kind: kind, tags: tags, rate: rate}
+
+		if kind == kindSet {
+			smp.setValue = raw
+			samples = append(samples, smp)
+			continue
+		}
+
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %q: invalid value %q", line, raw)
+		}
+		smp.value = v
+		smp.relative = kind == kindGauge && (raw[0] == '+' || raw[0] == '-')
+		samples = append(samples, smp)
+	}
+
+	if len(samples) == 0 {
+		return nil, errors.New("no values")
+	}
+	return samples, nil
+}
+
+// parseTags parses DogStatsD tags. Tags without a value are set to an empty
+// string.
+func parseTags(s string) map[string]string {
+	tags := make(map[string]string)
+	for _, t := range strings.Split(s, ",") {
+		if t == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(t, ":")
+		tags[k] = v
+	}
+	return tags
+}
@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseLine(t *testing.T) {
+	testCases := []struct {
+		name          string
+		line          string
+		expected      []sample
+		expectedError string
+	}{
+		{
+			name:     "counter",
+			line:     "requests:1|c",
+			expected: []sample{{name: "requests", kind: kindCounter, value: 1, rate: 1}},
+		},
+		{
+			name:     "sampled counter with tags",
+			line:     "requests:2|c|@0.5|#service:web,canary",
+			expected: []sample{{name: "requests", kind: kindCounter, value: 2, rate: 0.5, tags: map[string]string{"service": "web", "canary": ""}}},
+		},
+		{
+			name:     "relative gauge",
+			line:     "queue.depth:-3|g",
+			expected: []sample{{name: "queue.depth", kind: kindGauge, value: -3, relative: true, rate: 1}},
+		},
+		{
+			name: "multiple value distribution",
+			line: "latency:10:20|d|c:abc123",
+			expected: []sample{
+				{name: "latency", kind: kindTimer, value: 10, rate: 1},
+				{name: "latency", kind: kindTimer, value: 20, rate: 1},
+			},
+		},
+		{
+			name:     "set",
+			line:     "users:alice|s",
+			expected: []sample{{name: "users", kind: kindSet, setValue: "alice", rate: 1}},
+		},
+		{
+			name: "event",
+			line: "_e{5,4}:title|text",
+		},
+		{
+			name:          "missing type",
+			line:          "requests:1",
+			expectedError: "missing type",
+		},
+		{
+			name:          "unsupported type",
+			line:          "requests:1|x",
+			expectedError: `unsupported type "x"`,
+		},
+		{
+			name:          "invalid value",
+			line:          "requests:one|c",
+			expectedError: `invalid value "one"`,
+		},
+		{
+			name:          "invalid sample rate",
+			line:          "requests:1|c|@2",
+			expectedError: `invalid sample rate "2"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			samples, err := parseLine(tc.line)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, samples)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "statsd"
+
+	// configKeyAddress is the UDP address the StatsD listener binds to.
+	configKeyAddress          = "address"
+	configValueAddressDefault = "0.0.0.0:8125"
+
+	// configKeyFlushInterval is the duration samples are aggregated over to
+	// produce each metric point.
+	configKeyFlushInterval          = "flush_interval"
+	configValueFlushIntervalDefault = 10 * time.Second
+
+	// configKeyRetention is the sliding window of points kept in memory,
+	// which limits the query window of checks. Series which are not updated
+	// within the window are removed.
+	configKeyRetention          = "retention"
+	configValueRetentionDefault = 10 * time.Minute
+
+	// maxPacketSize is the maximum size of a UDP datagram.
+	maxPacketSize = 65535
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewStatsDPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the StatsD implementation of the apm.APM interface. It runs a
+// StatsD and DogStatsD UDP listener, aggregating the received metrics in
+// memory so they can be queried without an external metrics backend.
+type APMPlugin struct {
+	logger hclog.Logger
+	store  *store
+
+	// lock protects the fields below, which are updated by SetConfig.
+	lock     sync.Mutex
+	addr     string
+	conn     net.PacketConn
+	interval time.Duration
+	stopCh   chan struct{}
+}
+
+func NewStatsDPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+		store:  newStore(configValueFlushIntervalDefault, configValueRetentionDefault),
+	}
+}
+
+// SetConfig configures the plugin and starts the listener. When called
+// again, the listener is only restarted if its address has changed, and
+// aggregated metrics are kept.
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	addr := config[configKeyAddress]
+	if addr == "" {
+		addr = configValueAddressDefault
+	}
+
+	interval, err := parsePositiveDuration(config, configKeyFlushInterval, configValueFlushIntervalDefault)
+	if err != nil {
+		return err
+	}
+	retention, err := parsePositiveDuration(config, configKeyRetention, configValueRetentionDefault)
+	if err != nil {
+		return err
+	}
+	if retention < interval {
+		return fmt.Errorf("%q must not be less than %q", configKeyRetention, configKeyFlushInterval)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.store.setConfig(interval, retention)
+
+	if addr != a.addr {
+		if a.conn != nil {
+			_ = a.conn.Close()
+			a.addr, a.conn = "", nil
+		}
+
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to start StatsD listener: %v", err)
+		}
+		a.addr, a.conn = addr, conn
+
+		a.logger.Info("starting StatsD listener", "address", conn.LocalAddr().String())
+		go a.listen(conn)
+	}
+
+	if interval != a.interval || a.stopCh == nil {
+		if a.stopCh != nil {
+			close(a.stopCh)
+		}
+		a.interval = interval
+		a.stopCh = make(chan struct{})
+		go a.flushLoop(interval, a.stopCh)
+	}
+
+	return nil
+}
+
+func parsePositiveDuration(config map[string]string, key string, def time.Duration) (time.Duration, error) {
+	v := config[key]
+	if v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %q: %v", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%q must be greater than zero", key)
+	}
+	return d, nil
+}
+
+// listen reads packets from the connection until it is closed. Lines which
+// cannot be parsed are logged and skipped.
+func (a *APMPlugin) listen(conn net.PacketConn) {
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				a.logger.Error("StatsD listener stopped", "error", err)
+			}
+			return
+		}
+
+		now := time.Now()
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			samples, err := parseLine(line)
+			if err != nil {
+				a.logger.Debug("failed to parse StatsD line", "error", err)
+				continue
+			}
+			for _, smp := range samples {
+				a.store.add(smp, now)
+			}
+		}
+	}
+}
+
+func (a *APMPlugin) flushLoop(interval time.Duration, stopCh chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case now := <-ticker.C:
+			a.store.flush(now)
+		}
+	}
+}
+
+// Close stops the listener. It implements io.Closer so the plugin manager
+// stops the listener when the plugin is removed.
+func (a *APMPlugin) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.conn != nil {
+		_ = a.conn.Close()
+		a.addr, a.conn = "", nil
+	}
+	if a.stopCh != nil {
+		close(a.stopCh)
+		a.interval, a.stopCh = 0, nil
+	}
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple returns the aggregated points of the series matching the
+// query within the time range.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying StatsD metrics", "query", q, "range", r)
+
+	parsed, err := parseQuery(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %v", err)
+	}
+
+	results, err := a.store.query(parsed, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from statsd, try a wider query window")
+	}
+	return results, nil
+}
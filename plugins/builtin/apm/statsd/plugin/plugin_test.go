@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "invalid flush interval",
+			config:        map[string]string{"address": "127.0.0.1:0", "flush_interval": "often"},
+			expectedError: `failed to parse "flush_interval"`,
+		},
+		{
+			name:          "retention less than flush interval",
+			config:        map[string]string{"address": "127.0.0.1:0", "flush_interval": "1m", "retention": "30s"},
+			expectedError: `"retention" must not be less than "flush_interval"`,
+		},
+		{
+			name:          "invalid address",
+			config:        map[string]string{"address": "127.0.0.1:-1"},
+			expectedError: "failed to start StatsD listener",
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"address": "127.0.0.1:0", "flush_interval": "5s", "retention": "5m"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := NewStatsDPlugin(hclog.NewNullLogger()).(*APMPlugin)
+			defer apmPlugin.Close()
+
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.conn)
+		})
+	}
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	apmPlugin := NewStatsDPlugin(hclog.NewNullLogger()).(*APMPlugin)
+	defer apmPlugin.Close()
+
+	// Use a long flush interval so the test controls flushing.
+	require.NoError(t, apmPlugin.SetConfig(map[string]string{"address": "127.0.0.1:0", "flush_interval": "1h", "retention": "2h"}))
+
+	conn, err := net.Dial("udp", apmPlugin.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("requests:1|c|#service:web\nrequests:2|c|#service:api\ninvalid\nrequests:3|c|#service:web"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		apmPlugin.store.lock.Lock()
+		defer apmPlugin.store.lock.Unlock()
+		return len(apmPlugin.store.series) == 2 && apmPlugin.store.series[seriesKey("requests", map[string]string{"service": "web"})].value == 4
+	}, time.Second, 10*time.Millisecond)
+
+	now := time.Now()
+	apmPlugin.store.flush(now)
+
+	r := sdk.TimeRange{From: now.Add(-time.Minute), To: now}
+
+	metric, err := apmPlugin.Query("requests{service=web}", r)
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{{Timestamp: now, Value: 4}}, metric)
+
+	_, err = apmPlugin.Query("requests", r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+
+	_, err = apmPlugin.Query("requests{", r)
+	assert.ErrorContains(t, err, "failed to parse query")
+}
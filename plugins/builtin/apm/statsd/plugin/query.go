@@ -0,0 +1,169 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// queryRe matches the check query form:
+//
+//	[<stat>:]<metric_name>[{<tag>=<value>,...}]
+var queryRe = regexp.MustCompile(`^(?:([a-z0-9.]+):)?([^:{}\s|@#]+)(?:\{([^{}]*)\})?$`)
+
+// percentileRe matches percentile stats, such as p95 or p99.9.
+var percentileRe = regexp.MustCompile(`^p(\d+(?:\.\d+)?)$`)
+
+// query is a parsed check query.
+type query struct {
+	stat     string
+	name     string
+	matchers map[string]string
+}
+
+// parseQuery parses the check query, such as `p95:api.latency{env=prod}`.
+//
+// The stat selects the value of each flush interval, and depends on the
+// metric type:
+//
+//   - counters: count (the default) or rate, the count per second
+//   - gauges: value (the default)
+//   - sets: count (the default), the number of unique members
+//   - timers: mean (the default), count, rate, sum, min, max, median or a
+//     percentile such as p95
+//
+// A metric stream is returned for each series which matches the name and
+// optional tags.
+func parseQuery(q string) (*query, error) {
+	match := queryRe.FindStringSubmatch(strings.TrimSpace(q))
+	if match == nil {
+		return nil, fmt.Errorf("invalid query %q", q)
+	}
+
+	parsed := &query{
+		stat:     match[1],
+		name:     match[2],
+		matchers: make(map[string]string),
+	}
+
+	if strings.TrimSpace(match[3]) == "" {
+		return parsed, nil
+	}
+
+	for _, m := range strings.Split(match[3], ",") {
+		k, v, ok := strings.Cut(m, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid tag matcher %q", strings.TrimSpace(m))
+		}
+		parsed.matchers[k] = strings.TrimSpace(v)
+	}
+
+	return parsed, nil
+}
+
+// query returns the metric streams of the series matching the query within
+// the time range.
+func (s *store) query(q *query, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var results []sdk.TimestampedMetrics
+
+	for _, ser := range s.matching(q.name, q.matchers) {
+		var result sdk.TimestampedMetrics
+
+		for _, p := range ser.points {
+			if p.timestamp.Before(r.From) || p.timestamp.After(r.To) {
+				continue
+			}
+
+			v, ok, err := p.stat(ser.kind, q.stat, s.interval.Seconds())
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			result = append(result, sdk.TimestampedMetric{Timestamp: p.timestamp, Value: v})
+		}
+
+		if len(result) > 0 {
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// stat returns the value of the stat for the point. False is returned if the
+// stat has no value, such as the mean of a timer without any samples.
+func (p *point) stat(kind metricKind, stat string, intervalSeconds float64) (float64, bool, error) {
+	switch kind {
+	case kindCounter:
+		switch stat {
+		case "", "count":
+			return p.value, true, nil
+		case "rate":
+			return p.value / intervalSeconds, true, nil
+		}
+	case kindGauge:
+		if stat == "" || stat == "value" {
+			return p.value, true, nil
+		}
+	case kindSet:
+		if stat == "" || stat == "count" {
+			return p.value, true, nil
+		}
+	case kindTimer:
+		switch stat {
+		case "count":
+			return p.count, true, nil
+		case "rate":
+			return p.count / intervalSeconds, true, nil
+		case "sum":
+			return p.sum, true, nil
+		case "", "mean", "min", "max", "median":
+			if len(p.samples) == 0 {
+				return 0, false, nil
+			}
+			switch stat {
+			case "min":
+				return p.samples[0], true, nil
+			case "max":
+				return p.samples[len(p.samples)-1], true, nil
+			case "median":
+				return percentile(p.samples, 50), true, nil
+			}
+			return p.mean, true, nil
+		}
+
+		if m := percentileRe.FindStringSubmatch(stat); m != nil {
+			pct, _ := strconv.ParseFloat(m[1], 64)
+			if pct > 0 && pct <= 100 {
+				if len(p.samples) == 0 {
+					return 0, false, nil
+				}
+				return percentile(p.samples, pct), true, nil
+			}
+		}
+	}
+
+	return 0, false, fmt.Errorf("stat %q is not supported for %s metrics", stat, kind)
+}
+
+// percentile returns the nearest rank percentile of the sorted values.
+func percentile(sorted []float64, pct float64) float64 {
+	i := int(math.Ceil(pct/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,145 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseQuery(t *testing.T) {
+	q, err := parseQuery("p99.9:api.latency{env=prod, service = web}")
+	require.NoError(t, err)
+	assert.Equal(t, &query{
+		stat:     "p99.9",
+		name:     "api.latency",
+		matchers: map[string]string{"env": "prod", "service": "web"},
+	}, q)
+
+	q, err = parseQuery("requests")
+	require.NoError(t, err)
+	assert.Equal(t, &query{name: "requests", matchers: map[string]string{}}, q)
+
+	_, err = parseQuery("requests{service}")
+	assert.EqualError(t, err, `invalid tag matcher "service"`)
+
+	_, err = parseQuery("requests:1|c")
+	assert.EqualError(t, err, `invalid query "requests:1|c"`)
+}
+
+func Test_store_query(t *testing.T) {
+	s := newStore(10*time.Second, time.Minute)
+	start := time.Unix(1704067200, 0)
+	r := sdk.TimeRange{From: start, To: start.Add(time.Minute)}
+
+	now := start
+	add := func(line string) {
+		samples, err := parseLine(line)
+		require.NoError(t, err)
+		for _, smp := range samples {
+			s.add(smp, now)
+		}
+	}
+
+	add("requests:5|c|#service:web")
+	add("requests:1|c|@0.1|#service:web")
+	add("requests:3|c|#service:api")
+	add("queue.depth:10|g")
+	add("queue.depth:+5|g")
+	add("latency:10:20:30:40|ms")
+	add("users:alice|s")
+	add("users:bob|s")
+	add("users:alice|s")
+	s.flush(start.Add(10 * time.Second))
+
+	// Counters and timers reset each interval, while gauges keep their
+	// value.
+	now = start.Add(15 * time.Second)
+	add("queue.depth:-3|g")
+	s.flush(start.Add(20 * time.Second))
+
+	testCases := []struct {
+		query         string
+		expected      []sdk.TimestampedMetrics
+		expectedError string
+	}{
+		{
+			query: "requests{service=web}",
+			expected: []sdk.TimestampedMetrics{{
+				{Timestamp: start.Add(10 * time.Second), Value: 15},
+				{Timestamp: start.Add(20 * time.Second), Value: 0},
+			}},
+		},
+		{
+			query: "rate:requests{service=api}",
+			expected: []sdk.TimestampedMetrics{{
+				{Timestamp: start.Add(10 * time.Second), Value: 0.3},
+				{Timestamp: start.Add(20 * time.Second), Value: 0},
+			}},
+		},
+		{
+			query: "queue.depth",
+			expected: []sdk.TimestampedMetrics{{
+				{Timestamp: start.Add(10 * time.Second), Value: 15},
+				{Timestamp: start.Add(20 * time.Second), Value: 12},
+			}},
+		},
+		{
+			// Timer intervals without samples have no mean.
+			query:    "latency",
+			expected: []sdk.TimestampedMetrics{{{Timestamp: start.Add(10 * time.Second), Value: 25}}},
+		},
+		{
+			query:    "p75:latency",
+			expected: []sdk.TimestampedMetrics{{{Timestamp: start.Add(10 * time.Second), Value: 30}}},
+		},
+		{
+			query:    "max:latency",
+			expected: []sdk.TimestampedMetrics{{{Timestamp: start.Add(10 * time.Second), Value: 40}}},
+		},
+		{
+			query: "count:latency",
+			expected: []sdk.TimestampedMetrics{{
+				{Timestamp: start.Add(10 * time.Second), Value: 4},
+				{Timestamp: start.Add(20 * time.Second), Value: 0},
+			}},
+		},
+		{
+			query: "users",
+			expected: []sdk.TimestampedMetrics{{
+				{Timestamp: start.Add(10 * time.Second), Value: 2},
+				{Timestamp: start.Add(20 * time.Second), Value: 0},
+			}},
+		},
+		{
+			query:         "p95:requests",
+			expectedError: `stat "p95" is not supported for counter metrics`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := parseQuery(tc.query)
+			require.NoError(t, err)
+
+			metrics, err := s.query(q, r)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, metrics)
+		})
+	}
+
+	// Series which are not updated within the retention window are removed.
+	s.flush(start.Add(50 * time.Second))
+	assert.Len(t, s.series, 5)
+	s.flush(start.Add(70 * time.Second))
+	assert.Len(t, s.series, 1)
+}
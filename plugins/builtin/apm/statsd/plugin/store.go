@@ -0,0 +1,204 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTimerSamples is the maximum number of timer values kept for each flush
+// interval. Once reached, values are reservoir sampled, while the count and
+// sum remain exact.
+const maxTimerSamples = 4096
+
+// store aggregates the received samples into a point per flush interval for
+// each series, keeping the points within the retention window.
+type store struct {
+	lock      sync.Mutex
+	interval  time.Duration
+	retention time.Duration
+	series    map[string]*series
+}
+
+// series is a single metric, identified by its name and tags.
+type series struct {
+	name       string
+	kind       metricKind
+	tags       map[string]string
+	lastUpdate time.Time
+
+	// The values of the current flush interval. Gauges keep their value
+	// across intervals.
+	value   float64
+	count   float64
+	sum     float64
+	seen    int
+	samples []float64
+	set     map[string]struct{}
+
+	points []point
+}
+
+// point is an aggregated flush interval of a series. Value is the counter
+// total, gauge value or number of unique set members, while timers use the
+// remaining fields. The timer count is scaled by the sample rate, while the
+// mean is of the received values.
+type point struct {
+	timestamp time.Time
+	value     float64
+	count     float64
+	sum       float64
+	mean      float64
+	samples   []float64
+}
+
+func newStore(interval, retention time.Duration) *store {
+	return &store{
+		interval:  interval,
+		retention: retention,
+		series:    make(map[string]*series),
+	}
+}
+
+// setConfig updates the flush interval and retention window.
+func (s *store) setConfig(interval, retention time.Duration) {
+	s.lock.Lock()
+	s.interval = interval
+	s.retention = retention
+	s.lock.Unlock()
+}
+
+// add records the sample within the current flush interval of its series.
+func (s *store) add(smp sample, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := seriesKey(smp.name, smp.tags)
+	ser, ok := s.series[key]
+
+	// A metric which changes type is treated as a new series.
+	if !ok || ser.kind != smp.kind {
+		ser = &series{name: smp.name, kind: smp.kind, tags: smp.tags}
+		s.series[key] = ser
+	}
+	ser.lastUpdate = now
+
+	switch smp.kind {
+	case kindCounter:
+		ser.value += smp.value / smp.rate
+	case kindGauge:
+		if smp.relative {
+			ser.value += smp.value
+		} else {
+			ser.value = smp.value
+		}
+	case kindTimer:
+		ser.count += 1 / smp.rate
+		ser.sum += smp.value
+		ser.seen++
+		if len(ser.samples) < maxTimerSamples {
+			ser.samples = append(ser.samples, smp.value)
+		} else if i := rand.Intn(ser.seen); i < maxTimerSamples {
+			ser.samples[i] = smp.value
+		}
+	case kindSet:
+		if ser.set == nil {
+			ser.set = make(map[string]struct{})
+		}
+		ser.set[smp.setValue] = struct{}{}
+	}
+}
+
+// flush closes the current flush interval of each series, recording its
+// point with the passed timestamp. Points outside the retention window are
+// dropped, along with series which have not been updated within it.
+func (s *store) flush(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cutoff := now.Add(-s.retention)
+
+	for key, ser := range s.series {
+		if ser.lastUpdate.Before(cutoff) {
+			delete(s.series, key)
+			continue
+		}
+
+		p := point{timestamp: now}
+
+		switch ser.kind {
+		case kindCounter:
+			p.value = ser.value
+			ser.value = 0
+		case kindGauge:
+			p.value = ser.value
+		case kindTimer:
+			sort.Float64s(ser.samples)
+			p.count, p.sum, p.samples = ser.count, ser.sum, ser.samples
+			if ser.seen > 0 {
+				p.mean = ser.sum / float64(ser.seen)
+			}
+			ser.count, ser.sum, ser.seen, ser.samples = 0, 0, 0, nil
+		case kindSet:
+			p.value = float64(len(ser.set))
+			ser.set = nil
+		}
+
+		i := 0
+		for i < len(ser.points) && ser.points[i].timestamp.Before(cutoff) {
+			i++
+		}
+		ser.points = append(ser.points[i:], p)
+	}
+}
+
+// matching returns the series with the passed name and matching tags, in a
+// stable order.
+func (s *store) matching(name string, matchers map[string]string) []*series {
+	var keys []string
+	for key, ser := range s.series {
+		if ser.name == name && ser.matches(matchers) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	results := make([]*series, len(keys))
+	for i, key := range keys {
+		results[i] = s.series[key]
+	}
+	return results
+}
+
+func (s *series) matches(matchers map[string]string) bool {
+	for k, v := range matchers {
+		if tv, ok := s.tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// seriesKey returns the unique key of a series.
+func seriesKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteString("\x00")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(tags[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,241 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the name of the plugin
+	pluginName = "wavefront"
+
+	// configKeyAddress is the address of the Wavefront cluster, such as
+	// "https://example.wavefront.com".
+	configKeyAddress = "address"
+
+	// configKeyAPIToken is the API token used to authenticate queries.
+	configKeyAPIToken = "api_token"
+
+	// configKeyGranularity is the resolution of the returned timeseries. It
+	// can be one of the Wavefront granularities s, m, h or d, or auto to
+	// select it based on the query window.
+	configKeyGranularity          = "granularity"
+	configValueGranularityAuto    = "auto"
+	configValueGranularityDefault = configValueGranularityAuto
+
+	// configKeySummarization is the function used to summarize points
+	// within each granularity bucket, such as MEAN or MAX.
+	configKeySummarization = "summarization"
+
+	// configKeyTimeout is the maximum duration of a query.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 10 * time.Second
+)
+
+var (
+	PluginID = plugins.PluginID{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewWavefrontPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeAPM,
+	}
+)
+
+// APMPlugin is the Wavefront implementation of the apm.APM interface, which
+// runs WQL queries using the chart API.
+type APMPlugin struct {
+	client        *http.Client
+	logger        hclog.Logger
+	address       string
+	apiToken      string
+	granularity   string
+	summarization string
+	timeout       time.Duration
+}
+
+func NewWavefrontPlugin(log hclog.Logger) apm.APM {
+	return &APMPlugin{
+		logger: log,
+	}
+}
+
+func (a *APMPlugin) SetConfig(config map[string]string) error {
+	addr := config[configKeyAddress]
+	if addr == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAddress)
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+	}
+	if !u.IsAbs() {
+		return fmt.Errorf("%q must be an absolute URL", configKeyAddress)
+	}
+	a.address = strings.TrimSuffix(addr, "/")
+
+	a.apiToken = config[configKeyAPIToken]
+	if a.apiToken == "" {
+		return fmt.Errorf("%q config value cannot be empty", configKeyAPIToken)
+	}
+
+	a.granularity = configValueGranularityDefault
+	if v := config[configKeyGranularity]; v != "" {
+		switch v {
+		case configValueGranularityAuto, "s", "m", "h", "d":
+		default:
+			return fmt.Errorf("invalid %q %q, allowed values are auto, s, m, h or d", configKeyGranularity, v)
+		}
+		a.granularity = v
+	}
+
+	a.summarization = strings.ToUpper(config[configKeySummarization])
+
+	a.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		a.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	a.client = &http.Client{Transport: transport}
+
+	return nil
+}
+
+func (a *APMPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+func (a *APMPlugin) Query(q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	m, err := a.QueryMultiple(q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(m) {
+	case 0:
+		return sdk.TimestampedMetrics{}, nil
+	case 1:
+		return m[0], nil
+	default:
+		return nil, fmt.Errorf("query returned %d metric streams, only 1 is expected", len(m))
+	}
+}
+
+// QueryMultiple runs the WQL query, returning a metric stream for each
+// timeseries in the response.
+func (a *APMPlugin) QueryMultiple(q string, r sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	a.logger.Debug("querying Wavefront", "query", q, "range", r)
+
+	params := url.Values{
+		"q":      {q},
+		"s":      {strconv.FormatInt(r.From.UnixMilli(), 10)},
+		"e":      {strconv.FormatInt(r.To.UnixMilli(), 10)},
+		"g":      {a.queryGranularity(r)},
+		"strict": {"true"},
+	}
+	if a.summarization != "" {
+		params.Set("summarization", a.summarization)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.address+"/api/v2/chart/api?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.apiToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying metrics from wavefront: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("error querying metrics from wavefront: unexpected response code %d: %s",
+			resp.StatusCode, errorMessage(b))
+	}
+
+	var result chartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode wavefront response: %v", err)
+	}
+	if result.Warnings != "" {
+		a.logger.Warn("wavefront query returned warnings", "query", q, "warnings", result.Warnings)
+	}
+
+	results := result.metrics()
+	if len(results) == 0 {
+		a.logger.Warn("empty time series response from wavefront, try a wider query window")
+	}
+	return results, nil
+}
+
+// queryGranularity returns the granularity used to query the time range.
+// The automatic granularity uses the finest resolution which keeps the
+// number of points per timeseries manageable.
+func (a *APMPlugin) queryGranularity(r sdk.TimeRange) string {
+	if a.granularity != configValueGranularityAuto {
+		return a.granularity
+	}
+
+	switch window := r.To.Sub(r.From); {
+	case window <= 15*time.Minute:
+		return "s"
+	case window <= 24*time.Hour:
+		return "m"
+	case window <= 30*24*time.Hour:
+		return "h"
+	default:
+		return "d"
+	}
+}
+
+// errorMessage returns the message of an API error response, falling back to
+// the raw body if it can't be decoded.
+func errorMessage(b []byte) string {
+	var resp struct {
+		ErrorMessage string `json:"errorMessage"`
+	}
+	if err := json.Unmarshal(b, &resp); err == nil && resp.ErrorMessage != "" {
+		return resp.ErrorMessage
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,177 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "missing address",
+			config:        map[string]string{"api_token": "token"},
+			expectedError: `"address" config value cannot be empty`,
+		},
+		{
+			name:          "relative address",
+			config:        map[string]string{"address": "example.wavefront.com", "api_token": "token"},
+			expectedError: `"address" must be an absolute URL`,
+		},
+		{
+			name:          "missing api token",
+			config:        map[string]string{"address": "https://example.wavefront.com"},
+			expectedError: `"api_token" config value cannot be empty`,
+		},
+		{
+			name:          "invalid granularity",
+			config:        map[string]string{"address": "https://example.wavefront.com", "api_token": "token", "granularity": "w"},
+			expectedError: `invalid "granularity" "w", allowed values are auto, s, m, h or d`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"address": "https://example.wavefront.com", "api_token": "token", "skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name: "valid",
+			config: map[string]string{
+				"address":       "https://example.wavefront.com/",
+				"api_token":     "token",
+				"granularity":   "m",
+				"summarization": "max",
+				"timeout":       "30s",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apmPlugin := APMPlugin{logger: hclog.NewNullLogger()}
+			err := apmPlugin.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, apmPlugin.client)
+			assert.Equal(t, "https://example.wavefront.com", apmPlugin.address)
+		})
+	}
+}
+
+func TestAPMPlugin_queryGranularity(t *testing.T) {
+	now := time.Now()
+	window := func(d time.Duration) sdk.TimeRange { return sdk.TimeRange{From: now.Add(-d), To: now} }
+
+	a := &APMPlugin{granularity: configValueGranularityAuto}
+	assert.Equal(t, "s", a.queryGranularity(window(time.Minute)))
+	assert.Equal(t, "m", a.queryGranularity(window(time.Hour)))
+	assert.Equal(t, "h", a.queryGranularity(window(7*24*time.Hour)))
+	assert.Equal(t, "d", a.queryGranularity(window(90*24*time.Hour)))
+
+	// A fixed granularity is used regardless of the window.
+	a.granularity = "m"
+	assert.Equal(t, "m", a.queryGranularity(window(time.Minute)))
+}
+
+func TestAPMPlugin_Query(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/chart/api", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		qp := r.URL.Query()
+		assert.Equal(t, "ts(nomad.client.allocs.cpu.total_percent, task_group=web)", qp.Get("q"))
+		assert.Equal(t, "1704067200000", qp.Get("s"))
+		assert.Equal(t, "1704067500000", qp.Get("e"))
+		assert.Equal(t, "s", qp.Get("g"))
+		assert.Equal(t, "true", qp.Get("strict"))
+		assert.Equal(t, "MAX", qp.Get("summarization"))
+
+		http.ServeFile(w, r, path.Join("./test-fixtures", "chart_200.json"))
+	}))
+	defer srv.Close()
+
+	plugin := NewWavefrontPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{
+		"address":       srv.URL,
+		"api_token":     "secret",
+		"summarization": "max",
+	}))
+
+	metrics, err := plugin.Query("ts(nomad.client.allocs.cpu.total_percent, task_group=web)", sdk.TimeRange{
+		From: time.Unix(1704067200, 0),
+		To:   time.Unix(1704067500, 0),
+	})
+	require.NoError(t, err)
+
+	// Null values should be skipped and metrics sorted by timestamp.
+	assert.Equal(t, sdk.TimestampedMetrics{
+		{Timestamp: time.Unix(1704067260, 0), Value: 64.5},
+		{Timestamp: time.Unix(1704067320, 0), Value: 71.25},
+	}, metrics)
+}
+
+func TestAPMPlugin_QueryMultiple(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"timeseries": [
+  {"host": "a", "data": [[1704067260, 1]]},
+  {"host": "b", "data": []},
+  {"host": "c", "data": [[1704067260.5, 3]]}
+]}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewWavefrontPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "api_token": "secret"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	// Timeseries without any points should be skipped.
+	metrics, err := plugin.QueryMultiple("ts(cpu.usage)", r)
+	require.NoError(t, err)
+	assert.Equal(t, []sdk.TimestampedMetrics{
+		{{Timestamp: time.Unix(1704067260, 0), Value: 1}},
+		{{Timestamp: time.Unix(1704067260, 5e8), Value: 3}},
+	}, metrics)
+
+	_, err = plugin.Query("ts(cpu.usage)", r)
+	assert.EqualError(t, err, "query returned 2 metric streams, only 1 is expected")
+}
+
+func TestAPMPlugin_Query_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		if r.URL.Query().Get("q") == "raw" {
+			_, _ = w.Write([]byte("bad request\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errorType": "parse", "errorMessage": "Syntax error at 1:3"}`))
+	}))
+	defer srv.Close()
+
+	plugin := NewWavefrontPlugin(hclog.NewNullLogger())
+	require.NoError(t, plugin.SetConfig(map[string]string{"address": srv.URL, "api_token": "secret"}))
+
+	r := sdk.TimeRange{From: time.Now().Add(-time.Minute), To: time.Now()}
+
+	_, err := plugin.Query("ts(", r)
+	assert.EqualError(t, err, "error querying metrics from wavefront: unexpected response code 400: Syntax error at 1:3")
+
+	_, err = plugin.Query("raw", r)
+	assert.EqualError(t, err, "error querying metrics from wavefront: unexpected response code 400: bad request")
+}
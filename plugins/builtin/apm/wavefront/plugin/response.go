@@ -0,0 +1,57 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// chartResponse is the body of a chart API response.
+type chartResponse struct {
+	Name       string       `json:"name"`
+	Query      string       `json:"query"`
+	Warnings   string       `json:"warnings"`
+	Timeseries []timeseries `json:"timeseries"`
+}
+
+// timeseries is a single timeseries of a chart API response. Its data is
+// encoded densely as [<unix seconds>, <value>] pairs.
+type timeseries struct {
+	Label string            `json:"label"`
+	Host  string            `json:"host"`
+	Tags  map[string]string `json:"tags"`
+	Data  [][]*float64      `json:"data"`
+}
+
+// metrics converts the response into metrics, one stream per timeseries.
+// Incomplete points and timeseries without any points are skipped.
+func (resp *chartResponse) metrics() []sdk.TimestampedMetrics {
+	var results []sdk.TimestampedMetrics
+
+	for _, ts := range resp.Timeseries {
+		var result sdk.TimestampedMetrics
+		for _, p := range ts.Data {
+			if len(p) != 2 || p[0] == nil || p[1] == nil {
+				continue
+			}
+
+			sec, frac := math.Modf(*p[0])
+			result = append(result, sdk.TimestampedMetric{
+				Timestamp: time.Unix(int64(sec), int64(frac*1e9)),
+				Value:     *p[1],
+			})
+		}
+
+		if len(result) > 0 {
+			sort.Stable(result)
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,6 +16,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials/ec2rolecreds"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
 	"github.com/hashicorp/nomad/api"
 )
@@ -78,6 +81,12 @@ func (t *TargetPlugin) setupAWSClients(config map[string]string) error {
 	// Set up our AWS client.
 	t.asg = autoscaling.NewFromConfig(cfg)
 
+	// Set up the receiver of the Spot events if the operator has configured
+	// the queue they are routed to.
+	if queueURL := config[configKeySpotEventsQueueURL]; queueURL != "" {
+		t.spotEvents = newSpotEventsReceiver(t.logger, sqs.NewFromConfig(cfg), queueURL)
+	}
+
 	return nil
 }
 
@@ -90,6 +99,22 @@ func (t *TargetPlugin) scaleOut(ctx context.Context, asg *types.AutoScalingGroup
 	log := t.logger.With("action", "scale_out", "asg_name", *asg.AutoScalingGroupName,
 		"desired_count", count)
 
+	// When the ASG has a warm pool, AWS activates its prepared instances
+	// before launching new ones, which brings the new capacity into service
+	// much faster. Log how the scale out will be fulfilled so operators can
+	// size the warm pool accordingly.
+	if hasWarmPool(asg) {
+		warmPool, err := t.describeWarmPool(ctx, *asg.AutoScalingGroupName)
+		if err != nil {
+			log.Warn("failed to describe warm pool", "error", err)
+		} else {
+			warmed, _ := warmPoolCounts(warmPool)
+			activated := warmPoolActivations(warmed, int64(*asg.DesiredCapacity), count)
+			log.Info("scaling out using warm pool", "warm_pool_activations", activated,
+				"new_launches", count-int64(*asg.DesiredCapacity)-activated)
+		}
+	}
+
 	input := autoscaling.UpdateAutoScalingGroupInput{
 		AutoScalingGroupName: asg.AutoScalingGroupName,
 		AvailabilityZones:    asg.AvailabilityZones,
@@ -116,28 +141,51 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, asg *types.AutoScalingGroup,
 	// would like on all log lines.
 	log := t.logger.With("action", "scale_in", "asg_name", *asg.AutoScalingGroupName)
 
+	protectDraining, err := strconv.ParseBool(
+		getConfigValue(config, configKeyProtectDraining, configValueProtectDrainingDefault))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", configKeyProtectDraining, err)
+	}
+
+	// Ensure the lifecycle hook exists before draining any node, as the
+	// terminated instances would otherwise wait for the hook to time out.
+	hookName := config[configKeyLifecycleHookName]
+	if hookName != "" {
+		if _, err := t.describeLifecycleHook(ctx, *asg.AutoScalingGroupName, hookName); err != nil {
+			return fmt.Errorf("failed to describe lifecycle hook %s: %v", hookName, err)
+		}
+	}
+
 	// Find instance IDs in the target ASG and perform pre-scale tasks.
-	remoteIDs := []string{}
-	for _, inst := range asg.Instances {
-		if *inst.HealthStatus == "Healthy" && inst.LifecycleState == types.LifecycleStateInService {
-			log.Debug("found healthy instance", "instance_id", *inst.InstanceId)
-			remoteIDs = append(remoteIDs, *inst.InstanceId)
-		} else {
-			log.Debug("skipping instance", "instance_id", *inst.InstanceId, "health_status", *inst.HealthStatus, "lifecycle_state", inst.LifecycleState)
+	ids, err := t.scaleInNodes(ctx, log, asg, num, config)
+	if err != nil {
+		return err
+	}
+
+	// The nodes of the selected instances are drained here, so their
+	// lifecycle actions must not be handled as terminations initiated by the
+	// ASG.
+	if hookName != "" {
+		for _, id := range ids {
+			t.lifecycleActions.claim(id.RemoteResourceID)
 		}
+		defer t.lifecycleActions.release(nodeRemoteIDs(ids)...)
 	}
 
-	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, remoteIDs, int(num))
+	// Drain the nodes.
+	if protectDraining {
+		err = t.protectAndDrainNodes(ctx, log, *asg.AutoScalingGroupName, config, ids)
+	} else {
+		err = t.clusterUtils.DrainNodes(ctx, config, ids)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
 	}
+	log.Debug("pre scale-in tasks now complete")
 
 	// Create the event writer and write that the drain event has been
 	// completed.
-	selectedRemoteIDs := []string{}
-	for _, id := range ids {
-		selectedRemoteIDs = append(selectedRemoteIDs, id.RemoteResourceID)
-	}
+	selectedRemoteIDs := nodeRemoteIDs(ids)
 	eWriter := newEventWriter(t.logger, t.asg, selectedRemoteIDs, *asg.AutoScalingGroupName)
 	eWriter.write(ctx, scalingEventDrain)
 
@@ -151,6 +199,9 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, asg *types.AutoScalingGroup,
 	// If we have any failures, perform our revert so we don't leave nodes in
 	// an undesired state.
 	if result.lenFailure() > 0 {
+		if protectDraining {
+			t.removeInstanceProtection(ctx, log, *asg.AutoScalingGroupName, nodeRemoteIDs(result.failedIDs()))
+		}
 		failedTaskErr = t.clusterUtils.RunPostScaleInTasksOnFailure(result.failedIDs())
 	}
 
@@ -160,6 +211,12 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, asg *types.AutoScalingGroup,
 	// slowness in the AWS system and us timing out.
 	if result.lenSuccess() > 0 {
 
+		// The terminated instances wait on the lifecycle hook until their
+		// action is completed, which would otherwise delay the activities.
+		if hookName != "" {
+			t.continueLifecycleActions(ctx, log, *asg.AutoScalingGroupName, hookName, result.successfulIDs())
+		}
+
 		t.logger.Debug("ensuring AWS ASG activities complete")
 
 		if err := t.ensureActivitiesComplete(ctx, *asg.AutoScalingGroupName, result.activityIDs()); err != nil {
@@ -190,6 +247,32 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, asg *types.AutoScalingGroup,
 	return result.errorOrNil()
 }
 
+// scaleInNodes selects the nodes of the ASG instances to drain and terminate
+// when removing num from the ASG capacity.
+func (t *TargetPlugin) scaleInNodes(ctx context.Context, log hclog.Logger, asg *types.AutoScalingGroup,
+	num int64, config map[string]string) ([]scaleutils.NodeResourceID, error) {
+
+	remoteIDs := scaleInCandidates(log, asg.Instances)
+
+	ids, err := t.selectScaleInNodes(ctx, log, config, remoteIDs, int(num))
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	// The number to remove is in capacity units for weighted ASGs. Each
+	// instance provides at least a unit, so enough nodes have been selected,
+	// but only those fitting within the units can be terminated.
+	if isWeighted(asg) {
+		ids = fitCapacity(ids, asg.Instances, num)
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no selected instances fit within %d capacity units", num)
+		}
+		log.Debug("selected instances within capacity units", "capacity_units", num, "instances", len(ids))
+	}
+
+	return ids, nil
+}
+
 // terminateInstancesInASG handles terminating all instances passed and returns
 // an object detailing the complete status of the performed action.
 func (t *TargetPlugin) terminateInstancesInASG(ctx context.Context, ids []scaleutils.NodeResourceID) instanceTerminationResult {
@@ -313,7 +396,7 @@ func (t *TargetPlugin) ensureASGInstancesCount(ctx context.Context, desired int6
 			return true, err
 		}
 
-		if len(asg.Instances) == int(desired) {
+		if isCapacityFulfilled(asg, desired) {
 			return true, nil
 		}
 		return false, fmt.Errorf("AutoScaling Group at %v instances of desired %v", asg.Instances, desired)
@@ -322,6 +405,27 @@ func (t *TargetPlugin) ensureASGInstancesCount(ctx context.Context, desired int6
 	return retry(ctx, defaultRetryInterval, t.retryAttempts, f)
 }
 
+// instanceExists is used to verify whether a scaled in instance is still part
+// of an ASG. Instances are removed from the ASG once terminated, although the
+// terminated lifecycle state may still be reported briefly.
+func (t *TargetPlugin) instanceExists(ctx context.Context, id string) (bool, error) {
+
+	input := autoscaling.DescribeAutoScalingInstancesInput{InstanceIds: []string{id}}
+
+	resp, err := t.asg.DescribeAutoScalingInstances(ctx, &input)
+	if err != nil {
+		return false, err
+	}
+
+	for _, inst := range resp.AutoScalingInstances {
+		if aws.ToString(inst.InstanceId) == id &&
+			aws.ToString(inst.LifecycleState) != string(types.LifecycleStateTerminated) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // awsNodeIDMap is used to identify the AWS InstanceID of a Nomad node using
 // the relevant attribute value.
 func awsNodeIDMap(n *api.Node) (string, error) {
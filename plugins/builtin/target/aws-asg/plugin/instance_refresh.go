@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
+)
+
+const (
+	// configKeyRefreshScaleOutStep is the maximum capacity added to the ASG by
+	// a single scale out action while an instance refresh is in progress. By
+	// default, the target is reported as not ready during a refresh, so no
+	// scaling takes place until the refresh completes.
+	configKeyRefreshScaleOutStep = "aws_instance_refresh_scale_out_step"
+
+	// metaKeys are the status meta keys used to report the instance refresh
+	// in progress within the ASG.
+	metaKeyInstanceRefreshID                 = "aws_asg.instance_refresh_id"
+	metaKeyInstanceRefreshStatus             = "aws_asg.instance_refresh_status"
+	metaKeyInstanceRefreshPercentageComplete = "aws_asg.instance_refresh_percentage_complete"
+)
+
+// isRefreshActive returns whether the instance refresh is still replacing
+// instances within the ASG.
+func isRefreshActive(refresh types.InstanceRefresh) bool {
+	switch refresh.Status {
+	case types.InstanceRefreshStatusPending,
+		types.InstanceRefreshStatusInProgress,
+		types.InstanceRefreshStatusCancelling:
+		return true
+	default:
+		return false
+	}
+}
+
+// activeInstanceRefresh returns the instance refresh in progress within the
+// ASG, or nil if there is none.
+func (t *TargetPlugin) activeInstanceRefresh(ctx context.Context, asgName string) (*types.InstanceRefresh, error) {
+
+	input := autoscaling.DescribeInstanceRefreshesInput{
+		AutoScalingGroupName: &asgName,
+		MaxRecords:           ptr.Int32ToPtr(1),
+	}
+
+	refreshes, err := t.asg.DescribeInstanceRefreshes(ctx, &input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe AWS InstanceRefresh: %v", err)
+	}
+
+	for _, refresh := range refreshes.InstanceRefreshes {
+		if isRefreshActive(refresh) {
+			return &refresh, nil
+		}
+	}
+	return nil, nil
+}
+
+// refreshScaleOutStep returns the configured scale out step used while an
+// instance refresh is in progress. Zero indicates scaling is blocked.
+func refreshScaleOutStep(config map[string]string) (int64, error) {
+	val, ok := config[configKeyRefreshScaleOutStep]
+	if !ok {
+		return 0, nil
+	}
+
+	step, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || step < 0 {
+		return 0, fmt.Errorf("invalid %s value %q, must be a non-negative integer", configKeyRefreshScaleOutStep, val)
+	}
+	return step, nil
+}
+
+// refreshScaleOutCount returns the count to scale out to while an instance
+// refresh is in progress, adding at most step to the current count so the
+// autoscaler does not fight the replacement of instances.
+func refreshScaleOutCount(current, desired, step int64) int64 {
+	if desired > current+step {
+		return current + step
+	}
+	return desired
+}
+
+// processInstanceRefresh updates the status object with the details of the
+// instance refresh in progress. The target is not ready unless scale outs are
+// allowed during the refresh, in which case Scale blocks scale ins instead.
+func processInstanceRefresh(refresh *types.InstanceRefresh, step int64, status *sdk.TargetStatus) {
+	if refresh == nil {
+		return
+	}
+
+	status.Meta[metaKeyInstanceRefreshID] = aws.ToString(refresh.InstanceRefreshId)
+	status.Meta[metaKeyInstanceRefreshStatus] = string(refresh.Status)
+	status.Meta[metaKeyInstanceRefreshPercentageComplete] = strconv.FormatInt(int64(aws.ToInt32(refresh.PercentageComplete)), 10)
+
+	if step == 0 {
+		status.Ready = false
+	}
+}
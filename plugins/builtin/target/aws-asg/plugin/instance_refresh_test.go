@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isRefreshActive(t *testing.T) {
+	assert.True(t, isRefreshActive(types.InstanceRefresh{Status: types.InstanceRefreshStatusPending}))
+	assert.True(t, isRefreshActive(types.InstanceRefresh{Status: types.InstanceRefreshStatusInProgress}))
+	assert.True(t, isRefreshActive(types.InstanceRefresh{Status: types.InstanceRefreshStatusCancelling}))
+	assert.False(t, isRefreshActive(types.InstanceRefresh{Status: types.InstanceRefreshStatusSuccessful}))
+	assert.False(t, isRefreshActive(types.InstanceRefresh{Status: types.InstanceRefreshStatusFailed}))
+	assert.False(t, isRefreshActive(types.InstanceRefresh{Status: types.InstanceRefreshStatusCancelled}))
+}
+
+func Test_refreshScaleOutStep(t *testing.T) {
+	testCases := []struct {
+		inputConfig    map[string]string
+		expectedOutput int64
+		expectedError  error
+		name           string
+	}{
+		{
+			inputConfig:    map[string]string{},
+			expectedOutput: 0,
+			name:           "not configured",
+		},
+		{
+			inputConfig:    map[string]string{configKeyRefreshScaleOutStep: "2"},
+			expectedOutput: 2,
+			name:           "configured",
+		},
+		{
+			inputConfig:   map[string]string{configKeyRefreshScaleOutStep: "-1"},
+			expectedError: errors.New(`invalid aws_instance_refresh_scale_out_step value "-1", must be a non-negative integer`),
+			name:          "negative",
+		},
+		{
+			inputConfig:   map[string]string{configKeyRefreshScaleOutStep: "two"},
+			expectedError: errors.New(`invalid aws_instance_refresh_scale_out_step value "two", must be a non-negative integer`),
+			name:          "invalid",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualError := refreshScaleOutStep(tc.inputConfig)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedError, actualError, tc.name)
+		})
+	}
+}
+
+func Test_refreshScaleOutCount(t *testing.T) {
+	assert.Equal(t, int64(5), refreshScaleOutCount(3, 8, 2))
+	assert.Equal(t, int64(4), refreshScaleOutCount(3, 4, 2))
+	assert.Equal(t, int64(5), refreshScaleOutCount(3, 5, 2))
+}
+
+func Test_processInstanceRefresh(t *testing.T) {
+	refresh := &types.InstanceRefresh{
+		InstanceRefreshId:  aws.String("refresh-1"),
+		Status:             types.InstanceRefreshStatusInProgress,
+		PercentageComplete: aws.Int32(40),
+	}
+	expectedMeta := map[string]string{
+		metaKeyInstanceRefreshID:                 "refresh-1",
+		metaKeyInstanceRefreshStatus:             "InProgress",
+		metaKeyInstanceRefreshPercentageComplete: "40",
+	}
+
+	status := sdk.TargetStatus{Ready: true, Meta: make(map[string]string)}
+	processInstanceRefresh(nil, 0, &status)
+	assert.True(t, status.Ready)
+	assert.Empty(t, status.Meta)
+
+	processInstanceRefresh(refresh, 0, &status)
+	assert.False(t, status.Ready, "scaling is blocked without a scale out step")
+	assert.Equal(t, expectedMeta, status.Meta)
+
+	status = sdk.TargetStatus{Ready: true, Meta: make(map[string]string)}
+	processInstanceRefresh(refresh, 2, &status)
+	assert.True(t, status.Ready, "paced scale outs are allowed with a scale out step")
+	assert.Equal(t, expectedMeta, status.Meta)
+}
@@ -0,0 +1,249 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+const (
+	// lifecycleActionResults are the results used to complete the lifecycle
+	// action of a terminating instance. CONTINUE is used once the Nomad node
+	// has been drained, while ABANDON is used if the drain failed. Both let
+	// the termination proceed, but ABANDON skips any remaining hooks.
+	lifecycleActionResultContinue = "CONTINUE"
+	lifecycleActionResultAbandon  = "ABANDON"
+
+	// lifecycleTransitionTerminating is the transition of the lifecycle hooks
+	// supported by the plugin.
+	lifecycleTransitionTerminating = "autoscaling:EC2_INSTANCE_TERMINATING"
+
+	// lifecycleMinHeartbeatInterval is the minimum interval at which the
+	// heartbeat of a lifecycle action is recorded while draining.
+	lifecycleMinHeartbeatInterval = 10 * time.Second
+)
+
+// lifecycleActions tracks the instances whose termination lifecycle action is
+// being handled by the plugin, so each is only handled once. Instances
+// terminated by the plugin itself are tracked too, as their node is drained
+// before the termination is requested.
+type lifecycleActions struct {
+	lock      sync.Mutex
+	instances map[string]struct{}
+}
+
+// claim marks the instance as being handled, returning false if it already
+// was.
+func (l *lifecycleActions) claim(id string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.instances == nil {
+		l.instances = make(map[string]struct{})
+	}
+	if _, ok := l.instances[id]; ok {
+		return false
+	}
+	l.instances[id] = struct{}{}
+	return true
+}
+
+// release marks the instances as no longer being handled.
+func (l *lifecycleActions) release(ids ...string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, id := range ids {
+		delete(l.instances, id)
+	}
+}
+
+// terminatingWaitInstances returns the IDs of the instances waiting on a
+// termination lifecycle hook.
+func terminatingWaitInstances(instances []types.Instance) []string {
+	var ids []string
+	for _, inst := range instances {
+		if inst.LifecycleState == types.LifecycleStateTerminatingWait {
+			ids = append(ids, aws.ToString(inst.InstanceId))
+		}
+	}
+	return ids
+}
+
+// heartbeatInterval returns the interval at which the heartbeat of the
+// lifecycle hook is recorded, leaving room for a missed heartbeat before the
+// hook times out.
+func heartbeatInterval(hook *types.LifecycleHook) time.Duration {
+	interval := time.Duration(aws.ToInt32(hook.HeartbeatTimeout)) * time.Second / 2
+	if interval < lifecycleMinHeartbeatInterval {
+		return lifecycleMinHeartbeatInterval
+	}
+	return interval
+}
+
+// handleTerminatingInstances drains the Nomad nodes of the instances the ASG
+// is terminating, such as when replacing unhealthy instances or rebalancing
+// availability zones, before completing their lifecycle action. Each instance
+// is handled in the background, so the caller does not wait on the drains.
+func (t *TargetPlugin) handleTerminatingInstances(ctx context.Context, asg *types.AutoScalingGroup, hookName string, config map[string]string) {
+
+	ids := terminatingWaitInstances(asg.Instances)
+	if len(ids) == 0 {
+		return
+	}
+
+	log := t.logger.With("action", "lifecycle_hook", "asg_name", aws.ToString(asg.AutoScalingGroupName),
+		"lifecycle_hook_name", hookName)
+
+	hook, err := t.describeLifecycleHook(ctx, aws.ToString(asg.AutoScalingGroupName), hookName)
+	if err != nil {
+		log.Error("failed to describe lifecycle hook", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		if !t.lifecycleActions.claim(id) {
+			continue
+		}
+		log.Info("draining Nomad node of terminating instance", "instance_id", id)
+
+		go t.handleTerminatingInstance(ctx, log, hook, id, config)
+	}
+}
+
+// handleTerminatingInstance drains the Nomad node of the instance, recording
+// heartbeats of the lifecycle action until the drain completes, and then
+// completes the action.
+func (t *TargetPlugin) handleTerminatingInstance(ctx context.Context, log hclog.Logger,
+	hook *types.LifecycleHook, id string, config map[string]string) {
+
+	defer t.lifecycleActions.release(id)
+
+	log = log.With("instance_id", id)
+	asgName, hookName := aws.ToString(hook.AutoScalingGroupName), aws.ToString(hook.LifecycleHookName)
+
+	result := lifecycleActionResultContinue
+
+	if err := t.drainTerminatingInstance(ctx, log, hook, id, config); err != nil {
+		log.Error("failed to drain Nomad node of terminating instance", "error", err)
+		result = lifecycleActionResultAbandon
+	}
+
+	if err := t.completeLifecycleAction(ctx, asgName, hookName, id, result); err != nil {
+		log.Error("failed to complete lifecycle action", "result", result, "error", err)
+		return
+	}
+	log.Info("successfully completed lifecycle action", "result", result)
+}
+
+// drainTerminatingInstance drains the Nomad node of the instance while
+// recording heartbeats of its lifecycle action.
+func (t *TargetPlugin) drainTerminatingInstance(ctx context.Context, log hclog.Logger,
+	hook *types.LifecycleHook, id string, config map[string]string) error {
+
+	ids, err := t.clusterUtils.IdentifyNodesByRemoteIDs([]string{id})
+	if err != nil {
+		return fmt.Errorf("failed to identify Nomad node: %v", err)
+	}
+	if len(ids) == 0 {
+		log.Debug("no Nomad node found for terminating instance")
+		return nil
+	}
+
+	hbCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go t.recordHeartbeats(hbCtx, log, hook, id)
+
+	return t.clusterUtils.DrainNodes(ctx, config, ids)
+}
+
+// recordHeartbeats records heartbeats of the lifecycle action of the instance
+// until the context is cancelled, so the hook does not time out while the
+// Nomad node is draining.
+func (t *TargetPlugin) recordHeartbeats(ctx context.Context, log hclog.Logger, hook *types.LifecycleHook, id string) {
+
+	ticker := time.NewTicker(heartbeatInterval(hook))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := t.asg.RecordLifecycleActionHeartbeat(ctx, &autoscaling.RecordLifecycleActionHeartbeatInput{
+			AutoScalingGroupName: hook.AutoScalingGroupName,
+			LifecycleHookName:    hook.LifecycleHookName,
+			InstanceId:           aws.String(id),
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Warn("failed to record lifecycle action heartbeat", "error", err)
+		}
+	}
+}
+
+// continueLifecycleActions completes the lifecycle actions of the instances
+// terminated by the plugin, whose nodes have already been drained, so their
+// termination does not wait for the hook to time out. The instances only
+// enter the Terminating:Wait state shortly after being terminated, so
+// completing the actions is retried.
+func (t *TargetPlugin) continueLifecycleActions(ctx context.Context, log hclog.Logger, asgName, hookName string, ids []scaleutils.NodeResourceID) {
+
+	for _, id := range ids {
+		f := func(ctx context.Context) (bool, error) {
+			err := t.completeLifecycleAction(ctx, asgName, hookName, id.RemoteResourceID, lifecycleActionResultContinue)
+			return err == nil, err
+		}
+
+		if err := retry(ctx, defaultRetryInterval, t.retryAttempts, f); err != nil {
+			log.Error("failed to complete lifecycle action", "instance_id", id.RemoteResourceID, "error", err)
+		}
+	}
+}
+
+// completeLifecycleAction completes the lifecycle action of the instance with
+// the result.
+func (t *TargetPlugin) completeLifecycleAction(ctx context.Context, asgName, hookName, id, result string) error {
+	_, err := t.asg.CompleteLifecycleAction(ctx, &autoscaling.CompleteLifecycleActionInput{
+		AutoScalingGroupName:  aws.String(asgName),
+		LifecycleHookName:     aws.String(hookName),
+		InstanceId:            aws.String(id),
+		LifecycleActionResult: aws.String(result),
+	})
+	return err
+}
+
+// describeLifecycleHook returns the lifecycle hook of the ASG, ensuring it is
+// a termination hook.
+func (t *TargetPlugin) describeLifecycleHook(ctx context.Context, asgName, hookName string) (*types.LifecycleHook, error) {
+
+	resp, err := t.asg.DescribeLifecycleHooks(ctx, &autoscaling.DescribeLifecycleHooksInput{
+		AutoScalingGroupName: aws.String(asgName),
+		LifecycleHookNames:   []string{hookName},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.LifecycleHooks) != 1 {
+		return nil, errors.New("lifecycle hook not found")
+	}
+
+	hook := resp.LifecycleHooks[0]
+	if aws.ToString(hook.LifecycleTransition) != lifecycleTransitionTerminating {
+		return nil, fmt.Errorf("lifecycle hook transition must be %s", lifecycleTransitionTerminating)
+	}
+	return &hook, nil
+}
@@ -0,0 +1,65 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_terminatingWaitInstances(t *testing.T) {
+	instances := []types.Instance{
+		{InstanceId: aws.String("i-1"), LifecycleState: types.LifecycleStateInService},
+		{InstanceId: aws.String("i-2"), LifecycleState: types.LifecycleStateTerminatingWait},
+		{InstanceId: aws.String("i-3"), LifecycleState: types.LifecycleStateTerminatingProceed},
+		{InstanceId: aws.String("i-4"), LifecycleState: types.LifecycleStateTerminatingWait},
+	}
+	assert.Equal(t, []string{"i-2", "i-4"}, terminatingWaitInstances(instances))
+	assert.Nil(t, terminatingWaitInstances(nil))
+}
+
+func Test_heartbeatInterval(t *testing.T) {
+	testCases := []struct {
+		inputHook      *types.LifecycleHook
+		expectedOutput time.Duration
+		name           string
+	}{
+		{
+			inputHook:      &types.LifecycleHook{HeartbeatTimeout: aws.Int32(300)},
+			expectedOutput: 150 * time.Second,
+			name:           "half of heartbeat timeout",
+		},
+		{
+			inputHook:      &types.LifecycleHook{HeartbeatTimeout: aws.Int32(10)},
+			expectedOutput: lifecycleMinHeartbeatInterval,
+			name:           "short heartbeat timeout",
+		},
+		{
+			inputHook:      &types.LifecycleHook{},
+			expectedOutput: lifecycleMinHeartbeatInterval,
+			name:           "no heartbeat timeout",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, heartbeatInterval(tc.inputHook), tc.name)
+		})
+	}
+}
+
+func Test_lifecycleActions(t *testing.T) {
+	var l lifecycleActions
+
+	assert.True(t, l.claim("i-1"))
+	assert.True(t, l.claim("i-2"))
+	assert.False(t, l.claim("i-1"), "instances should only be claimed once")
+
+	l.release("i-1", "i-2")
+	assert.True(t, l.claim("i-1"), "released instances should be claimable")
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
 	hclog "github.com/hashicorp/go-hclog"
@@ -16,7 +17,6 @@ import (
 	"github.com/hashicorp/nomad-autoscaler/plugins/target"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
 	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
-	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
 	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
 )
 
@@ -33,15 +33,23 @@ const (
 	configKeyASGName            = "aws_asg_name"
 	configKeyCredentialProvider = "aws_credential_provider"
 	configKeyRetryAttempts      = "retry_attempts"
+	configKeyProtectDraining    = "aws_protect_draining_instances"
+	configKeySpotEventsQueueURL = "aws_spot_events_queue_url"
+	configKeyLifecycleHookName  = "aws_lifecycle_hook_name"
 
 	// configValues are the default values used when a configuration key is not
 	// supplied by the operator that are specific to the plugin.
-	configValueRegionDefault        = "us-east-1"
-	configValueRetryAttemptsDefault = "15"
+	configValueRegionDefault          = "us-east-1"
+	configValueRetryAttemptsDefault   = "15"
+	configValueProtectDrainingDefault = "false"
 
 	// credentialProvider are the valid options for the aws_credential_provider
 	// configuration key.
 	credentialProviderEC2Role = "ec2_role"
+
+	// maxReportedActivities is the maximum number of recent scaling
+	// activities added to the status response.
+	maxReportedActivities = 10
 )
 
 var (
@@ -55,8 +63,12 @@ var (
 	}
 )
 
-// Assert that TargetPlugin meets the target.Target interface.
-var _ target.Target = (*TargetPlugin)(nil)
+// Assert that TargetPlugin meets the target.Target and target.DryRunner
+// interfaces.
+var (
+	_ target.Target    = (*TargetPlugin)(nil)
+	_ target.DryRunner = (*TargetPlugin)(nil)
+)
 
 // TargetPlugin is the AWS ASG implementation of the target.Target interface.
 type TargetPlugin struct {
@@ -71,6 +83,15 @@ type TargetPlugin struct {
 	// clusterUtils provides general cluster scaling utilities for querying the
 	// state of nodes pools and performing scaling tasks.
 	clusterUtils *scaleutils.ClusterScaleUtils
+
+	// spotEvents tracks the instances flagged for reclamation by EC2 Spot
+	// events, which are preferred when scaling in. It is nil unless the
+	// queue receiving the events is configured.
+	spotEvents *spotEventsReceiver
+
+	// lifecycleActions tracks the instances whose termination lifecycle
+	// action is being handled, when the target has a lifecycle hook.
+	lifecycleActions lifecycleActions
 }
 
 // NewAWSASGPlugin returns the AWS ASG implementation of the target.Target
@@ -95,9 +116,10 @@ func (t *TargetPlugin) SetConfig(config map[string]string) error {
 		return err
 	}
 
-	// Store and set the remote ID callback function.
+	// Store and set the remote ID callback functions.
 	t.clusterUtils = clusterUtils
 	t.clusterUtils.ClusterNodeIDLookupFunc = awsNodeIDMap
+	t.clusterUtils.ClusterRemoteIDExistsFunc = t.instanceExists
 
 	retryLimit, err := strconv.Atoi(getConfigValue(config, configKeyRetryAttempts, configValueRetryAttemptsDefault))
 	if err != nil {
@@ -138,27 +160,31 @@ func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string)
 	}
 
 	// Autoscaling can interfere with a running instance refresh so we
-	// prevent any scaling action while a refresh is Pending or InProgress
-	input := autoscaling.DescribeInstanceRefreshesInput{
-		AutoScalingGroupName: &asgName,
-		MaxRecords:           ptr.Int32ToPtr(1),
-	}
-
-	refreshes, err := t.asg.DescribeInstanceRefreshes(ctx, &input)
+	// prevent scaling in while a refresh is in progress, and only allow
+	// paced scale outs when configured.
+	refresh, err := t.activeInstanceRefresh(ctx, asgName)
 	if err != nil {
-		return fmt.Errorf("failed to describe AWS InstanceRefresh: %v", err)
+		return err
 	}
 
-	for _, refresh := range refreshes.InstanceRefreshes {
-		active := refresh.Status == types.InstanceRefreshStatusInProgress ||
-			refresh.Status == types.InstanceRefreshStatusPending
+	if refresh != nil {
+		step, err := refreshScaleOutStep(config)
+		if err != nil {
+			return err
+		}
+
+		current := int64(*curASG.DesiredCapacity)
+		if action.Count < current || step == 0 {
+			return sdk.NewTargetScalingNoOpError("scaling blocked due to instance refresh %s with status %s",
+				aws.ToString(refresh.InstanceRefreshId), refresh.Status)
+		}
 
-		if active {
-			t.logger.Warn("scaling will not take place due to InstanceRefresh",
+		if count := refreshScaleOutCount(current, action.Count, step); count < action.Count {
+			t.logger.Info("pacing scale out due to instance refresh",
 				"asg_name", asgName,
-				"refresh_id", refresh.InstanceRefreshId,
-				"refresh_status", refresh.Status)
-			return nil
+				"refresh_id", aws.ToString(refresh.InstanceRefreshId),
+				"strategy_count", action.Count, "count", count)
+			action.Count = count
 		}
 	}
 
@@ -186,6 +212,44 @@ func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string)
 	return err
 }
 
+// DryRun satisfies the DryRun function on the target.DryRunner interface.
+// When scaling in, the nodes which would be drained and terminated are
+// selected using the same logic as Scale, but are left untouched.
+func (t *TargetPlugin) DryRun(action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error) {
+
+	asgName, ok := config[configKeyASGName]
+	if !ok {
+		return nil, fmt.Errorf("required config param %s not found", configKeyASGName)
+	}
+	ctx := context.Background()
+
+	curASG, err := t.describeASG(ctx, asgName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe AWS Autoscaling Group: %v", err)
+	}
+
+	result := sdk.TargetDryRunResult{Count: action.Count}
+
+	num, direction := t.calculateDirection(int64(*curASG.DesiredCapacity), action.Count)
+	if direction != "in" {
+		return &result, nil
+	}
+
+	log := t.logger.With("action", "dry_run", "asg_name", asgName)
+
+	ids, err := t.scaleInNodes(ctx, log, curASG, num, config)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		result.Nodes = append(result.Nodes, sdk.TargetDryRunNode{
+			NomadNodeID:      id.NomadNodeID,
+			RemoteResourceID: id.RemoteResourceID,
+		})
+	}
+	return &result, nil
+}
+
 // Status satisfies the Status function on the target.Target interface.
 func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
 
@@ -196,7 +260,12 @@ func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, erro
 	if err != nil {
 		return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
 	}
-	if !ready {
+
+	// When the target has a lifecycle hook, the instances the ASG terminates
+	// need their nodes draining even while the pool is not ready, so the ASG
+	// is always described.
+	hookName := config[configKeyLifecycleHookName]
+	if !ready && hookName == "" {
 		return &sdk.TargetStatus{Ready: ready}, nil
 	}
 
@@ -212,6 +281,13 @@ func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, erro
 		return nil, fmt.Errorf("failed to describe AWS Autoscaling Group: %v", err)
 	}
 
+	if hookName != "" {
+		t.handleTerminatingInstances(ctx, asg, hookName, config)
+	}
+	if !ready {
+		return &sdk.TargetStatus{Ready: ready}, nil
+	}
+
 	events, err := t.describeActivities(ctx, asgName, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to describe AWS Autoscaling Group activities: %v", err)
@@ -229,6 +305,31 @@ func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, erro
 	if len(events) > 0 {
 		processLastActivity(events[0], &resp)
 	}
+	processActivities(events, &resp)
+
+	// Report the warm pool capacity separately from the in-service capacity,
+	// as warm pool instances are not counted within the desired capacity.
+	var warmPool []types.Instance
+	if hasWarmPool(asg) {
+		warmPool, err = t.describeWarmPool(ctx, asgName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe AWS Autoscaling Group warm pool: %v", err)
+		}
+	}
+	processWarmPool(asg, warmPool, &resp)
+	processWeightedCapacity(asg, &resp)
+
+	// Report an instance refresh in progress, so the autoscaler does not fight
+	// the replacement of instances.
+	refresh, err := t.activeInstanceRefresh(ctx, asgName)
+	if err != nil {
+		return nil, err
+	}
+	step, err := refreshScaleOutStep(config)
+	if err != nil {
+		return nil, err
+	}
+	processInstanceRefresh(refresh, step, &resp)
 
 	return &resp, nil
 }
@@ -245,6 +346,43 @@ func (t *TargetPlugin) calculateDirection(asgDesired, strategyDesired int64) (in
 }
 
 // processLastActivity updates the status object based on the details within
+// processActivities adds the most recent scaling activities of the ASG to the
+// status object, so failures to fulfil previous scaling actions are reported.
+// The activities are returned by AWS ordered from newest to oldest.
+func processActivities(activities []types.Activity, status *sdk.TargetStatus) {
+
+	if len(activities) > maxReportedActivities {
+		activities = activities[:maxReportedActivities]
+	}
+
+	for _, activity := range activities {
+		a := sdk.TargetActivity{
+			ID:          aws.ToString(activity.ActivityId),
+			Status:      activityStatus(activity.StatusCode),
+			Description: aws.ToString(activity.Description),
+			Message:     aws.ToString(activity.StatusMessage),
+			StartTime:   aws.ToTime(activity.StartTime),
+			EndTime:     aws.ToTime(activity.EndTime),
+		}
+		status.Activities = append(status.Activities, a)
+	}
+}
+
+// activityStatus converts the status code of an ASG scaling activity to the
+// status of a target activity. All non-terminal codes are in progress.
+func activityStatus(code types.ScalingActivityStatusCode) sdk.TargetActivityStatus {
+	switch code {
+	case types.ScalingActivityStatusCodeSuccessful:
+		return sdk.TargetActivityStatusSuccessful
+	case types.ScalingActivityStatusCodeFailed:
+		return sdk.TargetActivityStatusFailed
+	case types.ScalingActivityStatusCodeCancelled:
+		return sdk.TargetActivityStatusCancelled
+	default:
+		return sdk.TargetActivityStatusInProgress
+	}
+}
+
 // the last scaling activity.
 func processLastActivity(activity types.Activity, status *sdk.TargetStatus) {
 
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
 	"github.com/stretchr/testify/assert"
@@ -123,6 +124,66 @@ func Test_processLastActivity(t *testing.T) {
 	}
 }
 
+func Test_processActivities(t *testing.T) {
+
+	start := time.Date(2020, time.April, 13, 8, 4, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+
+	activities := []types.Activity{
+		{
+			ActivityId:  aws.String("a-3"),
+			StatusCode:  types.ScalingActivityStatusCodeWaitingForSpotInstanceId,
+			Description: aws.String("Launching a new EC2 instance"),
+			StartTime:   &start,
+		},
+		{
+			ActivityId:    aws.String("a-2"),
+			StatusCode:    types.ScalingActivityStatusCodeFailed,
+			Description:   aws.String("Launching a new EC2 instance.  Status Reason: insufficient capacity"),
+			StatusMessage: aws.String("We currently do not have sufficient capacity"),
+			StartTime:     &start,
+			EndTime:       &end,
+		},
+		{
+			ActivityId: aws.String("a-1"),
+			StatusCode: types.ScalingActivityStatusCodeSuccessful,
+			StartTime:  &start,
+			EndTime:    &end,
+		},
+	}
+
+	status := sdk.TargetStatus{}
+	processActivities(activities, &status)
+
+	assert.Equal(t, []sdk.TargetActivity{
+		{
+			ID:          "a-3",
+			Status:      sdk.TargetActivityStatusInProgress,
+			Description: "Launching a new EC2 instance",
+			StartTime:   start,
+		},
+		{
+			ID:          "a-2",
+			Status:      sdk.TargetActivityStatusFailed,
+			Description: "Launching a new EC2 instance.  Status Reason: insufficient capacity",
+			Message:     "We currently do not have sufficient capacity",
+			StartTime:   start,
+			EndTime:     end,
+		},
+		{
+			ID:        "a-1",
+			Status:    sdk.TargetActivityStatusSuccessful,
+			StartTime: start,
+			EndTime:   end,
+		},
+	}, status.Activities)
+
+	// Only the most recent activities are reported.
+	status = sdk.TargetStatus{}
+	processActivities(make([]types.Activity, maxReportedActivities+5), &status)
+	assert.Len(t, status.Activities, maxReportedActivities)
+}
+
 func int64ToPtr(v int64) *int64 {
 	return &v
 }
@@ -0,0 +1,115 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+// setInstanceProtectionMaxIDs is the maximum number of instance IDs accepted
+// by a single SetInstanceProtection API call.
+const setInstanceProtectionMaxIDs = 50
+
+// scaleInCandidates returns the IDs of the instances which can be selected
+// for termination. Instances must be healthy and in service, and must not be
+// protected from scale in, as the protection is set by operators or other
+// tooling to indicate the instance must be kept.
+func scaleInCandidates(log hclog.Logger, instances []types.Instance) []string {
+
+	remoteIDs := []string{}
+
+	for _, inst := range instances {
+		switch {
+		case aws.ToString(inst.HealthStatus) != "Healthy" || inst.LifecycleState != types.LifecycleStateInService:
+			log.Debug("skipping instance", "instance_id", aws.ToString(inst.InstanceId),
+				"health_status", aws.ToString(inst.HealthStatus), "lifecycle_state", inst.LifecycleState)
+		case aws.ToBool(inst.ProtectedFromScaleIn):
+			log.Debug("skipping instance protected from scale in", "instance_id", aws.ToString(inst.InstanceId))
+		default:
+			log.Debug("found healthy instance", "instance_id", aws.ToString(inst.InstanceId))
+			remoteIDs = append(remoteIDs, aws.ToString(inst.InstanceId))
+		}
+	}
+
+	return remoteIDs
+}
+
+// protectAndDrainNodes protects the instances of the selected nodes from
+// scale in while the nodes are drained, so that the ASG does not terminate
+// other instances if its desired capacity changes in the meantime. The
+// protection is removed if the drain fails.
+func (t *TargetPlugin) protectAndDrainNodes(ctx context.Context, log hclog.Logger, asgName string,
+	config map[string]string, ids []scaleutils.NodeResourceID) error {
+
+	selectedRemoteIDs := nodeRemoteIDs(ids)
+
+	if err := t.setInstanceProtection(ctx, asgName, selectedRemoteIDs, true); err != nil {
+		return fmt.Errorf("failed to protect instances from scale in: %v", err)
+	}
+	log.Debug("protected instances from scale in while draining", "instance_ids", selectedRemoteIDs)
+
+	if err := t.clusterUtils.DrainNodes(ctx, config, ids); err != nil {
+		t.removeInstanceProtection(ctx, log, asgName, selectedRemoteIDs)
+		return err
+	}
+	return nil
+}
+
+// removeInstanceProtection removes the scale in protection set while
+// draining. Failures are only logged, as the original error is more useful to
+// the operator.
+func (t *TargetPlugin) removeInstanceProtection(ctx context.Context, log hclog.Logger, asgName string, ids []string) {
+	if err := t.setInstanceProtection(ctx, asgName, ids, false); err != nil {
+		log.Error("failed to remove instance scale in protection", "instance_ids", ids, "error", err)
+	}
+}
+
+// setInstanceProtection sets the scale in protection of the instances,
+// batching the IDs to stay within the API limit.
+func (t *TargetPlugin) setInstanceProtection(ctx context.Context, asgName string, ids []string, protect bool) error {
+
+	for _, batch := range batchIDs(ids, setInstanceProtectionMaxIDs) {
+		input := autoscaling.SetInstanceProtectionInput{
+			AutoScalingGroupName: aws.String(asgName),
+			InstanceIds:          batch,
+			ProtectedFromScaleIn: aws.Bool(protect),
+		}
+
+		if _, err := t.asg.SetInstanceProtection(ctx, &input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchIDs splits the IDs into batches containing at most size IDs.
+func batchIDs(ids []string, size int) [][]string {
+
+	var batches [][]string
+
+	for len(ids) > size {
+		batches = append(batches, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		batches = append(batches, ids)
+	}
+	return batches
+}
+
+// nodeRemoteIDs returns the remote resource IDs of the nodes.
+func nodeRemoteIDs(ids []scaleutils.NodeResourceID) []string {
+	remoteIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		remoteIDs = append(remoteIDs, id.RemoteResourceID)
+	}
+	return remoteIDs
+}
@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_scaleInCandidates(t *testing.T) {
+	testCases := []struct {
+		inputInstances []types.Instance
+		expectedOutput []string
+		name           string
+	}{
+		{
+			inputInstances: []types.Instance{
+				{InstanceId: aws.String("i-1"), HealthStatus: aws.String("Healthy"), LifecycleState: types.LifecycleStateInService},
+				{InstanceId: aws.String("i-2"), HealthStatus: aws.String("Healthy"), LifecycleState: types.LifecycleStateInService, ProtectedFromScaleIn: aws.Bool(false)},
+			},
+			expectedOutput: []string{"i-1", "i-2"},
+			name:           "all instances are candidates",
+		},
+		{
+			inputInstances: []types.Instance{
+				{InstanceId: aws.String("i-1"), HealthStatus: aws.String("Unhealthy"), LifecycleState: types.LifecycleStateInService},
+				{InstanceId: aws.String("i-2"), HealthStatus: aws.String("Healthy"), LifecycleState: types.LifecycleStatePending},
+				{InstanceId: aws.String("i-3"), HealthStatus: aws.String("Healthy"), LifecycleState: types.LifecycleStateInService},
+			},
+			expectedOutput: []string{"i-3"},
+			name:           "unhealthy and pending instances",
+		},
+		{
+			inputInstances: []types.Instance{
+				{InstanceId: aws.String("i-1"), HealthStatus: aws.String("Healthy"), LifecycleState: types.LifecycleStateInService, ProtectedFromScaleIn: aws.Bool(true)},
+				{InstanceId: aws.String("i-2"), HealthStatus: aws.String("Healthy"), LifecycleState: types.LifecycleStateInService},
+			},
+			expectedOutput: []string{"i-2"},
+			name:           "protected instance",
+		},
+		{
+			inputInstances: []types.Instance{
+				{InstanceId: aws.String("i-1"), HealthStatus: aws.String("Healthy"), LifecycleState: types.LifecycleStateInService, ProtectedFromScaleIn: aws.Bool(true)},
+			},
+			expectedOutput: []string{},
+			name:           "all instances protected",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, scaleInCandidates(hclog.NewNullLogger(), tc.inputInstances), tc.name)
+		})
+	}
+}
+
+func Test_batchIDs(t *testing.T) {
+	testCases := []struct {
+		inputIDs       []string
+		inputSize      int
+		expectedOutput [][]string
+		name           string
+	}{
+		{
+			inputIDs:       nil,
+			inputSize:      2,
+			expectedOutput: nil,
+			name:           "no IDs",
+		},
+		{
+			inputIDs:       []string{"i-1", "i-2"},
+			inputSize:      2,
+			expectedOutput: [][]string{{"i-1", "i-2"}},
+			name:           "single full batch",
+		},
+		{
+			inputIDs:       []string{"i-1", "i-2", "i-3", "i-4", "i-5"},
+			inputSize:      2,
+			expectedOutput: [][]string{{"i-1", "i-2"}, {"i-3", "i-4"}, {"i-5"}},
+			name:           "partial last batch",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, batchIDs(tc.inputIDs, tc.inputSize), tc.name)
+		})
+	}
+}
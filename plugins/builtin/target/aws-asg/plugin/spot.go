@@ -0,0 +1,254 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+const (
+	// spotEventDetailTypes are the EventBridge detail types of the EC2 events
+	// signalling that a Spot instance is about to be, or is at an elevated
+	// risk of being, reclaimed.
+	spotEventDetailTypeInterruption = "EC2 Spot Instance Interruption Warning"
+	spotEventDetailTypeRebalance    = "EC2 Instance Rebalance Recommendation"
+
+	// spotSignalTTL is how long a signal is considered valid after the event
+	// was emitted. Interrupted instances are reclaimed within two minutes,
+	// while instances with a rebalance recommendation may keep running for
+	// much longer.
+	spotSignalTTL = 6 * time.Hour
+
+	// spotEventsMaxReceives is the maximum number of ReceiveMessage calls
+	// performed each time the queue is polled, bounding the time spent
+	// polling a busy queue during a scaling action.
+	spotEventsMaxReceives = 10
+
+	// spotEventsMaxMessages is the maximum number of messages accepted by a
+	// single ReceiveMessage or DeleteMessageBatch API call.
+	spotEventsMaxMessages = 10
+)
+
+// spotEventsAPI is the subset of the SQS client used to receive the Spot
+// events.
+type spotEventsAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+}
+
+// spotEvent is the subset of the EventBridge event fields used to identify
+// the instance it refers to.
+type spotEvent struct {
+	DetailType string    `json:"detail-type"`
+	Time       time.Time `json:"time"`
+	Detail     struct {
+		InstanceID string `json:"instance-id"`
+	} `json:"detail"`
+}
+
+// spotEventsReceiver tracks the instances flagged for reclamation by EC2 Spot
+// interruption warnings and rebalance recommendations. EC2 only exposes these
+// signals through the instance metadata and EventBridge, so the events are
+// expected to be routed to a dedicated SQS queue by an EventBridge rule. The
+// plugin is shared by all the policies targeting an ASG, so the signals are
+// kept in memory until they expire rather than being scoped to a single ASG.
+type spotEventsReceiver struct {
+	client   spotEventsAPI
+	queueURL string
+	logger   hclog.Logger
+
+	lock    sync.Mutex
+	signals map[string]time.Time
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+// newSpotEventsReceiver returns a new spotEventsReceiver for the queue.
+func newSpotEventsReceiver(log hclog.Logger, client spotEventsAPI, queueURL string) *spotEventsReceiver {
+	return &spotEventsReceiver{
+		client:   client,
+		queueURL: queueURL,
+		logger:   log.With("queue_url", queueURL),
+		signals:  make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// flaggedInstances polls the queue for new events and returns the instances
+// within ids which have been flagged for reclamation. Failing to poll the
+// queue is logged rather than returned, as the signals only affect which
+// instances are preferred. A nil spotEventsReceiver never flags instances.
+func (r *spotEventsReceiver) flaggedInstances(ctx context.Context, ids []string) []string {
+	if r == nil {
+		return nil
+	}
+
+	if err := r.poll(ctx); err != nil {
+		r.logger.Warn("failed to receive Spot events", "error", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.now()
+	for id, t := range r.signals {
+		if now.Sub(t) >= spotSignalTTL {
+			delete(r.signals, id)
+		}
+	}
+
+	var out []string
+	for _, id := range ids {
+		if _, ok := r.signals[id]; ok {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// poll receives the messages currently available in the queue, recording the
+// instances they flag and deleting them once processed. Messages which are
+// not Spot events are deleted too, as the queue is dedicated to them.
+func (r *spotEventsReceiver) poll(ctx context.Context) error {
+
+	for i := 0; i < spotEventsMaxReceives; i++ {
+		out, err := r.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(r.queueURL),
+			MaxNumberOfMessages: spotEventsMaxMessages,
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Messages) == 0 {
+			return nil
+		}
+
+		entries := make([]sqstypes.DeleteMessageBatchRequestEntry, 0, len(out.Messages))
+
+		for _, msg := range out.Messages {
+			r.record(aws.ToString(msg.Body))
+			entries = append(entries, sqstypes.DeleteMessageBatchRequestEntry{
+				Id:            msg.MessageId,
+				ReceiptHandle: msg.ReceiptHandle,
+			})
+		}
+
+		// Messages which fail to delete will be received again once their
+		// visibility timeout expires, which only refreshes their signal.
+		if _, err := r.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(r.queueURL),
+			Entries:  entries,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record parses the message body as an EventBridge event, flagging the
+// instance it refers to if it is a Spot event.
+func (r *spotEventsReceiver) record(body string) {
+
+	var event spotEvent
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		r.logger.Debug("ignoring malformed Spot event", "error", err)
+		return
+	}
+
+	switch event.DetailType {
+	case spotEventDetailTypeInterruption, spotEventDetailTypeRebalance:
+	default:
+		r.logger.Debug("ignoring unknown event", "detail_type", event.DetailType)
+		return
+	}
+
+	if event.Detail.InstanceID == "" {
+		r.logger.Debug("ignoring Spot event without instance ID", "detail_type", event.DetailType)
+		return
+	}
+
+	// Events published before the plugin started may be older than the
+	// current time, so their own time is used when available.
+	t := event.Time
+	if t.IsZero() {
+		t = r.now()
+	}
+
+	r.logger.Debug("received Spot event", "detail_type", event.DetailType, "instance_id", event.Detail.InstanceID)
+
+	r.lock.Lock()
+	if cur, ok := r.signals[event.Detail.InstanceID]; !ok || t.After(cur) {
+		r.signals[event.Detail.InstanceID] = t
+	}
+	r.lock.Unlock()
+}
+
+// selectScaleInNodes selects the nodes to terminate, preferring the instances
+// flagged for reclamation by Spot events. EC2 is about to terminate these
+// instances or replace them through capacity rebalancing, so removing them
+// first avoids draining healthy capacity only to lose the flagged instances
+// shortly after. The flagged instances are still selected using the policy's
+// node selector strategy.
+func (t *TargetPlugin) selectScaleInNodes(ctx context.Context, log hclog.Logger, config map[string]string,
+	remoteIDs []string, num int) ([]scaleutils.NodeResourceID, error) {
+
+	flagged := t.spotEvents.flaggedInstances(ctx, remoteIDs)
+	if len(flagged) == 0 {
+		return t.clusterUtils.SelectScaleInNodesWithRemoteCheck(config, remoteIDs, num)
+	}
+	log.Info("preferring instances flagged for reclamation", "instance_ids", flagged)
+
+	flaggedNum := num
+	if len(flagged) < flaggedNum {
+		flaggedNum = len(flagged)
+	}
+
+	selected, err := t.clusterUtils.SelectScaleInNodesWithRemoteCheck(config, flagged, flaggedNum)
+	if err != nil {
+		log.Warn("failed to select instances flagged for reclamation", "error", err)
+		selected = nil
+	}
+	if len(selected) >= num {
+		return selected, nil
+	}
+
+	// Select the remaining nodes from the instances which were not selected.
+	rest, err := t.clusterUtils.SelectScaleInNodesWithRemoteCheck(
+		config, excludeIDs(remoteIDs, nodeRemoteIDs(selected)), num-len(selected))
+	if err != nil {
+		if len(selected) > 0 {
+			log.Warn("failed to select additional instances", "error", err)
+			return selected, nil
+		}
+		return nil, err
+	}
+	return append(selected, rest...), nil
+}
+
+// excludeIDs returns the IDs which are not within exclude.
+func excludeIDs(ids, exclude []string) []string {
+
+	excluded := make(map[string]struct{}, len(exclude))
+	for _, id := range exclude {
+		excluded[id] = struct{}{}
+	}
+
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := excluded[id]; !ok {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpotEventsQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/spot-events"
+
+// mockSpotEvents returns the configured batches of messages, recording the
+// messages deleted.
+type mockSpotEvents struct {
+	batches    [][]string
+	receiveErr error
+	receives   int
+	deleted    []string
+}
+
+func (m *mockSpotEvents) ReceiveMessage(_ context.Context, params *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	if m.receiveErr != nil {
+		return nil, m.receiveErr
+	}
+	if aws.ToString(params.QueueUrl) != testSpotEventsQueueURL {
+		return nil, errors.New("unexpected queue URL")
+	}
+
+	var out sqs.ReceiveMessageOutput
+	if m.receives < len(m.batches) {
+		for i, body := range m.batches[m.receives] {
+			out.Messages = append(out.Messages, sqstypes.Message{
+				Body:          aws.String(body),
+				MessageId:     aws.String(strconv.Itoa(i)),
+				ReceiptHandle: aws.String(body),
+			})
+		}
+	}
+	m.receives++
+	return &out, nil
+}
+
+func (m *mockSpotEvents) DeleteMessageBatch(_ context.Context, params *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	for _, e := range params.Entries {
+		m.deleted = append(m.deleted, aws.ToString(e.ReceiptHandle))
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func Test_spotEventsReceiver_flaggedInstances(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	interruption := `{"detail-type":"EC2 Spot Instance Interruption Warning","time":"2023-10-01T11:59:00Z","detail":{"instance-id":"i-1","instance-action":"terminate"}}`
+	rebalance := `{"detail-type":"EC2 Instance Rebalance Recommendation","time":"2023-10-01T11:00:00Z","detail":{"instance-id":"i-2"}}`
+	stale := `{"detail-type":"EC2 Instance Rebalance Recommendation","time":"2023-10-01T05:00:00Z","detail":{"instance-id":"i-3"}}`
+	unknown := `{"detail-type":"EC2 Instance State-change Notification","detail":{"instance-id":"i-4"}}`
+	malformed := `not json`
+
+	client := &mockSpotEvents{batches: [][]string{{interruption, rebalance}, {stale, unknown, malformed}}}
+	r := newSpotEventsReceiver(hclog.NewNullLogger(), client, testSpotEventsQueueURL)
+	r.now = func() time.Time { return now }
+
+	// Only instances within the input IDs are returned.
+	flagged := r.flaggedInstances(context.Background(), []string{"i-2", "i-3", "i-4", "i-5"})
+	assert.Equal(t, []string{"i-2"}, flagged)
+	assert.Equal(t, 3, client.receives, "queue should be polled until empty")
+	assert.ElementsMatch(t, []string{interruption, rebalance, stale, unknown, malformed}, client.deleted,
+		"all received messages should be deleted")
+
+	// Signals are kept between polls until they expire.
+	flagged = r.flaggedInstances(context.Background(), []string{"i-1", "i-2"})
+	assert.Equal(t, []string{"i-1", "i-2"}, flagged)
+
+	now = now.Add(spotSignalTTL - 30*time.Minute)
+	flagged = r.flaggedInstances(context.Background(), []string{"i-1", "i-2"})
+	assert.Equal(t, []string{"i-1"}, flagged)
+
+	// Failing to poll the queue still returns the known signals.
+	client.receiveErr = errors.New("access denied")
+	flagged = r.flaggedInstances(context.Background(), []string{"i-1", "i-2"})
+	assert.Equal(t, []string{"i-1"}, flagged)
+
+	// A nil receiver never flags instances.
+	var nilReceiver *spotEventsReceiver
+	assert.Nil(t, nilReceiver.flaggedInstances(context.Background(), []string{"i-1"}))
+}
+
+func Test_spotEventsReceiver_record(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	r := newSpotEventsReceiver(hclog.NewNullLogger(), &mockSpotEvents{}, testSpotEventsQueueURL)
+	r.now = func() time.Time { return now }
+
+	// Events without a time use the current time.
+	r.record(`{"detail-type":"EC2 Instance Rebalance Recommendation","detail":{"instance-id":"i-1"}}`)
+	require.Contains(t, r.signals, "i-1")
+	assert.Equal(t, now, r.signals["i-1"])
+
+	// Older events do not overwrite newer signals.
+	r.record(`{"detail-type":"EC2 Spot Instance Interruption Warning","time":"2023-10-01T11:00:00Z","detail":{"instance-id":"i-1"}}`)
+	assert.Equal(t, now, r.signals["i-1"])
+
+	// Events without an instance ID are ignored.
+	r.record(`{"detail-type":"EC2 Spot Instance Interruption Warning","detail":{}}`)
+	assert.Len(t, r.signals, 1)
+}
+
+func Test_excludeIDs(t *testing.T) {
+	assert.Equal(t, []string{"i-1", "i-3"}, excludeIDs([]string{"i-1", "i-2", "i-3"}, []string{"i-2", "i-4"}))
+	assert.Equal(t, []string{"i-1"}, excludeIDs([]string{"i-1"}, nil))
+	assert.Equal(t, []string{}, excludeIDs([]string{"i-1"}, []string{"i-1"}))
+}
@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// metaKeys are the status meta keys used to report the capacity of the
+	// ASG. The in-service count is the number of instances able to run
+	// workloads, while the warm pool counts are the instances which are
+	// stopped or running in the warm pool and can be activated on scale out.
+	metaKeyInServiceCount  = "aws_asg.in_service_count"
+	metaKeyWarmPoolSize    = "aws_asg.warm_pool_size"
+	metaKeyWarmPoolWarmed  = "aws_asg.warm_pool_warmed_count"
+	metaKeyWarmPoolPending = "aws_asg.warm_pool_pending_count"
+
+	// warmPoolDescribeMaxRecords is the maximum page size of the
+	// DescribeWarmPool API.
+	warmPoolDescribeMaxRecords = 50
+)
+
+// hasWarmPool reports whether the ASG has a warm pool which is not being
+// deleted.
+func hasWarmPool(asg *types.AutoScalingGroup) bool {
+	return asg.WarmPoolConfiguration != nil &&
+		asg.WarmPoolConfiguration.Status != types.WarmPoolStatusPendingDelete
+}
+
+// describeWarmPool returns all the instances within the warm pool of the
+// ASG.
+func (t *TargetPlugin) describeWarmPool(ctx context.Context, asgName string) ([]types.Instance, error) {
+
+	input := autoscaling.DescribeWarmPoolInput{
+		AutoScalingGroupName: aws.String(asgName),
+		MaxRecords:           aws.Int32(warmPoolDescribeMaxRecords),
+	}
+
+	var instances []types.Instance
+
+	for {
+		resp, err := t.asg.DescribeWarmPool(ctx, &input)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.Instances...)
+
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			return instances, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// warmPoolCounts returns the number of warm pool instances which have been
+// prepared and can be activated, and the number which are still being
+// prepared.
+func warmPoolCounts(instances []types.Instance) (warmed, pending int64) {
+	for _, inst := range instances {
+		switch inst.LifecycleState {
+		case types.LifecycleStateWarmedStopped,
+			types.LifecycleStateWarmedRunning,
+			types.LifecycleStateWarmedHibernated:
+			warmed++
+		case types.LifecycleStateWarmedPending,
+			types.LifecycleStateWarmedPendingWait,
+			types.LifecycleStateWarmedPendingProceed:
+			pending++
+		}
+	}
+	return warmed, pending
+}
+
+// inServiceCount returns the number of instances of the ASG which are in
+// service.
+func inServiceCount(instances []types.Instance) int64 {
+	var n int64
+	for _, inst := range instances {
+		if inst.LifecycleState == types.LifecycleStateInService {
+			n++
+		}
+	}
+	return n
+}
+
+// warmPoolActivations returns how many of the new instances required to scale
+// the ASG out will be activated from the warm pool, with the rest being
+// launched from scratch.
+func warmPoolActivations(warmed, current, desired int64) int64 {
+	required := desired - current
+	if required <= 0 {
+		return 0
+	}
+	if warmed < required {
+		return warmed
+	}
+	return required
+}
+
+// processWarmPool updates the status meta with the in-service and warm pool
+// capacity of the ASG, so operators can distinguish instances able to run
+// workloads from those waiting in the warm pool.
+func processWarmPool(asg *types.AutoScalingGroup, warmPool []types.Instance, status *sdk.TargetStatus) {
+	status.Meta[metaKeyInServiceCount] = strconv.FormatInt(inServiceCount(asg.Instances), 10)
+
+	if !hasWarmPool(asg) {
+		return
+	}
+
+	var size int64
+	if asg.WarmPoolSize != nil {
+		size = int64(*asg.WarmPoolSize)
+	}
+	warmed, pending := warmPoolCounts(warmPool)
+
+	status.Meta[metaKeyWarmPoolSize] = strconv.FormatInt(size, 10)
+	status.Meta[metaKeyWarmPoolWarmed] = strconv.FormatInt(warmed, 10)
+	status.Meta[metaKeyWarmPoolPending] = strconv.FormatInt(pending, 10)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_warmPoolActivations(t *testing.T) {
+	testCases := []struct {
+		inputWarmed    int64
+		inputCurrent   int64
+		inputDesired   int64
+		expectedOutput int64
+		name           string
+	}{
+		{
+			inputWarmed:    5,
+			inputCurrent:   2,
+			inputDesired:   4,
+			expectedOutput: 2,
+			name:           "warm pool covers scale out",
+		},
+		{
+			inputWarmed:    1,
+			inputCurrent:   2,
+			inputDesired:   5,
+			expectedOutput: 1,
+			name:           "warm pool partially covers scale out",
+		},
+		{
+			inputWarmed:    0,
+			inputCurrent:   2,
+			inputDesired:   5,
+			expectedOutput: 0,
+			name:           "empty warm pool",
+		},
+		{
+			inputWarmed:    3,
+			inputCurrent:   5,
+			inputDesired:   4,
+			expectedOutput: 0,
+			name:           "not scaling out",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput := warmPoolActivations(tc.inputWarmed, tc.inputCurrent, tc.inputDesired)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+		})
+	}
+}
+
+func Test_processWarmPool(t *testing.T) {
+	instances := []types.Instance{
+		{LifecycleState: types.LifecycleStateInService},
+		{LifecycleState: types.LifecycleStateInService},
+		{LifecycleState: types.LifecycleStatePending},
+	}
+	warmPool := []types.Instance{
+		{LifecycleState: types.LifecycleStateWarmedStopped},
+		{LifecycleState: types.LifecycleStateWarmedHibernated},
+		{LifecycleState: types.LifecycleStateWarmedPendingWait},
+		{LifecycleState: types.LifecycleStateWarmedTerminating},
+	}
+
+	testCases := []struct {
+		inputASG       *types.AutoScalingGroup
+		inputWarmPool  []types.Instance
+		expectedOutput map[string]string
+		name           string
+	}{
+		{
+			inputASG: &types.AutoScalingGroup{Instances: instances},
+			expectedOutput: map[string]string{
+				"aws_asg.in_service_count": "2",
+			},
+			name: "no warm pool",
+		},
+		{
+			inputASG: &types.AutoScalingGroup{
+				Instances:             instances,
+				WarmPoolConfiguration: &types.WarmPoolConfiguration{},
+				WarmPoolSize:          ptr.Int32ToPtr(4),
+			},
+			inputWarmPool: warmPool,
+			expectedOutput: map[string]string{
+				"aws_asg.in_service_count":        "2",
+				"aws_asg.warm_pool_size":          "4",
+				"aws_asg.warm_pool_warmed_count":  "2",
+				"aws_asg.warm_pool_pending_count": "1",
+			},
+			name: "warm pool",
+		},
+		{
+			inputASG: &types.AutoScalingGroup{
+				Instances: instances,
+				WarmPoolConfiguration: &types.WarmPoolConfiguration{
+					Status: types.WarmPoolStatusPendingDelete,
+				},
+				WarmPoolSize: ptr.Int32ToPtr(4),
+			},
+			inputWarmPool: warmPool,
+			expectedOutput: map[string]string{
+				"aws_asg.in_service_count": "2",
+			},
+			name: "warm pool pending delete",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := &sdk.TargetStatus{Meta: map[string]string{}}
+			processWarmPool(tc.inputASG, tc.inputWarmPool, status)
+			assert.Equal(t, tc.expectedOutput, status.Meta, tc.name)
+		})
+	}
+}
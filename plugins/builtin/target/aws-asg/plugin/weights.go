@@ -0,0 +1,121 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+// metaKeyInServiceCapacity is the status meta key used to report the
+// capacity units provided by the in-service instances of ASGs using weights.
+const metaKeyInServiceCapacity = "aws_asg.in_service_capacity"
+
+// isWeighted returns whether the capacity of the ASG is expressed in weighted
+// capacity units rather than instances. This is the case when the ASG uses a
+// mixed instances policy with weights, or a desired capacity type other than
+// units. Each instance then provides as many units as its weighted capacity,
+// and the target count and scaling actions are in capacity units too.
+func isWeighted(asg *types.AutoScalingGroup) bool {
+	if t := aws.ToString(asg.DesiredCapacityType); t != "" && t != "units" {
+		return true
+	}
+
+	if asg.MixedInstancesPolicy == nil || asg.MixedInstancesPolicy.LaunchTemplate == nil {
+		return false
+	}
+	for _, o := range asg.MixedInstancesPolicy.LaunchTemplate.Overrides {
+		if o.WeightedCapacity != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceWeight returns the capacity units provided by the instance.
+// Instances without a valid weighted capacity provide a single unit.
+func instanceWeight(inst types.Instance) int64 {
+	if inst.WeightedCapacity == nil {
+		return 1
+	}
+	w, err := strconv.ParseInt(*inst.WeightedCapacity, 10, 64)
+	if err != nil || w < 1 {
+		return 1
+	}
+	return w
+}
+
+// instancesCapacity returns the capacity units provided by the instances.
+func instancesCapacity(instances []types.Instance) int64 {
+	var n int64
+	for _, inst := range instances {
+		n += instanceWeight(inst)
+	}
+	return n
+}
+
+// inServiceCapacity returns the capacity units provided by the instances of
+// the ASG which are in service.
+func inServiceCapacity(instances []types.Instance) int64 {
+	var n int64
+	for _, inst := range instances {
+		if inst.LifecycleState == types.LifecycleStateInService {
+			n += instanceWeight(inst)
+		}
+	}
+	return n
+}
+
+// isCapacityFulfilled returns whether the instances of the ASG provide its
+// desired capacity. Weighted ASGs may launch instances providing more units
+// than required, so their capacity only needs to reach the desired value.
+func isCapacityFulfilled(asg *types.AutoScalingGroup, desired int64) bool {
+	if !isWeighted(asg) {
+		return int64(len(asg.Instances)) == desired
+	}
+	return instancesCapacity(asg.Instances) >= desired
+}
+
+// fitCapacity returns the selected nodes, in order of selection, whose
+// instances provide at most the capacity units to remove. Terminating an
+// instance lowers the desired capacity of the ASG by its weight, so removing
+// more units would take the ASG below the desired count.
+func fitCapacity(ids []scaleutils.NodeResourceID, instances []types.Instance, units int64) []scaleutils.NodeResourceID {
+
+	weights := make(map[string]int64, len(instances))
+	for _, inst := range instances {
+		weights[aws.ToString(inst.InstanceId)] = instanceWeight(inst)
+	}
+
+	var (
+		out     []scaleutils.NodeResourceID
+		removed int64
+	)
+
+	for _, id := range ids {
+		w, ok := weights[id.RemoteResourceID]
+		if !ok {
+			w = 1
+		}
+		if removed+w > units {
+			continue
+		}
+		out = append(out, id)
+		removed += w
+	}
+	return out
+}
+
+// processWeightedCapacity updates the status meta with the capacity units
+// provided by the in-service instances of weighted ASGs.
+func processWeightedCapacity(asg *types.AutoScalingGroup, status *sdk.TargetStatus) {
+	if !isWeighted(asg) {
+		return
+	}
+	status.Meta[metaKeyInServiceCapacity] = strconv.FormatInt(inServiceCapacity(asg.Instances), 10)
+}
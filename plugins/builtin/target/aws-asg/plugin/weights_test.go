@@ -0,0 +1,158 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isWeighted(t *testing.T) {
+	testCases := []struct {
+		inputASG       *types.AutoScalingGroup
+		expectedOutput bool
+		name           string
+	}{
+		{
+			inputASG:       &types.AutoScalingGroup{},
+			expectedOutput: false,
+			name:           "launch template",
+		},
+		{
+			inputASG: &types.AutoScalingGroup{
+				MixedInstancesPolicy: &types.MixedInstancesPolicy{
+					LaunchTemplate: &types.LaunchTemplate{
+						Overrides: []types.LaunchTemplateOverrides{
+							{InstanceType: aws.String("m5.large")},
+							{InstanceType: aws.String("m5.xlarge")},
+						},
+					},
+				},
+			},
+			expectedOutput: false,
+			name:           "mixed instances without weights",
+		},
+		{
+			inputASG: &types.AutoScalingGroup{
+				MixedInstancesPolicy: &types.MixedInstancesPolicy{
+					LaunchTemplate: &types.LaunchTemplate{
+						Overrides: []types.LaunchTemplateOverrides{
+							{InstanceType: aws.String("m5.large"), WeightedCapacity: aws.String("1")},
+							{InstanceType: aws.String("m5.xlarge"), WeightedCapacity: aws.String("2")},
+						},
+					},
+				},
+			},
+			expectedOutput: true,
+			name:           "mixed instances with weights",
+		},
+		{
+			inputASG:       &types.AutoScalingGroup{DesiredCapacityType: aws.String("units")},
+			expectedOutput: false,
+			name:           "units desired capacity type",
+		},
+		{
+			inputASG:       &types.AutoScalingGroup{DesiredCapacityType: aws.String("vcpu")},
+			expectedOutput: true,
+			name:           "vcpu desired capacity type",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, isWeighted(tc.inputASG), tc.name)
+		})
+	}
+}
+
+func Test_instanceWeight(t *testing.T) {
+	assert.Equal(t, int64(1), instanceWeight(types.Instance{}))
+	assert.Equal(t, int64(4), instanceWeight(types.Instance{WeightedCapacity: aws.String("4")}))
+	assert.Equal(t, int64(1), instanceWeight(types.Instance{WeightedCapacity: aws.String("0")}))
+	assert.Equal(t, int64(1), instanceWeight(types.Instance{WeightedCapacity: aws.String("large")}))
+}
+
+func Test_isCapacityFulfilled(t *testing.T) {
+	instances := []types.Instance{
+		{WeightedCapacity: aws.String("4")},
+		{WeightedCapacity: aws.String("4")},
+	}
+
+	unweighted := &types.AutoScalingGroup{Instances: instances}
+	assert.True(t, isCapacityFulfilled(unweighted, 2))
+	assert.False(t, isCapacityFulfilled(unweighted, 3))
+
+	weighted := &types.AutoScalingGroup{Instances: instances, DesiredCapacityType: aws.String("vcpu")}
+	assert.True(t, isCapacityFulfilled(weighted, 8))
+	assert.True(t, isCapacityFulfilled(weighted, 7), "weighted capacity may exceed the desired capacity")
+	assert.False(t, isCapacityFulfilled(weighted, 9))
+}
+
+func Test_fitCapacity(t *testing.T) {
+	instances := []types.Instance{
+		{InstanceId: aws.String("i-1"), WeightedCapacity: aws.String("4")},
+		{InstanceId: aws.String("i-2"), WeightedCapacity: aws.String("2")},
+		{InstanceId: aws.String("i-3"), WeightedCapacity: aws.String("1")},
+		{InstanceId: aws.String("i-4"), WeightedCapacity: aws.String("2")},
+	}
+	ids := []scaleutils.NodeResourceID{
+		{NomadNodeID: "node-1", RemoteResourceID: "i-1"},
+		{NomadNodeID: "node-2", RemoteResourceID: "i-2"},
+		{NomadNodeID: "node-3", RemoteResourceID: "i-3"},
+		{NomadNodeID: "node-4", RemoteResourceID: "i-4"},
+	}
+
+	testCases := []struct {
+		inputUnits     int64
+		expectedOutput []scaleutils.NodeResourceID
+		name           string
+	}{
+		{
+			inputUnits:     9,
+			expectedOutput: ids,
+			name:           "all instances fit",
+		},
+		{
+			inputUnits:     5,
+			expectedOutput: []scaleutils.NodeResourceID{ids[0], ids[2]},
+			name:           "skip instances exceeding remaining units",
+		},
+		{
+			inputUnits:     3,
+			expectedOutput: []scaleutils.NodeResourceID{ids[1], ids[2]},
+			name:           "first selected instance too large",
+		},
+		{
+			inputUnits:     0,
+			expectedOutput: nil,
+			name:           "no units",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, fitCapacity(ids, instances, tc.inputUnits), tc.name)
+		})
+	}
+}
+
+func Test_processWeightedCapacity(t *testing.T) {
+	instances := []types.Instance{
+		{LifecycleState: types.LifecycleStateInService, WeightedCapacity: aws.String("4")},
+		{LifecycleState: types.LifecycleStateInService, WeightedCapacity: aws.String("2")},
+		{LifecycleState: types.LifecycleStatePending, WeightedCapacity: aws.String("2")},
+	}
+
+	status := sdk.TargetStatus{Meta: make(map[string]string)}
+	processWeightedCapacity(&types.AutoScalingGroup{Instances: instances}, &status)
+	assert.Empty(t, status.Meta)
+
+	processWeightedCapacity(&types.AutoScalingGroup{Instances: instances, DesiredCapacityType: aws.String("vcpu")}, &status)
+	assert.Equal(t, map[string]string{metaKeyInServiceCapacity: "6"}, status.Meta)
+}
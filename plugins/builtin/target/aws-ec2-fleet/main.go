@@ -0,0 +1,19 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package main
+
+import (
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	awsFleet "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/aws-ec2-fleet/plugin"
+)
+
+func main() {
+	plugins.Serve(factory)
+}
+
+// factory returns a new instance of the AWS EC2 Fleet plugin.
+func factory(log hclog.Logger) interface{} {
+	return awsFleet.NewAWSFleetPlugin(log)
+}
@@ -0,0 +1,256 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	defaultRetryInterval  = 10 * time.Second
+	nodeAttrAWSInstanceID = "unique.platform.aws.instance-id"
+)
+
+// setupAWSClients takes the passed config mapping and instantiates the
+// required AWS service clients.
+func (t *TargetPlugin) setupAWSClients(config map[string]string) error {
+
+	// Load our default AWS config. This handles pulling configuration from
+	// default profiles and environment variables.
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return fmt.Errorf("failed to load default AWS config: %v", err)
+	}
+
+	// If the operator has provided a configuration region, overwrite that set
+	// by the AWS client.
+	region, ok := config[configKeyRegion]
+	if ok {
+		t.logger.Debug("setting AWS region for client", "region", region)
+		cfg.Region = region
+	}
+
+	// In the situation where the plugin is not running on an EC2 instance, nor
+	// has the operator set an parameter, set the region to the default.
+	if cfg.Region == "" {
+		cfg.Region = configValueRegionDefault
+	}
+
+	// Attempt to pull access credentials for the AWS client from the user
+	// supplied configuration. In order to use these static credentials both
+	// the access key and secret key need to be present; the session token is
+	// optional.
+	// If static credentials are not set, check for specific credential
+	// provider.
+	keyID := config[configKeyAccessID]
+	secretKey := config[configKeySecretKey]
+	session := config[configKeySessionToken]
+	credProvider := config[configKeyCredentialProvider]
+
+	if keyID != "" && secretKey != "" {
+		t.logger.Trace("setting AWS access credentials from config map")
+		cfg.Credentials = credentials.NewStaticCredentialsProvider(keyID, secretKey, session)
+	} else if credProvider != "" {
+		switch credProvider {
+		case credentialProviderEC2Role:
+			t.logger.Trace("AWS access credentials empty - using EC2 instance role credentials instead")
+			cfg.Credentials = aws.NewCredentialsCache(ec2rolecreds.New())
+		default:
+			return fmt.Errorf("invalid value %s for aws_credential_provider", credProvider)
+		}
+	} else {
+		t.logger.Trace("Using default AWS credential chain")
+	}
+
+	// Set up our AWS client.
+	t.ec2 = ec2.NewFromConfig(cfg)
+
+	return nil
+}
+
+// scaleOut updates the fleet target capacity to match what the Autoscaler has
+// deemed required.
+func (t *TargetPlugin) scaleOut(ctx context.Context, f fleet, count int64) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_out", "fleet_id", f.id(), "desired_count", count)
+
+	if err := f.setTargetCapacity(ctx, count, true); err != nil {
+		return fmt.Errorf("failed to update fleet target capacity: %v", err)
+	}
+
+	if err := t.ensureFleetInstancesCount(ctx, f, count); err != nil {
+		return fmt.Errorf("failed to confirm scale out AWS EC2 Fleet: %v", err)
+	}
+
+	log.Info("successfully performed and verified scaling out")
+	return nil
+}
+
+// scaleIn drains the Nomad nodes selected for removal, lowers the fleet target
+// capacity without letting the fleet pick instances itself, and then
+// terminates the drained instances.
+func (t *TargetPlugin) scaleIn(ctx context.Context, f fleet, status *fleetStatus, num int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "fleet_id", f.id())
+
+	instances, err := f.activeInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to describe fleet instances: %v", err)
+	}
+
+	// Find instance IDs in the target fleet and perform pre-scale tasks.
+	remoteIDs := []string{}
+	for _, inst := range instances {
+		if inst.InstanceHealth != types.InstanceHealthStatusUnhealthyStatus {
+			log.Debug("found healthy instance", "instance_id", *inst.InstanceId)
+			remoteIDs = append(remoteIDs, *inst.InstanceId)
+		} else {
+			log.Debug("skipping instance", "instance_id", *inst.InstanceId, "health_status", inst.InstanceHealth)
+		}
+	}
+
+	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, remoteIDs, int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	// Lower the target capacity before terminating the instances, otherwise
+	// the fleet would replace them to maintain its capacity. Excess instances
+	// are not terminated by the fleet, so only the drained instances are
+	// removed.
+	desired := status.targetCapacity - int64(len(ids))
+	if err := f.setTargetCapacity(ctx, desired, false); err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return fmt.Errorf("failed to update fleet target capacity: %v", err)
+	}
+
+	if err := t.terminateInstances(ctx, ids); err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return fmt.Errorf("failed to terminate instances: %v", err)
+	}
+
+	// Track the terminations until the instances leave the fleet. A failure
+	// here should not fail the scaling activity as AWS should honour the
+	// contract, it could be a case of there being slowness in the AWS system
+	// and us timing out.
+	if err := t.ensureInstancesTerminated(ctx, f, ids); err != nil {
+		log.Error("failed to ensure all instances terminated", "error", err)
+	} else {
+		log.Debug("confirmed AWS EC2 Fleet instances terminated")
+	}
+
+	// The tasks run on nodes that have been successfully terminated should not
+	// cause a failure of the scaling pipeline.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		log.Error("failed to perform post-scale Nomad scale in tasks", "error", err)
+	}
+
+	log.Info("successfully performed and verified scaling in", "desired_count", desired)
+	return nil
+}
+
+// terminateInstances terminates the instances, which the EC2 API allows within
+// a single request.
+func (t *TargetPlugin) terminateInstances(ctx context.Context, ids []scaleutils.NodeResourceID) error {
+
+	input := ec2.TerminateInstancesInput{InstanceIds: instanceIDs(ids)}
+
+	_, err := t.ec2.TerminateInstances(ctx, &input)
+	return err
+}
+
+// ensureFleetInstancesCount waits until the fleet has the desired number of
+// active instances.
+func (t *TargetPlugin) ensureFleetInstancesCount(ctx context.Context, f fleet, desired int64) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		instances, err := f.activeInstances(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		if len(instances) >= int(desired) {
+			return true, nil
+		}
+		return false, fmt.Errorf("EC2 Fleet at %v instances of desired %v", len(instances), desired)
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// ensureInstancesTerminated waits until none of the instances are active
+// within the fleet.
+func (t *TargetPlugin) ensureInstancesTerminated(ctx context.Context, f fleet, ids []scaleutils.NodeResourceID) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		instances, err := f.activeInstances(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		if n := countActive(instances, instanceIDs(ids)); n > 0 {
+			return false, fmt.Errorf("waiting for %v instances to terminate", n)
+		}
+		return true, nil
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// countActive returns how many of the instance IDs are within the active
+// instances.
+func countActive(instances []types.ActiveInstance, ids []string) int {
+	active := make(map[string]struct{}, len(instances))
+	for _, inst := range instances {
+		if inst.InstanceId != nil {
+			active[*inst.InstanceId] = struct{}{}
+		}
+	}
+
+	var n int
+	for _, id := range ids {
+		if _, ok := active[id]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// instanceIDs returns the AWS instance IDs of the nodes.
+func instanceIDs(ids []scaleutils.NodeResourceID) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, id.RemoteResourceID)
+	}
+	return out
+}
+
+// awsNodeIDMap is used to identify the AWS InstanceID of a Nomad node using
+// the relevant attribute value.
+func awsNodeIDMap(n *api.Node) (string, error) {
+	val, ok := n.Attributes[nodeAttrAWSInstanceID]
+	if !ok || val == "" {
+		return "", fmt.Errorf("attribute %q not found", nodeAttrAWSInstanceID)
+	}
+	return val, nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_awsNodeIDMap(t *testing.T) {
+	testCases := []struct {
+		inputNode           *api.Node
+		expectedOutputID    string
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{"unique.platform.aws.instance-id": "i-1234567890abcdef0"},
+			},
+			expectedOutputID:    "i-1234567890abcdef0",
+			expectedOutputError: nil,
+			name:                "required attribute found",
+		},
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{},
+			},
+			expectedOutputID:    "",
+			expectedOutputError: errors.New(`attribute "unique.platform.aws.instance-id" not found`),
+			name:                "required attribute not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualID, actualErr := awsNodeIDMap(tc.inputNode)
+			assert.Equal(t, tc.expectedOutputID, actualID, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_countActive(t *testing.T) {
+	instances := []types.ActiveInstance{
+		{InstanceId: aws.String("i-1")},
+		{InstanceId: aws.String("i-2")},
+		{},
+	}
+
+	assert.Equal(t, 2, countActive(instances, []string{"i-1", "i-2", "i-3"}))
+	assert.Equal(t, 0, countActive(instances, []string{"i-3"}))
+	assert.Equal(t, 0, countActive(nil, []string{"i-1"}))
+}
@@ -0,0 +1,253 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// fleetIDPrefixes are used to identify whether a fleet ID refers to an
+	// EC2 Fleet or a Spot Fleet request, as they are managed using different
+	// APIs.
+	fleetIDPrefixEC2  = "fleet-"
+	fleetIDPrefixSpot = "sfr-"
+)
+
+// fleetStatus is the provider agnostic state of an EC2 Fleet or Spot Fleet
+// request.
+type fleetStatus struct {
+	// targetCapacity is the total target capacity of the fleet.
+	targetCapacity int64
+
+	// stable indicates the fleet is active and has fulfilled its target
+	// capacity, meaning no modification or fulfillment is in progress.
+	stable bool
+
+	// fleetType is the request type of the fleet. Only fleets of type
+	// maintain can have their target capacity modified.
+	fleetType types.FleetType
+
+	// unitType is the unit of the target capacity. An empty value or units
+	// means capacity is measured in instances.
+	unitType types.TargetCapacityUnitType
+}
+
+// validate ensures the fleet can be scaled by the plugin.
+func (s *fleetStatus) validate() error {
+	if s.fleetType != types.FleetTypeMaintain {
+		return fmt.Errorf("fleet type %q cannot be scaled, only %q fleets are supported",
+			s.fleetType, types.FleetTypeMaintain)
+	}
+	if s.unitType != "" && s.unitType != types.TargetCapacityUnitTypeUnits {
+		return fmt.Errorf("fleet target capacity unit type %q is not supported, only %q is supported",
+			s.unitType, types.TargetCapacityUnitTypeUnits)
+	}
+	return nil
+}
+
+// fleet abstracts the differences between the EC2 Fleet and Spot Fleet APIs,
+// so the plugin can scale either using the same workflow.
+type fleet interface {
+	// id returns the ID of the fleet.
+	id() string
+
+	// describe returns the current status of the fleet.
+	describe(ctx context.Context) (*fleetStatus, error)
+
+	// setTargetCapacity modifies the total target capacity of the fleet. If
+	// terminate is false, the fleet does not terminate instances when the
+	// capacity is decreased, which allows the plugin to select and drain the
+	// instances to remove itself.
+	setTargetCapacity(ctx context.Context, capacity int64, terminate bool) error
+
+	// activeInstances returns the running instances of the fleet.
+	activeInstances(ctx context.Context) ([]types.ActiveInstance, error)
+}
+
+// newFleet returns the fleet implementation for the fleet ID, based on its
+// prefix.
+func newFleet(client *ec2.Client, id string) (fleet, error) {
+	switch {
+	case strings.HasPrefix(id, fleetIDPrefixEC2):
+		return &ec2Fleet{client: client, fleetID: id}, nil
+	case strings.HasPrefix(id, fleetIDPrefixSpot):
+		return &spotFleet{client: client, fleetID: id}, nil
+	default:
+		return nil, fmt.Errorf("unsupported fleet ID %q, expected an EC2 Fleet ID prefixed with %q or a Spot Fleet request ID prefixed with %q",
+			id, fleetIDPrefixEC2, fleetIDPrefixSpot)
+	}
+}
+
+// ec2Fleet is the fleet implementation for EC2 Fleets.
+type ec2Fleet struct {
+	client  *ec2.Client
+	fleetID string
+}
+
+func (f *ec2Fleet) id() string { return f.fleetID }
+
+func (f *ec2Fleet) describe(ctx context.Context) (*fleetStatus, error) {
+
+	input := ec2.DescribeFleetsInput{FleetIds: []string{f.fleetID}}
+
+	resp, err := f.client.DescribeFleets(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Fleets) != 1 {
+		return nil, fmt.Errorf("expected 1 EC2 Fleet, got %v", len(resp.Fleets))
+	}
+	return ec2FleetStatus(resp.Fleets[0]), nil
+}
+
+func (f *ec2Fleet) setTargetCapacity(ctx context.Context, capacity int64, terminate bool) error {
+
+	policy := types.FleetExcessCapacityTerminationPolicyTermination
+	if !terminate {
+		policy = types.FleetExcessCapacityTerminationPolicyNoTermination
+	}
+
+	input := ec2.ModifyFleetInput{
+		FleetId:                         aws.String(f.fleetID),
+		ExcessCapacityTerminationPolicy: policy,
+		TargetCapacitySpecification: &types.TargetCapacitySpecificationRequest{
+			TotalTargetCapacity: aws.Int32(int32(capacity)),
+		},
+	}
+
+	// Ignore the response from ModifyFleet as it only holds a success flag
+	// which is false when an error is returned.
+	_, err := f.client.ModifyFleet(ctx, &input)
+	return err
+}
+
+func (f *ec2Fleet) activeInstances(ctx context.Context) ([]types.ActiveInstance, error) {
+
+	input := ec2.DescribeFleetInstancesInput{FleetId: aws.String(f.fleetID)}
+
+	var instances []types.ActiveInstance
+
+	for {
+		resp, err := f.client.DescribeFleetInstances(ctx, &input)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.ActiveInstances...)
+
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			return instances, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// ec2FleetStatus converts the EC2 Fleet API response to the fleet status.
+func ec2FleetStatus(data types.FleetData) *fleetStatus {
+	status := fleetStatus{
+		stable: data.FleetState == types.FleetStateCodeActive &&
+			data.ActivityStatus == types.FleetActivityStatusFulfilled,
+		fleetType: data.Type,
+	}
+
+	if spec := data.TargetCapacitySpecification; spec != nil {
+		if spec.TotalTargetCapacity != nil {
+			status.targetCapacity = int64(*spec.TotalTargetCapacity)
+		}
+		status.unitType = spec.TargetCapacityUnitType
+	}
+	return &status
+}
+
+// spotFleet is the fleet implementation for Spot Fleet requests.
+type spotFleet struct {
+	client  *ec2.Client
+	fleetID string
+}
+
+func (f *spotFleet) id() string { return f.fleetID }
+
+func (f *spotFleet) describe(ctx context.Context) (*fleetStatus, error) {
+
+	input := ec2.DescribeSpotFleetRequestsInput{SpotFleetRequestIds: []string{f.fleetID}}
+
+	resp, err := f.client.DescribeSpotFleetRequests(ctx, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.SpotFleetRequestConfigs) != 1 {
+		return nil, fmt.Errorf("expected 1 Spot Fleet request, got %v", len(resp.SpotFleetRequestConfigs))
+	}
+	return spotFleetStatus(resp.SpotFleetRequestConfigs[0]), nil
+}
+
+func (f *spotFleet) setTargetCapacity(ctx context.Context, capacity int64, terminate bool) error {
+
+	policy := types.ExcessCapacityTerminationPolicyDefault
+	if !terminate {
+		policy = types.ExcessCapacityTerminationPolicyNoTermination
+	}
+
+	input := ec2.ModifySpotFleetRequestInput{
+		SpotFleetRequestId:              aws.String(f.fleetID),
+		ExcessCapacityTerminationPolicy: policy,
+		TargetCapacity:                  aws.Int32(int32(capacity)),
+	}
+
+	// Ignore the response from ModifySpotFleetRequest as it only holds a
+	// success flag which is false when an error is returned.
+	_, err := f.client.ModifySpotFleetRequest(ctx, &input)
+	return err
+}
+
+func (f *spotFleet) activeInstances(ctx context.Context) ([]types.ActiveInstance, error) {
+
+	input := ec2.DescribeSpotFleetInstancesInput{SpotFleetRequestId: aws.String(f.fleetID)}
+
+	var instances []types.ActiveInstance
+
+	for {
+		resp, err := f.client.DescribeSpotFleetInstances(ctx, &input)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.ActiveInstances...)
+
+		if resp.NextToken == nil || *resp.NextToken == "" {
+			return instances, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// spotFleetStatus converts the Spot Fleet API response to the fleet status.
+func spotFleetStatus(config types.SpotFleetRequestConfig) *fleetStatus {
+	status := fleetStatus{
+		stable: config.SpotFleetRequestState == types.BatchStateActive &&
+			config.ActivityStatus == types.ActivityStatusFulfilled,
+	}
+
+	if data := config.SpotFleetRequestConfig; data != nil {
+		if data.TargetCapacity != nil {
+			status.targetCapacity = int64(*data.TargetCapacity)
+		}
+		status.unitType = data.TargetCapacityUnitType
+
+		// Spot Fleet requests default to the maintain type when it is not
+		// set.
+		status.fleetType = data.Type
+		if status.fleetType == "" {
+			status.fleetType = types.FleetTypeMaintain
+		}
+	}
+	return &status
+}
@@ -0,0 +1,144 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newFleet(t *testing.T) {
+	f, err := newFleet(nil, "fleet-73fdd617-1234-4b5f-9a96-0c1ab4d0d5b0")
+	assert.Nil(t, err)
+	assert.IsType(t, &ec2Fleet{}, f)
+	assert.Equal(t, "fleet-73fdd617-1234-4b5f-9a96-0c1ab4d0d5b0", f.id())
+
+	f, err = newFleet(nil, "sfr-73fdd617-1234-4b5f-9a96-0c1ab4d0d5b0")
+	assert.Nil(t, err)
+	assert.IsType(t, &spotFleet{}, f)
+
+	_, err = newFleet(nil, "my-asg")
+	assert.EqualError(t, err, `unsupported fleet ID "my-asg", expected an EC2 Fleet ID prefixed with "fleet-" or a Spot Fleet request ID prefixed with "sfr-"`)
+}
+
+func Test_ec2FleetStatus(t *testing.T) {
+	testCases := []struct {
+		inputFleet     types.FleetData
+		expectedOutput *fleetStatus
+		name           string
+	}{
+		{
+			inputFleet: types.FleetData{
+				FleetState:     types.FleetStateCodeActive,
+				ActivityStatus: types.FleetActivityStatusFulfilled,
+				Type:           types.FleetTypeMaintain,
+				TargetCapacitySpecification: &types.TargetCapacitySpecification{
+					TotalTargetCapacity: aws.Int32(5),
+				},
+			},
+			expectedOutput: &fleetStatus{targetCapacity: 5, stable: true, fleetType: types.FleetTypeMaintain},
+			name:           "fulfilled fleet",
+		},
+		{
+			inputFleet: types.FleetData{
+				FleetState:     types.FleetStateCodeModifying,
+				ActivityStatus: types.FleetActivityStatusPendingFulfillment,
+				Type:           types.FleetTypeMaintain,
+				TargetCapacitySpecification: &types.TargetCapacitySpecification{
+					TotalTargetCapacity:    aws.Int32(8),
+					TargetCapacityUnitType: types.TargetCapacityUnitTypeVcpu,
+				},
+			},
+			expectedOutput: &fleetStatus{
+				targetCapacity: 8,
+				fleetType:      types.FleetTypeMaintain,
+				unitType:       types.TargetCapacityUnitTypeVcpu,
+			},
+			name: "modifying fleet",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, ec2FleetStatus(tc.inputFleet), tc.name)
+		})
+	}
+}
+
+func Test_spotFleetStatus(t *testing.T) {
+	testCases := []struct {
+		inputConfig    types.SpotFleetRequestConfig
+		expectedOutput *fleetStatus
+		name           string
+	}{
+		{
+			inputConfig: types.SpotFleetRequestConfig{
+				SpotFleetRequestState: types.BatchStateActive,
+				ActivityStatus:        types.ActivityStatusFulfilled,
+				SpotFleetRequestConfig: &types.SpotFleetRequestConfigData{
+					TargetCapacity: aws.Int32(3),
+				},
+			},
+			expectedOutput: &fleetStatus{targetCapacity: 3, stable: true, fleetType: types.FleetTypeMaintain},
+			name:           "fulfilled request with default type",
+		},
+		{
+			inputConfig: types.SpotFleetRequestConfig{
+				SpotFleetRequestState: types.BatchStateActive,
+				ActivityStatus:        types.ActivityStatusPendingFulfillment,
+				SpotFleetRequestConfig: &types.SpotFleetRequestConfigData{
+					TargetCapacity: aws.Int32(3),
+					Type:           types.FleetTypeRequest,
+				},
+			},
+			expectedOutput: &fleetStatus{targetCapacity: 3, fleetType: types.FleetTypeRequest},
+			name:           "pending one-time request",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, spotFleetStatus(tc.inputConfig), tc.name)
+		})
+	}
+}
+
+func Test_fleetStatus_validate(t *testing.T) {
+	testCases := []struct {
+		inputStatus    *fleetStatus
+		expectedOutput error
+		name           string
+	}{
+		{
+			inputStatus:    &fleetStatus{fleetType: types.FleetTypeMaintain},
+			expectedOutput: nil,
+			name:           "maintain fleet",
+		},
+		{
+			inputStatus:    &fleetStatus{fleetType: types.FleetTypeMaintain, unitType: types.TargetCapacityUnitTypeUnits},
+			expectedOutput: nil,
+			name:           "maintain fleet in units",
+		},
+		{
+			inputStatus:    &fleetStatus{fleetType: types.FleetTypeInstant},
+			expectedOutput: errors.New(`fleet type "instant" cannot be scaled, only "maintain" fleets are supported`),
+			name:           "instant fleet",
+		},
+		{
+			inputStatus:    &fleetStatus{fleetType: types.FleetTypeMaintain, unitType: types.TargetCapacityUnitTypeMemoryMib},
+			expectedOutput: errors.New(`fleet target capacity unit type "memory-mib" is not supported, only "units" is supported`),
+			name:           "capacity in memory",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, tc.inputStatus.validate(), tc.name)
+		})
+	}
+}
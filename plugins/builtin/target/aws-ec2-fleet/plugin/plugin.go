@@ -0,0 +1,230 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "aws-ec2-fleet"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle.
+	configKeyRegion             = "aws_region"
+	configKeyAccessID           = "aws_access_key_id"
+	configKeySecretKey          = "aws_secret_access_key"
+	configKeySessionToken       = "aws_session_token"
+	configKeyFleetID            = "aws_fleet_id"
+	configKeyCredentialProvider = "aws_credential_provider"
+	configKeyRetryAttempts      = "retry_attempts"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueRegionDefault        = "us-east-1"
+	configValueRetryAttemptsDefault = "15"
+
+	// credentialProvider are the valid options for the aws_credential_provider
+	// configuration key.
+	credentialProviderEC2Role = "ec2_role"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewAWSFleetPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the AWS EC2 Fleet implementation of the target.Target
+// interface. It scales both EC2 Fleets and Spot Fleet requests of type
+// maintain, where each instance is expected to provide one unit of the fleet
+// target capacity.
+type TargetPlugin struct {
+	config map[string]string
+	logger hclog.Logger
+	ec2    *ec2.Client
+
+	// retryAttempts is the number of times operations such as wating for a
+	// given fleet state should be retried.
+	retryAttempts int
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools and performing scaling tasks.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewAWSFleetPlugin returns the AWS EC2 Fleet implementation of the
+// target.Target interface.
+func NewAWSFleetPlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	if err := t.setupAWSClients(config); err != nil {
+		return err
+	}
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+
+	// Store and set the remote ID callback function.
+	t.clusterUtils = clusterUtils
+	t.clusterUtils.ClusterNodeIDLookupFunc = awsNodeIDMap
+
+	retryLimit, err := strconv.Atoi(getConfigValue(config, configKeyRetryAttempts, configValueRetryAttemptsDefault))
+	if err != nil {
+		return err
+	}
+	t.retryAttempts = retryLimit
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// AWS can't support dry-run like Nomad, so just exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	f, err := t.fleetFromConfig(config)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	// Describe the fleet. This serves to both validate the config value is
+	// correct and ensure the AWS client is configured correctly. The response
+	// can also be used when performing the scaling, meaning we only need to
+	// call it once.
+	status, err := f.describe(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to describe AWS EC2 Fleet: %v", err)
+	}
+	if err := status.validate(); err != nil {
+		return err
+	}
+
+	// The fleet target requires different details depending on which
+	// direction we want to scale. Therefore calculate the direction and the
+	// relevant number so we can correctly perform the AWS work.
+	num, direction := t.calculateDirection(status.targetCapacity, action.Count)
+
+	switch direction {
+	case "in":
+		err = t.scaleIn(ctx, f, status, num, config)
+	case "out":
+		err = t.scaleOut(ctx, f, num)
+	default:
+		t.logger.Info("scaling not required", "fleet_id", config[configKeyFleetID],
+			"current_count", status.targetCapacity, "strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err != nil {
+		err = fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return err
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool. If the pool is not ready, we
+	// can exit here and avoid calling the AWS API as it won't affect the
+	// outcome.
+	ready, err := t.clusterUtils.IsPoolReady(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+	}
+	if !ready {
+		return &sdk.TargetStatus{Ready: ready}, nil
+	}
+
+	f, err := t.fleetFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := f.describe(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe AWS EC2 Fleet: %v", err)
+	}
+
+	// The fleet is only ready once it has fulfilled its target capacity, so
+	// the autoscaler does not act while a previous modification is still in
+	// progress.
+	return &sdk.TargetStatus{
+		Ready: status.stable,
+		Count: status.targetCapacity,
+		Meta:  make(map[string]string),
+	}, nil
+}
+
+// fleetFromConfig returns the fleet identified by the policy target config.
+func (t *TargetPlugin) fleetFromConfig(config map[string]string) (fleet, error) {
+
+	// We cannot scale a fleet without knowing its ID.
+	id, ok := config[configKeyFleetID]
+	if !ok {
+		return nil, fmt.Errorf("required config param %s not found", configKeyFleetID)
+	}
+	return newFleet(t.ec2, id)
+}
+
+func (t *TargetPlugin) calculateDirection(fleetDesired, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < fleetDesired {
+		return fleetDesired - strategyDesired, "in"
+	}
+	if strategyDesired > fleetDesired {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
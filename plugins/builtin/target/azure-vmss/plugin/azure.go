@@ -10,7 +10,7 @@ import (
 	"os"
 	"strings"
 
-	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2020-06-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-12-01/compute"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
@@ -65,6 +65,12 @@ func (t *TargetPlugin) setupAzureClient(config map[string]string) error {
 
 	t.vmssVMs = vmssVMs
 
+	vms := compute.NewVirtualMachinesClient(subscriptionID)
+	vms.Sender = autorest.CreateSender()
+	vms.Authorizer = authorizer
+
+	t.vms = vms
+
 	return nil
 }
 
@@ -96,12 +102,22 @@ func (t *TargetPlugin) scaleOut(ctx context.Context, resourceGroup string, vmSca
 }
 
 // scaleIn drain and delete Scale Set instances to match the Autoscaler has deemed required.
-func (t *TargetPlugin) scaleIn(ctx context.Context, resourceGroup string, vmScaleSet string, num int64, config map[string]string) error {
+func (t *TargetPlugin) scaleIn(ctx context.Context, resourceGroup string, vmss compute.VirtualMachineScaleSet, num int64, config map[string]string) error {
+	if isFlexible(vmss) {
+		return t.scaleInFlexible(ctx, resourceGroup, vmss, num, config)
+	}
+
+	vmScaleSet := *vmss.Name
+
 	// Create a logger for this action to pre-populate useful information we
 	// would like on all log lines.
 	log := t.logger.With("action", "scale_in", "resource_group", resourceGroup, "vmss_name", vmScaleSet)
 
-	// Find instance IDs in the target VMSS and perform pre-scale tasks.
+	// Find instance IDs in the target VMSS and perform pre-scale tasks. The
+	// instances being evicted are already draining and will be stopped by
+	// Azure, so they are not selected.
+	evictions := t.evictionsWatcher()
+
 	pager, err := t.vmssVMs.List(ctx, resourceGroup, vmScaleSet,
 		"startswith(instanceView/statuses/code, 'PowerState') eq true",
 		"instanceView/statuses", "instanceView")
@@ -114,11 +130,15 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, resourceGroup string, vmScal
 		for _, vm := range pager.Values() {
 			for _, s := range *vm.VirtualMachineScaleSetVMProperties.InstanceView.Statuses {
 				if strings.HasPrefix(*s.Code, "PowerState/") {
-					if *s.Code == "PowerState/running" {
-						log.Debug("found healthy instance", "id", *vm.ID, "instance_id", *vm.InstanceID)
-						remoteIDs = append(remoteIDs, fmt.Sprintf("%s_%s", vmScaleSet, *vm.InstanceID))
-					} else {
+					remoteID := fmt.Sprintf("%s_%s", vmScaleSet, *vm.InstanceID)
+					switch {
+					case *s.Code != "PowerState/running":
 						log.Debug("skipping instance", "id", *vm.ID, "instance_id", *vm.InstanceID, "code", *s.Code)
+					case evictions.isEvicting(remoteID):
+						log.Debug("skipping instance being evicted", "id", *vm.ID, "instance_id", *vm.InstanceID)
+					default:
+						log.Debug("found healthy instance", "id", *vm.ID, "instance_id", *vm.InstanceID)
+						remoteIDs = append(remoteIDs, remoteID)
 					}
 					break
 				}
@@ -156,7 +176,7 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, resourceGroup string, vmScal
 
 	future, err := t.vmss.DeleteInstances(ctx, resourceGroup, vmScaleSet, compute.VirtualMachineScaleSetVMInstanceRequiredIDs{
 		InstanceIds: ptr.StringArrToPtr(instanceIDs),
-	})
+	}, nil)
 
 	if err != nil {
 		return fmt.Errorf("failed to scale in Azure ScaleSet: %v", err)
@@ -176,6 +196,51 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, resourceGroup string, vmScal
 	return nil
 }
 
+// scaleInFlexible drains and deletes the virtual machines of a Flexible Scale
+// Set to match what the Autoscaler has deemed required.
+func (t *TargetPlugin) scaleInFlexible(ctx context.Context, resourceGroup string, vmss compute.VirtualMachineScaleSet, num int64, config map[string]string) error {
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "resource_group", resourceGroup,
+		"vmss_name", *vmss.Name, "orchestration_mode", compute.OrchestrationModeFlexible)
+
+	// Find the virtual machines in the target VMSS and perform pre-scale
+	// tasks.
+	vms, err := t.listFlexibleVMs(ctx, resourceGroup, vmss)
+	if err != nil {
+		return fmt.Errorf("failed to list virtual machines in VMSS: %v", err)
+	}
+	remoteIDs := excludeEvicting(flexibleRemoteIDs(vms), t.evictionsWatcher())
+	log.Debug("found healthy instances", "instances", remoteIDs)
+
+	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, remoteIDs, int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	names := make([]string, 0, len(ids))
+	for _, node := range ids {
+		names = append(names, node.RemoteResourceID)
+	}
+
+	// Delete the drained virtual machines, which also lowers the capacity of
+	// the Scale Set.
+	log.Debug("deleting Azure ScaleSet virtual machines", "instances", names)
+
+	if err := t.deleteFlexibleVMs(ctx, resourceGroup, names); err != nil {
+		return fmt.Errorf("failed to scale in Azure ScaleSet: %v", err)
+	}
+
+	log.Info("successfully deleted Azure ScaleSet virtual machines")
+
+	// Run any post scale in tasks that are desired.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		return fmt.Errorf("failed to perform post-scale Nomad scale in tasks: %v", err)
+	}
+
+	return nil
+}
+
 // azureNodeIDMap is used to identify the Azure InstanceID of a Nomad node using
 // the relevant attribute value.
 func azureNodeIDMap(n *api.Node) (string, error) {
@@ -0,0 +1,122 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-12-01/compute"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// provisioningStateSucceeded is the provisioning state of resources which are
+// not being created, updated or deleted.
+const provisioningStateSucceeded = "Succeeded"
+
+// isFlexible returns whether the Scale Set uses the Flexible orchestration
+// mode. In this mode the instances are regular virtual machines, so they are
+// listed and deleted using the virtual machines API rather than the Scale Set
+// VMs API.
+func isFlexible(vmss compute.VirtualMachineScaleSet) bool {
+	return vmss.VirtualMachineScaleSetProperties != nil &&
+		vmss.VirtualMachineScaleSetProperties.OrchestrationMode == compute.OrchestrationModeFlexible
+}
+
+// listFlexibleVMs returns the virtual machines which are part of the Flexible
+// Scale Set.
+func (t *TargetPlugin) listFlexibleVMs(ctx context.Context, resourceGroup string, vmss compute.VirtualMachineScaleSet) ([]compute.VirtualMachine, error) {
+	filter := fmt.Sprintf("'virtualMachineScaleSet/id' eq '%s'", *vmss.ID)
+
+	pager, err := t.vms.List(ctx, resourceGroup, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var vms []compute.VirtualMachine
+	for pager.NotDone() {
+		vms = append(vms, pager.Values()...)
+
+		if err := pager.NextWithContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return vms, nil
+}
+
+// flexibleRemoteIDs returns the names of the virtual machines which can be
+// selected for scale in. Instances of a Flexible Scale Set are identified by
+// their virtual machine name, which is also the value Nomad fingerprints.
+func flexibleRemoteIDs(vms []compute.VirtualMachine) []string {
+	remoteIDs := []string{}
+	for _, vm := range vms {
+		if vm.Name == nil || vm.VirtualMachineProperties == nil || vm.VirtualMachineProperties.ProvisioningState == nil {
+			continue
+		}
+		if strings.EqualFold(*vm.VirtualMachineProperties.ProvisioningState, provisioningStateSucceeded) {
+			remoteIDs = append(remoteIDs, *vm.Name)
+		}
+	}
+	return remoteIDs
+}
+
+// excludeEvicting returns the virtual machine names which are not being
+// evicted.
+func excludeEvicting(names []string, evictions *scheduledEventsWatcher) []string {
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if !evictions.isEvicting(name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// deleteFlexibleVMs deletes the virtual machines of the Flexible Scale Set and
+// waits for the deletions to complete. Deletions are started concurrently so
+// removing several instances does not take several times as long.
+func (t *TargetPlugin) deleteFlexibleVMs(ctx context.Context, resourceGroup string, names []string) error {
+	futures := make([]compute.VirtualMachinesDeleteFuture, 0, len(names))
+
+	for _, name := range names {
+		future, err := t.vms.Delete(ctx, resourceGroup, name, nil)
+		if err != nil {
+			return fmt.Errorf("failed to delete virtual machine %s: %v", name, err)
+		}
+		futures = append(futures, future)
+	}
+
+	for i, future := range futures {
+		if err := future.WaitForCompletionRef(ctx, t.vms.Client); err != nil {
+			return fmt.Errorf("failed to delete virtual machine %s: %v", names[i], err)
+		}
+	}
+	return nil
+}
+
+// countFlexibleEvictions returns the number of virtual machines of a Flexible
+// Scale Set which are being evicted.
+func countFlexibleEvictions(vms []compute.VirtualMachine, evictions *scheduledEventsWatcher) int64 {
+	var count int64
+	for _, vm := range vms {
+		if vm.Name != nil && evictions.isEvicting(*vm.Name) {
+			count++
+		}
+	}
+	return count
+}
+
+// processFlexibleVMs updates the status object based on the provisioning state
+// of the virtual machines of a Flexible Scale Set, which does not provide a
+// Scale Set wide instance view summary.
+func processFlexibleVMs(vms []compute.VirtualMachine, status *sdk.TargetStatus) {
+	for _, vm := range vms {
+		if vm.VirtualMachineProperties == nil || vm.VirtualMachineProperties.ProvisioningState == nil ||
+			!strings.EqualFold(*vm.VirtualMachineProperties.ProvisioningState, provisioningStateSucceeded) {
+			status.Ready = false
+			return
+		}
+	}
+}
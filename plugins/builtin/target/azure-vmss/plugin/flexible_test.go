@@ -0,0 +1,84 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-12-01/compute"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isFlexible(t *testing.T) {
+	assert.False(t, isFlexible(compute.VirtualMachineScaleSet{}))
+	assert.False(t, isFlexible(compute.VirtualMachineScaleSet{
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{
+			OrchestrationMode: compute.OrchestrationModeUniform,
+		},
+	}))
+	assert.True(t, isFlexible(compute.VirtualMachineScaleSet{
+		VirtualMachineScaleSetProperties: &compute.VirtualMachineScaleSetProperties{
+			OrchestrationMode: compute.OrchestrationModeFlexible,
+		},
+	}))
+}
+
+func Test_flexibleRemoteIDs(t *testing.T) {
+	vms := []compute.VirtualMachine{
+		flexibleVM("vmss_1a2b3c4d", "Succeeded"),
+		flexibleVM("vmss_5e6f7a8b", "Creating"),
+		flexibleVM("vmss_9c0d1e2f", "succeeded"),
+		{Name: stringToPtr("vmss_unknown")},
+	}
+	assert.Equal(t, []string{"vmss_1a2b3c4d", "vmss_9c0d1e2f"}, flexibleRemoteIDs(vms))
+	assert.Equal(t, []string{}, flexibleRemoteIDs(nil))
+}
+
+func Test_processFlexibleVMs(t *testing.T) {
+	testCases := []struct {
+		inputVMs      []compute.VirtualMachine
+		expectedReady bool
+		name          string
+	}{
+		{
+			inputVMs: []compute.VirtualMachine{
+				flexibleVM("vmss_1a2b3c4d", "Succeeded"),
+				flexibleVM("vmss_5e6f7a8b", "Succeeded"),
+			},
+			expectedReady: true,
+			name:          "all virtual machines provisioned",
+		},
+		{
+			inputVMs: []compute.VirtualMachine{
+				flexibleVM("vmss_1a2b3c4d", "Succeeded"),
+				flexibleVM("vmss_5e6f7a8b", "Deleting"),
+			},
+			expectedReady: false,
+			name:          "virtual machine being deleted",
+		},
+		{
+			inputVMs:      []compute.VirtualMachine{{Name: stringToPtr("vmss_1a2b3c4d")}},
+			expectedReady: false,
+			name:          "virtual machine without provisioning state",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := &sdk.TargetStatus{Ready: true, Meta: map[string]string{}}
+			processFlexibleVMs(tc.inputVMs, status)
+			assert.Equal(t, tc.expectedReady, status.Ready, tc.name)
+		})
+	}
+}
+
+func flexibleVM(name, state string) compute.VirtualMachine {
+	return compute.VirtualMachine{
+		Name: stringToPtr(name),
+		VirtualMachineProperties: &compute.VirtualMachineProperties{
+			ProvisioningState: stringToPtr(state),
+		},
+	}
+}
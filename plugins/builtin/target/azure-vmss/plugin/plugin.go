@@ -8,8 +8,9 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"sync"
 
-	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2020-06-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-12-01/compute"
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad-autoscaler/plugins"
 	"github.com/hashicorp/nomad-autoscaler/plugins/base"
@@ -32,6 +33,15 @@ const (
 	configKeySecretKey      = "secret_access_key"
 	configKeyResoureGroup   = "resource_group"
 	configKeyVMSS           = "vm_scale_set"
+
+	// configKeyScheduledEvents enables watching the Scheduled Events API for
+	// Spot evictions. It is set within the plugin config, as the events of
+	// all the Scale Sets are watched by a single watcher.
+	configKeyScheduledEvents = "scheduled_events_enabled"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueScheduledEventsDefault = "false"
 )
 
 var (
@@ -54,10 +64,18 @@ type TargetPlugin struct {
 	logger  hclog.Logger
 	vmss    compute.VirtualMachineScaleSetsClient
 	vmssVMs compute.VirtualMachineScaleSetVMsClient
+	vms     compute.VirtualMachinesClient
 
 	// clusterUtils provides general cluster scaling utilities for querying the
 	// state of nodes pools and performing scaling tasks.
 	clusterUtils *scaleutils.ClusterScaleUtils
+
+	// evictions watches the Scheduled Events API for Spot evictions. It is nil
+	// unless enabled by the operator. The lock protects the watcher and the
+	// function used to stop it, as SetConfig may be called again on reload.
+	evictions       *scheduledEventsWatcher
+	evictionsCancel context.CancelFunc
+	evictionsLock   sync.RWMutex
 }
 
 // NewAzureVMSSPlugin returns the Azure VMSS implementation of the target.Target
@@ -86,6 +104,48 @@ func (t *TargetPlugin) SetConfig(config map[string]string) error {
 	t.clusterUtils = clusterUtils
 	t.clusterUtils.ClusterNodeIDLookupFunc = azureNodeIDMap
 
+	enabled, err := strconv.ParseBool(getConfigValue(config, configKeyScheduledEvents, configValueScheduledEventsDefault))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", configKeyScheduledEvents, err)
+	}
+	t.watchEvictions(enabled)
+
+	return nil
+}
+
+// watchEvictions starts watching the Scheduled Events API for Spot evictions,
+// replacing any existing watcher, or stops watching if disabled.
+func (t *TargetPlugin) watchEvictions(enabled bool) {
+	t.evictionsLock.Lock()
+	defer t.evictionsLock.Unlock()
+
+	if t.evictionsCancel != nil {
+		t.evictionsCancel()
+		t.evictions, t.evictionsCancel = nil, nil
+	}
+	if !enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.evictions = newScheduledEventsWatcher(t.logger, scheduledEventsEndpoint, t.drainEvictedNodes)
+	t.evictionsCancel = cancel
+
+	go t.evictions.run(ctx)
+}
+
+// evictionsWatcher returns the current Scheduled Events watcher, which is nil
+// if watching is disabled.
+func (t *TargetPlugin) evictionsWatcher() *scheduledEventsWatcher {
+	t.evictionsLock.RLock()
+	defer t.evictionsLock.RUnlock()
+	return t.evictions
+}
+
+// Close stops watching the Scheduled Events API. It implements io.Closer so
+// the plugin manager stops the watcher when the plugin is removed.
+func (t *TargetPlugin) Close() error {
+	t.watchEvictions(false)
 	return nil
 }
 
@@ -112,12 +172,25 @@ func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string)
 	}
 	ctx := context.Background()
 
-	currVMSS, err := t.vmss.Get(ctx, resourceGroup, vmScaleSet)
+	currVMSS, err := t.vmss.Get(ctx, resourceGroup, vmScaleSet, "")
 	if err != nil {
 		return fmt.Errorf("failed to get Azure vmss: %v", err)
 	}
 
-	capacity := ptr.PtrToInt64(currVMSS.Sku.Capacity)
+	// Flexible Scale Sets can only be scaled when they define a virtual
+	// machine profile, otherwise they have no capacity.
+	if currVMSS.Sku == nil {
+		return fmt.Errorf("Azure vmss %s has no capacity, Flexible scale sets require a virtual machine profile", vmScaleSet)
+	}
+
+	// Virtual machines being evicted are excluded from the count reported by
+	// Status, so they must also be excluded here for the action count to be
+	// compared against the same value.
+	evicting, err := t.evictingCount(ctx, resourceGroup, vmScaleSet, currVMSS)
+	if err != nil {
+		return err
+	}
+	capacity := ptr.PtrToInt64(currVMSS.Sku.Capacity) - evicting
 
 	// The Azure VMSS target requires different details depending on which
 	// direction we want to scale. Therefore calculate the direction and the
@@ -126,9 +199,11 @@ func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string)
 
 	switch direction {
 	case "in":
-		err = t.scaleIn(ctx, resourceGroup, vmScaleSet, num, config)
+		err = t.scaleIn(ctx, resourceGroup, currVMSS, num, config)
 	case "out":
-		err = t.scaleOut(ctx, resourceGroup, vmScaleSet, num)
+		// The evicted virtual machines are part of the Scale Set capacity
+		// until Azure stops them, so are added to the desired capacity.
+		err = t.scaleOut(ctx, resourceGroup, vmScaleSet, num+evicting)
 	default:
 		t.logger.Info("scaling not required", "resource_group", resourceGroup, "vmss", vmScaleSet,
 			"current_count", capacity, "strategy_count", action.Count)
@@ -169,14 +244,12 @@ func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, erro
 
 	ctx := context.Background()
 
-	vmss, err := t.vmss.Get(ctx, resourceGroup, vmScaleSet)
+	vmss, err := t.vmss.Get(ctx, resourceGroup, vmScaleSet, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Azure ScaleSet: %v", err)
 	}
-
-	instanceView, err := t.vmss.GetInstanceView(ctx, resourceGroup, vmScaleSet)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get Azure ScaleSet Instance View: %v", err)
+	if vmss.Sku == nil {
+		return nil, fmt.Errorf("Azure ScaleSet %s has no capacity, Flexible scale sets require a virtual machine profile", vmScaleSet)
 	}
 
 	// Set our initial status.
@@ -186,11 +259,51 @@ func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, erro
 		Meta:  make(map[string]string),
 	}
 
+	// Spot virtual machines being evicted are excluded from the count, so the
+	// capacity they provide is replaced without waiting for them to stop.
+	evictions := t.evictionsWatcher()
+
+	// Flexible Scale Sets do not summarise the state of their instances, so
+	// check the virtual machines directly.
+	if isFlexible(vmss) {
+		vms, err := t.listFlexibleVMs(ctx, resourceGroup, vmss)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Azure ScaleSet virtual machines: %v", err)
+		}
+		processFlexibleVMs(vms, &resp)
+		resp.Count -= countFlexibleEvictions(vms, evictions)
+		return &resp, nil
+	}
+	resp.Count -= evictions.evictingCount(vmScaleSet)
+
+	instanceView, err := t.vmss.GetInstanceView(ctx, resourceGroup, vmScaleSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure ScaleSet Instance View: %v", err)
+	}
+
 	processInstanceView(instanceView, &resp)
 
 	return &resp, nil
 }
 
+// evictingCount returns the number of virtual machines within the Scale Set
+// which are being evicted, or zero if evictions are not watched.
+func (t *TargetPlugin) evictingCount(ctx context.Context, resourceGroup, vmScaleSet string, vmss compute.VirtualMachineScaleSet) (int64, error) {
+	evictions := t.evictionsWatcher()
+	if evictions == nil {
+		return 0, nil
+	}
+	if !isFlexible(vmss) {
+		return evictions.evictingCount(vmScaleSet), nil
+	}
+
+	vms, err := t.listFlexibleVMs(ctx, resourceGroup, vmss)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list Azure ScaleSet virtual machines: %v", err)
+	}
+	return countFlexibleEvictions(vms, evictions), nil
+}
+
 func (t *TargetPlugin) calculateDirection(vmssDesired, strategyDesired int64) (int64, string) {
 
 	if strategyDesired < vmssDesired {
@@ -229,3 +342,12 @@ func processInstanceView(instanceView compute.VirtualMachineScaleSetInstanceView
 		}
 	}
 }
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
@@ -4,13 +4,21 @@
 package plugin
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2020-06-01/compute"
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-12-01/compute"
 	"github.com/Azure/go-autorest/autorest/date"
+	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTargetPlugin_calculateDirection(t *testing.T) {
@@ -208,3 +216,60 @@ func int32ToPtr(v int32) *int32 {
 func stringToPtr(v string) *string {
 	return &v
 }
+
+func TestTargetPlugin_evictedCapacity(t *testing.T) {
+	var updates []int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/nodes":
+			_, _ = w.Write([]byte(`[{"ID":"node-1","NodeClass":"clients","Status":"ready"}]`))
+		case strings.HasSuffix(r.URL.Path, "/instanceView"):
+			_, _ = w.Write([]byte(`{"virtualMachine":{"statusesSummary":[{"code":"ProvisioningState/succeeded","count":5}]},"statuses":[]}`))
+		case r.Method == http.MethodPatch:
+			var update compute.VirtualMachineScaleSetUpdate
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&update))
+			updates = append(updates, *update.Sku.Capacity)
+			_, _ = w.Write([]byte(`{"properties":{"provisioningState":"Succeeded"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"name":"clients","sku":{"capacity":5},"properties":{}}`))
+		}
+	}))
+	defer srv.Close()
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(&api.Config{Address: srv.URL}, hclog.NewNullLogger())
+	require.NoError(t, err)
+
+	evictions := newScheduledEventsWatcher(hclog.NewNullLogger(), "", nil)
+	evictions.evicting["clients_2"] = struct{}{}
+	evictions.evicting["clients_4"] = struct{}{}
+
+	tp := &TargetPlugin{
+		logger:       hclog.NewNullLogger(),
+		vmss:         compute.NewVirtualMachineScaleSetsClientWithBaseURI(srv.URL, "subscription"),
+		clusterUtils: clusterUtils,
+		evictions:    evictions,
+	}
+	tp.vmss.PollingDelay = 0
+
+	cfg := map[string]string{
+		configKeyResoureGroup:    "group",
+		configKeyVMSS:            "clients",
+		sdk.TargetConfigKeyClass: "clients",
+	}
+
+	// The virtual machines being evicted are excluded from the count.
+	status, err := tp.Status(cfg)
+	require.NoError(t, err)
+	assert.True(t, status.Ready)
+	assert.Equal(t, int64(3), status.Count)
+
+	// Scaling to the reported count does not change the Scale Set.
+	require.NoError(t, tp.Scale(sdk.ScalingAction{Count: status.Count}, cfg))
+	assert.Empty(t, updates)
+
+	// Scaling out keeps the capacity of the virtual machines being evicted
+	// until Azure stops them.
+	require.NoError(t, tp.Scale(sdk.ScalingAction{Count: status.Count + 1}, cfg))
+	assert.Equal(t, []int64{6}, updates)
+}
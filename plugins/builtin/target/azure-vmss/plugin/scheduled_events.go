@@ -0,0 +1,259 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// scheduledEventsEndpoint is the Azure Instance Metadata Service endpoint
+	// of the Scheduled Events API. Events are delivered to all the virtual
+	// machines of the same Scale Set placement group, so the autoscaler must
+	// run on one of them to observe the evictions.
+	scheduledEventsEndpoint = "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01"
+
+	// scheduledEventsPollInterval is the interval at which the Scheduled
+	// Events API is polled. Spot evictions are signalled at least 30 seconds
+	// in advance, so the interval needs to leave time to drain the node.
+	scheduledEventsPollInterval = 5 * time.Second
+
+	// scheduledEventsTimeout is the timeout of each Scheduled Events API
+	// request.
+	scheduledEventsTimeout = 5 * time.Second
+
+	// scheduledEventTypePreempt is the type of the events signalling that a
+	// Spot virtual machine is being evicted.
+	scheduledEventTypePreempt = "Preempt"
+
+	// evictionMinDrainDeadline is the drain deadline used when the eviction
+	// is already due, giving allocations a chance to stop gracefully.
+	evictionMinDrainDeadline = 5 * time.Second
+)
+
+// scheduledEvents is the document returned by the Scheduled Events API.
+type scheduledEvents struct {
+	DocumentIncarnation int              `json:"DocumentIncarnation"`
+	Events              []scheduledEvent `json:"Events"`
+}
+
+// scheduledEvent is a single event returned by the Scheduled Events API. The
+// resources are the names of the virtual machines affected by the event.
+type scheduledEvent struct {
+	EventID      string   `json:"EventId"`
+	EventType    string   `json:"EventType"`
+	ResourceType string   `json:"ResourceType"`
+	Resources    []string `json:"Resources"`
+	EventStatus  string   `json:"EventStatus"`
+	NotBefore    string   `json:"NotBefore"`
+}
+
+// drainDeadline returns the drain deadline of the nodes affected by the
+// event, which is the time remaining before the event starts.
+func (e *scheduledEvent) drainDeadline(now time.Time) time.Duration {
+	notBefore, err := time.Parse(time.RFC1123, e.NotBefore)
+	if err != nil {
+		return evictionMinDrainDeadline
+	}
+	if d := notBefore.Sub(now); d > evictionMinDrainDeadline {
+		return d
+	}
+	return evictionMinDrainDeadline
+}
+
+// evictionDrainFunc drains the Nomad nodes running on the virtual machines,
+// identified by their name, within the deadline.
+type evictionDrainFunc func(ctx context.Context, names []string, deadline time.Duration) error
+
+// scheduledEventsWatcher polls the Scheduled Events API for Spot evictions,
+// draining the Nomad nodes of the virtual machines being evicted and
+// tracking them so they can be excluded from the Scale Set counts.
+type scheduledEventsWatcher struct {
+	client   *http.Client
+	endpoint string
+	logger   hclog.Logger
+	drainFn  evictionDrainFunc
+
+	lock sync.Mutex
+
+	// evicting is the set of virtual machine names currently being evicted.
+	evicting map[string]struct{}
+
+	// drained is the set of event IDs whose nodes have been drained, so each
+	// eviction only triggers a single drain.
+	drained map[string]struct{}
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+// newScheduledEventsWatcher returns a new scheduledEventsWatcher using the
+// drain function to drain the nodes being evicted.
+func newScheduledEventsWatcher(log hclog.Logger, endpoint string, drainFn evictionDrainFunc) *scheduledEventsWatcher {
+	return &scheduledEventsWatcher{
+		client:   &http.Client{Timeout: scheduledEventsTimeout},
+		endpoint: endpoint,
+		logger:   log.Named("scheduled_events"),
+		drainFn:  drainFn,
+		evicting: make(map[string]struct{}),
+		drained:  make(map[string]struct{}),
+		now:      time.Now,
+	}
+}
+
+// run polls the Scheduled Events API until the context is cancelled.
+func (w *scheduledEventsWatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(scheduledEventsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Warn("failed to poll Scheduled Events", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the current events and processes them.
+func (w *scheduledEventsWatcher) poll(ctx context.Context) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	var doc scheduledEvents
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	w.process(ctx, doc.Events)
+	return nil
+}
+
+// process updates the virtual machines being evicted from the current events,
+// triggering the drain of the Nomad nodes affected by new evictions. Events
+// are removed from the API once they complete, so the evicting set only ever
+// contains the current evictions.
+func (w *scheduledEventsWatcher) process(ctx context.Context, events []scheduledEvent) {
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	evicting := make(map[string]struct{})
+	current := make(map[string]struct{})
+
+	for _, event := range events {
+		if event.EventType != scheduledEventTypePreempt {
+			continue
+		}
+		current[event.EventID] = struct{}{}
+
+		for _, name := range event.Resources {
+			evicting[strings.ToLower(name)] = struct{}{}
+		}
+
+		if _, ok := w.drained[event.EventID]; ok {
+			continue
+		}
+		w.drained[event.EventID] = struct{}{}
+
+		deadline := event.drainDeadline(w.now())
+		w.logger.Info("draining Nomad nodes of evicted virtual machines",
+			"event_id", event.EventID, "instances", event.Resources, "deadline", deadline)
+
+		go func(names []string) {
+			if err := w.drainFn(ctx, names, deadline); err != nil {
+				w.logger.Error("failed to drain Nomad nodes of evicted virtual machines",
+					"instances", names, "error", err)
+			}
+		}(event.Resources)
+	}
+
+	for id := range w.drained {
+		if _, ok := current[id]; !ok {
+			delete(w.drained, id)
+		}
+	}
+	w.evicting = evicting
+}
+
+// isEvicting returns whether the virtual machine is being evicted. Virtual
+// machine names are case insensitive. A nil scheduledEventsWatcher never
+// reports evictions.
+func (w *scheduledEventsWatcher) isEvicting(name string) bool {
+	if w == nil {
+		return false
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, ok := w.evicting[strings.ToLower(name)]
+	return ok
+}
+
+// evictingCount returns the number of virtual machines within the Scale Set
+// which are being evicted. Virtual machines of uniform Scale Sets are named
+// using the "{scale-set-name}_{instance-id}" format.
+func (w *scheduledEventsWatcher) evictingCount(vmScaleSet string) int64 {
+	if w == nil {
+		return 0
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var count int64
+	for name := range w.evicting {
+		if idx := strings.LastIndex(name, "_"); idx != -1 && strings.EqualFold(name[0:idx], vmScaleSet) {
+			count++
+		}
+	}
+	return count
+}
+
+// drainEvictedNodes drains the Nomad nodes running on the virtual machines
+// being evicted, so their allocations are rescheduled before the virtual
+// machines are stopped.
+func (t *TargetPlugin) drainEvictedNodes(ctx context.Context, names []string, deadline time.Duration) error {
+
+	ids, err := t.clusterUtils.IdentifyNodesByRemoteIDs(names)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		t.logger.Debug("no Nomad nodes found for evicted virtual machines", "instances", names)
+		return nil
+	}
+
+	return t.clusterUtils.DrainNodes(ctx, map[string]string{
+		sdk.TargetConfigKeyDrainDeadline: deadline.String(),
+	}, ids)
+}
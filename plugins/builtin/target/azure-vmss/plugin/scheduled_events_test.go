@@ -0,0 +1,142 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2021-12-01/compute"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDrains records the calls made to the eviction drain function.
+type testDrains struct {
+	lock      sync.Mutex
+	names     [][]string
+	deadlines []time.Duration
+}
+
+func (d *testDrains) drain(_ context.Context, names []string, deadline time.Duration) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.names = append(d.names, names)
+	d.deadlines = append(d.deadlines, deadline)
+	return nil
+}
+
+func (d *testDrains) len() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return len(d.names)
+}
+
+func Test_scheduledEventsWatcher_poll(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	body := `{"DocumentIncarnation":2,"Events":[
+		{"EventId":"A","EventType":"Preempt","ResourceType":"VirtualMachine","Resources":["clients_2","clients_5"],"EventStatus":"Scheduled","NotBefore":"Sun, 01 Oct 2023 12:00:30 GMT"},
+		{"EventId":"B","EventType":"Reboot","ResourceType":"VirtualMachine","Resources":["clients_3"],"EventStatus":"Scheduled","NotBefore":"Sun, 01 Oct 2023 12:15:00 GMT"}
+	]}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	drains := &testDrains{}
+	w := newScheduledEventsWatcher(hclog.NewNullLogger(), srv.URL, drains.drain)
+	w.now = func() time.Time { return now }
+
+	// New evictions trigger a drain of their virtual machines.
+	require.NoError(t, w.poll(context.Background()))
+	assert.Eventually(t, func() bool { return drains.len() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, [][]string{{"clients_2", "clients_5"}}, drains.names)
+	assert.Equal(t, []time.Duration{30 * time.Second}, drains.deadlines)
+
+	assert.True(t, w.isEvicting("clients_2"))
+	assert.True(t, w.isEvicting("CLIENTS_5"))
+	assert.False(t, w.isEvicting("clients_3"), "only Preempt events are evictions")
+	assert.Equal(t, int64(2), w.evictingCount("clients"))
+	assert.Equal(t, int64(0), w.evictingCount("servers"))
+
+	// Evictions are only drained once.
+	require.NoError(t, w.poll(context.Background()))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, drains.len())
+
+	// Completed events are removed from the API.
+	body = `{"DocumentIncarnation":3,"Events":[]}`
+	require.NoError(t, w.poll(context.Background()))
+	assert.False(t, w.isEvicting("clients_2"))
+	assert.Equal(t, int64(0), w.evictingCount("clients"))
+	assert.Empty(t, w.drained)
+
+	// Unexpected responses are errors.
+	body = `not json`
+	assert.ErrorContains(t, w.poll(context.Background()), "failed to decode response")
+}
+
+func Test_scheduledEvent_drainDeadline(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		inputNotBefore string
+		expectedOutput time.Duration
+		name           string
+	}{
+		{
+			inputNotBefore: "Sun, 01 Oct 2023 12:00:30 GMT",
+			expectedOutput: 30 * time.Second,
+			name:           "eviction in the future",
+		},
+		{
+			inputNotBefore: "Sun, 01 Oct 2023 11:59:30 GMT",
+			expectedOutput: evictionMinDrainDeadline,
+			name:           "eviction already due",
+		},
+		{
+			inputNotBefore: "",
+			expectedOutput: evictionMinDrainDeadline,
+			name:           "eviction started",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := scheduledEvent{NotBefore: tc.inputNotBefore}
+			assert.Equal(t, tc.expectedOutput, event.drainDeadline(now), tc.name)
+		})
+	}
+}
+
+func Test_flexibleEvictions(t *testing.T) {
+	w := newScheduledEventsWatcher(hclog.NewNullLogger(), "", nil)
+	w.evicting = map[string]struct{}{"vm-2": {}}
+
+	vms := []compute.VirtualMachine{
+		{Name: ptr.StringToPtr("vm-1")},
+		{Name: ptr.StringToPtr("VM-2")},
+		{},
+	}
+	assert.Equal(t, int64(1), countFlexibleEvictions(vms, w))
+	assert.Equal(t, []string{"vm-1"}, excludeEvicting([]string{"vm-1", "vm-2"}, w))
+
+	// A nil watcher never reports evictions.
+	var nilWatcher *scheduledEventsWatcher
+	assert.Equal(t, int64(0), countFlexibleEvictions(vms, nilWatcher))
+	assert.Equal(t, []string{"vm-1", "vm-2"}, excludeEvicting([]string{"vm-1", "vm-2"}, nilWatcher))
+	assert.Equal(t, int64(0), nilWatcher.evictingCount("clients"))
+}
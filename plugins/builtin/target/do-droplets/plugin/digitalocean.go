@@ -0,0 +1,252 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	defaultRetryInterval = 10 * time.Second
+	nodeAttrDODropletID  = "unique.platform.digitalocean.id"
+
+	// dropletsListPerPage is the maximum page size of the Droplets list API.
+	dropletsListPerPage = 200
+
+	// dropletStatuses are the Droplet statuses used to determine which
+	// Droplets provide capacity and can be selected for scale in.
+	dropletStatusActive  = "active"
+	dropletStatusArchive = "archive"
+)
+
+// setupDOClient takes the passed config mapping and instantiates the required
+// DigitalOcean API client.
+func (t *TargetPlugin) setupDOClient(config map[string]string) error {
+
+	token, ok := config[configKeyToken]
+	if !ok || token == "" {
+		token = os.Getenv(envVarToken)
+	}
+	if token == "" {
+		return fmt.Errorf("required config param %s or environment variable %s not found", configKeyToken, envVarToken)
+	}
+
+	t.client = godo.NewFromToken(token)
+	return nil
+}
+
+// listDroplets returns all the Droplets with the tag, excluding those which
+// have been archived as they no longer provide any capacity.
+func (t *TargetPlugin) listDroplets(ctx context.Context, tag string) ([]godo.Droplet, error) {
+
+	opt := &godo.ListOptions{PerPage: dropletsListPerPage}
+
+	var droplets []godo.Droplet
+
+	for {
+		page, resp, err := t.client.Droplets.ListByTag(ctx, tag, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range page {
+			if d.Status != dropletStatusArchive {
+				droplets = append(droplets, d)
+			}
+		}
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			return droplets, nil
+		}
+
+		current, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = current + 1
+	}
+}
+
+// scaleOut creates the Droplets required to reach the count the Autoscaler
+// has deemed required, using the Droplet template from the target config.
+func (t *TargetPlugin) scaleOut(ctx context.Context, tag string, current, count int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_out", "droplet_tag", tag, "desired_count", count)
+
+	tmpl, err := newDropletTemplate(tag, config)
+	if err != nil {
+		return err
+	}
+
+	reqs, err := tmpl.createRequests(count - current)
+	if err != nil {
+		return err
+	}
+
+	for _, req := range reqs {
+		if _, _, err := t.client.Droplets.CreateMultiple(ctx, req); err != nil {
+			return fmt.Errorf("failed to create DigitalOcean Droplets: %v", err)
+		}
+		log.Debug("requested DigitalOcean Droplets", "names", req.Names)
+	}
+
+	if err := t.ensureDropletsActive(ctx, tag, count); err != nil {
+		return fmt.Errorf("failed to confirm scale out DigitalOcean Droplets: %v", err)
+	}
+
+	log.Info("successfully performed and verified scaling out")
+	return nil
+}
+
+// scaleIn drains the Nomad nodes selected for removal and then deletes their
+// Droplets.
+func (t *TargetPlugin) scaleIn(ctx context.Context, tag string, droplets []godo.Droplet, num int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "droplet_tag", tag)
+
+	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, dropletRemoteIDs(droplets), int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	if err := t.deleteDroplets(ctx, ids); err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return fmt.Errorf("failed to delete DigitalOcean Droplets: %v", err)
+	}
+
+	// Track the deletions until the Droplets no longer exist. A failure here
+	// should not fail the scaling activity as the deletion requests have been
+	// accepted.
+	if err := t.ensureDropletsDeleted(ctx, tag, ids); err != nil {
+		log.Error("failed to ensure all Droplets deleted", "error", err)
+	} else {
+		log.Debug("confirmed DigitalOcean Droplets deleted")
+	}
+
+	// The tasks run on nodes that have been successfully deleted should not
+	// cause a failure of the scaling pipeline.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		log.Error("failed to perform post-scale Nomad scale in tasks", "error", err)
+	}
+
+	log.Info("successfully performed and verified scaling in")
+	return nil
+}
+
+// deleteDroplets deletes the Droplets of the nodes. The DigitalOcean API only
+// allows deleting a single Droplet by ID per request.
+func (t *TargetPlugin) deleteDroplets(ctx context.Context, ids []scaleutils.NodeResourceID) error {
+	for _, id := range ids {
+		dropletID, err := strconv.Atoi(id.RemoteResourceID)
+		if err != nil {
+			return fmt.Errorf("invalid Droplet ID %q: %v", id.RemoteResourceID, err)
+		}
+		if _, err := t.client.Droplets.Delete(ctx, dropletID); err != nil {
+			return fmt.Errorf("failed to delete Droplet %v: %v", dropletID, err)
+		}
+	}
+	return nil
+}
+
+// ensureDropletsActive waits until the tag has the desired number of active
+// Droplets.
+func (t *TargetPlugin) ensureDropletsActive(ctx context.Context, tag string, desired int64) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		droplets, err := t.listDroplets(ctx, tag)
+		if err != nil {
+			return true, err
+		}
+
+		if n := countActive(droplets); n < int(desired) {
+			return false, fmt.Errorf("DigitalOcean Droplets at %v active of desired %v", n, desired)
+		}
+		return true, nil
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// ensureDropletsDeleted waits until none of the Droplets of the nodes are
+// listed with the tag.
+func (t *TargetPlugin) ensureDropletsDeleted(ctx context.Context, tag string, ids []scaleutils.NodeResourceID) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		droplets, err := t.listDroplets(ctx, tag)
+		if err != nil {
+			return true, err
+		}
+
+		if n := countRemaining(droplets, ids); n > 0 {
+			return false, fmt.Errorf("waiting for %v Droplets to delete", n)
+		}
+		return true, nil
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// dropletRemoteIDs returns the IDs of the Droplets which can be selected for
+// scale in.
+func dropletRemoteIDs(droplets []godo.Droplet) []string {
+	remoteIDs := []string{}
+	for _, d := range droplets {
+		if d.Status == dropletStatusActive {
+			remoteIDs = append(remoteIDs, strconv.Itoa(d.ID))
+		}
+	}
+	return remoteIDs
+}
+
+// countActive returns the number of active Droplets.
+func countActive(droplets []godo.Droplet) int {
+	var n int
+	for _, d := range droplets {
+		if d.Status == dropletStatusActive {
+			n++
+		}
+	}
+	return n
+}
+
+// countRemaining returns how many of the Droplets of the nodes are still
+// listed.
+func countRemaining(droplets []godo.Droplet, ids []scaleutils.NodeResourceID) int {
+	existing := make(map[string]struct{}, len(droplets))
+	for _, d := range droplets {
+		existing[strconv.Itoa(d.ID)] = struct{}{}
+	}
+
+	var n int
+	for _, id := range ids {
+		if _, ok := existing[id.RemoteResourceID]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// doNodeIDMap is used to identify the DigitalOcean Droplet ID of a Nomad node
+// using the relevant attribute value.
+func doNodeIDMap(n *api.Node) (string, error) {
+	val, ok := n.Attributes[nodeAttrDODropletID]
+	if !ok || val == "" {
+		return "", fmt.Errorf("attribute %q not found", nodeAttrDODropletID)
+	}
+	return val, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_doNodeIDMap(t *testing.T) {
+	testCases := []struct {
+		inputNode           *api.Node
+		expectedOutputID    string
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{"unique.platform.digitalocean.id": "362139409"},
+			},
+			expectedOutputID:    "362139409",
+			expectedOutputError: nil,
+			name:                "required attribute found",
+		},
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{},
+			},
+			expectedOutputID:    "",
+			expectedOutputError: errors.New(`attribute "unique.platform.digitalocean.id" not found`),
+			name:                "required attribute not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualID, actualErr := doNodeIDMap(tc.inputNode)
+			assert.Equal(t, tc.expectedOutputID, actualID, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_dropletRemoteIDs(t *testing.T) {
+	droplets := []godo.Droplet{
+		{ID: 1, Status: "active"},
+		{ID: 2, Status: "new"},
+		{ID: 3, Status: "off"},
+		{ID: 4, Status: "active"},
+	}
+
+	assert.Equal(t, []string{"1", "4"}, dropletRemoteIDs(droplets))
+	assert.Equal(t, 2, countActive(droplets))
+	assert.Equal(t, []string{}, dropletRemoteIDs(nil))
+}
+
+func Test_countRemaining(t *testing.T) {
+	droplets := []godo.Droplet{{ID: 1}, {ID: 2}}
+
+	ids := []scaleutils.NodeResourceID{
+		{NomadNodeID: "node-1", RemoteResourceID: "1"},
+		{NomadNodeID: "node-3", RemoteResourceID: "3"},
+	}
+
+	assert.Equal(t, 1, countRemaining(droplets, ids))
+	assert.Equal(t, 0, countRemaining(nil, ids))
+}
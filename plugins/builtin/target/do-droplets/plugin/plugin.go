@@ -0,0 +1,218 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/digitalocean/godo"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "do-droplets"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle.
+	configKeyToken         = "token"
+	configKeyTag           = "droplet_tag"
+	configKeyName          = "name"
+	configKeyRegion        = "region"
+	configKeySize          = "size"
+	configKeyImage         = "image"
+	configKeySSHKeys       = "ssh_keys"
+	configKeyTags          = "tags"
+	configKeyUserData      = "user_data"
+	configKeyVPCUUID       = "vpc_uuid"
+	configKeyIPv6          = "ipv6"
+	configKeyMonitoring    = "monitoring"
+	configKeyRetryAttempts = "retry_attempts"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueRetryAttemptsDefault = "15"
+
+	// envVarToken is the environment variable used to read the DigitalOcean
+	// API token when it is not set within the plugin config.
+	envVarToken = "DIGITALOCEAN_TOKEN"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewDODropletsPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the DigitalOcean Droplets implementation of the
+// target.Target interface. A group of Droplets is identified by a tag which is
+// added to all the Droplets the plugin creates.
+type TargetPlugin struct {
+	config map[string]string
+	logger hclog.Logger
+	client *godo.Client
+
+	// retryAttempts is the number of times operations such as wating for the
+	// Droplets to become active should be retried.
+	retryAttempts int
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools and performing scaling tasks.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewDODropletsPlugin returns the DigitalOcean Droplets implementation of the
+// target.Target interface.
+func NewDODropletsPlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	if err := t.setupDOClient(config); err != nil {
+		return err
+	}
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+
+	// Store and set the remote ID callback function.
+	t.clusterUtils = clusterUtils
+	t.clusterUtils.ClusterNodeIDLookupFunc = doNodeIDMap
+
+	retryLimit, err := strconv.Atoi(getConfigValue(config, configKeyRetryAttempts, configValueRetryAttemptsDefault))
+	if err != nil {
+		return err
+	}
+	t.retryAttempts = retryLimit
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// DigitalOcean can't support dry-run like Nomad, so just exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	// We cannot scale the Droplets without knowing the tag which identifies
+	// them.
+	tag, ok := config[configKeyTag]
+	if !ok {
+		return fmt.Errorf("required config param %s not found", configKeyTag)
+	}
+	ctx := context.Background()
+
+	droplets, err := t.listDroplets(ctx, tag)
+	if err != nil {
+		return fmt.Errorf("failed to list DigitalOcean Droplets: %v", err)
+	}
+	currentCount := int64(len(droplets))
+
+	// The Droplets require different details depending on which direction we
+	// want to scale. Therefore calculate the direction and the relevant number
+	// so we can correctly perform the DigitalOcean work.
+	num, direction := t.calculateDirection(currentCount, action.Count)
+
+	switch direction {
+	case "in":
+		err = t.scaleIn(ctx, tag, droplets, num, config)
+	case "out":
+		err = t.scaleOut(ctx, tag, currentCount, num, config)
+	default:
+		t.logger.Info("scaling not required", "droplet_tag", tag,
+			"current_count", currentCount, "strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err != nil {
+		err = fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return err
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool. If the pool is not ready, we
+	// can exit here and avoid calling the DigitalOcean API as it won't affect
+	// the outcome.
+	ready, err := t.clusterUtils.IsPoolReady(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+	}
+	if !ready {
+		return &sdk.TargetStatus{Ready: ready}, nil
+	}
+
+	tag, ok := config[configKeyTag]
+	if !ok {
+		return nil, fmt.Errorf("required config param %s not found", configKeyTag)
+	}
+
+	droplets, err := t.listDroplets(context.Background(), tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DigitalOcean Droplets: %v", err)
+	}
+
+	// The Droplets are only ready once they are all active, so the autoscaler
+	// does not act while Droplets are still being created.
+	return &sdk.TargetStatus{
+		Ready: countActive(droplets) == len(droplets),
+		Count: int64(len(droplets)),
+		Meta:  make(map[string]string),
+	}, nil
+}
+
+func (t *TargetPlugin) calculateDirection(dropletsCount, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < dropletsCount {
+		return dropletsCount - strategyDesired, "in"
+	}
+	if strategyDesired > dropletsCount {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
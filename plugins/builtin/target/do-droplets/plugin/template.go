@@ -0,0 +1,168 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+)
+
+// dropletsCreateMaxNames is the maximum number of Droplets which can be
+// created within a single multiple create request.
+const dropletsCreateMaxNames = 10
+
+// dropletTemplate holds the details used to create new Droplets when scaling
+// out.
+type dropletTemplate struct {
+	tag        string
+	name       string
+	region     string
+	size       string
+	image      godo.DropletCreateImage
+	sshKeys    []godo.DropletCreateSSHKey
+	tags       []string
+	userData   string
+	vpcUUID    string
+	ipv6       bool
+	monitoring bool
+}
+
+// newDropletTemplate builds the Droplet template from the target config.
+func newDropletTemplate(tag string, config map[string]string) (*dropletTemplate, error) {
+
+	tmpl := dropletTemplate{
+		tag:      tag,
+		name:     getConfigValue(config, configKeyName, tag),
+		userData: config[configKeyUserData],
+		vpcUUID:  config[configKeyVPCUUID],
+		tags:     []string{tag},
+	}
+
+	// The region, size and image are required by the DigitalOcean API to
+	// create a Droplet.
+	for key, dst := range map[string]*string{
+		configKeyRegion: &tmpl.region,
+		configKeySize:   &tmpl.size,
+	} {
+		v, ok := config[key]
+		if !ok || v == "" {
+			return nil, fmt.Errorf("required config param %s not found", key)
+		}
+		*dst = v
+	}
+
+	image, ok := config[configKeyImage]
+	if !ok || image == "" {
+		return nil, fmt.Errorf("required config param %s not found", configKeyImage)
+	}
+	tmpl.image = parseImage(image)
+
+	for _, key := range splitList(config[configKeySSHKeys]) {
+		tmpl.sshKeys = append(tmpl.sshKeys, parseSSHKey(key))
+	}
+
+	for _, t := range splitList(config[configKeyTags]) {
+		if t != tag {
+			tmpl.tags = append(tmpl.tags, t)
+		}
+	}
+
+	for key, dst := range map[string]*bool{
+		configKeyIPv6:       &tmpl.ipv6,
+		configKeyMonitoring: &tmpl.monitoring,
+	} {
+		v, ok := config[key]
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", key, err)
+		}
+		*dst = b
+	}
+
+	return &tmpl, nil
+}
+
+// createRequests returns the requests needed to create the number of
+// Droplets, split to honour the maximum number of names per request.
+func (d *dropletTemplate) createRequests(count int64) ([]*godo.DropletMultiCreateRequest, error) {
+
+	var reqs []*godo.DropletMultiCreateRequest
+
+	for remaining := count; remaining > 0; remaining -= dropletsCreateMaxNames {
+		n := remaining
+		if n > dropletsCreateMaxNames {
+			n = dropletsCreateMaxNames
+		}
+
+		names := make([]string, 0, n)
+		for i := int64(0); i < n; i++ {
+			name, err := d.dropletName()
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+
+		reqs = append(reqs, &godo.DropletMultiCreateRequest{
+			Names:      names,
+			Region:     d.region,
+			Size:       d.size,
+			Image:      d.image,
+			SSHKeys:    d.sshKeys,
+			IPv6:       d.ipv6,
+			Monitoring: d.monitoring,
+			UserData:   d.userData,
+			Tags:       d.tags,
+			VPCUUID:    d.vpcUUID,
+		})
+	}
+	return reqs, nil
+}
+
+// dropletName returns a unique name for a new Droplet using the name prefix
+// and a random suffix.
+func (d *dropletTemplate) dropletName() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate Droplet name: %v", err)
+	}
+	return d.name + "-" + hex.EncodeToString(b), nil
+}
+
+// parseImage returns the image reference, which is either a numeric image or
+// snapshot ID, or a public image slug.
+func parseImage(image string) godo.DropletCreateImage {
+	if id, err := strconv.Atoi(image); err == nil {
+		return godo.DropletCreateImage{ID: id}
+	}
+	return godo.DropletCreateImage{Slug: image}
+}
+
+// parseSSHKey returns the SSH key reference, which is either a numeric key ID
+// or a key fingerprint.
+func parseSSHKey(key string) godo.DropletCreateSSHKey {
+	if id, err := strconv.Atoi(key); err == nil {
+		return godo.DropletCreateSSHKey{ID: id}
+	}
+	return godo.DropletCreateSSHKey{Fingerprint: key}
+}
+
+// splitList splits a comma separated config value, ignoring empty elements.
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
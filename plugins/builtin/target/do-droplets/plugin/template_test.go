@@ -0,0 +1,133 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newDropletTemplate(t *testing.T) {
+	testCases := []struct {
+		inputConfig         map[string]string
+		expectedOutput      *dropletTemplate
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputConfig: map[string]string{
+				"region": "nyc3",
+				"size":   "s-2vcpu-4gb",
+				"image":  "ubuntu-22-04-x64",
+			},
+			expectedOutput: &dropletTemplate{
+				tag:    "nomad-client",
+				name:   "nomad-client",
+				region: "nyc3",
+				size:   "s-2vcpu-4gb",
+				image:  godo.DropletCreateImage{Slug: "ubuntu-22-04-x64"},
+				tags:   []string{"nomad-client"},
+			},
+			expectedOutputError: nil,
+			name:                "required params only",
+		},
+		{
+			inputConfig: map[string]string{
+				"name":       "client",
+				"region":     "nyc3",
+				"size":       "s-2vcpu-4gb",
+				"image":      "136361126",
+				"ssh_keys":   "512189, 3b:16:bf:e4:8b:00:8b:b8:59:8c:a9:d3:f0:19:45:fa",
+				"tags":       "nomad, nomad-client",
+				"user_data":  "#!/bin/bash",
+				"vpc_uuid":   "760e09ef-dc84-11e8-981e-3cfdfeaae000",
+				"ipv6":       "true",
+				"monitoring": "true",
+			},
+			expectedOutput: &dropletTemplate{
+				tag:    "nomad-client",
+				name:   "client",
+				region: "nyc3",
+				size:   "s-2vcpu-4gb",
+				image:  godo.DropletCreateImage{ID: 136361126},
+				sshKeys: []godo.DropletCreateSSHKey{
+					{ID: 512189},
+					{Fingerprint: "3b:16:bf:e4:8b:00:8b:b8:59:8c:a9:d3:f0:19:45:fa"},
+				},
+				tags:       []string{"nomad-client", "nomad"},
+				userData:   "#!/bin/bash",
+				vpcUUID:    "760e09ef-dc84-11e8-981e-3cfdfeaae000",
+				ipv6:       true,
+				monitoring: true,
+			},
+			expectedOutputError: nil,
+			name:                "all params",
+		},
+		{
+			inputConfig: map[string]string{
+				"region": "nyc3",
+				"size":   "s-2vcpu-4gb",
+			},
+			expectedOutput:      nil,
+			expectedOutputError: errors.New("required config param image not found"),
+			name:                "missing image",
+		},
+		{
+			inputConfig: map[string]string{
+				"region": "nyc3",
+				"size":   "s-2vcpu-4gb",
+				"image":  "ubuntu-22-04-x64",
+				"ipv6":   "maybe",
+			},
+			expectedOutput:      nil,
+			expectedOutputError: errors.New(`failed to parse ipv6: strconv.ParseBool: parsing "maybe": invalid syntax`),
+			name:                "invalid bool",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualErr := newDropletTemplate("nomad-client", tc.inputConfig)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_dropletTemplate_createRequests(t *testing.T) {
+	tmpl := dropletTemplate{
+		tag:    "nomad-client",
+		name:   "client",
+		region: "nyc3",
+		size:   "s-2vcpu-4gb",
+		image:  godo.DropletCreateImage{Slug: "ubuntu-22-04-x64"},
+		tags:   []string{"nomad-client"},
+	}
+
+	reqs, err := tmpl.createRequests(23)
+	require.NoError(t, err)
+	require.Len(t, reqs, 3)
+
+	names := map[string]struct{}{}
+	for i, expected := range []int{10, 10, 3} {
+		assert.Len(t, reqs[i].Names, expected)
+		assert.Equal(t, "nyc3", reqs[i].Region)
+		assert.Equal(t, []string{"nomad-client"}, reqs[i].Tags)
+
+		for _, name := range reqs[i].Names {
+			assert.True(t, strings.HasPrefix(name, "client-"), name)
+			names[name] = struct{}{}
+		}
+	}
+	assert.Len(t, names, 23)
+
+	reqs, err = tmpl.createRequests(0)
+	require.NoError(t, err)
+	assert.Empty(t, reqs)
+}
@@ -0,0 +1,228 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// commandWaitDelay is how long to wait for the output of a command to be
+	// closed once it has exited or been killed, as child processes may hold
+	// on to it.
+	commandWaitDelay = 5 * time.Second
+
+	// maxErrorOutputLen is the maximum length of the stderr output included
+	// within errors.
+	maxErrorOutputLen = 512
+)
+
+// commandRequest is the JSON document written to the stdin of the status and
+// count commands.
+type commandRequest struct {
+	Config map[string]string `json:"config"`
+}
+
+// scaleRequest is the JSON document written to the stdin of the scale
+// command.
+type scaleRequest struct {
+
+	// Count is the desired count of the target, and CurrentCount the count
+	// reported by the count or status command before scaling.
+	Count        int64  `json:"count"`
+	CurrentCount int64  `json:"current_count"`
+	Direction    string `json:"direction"`
+
+	Reason string                 `json:"reason"`
+	Error  bool                   `json:"error"`
+	Meta   map[string]interface{} `json:"meta"`
+
+	// Nodes are the drained Nomad nodes the command must remove when scaling
+	// in a pool of Nomad nodes.
+	Nodes []scaleNode `json:"nodes,omitempty"`
+
+	Config map[string]string `json:"config"`
+}
+
+// scaleNode identifies a Nomad node and its remote resource.
+type scaleNode struct {
+	NomadNodeID string `json:"nomad_node_id"`
+	RemoteID    string `json:"remote_id"`
+}
+
+// statusResponse is the JSON document read from the stdout of the status
+// command.
+type statusResponse struct {
+	Ready *bool             `json:"ready"`
+	Count *int64            `json:"count"`
+	Meta  map[string]string `json:"meta"`
+}
+
+func (r *statusResponse) validate() error {
+	if r.Ready == nil {
+		return errors.New("ready is required")
+	}
+	if r.Count == nil {
+		return errors.New("count is required")
+	}
+	if *r.Count < 0 {
+		return errors.New("count must not be negative")
+	}
+	return nil
+}
+
+// countResponse is the JSON document read from the stdout of the count
+// command.
+type countResponse struct {
+	Count *int64 `json:"count"`
+}
+
+// currentCount returns the current count of the target using the count
+// command, or the status command when no count command is configured.
+func (t *TargetPlugin) currentCount(ctx context.Context, config map[string]string) (int64, error) {
+
+	timeout, err := parseTimeout(config, configKeyStatusTimeout, configValueStatusTimeoutDefault)
+	if err != nil {
+		return 0, err
+	}
+	req := commandRequest{Config: config}
+
+	if command, ok := config[configKeyCountCommand]; ok && command != "" {
+		var resp countResponse
+		if err := runCommand(ctx, command, timeout, &req, &resp); err != nil {
+			return 0, fmt.Errorf("failed to run count command: %v", err)
+		}
+		if resp.Count == nil || *resp.Count < 0 {
+			return 0, errors.New("invalid count command output: count is required and must not be negative")
+		}
+		return *resp.Count, nil
+	}
+
+	if command, ok := config[configKeyStatusCommand]; ok && command != "" {
+		var resp statusResponse
+		if err := runCommand(ctx, command, timeout, &req, &resp); err != nil {
+			return 0, fmt.Errorf("failed to run status command: %v", err)
+		}
+		if err := resp.validate(); err != nil {
+			return 0, fmt.Errorf("invalid status command output: %v", err)
+		}
+		return *resp.Count, nil
+	}
+
+	return 0, fmt.Errorf("required config param %s or %s not found", configKeyCountCommand, configKeyStatusCommand)
+}
+
+// scaleIn runs the scale command to scale in the target. When the target is a
+// pool of Nomad nodes, the nodes are selected and drained first and passed to
+// the command, which is responsible for removing them.
+func (t *TargetPlugin) scaleIn(ctx context.Context, command string, timeout time.Duration, num int64, req scaleRequest, config map[string]string) error {
+
+	if !isNodePoolTarget(config) {
+		return runCommand(ctx, command, timeout, &req, nil)
+	}
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "command", command)
+
+	ids, err := t.clusterUtils.RunPreScaleInTasks(ctx, config, int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	// Fewer nodes than requested may have been identified, so the desired
+	// count is adjusted to match the nodes removed.
+	req.Nodes = make([]scaleNode, 0, len(ids))
+	for _, id := range ids {
+		req.Nodes = append(req.Nodes, scaleNode{NomadNodeID: id.NomadNodeID, RemoteID: id.RemoteResourceID})
+	}
+	req.Count = req.CurrentCount - int64(len(ids))
+
+	if err := runCommand(ctx, command, timeout, &req, nil); err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return err
+	}
+
+	// The tasks run on nodes that have been successfully removed should not
+	// cause a failure of the scaling pipeline.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		log.Error("failed to perform post-scale Nomad scale in tasks", "error", err)
+	}
+
+	log.Info("successfully performed scaling in")
+	return nil
+}
+
+// runCommand runs the command, writing the JSON encoded input to its stdin.
+// When output is not nil, the stdout of the command is decoded into it. The
+// command is killed if it does not exit within the timeout.
+func runCommand(ctx context.Context, command string, timeout time.Duration, input, output interface{}) error {
+
+	in, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to encode command input: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = commandWaitDelay
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("command %s timed out after %s", command, timeout)
+		}
+		if msg := errorOutput(stderr.String()); msg != "" {
+			return fmt.Errorf("command %s failed: %v: %s", command, err, msg)
+		}
+		return fmt.Errorf("command %s failed: %v", command, err)
+	}
+
+	if output == nil {
+		return nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), output); err != nil {
+		return fmt.Errorf("failed to decode command %s output: %v", command, err)
+	}
+	return nil
+}
+
+// errorOutput returns the trimmed stderr output of a command, truncated to
+// its last maxErrorOutputLen bytes which are most likely to hold the cause.
+func errorOutput(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if len(stderr) > maxErrorOutputLen {
+		stderr = "..." + stderr[len(stderr)-maxErrorOutputLen:]
+	}
+	return stderr
+}
+
+// nodeIDMapFunc returns the function used to identify the remote resource of
+// a Nomad node using the value of the attribute.
+func nodeIDMapFunc(attr string) func(*api.Node) (string, error) {
+	return func(n *api.Node) (string, error) {
+		val, ok := n.Attributes[attr]
+		if !ok || val == "" {
+			return "", fmt.Errorf("attribute %q not found", attr)
+		}
+		return val, nil
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes an executable shell script to a temporary directory and
+// returns its path.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755))
+	return path
+}
+
+func Test_runCommand(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("output decoded", func(t *testing.T) {
+		cmd := writeScript(t, `cat`)
+
+		var out commandRequest
+		err := runCommand(ctx, cmd, time.Second, &commandRequest{Config: map[string]string{"k": "v"}}, &out)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"k": "v"}, out.Config)
+	})
+
+	t.Run("failure includes stderr", func(t *testing.T) {
+		cmd := writeScript(t, `echo "no capacity" >&2; exit 3`)
+
+		err := runCommand(ctx, cmd, time.Second, &commandRequest{}, nil)
+		assert.EqualError(t, err, "command "+cmd+" failed: exit status 3: no capacity")
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		cmd := writeScript(t, `echo "not json"`)
+
+		var out countResponse
+		err := runCommand(ctx, cmd, time.Second, &commandRequest{}, &out)
+		require.Error(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "failed to decode command "+cmd+" output"))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cmd := writeScript(t, `exec sleep 10`)
+
+		err := runCommand(ctx, cmd, 100*time.Millisecond, &commandRequest{}, nil)
+		assert.EqualError(t, err, "command "+cmd+" timed out after 100ms")
+	})
+}
+
+func Test_errorOutput(t *testing.T) {
+	assert.Equal(t, "", errorOutput(" \n"))
+	assert.Equal(t, "failed", errorOutput("failed\n"))
+
+	actual := errorOutput(strings.Repeat("a", maxErrorOutputLen) + "cause")
+	assert.Len(t, actual, maxErrorOutputLen+3)
+	assert.True(t, strings.HasSuffix(actual, "cause"))
+}
+
+func TestTargetPlugin_Status(t *testing.T) {
+	tp := NewExecPlugin(hclog.NewNullLogger())
+
+	t.Run("status command", func(t *testing.T) {
+		cmd := writeScript(t, `echo '{"ready": false, "count": 3, "meta": {"state": "scaling"}}'`)
+
+		actual, err := tp.Status(map[string]string{"exec_status_command": cmd})
+		require.NoError(t, err)
+		assert.Equal(t, &sdk.TargetStatus{Ready: false, Count: 3, Meta: map[string]string{"state": "scaling"}}, actual)
+	})
+
+	t.Run("count command", func(t *testing.T) {
+		cmd := writeScript(t, `echo '{"count": 2}'`)
+
+		actual, err := tp.Status(map[string]string{"exec_count_command": cmd})
+		require.NoError(t, err)
+		assert.Equal(t, &sdk.TargetStatus{Ready: true, Count: 2, Meta: map[string]string{}}, actual)
+	})
+
+	t.Run("status command missing count", func(t *testing.T) {
+		cmd := writeScript(t, `echo '{"ready": true}'`)
+
+		_, err := tp.Status(map[string]string{"exec_status_command": cmd})
+		assert.EqualError(t, err, "invalid status command output: count is required")
+	})
+
+	t.Run("no commands", func(t *testing.T) {
+		_, err := tp.Status(map[string]string{})
+		assert.EqualError(t, err, "required config param exec_count_command or exec_status_command not found")
+	})
+}
+
+func TestTargetPlugin_Scale(t *testing.T) {
+	tp := NewExecPlugin(hclog.NewNullLogger())
+
+	input := filepath.Join(t.TempDir(), "input.json")
+	config := map[string]string{
+		"exec_count_command": writeScript(t, `echo '{"count": 2}'`),
+		"exec_scale_command": writeScript(t, `cat > `+input),
+	}
+
+	err := tp.Scale(sdk.ScalingAction{Count: 5, Reason: "scale out", Direction: sdk.ScaleDirectionUp}, config)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(input)
+	require.NoError(t, err)
+
+	var actual scaleRequest
+	require.NoError(t, json.Unmarshal(data, &actual))
+	assert.Equal(t, scaleRequest{
+		Count:        5,
+		CurrentCount: 2,
+		Direction:    "out",
+		Reason:       "scale out",
+		Config:       config,
+	}, actual)
+
+	config["exec_scale_command"] = writeScript(t, `exit 1`)
+	err = tp.Scale(sdk.ScalingAction{Count: 1}, config)
+	assert.EqualError(t, err, "failed to perform scaling action: command "+config["exec_scale_command"]+" failed: exit status 1")
+}
+
+func Test_nodeIDMapFunc(t *testing.T) {
+	fn := nodeIDMapFunc("unique.platform.id")
+
+	actual, err := fn(&api.Node{Attributes: map[string]string{"unique.platform.id": "vm-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "vm-1", actual)
+
+	_, err = fn(&api.Node{Attributes: map[string]string{}})
+	assert.EqualError(t, err, `attribute "unique.platform.id" not found`)
+}
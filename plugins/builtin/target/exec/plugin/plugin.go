@@ -0,0 +1,245 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "exec"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle. The remote ID
+	// attribute is set within the plugin config, while the commands and
+	// timeouts are set within the policy target config.
+	configKeyRemoteIDAttribute = "exec_remote_id_attribute"
+	configKeyScaleCommand      = "exec_scale_command"
+	configKeyStatusCommand     = "exec_status_command"
+	configKeyCountCommand      = "exec_count_command"
+	configKeyScaleTimeout      = "exec_scale_timeout"
+	configKeyStatusTimeout     = "exec_status_timeout"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueRemoteIDAttributeDefault = "unique.hostname"
+	configValueScaleTimeoutDefault      = "10m"
+	configValueStatusTimeoutDefault     = "30s"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewExecPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the exec implementation of the target.Target interface. It
+// runs operator supplied commands to scale and query the status of a target,
+// exchanging JSON documents with them over stdin and stdout.
+type TargetPlugin struct {
+	config map[string]string
+	logger hclog.Logger
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools and performing scaling tasks. It is only used when
+	// the target is a pool of Nomad nodes.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewExecPlugin returns the exec implementation of the target.Target
+// interface.
+func NewExecPlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+
+	// Store and set the remote ID callback function.
+	t.clusterUtils = clusterUtils
+	t.clusterUtils.ClusterNodeIDLookupFunc = nodeIDMapFunc(
+		getConfigValue(config, configKeyRemoteIDAttribute, configValueRemoteIDAttributeDefault))
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// The commands are not expected to support dry-run like Nomad, so just
+	// exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	command, ok := config[configKeyScaleCommand]
+	if !ok || command == "" {
+		return fmt.Errorf("required config param %s not found", configKeyScaleCommand)
+	}
+
+	timeout, err := parseTimeout(config, configKeyScaleTimeout, configValueScaleTimeoutDefault)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	currentCount, err := t.currentCount(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	num, direction := t.calculateDirection(currentCount, action.Count)
+
+	req := scaleRequest{
+		Count:        action.Count,
+		CurrentCount: currentCount,
+		Direction:    direction,
+		Reason:       action.Reason,
+		Error:        action.Error,
+		Meta:         action.Meta,
+		Config:       config,
+	}
+
+	switch direction {
+	case "in":
+		err = t.scaleIn(ctx, command, timeout, num, req, config)
+	case "out":
+		err = runCommand(ctx, command, timeout, &req, nil)
+	default:
+		t.logger.Info("scaling not required", "current_count", currentCount,
+			"strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err != nil {
+		err = fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return err
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool when the target is a pool of
+	// nodes. If the pool is not ready, we can exit here and avoid running the
+	// command as it won't affect the outcome.
+	if isNodePoolTarget(config) {
+		ready, err := t.clusterUtils.IsPoolReady(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+		}
+		if !ready {
+			return &sdk.TargetStatus{Ready: ready}, nil
+		}
+	}
+
+	timeout, err := parseTimeout(config, configKeyStatusTimeout, configValueStatusTimeoutDefault)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	// Without a status command, the target is always considered ready and
+	// only its count is queried.
+	command, ok := config[configKeyStatusCommand]
+	if !ok || command == "" {
+		count, err := t.currentCount(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		return &sdk.TargetStatus{Ready: true, Count: count, Meta: make(map[string]string)}, nil
+	}
+
+	var resp statusResponse
+	if err := runCommand(ctx, command, timeout, &commandRequest{Config: config}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to run status command: %v", err)
+	}
+	if err := resp.validate(); err != nil {
+		return nil, fmt.Errorf("invalid status command output: %v", err)
+	}
+
+	status := sdk.TargetStatus{
+		Ready: *resp.Ready,
+		Count: *resp.Count,
+		Meta:  resp.Meta,
+	}
+	if status.Meta == nil {
+		status.Meta = make(map[string]string)
+	}
+	return &status, nil
+}
+
+func (t *TargetPlugin) calculateDirection(currentCount, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < currentCount {
+		return currentCount - strategyDesired, "in"
+	}
+	if strategyDesired > currentCount {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+// parseTimeout parses the timeout config param, which must be a positive
+// duration.
+func parseTimeout(config map[string]string, key, defaultValue string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(getConfigValue(config, key, defaultValue))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %v", key, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("%s must be positive", key)
+	}
+	return timeout, nil
+}
+
+// isNodePoolTarget returns whether the target config identifies a pool of
+// Nomad nodes, which are drained before the scale command removes them.
+func isNodePoolTarget(config map[string]string) bool {
+	return (&sdk.ScalingPolicyTarget{Config: config}).IsNodePoolTarget()
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,92 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetPlugin_calculateDirection(t *testing.T) {
+	testCases := []struct {
+		inputCurrentCount    int64
+		inputStrategyDesired int64
+		expectedOutputNum    int64
+		expectedOutputString string
+		name                 string
+	}{
+		{
+			inputCurrentCount:    10,
+			inputStrategyDesired: 11,
+			expectedOutputNum:    11,
+			expectedOutputString: "out",
+			name:                 "scale out desired",
+		},
+		{
+			inputCurrentCount:    10,
+			inputStrategyDesired: 9,
+			expectedOutputNum:    1,
+			expectedOutputString: "in",
+			name:                 "scale in desired",
+		},
+		{
+			inputCurrentCount:    10,
+			inputStrategyDesired: 10,
+			expectedOutputNum:    0,
+			expectedOutputString: "",
+			name:                 "scale not desired",
+		},
+	}
+
+	tp := TargetPlugin{}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualNum, actualString := tp.calculateDirection(tc.inputCurrentCount, tc.inputStrategyDesired)
+			assert.Equal(t, tc.expectedOutputNum, actualNum, tc.name)
+			assert.Equal(t, tc.expectedOutputString, actualString, tc.name)
+		})
+	}
+}
+
+func Test_parseTimeout(t *testing.T) {
+	testCases := []struct {
+		inputConfig         map[string]string
+		expectedOutput      time.Duration
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputConfig:    map[string]string{},
+			expectedOutput: 30 * time.Second,
+			name:           "default",
+		},
+		{
+			inputConfig:    map[string]string{"exec_status_timeout": "2m"},
+			expectedOutput: 2 * time.Minute,
+			name:           "configured",
+		},
+		{
+			inputConfig:         map[string]string{"exec_status_timeout": "0s"},
+			expectedOutputError: errors.New("exec_status_timeout must be positive"),
+			name:                "not positive",
+		},
+		{
+			inputConfig:         map[string]string{"exec_status_timeout": "soon"},
+			expectedOutputError: errors.New(`failed to parse exec_status_timeout: time: invalid duration "soon"`),
+			name:                "invalid",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualErr := parseTimeout(tc.inputConfig, configKeyStatusTimeout, configValueStatusTimeoutDefault)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
 	"github.com/hashicorp/nomad/api"
 	"github.com/mitchellh/go-homedir"
 	"google.golang.org/api/compute/v1"
@@ -22,6 +25,10 @@ const (
 	defaultRetryInterval = 10 * time.Second
 	defaultRetryLimit    = 15
 
+	// maxReportedActivities is the maximum number of recent MIG operations
+	// added to the status response.
+	maxReportedActivities = 10
+
 	// nodeAttrGCEHostname is the node attribute to use when identifying the
 	// GCE hostname of a node.
 	nodeAttrGCEHostname = "unique.platform.gce.hostname"
@@ -60,6 +67,24 @@ func (t *TargetPlugin) status(ctx context.Context, ig instanceGroup) (bool, int6
 	return ig.status(ctx, t.service)
 }
 
+// activities returns the most recent operations of the MIG as target
+// activities. Listing operations requires additional permissions, so errors
+// are logged rather than failing the status call.
+func (t *TargetPlugin) activities(ctx context.Context, ig instanceGroup) []sdk.TargetActivity {
+	ops, err := ig.listOperations(ctx, t.service, maxReportedActivities)
+	if err != nil {
+		t.logger.Warn("failed to list GCE Managed Instance Group operations",
+			"instance_group", ig.getName(), "error", err)
+		return nil
+	}
+
+	activities := make([]sdk.TargetActivity, 0, len(ops))
+	for _, op := range ops {
+		activities = append(activities, operationActivity(op))
+	}
+	return activities
+}
+
 func (t *TargetPlugin) scaleOut(ctx context.Context, ig instanceGroup, num int64) error {
 	log := t.logger.With("action", "scale_out", "instance_group", ig.getName())
 	if err := ig.resize(ctx, t.service, num); err != nil {
@@ -72,6 +97,10 @@ func (t *TargetPlugin) scaleOut(ctx context.Context, ig instanceGroup, num int64
 	return nil
 }
 
+// scaleIn drains the Nomad nodes selected for removal and deletes their
+// instances from the MIG using deleteInstances, so GCE does not pick which
+// instances to remove. Instances with per-instance configs are only considered
+// when scale_in_stateful_instances is enabled, to avoid losing their state.
 func (t *TargetPlugin) scaleIn(ctx context.Context, group instanceGroup, num int64, config map[string]string) error {
 	// Create a logger for this action to pre-populate useful information we
 	// would like on all log lines.
@@ -83,19 +112,16 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, group instanceGroup, num int
 		return fmt.Errorf("failed to list GCE MIG instances: %v", err)
 	}
 
-	remoteIDs := []string{}
-	for _, inst := range instances {
-		if inst.InstanceStatus == "RUNNING" && inst.CurrentAction == "NONE" {
-			log.Debug("found healthy instance", "instance_id", inst.Id, "instance", inst.Instance)
-
-			// Use the partial URL since that's what gceNodeIDMap returns.
-			idx := strings.Index(inst.Instance, "/zones/")
-			remoteIDs = append(remoteIDs, inst.Instance[idx+1:])
-		} else {
-			log.Debug("skipping instance", "instance_id", inst.Id, "instance", inst.Instance, "instance_status", inst.InstanceStatus, "current_action", inst.CurrentAction)
+	includeStateful := false
+	if v, ok := t.getValue(config, configKeyScaleInStateful); ok {
+		includeStateful, err = strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", configKeyScaleInStateful, err)
 		}
 	}
 
+	remoteIDs := scaleInCandidates(log, instances, includeStateful)
+
 	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, remoteIDs, int(num))
 	if err != nil {
 		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
@@ -132,6 +158,29 @@ func (t *TargetPlugin) scaleIn(ctx context.Context, group instanceGroup, num int
 	return nil
 }
 
+// scaleInCandidates returns the partial URLs of the instances which can be
+// selected for scale in.
+func scaleInCandidates(log hclog.Logger, instances []*compute.ManagedInstance, includeStateful bool) []string {
+	remoteIDs := []string{}
+	for _, inst := range instances {
+		if inst.InstanceStatus != "RUNNING" || inst.CurrentAction != "NONE" {
+			log.Debug("skipping instance", "instance_id", inst.Id, "instance", inst.Instance, "instance_status", inst.InstanceStatus, "current_action", inst.CurrentAction)
+			continue
+		}
+		if !includeStateful && hasPreservedState(inst) {
+			log.Debug("skipping stateful instance", "instance_id", inst.Id, "instance", inst.Instance)
+			continue
+		}
+
+		log.Debug("found healthy instance", "instance_id", inst.Id, "instance", inst.Instance)
+
+		// Use the partial URL since that's what gceNodeIDMap returns.
+		idx := strings.Index(inst.Instance, "/zones/")
+		remoteIDs = append(remoteIDs, inst.Instance[idx+1:])
+	}
+	return remoteIDs
+}
+
 func (t *TargetPlugin) ensureInstanceGroupIsStable(ctx context.Context, group instanceGroup) error {
 
 	f := func(ctx context.Context) (bool, error) {
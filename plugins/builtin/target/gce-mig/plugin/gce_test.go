@@ -7,8 +7,10 @@ import (
 	"errors"
 	"testing"
 
+	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad/api"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
 )
 
 func Test_gceNodeIDMap(t *testing.T) {
@@ -68,3 +70,60 @@ func Test_gceNodeIDMap(t *testing.T) {
 		})
 	}
 }
+
+func Test_scaleInCandidates(t *testing.T) {
+	healthy := &compute.ManagedInstance{
+		Instance:       "https://www.googleapis.com/compute/v1/projects/p/zones/us-central1-f/instances/instance-1",
+		InstanceStatus: "RUNNING",
+		CurrentAction:  "NONE",
+	}
+	stateful := &compute.ManagedInstance{
+		Instance:       "https://www.googleapis.com/compute/v1/projects/p/zones/us-central1-f/instances/instance-2",
+		InstanceStatus: "RUNNING",
+		CurrentAction:  "NONE",
+		PreservedStateFromConfig: &compute.PreservedState{
+			Disks: map[string]compute.PreservedStatePreservedDisk{"data": {Source: "disk-2"}},
+		},
+	}
+	recreating := &compute.ManagedInstance{
+		Instance:       "https://www.googleapis.com/compute/v1/projects/p/zones/us-central1-f/instances/instance-3",
+		InstanceStatus: "RUNNING",
+		CurrentAction:  "RECREATING",
+	}
+
+	testCases := []struct {
+		inputInstances       []*compute.ManagedInstance
+		inputIncludeStateful bool
+		expectedOutput       []string
+		name                 string
+	}{
+		{
+			inputInstances:       []*compute.ManagedInstance{healthy, stateful, recreating},
+			inputIncludeStateful: false,
+			expectedOutput:       []string{"zones/us-central1-f/instances/instance-1"},
+			name:                 "stateful instances excluded",
+		},
+		{
+			inputInstances:       []*compute.ManagedInstance{healthy, stateful, recreating},
+			inputIncludeStateful: true,
+			expectedOutput: []string{
+				"zones/us-central1-f/instances/instance-1",
+				"zones/us-central1-f/instances/instance-2",
+			},
+			name: "stateful instances included",
+		},
+		{
+			inputInstances:       []*compute.ManagedInstance{recreating},
+			inputIncludeStateful: true,
+			expectedOutput:       []string{},
+			name:                 "no healthy instances",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput := scaleInCandidates(hclog.NewNullLogger(), tc.inputInstances, tc.inputIncludeStateful)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+		})
+	}
+}
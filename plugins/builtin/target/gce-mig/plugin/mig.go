@@ -5,16 +5,24 @@ package plugin
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/nomad-autoscaler/sdk"
 	"google.golang.org/api/compute/v1"
 )
 
+// operationStatusDone is the status of completed MIG operations.
+const operationStatusDone = "DONE"
+
 type instanceGroup interface {
 	getName() string
 	status(ctx context.Context, service *compute.Service) (bool, int64, error)
 	listInstances(ctx context.Context, service *compute.Service) ([]*compute.ManagedInstance, error)
 	resize(ctx context.Context, service *compute.Service, num int64) error
 	deleteInstance(ctx context.Context, service *compute.Service, instanceIDs []string) error
+	listOperations(ctx context.Context, service *compute.Service, max int64) ([]*compute.Operation, error)
 }
 
 type regionalInstanceGroup struct {
@@ -38,15 +46,21 @@ func (z *zonalInstanceGroup) status(ctx context.Context, service *compute.Servic
 	if err != nil {
 		return false, -1, err
 	}
-	return mig.Status.IsStable, mig.TargetSize, nil
+	return isStable(mig.Status), mig.TargetSize, nil
 }
 
 func (z *zonalInstanceGroup) listInstances(ctx context.Context, service *compute.Service) ([]*compute.ManagedInstance, error) {
-	instances, err := service.InstanceGroupManagers.ListManagedInstances(z.project, z.zone, z.name).Context(ctx).Do()
+	var instances []*compute.ManagedInstance
+
+	err := service.InstanceGroupManagers.ListManagedInstances(z.project, z.zone, z.name).Pages(ctx,
+		func(resp *compute.InstanceGroupManagersListManagedInstancesResponse) error {
+			instances = append(instances, resp.ManagedInstances...)
+			return nil
+		})
 	if err != nil {
 		return nil, err
 	}
-	return instances.ManagedInstances, nil
+	return instances, nil
 }
 
 func (z *zonalInstanceGroup) resize(ctx context.Context, service *compute.Service, num int64) error {
@@ -63,6 +77,18 @@ func (z *zonalInstanceGroup) deleteInstance(ctx context.Context, service *comput
 	return err
 }
 
+func (z *zonalInstanceGroup) listOperations(ctx context.Context, service *compute.Service, max int64) ([]*compute.Operation, error) {
+	resp, err := service.ZoneOperations.List(z.project, z.zone).
+		Filter(operationsFilter(z.name)).
+		OrderBy("creationTimestamp desc").
+		MaxResults(max).
+		Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
 func (r *regionalInstanceGroup) getName() string {
 	return r.name
 }
@@ -72,15 +98,21 @@ func (r *regionalInstanceGroup) status(ctx context.Context, service *compute.Ser
 	if err != nil {
 		return false, -1, err
 	}
-	return mig.Status.IsStable, mig.TargetSize, nil
+	return isStable(mig.Status), mig.TargetSize, nil
 }
 
 func (r *regionalInstanceGroup) listInstances(ctx context.Context, service *compute.Service) ([]*compute.ManagedInstance, error) {
-	instances, err := service.RegionInstanceGroupManagers.ListManagedInstances(r.project, r.region, r.name).Context(ctx).Do()
+	var instances []*compute.ManagedInstance
+
+	err := service.RegionInstanceGroupManagers.ListManagedInstances(r.project, r.region, r.name).Pages(ctx,
+		func(resp *compute.RegionInstanceGroupManagersListInstancesResponse) error {
+			instances = append(instances, resp.ManagedInstances...)
+			return nil
+		})
 	if err != nil {
 		return nil, err
 	}
-	return instances.ManagedInstances, nil
+	return instances, nil
 }
 
 func (r *regionalInstanceGroup) resize(ctx context.Context, service *compute.Service, num int64) error {
@@ -96,3 +128,81 @@ func (r *regionalInstanceGroup) deleteInstance(ctx context.Context, service *com
 	_, err := service.RegionInstanceGroupManagers.DeleteInstances(r.project, r.region, r.name, request).Context(ctx).Do()
 	return err
 }
+
+func (r *regionalInstanceGroup) listOperations(ctx context.Context, service *compute.Service, max int64) ([]*compute.Operation, error) {
+	resp, err := service.RegionOperations.List(r.project, r.region).
+		Filter(operationsFilter(r.name)).
+		OrderBy("creationTimestamp desc").
+		MaxResults(max).
+		Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// operationsFilter returns the filter matching the operations targeting the
+// named MIG. The filter value is a regular expression matching the entire
+// target link.
+func operationsFilter(name string) string {
+	return fmt.Sprintf("targetLink eq .*/instanceGroupManagers/%s", name)
+}
+
+// operationActivity converts a MIG operation to a target activity. Done
+// operations which include errors have failed.
+func operationActivity(op *compute.Operation) sdk.TargetActivity {
+
+	a := sdk.TargetActivity{
+		ID:          op.Name,
+		Status:      sdk.TargetActivityStatusInProgress,
+		Description: op.Description,
+		Message:     op.StatusMessage,
+	}
+	if a.Description == "" {
+		a.Description = op.OperationType
+	}
+
+	if op.Status == operationStatusDone {
+		a.Status = sdk.TargetActivityStatusSuccessful
+
+		if op.Error != nil && len(op.Error.Errors) > 0 {
+			a.Status = sdk.TargetActivityStatusFailed
+
+			msgs := make([]string, 0, len(op.Error.Errors))
+			for _, e := range op.Error.Errors {
+				msgs = append(msgs, fmt.Sprintf("%s: %s", e.Code, e.Message))
+			}
+			a.Message = strings.Join(msgs, "; ")
+		}
+	}
+
+	start := op.StartTime
+	if start == "" {
+		start = op.InsertTime
+	}
+	a.StartTime, _ = time.Parse(time.RFC3339, start)
+	a.EndTime, _ = time.Parse(time.RFC3339, op.EndTime)
+
+	return a
+}
+
+// isStable returns whether the MIG has no ongoing actions. Stateful MIGs are
+// only stable once all their per-instance configs have been applied, so the
+// instances hold the expected preserved state.
+func isStable(status *compute.InstanceGroupManagerStatus) bool {
+	if status == nil || !status.IsStable {
+		return false
+	}
+	if s := status.Stateful; s != nil && s.PerInstanceConfigs != nil {
+		return s.PerInstanceConfigs.AllEffective
+	}
+	return true
+}
+
+// hasPreservedState returns whether the instance has a per-instance config
+// holding preserved state. Deleting the instance also deletes its per-instance
+// config and, depending on the auto-delete rules, its stateful disks.
+func hasPreservedState(inst *compute.ManagedInstance) bool {
+	ps := inst.PreservedStateFromConfig
+	return ps != nil && (len(ps.Disks) > 0 || len(ps.Metadata) > 0)
+}
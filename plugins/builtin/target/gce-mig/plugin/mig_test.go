@@ -0,0 +1,140 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/compute/v1"
+)
+
+func Test_isStable(t *testing.T) {
+	testCases := []struct {
+		inputStatus    *compute.InstanceGroupManagerStatus
+		expectedOutput bool
+		name           string
+	}{
+		{
+			inputStatus:    nil,
+			expectedOutput: false,
+			name:           "nil status",
+		},
+		{
+			inputStatus:    &compute.InstanceGroupManagerStatus{IsStable: false},
+			expectedOutput: false,
+			name:           "not stable",
+		},
+		{
+			inputStatus:    &compute.InstanceGroupManagerStatus{IsStable: true},
+			expectedOutput: true,
+			name:           "stable stateless",
+		},
+		{
+			inputStatus: &compute.InstanceGroupManagerStatus{
+				IsStable: true,
+				Stateful: &compute.InstanceGroupManagerStatusStateful{
+					HasStatefulConfig:  true,
+					PerInstanceConfigs: &compute.InstanceGroupManagerStatusStatefulPerInstanceConfigs{AllEffective: true},
+				},
+			},
+			expectedOutput: true,
+			name:           "stable stateful with effective configs",
+		},
+		{
+			inputStatus: &compute.InstanceGroupManagerStatus{
+				IsStable: true,
+				Stateful: &compute.InstanceGroupManagerStatusStateful{
+					HasStatefulConfig:  true,
+					PerInstanceConfigs: &compute.InstanceGroupManagerStatusStatefulPerInstanceConfigs{AllEffective: false},
+				},
+			},
+			expectedOutput: false,
+			name:           "stable stateful with pending configs",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, isStable(tc.inputStatus), tc.name)
+		})
+	}
+}
+
+func Test_operationActivity(t *testing.T) {
+	start := time.Date(2020, time.April, 13, 8, 4, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+
+	testCases := []struct {
+		inputOperation *compute.Operation
+		expectedOutput sdk.TargetActivity
+		name           string
+	}{
+		{
+			inputOperation: &compute.Operation{
+				Name:          "operation-1",
+				OperationType: "compute.instanceGroupManagers.resize",
+				Status:        "RUNNING",
+				InsertTime:    "2020-04-13T08:04:00Z",
+			},
+			expectedOutput: sdk.TargetActivity{
+				ID:          "operation-1",
+				Status:      sdk.TargetActivityStatusInProgress,
+				Description: "compute.instanceGroupManagers.resize",
+				StartTime:   start,
+			},
+			name: "running operation",
+		},
+		{
+			inputOperation: &compute.Operation{
+				Name:          "operation-2",
+				OperationType: "compute.instanceGroupManagers.resize",
+				Status:        "DONE",
+				StartTime:     "2020-04-13T08:04:00Z",
+				EndTime:       "2020-04-13T08:05:00Z",
+			},
+			expectedOutput: sdk.TargetActivity{
+				ID:          "operation-2",
+				Status:      sdk.TargetActivityStatusSuccessful,
+				Description: "compute.instanceGroupManagers.resize",
+				StartTime:   start,
+				EndTime:     end,
+			},
+			name: "successful operation",
+		},
+		{
+			inputOperation: &compute.Operation{
+				Name:          "operation-3",
+				OperationType: "compute.instanceGroupManagers.deleteInstances",
+				Description:   "delete instances",
+				Status:        "DONE",
+				StartTime:     "2020-04-13T08:04:00Z",
+				EndTime:       "2020-04-13T08:05:00Z",
+				Error: &compute.OperationError{
+					Errors: []*compute.OperationErrorErrors{
+						{Code: "QUOTA_EXCEEDED", Message: "quota exceeded"},
+						{Code: "RESOURCE_NOT_FOUND", Message: "instance not found"},
+					},
+				},
+			},
+			expectedOutput: sdk.TargetActivity{
+				ID:          "operation-3",
+				Status:      sdk.TargetActivityStatusFailed,
+				Description: "delete instances",
+				Message:     "QUOTA_EXCEEDED: quota exceeded; RESOURCE_NOT_FOUND: instance not found",
+				StartTime:   start,
+				EndTime:     end,
+			},
+			name: "failed operation",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, operationActivity(tc.inputOperation), tc.name)
+		})
+	}
+}
@@ -26,6 +26,10 @@ const (
 	configKeyRegion      = "region"
 	configKeyZone        = "zone"
 	configKeyMIGName     = "mig_name"
+
+	// configKeyScaleInStateful allows instances with per-instance configs,
+	// which hold preserved state such as disks, to be deleted on scale in.
+	configKeyScaleInStateful = "scale_in_stateful_instances"
 )
 
 var (
@@ -155,9 +159,10 @@ func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, erro
 	}
 
 	resp := sdk.TargetStatus{
-		Ready: stable,
-		Count: currentCount,
-		Meta:  make(map[string]string),
+		Ready:      stable,
+		Count:      currentCount,
+		Meta:       make(map[string]string),
+		Activities: t.activities(ctx, group),
 	}
 
 	return &resp, nil
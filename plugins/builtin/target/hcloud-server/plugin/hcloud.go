@@ -0,0 +1,253 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+const (
+	defaultRetryInterval = 10 * time.Second
+
+	// nodeAttrHostname is the attribute used to identify the server of a
+	// Nomad node. Hetzner Cloud sets the hostname of a server to its name and
+	// Nomad does not fingerprint Hetzner Cloud specific attributes.
+	nodeAttrHostname = "unique.hostname"
+
+	// serversListPerPage is the maximum page size of the servers list API.
+	serversListPerPage = 50
+)
+
+// setupHCloudClient takes the passed config mapping and instantiates the
+// required Hetzner Cloud API client.
+func (t *TargetPlugin) setupHCloudClient(config map[string]string) error {
+
+	token, ok := config[configKeyToken]
+	if !ok || token == "" {
+		token = os.Getenv(envVarToken)
+	}
+	if token == "" {
+		return fmt.Errorf("required config param %s or environment variable %s not found", configKeyToken, envVarToken)
+	}
+
+	t.client = hcloud.NewClient(hcloud.WithToken(token), hcloud.WithApplication("nomad-autoscaler", ""))
+	return nil
+}
+
+// listServers returns all the servers matching the label selector, excluding
+// those which are being deleted as they no longer provide any capacity.
+func (t *TargetPlugin) listServers(ctx context.Context, selector string) ([]*hcloud.Server, error) {
+
+	opts := hcloud.ServerListOpts{
+		ListOpts: hcloud.ListOpts{LabelSelector: selector, PerPage: serversListPerPage},
+	}
+
+	all, err := t.client.Server.AllWithOpts(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]*hcloud.Server, 0, len(all))
+	for _, s := range all {
+		if s.Status != hcloud.ServerStatusDeleting {
+			servers = append(servers, s)
+		}
+	}
+	return servers, nil
+}
+
+// scaleOut creates the servers required to reach the count the Autoscaler has
+// deemed required, using the server template from the target config.
+func (t *TargetPlugin) scaleOut(ctx context.Context, selector string, current, count int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_out", "label_selector", selector, "desired_count", count)
+
+	tmpl, err := newServerTemplate(selector, config)
+	if err != nil {
+		return err
+	}
+
+	// The Hetzner Cloud API only allows creating a single server per request.
+	for i := current; i < count; i++ {
+		opts, err := tmpl.createOpts()
+		if err != nil {
+			return err
+		}
+		if _, _, err := t.client.Server.Create(ctx, opts); err != nil {
+			return fmt.Errorf("failed to create Hetzner Cloud server: %v", err)
+		}
+		log.Debug("requested Hetzner Cloud server", "name", opts.Name)
+	}
+
+	if err := t.ensureServersRunning(ctx, selector, count); err != nil {
+		return fmt.Errorf("failed to confirm scale out Hetzner Cloud servers: %v", err)
+	}
+
+	log.Info("successfully performed and verified scaling out")
+	return nil
+}
+
+// scaleIn drains the Nomad nodes selected for removal and then deletes their
+// servers.
+func (t *TargetPlugin) scaleIn(ctx context.Context, selector string, servers []*hcloud.Server, num int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "label_selector", selector)
+
+	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, serverRemoteIDs(servers), int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	if err := t.deleteServers(ctx, servers, ids); err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return fmt.Errorf("failed to delete Hetzner Cloud servers: %v", err)
+	}
+
+	// Track the deletions until the servers no longer exist. A failure here
+	// should not fail the scaling activity as the deletion requests have been
+	// accepted.
+	if err := t.ensureServersDeleted(ctx, selector, ids); err != nil {
+		log.Error("failed to ensure all servers deleted", "error", err)
+	} else {
+		log.Debug("confirmed Hetzner Cloud servers deleted")
+	}
+
+	// The tasks run on nodes that have been successfully deleted should not
+	// cause a failure of the scaling pipeline.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		log.Error("failed to perform post-scale Nomad scale in tasks", "error", err)
+	}
+
+	log.Info("successfully performed and verified scaling in")
+	return nil
+}
+
+// deleteServers deletes the servers of the nodes, which are identified by
+// their name.
+func (t *TargetPlugin) deleteServers(ctx context.Context, servers []*hcloud.Server, ids []scaleutils.NodeResourceID) error {
+
+	byName := make(map[string]*hcloud.Server, len(servers))
+	for _, s := range servers {
+		byName[s.Name] = s
+	}
+
+	for _, id := range ids {
+		server, ok := byName[id.RemoteResourceID]
+		if !ok {
+			return fmt.Errorf("server %s not found", id.RemoteResourceID)
+		}
+		if _, _, err := t.client.Server.DeleteWithResult(ctx, server); err != nil {
+			return fmt.Errorf("failed to delete server %s: %v", server.Name, err)
+		}
+	}
+	return nil
+}
+
+// ensureServersRunning waits until the pool has the desired number of running
+// servers.
+func (t *TargetPlugin) ensureServersRunning(ctx context.Context, selector string, desired int64) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		servers, err := t.listServers(ctx, selector)
+		if err != nil {
+			return true, err
+		}
+
+		if n := countRunning(servers); n < int(desired) {
+			return false, fmt.Errorf("Hetzner Cloud servers at %v running of desired %v", n, desired)
+		}
+		return true, nil
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// ensureServersDeleted waits until none of the servers of the nodes are part
+// of the pool.
+func (t *TargetPlugin) ensureServersDeleted(ctx context.Context, selector string, ids []scaleutils.NodeResourceID) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		servers, err := t.listServers(ctx, selector)
+		if err != nil {
+			return true, err
+		}
+
+		if n := countRemaining(servers, ids); n > 0 {
+			return false, fmt.Errorf("waiting for %v servers to delete", n)
+		}
+		return true, nil
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// serverRemoteIDs returns the names of the servers which can be selected for
+// scale in.
+func serverRemoteIDs(servers []*hcloud.Server) []string {
+	remoteIDs := []string{}
+	for _, s := range servers {
+		if s.Status == hcloud.ServerStatusRunning {
+			remoteIDs = append(remoteIDs, s.Name)
+		}
+	}
+	return remoteIDs
+}
+
+// countRunning returns the number of running servers.
+func countRunning(servers []*hcloud.Server) int {
+	var n int
+	for _, s := range servers {
+		if s.Status == hcloud.ServerStatusRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// countRemaining returns how many of the servers of the nodes are still part
+// of the pool.
+func countRemaining(servers []*hcloud.Server, ids []scaleutils.NodeResourceID) int {
+	existing := make(map[string]struct{}, len(servers))
+	for _, s := range servers {
+		existing[s.Name] = struct{}{}
+	}
+
+	var n int
+	for _, id := range ids {
+		if _, ok := existing[id.RemoteResourceID]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// hcloudNodeIDMap is used to identify the Hetzner Cloud server name of a Nomad
+// node using its hostname.
+func hcloudNodeIDMap(n *api.Node) (string, error) {
+	val, ok := n.Attributes[nodeAttrHostname]
+	if !ok || val == "" {
+		return "", fmt.Errorf("attribute %q not found", nodeAttrHostname)
+	}
+
+	// Servers may be configured to use a fully qualified hostname, so only
+	// use the first label which holds the server name.
+	if idx := strings.Index(val, "."); idx != -1 {
+		val = val[:idx]
+	}
+	return val, nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_hcloudNodeIDMap(t *testing.T) {
+	testCases := []struct {
+		inputNode           *api.Node
+		expectedOutputID    string
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{"unique.hostname": "nomad-client-1a2b3c4d"},
+			},
+			expectedOutputID:    "nomad-client-1a2b3c4d",
+			expectedOutputError: nil,
+			name:                "required attribute found",
+		},
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{"unique.hostname": "nomad-client-1a2b3c4d.example.com"},
+			},
+			expectedOutputID:    "nomad-client-1a2b3c4d",
+			expectedOutputError: nil,
+			name:                "fully qualified hostname",
+		},
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{},
+			},
+			expectedOutputID:    "",
+			expectedOutputError: errors.New(`attribute "unique.hostname" not found`),
+			name:                "required attribute not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualID, actualErr := hcloudNodeIDMap(tc.inputNode)
+			assert.Equal(t, tc.expectedOutputID, actualID, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_serverRemoteIDs(t *testing.T) {
+	servers := []*hcloud.Server{
+		{Name: "client-1", Status: hcloud.ServerStatusRunning},
+		{Name: "client-2", Status: hcloud.ServerStatusInitializing},
+		{Name: "client-3", Status: hcloud.ServerStatusOff},
+		{Name: "client-4", Status: hcloud.ServerStatusRunning},
+	}
+
+	assert.Equal(t, []string{"client-1", "client-4"}, serverRemoteIDs(servers))
+	assert.Equal(t, 2, countRunning(servers))
+	assert.Equal(t, []string{}, serverRemoteIDs(nil))
+}
+
+func Test_countRemaining(t *testing.T) {
+	servers := []*hcloud.Server{{Name: "client-1"}, {Name: "client-2"}}
+
+	ids := []scaleutils.NodeResourceID{
+		{NomadNodeID: "node-1", RemoteResourceID: "client-1"},
+		{NomadNodeID: "node-3", RemoteResourceID: "client-3"},
+	}
+
+	assert.Equal(t, 1, countRemaining(servers, ids))
+	assert.Equal(t, 0, countRemaining(nil, ids))
+}
@@ -0,0 +1,218 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "hcloud-server"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle.
+	configKeyToken          = "hcloud_token"
+	configKeyLabelSelector  = "hcloud_label_selector"
+	configKeyNamePrefix     = "hcloud_name_prefix"
+	configKeyLocation       = "hcloud_location"
+	configKeyServerType     = "hcloud_server_type"
+	configKeyImage          = "hcloud_image"
+	configKeyUserData       = "hcloud_user_data"
+	configKeySSHKeys        = "hcloud_ssh_keys"
+	configKeyNetworks       = "hcloud_networks"
+	configKeyFirewalls      = "hcloud_firewalls"
+	configKeyPlacementGroup = "hcloud_placement_group"
+	configKeyLabels         = "hcloud_labels"
+	configKeyRetryAttempts  = "retry_attempts"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueRetryAttemptsDefault = "15"
+
+	// envVarToken is the environment variable used to read the Hetzner Cloud
+	// API token when it is not set within the plugin config.
+	envVarToken = "HCLOUD_TOKEN"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewHCloudServerPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the Hetzner Cloud server implementation of the
+// target.Target interface. A pool of servers is identified by a label
+// selector, and the servers the plugin creates are labelled so they match it.
+type TargetPlugin struct {
+	config map[string]string
+	logger hclog.Logger
+	client *hcloud.Client
+
+	// retryAttempts is the number of times operations such as wating for the
+	// servers to start should be retried.
+	retryAttempts int
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools and performing scaling tasks.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewHCloudServerPlugin returns the Hetzner Cloud server implementation of
+// the target.Target interface.
+func NewHCloudServerPlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	if err := t.setupHCloudClient(config); err != nil {
+		return err
+	}
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+
+	// Store and set the remote ID callback function.
+	t.clusterUtils = clusterUtils
+	t.clusterUtils.ClusterNodeIDLookupFunc = hcloudNodeIDMap
+
+	retryLimit, err := strconv.Atoi(getConfigValue(config, configKeyRetryAttempts, configValueRetryAttemptsDefault))
+	if err != nil {
+		return err
+	}
+	t.retryAttempts = retryLimit
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// Hetzner Cloud can't support dry-run like Nomad, so just exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	// We cannot scale the pool without knowing the label selector which
+	// identifies its servers.
+	selector, ok := config[configKeyLabelSelector]
+	if !ok {
+		return fmt.Errorf("required config param %s not found", configKeyLabelSelector)
+	}
+	ctx := context.Background()
+
+	servers, err := t.listServers(ctx, selector)
+	if err != nil {
+		return fmt.Errorf("failed to list Hetzner Cloud servers: %v", err)
+	}
+	currentCount := int64(len(servers))
+
+	// The pool requires different details depending on which direction we
+	// want to scale. Therefore calculate the direction and the relevant number
+	// so we can correctly perform the Hetzner Cloud work.
+	num, direction := t.calculateDirection(currentCount, action.Count)
+
+	switch direction {
+	case "in":
+		err = t.scaleIn(ctx, selector, servers, num, config)
+	case "out":
+		err = t.scaleOut(ctx, selector, currentCount, num, config)
+	default:
+		t.logger.Info("scaling not required", "label_selector", selector,
+			"current_count", currentCount, "strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err != nil {
+		err = fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return err
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool. If the pool is not ready, we
+	// can exit here and avoid calling the Hetzner Cloud API as it won't affect
+	// the outcome.
+	ready, err := t.clusterUtils.IsPoolReady(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+	}
+	if !ready {
+		return &sdk.TargetStatus{Ready: ready}, nil
+	}
+
+	selector, ok := config[configKeyLabelSelector]
+	if !ok {
+		return nil, fmt.Errorf("required config param %s not found", configKeyLabelSelector)
+	}
+
+	servers, err := t.listServers(context.Background(), selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Hetzner Cloud servers: %v", err)
+	}
+
+	// The pool is only ready once all its servers are running, so the
+	// autoscaler does not act while servers are still being created.
+	return &sdk.TargetStatus{
+		Ready: countRunning(servers) == len(servers),
+		Count: int64(len(servers)),
+		Meta:  make(map[string]string),
+	}, nil
+}
+
+func (t *TargetPlugin) calculateDirection(serversCount, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < serversCount {
+		return serversCount - strategyDesired, "in"
+	}
+	if strategyDesired > serversCount {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
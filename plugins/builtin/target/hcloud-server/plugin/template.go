@@ -0,0 +1,198 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// serverTemplate holds the details used to create new servers when scaling
+// out.
+type serverTemplate struct {
+	namePrefix     string
+	location       string
+	serverType     string
+	image          *hcloud.Image
+	userData       string
+	sshKeys        []*hcloud.SSHKey
+	networks       []*hcloud.Network
+	firewalls      []*hcloud.ServerCreateFirewall
+	placementGroup *hcloud.PlacementGroup
+	labels         map[string]string
+}
+
+// newServerTemplate builds the server template from the target config. The
+// servers are labelled using the equality requirements of the label selector,
+// so they are part of the pool once created, along with any additional labels.
+func newServerTemplate(selector string, config map[string]string) (*serverTemplate, error) {
+
+	tmpl := serverTemplate{
+		location: config[configKeyLocation],
+		userData: config[configKeyUserData],
+	}
+
+	// The name prefix, server type and image are required by the Hetzner
+	// Cloud API to create a server.
+	for key, dst := range map[string]*string{
+		configKeyNamePrefix: &tmpl.namePrefix,
+		configKeyServerType: &tmpl.serverType,
+	} {
+		v, ok := config[key]
+		if !ok || v == "" {
+			return nil, fmt.Errorf("required config param %s not found", key)
+		}
+		*dst = v
+	}
+
+	// The server name is used as its hostname, which Nomad nodes are mapped
+	// to using only the first label of the hostname.
+	if strings.Contains(tmpl.namePrefix, ".") {
+		return nil, fmt.Errorf("%s must not contain dots", configKeyNamePrefix)
+	}
+
+	image, ok := config[configKeyImage]
+	if !ok || image == "" {
+		return nil, fmt.Errorf("required config param %s not found", configKeyImage)
+	}
+	if id, err := strconv.ParseInt(image, 10, 64); err == nil {
+		tmpl.image = &hcloud.Image{ID: id}
+	} else {
+		tmpl.image = &hcloud.Image{Name: image}
+	}
+
+	sshKeys, err := parseIDs(config, configKeySSHKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range sshKeys {
+		tmpl.sshKeys = append(tmpl.sshKeys, &hcloud.SSHKey{ID: id})
+	}
+
+	networks, err := parseIDs(config, configKeyNetworks)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range networks {
+		tmpl.networks = append(tmpl.networks, &hcloud.Network{ID: id})
+	}
+
+	firewalls, err := parseIDs(config, configKeyFirewalls)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range firewalls {
+		tmpl.firewalls = append(tmpl.firewalls, &hcloud.ServerCreateFirewall{Firewall: hcloud.Firewall{ID: id}})
+	}
+
+	if v, ok := config[configKeyPlacementGroup]; ok && v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", configKeyPlacementGroup, err)
+		}
+		tmpl.placementGroup = &hcloud.PlacementGroup{ID: id}
+	}
+
+	tmpl.labels, err = parseLabels(config[configKeyLabels])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", configKeyLabels, err)
+	}
+
+	selectorLabels := selectorEqualityLabels(selector)
+	if len(selectorLabels) == 0 {
+		return nil, fmt.Errorf("%s must contain at least one equality requirement to label new servers", configKeyLabelSelector)
+	}
+	for k, v := range selectorLabels {
+		tmpl.labels[k] = v
+	}
+
+	return &tmpl, nil
+}
+
+// createOpts returns the options used to create a single server.
+func (s *serverTemplate) createOpts() (hcloud.ServerCreateOpts, error) {
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return hcloud.ServerCreateOpts{}, fmt.Errorf("failed to generate server name: %v", err)
+	}
+
+	opts := hcloud.ServerCreateOpts{
+		Name:           s.namePrefix + "-" + hex.EncodeToString(b),
+		ServerType:     &hcloud.ServerType{Name: s.serverType},
+		Image:          s.image,
+		UserData:       s.userData,
+		SSHKeys:        s.sshKeys,
+		Networks:       s.networks,
+		Firewalls:      s.firewalls,
+		PlacementGroup: s.placementGroup,
+		Labels:         s.labels,
+	}
+	if s.location != "" {
+		opts.Location = &hcloud.Location{Name: s.location}
+	}
+	return opts, nil
+}
+
+// selectorEqualityLabels returns the labels of the equality requirements of
+// the label selector, such as "role=nomad-client" or "role==nomad-client".
+// Other requirements cannot be translated into labels and are ignored.
+func selectorEqualityLabels(selector string) map[string]string {
+	labels := map[string]string{}
+	for _, term := range splitList(selector) {
+		if strings.Contains(term, "!=") {
+			continue
+		}
+		k, v, ok := strings.Cut(term, "==")
+		if !ok {
+			k, v, ok = strings.Cut(term, "=")
+		}
+		if ok && k != "" {
+			labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return labels
+}
+
+// parseLabels parses a comma separated list of key=value labels.
+func parseLabels(v string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, term := range splitList(v) {
+		k, v, ok := strings.Cut(term, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", term)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
+
+// parseIDs parses a comma separated list of numeric resource IDs.
+func parseIDs(config map[string]string, key string) ([]int64, error) {
+	var ids []int64
+	for _, s := range splitList(config[key]) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", key, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// splitList splits a comma separated config value, ignoring empty elements.
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
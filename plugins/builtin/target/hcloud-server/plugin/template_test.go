@@ -0,0 +1,144 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newServerTemplate(t *testing.T) {
+	testCases := []struct {
+		inputSelector       string
+		inputConfig         map[string]string
+		expectedOutput      *serverTemplate
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputSelector: "role=nomad-client",
+			inputConfig: map[string]string{
+				"hcloud_name_prefix": "client",
+				"hcloud_server_type": "cx21",
+				"hcloud_image":       "ubuntu-22.04",
+			},
+			expectedOutput: &serverTemplate{
+				namePrefix: "client",
+				serverType: "cx21",
+				image:      &hcloud.Image{Name: "ubuntu-22.04"},
+				labels:     map[string]string{"role": "nomad-client"},
+			},
+			expectedOutputError: nil,
+			name:                "required params only",
+		},
+		{
+			inputSelector: "role==nomad-client,env!=dev,managed",
+			inputConfig: map[string]string{
+				"hcloud_name_prefix":     "client",
+				"hcloud_location":        "fsn1",
+				"hcloud_server_type":     "cx21",
+				"hcloud_image":           "114690387",
+				"hcloud_user_data":       "#cloud-config",
+				"hcloud_ssh_keys":        "1, 2",
+				"hcloud_networks":        "3",
+				"hcloud_firewalls":       "4",
+				"hcloud_placement_group": "5",
+				"hcloud_labels":          "env=prod,managed=nomad",
+			},
+			expectedOutput: &serverTemplate{
+				namePrefix:     "client",
+				location:       "fsn1",
+				serverType:     "cx21",
+				image:          &hcloud.Image{ID: 114690387},
+				userData:       "#cloud-config",
+				sshKeys:        []*hcloud.SSHKey{{ID: 1}, {ID: 2}},
+				networks:       []*hcloud.Network{{ID: 3}},
+				firewalls:      []*hcloud.ServerCreateFirewall{{Firewall: hcloud.Firewall{ID: 4}}},
+				placementGroup: &hcloud.PlacementGroup{ID: 5},
+				labels:         map[string]string{"role": "nomad-client", "env": "prod", "managed": "nomad"},
+			},
+			expectedOutputError: nil,
+			name:                "all params",
+		},
+		{
+			inputSelector: "role=nomad-client",
+			inputConfig: map[string]string{
+				"hcloud_name_prefix": "client",
+				"hcloud_server_type": "cx21",
+			},
+			expectedOutput:      nil,
+			expectedOutputError: errors.New("required config param hcloud_image not found"),
+			name:                "missing image",
+		},
+		{
+			inputSelector: "role=nomad-client",
+			inputConfig: map[string]string{
+				"hcloud_name_prefix": "client.example.com",
+				"hcloud_server_type": "cx21",
+				"hcloud_image":       "ubuntu-22.04",
+			},
+			expectedOutput:      nil,
+			expectedOutputError: errors.New("hcloud_name_prefix must not contain dots"),
+			name:                "name prefix with dots",
+		},
+		{
+			inputSelector: "role=nomad-client",
+			inputConfig: map[string]string{
+				"hcloud_name_prefix": "client",
+				"hcloud_server_type": "cx21",
+				"hcloud_image":       "ubuntu-22.04",
+				"hcloud_ssh_keys":    "my-key",
+			},
+			expectedOutput:      nil,
+			expectedOutputError: errors.New(`failed to parse hcloud_ssh_keys: strconv.ParseInt: parsing "my-key": invalid syntax`),
+			name:                "invalid ssh key ID",
+		},
+		{
+			inputSelector: "role!=nomad-server",
+			inputConfig: map[string]string{
+				"hcloud_name_prefix": "client",
+				"hcloud_server_type": "cx21",
+				"hcloud_image":       "ubuntu-22.04",
+			},
+			expectedOutput:      nil,
+			expectedOutputError: errors.New("hcloud_label_selector must contain at least one equality requirement to label new servers"),
+			name:                "selector without equality requirement",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualErr := newServerTemplate(tc.inputSelector, tc.inputConfig)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_serverTemplate_createOpts(t *testing.T) {
+	tmpl := serverTemplate{
+		namePrefix: "client",
+		location:   "fsn1",
+		serverType: "cx21",
+		image:      &hcloud.Image{Name: "ubuntu-22.04"},
+		labels:     map[string]string{"role": "nomad-client"},
+	}
+
+	opts, err := tmpl.createOpts()
+	require.NoError(t, err)
+	require.NoError(t, opts.Validate())
+
+	assert.True(t, strings.HasPrefix(opts.Name, "client-"), opts.Name)
+	assert.Equal(t, &hcloud.Location{Name: "fsn1"}, opts.Location)
+	assert.Equal(t, map[string]string{"role": "nomad-client"}, opts.Labels)
+
+	other, err := tmpl.createOpts()
+	require.NoError(t, err)
+	assert.NotEqual(t, opts.Name, other.Name)
+}
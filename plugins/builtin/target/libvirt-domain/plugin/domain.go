@@ -0,0 +1,255 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// domainNameSuffixLen is the length of the random hex suffix appended to
+	// the name prefix of the domains the plugin creates.
+	domainNameSuffixLen = 8
+
+	// volumeFormatQCOW2 is the format of the volumes created for new domains,
+	// which allows them to use the base volume as backing store.
+	volumeFormatQCOW2 = "qcow2"
+)
+
+// newDomainName returns a new unique name for a domain of the pool.
+func newDomainName(prefix string) (string, error) {
+	b := make([]byte, domainNameSuffixLen/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate domain name: %v", err)
+	}
+	return prefix + "-" + hex.EncodeToString(b), nil
+}
+
+// isPoolDomain returns whether the domain name was generated by newDomainName
+// for the prefix. This avoids considering the template domain, or other
+// domains which share the prefix, part of the pool.
+func isPoolDomain(prefix, name string) bool {
+	suffix, ok := strings.CutPrefix(name, prefix+"-")
+	if !ok || len(suffix) != domainNameSuffixLen {
+		return false
+	}
+	_, err := hex.DecodeString(suffix)
+	return err == nil
+}
+
+// volumeName returns the name of the disk volume of a domain.
+func volumeName(domainName string) string {
+	return domainName + "." + volumeFormatQCOW2
+}
+
+// xmlElement is a generic XML element, used to modify the template domain XML
+// without modelling the full libvirt domain schema.
+type xmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr    `xml:",any,attr"`
+	Text     string        `xml:",chardata"`
+	Children []*xmlElement `xml:",any"`
+}
+
+// child returns the first child element with the name, if any.
+func (e *xmlElement) child(name string) *xmlElement {
+	for _, c := range e.Children {
+		if c.XMLName.Local == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// removeChildren removes all child elements with the name.
+func (e *xmlElement) removeChildren(name string) {
+	children := e.Children[:0]
+	for _, c := range e.Children {
+		if c.XMLName.Local != name {
+			children = append(children, c)
+		}
+	}
+	e.Children = children
+}
+
+// attr returns the value of the attribute with the name.
+func (e *xmlElement) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// setAttr sets the value of the attribute with the name, adding it when not
+// present.
+func (e *xmlElement) setAttr(name, value string) {
+	for i, a := range e.Attrs {
+		if a.Name.Local == name {
+			e.Attrs[i].Value = value
+			return
+		}
+	}
+	e.Attrs = append(e.Attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+// normalize prepares the element for encoding. The indentation text of
+// elements with children is removed, so the document is indented
+// consistently, as are namespace declarations, since the encoder declares the
+// namespace of each namespaced element itself.
+func (e *xmlElement) normalize() {
+	if len(e.Children) > 0 && strings.TrimSpace(e.Text) == "" {
+		e.Text = ""
+	}
+
+	attrs := e.Attrs[:0]
+	for _, a := range e.Attrs {
+		if a.Name.Space != "xmlns" && !(a.Name.Space == "" && a.Name.Local == "xmlns") {
+			attrs = append(attrs, a)
+		}
+	}
+	e.Attrs = attrs
+
+	for _, c := range e.Children {
+		c.normalize()
+	}
+}
+
+// cloneDomainXML returns the XML of a new domain cloned from the template
+// domain XML. The identifiers libvirt generates for a domain are removed so
+// they are unique to the new domain, and its first disk is replaced by the
+// disk at diskPath.
+func cloneDomainXML(tmpl, name, diskPath string) (string, error) {
+
+	var root xmlElement
+	if err := xml.Unmarshal([]byte(tmpl), &root); err != nil {
+		return "", fmt.Errorf("failed to parse template domain XML: %v", err)
+	}
+	if root.XMLName.Local != "domain" {
+		return "", fmt.Errorf("unexpected template domain XML root element %q", root.XMLName.Local)
+	}
+
+	nameElem := root.child("name")
+	if nameElem == nil {
+		return "", errors.New("template domain XML has no name")
+	}
+	nameElem.Text = name
+
+	// The UUID is generated by libvirt when not set. The metadata is owned by
+	// the applications which created the template and does not describe the
+	// clone.
+	root.removeChildren("uuid")
+	root.removeChildren("metadata")
+
+	devices := root.child("devices")
+	if devices == nil {
+		return "", errors.New("template domain XML has no devices")
+	}
+
+	var disk *xmlElement
+	for _, c := range devices.Children {
+		switch c.XMLName.Local {
+		case "disk":
+			if disk == nil && c.attr("device") == "disk" {
+				disk = c
+			}
+		case "interface":
+			// The MAC address is generated by libvirt when not set.
+			c.removeChildren("mac")
+		}
+	}
+	if disk == nil {
+		return "", errors.New("template domain XML has no disk device")
+	}
+
+	disk.setAttr("type", "file")
+	disk.removeChildren("source")
+	disk.removeChildren("backingStore")
+	disk.Children = append(disk.Children, &xmlElement{
+		XMLName: xml.Name{Local: "source"},
+		Attrs:   []xml.Attr{{Name: xml.Name{Local: "file"}, Value: diskPath}},
+	})
+	if driver := disk.child("driver"); driver != nil {
+		driver.setAttr("type", volumeFormatQCOW2)
+	}
+
+	root.normalize()
+
+	out, err := xml.MarshalIndent(&root, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode domain XML: %v", err)
+	}
+	return string(out), nil
+}
+
+// storageVolume is the subset of the libvirt storage volume XML used to read
+// the base volume and create the disk volumes of new domains.
+type storageVolume struct {
+	XMLName  xml.Name `xml:"volume"`
+	Name     string   `xml:"name,omitempty"`
+	Capacity struct {
+		Unit  string `xml:"unit,attr,omitempty"`
+		Value uint64 `xml:",chardata"`
+	} `xml:"capacity"`
+	Target struct {
+		Path   string              `xml:"path,omitempty"`
+		Format storageVolumeFormat `xml:"format"`
+	} `xml:"target"`
+	BackingStore *storageVolumeBackingStore `xml:"backingStore,omitempty"`
+}
+
+type storageVolumeFormat struct {
+	Type string `xml:"type,attr"`
+}
+
+type storageVolumeBackingStore struct {
+	Path   string              `xml:"path"`
+	Format storageVolumeFormat `xml:"format"`
+}
+
+// volumeXML returns the XML of a copy-on-write volume which uses the base
+// volume as its backing store.
+func volumeXML(name string, base storageVolume) (string, error) {
+
+	vol := storageVolume{Name: name}
+	vol.Capacity.Unit = "bytes"
+	vol.Capacity.Value = base.Capacity.Value
+	vol.Target.Format.Type = volumeFormatQCOW2
+	vol.BackingStore = &storageVolumeBackingStore{
+		Path:   base.Target.Path,
+		Format: base.Target.Format,
+	}
+
+	out, err := xml.Marshal(&vol)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode volume XML: %v", err)
+	}
+	return string(out), nil
+}
+
+// parseVolumeXML parses the XML of the base volume, which provides the
+// capacity, path and format used for the backing store of new volumes.
+func parseVolumeXML(data string) (storageVolume, error) {
+
+	var vol storageVolume
+	if err := xml.Unmarshal([]byte(data), &vol); err != nil {
+		return vol, fmt.Errorf("failed to parse volume XML: %v", err)
+	}
+
+	// libvirt always reports the capacity of existing volumes in bytes, so
+	// only the path needs validating.
+	if vol.Target.Path == "" {
+		return vol, errors.New("volume XML has no target path")
+	}
+	if vol.Target.Format.Type == "" {
+		vol.Target.Format.Type = "raw"
+	}
+	return vol, nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_isPoolDomain(t *testing.T) {
+	name, err := newDomainName("nomad-client")
+	require.NoError(t, err)
+	assert.True(t, isPoolDomain("nomad-client", name))
+
+	assert.False(t, isPoolDomain("nomad-client", "nomad-client-template"))
+	assert.False(t, isPoolDomain("nomad-client", "nomad-client-0a1b2c3g"))
+	assert.False(t, isPoolDomain("nomad-client", "nomad-client-0a1b2c3d4e"))
+	assert.False(t, isPoolDomain("nomad", "nomad-client-0a1b2c3d"))
+}
+
+const testTemplateDomainXML = `<domain type='kvm' xmlns:qemu='http://libvirt.org/schemas/domain/qemu/1.0'>
+  <name>nomad-client-template</name>
+  <uuid>4dea22b3-1d52-d8f3-2516-782e98ab3fa0</uuid>
+  <metadata>
+    <libosinfo:libosinfo xmlns:libosinfo="http://libosinfo.org/xmlns/libvirt/domain/1.0">
+      <libosinfo:os id="http://debian.org/debian/12"/>
+    </libosinfo:libosinfo>
+  </metadata>
+  <memory unit='KiB'>2097152</memory>
+  <vcpu placement='static'>2</vcpu>
+  <devices>
+    <disk type='file' device='cdrom'>
+      <source file='/var/lib/libvirt/images/seed.iso'/>
+      <target dev='sda' bus='sata'/>
+    </disk>
+    <disk type='volume' device='disk'>
+      <driver name='qemu' type='raw'/>
+      <source pool='default' volume='debian-12.img'/>
+      <target dev='vda' bus='virtio'/>
+    </disk>
+    <interface type='network'>
+      <mac address='52:54:00:6b:3c:58'/>
+      <source network='default'/>
+      <model type='virtio'/>
+    </interface>
+  </devices>
+  <qemu:commandline>
+    <qemu:arg value='-nodefaults'/>
+  </qemu:commandline>
+</domain>`
+
+func Test_cloneDomainXML(t *testing.T) {
+	testCases := []struct {
+		inputTemplate       string
+		expectedOutput      string
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputTemplate: testTemplateDomainXML,
+			expectedOutput: `<domain type="kvm">
+  <name>nomad-client-0a1b2c3d</name>
+  <memory unit="KiB">2097152</memory>
+  <vcpu placement="static">2</vcpu>
+  <devices>
+    <disk type="file" device="cdrom">
+      <source file="/var/lib/libvirt/images/seed.iso"></source>
+      <target dev="sda" bus="sata"></target>
+    </disk>
+    <disk type="file" device="disk">
+      <driver name="qemu" type="qcow2"></driver>
+      <target dev="vda" bus="virtio"></target>
+      <source file="/var/lib/libvirt/images/nomad-client-0a1b2c3d.qcow2"></source>
+    </disk>
+    <interface type="network">
+      <source network="default"></source>
+      <model type="virtio"></model>
+    </interface>
+  </devices>
+  <commandline xmlns="http://libvirt.org/schemas/domain/qemu/1.0">
+    <arg xmlns="http://libvirt.org/schemas/domain/qemu/1.0" value="-nodefaults"></arg>
+  </commandline>
+</domain>`,
+			name: "template cloned",
+		},
+		{
+			inputTemplate:       `<domain type='kvm'><name>t</name></domain>`,
+			expectedOutputError: errors.New("template domain XML has no devices"),
+			name:                "no devices",
+		},
+		{
+			inputTemplate:       `<domain type='kvm'><name>t</name><devices><disk device='cdrom'/></devices></domain>`,
+			expectedOutputError: errors.New("template domain XML has no disk device"),
+			name:                "no disk device",
+		},
+		{
+			inputTemplate:       `<network><name>default</name></network>`,
+			expectedOutputError: errors.New(`unexpected template domain XML root element "network"`),
+			name:                "not a domain",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualErr := cloneDomainXML(tc.inputTemplate, "nomad-client-0a1b2c3d",
+				"/var/lib/libvirt/images/nomad-client-0a1b2c3d.qcow2")
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_volumeXML(t *testing.T) {
+	base, err := parseVolumeXML(`<volume type='file'>
+  <name>debian-12.img</name>
+  <capacity unit='bytes'>10737418240</capacity>
+  <allocation unit='bytes'>1073741824</allocation>
+  <target>
+    <path>/var/lib/libvirt/images/debian-12.img</path>
+    <format type='raw'/>
+  </target>
+</volume>`)
+	require.NoError(t, err)
+
+	actual, err := volumeXML("nomad-client-0a1b2c3d.qcow2", base)
+	require.NoError(t, err)
+	assert.Equal(t, `<volume><name>nomad-client-0a1b2c3d.qcow2</name>`+
+		`<capacity unit="bytes">10737418240</capacity>`+
+		`<target><format type="qcow2"></format></target>`+
+		`<backingStore><path>/var/lib/libvirt/images/debian-12.img</path><format type="raw"></format></backingStore>`+
+		`</volume>`, actual)
+
+	_, err = parseVolumeXML(`<volume><name>debian-12.img</name></volume>`)
+	assert.EqualError(t, err, "volume XML has no target path")
+}
@@ -0,0 +1,366 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/digitalocean/go-libvirt/socket"
+	"github.com/digitalocean/go-libvirt/socket/dialers"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// nodeAttrHostname is the attribute used to identify the domain of a
+	// Nomad node. The guest image is expected to set its hostname to the
+	// domain name, for example using cloud-init or DHCP, as Nomad does not
+	// fingerprint libvirt specific attributes.
+	nodeAttrHostname = "unique.hostname"
+
+	// domainUndefineFlags removes the state libvirt may hold for a domain
+	// alongside its definition, which would otherwise prevent the undefine.
+	domainUndefineFlags = libvirt.DomainUndefineManagedSave |
+		libvirt.DomainUndefineSnapshotsMetadata |
+		libvirt.DomainUndefineNvram
+)
+
+// domain is a libvirt domain which is part of the pool.
+type domain struct {
+	dom   libvirt.Domain
+	state libvirt.DomainState
+}
+
+// setupLibvirtClient takes the passed config mapping and instantiates the
+// libvirt client, connecting over the local socket unless a remote address
+// is configured.
+func (t *TargetPlugin) setupLibvirtClient(config map[string]string) error {
+
+	var dialer socket.Dialer
+	if addr, ok := config[configKeyAddress]; ok && addr != "" {
+		dialer = dialers.NewRemote(addr)
+	} else {
+		var opts []dialers.LocalOption
+		if path, ok := config[configKeySocket]; ok && path != "" {
+			opts = append(opts, dialers.WithSocket(path))
+		}
+		dialer = dialers.NewLocal(opts...)
+	}
+
+	t.client = libvirt.NewWithDialer(dialer)
+	t.uri = libvirt.ConnectURI(getConfigValue(config, configKeyURI, configValueURIDefault))
+
+	_, err := t.libvirtClient()
+	return err
+}
+
+// libvirtClient returns the connected libvirt client, re-establishing the
+// connection if it has been lost since the last call.
+func (t *TargetPlugin) libvirtClient() (*libvirt.Libvirt, error) {
+	t.clientLock.Lock()
+	defer t.clientLock.Unlock()
+
+	if t.client.IsConnected() {
+		return t.client, nil
+	}
+	if err := t.client.ConnectToURI(t.uri); err != nil {
+		return nil, fmt.Errorf("failed to connect to libvirt: %v", err)
+	}
+	return t.client, nil
+}
+
+// listDomains returns all the persistent domains which are part of the pool
+// identified by the name prefix.
+func (t *TargetPlugin) listDomains(prefix string) ([]domain, error) {
+
+	l, err := t.libvirtClient()
+	if err != nil {
+		return nil, err
+	}
+
+	all, _, err := l.ConnectListAllDomains(1, libvirt.ConnectListDomainsPersistent)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []domain{}
+	for _, dom := range all {
+		if !isPoolDomain(prefix, dom.Name) {
+			continue
+		}
+		state, _, err := l.DomainGetState(dom, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get state of domain %s: %v", dom.Name, err)
+		}
+		domains = append(domains, domain{dom: dom, state: libvirt.DomainState(state)})
+	}
+	return domains, nil
+}
+
+// scaleOut creates and starts the domains required to reach the count the
+// Autoscaler has deemed required, cloning them from the template domain.
+func (t *TargetPlugin) scaleOut(prefix string, current, count int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_out", "name_prefix", prefix, "desired_count", count)
+
+	// The domain name is used as the guest hostname, which Nomad nodes are
+	// mapped to using only the first label of the hostname.
+	if strings.Contains(prefix, ".") {
+		return fmt.Errorf("%s must not contain dots", configKeyNamePrefix)
+	}
+
+	l, err := t.libvirtClient()
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := requiredConfigValue(config, configKeyTemplateDomain)
+	if err != nil {
+		return err
+	}
+	baseName, err := requiredConfigValue(config, configKeyBaseVolume)
+	if err != nil {
+		return err
+	}
+
+	tmplDom, err := l.DomainLookupByName(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to get template domain %s: %v", tmpl, err)
+	}
+	tmplXML, err := l.DomainGetXMLDesc(tmplDom, libvirt.DomainXMLInactive)
+	if err != nil {
+		return fmt.Errorf("failed to get template domain XML: %v", err)
+	}
+
+	pool, err := l.StoragePoolLookupByName(getConfigValue(config, configKeyStoragePool, configValueStoragePoolDefault))
+	if err != nil {
+		return fmt.Errorf("failed to get storage pool: %v", err)
+	}
+	baseVol, err := l.StorageVolLookupByName(pool, baseName)
+	if err != nil {
+		return fmt.Errorf("failed to get base volume %s: %v", baseName, err)
+	}
+	baseXML, err := l.StorageVolGetXMLDesc(baseVol, 0)
+	if err != nil {
+		return fmt.Errorf("failed to get base volume XML: %v", err)
+	}
+	base, err := parseVolumeXML(baseXML)
+	if err != nil {
+		return err
+	}
+
+	for i := current; i < count; i++ {
+		name, err := newDomainName(prefix)
+		if err != nil {
+			return err
+		}
+		if err := t.createDomain(l, pool, base, tmplXML, name); err != nil {
+			// Remove whatever was created for the failed domain, so it does
+			// not count towards the pool.
+			if cleanupErr := t.deleteDomain(l, pool, name); cleanupErr != nil {
+				log.Error("failed to clean up libvirt domain", "name", name, "error", cleanupErr)
+			}
+			return fmt.Errorf("failed to create libvirt domain %s: %v", name, err)
+		}
+		log.Debug("created libvirt domain", "name", name)
+	}
+
+	log.Info("successfully performed and verified scaling out")
+	return nil
+}
+
+// createDomain creates the disk volume of the domain, then defines and starts
+// the domain. The domain is set to autostart, so it returns alongside the
+// host.
+func (t *TargetPlugin) createDomain(l *libvirt.Libvirt, pool libvirt.StoragePool, base storageVolume, tmplXML, name string) error {
+
+	volXML, err := volumeXML(volumeName(name), base)
+	if err != nil {
+		return err
+	}
+	vol, err := l.StorageVolCreateXML(pool, volXML, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create volume: %v", err)
+	}
+	path, err := l.StorageVolGetPath(vol)
+	if err != nil {
+		return fmt.Errorf("failed to get volume path: %v", err)
+	}
+
+	domXML, err := cloneDomainXML(tmplXML, name, path)
+	if err != nil {
+		return err
+	}
+	dom, err := l.DomainDefineXML(domXML)
+	if err != nil {
+		return fmt.Errorf("failed to define domain: %v", err)
+	}
+	if err := l.DomainSetAutostart(dom, 1); err != nil {
+		return fmt.Errorf("failed to set domain autostart: %v", err)
+	}
+	if err := l.DomainCreate(dom); err != nil {
+		return fmt.Errorf("failed to start domain: %v", err)
+	}
+	return nil
+}
+
+// scaleIn drains the Nomad nodes selected for removal and then destroys their
+// domains, removing their definition and disk volume.
+func (t *TargetPlugin) scaleIn(ctx context.Context, prefix string, domains []domain, num int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "name_prefix", prefix)
+
+	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, domainRemoteIDs(domains), int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	if err := t.deleteDomains(domains, ids, config); err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return fmt.Errorf("failed to delete libvirt domains: %v", err)
+	}
+
+	// The tasks run on nodes that have been successfully deleted should not
+	// cause a failure of the scaling pipeline.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		log.Error("failed to perform post-scale Nomad scale in tasks", "error", err)
+	}
+
+	log.Info("successfully performed and verified scaling in")
+	return nil
+}
+
+// deleteDomains deletes the domains of the nodes, which are identified by
+// their lowercase name.
+func (t *TargetPlugin) deleteDomains(domains []domain, ids []scaleutils.NodeResourceID, config map[string]string) error {
+
+	l, err := t.libvirtClient()
+	if err != nil {
+		return err
+	}
+
+	pool, err := l.StoragePoolLookupByName(getConfigValue(config, configKeyStoragePool, configValueStoragePoolDefault))
+	if err != nil {
+		return fmt.Errorf("failed to get storage pool: %v", err)
+	}
+
+	byName := make(map[string]string, len(domains))
+	for _, d := range domains {
+		byName[strings.ToLower(d.dom.Name)] = d.dom.Name
+	}
+
+	for _, id := range ids {
+		name, ok := byName[id.RemoteResourceID]
+		if !ok {
+			return fmt.Errorf("domain %s not found", id.RemoteResourceID)
+		}
+		if err := t.deleteDomain(l, pool, name); err != nil {
+			return fmt.Errorf("failed to delete domain %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// deleteDomain stops and undefines the domain, then deletes its disk volume.
+// Resources which do not exist are skipped, so a partially created domain can
+// also be deleted.
+func (t *TargetPlugin) deleteDomain(l *libvirt.Libvirt, pool libvirt.StoragePool, name string) error {
+
+	dom, err := l.DomainLookupByName(name)
+	switch {
+	case libvirt.IsNotFound(err):
+	case err != nil:
+		return err
+	default:
+		state, _, err := l.DomainGetState(dom, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get domain state: %v", err)
+		}
+		if libvirt.DomainState(state) != libvirt.DomainShutoff {
+			if err := l.DomainDestroy(dom); err != nil {
+				return fmt.Errorf("failed to stop domain: %v", err)
+			}
+		}
+		if err := l.DomainUndefineFlags(dom, domainUndefineFlags); err != nil {
+			return fmt.Errorf("failed to undefine domain: %v", err)
+		}
+	}
+
+	vol, err := l.StorageVolLookupByName(pool, volumeName(name))
+	if isNoStorageVol(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get volume: %v", err)
+	}
+	if err := l.StorageVolDelete(vol, libvirt.StorageVolDeleteNormal); err != nil {
+		return fmt.Errorf("failed to delete volume: %v", err)
+	}
+	return nil
+}
+
+// isNoStorageVol returns whether the error is the libvirt error returned when
+// a storage volume does not exist.
+func isNoStorageVol(err error) bool {
+	var e libvirt.Error
+	return errors.As(err, &e) && e.Code == uint32(libvirt.ErrNoStorageVol)
+}
+
+// requiredConfigValue returns the value of a config param which must be set.
+func requiredConfigValue(config map[string]string, key string) (string, error) {
+	v, ok := config[key]
+	if !ok || v == "" {
+		return "", fmt.Errorf("required config param %s not found", key)
+	}
+	return v, nil
+}
+
+// domainRemoteIDs returns the lowercase names of the domains which can be
+// selected for scale in, matching the hostnames of their guests.
+func domainRemoteIDs(domains []domain) []string {
+	remoteIDs := []string{}
+	for _, d := range domains {
+		if d.state == libvirt.DomainRunning {
+			remoteIDs = append(remoteIDs, strings.ToLower(d.dom.Name))
+		}
+	}
+	return remoteIDs
+}
+
+// countRunning returns the number of running domains.
+func countRunning(domains []domain) int {
+	var n int
+	for _, d := range domains {
+		if d.state == libvirt.DomainRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// libvirtNodeIDMap is used to identify the domain of a Nomad node using its
+// hostname.
+func libvirtNodeIDMap(n *api.Node) (string, error) {
+	val, ok := n.Attributes[nodeAttrHostname]
+	if !ok || val == "" {
+		return "", fmt.Errorf("attribute %q not found", nodeAttrHostname)
+	}
+
+	// Guests may be configured to use a fully qualified hostname, so only use
+	// the first label which holds the domain name.
+	if idx := strings.Index(val, "."); idx != -1 {
+		val = val[:idx]
+	}
+	return strings.ToLower(val), nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_libvirtNodeIDMap(t *testing.T) {
+	testCases := []struct {
+		inputNode           *api.Node
+		expectedOutputID    string
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{"unique.hostname": "nomad-client-0a1b2c3d"},
+			},
+			expectedOutputID:    "nomad-client-0a1b2c3d",
+			expectedOutputError: nil,
+			name:                "required attribute found",
+		},
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{"unique.hostname": "Nomad-Client-0A1B2C3D.lab.example.com"},
+			},
+			expectedOutputID:    "nomad-client-0a1b2c3d",
+			expectedOutputError: nil,
+			name:                "fully qualified hostname",
+		},
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{},
+			},
+			expectedOutputID:    "",
+			expectedOutputError: errors.New(`attribute "unique.hostname" not found`),
+			name:                "required attribute not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualID, actualErr := libvirtNodeIDMap(tc.inputNode)
+			assert.Equal(t, tc.expectedOutputID, actualID, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_domainRemoteIDs(t *testing.T) {
+	domains := []domain{
+		{dom: libvirt.Domain{Name: "nomad-client-0a1b2c3d"}, state: libvirt.DomainRunning},
+		{dom: libvirt.Domain{Name: "nomad-client-4e5f6a7b"}, state: libvirt.DomainShutoff},
+		{dom: libvirt.Domain{Name: "Nomad-Client-8C9D0E1F"}, state: libvirt.DomainRunning},
+	}
+
+	assert.Equal(t, []string{"nomad-client-0a1b2c3d", "nomad-client-8c9d0e1f"}, domainRemoteIDs(domains))
+	assert.Equal(t, 2, countRunning(domains))
+	assert.Equal(t, []string{}, domainRemoteIDs(nil))
+}
+
+func Test_isNoStorageVol(t *testing.T) {
+	assert.True(t, isNoStorageVol(libvirt.Error{Code: uint32(libvirt.ErrNoStorageVol)}))
+	assert.True(t, isNoStorageVol(fmt.Errorf("lookup: %w", libvirt.Error{Code: uint32(libvirt.ErrNoStorageVol)})))
+	assert.False(t, isNoStorageVol(libvirt.Error{Code: uint32(libvirt.ErrNoDomain)}))
+	assert.False(t, isNoStorageVol(nil))
+}
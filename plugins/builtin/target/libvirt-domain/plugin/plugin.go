@@ -0,0 +1,204 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/digitalocean/go-libvirt"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "libvirt-domain"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle.
+	configKeySocket         = "libvirt_socket"
+	configKeyAddress        = "libvirt_address"
+	configKeyURI            = "libvirt_uri"
+	configKeyNamePrefix     = "libvirt_name_prefix"
+	configKeyTemplateDomain = "libvirt_template_domain"
+	configKeyStoragePool    = "libvirt_storage_pool"
+	configKeyBaseVolume     = "libvirt_base_volume"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueURIDefault         = string(libvirt.QEMUSystem)
+	configValueStoragePoolDefault = "default"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewLibvirtDomainPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the libvirt domain implementation of the target.Target
+// interface. A pool of domains is identified by a name prefix, and new
+// domains are cloned from a template domain with a disk backed by a base
+// image volume.
+type TargetPlugin struct {
+	config map[string]string
+	logger hclog.Logger
+
+	// client is the connection to libvirtd, which is re-established using uri
+	// when lost. clientLock serialises reconnection attempts.
+	client     *libvirt.Libvirt
+	clientLock sync.Mutex
+	uri        libvirt.ConnectURI
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools and performing scaling tasks.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewLibvirtDomainPlugin returns the libvirt domain implementation of the
+// target.Target interface.
+func NewLibvirtDomainPlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	if err := t.setupLibvirtClient(config); err != nil {
+		return err
+	}
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+
+	// Store and set the remote ID callback function.
+	t.clusterUtils = clusterUtils
+	t.clusterUtils.ClusterNodeIDLookupFunc = libvirtNodeIDMap
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// libvirt can't support dry-run like Nomad, so just exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	// We cannot scale the pool without knowing the name prefix which
+	// identifies its domains.
+	prefix, ok := config[configKeyNamePrefix]
+	if !ok || prefix == "" {
+		return fmt.Errorf("required config param %s not found", configKeyNamePrefix)
+	}
+	ctx := context.Background()
+
+	domains, err := t.listDomains(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list libvirt domains: %v", err)
+	}
+	currentCount := int64(len(domains))
+
+	// The pool requires different details depending on which direction we
+	// want to scale. Therefore calculate the direction and the relevant number
+	// so we can correctly perform the libvirt work.
+	num, direction := t.calculateDirection(currentCount, action.Count)
+
+	switch direction {
+	case "in":
+		err = t.scaleIn(ctx, prefix, domains, num, config)
+	case "out":
+		err = t.scaleOut(prefix, currentCount, num, config)
+	default:
+		t.logger.Info("scaling not required", "name_prefix", prefix,
+			"current_count", currentCount, "strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err != nil {
+		err = fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return err
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool. If the pool is not ready, we
+	// can exit here and avoid calling libvirt as it won't affect the outcome.
+	ready, err := t.clusterUtils.IsPoolReady(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+	}
+	if !ready {
+		return &sdk.TargetStatus{Ready: ready}, nil
+	}
+
+	prefix, ok := config[configKeyNamePrefix]
+	if !ok || prefix == "" {
+		return nil, fmt.Errorf("required config param %s not found", configKeyNamePrefix)
+	}
+
+	domains, err := t.listDomains(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list libvirt domains: %v", err)
+	}
+
+	// The pool is only ready once all its domains are running, so the
+	// autoscaler does not act on domains which have been stopped or paused.
+	return &sdk.TargetStatus{
+		Ready: countRunning(domains) == len(domains),
+		Count: int64(len(domains)),
+		Meta:  make(map[string]string),
+	}, nil
+}
+
+func (t *TargetPlugin) calculateDirection(domainsCount, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < domainsCount {
+		return domainsCount - strategyDesired, "in"
+	}
+	if strategyDesired > domainsCount {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,225 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package nomad
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	errHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/error"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// configKeyDispatch keys configure the dispatch mode of the target, where
+	// the count is the number of active dispatched instances of the
+	// parameterized batch job, rather than the count of a job group.
+	configKeyDispatchJob         = "dispatch_job"
+	configKeyDispatchMeta        = "dispatch_meta"
+	configKeyDispatchPayload     = "dispatch_payload"
+	configKeyDispatchStopScaleIn = "dispatch_stop_on_scale_in"
+
+	// metaKeyDispatch suffixes are used when adding the count of pending and
+	// running dispatched instances to the status response meta object.
+	metaKeyDispatchPendingSuffix = ".dispatch_pending"
+	metaKeyDispatchRunningSuffix = ".dispatch_running"
+
+	// jobStatus are the Nomad job statuses of dispatched instances.
+	jobStatusPending = "pending"
+	jobStatusRunning = "running"
+)
+
+// isDispatchTarget returns whether the target config uses the dispatch mode.
+func isDispatchTarget(config map[string]string) bool {
+	_, ok := config[configKeyDispatchJob]
+	return ok
+}
+
+// dispatchStatus returns the status of a dispatch target. The count is the
+// number of dispatched instances of the parameterized job which have not yet
+// completed, and the target is ready as long as the job is not stopped.
+func (t *TargetPlugin) dispatchStatus(config map[string]string) (*sdk.TargetStatus, error) {
+
+	jobID := config[configKeyDispatchJob]
+	if jobID == "" {
+		return nil, fmt.Errorf("required config key %q not found", configKeyDispatchJob)
+	}
+	q := &api.QueryOptions{Namespace: config[configKeyNamespace]}
+
+	job, _, err := t.client.Jobs().Info(jobID, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %v", jobID, err)
+	}
+	if job.ParameterizedJob == nil {
+		return nil, fmt.Errorf("job %s is not a parameterized job", jobID)
+	}
+
+	children, err := t.dispatchedJobs(jobID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending, running int
+	for _, child := range children {
+		if child.Status == jobStatusPending {
+			pending++
+		} else {
+			running++
+		}
+	}
+
+	stopped := job.Stop != nil && *job.Stop
+
+	return &sdk.TargetStatus{
+		Ready: !stopped,
+		Count: int64(len(children)),
+		Meta: map[string]string{
+			metaKeyPrefix + jobID + metaKeyJobStoppedSuffix:      strconv.FormatBool(stopped),
+			metaKeyPrefix + jobID + metaKeyDispatchPendingSuffix: strconv.Itoa(pending),
+			metaKeyPrefix + jobID + metaKeyDispatchRunningSuffix: strconv.Itoa(running),
+		},
+	}, nil
+}
+
+// dispatchScale dispatches new instances of the parameterized job when
+// scaling out. Instances usually process a unit of work, so scaling in only
+// stops instances when the operator has enabled it, pending instances first
+// and then the most recently dispatched ones. Otherwise the instances are
+// left to complete.
+func (t *TargetPlugin) dispatchScale(action sdk.ScalingAction, config map[string]string) error {
+
+	// There is no scaling event to register for dispatch targets.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	jobID := config[configKeyDispatchJob]
+	if jobID == "" {
+		return fmt.Errorf("required config key %q not found", configKeyDispatchJob)
+	}
+
+	meta, err := parseDispatchMeta(config[configKeyDispatchMeta])
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", configKeyDispatchMeta, err)
+	}
+
+	stopScaleIn := false
+	if val, ok := config[configKeyDispatchStopScaleIn]; ok {
+		stopScaleIn, err = strconv.ParseBool(val)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", configKeyDispatchStopScaleIn, err)
+		}
+	}
+
+	namespace := config[configKeyNamespace]
+
+	children, err := t.dispatchedJobs(jobID, &api.QueryOptions{Namespace: namespace})
+	if err != nil {
+		return err
+	}
+	current := int64(len(children))
+
+	switch {
+	case action.Count > current:
+		return t.dispatchJobs(jobID, namespace, meta, []byte(config[configKeyDispatchPayload]), action.Count-current)
+	case action.Count < current:
+		if !stopScaleIn {
+			return sdk.NewTargetScalingNoOpError("leaving %d dispatched instances of job %s to complete",
+				current-action.Count, jobID)
+		}
+		return t.stopDispatchedJobs(namespace, children, current-action.Count)
+	default:
+		return nil
+	}
+}
+
+// dispatchJobs dispatches num instances of the parameterized job.
+func (t *TargetPlugin) dispatchJobs(jobID, namespace string, meta map[string]string, payload []byte, num int64) error {
+
+	q := &api.WriteOptions{Namespace: namespace}
+
+	for i := int64(0); i < num; i++ {
+		resp, _, err := t.client.Jobs().Dispatch(jobID, meta, payload, "", q)
+		if err != nil {
+			return fmt.Errorf("failed to dispatch job %s after %d of %d instances: %v", jobID, i, num, err)
+		}
+		t.logger.Debug("dispatched job instance", "job_id", jobID, "dispatched_job_id", resp.DispatchedJobID)
+	}
+
+	t.logger.Info("successfully dispatched job instances", "job_id", jobID, "count", num)
+	return nil
+}
+
+// stopDispatchedJobs stops num of the dispatched instances, pending instances
+// first and then the most recently dispatched ones.
+func (t *TargetPlugin) stopDispatchedJobs(namespace string, children []*api.JobListStub, num int64) error {
+
+	sort.SliceStable(children, func(i, j int) bool {
+		iPending, jPending := children[i].Status == jobStatusPending, children[j].Status == jobStatusPending
+		if iPending != jPending {
+			return iPending
+		}
+		return children[i].SubmitTime > children[j].SubmitTime
+	})
+
+	q := &api.WriteOptions{Namespace: namespace}
+
+	var mErr *multierror.Error
+
+	for _, child := range children[:num] {
+		if _, _, err := t.client.Jobs().Deregister(child.ID, false, q); err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to stop job %s: %v", child.ID, err))
+			continue
+		}
+		t.logger.Info("stopped dispatched job instance", "dispatched_job_id", child.ID)
+	}
+
+	return errHelper.FormattedMultiError(mErr)
+}
+
+// dispatchedJobs returns the dispatched instances of the parameterized job
+// which are pending or running.
+func (t *TargetPlugin) dispatchedJobs(jobID string, q *api.QueryOptions) ([]*api.JobListStub, error) {
+
+	opts := *q
+	opts.Prefix = jobID + "/dispatch-"
+
+	jobs, _, err := t.client.Jobs().List(&opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispatched instances of job %s: %v", jobID, err)
+	}
+
+	var out []*api.JobListStub
+	for _, job := range jobs {
+		if job.ParentID != jobID || job.Stop {
+			continue
+		}
+		if job.Status == jobStatusPending || job.Status == jobStatusRunning {
+			out = append(out, job)
+		}
+	}
+	return out, nil
+}
+
+// parseDispatchMeta parses the dispatch meta config value, a comma separated
+// list of <key>=<value> pairs.
+func parseDispatchMeta(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	meta := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid meta %q, expected <key>=<value>", pair)
+		}
+		meta[k] = v
+	}
+	return meta, nil
+}
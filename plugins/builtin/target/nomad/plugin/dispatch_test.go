@@ -0,0 +1,142 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package nomad
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseDispatchMeta(t *testing.T) {
+	testCases := []struct {
+		input          string
+		expectedOutput map[string]string
+		expectedError  error
+		name           string
+	}{
+		{
+			input:          "",
+			expectedOutput: nil,
+			expectedError:  nil,
+			name:           "empty",
+		},
+		{
+			input:          "queue=emails, priority=",
+			expectedOutput: map[string]string{"queue": "emails", "priority": ""},
+			expectedError:  nil,
+			name:           "pairs",
+		},
+		{
+			input:          "queue",
+			expectedOutput: nil,
+			expectedError:  errors.New(`invalid meta "queue", expected <key>=<value>`),
+			name:           "missing value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualError := parseDispatchMeta(tc.input)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedError, actualError, tc.name)
+		})
+	}
+}
+
+func TestTargetPlugin_dispatch(t *testing.T) {
+	nomad := newDispatchTestServer([]*api.JobListStub{
+		{ID: "worker/dispatch-1", ParentID: "worker", Status: jobStatusRunning, SubmitTime: 1},
+		{ID: "worker/dispatch-2", ParentID: "worker", Status: jobStatusRunning, SubmitTime: 3},
+		{ID: "worker/dispatch-3", ParentID: "worker", Status: jobStatusPending, SubmitTime: 2},
+		{ID: "worker/dispatch-4", ParentID: "worker", Status: "dead", SubmitTime: 4},
+	})
+	defer nomad.Close()
+
+	plugin := PluginConfig.Factory(hclog.NewNullLogger()).(*TargetPlugin)
+	require.NoError(t, plugin.SetConfig(map[string]string{"nomad_address": nomad.URL}))
+
+	config := map[string]string{"dispatch_job": "worker", "dispatch_meta": "queue=emails"}
+
+	status, err := plugin.Status(config)
+	require.NoError(t, err)
+	assert.Equal(t, &sdk.TargetStatus{
+		Ready: true,
+		Count: 3,
+		Meta: map[string]string{
+			"nomad_autoscaler.target.nomad.worker.stopped":          "false",
+			"nomad_autoscaler.target.nomad.worker.dispatch_pending": "1",
+			"nomad_autoscaler.target.nomad.worker.dispatch_running": "2",
+		},
+	}, status)
+
+	// Scaling out dispatches the missing instances.
+	require.NoError(t, plugin.Scale(sdk.ScalingAction{Count: 5}, config))
+	assert.Equal(t, []map[string]string{{"queue": "emails"}, {"queue": "emails"}}, nomad.dispatches)
+
+	// Scaling in leaves the instances to complete by default.
+	err = plugin.Scale(sdk.ScalingAction{Count: 1}, config)
+	assert.IsType(t, &sdk.TargetScalingNoOpError{}, err)
+	assert.Empty(t, nomad.stopped)
+
+	// Otherwise pending instances are stopped first, then the newest.
+	config["dispatch_stop_on_scale_in"] = "true"
+	require.NoError(t, plugin.Scale(sdk.ScalingAction{Count: 1}, config))
+	assert.Equal(t, []string{"worker/dispatch-3", "worker/dispatch-2"}, nomad.stopped)
+}
+
+// dispatchTestServer is a mock Nomad API serving a parameterized job and its
+// dispatched instances, recording dispatches and stopped jobs.
+type dispatchTestServer struct {
+	*httptest.Server
+
+	children []*api.JobListStub
+
+	lock       sync.Mutex
+	dispatches []map[string]string
+	stopped    []string
+}
+
+func newDispatchTestServer(children []*api.JobListStub) *dispatchTestServer {
+	s := &dispatchTestServer{children: children}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *dispatchTestServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case r.URL.Path == "/v1/jobs":
+		if r.URL.Query().Get("prefix") != "worker/dispatch-" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.children)
+	case r.URL.Path == "/v1/job/worker" && r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(api.Job{ID: ptr.StringToPtr("worker"), ParameterizedJob: &api.ParameterizedJobConfig{}})
+	case r.URL.Path == "/v1/job/worker/dispatch":
+		var req api.JobDispatchRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		s.dispatches = append(s.dispatches, req.Meta)
+		_ = json.NewEncoder(w).Encode(api.JobDispatchResponse{})
+	case r.Method == http.MethodDelete:
+		s.stopped = append(s.stopped, strings.TrimPrefix(r.URL.Path, "/v1/job/"))
+		_ = json.NewEncoder(w).Encode(api.JobDeregisterResponse{})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
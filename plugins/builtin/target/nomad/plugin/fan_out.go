@@ -0,0 +1,238 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package nomad
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	errHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/error"
+	"github.com/hashicorp/nomad/api"
+)
+
+// metaKeyFanOutCountSuffix is used when adding the count of fan out members
+// to the dry run result meta object.
+const metaKeyFanOutCountSuffix = ".count"
+
+// fanOutMember is a job group scaled alongside the target group of a policy.
+// The fan_out config value is a comma separated list of members, each in the
+// [<job>/]<group>[:<ratio>] format. Members omitting the job belong to the
+// target job, while the job may also be a glob selecting all the jobs with a
+// matching ID.
+type fanOutMember struct {
+	job   string
+	group string
+
+	// ratio is the fixed ratio of the member count to the target count. A
+	// zero ratio scales the member proportionally, so the ratio between the
+	// member and target counts is kept.
+	ratio float64
+
+	// selector indicates the job is a glob, which is resolved against the
+	// jobs registered in the namespace.
+	selector bool
+}
+
+// String returns the job group reference of the member.
+func (m fanOutMember) String() string { return m.job + "/" + m.group }
+
+// parseFanOut parses the fan_out config value of a policy targeting the job.
+func parseFanOut(s, jobID string) ([]fanOutMember, error) {
+
+	var members []fanOutMember
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		member := fanOutMember{job: jobID}
+
+		if ref, ratio, ok := strings.Cut(entry, ":"); ok {
+			r, err := strconv.ParseFloat(ratio, 64)
+			if err != nil || r <= 0 || math.IsInf(r, 0) {
+				return nil, fmt.Errorf("invalid ratio %q for %s, must be a positive number", ratio, ref)
+			}
+			member.ratio = r
+			entry = ref
+		}
+
+		if job, group, ok := strings.Cut(entry, "/"); ok {
+			member.job, member.group = job, group
+		} else {
+			member.group = entry
+		}
+
+		if member.job == "" || member.group == "" {
+			return nil, fmt.Errorf("invalid fan out group %q, expected [<job>/]<group>[:<ratio>]", entry)
+		}
+		if _, err := path.Match(member.job, ""); err != nil {
+			return nil, fmt.Errorf("invalid job selector %q: %v", member.job, err)
+		}
+		member.selector = strings.ContainsAny(member.job, "*?[")
+
+		members = append(members, member)
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("%s must include at least one group", configKeyFanOut)
+	}
+	return members, nil
+}
+
+// fanOutCount returns the count of the member when scaling the target group
+// from current to desired. Counts are rounded up, so members are never
+// scaled below their share of the target.
+func fanOutCount(m fanOutMember, memberCount, current, desired int64) int64 {
+	if m.ratio > 0 {
+		return int64(math.Ceil(m.ratio * float64(desired)))
+	}
+
+	// No proportion can be derived from an empty target group, so the member
+	// is scaled to the same count.
+	if current == 0 {
+		return desired
+	}
+	return int64(math.Ceil(float64(memberCount) * float64(desired) / float64(current)))
+}
+
+// scaleFanOut scales the target group of the policy, and then each of the
+// fan out members. The member counts are computed before the target group is
+// scaled, so proportional members use the ratio prior to scaling. If the
+// target group cannot be scaled, the members are not scaled either so that
+// the groups remain in lockstep.
+func (t *TargetPlugin) scaleFanOut(action sdk.ScalingAction, count *int, config map[string]string) error {
+
+	namespace, jobID, group := config[configKeyNamespace], config[configKeyJobID], config[configKeyGroup]
+
+	members, counts, err := t.fanOutCounts(count, config)
+	if err != nil {
+		return err
+	}
+
+	if err := t.scaleGroup(namespace, jobID, group, count, action); err != nil {
+		return err
+	}
+
+	var mErr *multierror.Error
+
+	for i, m := range members {
+		if err := t.scaleGroup(namespace, m.job, m.group, counts[i], action); err != nil {
+			mErr = multierror.Append(mErr, err)
+			continue
+		}
+		if counts[i] != nil {
+			t.logger.Debug("scaled fan out group", "group", m.String(), "count", *counts[i])
+		}
+	}
+
+	return errHelper.FormattedMultiError(mErr)
+}
+
+// fanOutCounts resolves the fan out members of the policy and returns them
+// alongside the count each is scaled to when the target group is scaled to
+// count. A nil count results in nil member counts.
+func (t *TargetPlugin) fanOutCounts(count *int, config map[string]string) ([]fanOutMember, []*int, error) {
+
+	namespace, jobID, group := config[configKeyNamespace], config[configKeyJobID], config[configKeyGroup]
+
+	members, err := parseFanOut(config[configKeyFanOut], jobID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %v", configKeyFanOut, err)
+	}
+
+	members, err = t.resolveFanOut(namespace, jobID, group, members)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	counts := make([]*int, len(members))
+
+	if count == nil {
+		return members, counts, nil
+	}
+
+	status, err := t.groupStatus(namespace, jobID, group)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get status of group %s/%s: %v", jobID, group, err)
+	}
+	if status == nil {
+		return nil, nil, fmt.Errorf("job %s not found", jobID)
+	}
+
+	for i, m := range members {
+		var memberCount int64
+		if m.ratio == 0 {
+			memberStatus, err := t.groupStatus(namespace, m.job, m.group)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get status of fan out group %s: %v", m, err)
+			}
+			if memberStatus == nil {
+				return nil, nil, fmt.Errorf("fan out job %s not found", m.job)
+			}
+			memberCount = memberStatus.Count
+		}
+
+		c := int(fanOutCount(m, memberCount, status.Count, int64(*count)))
+		counts[i] = &c
+	}
+
+	return members, counts, nil
+}
+
+// resolveFanOut expands the members using a job selector into a member for
+// each running job with a matching ID and the group. The target group itself
+// and duplicate groups are skipped.
+func (t *TargetPlugin) resolveFanOut(namespace, jobID, group string, members []fanOutMember) ([]fanOutMember, error) {
+
+	seen := map[string]struct{}{jobID + "/" + group: {}}
+
+	var (
+		out  []fanOutMember
+		jobs []*api.JobListStub
+	)
+
+	add := func(m fanOutMember) {
+		if _, ok := seen[m.String()]; ok {
+			return
+		}
+		seen[m.String()] = struct{}{}
+		out = append(out, m)
+	}
+
+	for _, m := range members {
+		if !m.selector {
+			add(m)
+			continue
+		}
+
+		if jobs == nil {
+			var err error
+			jobs, _, err = t.client.Jobs().List(&api.QueryOptions{Namespace: namespace})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list jobs: %v", err)
+			}
+		}
+
+		for _, job := range jobs {
+			if job.Stop || job.JobSummary == nil {
+				continue
+			}
+			if _, ok := job.JobSummary.Summary[m.group]; !ok {
+				continue
+			}
+			if ok, _ := path.Match(m.job, job.ID); ok {
+				add(fanOutMember{job: job.ID, group: m.group, ratio: m.ratio})
+			}
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,253 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package nomad
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseFanOut(t *testing.T) {
+	testCases := []struct {
+		inputFanOut    string
+		expectedOutput []fanOutMember
+		expectedError  error
+		name           string
+	}{
+		{
+			inputFanOut: "worker, api/proxy:0.5, batch-*/process",
+			expectedOutput: []fanOutMember{
+				{job: "frontend", group: "worker"},
+				{job: "api", group: "proxy", ratio: 0.5},
+				{job: "batch-*", group: "process", selector: true},
+			},
+			expectedError: nil,
+			name:          "mixed members",
+		},
+		{
+			inputFanOut:    "worker:zero",
+			expectedOutput: nil,
+			expectedError:  errors.New(`invalid ratio "zero" for worker, must be a positive number`),
+			name:           "invalid ratio",
+		},
+		{
+			inputFanOut:    "worker:0",
+			expectedOutput: nil,
+			expectedError:  errors.New(`invalid ratio "0" for worker, must be a positive number`),
+			name:           "zero ratio",
+		},
+		{
+			inputFanOut:    "api/",
+			expectedOutput: nil,
+			expectedError:  errors.New(`invalid fan out group "api/", expected [<job>/]<group>[:<ratio>]`),
+			name:           "missing group",
+		},
+		{
+			inputFanOut:    "batch-[/process",
+			expectedOutput: nil,
+			expectedError:  errors.New(`invalid job selector "batch-[": syntax error in pattern`),
+			name:           "invalid selector",
+		},
+		{
+			inputFanOut:    " , ",
+			expectedOutput: nil,
+			expectedError:  errors.New("fan_out must include at least one group"),
+			name:           "empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualError := parseFanOut(tc.inputFanOut, "frontend")
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedError, actualError, tc.name)
+		})
+	}
+}
+
+func Test_fanOutCount(t *testing.T) {
+	testCases := []struct {
+		inputMember      fanOutMember
+		inputMemberCount int64
+		inputCurrent     int64
+		inputDesired     int64
+		expectedOutput   int64
+		name             string
+	}{
+		{
+			inputMember:    fanOutMember{ratio: 2},
+			inputDesired:   3,
+			expectedOutput: 6,
+			name:           "fixed ratio",
+		},
+		{
+			inputMember:    fanOutMember{ratio: 0.5},
+			inputDesired:   3,
+			expectedOutput: 2,
+			name:           "fixed ratio rounded up",
+		},
+		{
+			inputMember:      fanOutMember{},
+			inputMemberCount: 6,
+			inputCurrent:     3,
+			inputDesired:     5,
+			expectedOutput:   10,
+			name:             "proportional",
+		},
+		{
+			inputMember:      fanOutMember{},
+			inputMemberCount: 5,
+			inputCurrent:     4,
+			inputDesired:     2,
+			expectedOutput:   3,
+			name:             "proportional rounded up",
+		},
+		{
+			inputMember:      fanOutMember{},
+			inputMemberCount: 2,
+			inputCurrent:     0,
+			inputDesired:     4,
+			expectedOutput:   4,
+			name:             "proportional empty target",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput,
+				fanOutCount(tc.inputMember, tc.inputMemberCount, tc.inputCurrent, tc.inputDesired), tc.name)
+		})
+	}
+}
+
+func TestTargetPlugin_Scale_fanOut(t *testing.T) {
+	nomad := newFanOutTestServer(map[string]map[string]int{
+		"frontend":  {"web": 2, "worker": 4},
+		"batch-eu":  {"process": 1},
+		"batch-us":  {"process": 1},
+		"reporting": {"process": 1},
+	})
+	defer nomad.Close()
+
+	plugin := PluginConfig.Factory(hclog.NewNullLogger()).(*TargetPlugin)
+	require.NoError(t, plugin.SetConfig(map[string]string{"nomad_address": nomad.URL}))
+
+	err := plugin.Scale(sdk.ScalingAction{Count: 3, Reason: "test"}, map[string]string{
+		"Job":     "frontend",
+		"Group":   "web",
+		"fan_out": "worker, batch-*/process:0.5",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{
+		"frontend/web":     3,
+		"frontend/worker":  6,
+		"batch-eu/process": 2,
+		"batch-us/process": 2,
+	}, nomad.scaled())
+}
+
+func TestTargetPlugin_DryRun_fanOut(t *testing.T) {
+	nomad := newFanOutTestServer(map[string]map[string]int{
+		"frontend": {"web": 2, "worker": 4},
+		"batch-eu": {"process": 1},
+	})
+	defer nomad.Close()
+
+	plugin := PluginConfig.Factory(hclog.NewNullLogger()).(*TargetPlugin)
+	require.NoError(t, plugin.SetConfig(map[string]string{"nomad_address": nomad.URL}))
+
+	result, err := plugin.DryRun(sdk.ScalingAction{Count: 3, Reason: "test"}, map[string]string{
+		"Job":     "frontend",
+		"Group":   "web",
+		"fan_out": "worker, batch-eu/process:0.5",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, &sdk.TargetDryRunResult{
+		Count: 3,
+		Meta: map[string]string{
+			"nomad_autoscaler.target.nomad.frontend/worker.count":  "6",
+			"nomad_autoscaler.target.nomad.batch-eu/process.count": "2",
+		},
+	}, result)
+	assert.Empty(t, nomad.scaled())
+}
+
+// fanOutTestServer is a mock Nomad API serving the job list, job scale status
+// and job scale endpoints for the jobs and groups counts.
+type fanOutTestServer struct {
+	*httptest.Server
+
+	counts map[string]map[string]int
+
+	lock   sync.Mutex
+	scales map[string]int
+}
+
+func newFanOutTestServer(counts map[string]map[string]int) *fanOutTestServer {
+	s := &fanOutTestServer{counts: counts, scales: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *fanOutTestServer) scaled() map[string]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.scales
+}
+
+func (s *fanOutTestServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1/jobs" {
+		var jobs []*api.JobListStub
+		for id, groups := range s.counts {
+			summary := &api.JobSummary{JobID: id, Summary: map[string]api.TaskGroupSummary{}}
+			for g := range groups {
+				summary.Summary[g] = api.TaskGroupSummary{}
+			}
+			jobs = append(jobs, &api.JobListStub{ID: id, JobSummary: summary})
+		}
+		_ = json.NewEncoder(w).Encode(jobs)
+		return
+	}
+
+	jobID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/job/"), "/scale")
+	groups, ok := s.counts[jobID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		status := api.JobScaleStatusResponse{JobID: jobID, TaskGroups: map[string]api.TaskGroupScaleStatus{}}
+		for g, c := range groups {
+			status.TaskGroups[g] = api.TaskGroupScaleStatus{Running: c}
+		}
+		_ = json.NewEncoder(w).Encode(status)
+		return
+	}
+
+	var req api.ScalingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	s.scales[fmt.Sprintf("%s/%s", jobID, req.Target["Group"])] = int(*req.Count)
+	s.lock.Unlock()
+	_ = json.NewEncoder(w).Encode(api.JobRegisterResponse{})
+}
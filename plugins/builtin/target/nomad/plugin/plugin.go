@@ -5,6 +5,7 @@ package nomad
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -28,6 +29,7 @@ const (
 	configKeyJobID     = "Job"
 	configKeyGroup     = "Group"
 	configKeyNamespace = "Namespace"
+	configKeyFanOut    = "fan_out"
 
 	// garbageCollectionNanoSecondThreshold is the nanosecond threshold used
 	// when performing garbage collection of job status handlers.
@@ -54,8 +56,12 @@ var (
 	}
 )
 
-// Assert that TargetPlugin meets the target.Target interface.
-var _ target.Target = (*TargetPlugin)(nil)
+// Assert that TargetPlugin meets the target.Target and target.DryRunner
+// interfaces.
+var (
+	_ target.Target    = (*TargetPlugin)(nil)
+	_ target.DryRunner = (*TargetPlugin)(nil)
+)
 
 // TargetPlugin is the Nomad implementation of the target.Target interface.
 type TargetPlugin struct {
@@ -124,24 +130,67 @@ func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
 
 // Scale satisfies the Scale function on the target.Target interface.
 func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+	if isDispatchTarget(config) {
+		return t.dispatchScale(action, config)
+	}
+
+	var countIntPtr *int
+	if action.Count != sdk.StrategyActionMetaValueDryRunCount {
+		countInt := int(action.Count)
+		countIntPtr = &countInt
+	}
+
+	// If the policy fans out to other groups, scale them alongside the
+	// target group.
+	if _, ok := config[configKeyFanOut]; ok {
+		return t.scaleFanOut(action, countIntPtr, config)
+	}
+
+	return t.scaleGroup(config[configKeyNamespace], config[configKeyJobID], config[configKeyGroup], countIntPtr, action)
+}
+
+// DryRun satisfies the DryRun function on the target.DryRunner interface.
+// The counts the fan out members would be scaled to are included in the
+// result meta.
+func (t *TargetPlugin) DryRun(action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error) {
+
+	result := sdk.TargetDryRunResult{Count: action.Count}
+
+	if _, ok := config[configKeyFanOut]; !ok || isDispatchTarget(config) {
+		return &result, nil
+	}
+
 	var countIntPtr *int
 	if action.Count != sdk.StrategyActionMetaValueDryRunCount {
 		countInt := int(action.Count)
 		countIntPtr = &countInt
 	}
 
-	// Setup the Nomad write options.
-	q := api.WriteOptions{}
+	members, counts, err := t.fanOutCounts(countIntPtr, config)
+	if err != nil {
+		return nil, err
+	}
 
-	// If namespace is included within the config, add this to write opts. If
-	// this is omitted, we fallback to Nomad standard practice.
-	if namespace, ok := config[configKeyNamespace]; ok {
-		q.Namespace = namespace
+	result.Meta = make(map[string]string, len(members))
+	for i, m := range members {
+		if counts[i] != nil {
+			result.Meta[metaKeyPrefix+m.String()+metaKeyFanOutCountSuffix] = strconv.Itoa(*counts[i])
+		}
 	}
+	return &result, nil
+}
+
+// scaleGroup scales the job group to the count. A nil count only registers
+// the scaling event.
+func (t *TargetPlugin) scaleGroup(namespace, jobID, group string, count *int, action sdk.ScalingAction) error {
 
-	_, _, err := t.client.Jobs().Scale(config[configKeyJobID],
-		config[configKeyGroup],
-		countIntPtr,
+	// Setup the Nomad write options. If the namespace is omitted, we fallback
+	// to Nomad standard practice.
+	q := api.WriteOptions{Namespace: namespace}
+
+	_, _, err := t.client.Jobs().Scale(jobID,
+		group,
+		count,
 		action.Reason,
 		action.Error,
 		action.Meta,
@@ -152,15 +201,18 @@ func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string)
 		// impactful to the target's eventual end state, so special case them
 		// to return a no-op error instead.
 		if strings.Contains(err.Error(), "job scaling blocked due to active deployment") {
-			return sdk.NewTargetScalingNoOpError("skipping scaling group %s/%s due to active deployment", config[configKeyJobID], config[configKeyGroup])
+			return sdk.NewTargetScalingNoOpError("skipping scaling group %s/%s due to active deployment", jobID, group)
 		}
-		return fmt.Errorf("failed to scale group %s/%s: %v", config[configKeyJobID], config[configKeyGroup], err)
+		return fmt.Errorf("failed to scale group %s/%s: %v", jobID, group, err)
 	}
 	return nil
 }
 
 // Status satisfies the Status function on the target.Target interface.
 func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+	if isDispatchTarget(config) {
+		return t.dispatchStatus(config)
+	}
 
 	// Get the JobID from the config map. This is a required param and results
 	// in an error if not found or is an empty string.
@@ -176,10 +228,16 @@ func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, erro
 		return nil, fmt.Errorf("required config key %q not found", configKeyGroup)
 	}
 
-	// Attempt to find the namespace config parameter. If this is not included
-	// use the Nomad default namespace "default".
-	namespace, ok := config[configKeyNamespace]
-	if !ok || namespace == "" {
+	return t.groupStatus(config[configKeyNamespace], jobID, group)
+}
+
+// groupStatus returns the status of the job group, creating a status handler
+// for the job if one does not currently exist.
+func (t *TargetPlugin) groupStatus(namespace, jobID, group string) (*sdk.TargetStatus, error) {
+
+	// If the namespace is not included use the Nomad default namespace
+	// "default".
+	if namespace == "" {
 		namespace = "default"
 	}
 
@@ -0,0 +1,371 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/common/auth"
+	"github.com/oracle/oci-go-sdk/v65/core"
+)
+
+const (
+	defaultRetryInterval = 10 * time.Second
+
+	// nodeAttrHostname is the attribute used to identify the instance of a
+	// Nomad node. OCI derives the hostname of pool instances from their
+	// display name, and Nomad does not fingerprint OCI specific attributes.
+	nodeAttrHostname = "unique.hostname"
+
+	// instanceStateRunning is the state of pool instances which are running
+	// and can be selected for scale in.
+	instanceStateRunning = "running"
+)
+
+// setupOCIClients takes the passed config mapping and instantiates the
+// required OCI service clients.
+func (t *TargetPlugin) setupOCIClients(config map[string]string) error {
+
+	provider, err := configProvider(config)
+	if err != nil {
+		return err
+	}
+
+	compute, err := core.NewComputeClientWithConfigurationProvider(provider)
+	if err != nil {
+		return fmt.Errorf("failed to create OCI compute client: %v", err)
+	}
+	management, err := core.NewComputeManagementClientWithConfigurationProvider(provider)
+	if err != nil {
+		return fmt.Errorf("failed to create OCI compute management client: %v", err)
+	}
+
+	// If the operator has provided a configuration region, overwrite that set
+	// by the configuration provider.
+	if region, ok := config[configKeyRegion]; ok && region != "" {
+		t.logger.Debug("setting OCI region for client", "region", region)
+		compute.SetRegion(region)
+		management.SetRegion(region)
+	}
+
+	t.compute = compute
+	t.management = management
+	return nil
+}
+
+// configProvider returns the OCI configuration provider based on the
+// authentication type. API keys are read from the config when the tenancy is
+// set, otherwise from the OCI configuration file and environment variables.
+func configProvider(config map[string]string) (common.ConfigurationProvider, error) {
+
+	switch authType := getConfigValue(config, configKeyAuthType, authTypeAPIKey); authType {
+	case authTypeInstancePrincipal:
+		return auth.InstancePrincipalConfigurationProvider()
+	case authTypeAPIKey:
+	default:
+		return nil, fmt.Errorf("invalid value %s for %s", authType, configKeyAuthType)
+	}
+
+	if tenancy, ok := config[configKeyTenancyOCID]; ok && tenancy != "" {
+		for _, key := range []string{configKeyUserOCID, configKeyFingerprint, configKeyPrivateKey} {
+			if config[key] == "" {
+				return nil, fmt.Errorf("required config param %s not found", key)
+			}
+		}
+
+		var passphrase *string
+		if v, ok := config[configKeyPrivateKeyPassphrase]; ok {
+			passphrase = &v
+		}
+		return common.NewRawConfigurationProvider(tenancy, config[configKeyUserOCID], config[configKeyRegion],
+			config[configKeyFingerprint], config[configKeyPrivateKey], passphrase), nil
+	}
+
+	if path, ok := config[configKeyConfigFile]; ok && path != "" {
+		profile := getConfigValue(config, configKeyConfigProfile, "DEFAULT")
+		return common.ConfigurationProviderFromFileWithProfile(path, profile, config[configKeyPrivateKeyPassphrase])
+	}
+
+	return common.DefaultConfigProvider(), nil
+}
+
+// getInstancePool returns the instance pool.
+func (t *TargetPlugin) getInstancePool(ctx context.Context, poolID string) (*core.InstancePool, error) {
+	resp, err := t.management.GetInstancePool(ctx, core.GetInstancePoolRequest{InstancePoolId: &poolID})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.InstancePool, nil
+}
+
+// listInstances returns all the instances attached to the instance pool.
+func (t *TargetPlugin) listInstances(ctx context.Context, pool *core.InstancePool) ([]core.InstanceSummary, error) {
+
+	req := core.ListInstancePoolInstancesRequest{
+		CompartmentId:  pool.CompartmentId,
+		InstancePoolId: pool.Id,
+	}
+
+	var instances []core.InstanceSummary
+
+	for {
+		resp, err := t.management.ListInstancePoolInstances(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.Items...)
+
+		if resp.OpcNextPage == nil || *resp.OpcNextPage == "" {
+			return instances, nil
+		}
+		req.Page = resp.OpcNextPage
+	}
+}
+
+// scaleOut updates the instance pool size to match what the Autoscaler has
+// deemed required, limited by the available capacity reservation when
+// configured to do so.
+func (t *TargetPlugin) scaleOut(ctx context.Context, pool *core.InstancePool, count int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_out", "instance_pool_id", *pool.Id, "desired_count", count)
+
+	strict := false
+	if v, ok := config[configKeyCapacityReservationStrict]; ok {
+		var err error
+		if strict, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", configKeyCapacityReservationStrict, err)
+		}
+	}
+
+	reservation, err := t.capacityReservation(ctx, pool, config)
+	if err != nil {
+		return fmt.Errorf("failed to get OCI capacity reservation: %v", err)
+	}
+
+	current := int64(*pool.Size)
+
+	size, err := reservation.limitScaleOut(current, count, strict)
+	if err != nil {
+		return err
+	}
+	if size < count {
+		log.Warn("limiting scale out to available reserved capacity",
+			"capacity_reservation_id", reservation.id, "size", size)
+	} else if reservation != nil && count-current > reservation.available() {
+		log.Warn("scale out exceeds available reserved capacity",
+			"capacity_reservation_id", reservation.id, "available", reservation.available())
+	}
+
+	s := int(size)
+	_, err = t.management.UpdateInstancePool(ctx, core.UpdateInstancePoolRequest{
+		InstancePoolId:            pool.Id,
+		UpdateInstancePoolDetails: core.UpdateInstancePoolDetails{Size: &s},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update OCI instance pool size: %v", err)
+	}
+
+	if err := t.ensureInstancePoolRunning(ctx, pool, size); err != nil {
+		return fmt.Errorf("failed to confirm scale out OCI instance pool: %v", err)
+	}
+
+	log.Info("successfully performed and verified scaling out", "size", size)
+	return nil
+}
+
+// scaleIn drains the Nomad nodes selected for removal and then detaches their
+// instances from the pool, decrementing its size and terminating them.
+func (t *TargetPlugin) scaleIn(ctx context.Context, pool *core.InstancePool, num int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "instance_pool_id", *pool.Id)
+
+	instances, err := t.listInstances(ctx, pool)
+	if err != nil {
+		return fmt.Errorf("failed to list OCI instance pool instances: %v", err)
+	}
+
+	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, instanceRemoteIDs(instances), int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	instanceIDs, err := instanceOCIDs(instances, ids)
+	if err == nil {
+		err = t.detachInstances(ctx, pool, instanceIDs)
+	}
+	if err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return fmt.Errorf("failed to detach OCI instance pool instances: %v", err)
+	}
+
+	// Track the detachments until the instances leave the pool. A failure
+	// here should not fail the scaling activity as the detach requests have
+	// been accepted.
+	if err := t.ensureInstancesDetached(ctx, pool, instanceIDs); err != nil {
+		log.Error("failed to ensure all instances detached", "error", err)
+	} else {
+		log.Debug("confirmed OCI instance pool instances detached")
+	}
+
+	// The tasks run on nodes that have been successfully terminated should not
+	// cause a failure of the scaling pipeline.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		log.Error("failed to perform post-scale Nomad scale in tasks", "error", err)
+	}
+
+	log.Info("successfully performed and verified scaling in")
+	return nil
+}
+
+// detachInstances detaches the instances from the pool. Decrementing the size
+// stops the pool from replacing the instances, which are terminated once
+// detached.
+func (t *TargetPlugin) detachInstances(ctx context.Context, pool *core.InstancePool, instanceIDs []string) error {
+
+	decrement, terminate := true, true
+
+	for _, id := range instanceIDs {
+		instanceID := id
+		_, err := t.management.DetachInstancePoolInstance(ctx, core.DetachInstancePoolInstanceRequest{
+			InstancePoolId: pool.Id,
+			DetachInstancePoolInstanceDetails: core.DetachInstancePoolInstanceDetails{
+				InstanceId:      &instanceID,
+				IsDecrementSize: &decrement,
+				IsAutoTerminate: &terminate,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to detach instance %s: %v", instanceID, err)
+		}
+	}
+	return nil
+}
+
+// ensureInstancePoolRunning waits until the instance pool is running with the
+// desired number of running instances.
+func (t *TargetPlugin) ensureInstancePoolRunning(ctx context.Context, pool *core.InstancePool, desired int64) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		current, err := t.getInstancePool(ctx, *pool.Id)
+		if err != nil {
+			return true, err
+		}
+		if current.LifecycleState != core.InstancePoolLifecycleStateRunning {
+			return false, fmt.Errorf("OCI instance pool is %s", strings.ToLower(string(current.LifecycleState)))
+		}
+
+		instances, err := t.listInstances(ctx, pool)
+		if err != nil {
+			return true, err
+		}
+		if n := len(instanceRemoteIDs(instances)); n < int(desired) {
+			return false, fmt.Errorf("OCI instance pool at %v running instances of desired %v", n, desired)
+		}
+		return true, nil
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// ensureInstancesDetached waits until none of the instances are attached to
+// the pool.
+func (t *TargetPlugin) ensureInstancesDetached(ctx context.Context, pool *core.InstancePool, instanceIDs []string) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		instances, err := t.listInstances(ctx, pool)
+		if err != nil {
+			return true, err
+		}
+
+		if n := countAttached(instances, instanceIDs); n > 0 {
+			return false, fmt.Errorf("waiting for %v instances to detach", n)
+		}
+		return true, nil
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// instanceRemoteIDs returns the lowercase display names of the pool instances
+// which can be selected for scale in, matching the hostnames OCI assigns to
+// the instances.
+func instanceRemoteIDs(instances []core.InstanceSummary) []string {
+	remoteIDs := []string{}
+	for _, inst := range instances {
+		if inst.DisplayName != nil && inst.State != nil && strings.EqualFold(*inst.State, instanceStateRunning) {
+			remoteIDs = append(remoteIDs, strings.ToLower(*inst.DisplayName))
+		}
+	}
+	return remoteIDs
+}
+
+// instanceOCIDs returns the OCIDs of the pool instances selected for scale
+// in.
+func instanceOCIDs(instances []core.InstanceSummary, ids []scaleutils.NodeResourceID) ([]string, error) {
+
+	byName := make(map[string]string, len(instances))
+	for _, inst := range instances {
+		if inst.DisplayName != nil && inst.Id != nil {
+			byName[strings.ToLower(*inst.DisplayName)] = *inst.Id
+		}
+	}
+
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		ocid, ok := byName[id.RemoteResourceID]
+		if !ok {
+			return nil, fmt.Errorf("instance %s not found in instance pool", id.RemoteResourceID)
+		}
+		out = append(out, ocid)
+	}
+	return out, nil
+}
+
+// countAttached returns how many of the instance OCIDs are still attached to
+// the pool.
+func countAttached(instances []core.InstanceSummary, instanceIDs []string) int {
+	attached := make(map[string]struct{}, len(instances))
+	for _, inst := range instances {
+		if inst.Id != nil {
+			attached[*inst.Id] = struct{}{}
+		}
+	}
+
+	var n int
+	for _, id := range instanceIDs {
+		if _, ok := attached[id]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// ociNodeIDMap is used to identify the pool instance of a Nomad node using its
+// hostname.
+func ociNodeIDMap(n *api.Node) (string, error) {
+	val, ok := n.Attributes[nodeAttrHostname]
+	if !ok || val == "" {
+		return "", fmt.Errorf("attribute %q not found", nodeAttrHostname)
+	}
+
+	// Instances may be configured to use a fully qualified hostname, so only
+	// use the first label which holds the instance name.
+	if idx := strings.Index(val, "."); idx != -1 {
+		val = val[:idx]
+	}
+	return strings.ToLower(val), nil
+}
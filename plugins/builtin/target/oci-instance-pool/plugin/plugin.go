@@ -0,0 +1,236 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/oracle/oci-go-sdk/v65/core"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "oci-instance-pool"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle.
+	configKeyAuthType             = "oci_auth_type"
+	configKeyTenancyOCID          = "oci_tenancy_ocid"
+	configKeyUserOCID             = "oci_user_ocid"
+	configKeyFingerprint          = "oci_fingerprint"
+	configKeyPrivateKey           = "oci_private_key"
+	configKeyPrivateKeyPassphrase = "oci_private_key_passphrase"
+	configKeyRegion               = "oci_region"
+	configKeyConfigFile           = "oci_config_file"
+	configKeyConfigProfile        = "oci_config_profile"
+	configKeyInstancePoolID       = "oci_instance_pool_id"
+	configKeyRetryAttempts        = "retry_attempts"
+
+	// configKeyCapacityReservationID overrides the capacity reservation
+	// discovered from the instance configuration of the pool, while
+	// configKeyCapacityReservationStrict limits scale out to the available
+	// reserved capacity.
+	configKeyCapacityReservationID     = "oci_capacity_reservation_id"
+	configKeyCapacityReservationStrict = "oci_capacity_reservation_strict"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueRetryAttemptsDefault = "15"
+
+	// authTypes are the valid options for the oci_auth_type configuration
+	// key.
+	authTypeAPIKey            = "api_key"
+	authTypeInstancePrincipal = "instance_principal"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewOCIInstancePoolPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the OCI instance pool implementation of the target.Target
+// interface.
+type TargetPlugin struct {
+	config     map[string]string
+	logger     hclog.Logger
+	compute    core.ComputeClient
+	management core.ComputeManagementClient
+
+	// retryAttempts is the number of times operations such as wating for a
+	// given instance pool state should be retried.
+	retryAttempts int
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools and performing scaling tasks.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewOCIInstancePoolPlugin returns the OCI instance pool implementation of
+// the target.Target interface.
+func NewOCIInstancePoolPlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	if err := t.setupOCIClients(config); err != nil {
+		return err
+	}
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+
+	// Store and set the remote ID callback function.
+	t.clusterUtils = clusterUtils
+	t.clusterUtils.ClusterNodeIDLookupFunc = ociNodeIDMap
+
+	retryLimit, err := strconv.Atoi(getConfigValue(config, configKeyRetryAttempts, configValueRetryAttemptsDefault))
+	if err != nil {
+		return err
+	}
+	t.retryAttempts = retryLimit
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// OCI can't support dry-run like Nomad, so just exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	// We cannot scale an instance pool without knowing its OCID.
+	poolID, ok := config[configKeyInstancePoolID]
+	if !ok {
+		return fmt.Errorf("required config param %s not found", configKeyInstancePoolID)
+	}
+	ctx := context.Background()
+
+	// Get the instance pool. This serves to both validate the config value is
+	// correct and ensure the OCI client is configured correctly. The response
+	// can also be used when performing the scaling, meaning we only need to
+	// call it once.
+	pool, err := t.getInstancePool(ctx, poolID)
+	if err != nil {
+		return fmt.Errorf("failed to get OCI instance pool: %v", err)
+	}
+	currentCount := int64(*pool.Size)
+
+	// The instance pool requires different details depending on which
+	// direction we want to scale. Therefore calculate the direction and the
+	// relevant number so we can correctly perform the OCI work.
+	num, direction := t.calculateDirection(currentCount, action.Count)
+
+	switch direction {
+	case "in":
+		err = t.scaleIn(ctx, pool, num, config)
+	case "out":
+		err = t.scaleOut(ctx, pool, num, config)
+	default:
+		t.logger.Info("scaling not required", "instance_pool_id", poolID,
+			"current_count", currentCount, "strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err != nil {
+		err = fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return err
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool. If the pool is not ready, we
+	// can exit here and avoid calling the OCI API as it won't affect the
+	// outcome.
+	ready, err := t.clusterUtils.IsPoolReady(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+	}
+	if !ready {
+		return &sdk.TargetStatus{Ready: ready}, nil
+	}
+
+	poolID, ok := config[configKeyInstancePoolID]
+	if !ok {
+		return nil, fmt.Errorf("required config param %s not found", configKeyInstancePoolID)
+	}
+	ctx := context.Background()
+
+	pool, err := t.getInstancePool(ctx, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OCI instance pool: %v", err)
+	}
+
+	// The instance pool is only ready when running, as it is otherwise still
+	// being scaled, started or stopped.
+	resp := sdk.TargetStatus{
+		Ready: pool.LifecycleState == core.InstancePoolLifecycleStateRunning,
+		Count: int64(*pool.Size),
+		Meta:  make(map[string]string),
+	}
+
+	reservation, err := t.capacityReservation(ctx, pool, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OCI capacity reservation: %v", err)
+	}
+	reservation.processMeta(&resp)
+
+	return &resp, nil
+}
+
+func (t *TargetPlugin) calculateDirection(poolSize, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < poolSize {
+		return poolSize - strategyDesired, "in"
+	}
+	if strategyDesired > poolSize {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,106 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/oracle/oci-go-sdk/v65/core"
+)
+
+const (
+	// metaKeys are the status meta keys used to report the capacity of the
+	// compute capacity reservation the instance pool launches instances into.
+	metaKeyReservationReserved  = "oci.capacity_reservation_reserved_count"
+	metaKeyReservationUsed      = "oci.capacity_reservation_used_count"
+	metaKeyReservationAvailable = "oci.capacity_reservation_available_count"
+)
+
+// reservationCapacity is the capacity of a compute capacity reservation. A nil
+// reservationCapacity means the instance pool does not use a reservation.
+type reservationCapacity struct {
+	id       string
+	reserved int64
+	used     int64
+}
+
+// available returns the number of reserved instances which are not in use.
+func (r *reservationCapacity) available() int64 {
+	if n := r.reserved - r.used; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// processMeta updates the status meta with the capacity of the reservation.
+func (r *reservationCapacity) processMeta(status *sdk.TargetStatus) {
+	if r == nil {
+		return
+	}
+	status.Meta[metaKeyReservationReserved] = strconv.FormatInt(r.reserved, 10)
+	status.Meta[metaKeyReservationUsed] = strconv.FormatInt(r.used, 10)
+	status.Meta[metaKeyReservationAvailable] = strconv.FormatInt(r.available(), 10)
+}
+
+// limitScaleOut returns the instance pool size to scale out to. When strict,
+// the size is limited to the available reserved capacity so the pool does not
+// attempt to launch instances which cannot be placed.
+func (r *reservationCapacity) limitScaleOut(current, desired int64, strict bool) (int64, error) {
+	if r == nil || !strict || desired-current <= r.available() {
+		return desired, nil
+	}
+	if r.available() == 0 {
+		return 0, fmt.Errorf("no capacity available in capacity reservation %s", r.id)
+	}
+	return current + r.available(), nil
+}
+
+// capacityReservation returns the capacity of the reservation the instance
+// pool launches instances into. The reservation is read from the instance
+// configuration of the pool, unless overridden within the config.
+func (t *TargetPlugin) capacityReservation(ctx context.Context, pool *core.InstancePool, config map[string]string) (*reservationCapacity, error) {
+
+	id, ok := config[configKeyCapacityReservationID]
+	if !ok {
+		resp, err := t.management.GetInstanceConfiguration(ctx, core.GetInstanceConfigurationRequest{
+			InstanceConfigurationId: pool.InstanceConfigurationId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get instance configuration: %v", err)
+		}
+		id = instanceConfigurationReservation(resp.InstanceConfiguration)
+	}
+	if id == "" {
+		return nil, nil
+	}
+
+	resp, err := t.compute.GetComputeCapacityReservation(ctx, core.GetComputeCapacityReservationRequest{
+		CapacityReservationId: &id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := reservationCapacity{id: id}
+	if resp.ReservedInstanceCount != nil {
+		r.reserved = *resp.ReservedInstanceCount
+	}
+	if resp.UsedInstanceCount != nil {
+		r.used = *resp.UsedInstanceCount
+	}
+	return &r, nil
+}
+
+// instanceConfigurationReservation returns the capacity reservation the
+// instance configuration launches instances into, if any.
+func instanceConfigurationReservation(ic core.InstanceConfiguration) string {
+	details, ok := ic.InstanceDetails.(core.ComputeInstanceDetails)
+	if !ok || details.LaunchDetails == nil || details.LaunchDetails.CapacityReservationId == nil {
+		return ""
+	}
+	return *details.LaunchDetails.CapacityReservationId
+}
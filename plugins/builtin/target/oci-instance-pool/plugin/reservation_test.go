@@ -0,0 +1,108 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/oracle/oci-go-sdk/v65/common"
+	"github.com/oracle/oci-go-sdk/v65/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_reservationCapacity_limitScaleOut(t *testing.T) {
+	testCases := []struct {
+		inputReservation    *reservationCapacity
+		inputCurrent        int64
+		inputDesired        int64
+		inputStrict         bool
+		expectedOutput      int64
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputReservation: nil,
+			inputCurrent:     2,
+			inputDesired:     5,
+			inputStrict:      true,
+			expectedOutput:   5,
+			name:             "no reservation",
+		},
+		{
+			inputReservation: &reservationCapacity{id: "r1", reserved: 10, used: 2},
+			inputCurrent:     2,
+			inputDesired:     5,
+			inputStrict:      true,
+			expectedOutput:   5,
+			name:             "within available capacity",
+		},
+		{
+			inputReservation: &reservationCapacity{id: "r1", reserved: 4, used: 2},
+			inputCurrent:     2,
+			inputDesired:     5,
+			inputStrict:      false,
+			expectedOutput:   5,
+			name:             "exceeds available capacity not strict",
+		},
+		{
+			inputReservation: &reservationCapacity{id: "r1", reserved: 4, used: 2},
+			inputCurrent:     2,
+			inputDesired:     5,
+			inputStrict:      true,
+			expectedOutput:   4,
+			name:             "exceeds available capacity strict",
+		},
+		{
+			inputReservation:    &reservationCapacity{id: "r1", reserved: 4, used: 4},
+			inputCurrent:        4,
+			inputDesired:        5,
+			inputStrict:         true,
+			expectedOutput:      0,
+			expectedOutputError: errors.New("no capacity available in capacity reservation r1"),
+			name:                "no available capacity strict",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualErr := tc.inputReservation.limitScaleOut(tc.inputCurrent, tc.inputDesired, tc.inputStrict)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_reservationCapacity_processMeta(t *testing.T) {
+	status := sdk.TargetStatus{Meta: map[string]string{}}
+
+	var r *reservationCapacity
+	r.processMeta(&status)
+	assert.Empty(t, status.Meta)
+
+	r = &reservationCapacity{id: "r1", reserved: 4, used: 5}
+	r.processMeta(&status)
+	assert.Equal(t, map[string]string{
+		"oci.capacity_reservation_reserved_count":  "4",
+		"oci.capacity_reservation_used_count":      "5",
+		"oci.capacity_reservation_available_count": "0",
+	}, status.Meta)
+}
+
+func Test_instanceConfigurationReservation(t *testing.T) {
+	assert.Equal(t, "", instanceConfigurationReservation(core.InstanceConfiguration{}))
+
+	assert.Equal(t, "", instanceConfigurationReservation(core.InstanceConfiguration{
+		InstanceDetails: core.ComputeInstanceDetails{LaunchDetails: &core.InstanceConfigurationLaunchInstanceDetails{}},
+	}))
+
+	assert.Equal(t, "ocid1.capacityreservation.1", instanceConfigurationReservation(core.InstanceConfiguration{
+		InstanceDetails: core.ComputeInstanceDetails{
+			LaunchDetails: &core.InstanceConfigurationLaunchInstanceDetails{
+				CapacityReservationId: common.String("ocid1.capacityreservation.1"),
+			},
+		},
+	}))
+}
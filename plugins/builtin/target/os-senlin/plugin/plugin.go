@@ -0,0 +1,218 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/gophercloud/gophercloud"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "os-senlin"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle. The authentication
+	// keys mirror the OS_* environment variables, which are used when
+	// os_auth_url is not set.
+	configKeyAuthURL                     = "os_auth_url"
+	configKeyUsername                    = "os_username"
+	configKeyPassword                    = "os_password"
+	configKeyUserDomainName              = "os_user_domain_name"
+	configKeyProjectID                   = "os_project_id"
+	configKeyProjectName                 = "os_project_name"
+	configKeyProjectDomainName           = "os_project_domain_name"
+	configKeyApplicationCredentialID     = "os_application_credential_id"
+	configKeyApplicationCredentialSecret = "os_application_credential_secret"
+	configKeyRegionName                  = "os_region_name"
+	configKeyCluster                     = "os_senlin_cluster"
+	configKeyRetryAttempts               = "retry_attempts"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin.
+	configValueRetryAttemptsDefault = "15"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewOpenStackSenlinPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the OpenStack Senlin implementation of the target.Target
+// interface. Senlin clusters manage a pool of Nova servers created from a
+// profile, and allow the plugin to choose which nodes are removed.
+type TargetPlugin struct {
+	config map[string]string
+	logger hclog.Logger
+	senlin *gophercloud.ServiceClient
+
+	// retryAttempts is the number of times operations such as wating for a
+	// Senlin action to complete should be retried.
+	retryAttempts int
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools and performing scaling tasks.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewOpenStackSenlinPlugin returns the OpenStack Senlin implementation of the
+// target.Target interface.
+func NewOpenStackSenlinPlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	if err := t.setupOpenStackClient(config); err != nil {
+		return err
+	}
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+
+	// Store and set the remote ID callback function.
+	t.clusterUtils = clusterUtils
+	t.clusterUtils.ClusterNodeIDLookupFunc = senlinNodeIDMap
+
+	retryLimit, err := strconv.Atoi(getConfigValue(config, configKeyRetryAttempts, configValueRetryAttemptsDefault))
+	if err != nil {
+		return err
+	}
+	t.retryAttempts = retryLimit
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// OpenStack can't support dry-run like Nomad, so just exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	// We cannot scale a Senlin cluster without knowing its name or ID.
+	clusterRef, ok := config[configKeyCluster]
+	if !ok {
+		return fmt.Errorf("required config param %s not found", configKeyCluster)
+	}
+	ctx := context.Background()
+
+	// Get the cluster. This serves to both validate the config value is
+	// correct and ensure the OpenStack client is configured correctly. The
+	// response can also be used when performing the scaling, meaning we only
+	// need to call it once.
+	cluster, err := t.getCluster(clusterRef)
+	if err != nil {
+		return fmt.Errorf("failed to get OpenStack Senlin cluster: %v", err)
+	}
+	currentCount := int64(cluster.DesiredCapacity)
+
+	// The cluster requires different details depending on which direction we
+	// want to scale. Therefore calculate the direction and the relevant number
+	// so we can correctly perform the OpenStack work.
+	num, direction := t.calculateDirection(currentCount, action.Count)
+
+	switch direction {
+	case "in":
+		err = t.scaleIn(ctx, cluster.ID, num, config)
+	case "out":
+		err = t.scaleOut(ctx, cluster.ID, num)
+	default:
+		t.logger.Info("scaling not required", "cluster", cluster.Name,
+			"current_count", currentCount, "strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err != nil {
+		err = fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return err
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool. If the pool is not ready, we
+	// can exit here and avoid calling the OpenStack API as it won't affect the
+	// outcome.
+	ready, err := t.clusterUtils.IsPoolReady(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+	}
+	if !ready {
+		return &sdk.TargetStatus{Ready: ready}, nil
+	}
+
+	clusterRef, ok := config[configKeyCluster]
+	if !ok {
+		return nil, fmt.Errorf("required config param %s not found", configKeyCluster)
+	}
+
+	cluster, err := t.getCluster(clusterRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OpenStack Senlin cluster: %v", err)
+	}
+
+	// The cluster is only ready when it is active, meaning no action such as
+	// a resize is in progress and all its nodes are healthy.
+	return &sdk.TargetStatus{
+		Ready: cluster.Status == clusterStatusActive,
+		Count: int64(cluster.DesiredCapacity),
+		Meta:  make(map[string]string),
+	}, nil
+}
+
+func (t *TargetPlugin) calculateDirection(clusterDesired, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < clusterDesired {
+		return clusterDesired - strategyDesired, "in"
+	}
+	if strategyDesired > clusterDesired {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
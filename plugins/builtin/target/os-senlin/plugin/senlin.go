@@ -0,0 +1,304 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/clustering/v1/actions"
+	"github.com/gophercloud/gophercloud/openstack/clustering/v1/clusters"
+	"github.com/gophercloud/gophercloud/openstack/clustering/v1/nodes"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	defaultRetryInterval = 10 * time.Second
+
+	// nodeAttrHostname is the attribute used to identify the Senlin node of a
+	// Nomad node. The Nova servers created by Senlin are named after their
+	// node, and Nova derives the lowercase hostname from the server name.
+	nodeAttrHostname = "unique.hostname"
+
+	// envVarRegionName is the environment variable used to read the region
+	// when the authentication details are read from the environment.
+	envVarRegionName = "OS_REGION_NAME"
+
+	// clusterStatusActive and nodeStatusActive are the Senlin statuses of
+	// clusters and nodes which are healthy and have no action in progress.
+	clusterStatusActive = "ACTIVE"
+	nodeStatusActive    = "ACTIVE"
+
+	// actionStatuses are the terminal statuses of a Senlin action.
+	actionStatusSucceeded = "SUCCEEDED"
+	actionStatusFailed    = "FAILED"
+	actionStatusCancelled = "CANCELLED"
+)
+
+// setupOpenStackClient takes the passed config mapping and instantiates the
+// required OpenStack Senlin service client.
+func (t *TargetPlugin) setupOpenStackClient(config map[string]string) error {
+
+	opts, region, err := authOptions(config)
+	if err != nil {
+		return err
+	}
+
+	// Allow the client to re-authenticate once the token expires, as the
+	// plugin is long running.
+	opts.AllowReauth = true
+
+	provider, err := openstack.AuthenticatedClient(opts)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate OpenStack client: %v", err)
+	}
+
+	senlin, err := openstack.NewClusteringV1(provider, gophercloud.EndpointOpts{Region: region})
+	if err != nil {
+		return fmt.Errorf("failed to create OpenStack Senlin client: %v", err)
+	}
+	t.senlin = senlin
+
+	return nil
+}
+
+// authOptions returns the OpenStack authentication options and region from
+// the config when os_auth_url is set, otherwise from the OS_* environment
+// variables.
+func authOptions(config map[string]string) (gophercloud.AuthOptions, string, error) {
+
+	authURL, ok := config[configKeyAuthURL]
+	if !ok || authURL == "" {
+		opts, err := openstack.AuthOptionsFromEnv()
+		if err != nil {
+			return gophercloud.AuthOptions{}, "", fmt.Errorf("failed to read OpenStack authentication from environment: %v", err)
+		}
+		return opts, os.Getenv(envVarRegionName), nil
+	}
+
+	opts := gophercloud.AuthOptions{
+		IdentityEndpoint:            authURL,
+		Username:                    config[configKeyUsername],
+		Password:                    config[configKeyPassword],
+		DomainName:                  config[configKeyUserDomainName],
+		TenantID:                    config[configKeyProjectID],
+		TenantName:                  config[configKeyProjectName],
+		ApplicationCredentialID:     config[configKeyApplicationCredentialID],
+		ApplicationCredentialSecret: config[configKeyApplicationCredentialSecret],
+	}
+
+	// Application credentials are already scoped to a project, otherwise the
+	// project may live in a different domain to the user.
+	if opts.ApplicationCredentialID == "" {
+		if domain := config[configKeyProjectDomainName]; domain != "" {
+			opts.Scope = &gophercloud.AuthScope{
+				ProjectID:   opts.TenantID,
+				ProjectName: opts.TenantName,
+				DomainName:  domain,
+			}
+		}
+	}
+
+	if opts.ApplicationCredentialID == "" && (opts.Username == "" || opts.Password == "") {
+		return gophercloud.AuthOptions{}, "", fmt.Errorf("required config params %s and %s, or %s and %s not found",
+			configKeyUsername, configKeyPassword, configKeyApplicationCredentialID, configKeyApplicationCredentialSecret)
+	}
+
+	return opts, config[configKeyRegionName], nil
+}
+
+// getCluster returns the Senlin cluster, which can be referenced by its name
+// or ID.
+func (t *TargetPlugin) getCluster(ref string) (*clusters.Cluster, error) {
+	return clusters.Get(t.senlin, ref).Extract()
+}
+
+// listNodes returns all the nodes of the Senlin cluster.
+func (t *TargetPlugin) listNodes(clusterID string) ([]nodes.Node, error) {
+	pages, err := nodes.List(t.senlin, nodes.ListOpts{ClusterID: clusterID}).AllPages()
+	if err != nil {
+		return nil, err
+	}
+	return nodes.ExtractNodes(pages)
+}
+
+// scaleOut resizes the Senlin cluster to the count the Autoscaler has deemed
+// required and waits for the new nodes to be created.
+func (t *TargetPlugin) scaleOut(ctx context.Context, clusterID string, count int64) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_out", "cluster_id", clusterID, "desired_count", count)
+
+	// Use a strict resize so the cluster is not silently resized to fit
+	// within its size limits.
+	strict := true
+	opts := clusters.ResizeOpts{
+		AdjustmentType: clusters.ExactCapacityAdjustment,
+		Number:         int(count),
+		Strict:         &strict,
+	}
+
+	actionID, err := clusters.Resize(t.senlin, clusterID, opts).Extract()
+	if err != nil {
+		return fmt.Errorf("failed to resize OpenStack Senlin cluster: %v", err)
+	}
+
+	if err := t.ensureActionComplete(ctx, actionID); err != nil {
+		return fmt.Errorf("failed to confirm scale out OpenStack Senlin cluster: %v", err)
+	}
+
+	log.Info("successfully performed and verified scaling out")
+	return nil
+}
+
+// scaleIn drains the Nomad nodes selected for removal and then deletes their
+// Senlin nodes, which also deletes the Nova servers and reduces the cluster
+// desired capacity.
+func (t *TargetPlugin) scaleIn(ctx context.Context, clusterID string, num int64, config map[string]string) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "cluster_id", clusterID)
+
+	clusterNodes, err := t.listNodes(clusterID)
+	if err != nil {
+		return fmt.Errorf("failed to list OpenStack Senlin cluster nodes: %v", err)
+	}
+
+	ids, err := t.clusterUtils.RunPreScaleInTasksWithRemoteCheck(ctx, config, nodeRemoteIDs(clusterNodes), int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	senlinIDs, err := senlinNodeIDs(clusterNodes, ids)
+	if err == nil {
+		err = t.deleteNodes(ctx, clusterID, senlinIDs)
+	}
+	if err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return fmt.Errorf("failed to delete OpenStack Senlin cluster nodes: %v", err)
+	}
+
+	// The tasks run on nodes that have been successfully deleted should not
+	// cause a failure of the scaling pipeline.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		log.Error("failed to perform post-scale Nomad scale in tasks", "error", err)
+	}
+
+	log.Info("successfully performed and verified scaling in")
+	return nil
+}
+
+// deleteNodesAction is the request body of the Senlin del_nodes cluster
+// action. The gophercloud RemoveNodesOpts does not support destroying the
+// nodes, which would otherwise be left running outside the cluster.
+type deleteNodesAction struct {
+	Nodes                []string `json:"nodes"`
+	DestroyAfterDeletion bool     `json:"destroy_after_deletion"`
+}
+
+// deleteNodes removes the nodes from the Senlin cluster, destroying them, and
+// waits for the action to complete.
+func (t *TargetPlugin) deleteNodes(ctx context.Context, clusterID string, nodeIDs []string) error {
+
+	body := map[string]interface{}{
+		"del_nodes": deleteNodesAction{Nodes: nodeIDs, DestroyAfterDeletion: true},
+	}
+
+	var r clusters.ActionResult
+	resp, err := t.senlin.Post(t.senlin.ServiceURL("v1", "clusters", clusterID, "actions"), body, &r.Body,
+		&gophercloud.RequestOpts{OkCodes: []int{202}})
+	_, r.Header, r.Err = gophercloud.ParseResponse(resp, err)
+
+	actionID, err := r.Extract()
+	if err != nil {
+		return err
+	}
+	return t.ensureActionComplete(ctx, actionID)
+}
+
+// ensureActionComplete waits until the Senlin action has completed, returning
+// an error if it did not succeed.
+func (t *TargetPlugin) ensureActionComplete(ctx context.Context, actionID string) error {
+
+	fn := func(ctx context.Context) (bool, error) {
+		action, err := actions.Get(t.senlin, actionID).Extract()
+		if err != nil {
+			return true, err
+		}
+		return actionDone(action)
+	}
+
+	return retry(ctx, defaultRetryInterval, t.retryAttempts, fn)
+}
+
+// actionDone returns whether the Senlin action reached a terminal status, and
+// an error if it did not succeed or is still in progress.
+func actionDone(action *actions.Action) (bool, error) {
+	switch action.Status {
+	case actionStatusSucceeded:
+		return true, nil
+	case actionStatusFailed, actionStatusCancelled:
+		return true, fmt.Errorf("action %s %s: %s", action.ID, strings.ToLower(action.Status), action.StatusReason)
+	default:
+		return false, fmt.Errorf("action %s is %s", action.ID, strings.ToLower(action.Status))
+	}
+}
+
+// nodeRemoteIDs returns the lowercase names of the Senlin nodes which can be
+// selected for scale in, matching the hostnames Nova assigns to the servers.
+func nodeRemoteIDs(clusterNodes []nodes.Node) []string {
+	remoteIDs := []string{}
+	for _, n := range clusterNodes {
+		if n.Status == nodeStatusActive {
+			remoteIDs = append(remoteIDs, strings.ToLower(n.Name))
+		}
+	}
+	return remoteIDs
+}
+
+// senlinNodeIDs returns the Senlin node IDs of the nodes selected for scale
+// in.
+func senlinNodeIDs(clusterNodes []nodes.Node, ids []scaleutils.NodeResourceID) ([]string, error) {
+
+	byName := make(map[string]string, len(clusterNodes))
+	for _, n := range clusterNodes {
+		byName[strings.ToLower(n.Name)] = n.ID
+	}
+
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		nodeID, ok := byName[id.RemoteResourceID]
+		if !ok {
+			return nil, fmt.Errorf("Senlin node %s not found", id.RemoteResourceID)
+		}
+		out = append(out, nodeID)
+	}
+	return out, nil
+}
+
+// senlinNodeIDMap is used to identify the Senlin node of a Nomad node using
+// its hostname.
+func senlinNodeIDMap(n *api.Node) (string, error) {
+	val, ok := n.Attributes[nodeAttrHostname]
+	if !ok || val == "" {
+		return "", fmt.Errorf("attribute %q not found", nodeAttrHostname)
+	}
+
+	// Servers may be configured to use a fully qualified hostname, so only
+	// use the first label which holds the server name.
+	if idx := strings.Index(val, "."); idx != -1 {
+		val = val[:idx]
+	}
+	return strings.ToLower(val), nil
+}
@@ -0,0 +1,186 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/clustering/v1/actions"
+	"github.com/gophercloud/gophercloud/openstack/clustering/v1/nodes"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_senlinNodeIDMap(t *testing.T) {
+	testCases := []struct {
+		inputNode           *api.Node
+		expectedOutputID    string
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{"unique.hostname": "node-abcd1234-001"},
+			},
+			expectedOutputID:    "node-abcd1234-001",
+			expectedOutputError: nil,
+			name:                "required attribute found",
+		},
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{"unique.hostname": "Node-AbCd1234-001.novalocal"},
+			},
+			expectedOutputID:    "node-abcd1234-001",
+			expectedOutputError: nil,
+			name:                "fully qualified hostname",
+		},
+		{
+			inputNode: &api.Node{
+				Attributes: map[string]string{},
+			},
+			expectedOutputID:    "",
+			expectedOutputError: errors.New(`attribute "unique.hostname" not found`),
+			name:                "required attribute not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualID, actualErr := senlinNodeIDMap(tc.inputNode)
+			assert.Equal(t, tc.expectedOutputID, actualID, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_nodeRemoteIDs(t *testing.T) {
+	clusterNodes := []nodes.Node{
+		{ID: "1", Name: "node-AbCd1234-001", Status: "ACTIVE"},
+		{ID: "2", Name: "node-AbCd1234-002", Status: "CREATING"},
+		{ID: "3", Name: "node-AbCd1234-003", Status: "ERROR"},
+		{ID: "4", Name: "node-AbCd1234-004", Status: "ACTIVE"},
+	}
+
+	assert.Equal(t, []string{"node-abcd1234-001", "node-abcd1234-004"}, nodeRemoteIDs(clusterNodes))
+	assert.Equal(t, []string{}, nodeRemoteIDs(nil))
+
+	ids, err := senlinNodeIDs(clusterNodes, []scaleutils.NodeResourceID{
+		{NomadNodeID: "nomad-4", RemoteResourceID: "node-abcd1234-004"},
+		{NomadNodeID: "nomad-1", RemoteResourceID: "node-abcd1234-001"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4", "1"}, ids)
+
+	_, err = senlinNodeIDs(clusterNodes, []scaleutils.NodeResourceID{
+		{NomadNodeID: "nomad-5", RemoteResourceID: "node-abcd1234-005"},
+	})
+	assert.EqualError(t, err, "Senlin node node-abcd1234-005 not found")
+}
+
+func Test_actionDone(t *testing.T) {
+	testCases := []struct {
+		inputAction         *actions.Action
+		expectedOutputStop  bool
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputAction:         &actions.Action{ID: "a1", Status: "SUCCEEDED"},
+			expectedOutputStop:  true,
+			expectedOutputError: nil,
+			name:                "succeeded",
+		},
+		{
+			inputAction:         &actions.Action{ID: "a1", Status: "FAILED", StatusReason: "quota exceeded"},
+			expectedOutputStop:  true,
+			expectedOutputError: errors.New("action a1 failed: quota exceeded"),
+			name:                "failed",
+		},
+		{
+			inputAction:         &actions.Action{ID: "a1", Status: "RUNNING"},
+			expectedOutputStop:  false,
+			expectedOutputError: errors.New("action a1 is running"),
+			name:                "in progress",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualStop, actualErr := actionDone(tc.inputAction)
+			assert.Equal(t, tc.expectedOutputStop, actualStop, tc.name)
+			assert.Equal(t, tc.expectedOutputError, actualErr, tc.name)
+		})
+	}
+}
+
+func Test_authOptions(t *testing.T) {
+	testCases := []struct {
+		inputConfig          map[string]string
+		expectedOutput       gophercloud.AuthOptions
+		expectedOutputRegion string
+		expectedOutputError  bool
+		name                 string
+	}{
+		{
+			inputConfig: map[string]string{
+				"os_auth_url":            "https://keystone.example.com:5000/v3",
+				"os_username":            "nomad",
+				"os_password":            "secret",
+				"os_user_domain_name":    "Default",
+				"os_project_name":        "nomad",
+				"os_project_domain_name": "Nomad",
+				"os_region_name":         "RegionOne",
+			},
+			expectedOutput: gophercloud.AuthOptions{
+				IdentityEndpoint: "https://keystone.example.com:5000/v3",
+				Username:         "nomad",
+				Password:         "secret",
+				DomainName:       "Default",
+				TenantName:       "nomad",
+				Scope:            &gophercloud.AuthScope{ProjectName: "nomad", DomainName: "Nomad"},
+			},
+			expectedOutputRegion: "RegionOne",
+			name:                 "password",
+		},
+		{
+			inputConfig: map[string]string{
+				"os_auth_url":                      "https://keystone.example.com:5000/v3",
+				"os_application_credential_id":     "id",
+				"os_application_credential_secret": "secret",
+				"os_project_domain_name":           "Nomad",
+			},
+			expectedOutput: gophercloud.AuthOptions{
+				IdentityEndpoint:            "https://keystone.example.com:5000/v3",
+				ApplicationCredentialID:     "id",
+				ApplicationCredentialSecret: "secret",
+			},
+			name: "application credential",
+		},
+		{
+			inputConfig: map[string]string{
+				"os_auth_url": "https://keystone.example.com:5000/v3",
+				"os_username": "nomad",
+			},
+			expectedOutputError: true,
+			name:                "missing password",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualRegion, actualErr := authOptions(tc.inputConfig)
+			if tc.expectedOutputError {
+				assert.Error(t, actualErr, tc.name)
+				return
+			}
+			require.NoError(t, actualErr, tc.name)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedOutputRegion, actualRegion, tc.name)
+		})
+	}
+}
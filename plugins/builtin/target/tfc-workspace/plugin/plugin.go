@@ -0,0 +1,248 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	hclog "github.com/hashicorp/go-hclog"
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "tfc-workspace"
+
+	// configKeys represents the known configuration parameters required at
+	// varying points throughout the plugins lifecycle. The address, token and
+	// retry attempts are set within the plugin config, while the remaining
+	// keys are set within the policy target config.
+	configKeyAddress       = "tfc_address"
+	configKeyToken         = "tfc_token"
+	configKeyRetryAttempts = "retry_attempts"
+	configKeyOrganization  = "tfc_organization"
+	configKeyWorkspace     = "tfc_workspace"
+	configKeyVariable      = "tfc_variable"
+	configKeyAutoApply     = "tfc_auto_apply"
+
+	// configValues are the default values used when a configuration key is not
+	// supplied by the operator that are specific to the plugin. Runs can take
+	// a long time to plan and apply, so they are polled for up to 30 minutes.
+	configValueRetryAttemptsDefault = "180"
+	configValueAutoApplyDefault     = "true"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewTFCWorkspacePlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the Terraform Cloud workspace implementation of the
+// target.Target interface. The count of the target is a Terraform variable of
+// the workspace, which is updated before triggering a run to apply it.
+//
+// Terraform decides which resources are destroyed when scaling in, so the
+// plugin cannot drain the Nomad nodes it removes beforehand. Pools of Nomad
+// nodes are only checked for readiness.
+type TargetPlugin struct {
+	config map[string]string
+	logger hclog.Logger
+	client *tfe.Client
+
+	// retryAttempts is the number of times the status of a run is polled
+	// while waiting for it to complete.
+	retryAttempts int
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools. It is only used when the target is a pool of Nomad
+	// nodes.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewTFCWorkspacePlugin returns the Terraform Cloud workspace implementation
+// of the target.Target interface.
+func NewTFCWorkspacePlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	if err := t.setupTFEClient(config); err != nil {
+		return err
+	}
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+	t.clusterUtils = clusterUtils
+
+	retryLimit, err := strconv.Atoi(getConfigValue(config, configKeyRetryAttempts, configValueRetryAttemptsDefault))
+	if err != nil {
+		return err
+	}
+	t.retryAttempts = retryLimit
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// Terraform Cloud can't support dry-run like Nomad, so just exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	ws, err := newWorkspaceTarget(config)
+	if err != nil {
+		return err
+	}
+
+	autoApply, err := strconv.ParseBool(getConfigValue(config, configKeyAutoApply, configValueAutoApplyDefault))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", configKeyAutoApply, err)
+	}
+	ctx := context.Background()
+
+	workspace, err := t.readWorkspace(ctx, ws)
+	if err != nil {
+		return fmt.Errorf("failed to read Terraform Cloud workspace: %v", err)
+	}
+
+	// Triggering a run while another is in progress would queue it behind
+	// the current run, and the count would no longer reflect the applied
+	// infrastructure.
+	if !isWorkspaceReady(workspace) {
+		return fmt.Errorf("workspace %s is locked or has a run in progress", ws)
+	}
+
+	variable, err := t.readVariable(ctx, workspace.ID, ws.variable)
+	if err != nil {
+		return fmt.Errorf("failed to read Terraform Cloud workspace variable: %v", err)
+	}
+
+	currentCount, err := parseCount(variable)
+	if err != nil {
+		return err
+	}
+
+	_, direction := t.calculateDirection(currentCount, action.Count)
+	if direction == "" {
+		t.logger.Info("scaling not required", "workspace", ws.String(),
+			"current_count", currentCount, "strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err := t.scale(ctx, workspace, variable, currentCount, action, autoApply); err != nil {
+		return fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return nil
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool when the target is a pool of
+	// nodes. If the pool is not ready, we can exit here and avoid calling the
+	// Terraform Cloud API as it won't affect the outcome.
+	if isNodePoolTarget(config) {
+		ready, err := t.clusterUtils.IsPoolReady(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+		}
+		if !ready {
+			return &sdk.TargetStatus{Ready: ready}, nil
+		}
+	}
+
+	ws, err := newWorkspaceTarget(config)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+
+	workspace, err := t.readWorkspace(ctx, ws)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Terraform Cloud workspace: %v", err)
+	}
+
+	variable, err := t.readVariable(ctx, workspace.ID, ws.variable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Terraform Cloud workspace variable: %v", err)
+	}
+
+	count, err := parseCount(variable)
+	if err != nil {
+		return nil, err
+	}
+
+	status := sdk.TargetStatus{
+		Ready: isWorkspaceReady(workspace),
+		Count: count,
+		Meta:  make(map[string]string),
+	}
+
+	if run := workspace.CurrentRun; run != nil && run.StatusTimestamps != nil &&
+		!run.StatusTimestamps.AppliedAt.IsZero() {
+		status.Meta[sdk.TargetStatusMetaKeyLastEvent] = strconv.FormatInt(run.StatusTimestamps.AppliedAt.UnixNano(), 10)
+	}
+
+	return &status, nil
+}
+
+func (t *TargetPlugin) calculateDirection(currentCount, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < currentCount {
+		return currentCount - strategyDesired, "in"
+	}
+	if strategyDesired > currentCount {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+// isNodePoolTarget returns whether the target config identifies a pool of
+// Nomad nodes, whose readiness is checked alongside the workspace.
+func isNodePoolTarget(config map[string]string) bool {
+	return (&sdk.ScalingPolicyTarget{Config: config}).IsNodePoolTarget()
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
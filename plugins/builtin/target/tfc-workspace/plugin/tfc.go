@@ -0,0 +1,261 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// defaultRetryInterval is the interval at which the status of a run is
+	// polled while waiting for it to complete.
+	defaultRetryInterval = 10 * time.Second
+
+	// variablesPageSize is the number of variables requested per page when
+	// listing the variables of a workspace.
+	variablesPageSize = 100
+)
+
+// workspaceTarget identifies the workspace and variable of a policy target.
+type workspaceTarget struct {
+	organization string
+	workspace    string
+	variable     string
+}
+
+// newWorkspaceTarget reads the workspace and variable from the policy target
+// config.
+func newWorkspaceTarget(config map[string]string) (*workspaceTarget, error) {
+
+	var ws workspaceTarget
+
+	for _, param := range []struct {
+		key string
+		val *string
+	}{
+		{key: configKeyOrganization, val: &ws.organization},
+		{key: configKeyWorkspace, val: &ws.workspace},
+		{key: configKeyVariable, val: &ws.variable},
+	} {
+		v, ok := config[param.key]
+		if !ok || v == "" {
+			return nil, fmt.Errorf("required config param %s not found", param.key)
+		}
+		*param.val = v
+	}
+	return &ws, nil
+}
+
+func (w *workspaceTarget) String() string {
+	return w.organization + "/" + w.workspace
+}
+
+// setupTFEClient takes the passed config mapping and instantiates the
+// required Terraform Cloud client. The address and token fall back to the
+// TFE_ADDRESS and TFE_TOKEN environment variables read by the client.
+func (t *TargetPlugin) setupTFEClient(config map[string]string) error {
+
+	client, err := tfe.NewClient(&tfe.Config{
+		Address:           config[configKeyAddress],
+		Token:             config[configKeyToken],
+		RetryServerErrors: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Terraform Cloud client: %v", err)
+	}
+
+	t.client = client
+	return nil
+}
+
+// readWorkspace reads the workspace along with its current run.
+func (t *TargetPlugin) readWorkspace(ctx context.Context, ws *workspaceTarget) (*tfe.Workspace, error) {
+	return t.client.Workspaces.ReadWithOptions(ctx, ws.organization, ws.workspace,
+		&tfe.WorkspaceReadOptions{Include: []tfe.WSIncludeOpt{tfe.WSCurrentRun}})
+}
+
+// readVariable finds the Terraform variable with the key within the
+// workspace.
+func (t *TargetPlugin) readVariable(ctx context.Context, workspaceID, key string) (*tfe.Variable, error) {
+
+	opts := tfe.VariableListOptions{ListOptions: tfe.ListOptions{PageSize: variablesPageSize}}
+
+	for {
+		list, err := t.client.Variables.List(ctx, workspaceID, &opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range list.Items {
+			if v.Key == key && v.Category == tfe.CategoryTerraform {
+				return v, nil
+			}
+		}
+
+		if list.Pagination == nil || list.Pagination.NextPage == 0 {
+			return nil, fmt.Errorf("terraform variable %q not found", key)
+		}
+		opts.PageNumber = list.Pagination.NextPage
+	}
+}
+
+// scale updates the variable to the desired count and triggers a run of the
+// workspace to apply it, waiting for the run to complete.
+func (t *TargetPlugin) scale(ctx context.Context, workspace *tfe.Workspace, variable *tfe.Variable,
+	currentCount int64, action sdk.ScalingAction, autoApply bool) error {
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale", "workspace_id", workspace.ID,
+		"variable", variable.Key, "current_count", currentCount, "desired_count", action.Count)
+
+	if err := t.updateVariable(ctx, workspace.ID, variable.ID, action.Count); err != nil {
+		return fmt.Errorf("failed to update variable %q: %v", variable.Key, err)
+	}
+
+	run, err := t.client.Runs.Create(ctx, tfe.RunCreateOptions{
+		Workspace: workspace,
+		Message:   tfe.String(runMessage(variable.Key, currentCount, action)),
+		AutoApply: tfe.Bool(autoApply),
+	})
+	if err != nil {
+		t.revertVariable(ctx, log, workspace.ID, variable.ID, currentCount)
+		return fmt.Errorf("failed to create run: %v", err)
+	}
+	log = log.With("run_id", run.ID)
+	log.Info("successfully created run")
+
+	run, err = t.waitForRun(ctx, run.ID, autoApply)
+	if err != nil {
+		// The next run would converge the infrastructure back to the previous
+		// count, so reverting the variable keeps the count reported by Status
+		// accurate. Runs which are still in progress are left alone.
+		if run != nil && isRunFailed(run.Status) {
+			t.revertVariable(ctx, log, workspace.ID, variable.ID, currentCount)
+		}
+		return err
+	}
+
+	log.Info("successfully performed scaling", "run_status", run.Status)
+	return nil
+}
+
+// updateVariable sets the value of the variable to the count.
+func (t *TargetPlugin) updateVariable(ctx context.Context, workspaceID, variableID string, count int64) error {
+	_, err := t.client.Variables.Update(ctx, workspaceID, variableID, tfe.VariableUpdateOptions{
+		Value: tfe.String(strconv.FormatInt(count, 10)),
+	})
+	return err
+}
+
+// revertVariable sets the value of the variable back to the count before
+// scaling. Failures are only logged, as the original error is more useful to
+// the operator.
+func (t *TargetPlugin) revertVariable(ctx context.Context, log hclog.Logger, workspaceID, variableID string, count int64) {
+	if err := t.updateVariable(ctx, workspaceID, variableID, count); err != nil {
+		log.Error("failed to revert variable", "error", err)
+	}
+}
+
+// waitForRun polls the run until it has been applied or needs no changes.
+// When auto apply is disabled, the run is left to the operator to confirm
+// once it has been planned. The last read run is returned alongside any
+// error so the caller can inspect its status.
+func (t *TargetPlugin) waitForRun(ctx context.Context, runID string, autoApply bool) (*tfe.Run, error) {
+
+	var run *tfe.Run
+
+	f := func(ctx context.Context) (bool, error) {
+
+		r, err := t.client.Runs.Read(ctx, runID)
+		if err != nil {
+			return false, err
+		}
+		run = r
+
+		switch {
+		case run.Status == tfe.RunApplied, run.Status == tfe.RunPlannedAndFinished:
+			return true, nil
+		case isRunFailed(run.Status):
+			return true, fmt.Errorf("run %s finished with status %s", runID, run.Status)
+		case run.Status == tfe.RunPolicySoftFailed:
+			return true, fmt.Errorf("run %s failed a policy check and requires an override", runID)
+		case !autoApply && run.Actions != nil && run.Actions.IsConfirmable:
+			t.logger.Info("run is awaiting confirmation", "run_id", runID)
+			return true, nil
+		}
+		return false, fmt.Errorf("run %s has status %s", runID, run.Status)
+	}
+
+	if err := retry(ctx, defaultRetryInterval, t.retryAttempts, f); err != nil {
+		return run, fmt.Errorf("failed to wait for run to complete: %v", err)
+	}
+	return run, nil
+}
+
+// isWorkspaceReady returns whether the workspace is unlocked and has no run
+// in progress, including runs awaiting confirmation.
+func isWorkspaceReady(workspace *tfe.Workspace) bool {
+	if workspace.Locked {
+		return false
+	}
+	if workspace.CurrentRun == nil {
+		return true
+	}
+
+	switch workspace.CurrentRun.Status {
+	case tfe.RunApplied, tfe.RunPlannedAndFinished:
+		return true
+	default:
+		return isRunFailed(workspace.CurrentRun.Status)
+	}
+}
+
+// isRunFailed returns whether the run finished without applying its changes.
+// Runs which soft failed a policy check are not, as they block the workspace
+// until an operator overrides or discards them.
+func isRunFailed(status tfe.RunStatus) bool {
+	switch status {
+	case tfe.RunErrored, tfe.RunDiscarded, tfe.RunCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseCount parses the value of the variable as the count of the target.
+func parseCount(variable *tfe.Variable) (int64, error) {
+
+	// The value of sensitive variables is never returned by the API, so the
+	// count cannot be read.
+	if variable.Sensitive {
+		return 0, fmt.Errorf("terraform variable %q must not be sensitive", variable.Key)
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(variable.Value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse terraform variable %q as count: %v", variable.Key, err)
+	}
+	if count < 0 {
+		return 0, fmt.Errorf("terraform variable %q must not be negative", variable.Key)
+	}
+	return count, nil
+}
+
+// runMessage returns the message of the run triggered to scale the target.
+func runMessage(key string, currentCount int64, action sdk.ScalingAction) string {
+	msg := fmt.Sprintf("Nomad Autoscaler: scaling %s from %d to %d", key, currentCount, action.Count)
+	if action.Reason != "" {
+		msg += ": " + action.Reason
+	}
+	return msg
+}
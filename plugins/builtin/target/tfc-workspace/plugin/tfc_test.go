@@ -0,0 +1,180 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	tfe "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newWorkspaceTarget(t *testing.T) {
+	testCases := []struct {
+		inputConfig    map[string]string
+		expectedOutput *workspaceTarget
+		expectedError  error
+		name           string
+	}{
+		{
+			inputConfig: map[string]string{
+				configKeyOrganization: "hashicorp",
+				configKeyWorkspace:    "nomad-clients",
+				configKeyVariable:     "client_count",
+			},
+			expectedOutput: &workspaceTarget{
+				organization: "hashicorp",
+				workspace:    "nomad-clients",
+				variable:     "client_count",
+			},
+			expectedError: nil,
+			name:          "valid config",
+		},
+		{
+			inputConfig: map[string]string{
+				configKeyWorkspace: "nomad-clients",
+				configKeyVariable:  "client_count",
+			},
+			expectedOutput: nil,
+			expectedError:  errors.New("required config param tfc_organization not found"),
+			name:           "missing organization",
+		},
+		{
+			inputConfig: map[string]string{
+				configKeyOrganization: "hashicorp",
+				configKeyWorkspace:    "",
+				configKeyVariable:     "client_count",
+			},
+			expectedOutput: nil,
+			expectedError:  errors.New("required config param tfc_workspace not found"),
+			name:           "empty workspace",
+		},
+		{
+			inputConfig: map[string]string{
+				configKeyOrganization: "hashicorp",
+				configKeyWorkspace:    "nomad-clients",
+			},
+			expectedOutput: nil,
+			expectedError:  errors.New("required config param tfc_variable not found"),
+			name:           "missing variable",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualError := newWorkspaceTarget(tc.inputConfig)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedError, actualError, tc.name)
+		})
+	}
+}
+
+func Test_isWorkspaceReady(t *testing.T) {
+	testCases := []struct {
+		inputWorkspace *tfe.Workspace
+		expectedOutput bool
+		name           string
+	}{
+		{
+			inputWorkspace: &tfe.Workspace{},
+			expectedOutput: true,
+			name:           "no current run",
+		},
+		{
+			inputWorkspace: &tfe.Workspace{Locked: true},
+			expectedOutput: false,
+			name:           "locked",
+		},
+		{
+			inputWorkspace: &tfe.Workspace{CurrentRun: &tfe.Run{Status: tfe.RunApplied}},
+			expectedOutput: true,
+			name:           "current run applied",
+		},
+		{
+			inputWorkspace: &tfe.Workspace{CurrentRun: &tfe.Run{Status: tfe.RunPlannedAndFinished}},
+			expectedOutput: true,
+			name:           "current run planned without changes",
+		},
+		{
+			inputWorkspace: &tfe.Workspace{CurrentRun: &tfe.Run{Status: tfe.RunErrored}},
+			expectedOutput: true,
+			name:           "current run errored",
+		},
+		{
+			inputWorkspace: &tfe.Workspace{CurrentRun: &tfe.Run{Status: tfe.RunApplying}},
+			expectedOutput: false,
+			name:           "current run applying",
+		},
+		{
+			inputWorkspace: &tfe.Workspace{CurrentRun: &tfe.Run{Status: tfe.RunPlanned}},
+			expectedOutput: false,
+			name:           "current run awaiting confirmation",
+		},
+		{
+			inputWorkspace: &tfe.Workspace{CurrentRun: &tfe.Run{Status: tfe.RunPolicySoftFailed}},
+			expectedOutput: false,
+			name:           "current run awaiting override",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, isWorkspaceReady(tc.inputWorkspace), tc.name)
+		})
+	}
+}
+
+func Test_parseCount(t *testing.T) {
+	testCases := []struct {
+		inputVariable  *tfe.Variable
+		expectedOutput int64
+		expectedError  error
+		name           string
+	}{
+		{
+			inputVariable:  &tfe.Variable{Key: "client_count", Value: "3"},
+			expectedOutput: 3,
+			expectedError:  nil,
+			name:           "valid count",
+		},
+		{
+			inputVariable:  &tfe.Variable{Key: "client_count", Value: " 0\n"},
+			expectedOutput: 0,
+			expectedError:  nil,
+			name:           "count with whitespace",
+		},
+		{
+			inputVariable:  &tfe.Variable{Key: "client_count", Sensitive: true},
+			expectedOutput: 0,
+			expectedError:  errors.New(`terraform variable "client_count" must not be sensitive`),
+			name:           "sensitive variable",
+		},
+		{
+			inputVariable:  &tfe.Variable{Key: "client_count", Value: "-1"},
+			expectedOutput: 0,
+			expectedError:  errors.New(`terraform variable "client_count" must not be negative`),
+			name:           "negative count",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualError := parseCount(tc.inputVariable)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedError, actualError, tc.name)
+		})
+	}
+
+	_, err := parseCount(&tfe.Variable{Key: "client_count", Value: "three"})
+	assert.ErrorContains(t, err, `failed to parse terraform variable "client_count" as count`)
+}
+
+func Test_runMessage(t *testing.T) {
+	assert.Equal(t, "Nomad Autoscaler: scaling client_count from 3 to 5: capacity below target",
+		runMessage("client_count", 3, sdk.ScalingAction{Count: 5, Reason: "capacity below target"}))
+	assert.Equal(t, "Nomad Autoscaler: scaling client_count from 3 to 1",
+		runMessage("client_count", 3, sdk.ScalingAction{Count: 1}))
+}
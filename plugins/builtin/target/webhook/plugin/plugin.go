@@ -0,0 +1,265 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/tlsutil"
+)
+
+const (
+	// pluginName is the unique name of the this plugin amongst Target plugins.
+	pluginName = "webhook"
+
+	// configKeyAddress is the optional base URL which relative scale and
+	// status URLs are resolved against.
+	configKeyAddress = "address"
+
+	// configKeyBasicAuthUser and configKeyBasicAuthPassword configure basic
+	// auth, while configKeyBearerToken configures bearer token auth.
+	configKeyBasicAuthUser     = "basic_auth_user"
+	configKeyBasicAuthPassword = "basic_auth_password"
+	configKeyBearerToken       = "bearer_token"
+
+	// configKeyHeadersPrefix is the prefix used to indicate that a
+	// configuration value should be set as an HTTP header.
+	configKeyHeadersPrefix = "header_"
+
+	// configKeyTimeout is the maximum duration of a request.
+	configKeyTimeout          = "timeout"
+	configValueTimeoutDefault = 30 * time.Second
+
+	// configKeyRemoteIDAttribute is the Nomad node attribute used to identify
+	// the remote resource of the nodes removed when scaling in a pool of Nomad
+	// nodes.
+	configKeyRemoteIDAttribute          = "remote_id_attribute"
+	configValueRemoteIDAttributeDefault = "unique.hostname"
+
+	// configKeyScaleURL and configKeyStatusURL are the policy target config
+	// params which identify the endpoints of the target.
+	configKeyScaleURL  = "scale_url"
+	configKeyStatusURL = "status_url"
+)
+
+var (
+	PluginConfig = &plugins.InternalPluginConfig{
+		Factory: func(l hclog.Logger) interface{} { return NewWebhookPlugin(l) },
+	}
+
+	pluginInfo = &base.PluginInfo{
+		Name:       pluginName,
+		PluginType: sdk.PluginTypeTarget,
+	}
+)
+
+// Assert that TargetPlugin meets the target.Target interface.
+var _ target.Target = (*TargetPlugin)(nil)
+
+// TargetPlugin is the webhook implementation of the target.Target interface.
+// It scales a target by POSTing the desired count to its scale URL, and reads
+// its status from its status URL.
+type TargetPlugin struct {
+	config  map[string]string
+	logger  hclog.Logger
+	client  *http.Client
+	address *url.URL
+	timeout time.Duration
+
+	// clusterUtils provides general cluster scaling utilities for querying the
+	// state of nodes pools and performing scaling tasks. It is only used when
+	// the target is a pool of Nomad nodes.
+	clusterUtils *scaleutils.ClusterScaleUtils
+}
+
+// NewWebhookPlugin returns the webhook implementation of the target.Target
+// interface.
+func NewWebhookPlugin(log hclog.Logger) *TargetPlugin {
+	return &TargetPlugin{
+		logger: log,
+	}
+}
+
+// SetConfig satisfies the SetConfig function on the base.Base interface.
+func (t *TargetPlugin) SetConfig(config map[string]string) error {
+
+	t.config = config
+
+	t.address = nil
+	if addr := config[configKeyAddress]; addr != "" {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyAddress, err)
+		}
+		if !u.IsAbs() {
+			return fmt.Errorf("%q must be an absolute URL", configKeyAddress)
+		}
+		t.address = u
+	}
+
+	t.timeout = configValueTimeoutDefault
+	if v := config[configKeyTimeout]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("failed to parse %q: %v", configKeyTimeout, err)
+		}
+		t.timeout = d
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS configuration: %v", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	t.client = &http.Client{Transport: transport}
+
+	clusterUtils, err := scaleutils.NewClusterScaleUtils(nomad.ConfigFromNamespacedMap(config), t.logger)
+	if err != nil {
+		return err
+	}
+
+	// Store and set the remote ID callback function.
+	t.clusterUtils = clusterUtils
+	t.clusterUtils.ClusterNodeIDLookupFunc = nodeIDMapFunc(
+		getConfigValue(config, configKeyRemoteIDAttribute, configValueRemoteIDAttributeDefault))
+
+	return nil
+}
+
+// PluginInfo satisfies the PluginInfo function on the base.Base interface.
+func (t *TargetPlugin) PluginInfo() (*base.PluginInfo, error) {
+	return pluginInfo, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *TargetPlugin) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// The endpoints are not expected to support dry-run like Nomad, so just
+	// exit.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+
+	scaleURL, err := t.endpoint(config, configKeyScaleURL)
+	if err != nil {
+		return err
+	}
+	statusURL, err := t.endpoint(config, configKeyStatusURL)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	status, err := t.status(ctx, statusURL)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook target status: %v", err)
+	}
+	currentCount := *status.Count
+
+	num, direction := t.calculateDirection(currentCount, action.Count)
+
+	req := scaleRequest{
+		Count:        action.Count,
+		CurrentCount: currentCount,
+		Direction:    direction,
+		Reason:       action.Reason,
+		Error:        action.Error,
+		Meta:         action.Meta,
+		Config:       config,
+	}
+
+	switch direction {
+	case "in":
+		err = t.scaleIn(ctx, scaleURL, num, req, config)
+	case "out":
+		err = t.scale(ctx, scaleURL, &req)
+	default:
+		t.logger.Info("scaling not required", "scale_url", scaleURL.Redacted(),
+			"current_count", currentCount, "strategy_count", action.Count)
+		return nil
+	}
+
+	// If we received an error while scaling, format this with an outer message
+	// so its nice for the operators and then return any error to the caller.
+	if err != nil {
+		err = fmt.Errorf("failed to perform scaling action: %v", err)
+	}
+	return err
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *TargetPlugin) Status(config map[string]string) (*sdk.TargetStatus, error) {
+
+	// Perform our check of the Nomad node pool when the target is a pool of
+	// nodes. If the pool is not ready, we can exit here and avoid calling the
+	// endpoint as it won't affect the outcome.
+	if isNodePoolTarget(config) {
+		ready, err := t.clusterUtils.IsPoolReady(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run Nomad node readiness check: %v", err)
+		}
+		if !ready {
+			return &sdk.TargetStatus{Ready: ready}, nil
+		}
+	}
+
+	statusURL, err := t.endpoint(config, configKeyStatusURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.status(context.Background(), statusURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook target status: %v", err)
+	}
+
+	status := sdk.TargetStatus{
+		Ready: *resp.Ready,
+		Count: *resp.Count,
+		Meta:  resp.Meta,
+	}
+	if status.Meta == nil {
+		status.Meta = make(map[string]string)
+	}
+	return &status, nil
+}
+
+func (t *TargetPlugin) calculateDirection(currentCount, strategyDesired int64) (int64, string) {
+
+	if strategyDesired < currentCount {
+		return currentCount - strategyDesired, "in"
+	}
+	if strategyDesired > currentCount {
+		return strategyDesired, "out"
+	}
+	return 0, ""
+}
+
+// isNodePoolTarget returns whether the target config identifies a pool of
+// Nomad nodes, which are drained before the scale endpoint removes them.
+func isNodePoolTarget(config map[string]string) bool {
+	return (&sdk.ScalingPolicyTarget{Config: config}).IsNodePoolTarget()
+}
+
+func getConfigValue(config map[string]string, key string, defaultValue string) string {
+	value, ok := config[key]
+	if !ok {
+		return defaultValue
+	}
+
+	return value
+}
@@ -0,0 +1,238 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// maxResponseSize limits the size of response bodies read by the plugin.
+	maxResponseSize = 16 * 1024 * 1024
+
+	// maxErrorBodyLen is the maximum length of the response body included
+	// within errors.
+	maxErrorBodyLen = 512
+)
+
+// scaleRequest is the JSON document POSTed to the scale URL.
+type scaleRequest struct {
+
+	// Count is the desired count of the target, and CurrentCount the count
+	// reported by the status URL before scaling.
+	Count        int64  `json:"count"`
+	CurrentCount int64  `json:"current_count"`
+	Direction    string `json:"direction"`
+
+	Reason string                 `json:"reason"`
+	Error  bool                   `json:"error"`
+	Meta   map[string]interface{} `json:"meta"`
+
+	// Nodes are the drained Nomad nodes the endpoint must remove when scaling
+	// in a pool of Nomad nodes.
+	Nodes []scaleNode `json:"nodes,omitempty"`
+
+	Config map[string]string `json:"config"`
+}
+
+// scaleNode identifies a Nomad node and its remote resource.
+type scaleNode struct {
+	NomadNodeID string `json:"nomad_node_id"`
+	RemoteID    string `json:"remote_id"`
+}
+
+// statusResponse is the JSON document returned by the status URL.
+type statusResponse struct {
+	Ready *bool             `json:"ready"`
+	Count *int64            `json:"count"`
+	Meta  map[string]string `json:"meta"`
+}
+
+func (r *statusResponse) validate() error {
+	if r.Ready == nil {
+		return errors.New("ready is required")
+	}
+	if r.Count == nil {
+		return errors.New("count is required")
+	}
+	if *r.Count < 0 {
+		return errors.New("count must not be negative")
+	}
+	return nil
+}
+
+// endpoint returns the URL of the policy target config param, resolved
+// against the configured address.
+func (t *TargetPlugin) endpoint(config map[string]string, key string) (*url.URL, error) {
+
+	raw, ok := config[key]
+	if !ok || raw == "" {
+		return nil, fmt.Errorf("required config param %s not found", key)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %v", key, err)
+	}
+	if t.address != nil {
+		u = t.address.ResolveReference(u)
+	}
+	if !u.IsAbs() {
+		return nil, fmt.Errorf("%q must be absolute when %q is not set", key, configKeyAddress)
+	}
+	return u, nil
+}
+
+// status performs a GET request to the status URL and validates the
+// response.
+func (t *TargetPlugin) status(ctx context.Context, statusURL *url.URL) (*statusResponse, error) {
+
+	body, err := t.do(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp statusResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	if err := resp.validate(); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	return &resp, nil
+}
+
+// scale POSTs the scale request to the scale URL. Any 2xx response is
+// considered a success, and the response body is ignored.
+func (t *TargetPlugin) scale(ctx context.Context, scaleURL *url.URL, req *scaleRequest) error {
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode scale request: %v", err)
+	}
+
+	if _, err := t.do(ctx, http.MethodPost, scaleURL, body); err != nil {
+		return fmt.Errorf("failed to call scale URL %s: %v", scaleURL.Redacted(), err)
+	}
+	return nil
+}
+
+// scaleIn POSTs the scale request to scale in the target. When the target is
+// a pool of Nomad nodes, the nodes are selected and drained first and passed
+// to the endpoint, which is responsible for removing them.
+func (t *TargetPlugin) scaleIn(ctx context.Context, scaleURL *url.URL, num int64, req scaleRequest, config map[string]string) error {
+
+	if !isNodePoolTarget(config) {
+		return t.scale(ctx, scaleURL, &req)
+	}
+
+	// Create a logger for this action to pre-populate useful information we
+	// would like on all log lines.
+	log := t.logger.With("action", "scale_in", "scale_url", scaleURL.Redacted())
+
+	ids, err := t.clusterUtils.RunPreScaleInTasks(ctx, config, int(num))
+	if err != nil {
+		return fmt.Errorf("failed to perform pre-scale Nomad scale in tasks: %v", err)
+	}
+
+	// Fewer nodes than requested may have been identified, so the desired
+	// count is adjusted to match the nodes removed.
+	req.Nodes = make([]scaleNode, 0, len(ids))
+	for _, id := range ids {
+		req.Nodes = append(req.Nodes, scaleNode{NomadNodeID: id.NomadNodeID, RemoteID: id.RemoteResourceID})
+	}
+	req.Count = req.CurrentCount - int64(len(ids))
+
+	if err := t.scale(ctx, scaleURL, &req); err != nil {
+		if revertErr := t.clusterUtils.RunPostScaleInTasksOnFailure(ids); revertErr != nil {
+			log.Error("failed to revert Nomad nodes", "error", revertErr)
+		}
+		return err
+	}
+
+	// The tasks run on nodes that have been successfully removed should not
+	// cause a failure of the scaling pipeline.
+	if err := t.clusterUtils.RunPostScaleInTasks(ctx, config, ids); err != nil {
+		log.Error("failed to perform post-scale Nomad scale in tasks", "error", err)
+	}
+
+	log.Info("successfully performed scaling in")
+	return nil
+}
+
+// do performs a request to the URL with the configured headers and auth,
+// returning the response body.
+func (t *TargetPlugin) do(ctx context.Context, method string, target *url.URL, body []byte) ([]byte, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for k, v := range t.config {
+		if name, ok := strings.CutPrefix(k, configKeyHeadersPrefix); ok {
+			req.Header.Set(name, v)
+		}
+	}
+	if token := t.config[configKeyBearerToken]; token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if user := t.config[configKeyBasicAuthUser]; user != "" {
+		req.SetBasicAuth(user, t.config[configKeyBasicAuthPassword])
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := strings.TrimSpace(string(respBody))
+		if len(msg) > maxErrorBodyLen {
+			msg = msg[:maxErrorBodyLen]
+		}
+		return nil, fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, msg)
+	}
+	return respBody, nil
+}
+
+// nodeIDMapFunc returns the function used to identify the remote resource of
+// a Nomad node using the value of the attribute.
+func nodeIDMapFunc(attr string) func(*api.Node) (string, error) {
+	return func(n *api.Node) (string, error) {
+		val, ok := n.Attributes[attr]
+		if !ok || val == "" {
+			return "", fmt.Errorf("attribute %q not found", attr)
+		}
+		return val, nil
+	}
+}
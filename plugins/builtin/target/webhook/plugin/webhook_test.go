@@ -0,0 +1,163 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetPlugin_SetConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        map[string]string
+		expectedError string
+	}{
+		{
+			name:          "invalid address",
+			config:        map[string]string{"address": "\n\n"},
+			expectedError: `failed to parse "address"`,
+		},
+		{
+			name:          "relative address",
+			config:        map[string]string{"address": "/api"},
+			expectedError: `"address" must be an absolute URL`,
+		},
+		{
+			name:          "invalid timeout",
+			config:        map[string]string{"timeout": "soon"},
+			expectedError: `failed to parse "timeout"`,
+		},
+		{
+			name:          "invalid skip_verify",
+			config:        map[string]string{"skip_verify": "maybe"},
+			expectedError: "failed to parse TLS configuration",
+		},
+		{
+			name:   "defaults",
+			config: map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tp := NewWebhookPlugin(hclog.NewNullLogger())
+			err := tp.SetConfig(tc.config)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, tp.client)
+			assert.Equal(t, configValueTimeoutDefault, tp.timeout)
+		})
+	}
+}
+
+func TestTargetPlugin_Status(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "team-a", r.Header.Get("X-Team"))
+
+		switch r.URL.Path {
+		case "/status":
+			_, _ = w.Write([]byte(`{"ready": true, "count": 4, "meta": {"provisioning": "0"}}`))
+		case "/invalid":
+			_, _ = w.Write([]byte(`{"count": 4}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	tp := NewWebhookPlugin(hclog.NewNullLogger())
+	require.NoError(t, tp.SetConfig(map[string]string{
+		"address":       ts.URL,
+		"bearer_token":  "secret",
+		"header_X-Team": "team-a",
+	}))
+
+	actual, err := tp.Status(map[string]string{"status_url": "/status"})
+	require.NoError(t, err)
+	assert.Equal(t, &sdk.TargetStatus{Ready: true, Count: 4, Meta: map[string]string{"provisioning": "0"}}, actual)
+
+	_, err = tp.Status(map[string]string{"status_url": "/invalid"})
+	assert.EqualError(t, err, "failed to get webhook target status: invalid response: ready is required")
+
+	_, err = tp.Status(map[string]string{"status_url": "/missing"})
+	assert.EqualError(t, err, "failed to get webhook target status: unexpected response code 404: not found")
+
+	_, err = tp.Status(map[string]string{})
+	assert.EqualError(t, err, "required config param status_url not found")
+}
+
+func TestTargetPlugin_Scale(t *testing.T) {
+	var received []scaleRequest
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			_, _ = w.Write([]byte(`{"ready": true, "count": 2}`))
+		case "/scale":
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			user, pass, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "autoscaler", user)
+			assert.Equal(t, "hunter2", pass)
+
+			var req scaleRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			received = append(received, req)
+			w.WriteHeader(http.StatusAccepted)
+		case "/fail":
+			http.Error(w, "quota exceeded", http.StatusConflict)
+		}
+	}))
+	defer ts.Close()
+
+	tp := NewWebhookPlugin(hclog.NewNullLogger())
+	require.NoError(t, tp.SetConfig(map[string]string{
+		"address":             ts.URL,
+		"basic_auth_user":     "autoscaler",
+		"basic_auth_password": "hunter2",
+	}))
+
+	config := map[string]string{"scale_url": "/scale", "status_url": "/status"}
+
+	require.NoError(t, tp.Scale(sdk.ScalingAction{Count: 5, Reason: "scale out"}, config))
+	require.NoError(t, tp.Scale(sdk.ScalingAction{Count: 1, Reason: "scale in"}, config))
+	require.NoError(t, tp.Scale(sdk.ScalingAction{Count: 2}, config))
+
+	assert.Equal(t, []scaleRequest{
+		{Count: 5, CurrentCount: 2, Direction: "out", Reason: "scale out", Config: config},
+		{Count: 1, CurrentCount: 2, Direction: "in", Reason: "scale in", Config: config},
+	}, received)
+
+	config["scale_url"] = "/fail"
+	err := tp.Scale(sdk.ScalingAction{Count: 5}, config)
+	assert.EqualError(t, err, "failed to perform scaling action: failed to call scale URL "+ts.URL+
+		"/fail: unexpected response code 409: quota exceeded")
+}
+
+func Test_nodeIDMapFunc(t *testing.T) {
+	fn := nodeIDMapFunc("unique.hostname")
+
+	actual, err := fn(&api.Node{Attributes: map[string]string{"unique.hostname": "worker-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "worker-1", actual)
+
+	_, err = fn(&api.Node{Attributes: map[string]string{}})
+	assert.EqualError(t, err, `attribute "unique.hostname" not found`)
+}
@@ -0,0 +1,241 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins"
+	targetpkg "github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	nomadHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/nomad"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+)
+
+// eligibilityScaleUtils is the subset of the scaleutils.ClusterScaleUtils
+// functionality used to scale targets in the eligibility only mode.
+type eligibilityScaleUtils interface {
+	IdentifyDrainedNodes(cfg map[string]string) ([]*api.NodeListStub, error)
+	IdentifyScaleInNodes(cfg map[string]string, num int) ([]*api.NodeListStub, error)
+	SelectScaleInNodes(nodes []*api.NodeListStub, cfg map[string]string, num int) ([]*api.NodeListStub, error)
+	DrainNodes(ctx context.Context, cfg map[string]string, nodes []scaleutils.NodeResourceID) error
+	MarkNodesEligible(nodes []*api.NodeListStub) error
+}
+
+// eligibilityTarget wraps a cluster target plugin for policies using the
+// eligibility only scale in mode. Scaling in drains the selected nodes,
+// leaving them ineligible for scheduling, without calling the target plugin,
+// so the machines can be removed by an external system. The status count
+// excludes the drained nodes which have not yet been removed, and scaling out
+// marks them eligible again before the target plugin is asked to add
+// capacity.
+type eligibilityTarget struct {
+	targetpkg.Target
+
+	logger hclog.Logger
+	utils  eligibilityScaleUtils
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *eligibilityTarget) Status(config map[string]string) (*sdk.TargetStatus, error) {
+	status, err := t.Target.Status(config)
+	if err != nil || status == nil {
+		return status, err
+	}
+
+	drained, err := t.utils.IdentifyDrainedNodes(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify drained nodes: %v", err)
+	}
+	status.Count = eligibleCount(status.Count, len(drained))
+
+	return status, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *eligibilityTarget) Scale(action sdk.ScalingAction, config map[string]string) error {
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return t.Target.Scale(action, config)
+	}
+
+	status, err := t.Target.Status(config)
+	if err != nil {
+		return fmt.Errorf("failed to get target status: %v", err)
+	}
+
+	drained, err := t.utils.IdentifyDrainedNodes(config)
+	if err != nil {
+		return fmt.Errorf("failed to identify drained nodes: %v", err)
+	}
+	count := eligibleCount(status.Count, len(drained))
+
+	switch {
+	case action.Count < count:
+		return t.scaleIn(config, int(count-action.Count))
+	case action.Count > count:
+		return t.scaleOut(action, config, status.Count, drained, action.Count-count)
+	default:
+		return sdk.NewTargetScalingNoOpError("eligible count already %d", count)
+	}
+}
+
+// DryRun satisfies the DryRun function on the target.DryRunner interface. It
+// reports the nodes which would be drained when scaling in, or marked
+// eligible again when scaling out, only asking the target plugin about the
+// capacity the drained nodes cannot provide.
+func (t *eligibilityTarget) DryRun(action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error) {
+
+	status, err := t.Target.Status(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target status: %v", err)
+	}
+
+	drained, err := t.utils.IdentifyDrainedNodes(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify drained nodes: %v", err)
+	}
+	count := eligibleCount(status.Count, len(drained))
+
+	result := sdk.TargetDryRunResult{Count: action.Count}
+
+	switch {
+	case action.Count < count:
+		num := int(count - action.Count)
+
+		nodes, err := t.utils.IdentifyScaleInNodes(config, num)
+		if err != nil {
+			return nil, err
+		}
+		selected, err := t.utils.SelectScaleInNodes(nodes, config, num)
+		if err != nil {
+			return nil, err
+		}
+		result.Nodes = dryRunNodes(selected)
+
+	case action.Count > count:
+		num := action.Count - count
+		if int64(len(drained)) > num {
+			drained = drained[:num]
+		}
+		result.Nodes = dryRunNodes(drained)
+
+		if num -= int64(len(drained)); num == 0 {
+			break
+		}
+
+		action.Count = status.Count + num
+		inner, err := targetpkg.DryRun(t.Target, action, config)
+		if err != nil {
+			if err == targetpkg.ErrDryRunUnsupported {
+				break
+			}
+			return nil, err
+		}
+		result.Nodes = append(result.Nodes, inner.Nodes...)
+		result.Meta = inner.Meta
+	}
+
+	return &result, nil
+}
+
+// dryRunNodes converts the nodes to their dry run representation.
+func dryRunNodes(nodes []*api.NodeListStub) []sdk.TargetDryRunNode {
+	out := make([]sdk.TargetDryRunNode, 0, len(nodes))
+	for _, node := range nodes {
+		out = append(out, sdk.TargetDryRunNode{NomadNodeID: node.ID})
+	}
+	return out
+}
+
+// scaleIn drains the selected nodes without terminating them.
+func (t *eligibilityTarget) scaleIn(config map[string]string, num int) error {
+
+	nodes, err := t.utils.IdentifyScaleInNodes(config, num)
+	if err != nil {
+		return err
+	}
+
+	selected, err := t.utils.SelectScaleInNodes(nodes, config, num)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]scaleutils.NodeResourceID, 0, len(selected))
+	for _, node := range selected {
+		ids = append(ids, scaleutils.NodeResourceID{NomadNodeID: node.ID})
+	}
+
+	if err := t.utils.DrainNodes(context.Background(), config, ids); err != nil {
+		return err
+	}
+	t.logger.Info("successfully drained nodes without terminating them", "count", len(ids))
+
+	return nil
+}
+
+// scaleOut marks drained nodes eligible again, only calling the target plugin
+// to add the capacity the drained nodes cannot provide.
+func (t *eligibilityTarget) scaleOut(action sdk.ScalingAction, config map[string]string,
+	current int64, drained []*api.NodeListStub, num int64) error {
+
+	if int64(len(drained)) > num {
+		drained = drained[:num]
+	}
+
+	if len(drained) > 0 {
+		if err := t.utils.MarkNodesEligible(drained); err != nil {
+			return err
+		}
+		num -= int64(len(drained))
+	}
+
+	if num == 0 {
+		return nil
+	}
+
+	action.Count = current + num
+	return t.Target.Scale(action, config)
+}
+
+// eligibleCount returns the count of the target excluding the drained nodes.
+func eligibleCount(count int64, drained int) int64 {
+	if n := count - int64(drained); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// eligibilityScaleUtils returns the ClusterScaleUtils used to scale targets
+// of the named target plugin in the eligibility only mode. It uses the Nomad
+// config of the target plugin, and is reused until the plugins are reloaded.
+func (pm *PluginManager) eligibilityScaleUtils(name string) (*scaleutils.ClusterScaleUtils, error) {
+	pm.eligibilityUtilsLock.Lock()
+	defer pm.eligibilityUtilsLock.Unlock()
+
+	if utils, ok := pm.eligibilityUtils[name]; ok {
+		return utils, nil
+	}
+
+	pm.pluginsLock.RLock()
+	info, ok := pm.plugins[plugins.PluginID{Name: name, PluginType: sdk.PluginTypeTarget}]
+	pm.pluginsLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("target plugin %s not found", name)
+	}
+
+	utils, err := scaleutils.NewClusterScaleUtils(nomadHelper.ConfigFromNamespacedMap(info.config),
+		pm.logger.Named("eligibility_only").With("plugin_name", name))
+	if err != nil {
+		return nil, err
+	}
+
+	if pm.eligibilityUtils == nil {
+		pm.eligibilityUtils = make(map[string]*scaleutils.ClusterScaleUtils)
+	}
+	pm.eligibilityUtils[name] = utils
+	return utils, nil
+}
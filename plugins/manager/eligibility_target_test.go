@@ -0,0 +1,205 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_eligibilityTarget_Status(t *testing.T) {
+	inner := &eligibilityTestTarget{count: 5}
+	utils := &eligibilityTestUtils{drained: []*api.NodeListStub{{ID: "node-1"}, {ID: "node-2"}}}
+	target := &eligibilityTarget{Target: inner, logger: hclog.NewNullLogger(), utils: utils}
+
+	status, err := target.Status(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), status.Count)
+}
+
+func Test_eligibilityTarget_Scale(t *testing.T) {
+	drained := []*api.NodeListStub{{ID: "drained-1"}, {ID: "drained-2"}}
+
+	testCases := []struct {
+		inputCount           int64
+		expectedDrainedNodes []string
+		expectedEligible     []string
+		expectedTargetCount  *int64
+		expectNoOp           bool
+		name                 string
+	}{
+		{
+			inputCount:           1,
+			expectedDrainedNodes: []string{"node-1", "node-2"},
+			name:                 "scale in drains nodes",
+		},
+		{
+			inputCount:       4,
+			expectedEligible: []string{"drained-1"},
+			name:             "scale out marks drained nodes eligible",
+		},
+		{
+			inputCount:          7,
+			expectedEligible:    []string{"drained-1", "drained-2"},
+			expectedTargetCount: ptr.Int64ToPtr(7),
+			name:                "scale out beyond drained nodes",
+		},
+		{
+			inputCount: 3,
+			expectNoOp: true,
+			name:       "eligible count unchanged",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &eligibilityTestTarget{count: 5}
+			utils := &eligibilityTestUtils{
+				drained: drained,
+				nodes:   []*api.NodeListStub{{ID: "node-1"}, {ID: "node-2"}, {ID: "node-3"}},
+			}
+			target := &eligibilityTarget{Target: inner, logger: hclog.NewNullLogger(), utils: utils}
+
+			err := target.Scale(sdk.ScalingAction{Count: tc.inputCount}, nil)
+			if tc.expectNoOp {
+				assert.IsType(t, &sdk.TargetScalingNoOpError{}, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedDrainedNodes, utils.drainedIDs, tc.name)
+			assert.Equal(t, tc.expectedEligible, utils.eligibleIDs, tc.name)
+			assert.Equal(t, tc.expectedTargetCount, inner.scaledCount, tc.name)
+		})
+	}
+}
+
+func Test_eligibilityTarget_DryRun(t *testing.T) {
+	drained := []*api.NodeListStub{{ID: "drained-1"}, {ID: "drained-2"}}
+
+	testCases := []struct {
+		inputCount     int64
+		expectedOutput *sdk.TargetDryRunResult
+		name           string
+	}{
+		{
+			inputCount: 1,
+			expectedOutput: &sdk.TargetDryRunResult{
+				Count: 1,
+				Nodes: []sdk.TargetDryRunNode{{NomadNodeID: "node-1"}, {NomadNodeID: "node-2"}},
+			},
+			name: "scale in selects nodes",
+		},
+		{
+			inputCount: 4,
+			expectedOutput: &sdk.TargetDryRunResult{
+				Count: 4,
+				Nodes: []sdk.TargetDryRunNode{{NomadNodeID: "drained-1"}},
+			},
+			name: "scale out selects drained nodes",
+		},
+		{
+			inputCount: 7,
+			expectedOutput: &sdk.TargetDryRunResult{
+				Count: 7,
+				Nodes: []sdk.TargetDryRunNode{{NomadNodeID: "drained-1"}, {NomadNodeID: "drained-2"}},
+			},
+			name: "scale out beyond drained nodes without target dry run",
+		},
+		{
+			inputCount:     3,
+			expectedOutput: &sdk.TargetDryRunResult{Count: 3},
+			name:           "eligible count unchanged",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &eligibilityTestTarget{count: 5}
+			utils := &eligibilityTestUtils{
+				drained: drained,
+				nodes:   []*api.NodeListStub{{ID: "node-1"}, {ID: "node-2"}, {ID: "node-3"}},
+			}
+			target := &eligibilityTarget{Target: inner, logger: hclog.NewNullLogger(), utils: utils}
+
+			result, err := target.DryRun(sdk.ScalingAction{Count: tc.inputCount}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, result, tc.name)
+
+			assert.Empty(t, utils.drainedIDs, tc.name)
+			assert.Empty(t, utils.eligibleIDs, tc.name)
+			assert.Nil(t, inner.scaledCount, tc.name)
+		})
+	}
+}
+
+func Test_eligibleCount(t *testing.T) {
+	assert.Equal(t, int64(3), eligibleCount(5, 2))
+	assert.Equal(t, int64(0), eligibleCount(1, 2))
+}
+
+// eligibilityTestTarget is a minimal target.Target implementation recording
+// the count it is scaled to.
+type eligibilityTestTarget struct {
+	count       int64
+	scaledCount *int64
+}
+
+func (t *eligibilityTestTarget) Status(_ map[string]string) (*sdk.TargetStatus, error) {
+	return &sdk.TargetStatus{Ready: true, Count: t.count}, nil
+}
+
+func (t *eligibilityTestTarget) Scale(action sdk.ScalingAction, _ map[string]string) error {
+	t.scaledCount = &action.Count
+	return nil
+}
+
+func (t *eligibilityTestTarget) SetConfig(_ map[string]string) error { return nil }
+
+func (t *eligibilityTestTarget) PluginInfo() (*base.PluginInfo, error) { return nil, nil }
+
+// eligibilityTestUtils is an eligibilityScaleUtils implementation recording
+// the nodes it drains and marks eligible.
+type eligibilityTestUtils struct {
+	drained []*api.NodeListStub
+	nodes   []*api.NodeListStub
+
+	drainedIDs  []string
+	eligibleIDs []string
+}
+
+func (u *eligibilityTestUtils) IdentifyDrainedNodes(_ map[string]string) ([]*api.NodeListStub, error) {
+	return u.drained, nil
+}
+
+func (u *eligibilityTestUtils) IdentifyScaleInNodes(_ map[string]string, _ int) ([]*api.NodeListStub, error) {
+	return u.nodes, nil
+}
+
+func (u *eligibilityTestUtils) SelectScaleInNodes(nodes []*api.NodeListStub, _ map[string]string, num int) ([]*api.NodeListStub, error) {
+	return nodes[:num], nil
+}
+
+func (u *eligibilityTestUtils) DrainNodes(_ context.Context, _ map[string]string, nodes []scaleutils.NodeResourceID) error {
+	for _, n := range nodes {
+		u.drainedIDs = append(u.drainedIDs, n.NomadNodeID)
+	}
+	return nil
+}
+
+func (u *eligibilityTestUtils) MarkNodesEligible(nodes []*api.NodeListStub) error {
+	for _, n := range nodes {
+		u.eligibleIDs = append(u.eligibleIDs, n.ID)
+	}
+	return nil
+}
@@ -3,14 +3,18 @@
 
 package manager
 
-import plugin "github.com/hashicorp/go-plugin"
+import (
+	"io"
+
+	plugin "github.com/hashicorp/go-plugin"
+)
 
 // PluginInstance is a wrapper of a plugin and provides a common interface
 // whether the plugin is internal or running externally via a binary.
 type PluginInstance interface {
 
-	// Kill kills the plugin if it is external. It is safe to call on internal
-	// plugins.
+	// Kill kills the plugin if it is external. Internal plugins are closed if
+	// they implement io.Closer, such as plugins which run listeners.
 	Kill()
 
 	// Plugin returns the wrapped plugin instance.
@@ -22,9 +26,14 @@ type internalPluginInstance struct {
 	instance interface{}
 }
 
-func (p *internalPluginInstance) Kill()               {}
 func (p *internalPluginInstance) Plugin() interface{} { return p.instance }
 
+func (p *internalPluginInstance) Kill() {
+	if c, ok := p.instance.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
 // externalPluginInstance wraps an external plugin.
 type externalPluginInstance struct {
 	client   *plugin.Client
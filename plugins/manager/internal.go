@@ -9,17 +9,45 @@ import (
 
 	"github.com/hashicorp/nomad-autoscaler/agent/config"
 	"github.com/hashicorp/nomad-autoscaler/plugins"
+	appdynamics "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/appdynamics/plugin"
+	cloudwatch "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/aws-cloudwatch/plugin"
+	sqs "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/aws-sqs/plugin"
 	datadog "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/datadog/plugin"
+	elasticsearch "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/elasticsearch/plugin"
+	graphite "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/graphite/plugin"
+	httpJSON "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/http-json/plugin"
+	influxdb "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/influxdb/plugin"
+	instana "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/instana/plugin"
+	kafka "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/kafka/plugin"
+	loki "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/loki/plugin"
+	nats "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/nats/plugin"
+	nomadEvents "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/nomad-events/plugin"
 	nomadAPM "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/nomad/plugin"
+	otel "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/otel/plugin"
 	prometheus "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/prometheus/plugin"
+	rabbitmq "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/rabbitmq/plugin"
+	redis "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/redis/plugin"
+	signalfx "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/signalfx/plugin"
+	splunk "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/splunk/plugin"
+	statsd "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/statsd/plugin"
+	wavefront "github.com/hashicorp/nomad-autoscaler/plugins/builtin/apm/wavefront/plugin"
 	fixedValue "github.com/hashicorp/nomad-autoscaler/plugins/builtin/strategy/fixed-value/plugin"
 	passthrough "github.com/hashicorp/nomad-autoscaler/plugins/builtin/strategy/pass-through/plugin"
 	targetValue "github.com/hashicorp/nomad-autoscaler/plugins/builtin/strategy/target-value/plugin"
 	threshold "github.com/hashicorp/nomad-autoscaler/plugins/builtin/strategy/threshold/plugin"
 	awsASG "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/aws-asg/plugin"
+	awsFleet "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/aws-ec2-fleet/plugin"
 	azureVMSS "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/azure-vmss/plugin"
+	doDroplets "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/do-droplets/plugin"
+	execTarget "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/exec/plugin"
 	gceMIG "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/gce-mig/plugin"
+	hcloudServer "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/hcloud-server/plugin"
+	libvirtDomain "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/libvirt-domain/plugin"
 	nomadTarget "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/nomad/plugin"
+	ociInstancePool "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/oci-instance-pool/plugin"
+	osSenlin "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/os-senlin/plugin"
+	tfcWorkspace "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/tfc-workspace/plugin"
+	webhookTarget "github.com/hashicorp/nomad-autoscaler/plugins/builtin/target/webhook/plugin"
 )
 
 // loadInternalPlugin takes the plugin configuration and attempts to load it
@@ -59,9 +87,93 @@ func (pm *PluginManager) loadInternalPlugin(cfg *config.Plugin, pluginType strin
 	case plugins.InternalTargetGCEMIG:
 		info.factory = gceMIG.PluginConfig.Factory
 		info.driver = "gce-mig"
+	case plugins.InternalTargetAWSEC2Fleet:
+		info.factory = awsFleet.PluginConfig.Factory
+		info.driver = "aws-ec2-fleet"
+	case plugins.InternalTargetDODroplets:
+		info.factory = doDroplets.PluginConfig.Factory
+		info.driver = "do-droplets"
+	case plugins.InternalTargetHCloudServer:
+		info.factory = hcloudServer.PluginConfig.Factory
+		info.driver = "hcloud-server"
+	case plugins.InternalTargetOpenStackSenlin:
+		info.factory = osSenlin.PluginConfig.Factory
+		info.driver = "os-senlin"
+	case plugins.InternalTargetOCIInstancePool:
+		info.factory = ociInstancePool.PluginConfig.Factory
+		info.driver = "oci-instance-pool"
+	case plugins.InternalTargetLibvirtDomain:
+		info.factory = libvirtDomain.PluginConfig.Factory
+		info.driver = "libvirt-domain"
+	case plugins.InternalTargetExec:
+		info.factory = execTarget.PluginConfig.Factory
+		info.driver = "exec"
+	case plugins.InternalTargetWebhook:
+		info.factory = webhookTarget.PluginConfig.Factory
+		info.driver = "webhook"
+	case plugins.InternalTargetTFCWorkspace:
+		info.factory = tfcWorkspace.PluginConfig.Factory
+		info.driver = "tfc-workspace"
 	case plugins.InternalAPMDatadog:
 		info.factory = datadog.PluginConfig.Factory
 		info.driver = "datadog"
+	case plugins.InternalAPMCloudWatch:
+		info.factory = cloudwatch.PluginConfig.Factory
+		info.driver = "aws-cloudwatch"
+	case plugins.InternalAPMInfluxDB:
+		info.factory = influxdb.PluginConfig.Factory
+		info.driver = "influxdb"
+	case plugins.InternalAPMElasticsearch:
+		info.factory = elasticsearch.PluginConfig.Factory
+		info.driver = "elasticsearch"
+	case plugins.InternalAPMGraphite:
+		info.factory = graphite.PluginConfig.Factory
+		info.driver = "graphite"
+	case plugins.InternalAPMSplunk:
+		info.factory = splunk.PluginConfig.Factory
+		info.driver = "splunk"
+	case plugins.InternalAPMOTel:
+		info.factory = otel.PluginConfig.Factory
+		info.driver = "otel"
+	case plugins.InternalAPMStatsD:
+		info.factory = statsd.PluginConfig.Factory
+		info.driver = "statsd"
+	case plugins.InternalAPMKafka:
+		info.factory = kafka.PluginConfig.Factory
+		info.driver = "kafka"
+	case plugins.InternalAPMRabbitMQ:
+		info.factory = rabbitmq.PluginConfig.Factory
+		info.driver = "rabbitmq"
+	case plugins.InternalAPMNATS:
+		info.factory = nats.PluginConfig.Factory
+		info.driver = "nats"
+	case plugins.InternalAPMSQS:
+		info.factory = sqs.PluginConfig.Factory
+		info.driver = "aws-sqs"
+	case plugins.InternalAPMRedis:
+		info.factory = redis.PluginConfig.Factory
+		info.driver = "redis"
+	case plugins.InternalAPMNomadEvents:
+		info.factory = nomadEvents.PluginConfig.Factory
+		info.driver = "nomad-events"
+	case plugins.InternalAPMHTTPJSON:
+		info.factory = httpJSON.PluginConfig.Factory
+		info.driver = "http-json"
+	case plugins.InternalAPMLoki:
+		info.factory = loki.PluginConfig.Factory
+		info.driver = "loki"
+	case plugins.InternalAPMInstana:
+		info.factory = instana.PluginConfig.Factory
+		info.driver = "instana"
+	case plugins.InternalAPMAppDynamics:
+		info.factory = appdynamics.PluginConfig.Factory
+		info.driver = "appdynamics"
+	case plugins.InternalAPMWavefront:
+		info.factory = wavefront.PluginConfig.Factory
+		info.driver = "wavefront"
+	case plugins.InternalAPMSignalFx:
+		info.factory = signalfx.PluginConfig.Factory
+		info.driver = "signalfx"
 	default:
 		pm.logger.Error("unsupported internal plugin", "plugin", cfg.Driver)
 		return
@@ -108,7 +220,35 @@ func (pm *PluginManager) useInternal(plugin string) bool {
 		plugins.InternalTargetAWSASG,
 		plugins.InternalTargetAzureVMSS,
 		plugins.InternalTargetGCEMIG,
-		plugins.InternalAPMDatadog:
+		plugins.InternalTargetAWSEC2Fleet,
+		plugins.InternalTargetDODroplets,
+		plugins.InternalTargetHCloudServer,
+		plugins.InternalTargetOpenStackSenlin,
+		plugins.InternalTargetOCIInstancePool,
+		plugins.InternalTargetLibvirtDomain,
+		plugins.InternalTargetExec,
+		plugins.InternalTargetWebhook,
+		plugins.InternalTargetTFCWorkspace,
+		plugins.InternalAPMDatadog,
+		plugins.InternalAPMCloudWatch,
+		plugins.InternalAPMInfluxDB,
+		plugins.InternalAPMElasticsearch,
+		plugins.InternalAPMGraphite,
+		plugins.InternalAPMSplunk,
+		plugins.InternalAPMOTel,
+		plugins.InternalAPMStatsD,
+		plugins.InternalAPMKafka,
+		plugins.InternalAPMRabbitMQ,
+		plugins.InternalAPMNATS,
+		plugins.InternalAPMSQS,
+		plugins.InternalAPMRedis,
+		plugins.InternalAPMNomadEvents,
+		plugins.InternalAPMHTTPJSON,
+		plugins.InternalAPMLoki,
+		plugins.InternalAPMInstana,
+		plugins.InternalAPMAppDynamics,
+		plugins.InternalAPMWavefront,
+		plugins.InternalAPMSignalFx:
 		return true
 	default:
 		return false
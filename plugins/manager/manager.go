@@ -20,6 +20,7 @@ import (
 	"github.com/hashicorp/nomad-autoscaler/plugins/strategy"
 	targetpkg "github.com/hashicorp/nomad-autoscaler/plugins/target"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils"
 )
 
 // PluginManager is the brains of the plugin operation and should be used to
@@ -42,6 +43,15 @@ type PluginManager struct {
 	// Nomad Autoscaler plugins.
 	pluginsLock sync.RWMutex
 	plugins     map[plugins.PluginID]*pluginInfo
+
+	// targetStatusCache is the optional cache used to read the status of
+	// the targets returned by GetTarget.
+	targetStatusCache *TargetStatusCache
+
+	// eligibilityUtils are the ClusterScaleUtils used to scale targets in the
+	// eligibility only mode, keyed by the target plugin name.
+	eligibilityUtilsLock sync.Mutex
+	eligibilityUtils     map[string]*scaleutils.ClusterScaleUtils
 }
 
 // pluginInfo contains all the required information to launch an Autoscaler
@@ -94,6 +104,15 @@ func (pm *PluginManager) Load() error {
 }
 
 func (pm *PluginManager) Reload(newCfg map[string][]*config.Plugin) error {
+	// The configuration of the target plugins may change, so statuses read
+	// using the previous configuration are discarded.
+	pm.targetStatusCache.Purge()
+
+	// The Nomad config of the target plugins may also change.
+	pm.eligibilityUtilsLock.Lock()
+	pm.eligibilityUtils = nil
+	pm.eligibilityUtilsLock.Unlock()
+
 	// Find plugins that are no longer in the new config and stop them.
 	pluginsToStop := []plugins.PluginID{}
 
@@ -347,6 +366,12 @@ func (pm *PluginManager) pluginLaunchCheck(id plugins.PluginID, info *pluginInfo
 	return pluginInfo, nil
 }
 
+// SetTargetStatusCache sets the cache used to read the status of the targets
+// returned by GetTarget. It must be called before any targets are used.
+func (pm *PluginManager) SetTargetStatusCache(c *TargetStatusCache) {
+	pm.targetStatusCache = c
+}
+
 func (pm *PluginManager) GetTarget(target *sdk.ScalingPolicyTarget) (targetpkg.Target, error) {
 	// Dispense an instance of target plugin used by the policy.
 	targetPlugin, err := pm.Dispense(target.Name, sdk.PluginTypeTarget)
@@ -360,6 +385,35 @@ func (pm *PluginManager) GetTarget(target *sdk.ScalingPolicyTarget) (targetpkg.T
 		return nil, err
 	}
 
+	if pm.targetStatusCache != nil {
+		targetInst = &cachedTarget{Target: targetInst, name: target.Name, cache: pm.targetStatusCache}
+	}
+
+	eligibilityOnly, err := scaleutils.IsEligibilityOnly(target.Config)
+	if err != nil {
+		return nil, err
+	}
+	if eligibilityOnly {
+		utils, err := pm.eligibilityScaleUtils(target.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup eligibility only mode: %v", err)
+		}
+		targetInst = &eligibilityTarget{
+			Target: targetInst,
+			logger: pm.logger.Named("eligibility_only").With("plugin_name", target.Name),
+			utils:  utils,
+		}
+	}
+
+	// Wrap the target last, so each sub target uses the status cache and the
+	// eligibility only mode with its own config.
+	if len(target.SubTargets) > 0 {
+		targetInst = &subTargetsTarget{
+			Target:     targetInst,
+			logger:     pm.logger.Named("sub_targets").With("plugin_name", target.Name),
+			subTargets: target.SubTargets,
+		}
+	}
 	return targetInst, nil
 }
 
@@ -0,0 +1,284 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package manager
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/go-hclog"
+	multierror "github.com/hashicorp/go-multierror"
+	targetpkg "github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	errHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/error"
+)
+
+// metaKeySubTargetPrefix is the prefix of the status and dry run meta keys
+// reported for each sub target, which are followed by the sub target name.
+const metaKeySubTargetPrefix = "sub_target."
+
+// subTargetsTarget wraps a cluster target plugin for policies spanning
+// several sub targets, such as one per node class. The status count is the
+// sum of the sub target counts, so the policy is evaluated against the
+// capacity of all of them, and scaling actions are distributed across the
+// sub targets according to their limits and weights. This allows capacity to
+// move between sub targets as they reach their limits, rather than each one
+// scaling in isolation.
+type subTargetsTarget struct {
+	targetpkg.Target
+
+	logger     hclog.Logger
+	subTargets []*sdk.ScalingPolicySubTarget
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *subTargetsTarget) Status(config map[string]string) (*sdk.TargetStatus, error) {
+	statuses, err := t.statuses(config)
+	if err != nil {
+		return nil, err
+	}
+
+	status := sdk.TargetStatus{Ready: true, Meta: make(map[string]string)}
+
+	for i, s := range t.subTargets {
+		sub := statuses[i]
+		prefix := metaKeySubTargetPrefix + s.Name + "."
+
+		status.Count += sub.Count
+		status.Ready = status.Ready && sub.Ready
+		status.Activities = append(status.Activities, sub.Activities...)
+
+		status.Meta[prefix+"count"] = strconv.FormatInt(sub.Count, 10)
+		status.Meta[prefix+"ready"] = strconv.FormatBool(sub.Ready)
+		for k, v := range sub.Meta {
+			status.Meta[prefix+k] = v
+		}
+	}
+
+	return &status, nil
+}
+
+// Scale satisfies the Scale function on the target.Target interface. Sub
+// targets which are scaled out are handled before those which are scaled in,
+// so capacity is added before it is removed when rebalancing.
+func (t *subTargetsTarget) Scale(action sdk.ScalingAction, config map[string]string) error {
+
+	// There is no count to distribute, but each sub target registers the
+	// scaling event.
+	if action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		var mErr *multierror.Error
+		for _, s := range t.subTargets {
+			if err := t.Target.Scale(action, s.TargetConfig(config)); err != nil {
+				mErr = multierror.Append(mErr, fmt.Errorf("failed to scale sub target %s: %v", s.Name, err))
+			}
+		}
+		return errHelper.FormattedMultiError(mErr)
+	}
+
+	counts, desired, err := t.distribute(action.Count, config)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mErr    *multierror.Error
+		changed int
+		noOps   int
+	)
+
+	for _, i := range scaleOrder(counts, desired) {
+		s := t.subTargets[i]
+		changed++
+
+		err := t.Target.Scale(subTargetAction(action, counts[i], desired[i]), s.TargetConfig(config))
+		if err != nil {
+			if _, ok := err.(*sdk.TargetScalingNoOpError); ok {
+				t.logger.Info("sub target scaling action skipped", "sub_target", s.Name, "reason", err)
+				noOps++
+				continue
+			}
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to scale sub target %s: %v", s.Name, err))
+			continue
+		}
+
+		t.logger.Info("scaled sub target", "sub_target", s.Name, "from", counts[i], "to", desired[i])
+	}
+
+	if err := errHelper.FormattedMultiError(mErr); err != nil {
+		return err
+	}
+	if changed == noOps {
+		return sdk.NewTargetScalingNoOpError("no sub target count changed")
+	}
+	return nil
+}
+
+// DryRun satisfies the DryRun function on the target.DryRunner interface.
+func (t *subTargetsTarget) DryRun(action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error) {
+	counts, desired, err := t.distribute(action.Count, config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := sdk.TargetDryRunResult{Meta: make(map[string]string)}
+
+	for i, s := range t.subTargets {
+		prefix := metaKeySubTargetPrefix + s.Name + "."
+		result.Count += desired[i]
+		result.Meta[prefix+"count"] = strconv.FormatInt(desired[i], 10)
+	}
+
+	for _, i := range scaleOrder(counts, desired) {
+		s := t.subTargets[i]
+
+		sub, err := targetpkg.DryRun(t.Target, subTargetAction(action, counts[i], desired[i]), s.TargetConfig(config))
+		if err != nil {
+			if err == targetpkg.ErrDryRunUnsupported {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to dry run sub target %s: %v", s.Name, err)
+		}
+
+		result.Nodes = append(result.Nodes, sub.Nodes...)
+		for k, v := range sub.Meta {
+			result.Meta[metaKeySubTargetPrefix+s.Name+"."+k] = v
+		}
+	}
+
+	return &result, nil
+}
+
+// statuses returns the status of each sub target, in the order the sub
+// targets are defined.
+func (t *subTargetsTarget) statuses(config map[string]string) ([]*sdk.TargetStatus, error) {
+	statuses := make([]*sdk.TargetStatus, len(t.subTargets))
+
+	for i, s := range t.subTargets {
+		status, err := t.Target.Status(s.TargetConfig(config))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status of sub target %s: %v", s.Name, err)
+		}
+		if status == nil {
+			return nil, fmt.Errorf("sub target %s not found", s.Name)
+		}
+		statuses[i] = status
+	}
+	return statuses, nil
+}
+
+// distribute returns the current and desired count of each sub target for
+// the total count.
+func (t *subTargetsTarget) distribute(total int64, config map[string]string) ([]int64, []int64, error) {
+	statuses, err := t.statuses(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	counts := make([]int64, len(statuses))
+	for i, status := range statuses {
+		counts[i] = status.Count
+	}
+	return counts, distributeCount(counts, t.subTargets, total), nil
+}
+
+// distributeCount returns the count of each sub target which sums to the
+// total, within the sub target limits where possible. Sub targets outside
+// their limits are brought back within them first. The difference with the
+// total is then added to, or removed from, one sub target at a time, picking
+// the sub target whose count is furthest from its weighted share, so counts
+// only change as much as required.
+func distributeCount(counts []int64, subTargets []*sdk.ScalingPolicySubTarget, total int64) []int64 {
+	desired := make([]int64, len(counts))
+
+	var sum int64
+	for i, s := range subTargets {
+		desired[i] = counts[i]
+		if desired[i] < s.Min {
+			desired[i] = s.Min
+		}
+		if s.Max > 0 && desired[i] > s.Max {
+			desired[i] = s.Max
+		}
+		sum += desired[i]
+	}
+
+	for ; sum < total; sum++ {
+		pick := -1
+		for i, s := range subTargets {
+			if s.Max > 0 && desired[i] >= s.Max {
+				continue
+			}
+			// Prefer the sub target with the lowest share once scaled.
+			if pick == -1 || (desired[i]+1)*subTargetWeight(subTargets[pick]) < (desired[pick]+1)*subTargetWeight(s) {
+				pick = i
+			}
+		}
+		if pick == -1 {
+			break
+		}
+		desired[pick]++
+	}
+
+	for ; sum > total; sum-- {
+		pick := -1
+		for i, s := range subTargets {
+			if desired[i] <= s.Min {
+				continue
+			}
+			// Prefer the sub target with the highest share.
+			if pick == -1 || desired[i]*subTargetWeight(subTargets[pick]) > desired[pick]*subTargetWeight(s) {
+				pick = i
+			}
+		}
+		if pick == -1 {
+			break
+		}
+		desired[pick]--
+	}
+
+	return desired
+}
+
+// subTargetWeight returns the weight of the sub target, which defaults to
+// one.
+func subTargetWeight(s *sdk.ScalingPolicySubTarget) int64 {
+	if s.Weight > 0 {
+		return s.Weight
+	}
+	return 1
+}
+
+// scaleOrder returns the indexes of the sub targets whose count changes,
+// with those scaling out first.
+func scaleOrder(counts, desired []int64) []int {
+	var out, in []int
+	for i := range counts {
+		switch {
+		case desired[i] > counts[i]:
+			out = append(out, i)
+		case desired[i] < counts[i]:
+			in = append(in, i)
+		}
+	}
+	return append(out, in...)
+}
+
+// subTargetAction returns the action scaling a sub target from its current
+// count to the desired count.
+func subTargetAction(action sdk.ScalingAction, current, desired int64) sdk.ScalingAction {
+	sub := action
+	sub.Count = desired
+	sub.Direction = sdk.ScaleDirectionDown
+	if desired > current {
+		sub.Direction = sdk.ScaleDirectionUp
+	}
+
+	if action.Meta != nil {
+		sub.Meta = make(map[string]interface{}, len(action.Meta))
+		for k, v := range action.Meta {
+			sub.Meta[k] = v
+		}
+	}
+	return sub
+}
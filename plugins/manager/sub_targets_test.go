@@ -0,0 +1,190 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_distributeCount(t *testing.T) {
+	testCases := []struct {
+		inputCounts     []int64
+		inputSubTargets []*sdk.ScalingPolicySubTarget
+		inputTotal      int64
+		expectedOutput  []int64
+		name            string
+	}{
+		{
+			inputCounts:     []int64{2, 2},
+			inputSubTargets: []*sdk.ScalingPolicySubTarget{{Name: "a"}, {Name: "b"}},
+			inputTotal:      6,
+			expectedOutput:  []int64{3, 3},
+			name:            "scale out evenly",
+		},
+		{
+			inputCounts:     []int64{0, 0},
+			inputSubTargets: []*sdk.ScalingPolicySubTarget{{Name: "a", Weight: 2}, {Name: "b"}},
+			inputTotal:      6,
+			expectedOutput:  []int64{4, 2},
+			name:            "scale out by weight",
+		},
+		{
+			inputCounts:     []int64{3, 1},
+			inputSubTargets: []*sdk.ScalingPolicySubTarget{{Name: "a", Max: 3}, {Name: "b"}},
+			inputTotal:      7,
+			expectedOutput:  []int64{3, 4},
+			name:            "scale out moves to sub targets below max",
+		},
+		{
+			inputCounts:     []int64{5, 1},
+			inputSubTargets: []*sdk.ScalingPolicySubTarget{{Name: "a"}, {Name: "b", Min: 1}},
+			inputTotal:      3,
+			expectedOutput:  []int64{2, 1},
+			name:            "scale in respects sub target min",
+		},
+		{
+			inputCounts:     []int64{6, 0},
+			inputSubTargets: []*sdk.ScalingPolicySubTarget{{Name: "a", Max: 4}, {Name: "b", Min: 1}},
+			inputTotal:      6,
+			expectedOutput:  []int64{4, 2},
+			name:            "rebalance sub targets outside their limits",
+		},
+		{
+			inputCounts:     []int64{4, 4},
+			inputSubTargets: []*sdk.ScalingPolicySubTarget{{Name: "a"}, {Name: "b"}},
+			inputTotal:      8,
+			expectedOutput:  []int64{4, 4},
+			name:            "total unchanged",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput := distributeCount(tc.inputCounts, tc.inputSubTargets, tc.inputTotal)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+		})
+	}
+}
+
+func Test_subTargetsTarget_Status(t *testing.T) {
+	inner := &subTargetsTestTarget{counts: map[string]int64{"a": 2, "b": 3}}
+	target := newSubTargetsTestTarget(inner)
+
+	status, err := target.Status(map[string]string{"region": "eu"})
+	require.NoError(t, err)
+	assert.True(t, status.Ready)
+	assert.Equal(t, int64(5), status.Count)
+	assert.Equal(t, map[string]string{
+		"sub_target.a.count":  "2",
+		"sub_target.a.ready":  "true",
+		"sub_target.a.region": "eu",
+		"sub_target.b.count":  "3",
+		"sub_target.b.ready":  "true",
+		"sub_target.b.region": "eu",
+	}, status.Meta)
+}
+
+func Test_subTargetsTarget_Scale(t *testing.T) {
+	testCases := []struct {
+		inputCount     int64
+		expectedScaled []string
+		expectedCounts map[string]int64
+		expectNoOp     bool
+		name           string
+	}{
+		{
+			inputCount:     7,
+			expectedScaled: []string{"a"},
+			expectedCounts: map[string]int64{"a": 4, "b": 3},
+			name:           "scale out",
+		},
+		{
+			inputCount:     2,
+			expectedScaled: []string{"a", "b"},
+			expectedCounts: map[string]int64{"a": 1, "b": 1},
+			name:           "scale in",
+		},
+		{
+			inputCount: 5,
+			expectNoOp: true,
+			name:       "count unchanged",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &subTargetsTestTarget{counts: map[string]int64{"a": 2, "b": 3}}
+			target := newSubTargetsTestTarget(inner)
+
+			err := target.Scale(sdk.ScalingAction{Count: tc.inputCount}, map[string]string{})
+			if tc.expectNoOp {
+				assert.IsType(t, &sdk.TargetScalingNoOpError{}, err)
+				assert.Empty(t, inner.scaled)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedScaled, inner.scaled, tc.name)
+			assert.Equal(t, tc.expectedCounts, inner.counts, tc.name)
+		})
+	}
+}
+
+func Test_subTargetsTarget_Scale_rebalanceOrder(t *testing.T) {
+	inner := &subTargetsTestTarget{counts: map[string]int64{"a": 6, "b": 0}}
+	target := &subTargetsTarget{
+		Target: inner,
+		logger: hclog.NewNullLogger(),
+		subTargets: []*sdk.ScalingPolicySubTarget{
+			{Name: "a", Max: 4, Config: map[string]string{"name": "a"}},
+			{Name: "b", Min: 2, Config: map[string]string{"name": "b"}},
+		},
+	}
+
+	require.NoError(t, target.Scale(sdk.ScalingAction{Count: 6}, map[string]string{}))
+	assert.Equal(t, []string{"b", "a"}, inner.scaled, "scale out happens before scale in")
+	assert.Equal(t, map[string]int64{"a": 4, "b": 2}, inner.counts)
+}
+
+func newSubTargetsTestTarget(inner *subTargetsTestTarget) *subTargetsTarget {
+	return &subTargetsTarget{
+		Target: inner,
+		logger: hclog.NewNullLogger(),
+		subTargets: []*sdk.ScalingPolicySubTarget{
+			{Name: "a", Config: map[string]string{"name": "a"}},
+			{Name: "b", Config: map[string]string{"name": "b"}, Min: 1},
+		},
+	}
+}
+
+// subTargetsTestTarget is a target which tracks the count of each sub target
+// using the name config key, recording the order sub targets are scaled in.
+type subTargetsTestTarget struct {
+	counts map[string]int64
+	scaled []string
+}
+
+func (t *subTargetsTestTarget) Status(config map[string]string) (*sdk.TargetStatus, error) {
+	meta := map[string]string{}
+	if region, ok := config["region"]; ok {
+		meta["region"] = region
+	}
+	return &sdk.TargetStatus{Ready: true, Count: t.counts[config["name"]], Meta: meta}, nil
+}
+
+func (t *subTargetsTestTarget) Scale(action sdk.ScalingAction, config map[string]string) error {
+	t.counts[config["name"]] = action.Count
+	t.scaled = append(t.scaled, config["name"])
+	return nil
+}
+
+func (t *subTargetsTestTarget) SetConfig(_ map[string]string) error { return nil }
+
+func (t *subTargetsTestTarget) PluginInfo() (*base.PluginInfo, error) { return nil, nil }
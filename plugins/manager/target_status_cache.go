@@ -0,0 +1,227 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package manager
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	targetpkg "github.com/hashicorp/nomad-autoscaler/plugins/target"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"golang.org/x/sync/singleflight"
+)
+
+// TargetStatusCache caches target status results for a short period of time,
+// so that policies with the same target reuse the same status instead of
+// each calling the target plugin, which usually calls a cloud provider API.
+// Concurrent calls for the same target are collapsed into a single call. A
+// nil TargetStatusCache is safe to use and never caches.
+type TargetStatusCache struct {
+	defaultTTL time.Duration
+	targetTTLs map[string]time.Duration
+
+	group singleflight.Group
+
+	lock    sync.Mutex
+	entries map[string]*targetStatusEntry
+
+	// generations is incremented for a key each time it is invalidated, and
+	// purges each time the cache is purged, so that the results of calls
+	// started before an invalidation or purge are not cached.
+	generations map[string]uint64
+	purges      uint64
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+// targetStatusEntry is a cached status.
+type targetStatusEntry struct {
+	status  *sdk.TargetStatus
+	expires time.Time
+}
+
+// NewTargetStatusCache returns a new TargetStatusCache which caches results
+// for ttl, unless the target plugin has its own TTL in targetTTLs.
+func NewTargetStatusCache(ttl time.Duration, targetTTLs map[string]time.Duration) *TargetStatusCache {
+	return &TargetStatusCache{
+		defaultTTL:  ttl,
+		targetTTLs:  targetTTLs,
+		entries:     make(map[string]*targetStatusEntry),
+		generations: make(map[string]uint64),
+		now:         time.Now,
+	}
+}
+
+// ttl returns the cache TTL for the target plugin.
+func (c *TargetStatusCache) ttl(name string) time.Duration {
+	if ttl, ok := c.targetTTLs[name]; ok {
+		return ttl
+	}
+	return c.defaultTTL
+}
+
+// Status returns the cached status of the target, calling fn to read the
+// status if there is no valid result. Errors are not cached.
+func (c *TargetStatusCache) Status(name string, config map[string]string, fn func() (*sdk.TargetStatus, error)) (*sdk.TargetStatus, error) {
+	if c == nil || c.ttl(name) <= 0 {
+		return fn()
+	}
+
+	key := targetStatusCacheKey(name, config)
+	labels := []metrics.Label{{Name: "plugin_name", Value: name}}
+
+	c.lock.Lock()
+	if entry, ok := c.entries[key]; ok && c.now().Before(entry.expires) {
+		c.lock.Unlock()
+		metrics.IncrCounterWithLabels([]string{"plugin", "target", "status", "cache_hit"}, 1, labels)
+		return copyTargetStatus(entry.status), nil
+	}
+	gen := c.generationLocked(key)
+	c.lock.Unlock()
+
+	// Calls started after an invalidation must not join a call started
+	// before it, so the generation is part of the singleflight key.
+	res, err, shared := c.group.Do(key+"\x00"+strconv.FormatUint(gen, 10), func() (interface{}, error) {
+		metrics.IncrCounterWithLabels([]string{"plugin", "target", "status", "cache_miss"}, 1, labels)
+
+		status, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		c.lock.Lock()
+		c.pruneLocked()
+		if c.generationLocked(key) == gen {
+			c.entries[key] = &targetStatusEntry{status: status, expires: c.now().Add(c.ttl(name))}
+		}
+		c.lock.Unlock()
+		return status, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if shared {
+		metrics.IncrCounterWithLabels([]string{"plugin", "target", "status", "cache_shared"}, 1, labels)
+	}
+	return copyTargetStatus(res.(*sdk.TargetStatus)), nil
+}
+
+// Invalidate removes the cached status of the target so the next call reads
+// a fresh status. It is called once the target has been scaled.
+func (c *TargetStatusCache) Invalidate(name string, config map[string]string) {
+	if c == nil {
+		return
+	}
+
+	key := targetStatusCacheKey(name, config)
+
+	c.lock.Lock()
+	delete(c.entries, key)
+	c.generations[key]++
+	c.lock.Unlock()
+}
+
+// Purge removes all cached statuses. It is called when the plugins are
+// reloaded, as their configuration may have changed.
+func (c *TargetStatusCache) Purge() {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	c.entries = make(map[string]*targetStatusEntry)
+	c.purges++
+	c.lock.Unlock()
+}
+
+// generationLocked returns the generation of the key. Both counters only ever
+// increase, so their sum changes whenever either does. It must be called
+// while holding the lock.
+func (c *TargetStatusCache) generationLocked(key string) uint64 {
+	return c.generations[key] + c.purges
+}
+
+// pruneLocked removes expired entries from the cache. It must be called while
+// holding the lock.
+func (c *TargetStatusCache) pruneLocked() {
+	now := c.now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// targetStatusCacheKey returns the cache key of the target. The config is
+// sorted by key so the same config always results in the same key.
+func targetStatusCacheKey(name string, config map[string]string) string {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(config[k])
+	}
+	return b.String()
+}
+
+// copyTargetStatus returns a copy of the input, so that callers modifying
+// their result do not affect the cached value.
+func copyTargetStatus(status *sdk.TargetStatus) *sdk.TargetStatus {
+	if status == nil {
+		return nil
+	}
+	out := *status
+	if status.Meta != nil {
+		out.Meta = make(map[string]string, len(status.Meta))
+		for k, v := range status.Meta {
+			out.Meta[k] = v
+		}
+	}
+	if status.Activities != nil {
+		out.Activities = append([]sdk.TargetActivity(nil), status.Activities...)
+	}
+	return &out
+}
+
+// cachedTarget wraps a target plugin, reading its status through the cache
+// and invalidating the cached status whenever the target is scaled.
+type cachedTarget struct {
+	targetpkg.Target
+
+	name  string
+	cache *TargetStatusCache
+}
+
+// Status satisfies the Status function on the target.Target interface.
+func (t *cachedTarget) Status(config map[string]string) (*sdk.TargetStatus, error) {
+	return t.cache.Status(t.name, config, func() (*sdk.TargetStatus, error) {
+		return t.Target.Status(config)
+	})
+}
+
+// Scale satisfies the Scale function on the target.Target interface.
+func (t *cachedTarget) Scale(action sdk.ScalingAction, config map[string]string) error {
+	defer t.cache.Invalidate(t.name, config)
+	return t.Target.Scale(action, config)
+}
+
+// DryRun satisfies the DryRun function on the target.DryRunner interface. The
+// target is not modified, so the cached status remains valid.
+func (t *cachedTarget) DryRun(action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error) {
+	return targetpkg.DryRun(t.Target, action, config)
+}
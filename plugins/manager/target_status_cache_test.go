@@ -0,0 +1,190 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package manager
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetStatusCache_Status(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := NewTargetStatusCache(10*time.Second, map[string]time.Duration{"nomad-target": 0})
+	cache.now = func() time.Time { return now }
+
+	asgConfig := map[string]string{"aws_asg_name": "clients", "node_class": "linux"}
+
+	var calls int64
+	fn := func() (*sdk.TargetStatus, error) {
+		calls++
+		return &sdk.TargetStatus{Ready: true, Count: calls, Meta: map[string]string{"foo": "bar"}}, nil
+	}
+
+	// Identical targets within the TTL reuse the status.
+	status, err := cache.Status("aws-asg", asgConfig, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Count)
+
+	status.Meta["foo"] = "baz"
+	status, err = cache.Status("aws-asg", map[string]string{"node_class": "linux", "aws_asg_name": "clients"}, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Count)
+	assert.Equal(t, "bar", status.Meta["foo"], "status should not be modified by callers")
+
+	// A different config is a different target.
+	status, err = cache.Status("aws-asg", map[string]string{"aws_asg_name": "servers"}, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.Count)
+
+	// Statuses expire after the TTL.
+	now = now.Add(10 * time.Second)
+	status, err = cache.Status("aws-asg", asgConfig, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), status.Count)
+	assert.Len(t, cache.entries, 1, "expired entries should be pruned")
+
+	// Invalidated statuses are read again.
+	cache.Invalidate("aws-asg", asgConfig)
+	status, err = cache.Status("aws-asg", asgConfig, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), status.Count)
+
+	// Purged statuses are read again.
+	cache.Purge()
+	status, err = cache.Status("aws-asg", asgConfig, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), status.Count)
+
+	// Target plugins with a zero TTL are not cached.
+	_, err = cache.Status("nomad-target", asgConfig, fn)
+	require.NoError(t, err)
+	_, err = cache.Status("nomad-target", asgConfig, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), calls)
+
+	// Errors are not cached.
+	_, err = cache.Status("aws-asg", nil, func() (*sdk.TargetStatus, error) {
+		return nil, errors.New("throttled")
+	})
+	assert.EqualError(t, err, "throttled")
+	_, err = cache.Status("aws-asg", nil, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), calls)
+
+	// A nil cache always reads the status.
+	var nilCache *TargetStatusCache
+	_, err = nilCache.Status("aws-asg", asgConfig, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), calls)
+	nilCache.Invalidate("aws-asg", asgConfig)
+	nilCache.Purge()
+}
+
+func TestTargetStatusCache_Status_concurrent(t *testing.T) {
+	cache := NewTargetStatusCache(time.Minute, nil)
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (*sdk.TargetStatus, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &sdk.TargetStatus{Ready: true, Count: 1}, nil
+	}
+
+	// Concurrent calls for the same target wait for the in-flight call.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := cache.Status("aws-asg", nil, fn)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), status.Count)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 10*time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestTargetStatusCache_Status_invalidatedInFlight(t *testing.T) {
+	cache := NewTargetStatusCache(time.Minute, nil)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		_, err := cache.Status("aws-asg", nil, func() (*sdk.TargetStatus, error) {
+			close(started)
+			<-release
+			return &sdk.TargetStatus{Count: 1}, nil
+		})
+		assert.NoError(t, err)
+	}()
+
+	// A status read while the target is being scaled must not be cached.
+	<-started
+	cache.Invalidate("aws-asg", nil)
+	close(release)
+	<-done
+
+	status, err := cache.Status("aws-asg", nil, func() (*sdk.TargetStatus, error) {
+		return &sdk.TargetStatus{Count: 2}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.Count)
+}
+
+func Test_cachedTarget(t *testing.T) {
+	cache := NewTargetStatusCache(time.Minute, nil)
+	inner := &testTarget{}
+	target := &cachedTarget{Target: inner, name: "aws-asg", cache: cache}
+	config := map[string]string{"aws_asg_name": "clients"}
+
+	_, err := target.Status(config)
+	require.NoError(t, err)
+	_, err = target.Status(config)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.statusCalls)
+
+	// Scaling the target invalidates its status, even when scaling fails.
+	inner.scaleErr = errors.New("scale failed")
+	assert.EqualError(t, target.Scale(sdk.ScalingAction{Count: 2}, config), "scale failed")
+
+	status, err := target.Status(config)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.statusCalls)
+	assert.Equal(t, int64(2), status.Count)
+}
+
+// testTarget is a minimal target.Target implementation counting its calls.
+type testTarget struct {
+	statusCalls int
+	scaleErr    error
+}
+
+func (t *testTarget) Status(_ map[string]string) (*sdk.TargetStatus, error) {
+	t.statusCalls++
+	return &sdk.TargetStatus{Ready: true, Count: int64(t.statusCalls)}, nil
+}
+
+func (t *testTarget) Scale(_ sdk.ScalingAction, _ map[string]string) error {
+	return t.scaleErr
+}
+
+func (t *testTarget) SetConfig(_ map[string]string) error { return nil }
+
+func (t *testTarget) PluginInfo() (*base.PluginInfo, error) { return nil, nil }
@@ -51,8 +51,99 @@ const (
 	// plugin.
 	InternalTargetGCEMIG = "gce-mig"
 
+	// InternalTargetAWSEC2Fleet is the Amazon Web Services EC2 Fleet and Spot
+	// Fleet target plugin.
+	InternalTargetAWSEC2Fleet = "aws-ec2-fleet"
+
+	// InternalTargetDODroplets is the DigitalOcean Droplets target plugin.
+	InternalTargetDODroplets = "do-droplets"
+
+	// InternalTargetHCloudServer is the Hetzner Cloud server target plugin.
+	InternalTargetHCloudServer = "hcloud-server"
+
+	// InternalTargetOpenStackSenlin is the OpenStack Senlin cluster target
+	// plugin.
+	InternalTargetOpenStackSenlin = "os-senlin"
+
+	// InternalTargetOCIInstancePool is the Oracle Cloud Infrastructure instance
+	// pool target plugin.
+	InternalTargetOCIInstancePool = "oci-instance-pool"
+
+	// InternalTargetLibvirtDomain is the libvirt domain target plugin.
+	InternalTargetLibvirtDomain = "libvirt-domain"
+
+	// InternalTargetExec is the target plugin which runs operator supplied
+	// commands.
+	InternalTargetExec = "exec"
+
+	// InternalTargetWebhook is the target plugin which calls operator supplied
+	// HTTP endpoints.
+	InternalTargetWebhook = "webhook"
+
+	// InternalTargetTFCWorkspace is the Terraform Cloud workspace target
+	// plugin name.
+	InternalTargetTFCWorkspace = "tfc-workspace"
+
 	// InternalAPMDatadog is the Datadog APM plugin name.
 	InternalAPMDatadog = "datadog"
+
+	// InternalAPMCloudWatch is the Amazon CloudWatch APM plugin name.
+	InternalAPMCloudWatch = "aws-cloudwatch"
+
+	// InternalAPMInfluxDB is the InfluxDB 2.x APM plugin name.
+	InternalAPMInfluxDB = "influxdb"
+
+	// InternalAPMElasticsearch is the Elasticsearch and OpenSearch APM plugin
+	// name.
+	InternalAPMElasticsearch = "elasticsearch"
+
+	// InternalAPMGraphite is the Graphite APM plugin name.
+	InternalAPMGraphite = "graphite"
+
+	// InternalAPMSplunk is the Splunk APM plugin name.
+	InternalAPMSplunk = "splunk"
+
+	// InternalAPMOTel is the OpenTelemetry OTLP receiver APM plugin name.
+	InternalAPMOTel = "otel"
+
+	// InternalAPMStatsD is the StatsD listener APM plugin name.
+	InternalAPMStatsD = "statsd"
+
+	// InternalAPMKafka is the Kafka consumer group lag APM plugin name.
+	InternalAPMKafka = "kafka"
+
+	// InternalAPMRabbitMQ is the RabbitMQ queue metrics APM plugin name.
+	InternalAPMRabbitMQ = "rabbitmq"
+
+	// InternalAPMNATS is the NATS JetStream APM plugin name.
+	InternalAPMNATS = "nats"
+
+	// InternalAPMSQS is the Amazon SQS APM plugin name.
+	InternalAPMSQS = "aws-sqs"
+
+	// InternalAPMRedis is the Redis APM plugin name.
+	InternalAPMRedis = "redis"
+
+	// InternalAPMNomadEvents is the Nomad event stream APM plugin name.
+	InternalAPMNomadEvents = "nomad-events"
+
+	// InternalAPMHTTPJSON is the HTTP JSON APM plugin name.
+	InternalAPMHTTPJSON = "http-json"
+
+	// InternalAPMLoki is the Grafana Loki APM plugin name.
+	InternalAPMLoki = "loki"
+
+	// InternalAPMInstana is the Instana APM plugin name.
+	InternalAPMInstana = "instana"
+
+	// InternalAPMAppDynamics is the AppDynamics APM plugin name.
+	InternalAPMAppDynamics = "appdynamics"
+
+	// InternalAPMWavefront is the Wavefront APM plugin name.
+	InternalAPMWavefront = "wavefront"
+
+	// InternalAPMSignalFx is the SignalFx APM plugin name.
+	InternalAPMSignalFx = "signalfx"
 )
 
 // ConfigKeyNomadConfigInherit is a generic plugin config map key that supports
@@ -6,10 +6,13 @@ package target
 import (
 	"context"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/hashicorp/nomad-autoscaler/plugins/base"
 	"github.com/hashicorp/nomad-autoscaler/plugins/shared"
 	"github.com/hashicorp/nomad-autoscaler/plugins/target/proto/v1"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // pluginClient is the gRPC client implementation of the Target interface.
@@ -43,9 +46,79 @@ func (p *pluginClient) Status(config map[string]string) (*sdk.TargetStatus, erro
 		return nil, err
 	}
 
+	activities, err := protoToActivities(statusResp.Activities)
+	if err != nil {
+		return nil, err
+	}
+
 	return &sdk.TargetStatus{
-		Ready: statusResp.Ready,
-		Count: statusResp.Count,
-		Meta:  statusResp.Meta,
+		Ready:      statusResp.Ready,
+		Count:      statusResp.Count,
+		Meta:       statusResp.Meta,
+		Activities: activities,
 	}, nil
 }
+
+// protoToActivities converts the proto target activities to their SDK
+// representation. Unset times are converted to zero times.
+func protoToActivities(input []*proto.TargetActivity) ([]sdk.TargetActivity, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+
+	out := make([]sdk.TargetActivity, 0, len(input))
+
+	for _, a := range input {
+		activity := sdk.TargetActivity{
+			ID:          a.GetId(),
+			Status:      sdk.TargetActivityStatus(a.GetStatus()),
+			Description: a.GetDescription(),
+			Message:     a.GetMessage(),
+		}
+
+		var err error
+		if a.GetStartTime() != nil {
+			if activity.StartTime, err = ptypes.Timestamp(a.GetStartTime()); err != nil {
+				return nil, err
+			}
+		}
+		if a.GetEndTime() != nil {
+			if activity.EndTime, err = ptypes.Timestamp(a.GetEndTime()); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, activity)
+	}
+	return out, nil
+}
+
+// DryRun is the gRPC client implementation of the DryRunner.DryRun interface
+// function. Plugins which do not implement the DryRunner interface, including
+// those built against older versions of the SDK, result in
+// ErrDryRunUnsupported.
+func (p *pluginClient) DryRun(action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error) {
+	req, err := shared.ScalingActionToProto(action)
+	if err != nil {
+		return nil, err
+	}
+
+	dryRunResp, err := p.client.DryRun(p.doneCTX, &proto.DryRunRequest{Action: req, Config: config})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil, ErrDryRunUnsupported
+		}
+		return nil, err
+	}
+
+	result := sdk.TargetDryRunResult{
+		Count: dryRunResp.Count,
+		Meta:  dryRunResp.Meta,
+	}
+	for _, node := range dryRunResp.Nodes {
+		result.Nodes = append(result.Nodes, sdk.TargetDryRunNode{
+			NomadNodeID:      node.GetNomadNodeId(),
+			RemoteResourceID: node.GetRemoteResourceId(),
+		})
+	}
+	return &result, nil
+}
@@ -6,9 +6,13 @@ package target
 import (
 	"context"
 
+	"github.com/golang/protobuf/ptypes"
 	plugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/nomad-autoscaler/plugins/shared"
 	"github.com/hashicorp/nomad-autoscaler/plugins/target/proto/v1"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // pluginServer is the gRPC server implementation of the Target interface.
@@ -36,9 +40,79 @@ func (p *pluginServer) Status(_ context.Context, req *proto.StatusRequest) (*pro
 		return nil, err
 	}
 
+	activities, err := activitiesToProto(statusResp.Activities)
+	if err != nil {
+		return nil, err
+	}
+
 	return &proto.StatusResponse{
-		Ready: statusResp.Ready,
-		Count: statusResp.Count,
-		Meta:  statusResp.Meta,
+		Ready:      statusResp.Ready,
+		Count:      statusResp.Count,
+		Meta:       statusResp.Meta,
+		Activities: activities,
 	}, nil
 }
+
+// activitiesToProto converts the target activities to their proto
+// representation. Zero times, such as the end time of an activity in
+// progress, are left unset.
+func activitiesToProto(input []sdk.TargetActivity) ([]*proto.TargetActivity, error) {
+
+	out := make([]*proto.TargetActivity, 0, len(input))
+
+	for _, activity := range input {
+		a := proto.TargetActivity{
+			Id:          activity.ID,
+			Status:      string(activity.Status),
+			Description: activity.Description,
+			Message:     activity.Message,
+		}
+
+		var err error
+		if !activity.StartTime.IsZero() {
+			if a.StartTime, err = ptypes.TimestampProto(activity.StartTime); err != nil {
+				return nil, err
+			}
+		}
+		if !activity.EndTime.IsZero() {
+			if a.EndTime, err = ptypes.TimestampProto(activity.EndTime); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, &a)
+	}
+	return out, nil
+}
+
+// DryRun is the gRPC server implementation of the DryRunner.DryRun interface
+// function. Plugins which do not implement the DryRunner interface return an
+// Unimplemented error, which the client translates to ErrDryRunUnsupported.
+func (p *pluginServer) DryRun(_ context.Context, req *proto.DryRunRequest) (*proto.DryRunResponse, error) {
+
+	dr, ok := p.impl.(DryRunner)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, ErrDryRunUnsupported.Error())
+	}
+
+	action, err := shared.ProtoToScalingAction(req.GetAction())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := dr.DryRun(action, req.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := proto.DryRunResponse{
+		Count: result.Count,
+		Meta:  result.Meta,
+	}
+	for _, node := range result.Nodes {
+		resp.Nodes = append(resp.Nodes, &proto.DryRunNode{
+			NomadNodeId:      node.NomadNodeID,
+			RemoteResourceId: node.RemoteResourceID,
+		})
+	}
+	return &resp, nil
+}
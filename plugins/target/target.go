@@ -4,6 +4,8 @@
 package target
 
 import (
+	"errors"
+
 	"github.com/hashicorp/nomad-autoscaler/plugins/base"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
 )
@@ -27,3 +29,28 @@ type Target interface {
 	// will be used when performing the strategy calculation.
 	Status(config map[string]string) (*sdk.TargetStatus, error)
 }
+
+// DryRunner is an optional interface which Target plugins can implement to
+// describe the changes a scaling action would make, such as which nodes
+// would be selected for draining, without performing any of them.
+type DryRunner interface {
+
+	// DryRun returns the result of performing the scaling action against the
+	// remote target as specified by the config func argument, without
+	// modifying the target.
+	DryRun(action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error)
+}
+
+// ErrDryRunUnsupported is returned by DryRun when the Target plugin does not
+// implement the DryRunner interface.
+var ErrDryRunUnsupported = errors.New("target plugin does not support dry run")
+
+// DryRun calls the DryRun function of the Target if it implements the
+// DryRunner interface, returning ErrDryRunUnsupported otherwise.
+func DryRun(t Target, action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error) {
+	dr, ok := t.(DryRunner)
+	if !ok {
+		return nil, ErrDryRunUnsupported
+	}
+	return dr.DryRun(action, config)
+}
@@ -6,8 +6,10 @@ package target
 import (
 	"os/exec"
 	"testing"
+	"time"
 
 	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -103,3 +105,97 @@ func TestTargetPluginRPCServerScale(t *testing.T) {
 	err = targetImpl.Scale(sdk.ScalingAction{}, nil)
 	require.NoError(t, err)
 }
+
+func TestTargetPluginRPCServerDryRun(t *testing.T) {
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  handshake,
+		Plugins:          map[string]plugin.Plugin{"target": &PluginTarget{}},
+		Cmd:              exec.Command("../test/bin/noop-target"),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+	})
+	defer client.Kill()
+
+	rpcClient, err := client.Client()
+	require.NoError(t, err)
+
+	raw, err := rpcClient.Dispense("target")
+	require.NoError(t, err)
+	targetImpl := raw.(Target)
+
+	result, err := DryRun(targetImpl, sdk.ScalingAction{Count: 2}, map[string]string{"node_id": "node-1", "remote_id": "i-1"})
+	require.NoError(t, err)
+	assert.Equal(t, &sdk.TargetDryRunResult{
+		Count: 2,
+		Nodes: []sdk.TargetDryRunNode{{NomadNodeID: "node-1", RemoteResourceID: "i-1"}},
+	}, result)
+}
+
+func TestTargetPluginRPCServerDryRunUnsupported(t *testing.T) {
+	rpcClient, server := plugin.TestPluginGRPCConn(t, map[string]plugin.Plugin{
+		"target": &PluginTarget{Impl: &testTarget{}},
+	})
+	defer rpcClient.Close()
+	defer server.Stop()
+
+	raw, err := rpcClient.Dispense("target")
+	require.NoError(t, err)
+	targetImpl := raw.(Target)
+
+	_, err = DryRun(targetImpl, sdk.ScalingAction{Count: 2}, nil)
+	assert.Equal(t, ErrDryRunUnsupported, err)
+
+	_, err = DryRun(&testTarget{}, sdk.ScalingAction{Count: 2}, nil)
+	assert.Equal(t, ErrDryRunUnsupported, err)
+}
+
+func TestTargetPluginRPCServerStatusActivities(t *testing.T) {
+	start := time.Date(2020, time.April, 13, 8, 4, 0, 0, time.UTC)
+
+	activities := []sdk.TargetActivity{
+		{
+			ID:          "a-2",
+			Status:      sdk.TargetActivityStatusInProgress,
+			Description: "Launching a new EC2 instance",
+			StartTime:   start,
+		},
+		{
+			ID:          "a-1",
+			Status:      sdk.TargetActivityStatusFailed,
+			Description: "Launching a new EC2 instance",
+			Message:     "insufficient capacity",
+			StartTime:   start,
+			EndTime:     start.Add(time.Minute),
+		},
+	}
+
+	rpcClient, server := plugin.TestPluginGRPCConn(t, map[string]plugin.Plugin{
+		"target": &PluginTarget{Impl: &testTarget{status: sdk.TargetStatus{Count: 1, Activities: activities}}},
+	})
+	defer rpcClient.Close()
+	defer server.Stop()
+
+	raw, err := rpcClient.Dispense("target")
+	require.NoError(t, err)
+	targetImpl := raw.(Target)
+
+	status, err := targetImpl.Status(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.Count)
+	assert.Equal(t, activities, status.Activities)
+}
+
+// testTarget is a minimal Target implementation which does not implement the
+// DryRunner interface.
+type testTarget struct {
+	status sdk.TargetStatus
+}
+
+func (t *testTarget) Scale(_ sdk.ScalingAction, _ map[string]string) error { return nil }
+
+func (t *testTarget) Status(_ map[string]string) (*sdk.TargetStatus, error) {
+	return &t.status, nil
+}
+
+func (t *testTarget) SetConfig(_ map[string]string) error { return nil }
+
+func (t *testTarget) PluginInfo() (*base.PluginInfo, error) { return nil, nil }
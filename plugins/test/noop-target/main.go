@@ -24,7 +24,10 @@ var (
 	}
 )
 
-var _ target.Target = (*Noop)(nil)
+var (
+	_ target.Target    = (*Noop)(nil)
+	_ target.DryRunner = (*Noop)(nil)
+)
 
 type Noop struct {
 	logger hclog.Logger
@@ -52,6 +55,20 @@ func (n *Noop) Status(config map[string]string) (*sdk.TargetStatus, error) {
 	}, nil
 }
 
+func (n *Noop) DryRun(action sdk.ScalingAction, config map[string]string) (*sdk.TargetDryRunResult, error) {
+	n.logger.Debug("received dry run action", "count", action.Count, "reason", action.Reason)
+
+	var nodes []sdk.TargetDryRunNode
+	if id := config["node_id"]; id != "" {
+		nodes = append(nodes, sdk.TargetDryRunNode{NomadNodeID: id, RemoteResourceID: config["remote_id"]})
+	}
+
+	return &sdk.TargetDryRunResult{
+		Count: action.Count,
+		Nodes: nodes,
+	}, nil
+}
+
 func (n *Noop) PluginInfo() (*base.PluginInfo, error) {
 	n.logger.Debug("plugin info")
 	return pluginInfo, nil
@@ -0,0 +1,183 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// admissionResponseLimit is the maximum size of an admission webhook response
+// body which will be read.
+const admissionResponseLimit = 1 << 20
+
+// errAdmissionRejected is returned when the admission webhook rejects a
+// policy, as opposed to the webhook not being reachable.
+var errAdmissionRejected = errors.New("policy rejected by admission webhook")
+
+// AdmissionRequest is the JSON payload sent to the admission webhook for each
+// new or updated policy.
+type AdmissionRequest struct {
+	PolicyID PolicyID           `json:"policy_id"`
+	Source   SourceName         `json:"source"`
+	Policy   *sdk.ScalingPolicy `json:"policy"`
+}
+
+// AdmissionResponse is the JSON payload expected from the admission webhook.
+// If Policy is set, it replaces the policy sent in the request, allowing the
+// webhook to mutate policies such as clamping their max value.
+type AdmissionResponse struct {
+	Allowed bool               `json:"allowed"`
+	Reason  string             `json:"reason,omitempty"`
+	Policy  *sdk.ScalingPolicy `json:"policy,omitempty"`
+}
+
+// AdmissionController calls an external admission webhook before a new or
+// updated policy becomes active. Policies are rejected if the webhook rejects
+// them or cannot be reached, in which case the previously admitted version of
+// the policy remains active. A nil AdmissionController is safe to use and
+// admits all policies unchanged.
+type AdmissionController struct {
+	log     hclog.Logger
+	address string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewAdmissionController returns a new AdmissionController which sends
+// admission requests to address.
+func NewAdmissionController(log hclog.Logger, address string, headers map[string]string, timeout time.Duration) *AdmissionController {
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	return &AdmissionController{
+		log:     log.Named("policy_admission"),
+		address: address,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Admit sends the policy to the admission webhook, returning the policy that
+// should become active or an error if it was rejected.
+func (a *AdmissionController) Admit(ctx context.Context, id PolicyID, source SourceName, p *sdk.ScalingPolicy) (*sdk.ScalingPolicy, error) {
+	if a == nil {
+		return p, nil
+	}
+
+	resp, err := a.send(ctx, &AdmissionRequest{PolicyID: id, Source: source, Policy: p})
+	if err != nil {
+		metrics.IncrCounter([]string{"policy", "admission", "error_count"}, 1)
+		return nil, fmt.Errorf("failed to call admission webhook: %v", err)
+	}
+
+	if !resp.Allowed {
+		metrics.IncrCounter([]string{"policy", "admission", "rejected_count"}, 1)
+		if resp.Reason == "" {
+			return nil, errAdmissionRejected
+		}
+		return nil, fmt.Errorf("%w: %s", errAdmissionRejected, resp.Reason)
+	}
+
+	if resp.Policy == nil {
+		metrics.IncrCounter([]string{"policy", "admission", "allowed_count"}, 1)
+		return p, nil
+	}
+
+	// The webhook must not be able to move the policy to a different ID, and
+	// the mutated policy must still be valid.
+	mutated := resp.Policy
+	mutated.ID = p.ID
+	if err := mutated.Validate(); err != nil {
+		metrics.IncrCounter([]string{"policy", "admission", "rejected_count"}, 1)
+		return nil, fmt.Errorf("%w: admission webhook returned an invalid policy: %v", errAdmissionRejected, err)
+	}
+	if mutated.Min > mutated.Max {
+		metrics.IncrCounter([]string{"policy", "admission", "rejected_count"}, 1)
+		return nil, fmt.Errorf("%w: admission webhook returned an invalid policy: min %d is greater than max %d",
+			errAdmissionRejected, mutated.Min, mutated.Max)
+	}
+
+	a.log.Debug("policy mutated by admission webhook", "policy_id", id, "changes", policyChanges(p, mutated))
+	metrics.IncrCounter([]string{"policy", "admission", "mutated_count"}, 1)
+	return mutated, nil
+}
+
+// admissionCache holds the last admission decision for a policy. Sources
+// resend policies which have not changed, so the cache allows the handler to
+// only call the webhook when the policy changes. It is not safe for concurrent
+// use.
+type admissionCache struct {
+	input  *sdk.ScalingPolicy
+	output *sdk.ScalingPolicy
+	err    error
+}
+
+// admit returns the cached decision if p is unchanged since it was last
+// admitted, otherwise it calls the admission controller and caches the
+// decision. Failures to call the webhook are not cached, so the policy is
+// submitted again when it is next received.
+func (c *admissionCache) admit(ctx context.Context, a *AdmissionController, id PolicyID, source SourceName, p *sdk.ScalingPolicy) (*sdk.ScalingPolicy, error) {
+	if c.input != nil && reflect.DeepEqual(c.input, p) {
+		return c.output, c.err
+	}
+
+	input := *p
+	output, err := a.Admit(ctx, id, source, p)
+	if err != nil && !errors.Is(err, errAdmissionRejected) {
+		c.input, c.output, c.err = nil, nil, nil
+		return nil, err
+	}
+
+	c.input, c.output, c.err = &input, nil, err
+	if output != nil {
+		cached := *output
+		c.output = &cached
+	}
+	return output, err
+}
+
+func (a *AdmissionController) send(ctx context.Context, r *AdmissionRequest) (*AdmissionResponse, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.address, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range a.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+
+	var out AdmissionResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, admissionResponseLimit)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return &out, nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmissionController_Admit(t *testing.T) {
+	var resp *AdmissionResponse
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var req AdmissionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, PolicyID("a"), req.PolicyID)
+		assert.Equal(t, SourceNameNomad, req.Source)
+
+		if resp == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer srv.Close()
+
+	a := NewAdmissionController(hclog.NewNullLogger(), srv.URL, map[string]string{"Authorization": "Bearer secret"}, 0)
+	p := &sdk.ScalingPolicy{ID: "a", Min: 1, Max: 50}
+
+	testCases := []struct {
+		name        string
+		resp        *AdmissionResponse
+		expectedMax int64
+		expectedErr string
+	}{
+		{
+			name:        "allowed",
+			resp:        &AdmissionResponse{Allowed: true},
+			expectedMax: 50,
+		},
+		{
+			name:        "mutated",
+			resp:        &AdmissionResponse{Allowed: true, Policy: &sdk.ScalingPolicy{ID: "other", Min: 1, Max: 20}},
+			expectedMax: 20,
+		},
+		{
+			name:        "rejected",
+			resp:        &AdmissionResponse{Allowed: false, Reason: "max is above 20"},
+			expectedErr: "policy rejected by admission webhook: max is above 20",
+		},
+		{
+			name:        "invalid mutation",
+			resp:        &AdmissionResponse{Allowed: true, Policy: &sdk.ScalingPolicy{Min: 30, Max: 20}},
+			expectedErr: "min 30 is greater than max 20",
+		},
+		{
+			name:        "webhook error",
+			expectedErr: "unexpected response code 500",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp = tc.resp
+
+			got, err := a.Admit(context.Background(), "a", SourceNameNomad, p)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "a", got.ID)
+			assert.Equal(t, tc.expectedMax, got.Max)
+		})
+	}
+
+	// A nil AdmissionController admits policies unchanged.
+	var nilAdmission *AdmissionController
+	got, err := nilAdmission.Admit(context.Background(), "a", SourceNameNomad, p)
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+}
+
+func TestAdmissionCache_admit(t *testing.T) {
+	var (
+		calls int
+		resp  *AdmissionResponse
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if resp == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer srv.Close()
+
+	a := NewAdmissionController(hclog.NewNullLogger(), srv.URL, nil, 0)
+	c := &admissionCache{}
+
+	// Unchanged policies reuse the previous decision.
+	resp = &AdmissionResponse{Allowed: true, Policy: &sdk.ScalingPolicy{Min: 1, Max: 20}}
+	for i := 0; i < 2; i++ {
+		got, err := c.admit(context.Background(), a, "a", SourceNameNomad, &sdk.ScalingPolicy{ID: "a", Min: 1, Max: 50})
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), got.Max)
+	}
+	assert.Equal(t, 1, calls)
+
+	// Rejections are cached too.
+	resp = &AdmissionResponse{Allowed: false, Reason: "max is above 20"}
+	for i := 0; i < 2; i++ {
+		_, err := c.admit(context.Background(), a, "a", SourceNameNomad, &sdk.ScalingPolicy{ID: "a", Min: 1, Max: 40})
+		assert.ErrorContains(t, err, "max is above 20")
+	}
+	assert.Equal(t, 2, calls)
+
+	// Failures to call the webhook are retried.
+	resp = nil
+	for i := 0; i < 2; i++ {
+		_, err := c.admit(context.Background(), a, "a", SourceNameNomad, &sdk.ScalingPolicy{ID: "a", Min: 1, Max: 30})
+		assert.ErrorContains(t, err, "unexpected response code 500")
+	}
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,438 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	hclog "github.com/hashicorp/go-hclog"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	filePolicy "github.com/hashicorp/nomad-autoscaler/policy/file"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/uuid"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// configKeys are the keys which can be set within the policy source
+	// config block.
+	configKeyToken       = "token"
+	configKeyPersistence = "persistence"
+	configKeyPath        = "path"
+	configKeyNamespace   = "namespace"
+
+	// configValues are the supported values of the persistence config key.
+	configValuePersistenceNone     = "none"
+	configValuePersistenceDisk     = "disk"
+	configValuePersistenceVariable = "nomad-variables"
+
+	// configValuePathDefault is the default variable path prefix used when
+	// persisting policies to Nomad variables.
+	configValuePathDefault = "nomad-autoscaler/api-policies"
+)
+
+var (
+	// ErrPermissionDenied is returned when a request does not present the
+	// token configured for the source.
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrSourceNotEnabled is returned when the API is used while the API
+	// policy source is not enabled.
+	ErrSourceNotEnabled = errors.New("api policy source is not enabled")
+
+	// ErrInvalidPolicy is wrapped by errors returned when the submitted
+	// policy document cannot be decoded or fails validation.
+	ErrInvalidPolicy = errors.New("invalid policy")
+
+	// validName is the set of characters allowed in a policy name, so that
+	// names can be safely used within URLs, file names and variable paths.
+	validName = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
+// Ensure Source satisfies the Source interface.
+var _ policy.Source = (*Source)(nil)
+
+// Source is the API implementation of the policy.Source interface. Policies
+// are pushed to the agent through the HTTP API and held in memory, optionally
+// being persisted so they survive agent restarts.
+type Source struct {
+	log             hclog.Logger
+	token           string
+	policyProcessor *policy.Processor
+	store           store
+
+	// reloadCh helps coordinate reloading the of the MonitorIDs routine.
+	reloadCh chan struct{}
+
+	// policies maps a policy name to the policy pushed to the source.
+	policies map[string]*StoredPolicy
+
+	// updateCh is closed, and replaced, each time the policies change so that
+	// the MonitorIDs and MonitorPolicy routines can react without polling.
+	updateCh chan struct{}
+
+	// lock protects the fields above.
+	lock sync.RWMutex
+
+	// writeLock serializes calls to Put and Delete, so the store and the
+	// in-memory policies are updated in the same order.
+	writeLock sync.Mutex
+}
+
+// StoredPolicy is a policy held by the API source.
+type StoredPolicy struct {
+
+	// Name is the name of the policy, which is used to address it within the
+	// HTTP API.
+	Name string
+
+	// ID is the policy ID assigned by the source.
+	ID policy.PolicyID
+
+	// Policy is the processed policy.
+	Policy *sdk.ScalingPolicy
+}
+
+// NewAPISource returns a new API policy source. The Nomad client is only used
+// when the source persists policies to Nomad variables.
+func NewAPISource(log hclog.Logger, nomad *api.Client, config map[string]string, policyProcessor *policy.Processor) (*Source, error) {
+	token := config[configKeyToken]
+	if token == "" {
+		return nil, fmt.Errorf("%q must be set", configKeyToken)
+	}
+
+	var st store
+	switch persistence := config[configKeyPersistence]; persistence {
+	case "", configValuePersistenceNone:
+	case configValuePersistenceDisk:
+		dir := config[configKeyPath]
+		if dir == "" {
+			return nil, fmt.Errorf("%q must be set when persisting policies to disk", configKeyPath)
+		}
+		st = &diskStore{dir: dir}
+	case configValuePersistenceVariable:
+		prefix := configValuePathDefault
+		if v := config[configKeyPath]; v != "" {
+			prefix = strings.Trim(v, "/")
+		}
+		st = &variableStore{client: nomad, prefix: prefix, namespace: config[configKeyNamespace]}
+	default:
+		return nil, fmt.Errorf("invalid %q value %q", configKeyPersistence, persistence)
+	}
+
+	return newSource(log, token, st, policyProcessor), nil
+}
+
+func newSource(log hclog.Logger, token string, st store, policyProcessor *policy.Processor) *Source {
+	return &Source{
+		log:             log.ResetNamed("api_policy_source"),
+		token:           token,
+		policyProcessor: policyProcessor,
+		store:           st,
+		reloadCh:        make(chan struct{}),
+		policies:        make(map[string]*StoredPolicy),
+		updateCh:        make(chan struct{}),
+	}
+}
+
+// SetNomadClient updates the Nomad client used to persist policies to Nomad
+// variables.
+func (s *Source) SetNomadClient(nomad *api.Client) {
+	if vs, ok := s.store.(*variableStore); ok {
+		vs.setClient(nomad)
+	}
+}
+
+// Name satisfies the Name function of the policy.Source interface.
+func (s *Source) Name() policy.SourceName {
+	return policy.SourceNameAPI
+}
+
+// ReloadIDsMonitor satisfies the ReloadIDsMonitor function of the
+// policy.Source interface.
+func (s *Source) ReloadIDsMonitor() {
+	s.reloadCh <- struct{}{}
+}
+
+// MonitorIDs loads any persisted policies and then sends the IDs of the
+// enabled policies held by the source in the resultCh channel each time they
+// change. Errors are sent through the errCh channel.
+//
+// This function blocks until the context is closed.
+func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
+	s.log.Debug("starting api policy source")
+
+	if err := s.load(ctx); err != nil {
+		policy.HandleSourceError(s.Name(), err, req.ErrCh)
+	}
+
+	for {
+		s.lock.RLock()
+		ids := s.enabledIDs()
+		updateCh := s.updateCh
+		s.lock.RUnlock()
+
+		req.ResultCh <- policy.IDMessage{IDs: ids, Source: s.Name()}
+
+		select {
+		case <-ctx.Done():
+			s.log.Trace("stopping api ID monitor")
+			return
+		case <-s.reloadCh:
+			s.log.Trace("reloading api policies")
+		case <-updateCh:
+		}
+	}
+}
+
+// MonitorPolicy satisfies the MonitorPolicy function of the policy.Source
+// interface. Policies are only modified through the HTTP API, so this only
+// needs to check whether the stored policy has changed after each update.
+func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq) {
+
+	// Close channels when done with the monitoring loop.
+	defer close(req.ResultCh)
+	defer close(req.ErrCh)
+
+	log := s.log.With("policy_id", req.ID)
+	log.Info("starting api policy monitor")
+
+	var last *sdk.ScalingPolicy
+
+	for {
+		s.lock.RLock()
+		p := s.getByID(req.ID)
+		updateCh := s.updateCh
+		s.lock.RUnlock()
+
+		switch {
+		case p == nil:
+			policy.HandleSourceError(s.Name(), fmt.Errorf("failed to get policy %s", req.ID), req.ErrCh)
+		case last == nil || !reflect.DeepEqual(last, p.Policy):
+			if last != nil {
+				log.Info("api policy content has changed", "name", p.Name)
+			}
+			last = p.Policy
+			req.ResultCh <- *p.Policy
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Debug("stopping api policy monitor due to context done")
+			return
+		case <-updateCh:
+		case <-req.ReloadCh:
+			log.Info("api policy source monitor received reload signal")
+		}
+	}
+}
+
+// Authorize checks the passed token against the token configured for the
+// source, returning ErrPermissionDenied if they do not match.
+func (s *Source) Authorize(token string) error {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// List returns all the policies held by the source, sorted by name.
+func (s *Source) List() []*StoredPolicy {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	out := make([]*StoredPolicy, 0, len(s.policies))
+	for _, p := range s.policies {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Get returns the named policy, or policy.ErrPolicyNotFound if the source
+// does not hold it.
+func (s *Source) Get(name string) (*StoredPolicy, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	p, ok := s.policies[name]
+	if !ok {
+		return nil, policy.ErrPolicyNotFound
+	}
+	return p, nil
+}
+
+// Put creates or replaces the named policy using the passed document, which
+// must use the file policy format and hold a single policy with the same
+// name. The filename is used to determine the document format.
+func (s *Source) Put(ctx context.Context, name, filename string, doc []byte) (*StoredPolicy, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("%w: name %q must only contain letters, numbers, '_', '-' and '.'", ErrInvalidPolicy, name)
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	s.lock.RLock()
+	existing := s.policies[name]
+	s.lock.RUnlock()
+
+	var id policy.PolicyID
+	if existing != nil {
+		id = existing.ID
+	} else {
+		id = policy.PolicyID(uuid.Generate())
+	}
+
+	p, err := s.decode(id, name, filename, doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+
+	// Persist the policy before it becomes active, so that an accepted
+	// policy is not lost on restart.
+	if s.store != nil {
+		if err := s.store.put(ctx, name, filename, doc); err != nil {
+			return nil, fmt.Errorf("failed to persist policy: %v", err)
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.policies[name] = p
+	s.notifyLocked()
+	return p, nil
+}
+
+// Delete removes the named policy, returning policy.ErrPolicyNotFound if the
+// source does not hold it.
+func (s *Source) Delete(ctx context.Context, name string) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+
+	if s.store != nil {
+		if err := s.store.delete(ctx, name); err != nil {
+			return fmt.Errorf("failed to delete persisted policy: %v", err)
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.policies, name)
+	s.notifyLocked()
+	return nil
+}
+
+// load reads the persisted policies into memory. Documents which fail to be
+// decoded are skipped so a single bad document doesn't block the rest.
+func (s *Source) load(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+
+	docs, err := s.store.load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load persisted policies: %v", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var mErr *multierror.Error
+	for name, d := range docs {
+		id := policy.PolicyID(uuid.Generate())
+		if existing, ok := s.policies[name]; ok {
+			id = existing.ID
+		}
+
+		p, err := s.decode(id, name, d.filename, d.doc)
+		if err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to decode persisted policy %s: %v", name, err))
+			continue
+		}
+		s.policies[name] = p
+	}
+	s.notifyLocked()
+
+	return mErr.ErrorOrNil()
+}
+
+// decode parses, processes and validates the policy document. Documents are
+// supplied by remote clients, so cannot use the functions which read from the
+// agent host.
+func (s *Source) decode(id policy.PolicyID, name, filename string, doc []byte) (*StoredPolicy, error) {
+	policies, err := filePolicy.DecodeUntrusted(filename, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(policies) != 1 {
+		return nil, fmt.Errorf("document must contain exactly one policy, found %d", len(policies))
+	}
+	p, ok := policies[name]
+	if !ok {
+		return nil, fmt.Errorf("document does not contain policy %q", name)
+	}
+
+	p.ID = id.String()
+	s.policyProcessor.ApplyPolicyDefaults(p)
+
+	if err := s.policyProcessor.ValidatePolicy(p); err != nil {
+		return nil, err
+	}
+
+	for _, c := range p.Checks {
+		s.policyProcessor.CanonicalizeCheck(c, p.Target)
+	}
+
+	return &StoredPolicy{Name: name, ID: id, Policy: p}, nil
+}
+
+// enabledIDs returns the sorted IDs of the enabled policies. The caller must
+// hold the lock.
+func (s *Source) enabledIDs() []policy.PolicyID {
+	ids := make([]policy.PolicyID, 0, len(s.policies))
+	for _, p := range s.policies {
+		if !p.Policy.Enabled {
+			s.log.Trace("policy is disabled therefore ignoring", "policy_id", p.ID, "name", p.Name)
+			continue
+		}
+		ids = append(ids, p.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// getByID returns the policy with the passed ID, or nil if the source does
+// not hold it. The caller must hold the lock.
+func (s *Source) getByID(id policy.PolicyID) *StoredPolicy {
+	for _, p := range s.policies {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// notifyLocked notifies the monitor routines that the policies have changed.
+// The caller must hold the write lock.
+func (s *Source) notifyLocked() {
+	close(s.updateCh)
+	s.updateCh = make(chan struct{})
+}
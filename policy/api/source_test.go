@@ -0,0 +1,264 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+scaling "web" {
+  enabled = true
+  min     = 1
+  max     = 10
+
+  policy {
+    target "aws-asg" {
+      aws_asg_name = "my-asg"
+    }
+  }
+}
+`
+
+const testJSONPolicy = `{
+  "scaling": {
+    "web": {
+      "enabled": true,
+      "max": 20,
+      "policy": {
+        "target": {
+          "aws-asg": {
+            "aws_asg_name": "my-asg"
+          }
+        }
+      }
+    }
+  }
+}`
+
+func newTestSource(t *testing.T, st store) *Source {
+	t.Helper()
+
+	processor := policy.NewProcessor(&policy.ConfigDefaults{
+		DefaultEvaluationInterval: 10 * time.Second,
+		DefaultCooldown:           time.Minute,
+	}, nil)
+	return newSource(hclog.NewNullLogger(), "secret", st, processor)
+}
+
+func TestNewAPISource(t *testing.T) {
+	processor := policy.NewProcessor(&policy.ConfigDefaults{}, nil)
+
+	_, err := NewAPISource(hclog.NewNullLogger(), nil, map[string]string{}, processor)
+	assert.ErrorContains(t, err, `"token" must be set`)
+
+	_, err = NewAPISource(hclog.NewNullLogger(), nil, map[string]string{"token": "secret", "persistence": "disk"}, processor)
+	assert.ErrorContains(t, err, `"path" must be set`)
+
+	_, err = NewAPISource(hclog.NewNullLogger(), nil, map[string]string{"token": "secret", "persistence": "s3"}, processor)
+	assert.ErrorContains(t, err, `invalid "persistence" value "s3"`)
+
+	s, err := NewAPISource(hclog.NewNullLogger(), nil, map[string]string{"token": "secret", "persistence": "nomad-variables"}, processor)
+	require.NoError(t, err)
+	assert.Equal(t, configValuePathDefault, s.store.(*variableStore).prefix)
+}
+
+func TestSource_Authorize(t *testing.T) {
+	s := newTestSource(t, nil)
+
+	assert.NoError(t, s.Authorize("secret"))
+	assert.ErrorIs(t, s.Authorize("wrong"), ErrPermissionDenied)
+	assert.ErrorIs(t, s.Authorize(""), ErrPermissionDenied)
+}
+
+func TestSource_crud(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSource(t, nil)
+
+	// Create a new policy.
+	p, err := s.Put(ctx, "web", "web.hcl", []byte(testPolicy))
+	require.NoError(t, err)
+	assert.Equal(t, "web", p.Name)
+	assert.Equal(t, p.ID.String(), p.Policy.ID)
+	assert.Equal(t, int64(10), p.Policy.Max)
+	assert.Equal(t, time.Minute, p.Policy.Cooldown)
+
+	// Updating the policy should keep its ID.
+	updated, err := s.Put(ctx, "web", "web.json", []byte(testJSONPolicy))
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, updated.ID)
+	assert.Equal(t, int64(20), updated.Policy.Max)
+
+	got, err := s.Get("web")
+	require.NoError(t, err)
+	assert.Equal(t, updated, got)
+	assert.Equal(t, []*StoredPolicy{updated}, s.List())
+
+	// Delete the policy.
+	require.NoError(t, s.Delete(ctx, "web"))
+	_, err = s.Get("web")
+	assert.ErrorIs(t, err, policy.ErrPolicyNotFound)
+	assert.ErrorIs(t, s.Delete(ctx, "web"), policy.ErrPolicyNotFound)
+	assert.Empty(t, s.List())
+}
+
+func TestSource_Put_invalid(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSource(t, nil)
+
+	testCases := []struct {
+		name        string
+		policyName  string
+		doc         string
+		expectedErr string
+	}{
+		{
+			name:        "invalid name",
+			policyName:  "../web",
+			doc:         testPolicy,
+			expectedErr: "must only contain",
+		},
+		{
+			name:        "name mismatch",
+			policyName:  "api",
+			doc:         testPolicy,
+			expectedErr: `document does not contain policy "api"`,
+		},
+		{
+			name:        "multiple policies",
+			policyName:  "web",
+			doc:         testPolicy + strings.ReplaceAll(testPolicy, `"web"`, `"api"`),
+			expectedErr: "exactly one policy",
+		},
+		{
+			name:        "syntax error",
+			policyName:  "web",
+			doc:         `scaling "web" {`,
+			expectedErr: "invalid policy",
+		},
+		{
+			name:        "fails validation",
+			policyName:  "web",
+			doc:         "scaling \"web\" {\n  min = 5\n  max = 1\n  policy {}\n}",
+			expectedErr: "policy Min must not be greater Max",
+		},
+		{
+			name:        "host functions",
+			policyName:  "web",
+			doc:         "scaling \"web\" {\n  max = file(\"/etc/hostname\")\n  policy {}\n}",
+			expectedErr: `There is no function named "file"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Put(ctx, tc.policyName, tc.policyName+".hcl", []byte(tc.doc))
+			assert.ErrorIs(t, err, ErrInvalidPolicy)
+			assert.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+	assert.Empty(t, s.List())
+}
+
+func TestSource_diskPersistence(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "policies")
+
+	s := newTestSource(t, &diskStore{dir: dir})
+	_, err := s.Put(ctx, "web", "web.hcl", []byte(testPolicy))
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "web.hcl"))
+
+	// Changing the format should replace the previous file.
+	_, err = s.Put(ctx, "web", "web.json", []byte(testJSONPolicy))
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "web.json"))
+	assert.NoFileExists(t, filepath.Join(dir, "web.hcl"))
+
+	_, err = s.Put(ctx, "api", "api.hcl", []byte(`scaling "api" {
+  enabled = true
+  max     = 3
+  policy {
+    target "aws-asg" {}
+  }
+}`))
+	require.NoError(t, err)
+
+	// A new source should load the persisted policies.
+	restarted := newTestSource(t, &diskStore{dir: dir})
+	require.NoError(t, restarted.load(ctx))
+	require.Len(t, restarted.List(), 2)
+
+	p, err := restarted.Get("web")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), p.Policy.Max)
+
+	// Deleted policies should be removed from disk.
+	require.NoError(t, restarted.Delete(ctx, "api"))
+	assert.NoFileExists(t, filepath.Join(dir, "api.hcl"))
+
+	// Persistence failures should leave the policy unchanged.
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.WriteFile(dir, nil, 0o644))
+	_, err = restarted.Put(ctx, "web", "web.hcl", []byte(testPolicy))
+	assert.ErrorContains(t, err, "failed to persist policy")
+
+	p, err = restarted.Get("web")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), p.Policy.Max)
+}
+
+// errStore is a store which fails all operations.
+type errStore struct{}
+
+func (errStore) load(context.Context) (map[string]*document, error) {
+	return nil, errors.New("load failed")
+}
+func (errStore) put(context.Context, string, string, []byte) error { return errors.New("put failed") }
+func (errStore) delete(context.Context, string) error              { return errors.New("delete failed") }
+
+func TestSource_MonitorIDs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := newTestSource(t, errStore{})
+
+	resultCh := make(chan policy.IDMessage)
+	errCh := make(chan error, 1)
+	go s.MonitorIDs(ctx, policy.MonitorIDsReq{ErrCh: errCh, ResultCh: resultCh})
+
+	// Load errors should be reported, followed by the empty list of IDs.
+	assert.ErrorContains(t, <-errCh, "load failed")
+	msg := <-resultCh
+	assert.Equal(t, policy.SourceNameAPI, msg.Source)
+	assert.Empty(t, msg.IDs)
+
+	// Add a policy directly, as the store rejects writes.
+	s.lock.Lock()
+	p, err := s.decode("id", "web", "web.hcl", []byte(testPolicy))
+	require.NoError(t, err)
+	s.policies["web"] = p
+	s.notifyLocked()
+	s.lock.Unlock()
+
+	msg = <-resultCh
+	assert.Equal(t, []policy.PolicyID{"id"}, msg.IDs)
+
+	// Policy monitors should receive the policy.
+	policyCh := make(chan sdk.ScalingPolicy)
+	go s.MonitorPolicy(ctx, policy.MonitorPolicyReq{ID: "id", ErrCh: make(chan error, 1), ResultCh: policyCh})
+	assert.Equal(t, int64(10), (<-policyCh).Max)
+}
@@ -0,0 +1,199 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// variableItem is the item of the Nomad variable which holds the policy
+// document.
+const variableItem = "policy"
+
+// store persists the policy documents pushed to the API source.
+type store interface {
+	// load returns all the persisted documents, keyed by policy name.
+	load(ctx context.Context) (map[string]*document, error)
+
+	// put persists the document of the named policy, replacing any previous
+	// version.
+	put(ctx context.Context, name, filename string, doc []byte) error
+
+	// delete removes the persisted document of the named policy.
+	delete(ctx context.Context, name string) error
+}
+
+// document is a persisted policy document along with the filename used to
+// determine its format.
+type document struct {
+	filename string
+	doc      []byte
+}
+
+// documentFilename returns the filename used to decode a persisted policy
+// document. Documents starting with a brace are treated as JSON, otherwise as
+// HCL.
+func documentFilename(name string, doc []byte) string {
+	if strings.HasPrefix(strings.TrimSpace(string(doc)), "{") {
+		return name + ".json"
+	}
+	return name + ".hcl"
+}
+
+// diskStore persists each policy document as a file within a directory.
+type diskStore struct {
+	dir string
+}
+
+func (d *diskStore) load(_ context.Context) (map[string]*document, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	docs := make(map[string]*document)
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".hcl" && ext != ".json") {
+			continue
+		}
+
+		doc, err := os.ReadFile(filepath.Join(d.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		docs[strings.TrimSuffix(e.Name(), ext)] = &document{filename: e.Name(), doc: doc}
+	}
+	return docs, nil
+}
+
+func (d *diskStore) put(_ context.Context, name, filename string, doc []byte) error {
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it, so a crash never leaves a
+	// partially written policy behind.
+	dst := filepath.Join(d.dir, name+filepath.Ext(filename))
+	tmp, err := os.CreateTemp(d.dir, "."+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(doc); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return err
+	}
+
+	// Remove any copy of the policy stored in the other format.
+	return d.removeExcept(name, dst)
+}
+
+func (d *diskStore) delete(_ context.Context, name string) error {
+	return d.removeExcept(name, "")
+}
+
+// removeExcept removes the files of the named policy, apart from keep.
+func (d *diskStore) removeExcept(name, keep string) error {
+	for _, ext := range []string{".hcl", ".json"} {
+		p := filepath.Join(d.dir, name+ext)
+		if p == keep {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// variableStore persists each policy document as a Nomad variable below a
+// path prefix.
+type variableStore struct {
+	prefix    string
+	namespace string
+
+	client     *api.Client
+	clientLock sync.RWMutex
+}
+
+func (v *variableStore) setClient(client *api.Client) {
+	v.clientLock.Lock()
+	defer v.clientLock.Unlock()
+	v.client = client
+}
+
+func (v *variableStore) variables() *api.Variables {
+	v.clientLock.RLock()
+	defer v.clientLock.RUnlock()
+	return v.client.Variables()
+}
+
+func (v *variableStore) load(ctx context.Context) (map[string]*document, error) {
+	q := &api.QueryOptions{Namespace: v.namespace}
+	metas, _, err := v.variables().PrefixList(v.prefix+"/", q.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list variables: %v", err)
+	}
+
+	docs := make(map[string]*document, len(metas))
+	for _, m := range metas {
+		name := path.Base(m.Path)
+		if m.Path != v.path(name) {
+			continue
+		}
+
+		vr, _, err := v.variables().Read(m.Path, q.WithContext(ctx))
+		if err != nil {
+			if errors.Is(err, api.ErrVariablePathNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read variable %s: %v", m.Path, err)
+		}
+
+		doc := []byte(vr.Items[variableItem])
+		docs[name] = &document{filename: documentFilename(name, doc), doc: doc}
+	}
+	return docs, nil
+}
+
+func (v *variableStore) put(ctx context.Context, name, _ string, doc []byte) error {
+	q := &api.WriteOptions{Namespace: v.namespace}
+	_, _, err := v.variables().Update(&api.Variable{
+		Namespace: v.namespace,
+		Path:      v.path(name),
+		Items:     api.VariableItems{variableItem: string(doc)},
+	}, q.WithContext(ctx))
+	return err
+}
+
+func (v *variableStore) delete(ctx context.Context, name string) error {
+	q := &api.WriteOptions{Namespace: v.namespace}
+	_, err := v.variables().Delete(v.path(name), q.WithContext(ctx))
+	return err
+}
+
+// path returns the variable path used to store the named policy.
+func (v *variableStore) path(name string) string {
+	return v.prefix + "/" + name
+}
@@ -0,0 +1,235 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// ConflictStrategyOverride means that when policies from multiple sources
+	// target the same resource, only the policies from the highest priority
+	// source are evaluated.
+	ConflictStrategyOverride = "override"
+
+	// ConflictStrategyNone means that conflicting policies are all evaluated,
+	// with the conflict only being logged and reported via metrics.
+	ConflictStrategyNone = "none"
+)
+
+// ConflictResolver tracks the targets of all active policies so that
+// conflicts, where policies from multiple sources target the same resource,
+// can be detected and resolved deterministically. A nil ConflictResolver is
+// safe to use and allows all policies.
+type ConflictResolver struct {
+	log      hclog.Logger
+	strategy string
+
+	// priority maps a source name to its rank, where a lower rank indicates a
+	// higher priority. Sources not found within the map are ranked below all
+	// configured sources.
+	priority map[SourceName]int
+
+	lock sync.RWMutex
+
+	// claims maps a target key to the policies, and their source, which
+	// target it.
+	claims map[string]map[PolicyID]SourceName
+
+	// keys maps a policy to the target key it currently claims.
+	keys map[PolicyID]string
+}
+
+// NewConflictResolver returns a new ConflictResolver using the passed
+// strategy. The priority list is ordered from the highest to the lowest
+// priority source.
+func NewConflictResolver(log hclog.Logger, strategy string, priority []SourceName) *ConflictResolver {
+	rank := make(map[SourceName]int, len(priority))
+	for i, s := range priority {
+		if _, ok := rank[s]; !ok {
+			rank[s] = i
+		}
+	}
+
+	return &ConflictResolver{
+		log:      log.Named("conflict_resolver"),
+		strategy: strategy,
+		priority: rank,
+		claims:   make(map[string]map[PolicyID]SourceName),
+		keys:     make(map[PolicyID]string),
+	}
+}
+
+// Register records the target of the passed policy, replacing any previous
+// registration for the same policy ID. If this results in a new conflict
+// with a policy from another source, the conflict is logged and a metric
+// emitted.
+func (r *ConflictResolver) Register(id PolicyID, source SourceName, t *sdk.ScalingPolicyTarget) {
+	if r == nil || t == nil {
+		return
+	}
+
+	key := targetKey(t)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if old, ok := r.keys[id]; ok {
+		if old == key {
+			return
+		}
+		r.removeClaimLocked(id, old)
+	}
+
+	claims, ok := r.claims[key]
+	if !ok {
+		claims = make(map[PolicyID]SourceName)
+		r.claims[key] = claims
+	}
+
+	// Only cross-source conflicts are handled here, so check whether this is
+	// the first claim from this source before adding it.
+	newConflict := false
+	for _, s := range claims {
+		if s != source {
+			newConflict = true
+			break
+		}
+	}
+
+	claims[id] = source
+	r.keys[id] = key
+
+	if !newConflict {
+		return
+	}
+
+	winner := r.winningSourceLocked(claims)
+
+	policies := make([]string, 0, len(claims))
+	for pID, s := range claims {
+		policies = append(policies, fmt.Sprintf("%s(%s)", pID, s))
+	}
+	sort.Strings(policies)
+
+	r.log.Warn("detected policies from multiple sources with the same target",
+		"target", key, "policies", policies, "strategy", r.strategy, "winning_source", winner)
+
+	metrics.IncrCounterWithLabels([]string{"policy", "conflict", "count"}, 1,
+		[]metrics.Label{
+			{Name: "policy_source", Value: string(source)},
+			{Name: "winning_source", Value: string(winner)},
+		})
+}
+
+// Deregister removes the target registration of the passed policy.
+func (r *ConflictResolver) Deregister(id PolicyID) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if key, ok := r.keys[id]; ok {
+		r.removeClaimLocked(id, key)
+	}
+}
+
+// Allowed returns whether the passed policy should be evaluated according to
+// the configured strategy. Policies which have not been registered are
+// always allowed, as are all policies unless the override strategy is used.
+func (r *ConflictResolver) Allowed(id PolicyID) bool {
+	if r == nil || r.strategy != ConflictStrategyOverride {
+		return true
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return true
+	}
+
+	claims := r.claims[key]
+	return claims[id] == r.winningSourceLocked(claims)
+}
+
+// winningSourceLocked returns the highest priority source within the passed
+// claims. Sources with the same rank are ordered by name so the result is
+// deterministic. The caller must hold the lock.
+func (r *ConflictResolver) winningSourceLocked(claims map[PolicyID]SourceName) SourceName {
+	var winner SourceName
+	winnerRank := -1
+
+	for _, s := range claims {
+		rank := r.rank(s)
+		if winnerRank == -1 || rank < winnerRank || (rank == winnerRank && s < winner) {
+			winner, winnerRank = s, rank
+		}
+	}
+	return winner
+}
+
+func (r *ConflictResolver) rank(s SourceName) int {
+	if rank, ok := r.priority[s]; ok {
+		return rank
+	}
+	return len(r.priority)
+}
+
+func (r *ConflictResolver) removeClaimLocked(id PolicyID, key string) {
+	delete(r.keys, id)
+	if claims, ok := r.claims[key]; ok {
+		delete(claims, id)
+		if len(claims) == 0 {
+			delete(r.claims, key)
+		}
+	}
+}
+
+// targetKey builds an identifier for the resource a target refers to. Nomad
+// job groups are identified regardless of the target plugin name, as the
+// Nomad source and the file source can express the same group differently.
+func targetKey(t *sdk.ScalingPolicyTarget) string {
+	if t.IsJobTaskGroupTarget() {
+		ns := t.Config["Namespace"]
+		if ns == "" {
+			ns = "default"
+		}
+		return fmt.Sprintf("nomad/%s/%s/%s", ns, t.Config[sdk.TargetConfigKeyJob], t.Config[sdk.TargetConfigKeyTaskGroup])
+	}
+
+	key := t.Name + "/" + configKey(t.Config)
+
+	// Policies spanning several sub targets refer to the resources of all of
+	// them.
+	for _, s := range t.SubTargets {
+		key += "/" + s.Name + ":" + configKey(s.Config)
+	}
+	return key
+}
+
+// configKey builds a stable identifier for a target config.
+func configKey(config map[string]string) string {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+config[k])
+	}
+	return strings.Join(parts, ",")
+}
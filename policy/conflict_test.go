@@ -0,0 +1,138 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictResolver(t *testing.T) {
+	groupTarget := func(name string) *sdk.ScalingPolicyTarget {
+		return &sdk.ScalingPolicyTarget{
+			Name:   name,
+			Config: map[string]string{"Job": "example", "Group": "cache"},
+		}
+	}
+	clusterTarget := &sdk.ScalingPolicyTarget{
+		Name:   "aws-asg",
+		Config: map[string]string{"aws_asg_name": "my-asg", "node_class": "hashistack"},
+	}
+
+	testCases := []struct {
+		name            string
+		strategy        string
+		priority        []SourceName
+		register        map[PolicyID]SourceName
+		targets         map[PolicyID]*sdk.ScalingPolicyTarget
+		deregister      []PolicyID
+		expectedAllowed map[PolicyID]bool
+	}{
+		{
+			name:     "no conflict",
+			strategy: ConflictStrategyOverride,
+			priority: []SourceName{SourceNameNomad, SourceNameFile},
+			register: map[PolicyID]SourceName{"a": SourceNameNomad, "b": SourceNameFile},
+			targets: map[PolicyID]*sdk.ScalingPolicyTarget{
+				"a": groupTarget("nomad-target"),
+				"b": clusterTarget,
+			},
+			expectedAllowed: map[PolicyID]bool{"a": true, "b": true},
+		},
+		{
+			name:     "override uses priority",
+			strategy: ConflictStrategyOverride,
+			priority: []SourceName{SourceNameFile, SourceNameNomad},
+			register: map[PolicyID]SourceName{"a": SourceNameNomad, "b": SourceNameFile},
+			targets: map[PolicyID]*sdk.ScalingPolicyTarget{
+				"a": groupTarget("nomad-target"),
+				"b": groupTarget(""),
+			},
+			expectedAllowed: map[PolicyID]bool{"a": false, "b": true},
+		},
+		{
+			name:     "unlisted sources are lowest priority",
+			strategy: ConflictStrategyOverride,
+			priority: []SourceName{SourceNameNomad},
+			register: map[PolicyID]SourceName{"a": SourceNameS3, "b": SourceNameFile},
+			targets: map[PolicyID]*sdk.ScalingPolicyTarget{
+				"a": clusterTarget,
+				"b": clusterTarget,
+			},
+			expectedAllowed: map[PolicyID]bool{"a": false, "b": true},
+		},
+		{
+			name:     "same source is not a conflict",
+			strategy: ConflictStrategyOverride,
+			priority: []SourceName{SourceNameNomad, SourceNameFile},
+			register: map[PolicyID]SourceName{"a": SourceNameFile, "b": SourceNameFile},
+			targets: map[PolicyID]*sdk.ScalingPolicyTarget{
+				"a": clusterTarget,
+				"b": clusterTarget,
+			},
+			expectedAllowed: map[PolicyID]bool{"a": true, "b": true},
+		},
+		{
+			name:     "none allows all",
+			strategy: ConflictStrategyNone,
+			priority: []SourceName{SourceNameNomad, SourceNameFile},
+			register: map[PolicyID]SourceName{"a": SourceNameNomad, "b": SourceNameFile},
+			targets: map[PolicyID]*sdk.ScalingPolicyTarget{
+				"a": groupTarget("nomad-target"),
+				"b": groupTarget("nomad-target"),
+			},
+			expectedAllowed: map[PolicyID]bool{"a": true, "b": true},
+		},
+		{
+			name:     "unset strategy allows all",
+			priority: []SourceName{SourceNameNomad, SourceNameFile},
+			register: map[PolicyID]SourceName{"a": SourceNameNomad, "b": SourceNameFile},
+			targets: map[PolicyID]*sdk.ScalingPolicyTarget{
+				"a": groupTarget("nomad-target"),
+				"b": groupTarget("nomad-target"),
+			},
+			expectedAllowed: map[PolicyID]bool{"a": true, "b": true},
+		},
+		{
+			name:     "deregister resolves conflict",
+			strategy: ConflictStrategyOverride,
+			priority: []SourceName{SourceNameNomad, SourceNameFile},
+			register: map[PolicyID]SourceName{"a": SourceNameNomad, "b": SourceNameFile},
+			targets: map[PolicyID]*sdk.ScalingPolicyTarget{
+				"a": groupTarget("nomad-target"),
+				"b": groupTarget("nomad-target"),
+			},
+			deregister:      []PolicyID{"a"},
+			expectedAllowed: map[PolicyID]bool{"a": true, "b": true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewConflictResolver(hclog.NewNullLogger(), tc.strategy, tc.priority)
+
+			for id, source := range tc.register {
+				r.Register(id, source, tc.targets[id])
+			}
+			for _, id := range tc.deregister {
+				r.Deregister(id)
+			}
+
+			for id, expected := range tc.expectedAllowed {
+				assert.Equal(t, expected, r.Allowed(id), id)
+			}
+		})
+	}
+}
+
+func TestConflictResolver_nil(t *testing.T) {
+	var r *ConflictResolver
+
+	r.Register("a", SourceNameFile, &sdk.ScalingPolicyTarget{})
+	r.Deregister("a")
+	assert.True(t, r.Allowed("a"))
+}
@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"sync"
+	"time"
+)
+
+// CooldownGroups tracks the cooldown shared by policies which set the same
+// cooldown_group. When any policy within a group scales its target, every
+// policy in the group is placed into cooldown. A nil CooldownGroups is safe
+// to use and never reports a cooldown.
+type CooldownGroups struct {
+	lock sync.RWMutex
+
+	// groups maps a policy to the cooldown group it belongs to.
+	groups map[PolicyID]string
+
+	// until maps a cooldown group to the time its cooldown ends.
+	until map[string]time.Time
+}
+
+// NewCooldownGroups returns a new, empty, CooldownGroups.
+func NewCooldownGroups() *CooldownGroups {
+	return &CooldownGroups{
+		groups: make(map[PolicyID]string),
+		until:  make(map[string]time.Time),
+	}
+}
+
+// Register records the cooldown group of the passed policy, replacing any
+// previous registration for the same policy ID. An empty group removes the
+// policy from any group.
+func (c *CooldownGroups) Register(id PolicyID, group string) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if group == "" {
+		delete(c.groups, id)
+		return
+	}
+	c.groups[id] = group
+}
+
+// Deregister removes the passed policy from its cooldown group. The group
+// cooldown is kept until it expires, so a policy which is re-registered does
+// not bypass it.
+func (c *CooldownGroups) Deregister(id PolicyID) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.groups, id)
+}
+
+// Start places the cooldown group of the passed policy into cooldown for the
+// duration d. An existing cooldown which ends later is not shortened.
+func (c *CooldownGroups) Start(id PolicyID, d time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	group, ok := c.groups[id]
+	if !ok {
+		return
+	}
+
+	if until := time.Now().Add(d); until.After(c.until[group]) {
+		c.until[group] = until
+	}
+}
+
+// Remaining returns the time left on the cooldown of the passed policy's
+// group. A value of zero or below indicates the group is not in cooldown.
+func (c *CooldownGroups) Remaining(id PolicyID) time.Duration {
+	if c == nil {
+		return 0
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	group, ok := c.groups[id]
+	if !ok {
+		return 0
+	}
+
+	until, ok := c.until[group]
+	if !ok {
+		return 0
+	}
+	return time.Until(until)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCooldownGroups(t *testing.T) {
+	c := NewCooldownGroups()
+
+	c.Register("web", "hashistack")
+	c.Register("api", "hashistack")
+	c.Register("batch", "")
+
+	// Starting the cooldown of one policy should apply to its whole group.
+	c.Start("web", time.Minute)
+	assert.Greater(t, c.Remaining("web"), 59*time.Second)
+	assert.Greater(t, c.Remaining("api"), 59*time.Second)
+	assert.Zero(t, c.Remaining("batch"))
+
+	// Policies outside a group do not start a cooldown.
+	c.Start("batch", time.Hour)
+	assert.Zero(t, c.Remaining("batch"))
+
+	// A shorter cooldown should not reduce an existing one.
+	c.Start("api", time.Second)
+	assert.Greater(t, c.Remaining("web"), 59*time.Second)
+
+	// Deregistered policies no longer share the cooldown, but the group keeps
+	// it when they rejoin.
+	c.Deregister("api")
+	assert.Zero(t, c.Remaining("api"))
+	c.Register("api", "hashistack")
+	assert.Greater(t, c.Remaining("api"), 59*time.Second)
+
+	// Moving a policy out of the group should clear its cooldown.
+	c.Register("web", "")
+	assert.Zero(t, c.Remaining("web"))
+
+	// A nil CooldownGroups never reports a cooldown.
+	var nilGroups *CooldownGroups
+	nilGroups.Register("web", "hashistack")
+	nilGroups.Start("web", time.Minute)
+	assert.Zero(t, nilGroups.Remaining("web"))
+}
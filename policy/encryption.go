@@ -0,0 +1,195 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	metrics "github.com/armon/go-metrics"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+const (
+	// ConfigKeyEncryptionType is the policy source config key used to enable
+	// decryption of encrypted policy documents. Supported values are
+	// EncryptionTypeAge and EncryptionTypeAWSKMS.
+	ConfigKeyEncryptionType = "encryption_type"
+
+	// ConfigKeyEncryptionKey is the policy source config key which holds the
+	// path to the age identity file used to decrypt policy documents.
+	ConfigKeyEncryptionKey = "encryption_key"
+
+	// ConfigKeyEncryptionRegion is the policy source config key which
+	// overrides the AWS region of the KMS client. If not set, the region is
+	// taken from the default AWS config.
+	ConfigKeyEncryptionRegion = "encryption_region"
+
+	// EncryptionTypeAge decrypts documents encrypted to one or more age
+	// recipients, in either binary or ASCII armored form.
+	EncryptionTypeAge = "age"
+
+	// EncryptionTypeAWSKMS decrypts documents sealed in a KMSEnvelope, where
+	// the data key is encrypted using AWS KMS.
+	EncryptionTypeAWSKMS = "aws-kms"
+
+	// EncryptionExtensionAge is appended to the name of a policy document
+	// encrypted using age, such as "policy.hcl.age".
+	EncryptionExtensionAge = ".age"
+
+	// EncryptionExtensionKMS is appended to the name of a policy document
+	// sealed in a KMSEnvelope, such as "policy.hcl.enc".
+	EncryptionExtensionKMS = ".enc"
+)
+
+// Decrypter decrypts encrypted policy documents.
+type Decrypter interface {
+
+	// Decrypt returns the plaintext of the encrypted document doc.
+	Decrypt(ctx context.Context, doc []byte) ([]byte, error)
+
+	// Extension returns the file extension which identifies documents
+	// encrypted for this Decrypter.
+	Extension() string
+}
+
+// NewDecrypter builds a Decrypter from the policy source config. A nil
+// Decrypter is returned if decryption is not configured.
+func NewDecrypter(config map[string]string) (Decrypter, error) {
+	encType := config[ConfigKeyEncryptionType]
+
+	switch encType {
+	case "":
+		return nil, nil
+	case EncryptionTypeAge:
+		keyPath := config[ConfigKeyEncryptionKey]
+		if keyPath == "" {
+			return nil, fmt.Errorf("%q config value is required when %q is %q",
+				ConfigKeyEncryptionKey, ConfigKeyEncryptionType, EncryptionTypeAge)
+		}
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key: %v", err)
+		}
+		return newAgeDecrypter(key)
+	case EncryptionTypeAWSKMS:
+		cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load default AWS config: %v", err)
+		}
+		if region, ok := config[ConfigKeyEncryptionRegion]; ok {
+			cfg.Region = region
+		}
+		return &kmsDecrypter{client: kms.NewFromConfig(cfg)}, nil
+	default:
+		return nil, fmt.Errorf("invalid %s %q, must be one of %q or %q",
+			ConfigKeyEncryptionType, encType, EncryptionTypeAge, EncryptionTypeAWSKMS)
+	}
+}
+
+// DecryptPolicy decrypts a policy document read from the named source,
+// emitting metrics so documents which cannot be decrypted can be alerted on.
+// The plaintext is only held in memory and is never written to disk.
+func DecryptPolicy(ctx context.Context, name SourceName, d Decrypter, doc []byte) ([]byte, error) {
+	labels := []metrics.Label{{Name: "policy_source", Value: string(name)}}
+
+	plaintext, err := d.Decrypt(ctx, doc)
+	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"policy", "decryption", "failed_count"}, 1, labels)
+		return nil, fmt.Errorf("failed to decrypt policy: %v", err)
+	}
+
+	metrics.IncrCounterWithLabels([]string{"policy", "decryption", "success_count"}, 1, labels)
+	return plaintext, nil
+}
+
+// ageDecrypter decrypts documents using a set of age identities.
+type ageDecrypter struct {
+	identities []age.Identity
+}
+
+func newAgeDecrypter(key []byte) (*ageDecrypter, error) {
+	identities, err := age.ParseIdentities(bytes.NewReader(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identities: %v", err)
+	}
+	return &ageDecrypter{identities: identities}, nil
+}
+
+func (a *ageDecrypter) Decrypt(_ context.Context, doc []byte) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(doc)
+	if bytes.HasPrefix(bytes.TrimSpace(doc), []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(doc)))
+	}
+
+	r, err := age.Decrypt(src, a.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func (a *ageDecrypter) Extension() string { return EncryptionExtensionAge }
+
+// KMSEnvelope is the JSON document format of policies encrypted using AWS KMS
+// envelope encryption. The policy is encrypted with AES-256-GCM using a data
+// key, such as one returned by the KMS GenerateDataKey API, and the data key
+// is stored encrypted by KMS alongside it. The byte fields are encoded as
+// base64 strings.
+type KMSEnvelope struct {
+	EncryptedKey []byte `json:"encrypted_key"`
+	Nonce        []byte `json:"nonce"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+// kmsClient is the subset of the KMS client used by kmsDecrypter.
+type kmsClient interface {
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// kmsDecrypter decrypts KMSEnvelope documents. The KMS key used is identified
+// by the encrypted data key, so it does not need to be configured.
+type kmsDecrypter struct {
+	client kmsClient
+}
+
+func (k *kmsDecrypter) Decrypt(ctx context.Context, doc []byte) ([]byte, error) {
+	var env KMSEnvelope
+	if err := json.Unmarshal(doc, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode envelope: %v", err)
+	}
+	if len(env.EncryptedKey) == 0 || len(env.Ciphertext) == 0 {
+		return nil, errors.New("envelope is missing the encrypted key or ciphertext")
+	}
+
+	out, err := k.client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: env.EncryptedKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %v", err)
+	}
+
+	block, err := aes.NewCipher(out.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d", len(env.Nonce))
+	}
+	return gcm.Open(nil, env.Nonce, env.Ciphertext, nil)
+}
+
+func (k *kmsDecrypter) Extension() string { return EncryptionExtensionKMS }
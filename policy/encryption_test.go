@@ -0,0 +1,182 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ageEncrypt(t *testing.T, recipient age.Recipient, armored bool) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var dst io.Writer = &buf
+
+	var a io.WriteCloser
+	if armored {
+		a = armor.NewWriter(&buf)
+		dst = a
+	}
+
+	w, err := age.Encrypt(dst, recipient)
+	require.NoError(t, err)
+	_, err = w.Write(testPolicyDoc)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	if a != nil {
+		require.NoError(t, a.Close())
+	}
+	return buf.Bytes()
+}
+
+func TestNewDecrypter(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	keyPath := writeSignatureKey(t, []byte(identity.String()+"\n"))
+
+	testCases := []struct {
+		name        string
+		config      map[string]string
+		expectNil   bool
+		expectedErr string
+	}{
+		{
+			name:      "not configured",
+			config:    map[string]string{},
+			expectNil: true,
+		},
+		{
+			name:        "missing key",
+			config:      map[string]string{"encryption_type": "age"},
+			expectedErr: `"encryption_key" config value is required`,
+		},
+		{
+			name:        "unreadable key",
+			config:      map[string]string{"encryption_type": "age", "encryption_key": filepath.Join(t.TempDir(), "missing")},
+			expectedErr: "failed to read encryption key",
+		},
+		{
+			name:        "invalid age identity",
+			config:      map[string]string{"encryption_type": "age", "encryption_key": writeSignatureKey(t, []byte("not-a-key"))},
+			expectedErr: "failed to parse age identities",
+		},
+		{
+			name:        "invalid type",
+			config:      map[string]string{"encryption_type": "gcp-kms"},
+			expectedErr: `invalid encryption_type "gcp-kms"`,
+		},
+		{
+			name:   "valid age",
+			config: map[string]string{"encryption_type": "age", "encryption_key": keyPath},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewDecrypter(tc.config)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectNil, d == nil)
+		})
+	}
+}
+
+func TestAgeDecrypter(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	d, err := newAgeDecrypter([]byte(identity.String()))
+	require.NoError(t, err)
+	assert.Equal(t, EncryptionExtensionAge, d.Extension())
+
+	ctx := context.Background()
+
+	for _, armored := range []bool{false, true} {
+		plaintext, err := d.Decrypt(ctx, ageEncrypt(t, identity.Recipient(), armored))
+		require.NoError(t, err)
+		assert.Equal(t, testPolicyDoc, plaintext)
+	}
+
+	_, err = DecryptPolicy(ctx, SourceNameFile, d, ageEncrypt(t, other.Recipient(), false))
+	assert.ErrorContains(t, err, "failed to decrypt policy")
+
+	_, err = DecryptPolicy(ctx, SourceNameFile, d, testPolicyDoc)
+	assert.ErrorContains(t, err, "failed to decrypt policy")
+}
+
+// mockKMSClient decrypts data keys by returning them unchanged, failing if
+// the key is not known.
+type mockKMSClient struct {
+	keys map[string][]byte
+}
+
+func (m *mockKMSClient) Decrypt(_ context.Context, params *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	key, ok := m.keys[string(params.CiphertextBlob)]
+	if !ok {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return &kms.DecryptOutput{Plaintext: key}, nil
+}
+
+func TestKMSDecrypter(t *testing.T) {
+	dataKey := make([]byte, 32)
+	_, err := rand.Read(dataKey)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(dataKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+
+	env := KMSEnvelope{
+		EncryptedKey: []byte("encrypted-key"),
+		Nonce:        nonce,
+		Ciphertext:   gcm.Seal(nil, nonce, testPolicyDoc, nil),
+	}
+	doc, err := json.Marshal(env)
+	require.NoError(t, err)
+
+	d := &kmsDecrypter{client: &mockKMSClient{keys: map[string][]byte{"encrypted-key": dataKey}}}
+	assert.Equal(t, EncryptionExtensionKMS, d.Extension())
+
+	plaintext, err := d.Decrypt(context.Background(), doc)
+	require.NoError(t, err)
+	assert.Equal(t, testPolicyDoc, plaintext)
+
+	// Unknown data keys should fail.
+	env.EncryptedKey = []byte("other-key")
+	doc, err = json.Marshal(env)
+	require.NoError(t, err)
+	_, err = d.Decrypt(context.Background(), doc)
+	assert.ErrorContains(t, err, "failed to decrypt data key")
+
+	// Plaintext policies are not valid envelopes.
+	_, err = d.Decrypt(context.Background(), testPolicyDoc)
+	assert.ErrorContains(t, err, "failed to decode envelope")
+}
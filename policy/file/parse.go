@@ -4,34 +4,93 @@
 package file
 
 import (
+	"fmt"
+	"os"
 	"time"
 
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclsimple"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/zclconf/go-cty/cty/function"
 )
 
 func decodeFile(file string) (map[string]*sdk.ScalingPolicy, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(file, src)
+}
+
+// Decode parses the HCL or JSON scaling policy document held in src,
+// returning the contained policies keyed by their name. The filename is used
+// to determine the document format via its suffix, and within diagnostics.
+// This allows policy sources which do not read from the local disk to share
+// the same parsing behaviour as the file source.
+//
+// Documents may contain a single variables block, whose attributes can be
+// referenced as var.<name> along with a small set of functions within the
+// scaling blocks.
+//
+// Documents may declare any number of policies, either as repeated scaling
+// blocks or, in JSON, as an array of scaling objects. Policy names must be
+// unique within a document. If individual policies fail to decode, the
+// returned map still holds the policies which succeeded alongside the error,
+// so a single invalid policy does not prevent the rest of a bundle from being
+// used.
+func Decode(filename string, src []byte) (map[string]*sdk.ScalingPolicy, error) {
+	return decode(filename, src, policyFunctions(filename, nil))
+}
+
+// DecodeUntrusted parses the scaling policy document held in src in the same
+// way as Decode, but without the env(), file() and nomad_var() functions. It
+// must be used for documents supplied by remote clients, which should not be
+// able to read the environment, files or Nomad variables of the agent host.
+func DecodeUntrusted(filename string, src []byte) (map[string]*sdk.ScalingPolicy, error) {
+	return decode(filename, src, untrustedPolicyFunctions())
+}
+
+// decode performs the work of Decode, making funcs available to the
+// expressions within the document.
+func decode(filename string, src []byte, funcs map[string]function.Function) (map[string]*sdk.ScalingPolicy, error) {
 	policies := make(map[string]*sdk.ScalingPolicy)
 
-	filePolicies := sdk.FileDecodeScalingPolicies{}
-	if err := hclsimple.DecodeFile(file, nil, &filePolicies); err != nil {
+	vars := fileDecodeVariables{}
+	if err := hclsimple.Decode(filename, src, nil, &vars); err != nil {
 		return nil, err
 	}
 
+	ctx, diags := policyEvalContext(vars.Variables, funcs)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	filePolicies := sdk.FileDecodeScalingPolicies{}
+	if diags := gohcl.DecodeBody(vars.Remain, ctx, &filePolicies); diags.HasErrors() {
+		return nil, diags
+	}
+
 	var mErr *multierror.Error
+	seen := make(map[string]bool, len(filePolicies.ScalingPolicies))
 	for _, p := range filePolicies.ScalingPolicies {
+		if seen[p.Name] {
+			mErr = multierror.Append(mErr, fmt.Errorf("duplicate policy name %q", p.Name))
+			continue
+		}
+		seen[p.Name] = true
+		if p.Doc == nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("policy %q: missing policy block", p.Name))
+			continue
+		}
 		if err := decodePolicyDoc(p); err != nil {
-			mErr = multierror.Append(mErr, multierror.Prefix(err, p.Name))
+			mErr = multierror.Append(mErr, fmt.Errorf("policy %q: %v", p.Name, err))
+			continue
 		}
 		policies[p.Name] = p.Translate()
 	}
-	if mErr != nil {
-		return nil, mErr.ErrorOrNil()
-	}
-
-	return policies, nil
 
+	return policies, mErr.ErrorOrNil()
 }
 
 func decodePolicyDoc(decodePolicy *sdk.FileDecodeScalingPolicy) error {
@@ -57,20 +116,41 @@ func decodePolicyDoc(decodePolicy *sdk.FileDecodeScalingPolicy) error {
 		decodePolicy.Doc.EvaluationInterval = d
 	}
 
-	// Parse query window for each check.
+	// Parse the duration of each schedule window.
+	if s := decodePolicy.Doc.EnabledSchedule; s != nil {
+		for _, w := range append(s.ActiveWindows, s.InactiveWindows...) {
+			d, err := time.ParseDuration(w.DurationHCL)
+			if err != nil {
+				return err
+			}
+			w.Duration = d
+		}
+	}
+
+	// Parse the query durations for each check, skipping those not set.
 	for i := 0; i < len(decodePolicy.Doc.Checks); i++ {
 		check := decodePolicy.Doc.Checks[i]
 
-		// Skip parsing if query_window not set.
-		if check.QueryWindowHCL == "" {
-			continue
-		}
+		for _, d := range []struct {
+			hcl string
+			out *time.Duration
+		}{
+			{check.QueryWindowHCL, &check.QueryWindow},
+			{check.QueryTimeoutHCL, &check.QueryTimeout},
+			{check.QueryRetryBackoffHCL, &check.QueryRetryBackoff},
+			{check.QueryRetryMaxBackoffHCL, &check.QueryRetryMaxBackoff},
+			{check.MissingDataMaxStaleHCL, &check.MissingDataMaxStaleness},
+		} {
+			if d.hcl == "" {
+				continue
+			}
 
-		w, err := time.ParseDuration(check.QueryWindowHCL)
-		if err != nil {
-			return err
+			w, err := time.ParseDuration(d.hcl)
+			if err != nil {
+				return err
+			}
+			*d.out = w
 		}
-		decodePolicy.Doc.Checks[i].QueryWindow = w
 	}
 
 	return nil
@@ -9,6 +9,7 @@ import (
 
 	"github.com/hashicorp/nomad-autoscaler/sdk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_decodeFile(t *testing.T) {
@@ -24,12 +25,24 @@ func Test_decodeFile(t *testing.T) {
 				"full-cluster-policy": {
 					ID:                 "",
 					Type:               sdk.ScalingPolicyTypeCluster,
+					Name:               "full-cluster-policy",
 					Enabled:            true,
 					Min:                10,
 					Max:                100,
 					Cooldown:           10 * time.Minute,
+					CooldownGroup:      "hashistack",
 					EvaluationInterval: 1 * time.Minute,
 					OnCheckError:       "error",
+					DryRun:             true,
+					EnabledSchedule: &sdk.ScalingPolicySchedule{
+						Timezone: "Europe/London",
+						ActiveWindows: []*sdk.ScalingPolicyScheduleWindow{
+							{Start: "0 9 * * 1-5", Duration: 8 * time.Hour},
+						},
+						InactiveWindows: []*sdk.ScalingPolicyScheduleWindow{
+							{Start: "0 12 * * 3", Duration: time.Hour},
+						},
+					},
 					Checks: []*sdk.ScalingPolicyCheck{
 						{
 							Name:        "cpu_nomad",
@@ -45,10 +58,16 @@ func Test_decodeFile(t *testing.T) {
 							},
 						},
 						{
-							Name:    "memory_prom",
-							OnError: "ignore",
-							Source:  "prometheus",
-							Query:   "nomad_client_allocated_memory*100/(nomad_client_allocated_memory+nomad_client_unallocated_memory)",
+							Name:              "memory_prom",
+							OnError:           "ignore",
+							Source:            "prometheus",
+							Query:             "nomad_client_allocated_memory*100/(nomad_client_allocated_memory+nomad_client_unallocated_memory)",
+							QueryTimeout:      5 * time.Second,
+							QueryRetries:      2,
+							QueryRetryBackoff: 500 * time.Millisecond,
+							Fallbacks: []*sdk.ScalingPolicyCheckFallback{
+								{Source: "aws-cloudwatch", Query: "AWS/EC2/MemoryUtilization"},
+							},
 							Strategy: &sdk.ScalingPolicyStrategy{
 								Name: "target-value",
 								Config: map[string]string{
@@ -76,6 +95,9 @@ func Test_decodeFile(t *testing.T) {
 				"full-task-group-policy": {
 					ID:                 "",
 					Type:               sdk.ScalingPolicyTypeHorizontal,
+					Name:               "full-task-group-policy",
+					DependsOn:          []string{"full-cluster-policy"},
+					Labels:             map[string]string{"team": "payments"},
 					Enabled:            true,
 					Min:                1,
 					Max:                10,
@@ -117,8 +139,46 @@ func Test_decodeFile(t *testing.T) {
 			expectedOutputError: nil,
 			name:                "full parsable task group scaling policy",
 		},
+		{
+			inputFile: "./test-fixtures/templated-cluster-policy.hcl",
+			expectedOutputPolicies: map[string]*sdk.ScalingPolicy{
+				"templated-cluster-policy": {
+					ID:       "",
+					Type:     sdk.ScalingPolicyTypeCluster,
+					Name:     "templated-cluster-policy",
+					Enabled:  true,
+					Min:      4,
+					Max:      12,
+					Cooldown: 4 * time.Minute,
+					Checks: []*sdk.ScalingPolicyCheck{
+						{
+							Name:   "cpu_nomad",
+							Source: "nomad_apm",
+							Query:  "cpu_high-memory",
+							Strategy: &sdk.ScalingPolicyStrategy{
+								Name: "target-value",
+								Config: map[string]string{
+									"target": "80",
+								},
+							},
+						},
+					},
+					Target: &sdk.ScalingPolicyTarget{
+						Name: "aws-asg",
+						Config: map[string]string{
+							"aws_asg_name": "prod-asg",
+							"node_class":   "high-memory",
+						},
+					},
+				},
+			},
+			expectedOutputError: nil,
+			name:                "templated cluster scaling policy",
+		},
 	}
 
+	t.Setenv("NOMAD_AUTOSCALER_TEST_ASG_PREFIX", "prod")
+
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			got, actualError := decodeFile(tc.inputFile)
@@ -132,3 +192,167 @@ func Test_decodeFile(t *testing.T) {
 		})
 	}
 }
+
+func TestDecode_multiplePolicies(t *testing.T) {
+	testCases := []struct {
+		name             string
+		filename         string
+		src              string
+		expectedPolicies []string
+		expectedErrors   []string
+	}{
+		{
+			name:     "hcl blocks",
+			filename: "policies.hcl",
+			src: `
+scaling "web" {
+  min = 1
+  max = 5
+  policy {}
+}
+
+scaling "api" {
+  min = 2
+  max = 10
+  policy {}
+}`,
+			expectedPolicies: []string{"api", "web"},
+		},
+		{
+			name:     "json array",
+			filename: "policies.json",
+			src: `{
+  "scaling": [
+    {"web": {"min": 1, "max": 5, "policy": {}}},
+    {"api": {"min": 2, "max": 10, "policy": {}}}
+  ]
+}`,
+			expectedPolicies: []string{"api", "web"},
+		},
+		{
+			name:     "duplicate name",
+			filename: "policies.hcl",
+			src: `
+scaling "web" {
+  min = 1
+  max = 5
+  policy {}
+}
+
+scaling "web" {
+  min = 2
+  max = 10
+  policy {}
+}`,
+			expectedPolicies: []string{"web"},
+			expectedErrors:   []string{`duplicate policy name "web"`},
+		},
+		{
+			name:     "invalid policy",
+			filename: "policies.hcl",
+			src: `
+scaling "web" {
+  min = 1
+  max = 5
+  policy {
+    cooldown = "not-a-duration"
+  }
+}
+
+scaling "api" {
+  min = 2
+  max = 10
+  policy {}
+}`,
+			expectedPolicies: []string{"api"},
+			expectedErrors:   []string{`policy "web": time: invalid duration`},
+		},
+		{
+			name:     "missing policy block",
+			filename: "policies.hcl",
+			src: `
+scaling "web" {
+  min = 1
+  max = 5
+}
+
+scaling "api" {
+  min = 2
+  max = 10
+  policy {}
+}`,
+			expectedPolicies: []string{"api"},
+			expectedErrors:   []string{`policy "web": missing policy block`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policies, err := Decode(tc.filename, []byte(tc.src))
+
+			var names []string
+			for name := range policies {
+				names = append(names, name)
+			}
+			assert.ElementsMatch(t, tc.expectedPolicies, names)
+
+			if len(tc.expectedErrors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, e := range tc.expectedErrors {
+				assert.ErrorContains(t, err, e)
+			}
+		})
+	}
+}
+
+func TestDecode_subTargets(t *testing.T) {
+	src := `
+scaling "cluster" {
+  min = 2
+  max = 20
+
+  policy {
+    target "aws-asg" {
+      node_drain_deadline = "5m"
+    }
+
+    sub_target "general" {
+      min          = 1
+      max          = 10
+      weight       = 2
+      aws_asg_name = "general"
+      node_class   = "general"
+    }
+
+    sub_target "compute" {
+      aws_asg_name = "compute"
+      node_class   = "compute"
+    }
+  }
+}`
+
+	policies, err := Decode("policies.hcl", []byte(src))
+	require.NoError(t, err)
+	require.Contains(t, policies, "cluster")
+
+	expected := &sdk.ScalingPolicyTarget{
+		Name:   "aws-asg",
+		Config: map[string]string{"node_drain_deadline": "5m"},
+		SubTargets: []*sdk.ScalingPolicySubTarget{
+			{
+				Name:   "general",
+				Min:    1,
+				Max:    10,
+				Weight: 2,
+				Config: map[string]string{"aws_asg_name": "general", "node_class": "general"},
+			},
+			{
+				Name:   "compute",
+				Config: map[string]string{"aws_asg_name": "compute", "node_class": "compute"},
+			},
+		},
+	}
+	assert.Equal(t, expected, policies["cluster"].Target)
+}
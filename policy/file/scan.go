@@ -0,0 +1,193 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package file
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	fileHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/file"
+)
+
+const (
+	// configKeys are the keys which can be set within the file policy source
+	// config block to control how the policy directory is scanned.
+	configKeyRecursive    = "recursive"
+	configKeyInclude      = "include"
+	configKeyExclude      = "exclude"
+	configKeyScanInterval = "scan_interval"
+)
+
+// scanOptions control how the policy directory is scanned for policy files.
+type scanOptions struct {
+
+	// recursive enables scanning nested directories, following symlinks.
+	recursive bool
+
+	// include and exclude are glob patterns matched against the file path
+	// relative to the policy directory, or the file name if the pattern does
+	// not contain a separator. Files must match an include pattern, if any
+	// are set, and must not match any exclude pattern.
+	include []string
+	exclude []string
+
+	// interval is the period at which the directory is re-scanned to detect
+	// changes. A value of zero only scans on start and reload.
+	interval time.Duration
+}
+
+// newScanOptions builds scanOptions from the policy source config. The
+// include and exclude values are comma separated lists of patterns.
+func newScanOptions(cfg map[string]string) (*scanOptions, error) {
+	opts := &scanOptions{}
+
+	if v := cfg[configKeyRecursive]; v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", configKeyRecursive, err)
+		}
+		opts.recursive = b
+	}
+
+	for key, dst := range map[string]*[]string{configKeyInclude: &opts.include, configKeyExclude: &opts.exclude} {
+		for _, p := range strings.Split(cfg[key], ",") {
+			if p = strings.TrimSpace(p); p == "" {
+				continue
+			}
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("invalid %s pattern %q: %v", key, p, err)
+			}
+			*dst = append(*dst, p)
+		}
+	}
+
+	if v := cfg[configKeyScanInterval]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", configKeyScanInterval, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("%q must not be negative", configKeyScanInterval)
+		}
+		opts.interval = d
+	}
+
+	return opts, nil
+}
+
+// listFiles returns the sorted list of policy files within dir which match
+// the scan options. If encryptedExt is not empty, policy files which have it
+// appended to their name are also returned. Symlinks are followed so trees that are swapped
+// atomically, such as Kubernetes ConfigMap volumes, are always read through
+// their current target. Hidden directories are skipped, which avoids reading
+// the timestamped data directories of those trees twice.
+func (o *scanOptions) listFiles(dir, encryptedExt string) ([]string, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("configuration path must be a directory: %s", dir)
+	}
+
+	var files []string
+	visited := make(map[string]bool)
+
+	var walk func(current string) error
+	walk = func(current string) error {
+
+		// Track the resolved directory so symlink loops are only walked
+		// once.
+		real, err := filepath.EvalSymlinks(current)
+		if err != nil {
+			return err
+		}
+		if visited[real] {
+			return nil
+		}
+		visited[real] = true
+
+		entries, err := os.ReadDir(current)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			name := e.Name()
+			full := filepath.Join(current, name)
+
+			// Use the symlink target to decide whether the entry is a file
+			// or a directory. Broken links are skipped.
+			info, err := os.Stat(full)
+			if err != nil {
+				continue
+			}
+
+			if info.IsDir() {
+				if o.recursive && !strings.HasPrefix(name, ".") {
+					if err := walk(full); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			policyName := name
+			if encryptedExt != "" {
+				policyName = strings.TrimSuffix(name, encryptedExt)
+			}
+			if !hasPolicySuffix(policyName) || fileHelper.IsTemporaryFile(name) {
+				continue
+			}
+
+			rel, err := filepath.Rel(dir, full)
+			if err != nil {
+				return err
+			}
+			if o.matches(filepath.ToSlash(rel)) {
+				files = append(files, full)
+			}
+		}
+		return nil
+	}
+
+	if err := walk(dir); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// matches returns whether the file, identified by its slash separated path
+// relative to the policy directory, passes the include and exclude patterns.
+func (o *scanOptions) matches(rel string) bool {
+	if len(o.include) > 0 && !matchAny(o.include, rel) {
+		return false
+	}
+	return !matchAny(o.exclude, rel)
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		target := rel
+		if !strings.Contains(p, "/") {
+			target = path.Base(rel)
+		}
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPolicySuffix(name string) bool {
+	return strings.HasSuffix(name, ".hcl") || strings.HasSuffix(name, ".json")
+}
@@ -0,0 +1,169 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newScanOptions(t *testing.T) {
+	testCases := []struct {
+		name         string
+		cfg          map[string]string
+		expectedOpts *scanOptions
+		expectedErr  string
+	}{
+		{
+			name:         "empty",
+			cfg:          map[string]string{},
+			expectedOpts: &scanOptions{},
+		},
+		{
+			name: "all options",
+			cfg: map[string]string{
+				"recursive":     "true",
+				"include":       "prod/*.hcl, *.json",
+				"exclude":       "*-draft.hcl",
+				"scan_interval": "30s",
+			},
+			expectedOpts: &scanOptions{
+				recursive: true,
+				include:   []string{"prod/*.hcl", "*.json"},
+				exclude:   []string{"*-draft.hcl"},
+				interval:  30 * time.Second,
+			},
+		},
+		{
+			name:        "invalid recursive",
+			cfg:         map[string]string{"recursive": "sometimes"},
+			expectedErr: `failed to parse "recursive"`,
+		},
+		{
+			name:        "invalid pattern",
+			cfg:         map[string]string{"exclude": "[a-"},
+			expectedErr: `invalid exclude pattern "[a-"`,
+		},
+		{
+			name:        "negative interval",
+			cfg:         map[string]string{"scan_interval": "-1s"},
+			expectedErr: `"scan_interval" must not be negative`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := newScanOptions(tc.cfg)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOpts, opts)
+		})
+	}
+}
+
+func Test_scanOptions_listFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(rel string) {
+		p := filepath.Join(dir, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte{}, 0o644))
+	}
+	write("top.hcl")
+	write("README.md")
+	write("top.hcl~")
+	write("secret.hcl.age")
+	write("prod/web.hcl")
+	write("prod/web-draft.hcl")
+	write("prod/nested/queue.json")
+
+	// Mimic the layout of a Kubernetes ConfigMap volume, where the visible
+	// files link through a hidden symlink to a timestamped directory.
+	write("configmap/..2024_01_01/cm.hcl")
+	require.NoError(t, os.Symlink("..2024_01_01", filepath.Join(dir, "configmap", "..data")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "cm.hcl"), filepath.Join(dir, "configmap", "cm.hcl")))
+
+	// A symlink to a parent directory must not cause an endless walk.
+	require.NoError(t, os.Symlink(dir, filepath.Join(dir, "prod", "loop")))
+
+	testCases := []struct {
+		name         string
+		opts         *scanOptions
+		encryptedExt string
+		expected     []string
+	}{
+		{
+			name:     "not recursive",
+			opts:     &scanOptions{},
+			expected: []string{"top.hcl"},
+		},
+		{
+			name:         "encrypted",
+			opts:         &scanOptions{},
+			encryptedExt: ".age",
+			expected:     []string{"secret.hcl.age", "top.hcl"},
+		},
+		{
+			name: "recursive",
+			opts: &scanOptions{recursive: true},
+			expected: []string{
+				"configmap/cm.hcl",
+				"prod/nested/queue.json",
+				"prod/web-draft.hcl",
+				"prod/web.hcl",
+				"top.hcl",
+			},
+		},
+		{
+			name: "include and exclude",
+			opts: &scanOptions{
+				recursive: true,
+				include:   []string{"prod/*", "*.json"},
+				exclude:   []string{"*-draft.hcl"},
+			},
+			expected: []string{
+				"prod/nested/queue.json",
+				"prod/web.hcl",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			files, err := tc.opts.listFiles(dir, tc.encryptedExt)
+			require.NoError(t, err)
+
+			rel := make([]string, 0, len(files))
+			for _, f := range files {
+				r, err := filepath.Rel(dir, f)
+				require.NoError(t, err)
+				rel = append(rel, filepath.ToSlash(r))
+			}
+			assert.Equal(t, tc.expected, rel)
+		})
+	}
+
+	// Swapping the ConfigMap data directory should be picked up through the
+	// unchanged visible path.
+	write("configmap/..2024_01_02/cm.hcl")
+	require.NoError(t, os.Symlink("..2024_01_02", filepath.Join(dir, "configmap", "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "configmap", "..data_tmp"), filepath.Join(dir, "configmap", "..data")))
+
+	target, err := filepath.EvalSymlinks(filepath.Join(dir, "configmap", "cm.hcl"))
+	require.NoError(t, err)
+	assert.Contains(t, target, "..2024_01_02")
+
+	files, err := (&scanOptions{recursive: true, include: []string{"configmap/*"}}).listFiles(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "configmap", "cm.hcl")}, files)
+}
@@ -6,21 +6,34 @@ package file
 import (
 	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"reflect"
+	"strings"
 	"sync"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/nomad-autoscaler/policy"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
-	fileHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/file"
 	"github.com/hashicorp/nomad-autoscaler/sdk/helper/uuid"
+	"github.com/hashicorp/nomad/api"
 )
 
 // Ensure NomadSource satisfies the Source interface.
 var _ policy.Source = (*Source)(nil)
 
+// nomadVariableReadTimeout is the maximum time to wait when reading a Nomad
+// variable referenced by a policy file.
+const nomadVariableReadTimeout = 10 * time.Second
+
+// decryptTimeout is the maximum time to wait when decrypting an encrypted
+// policy file, which may call out to a remote key management service.
+const decryptTimeout = 10 * time.Second
+
 // pathMD5Sum is the key used in the idMap. Having this as a type makes it
 // clearer to readers what this represents.
 type pathMD5Sum [16]byte
@@ -31,6 +44,22 @@ type Source struct {
 	log             hclog.Logger
 	policyProcessor *policy.Processor
 
+	// scan controls how the policy directory is scanned for policy files.
+	scan *scanOptions
+
+	// verifier, if set, is used to check the detached signature of each
+	// policy file before it is decoded.
+	verifier policy.SignatureVerifier
+
+	// decrypter, if set, is used to decrypt policy files which have its
+	// extension appended to their name.
+	decrypter policy.Decrypter
+
+	// nomad is used to resolve nomad_var() calls within policy files. It is
+	// nil if the source has not been given a Nomad client.
+	nomad     *api.Client
+	nomadLock sync.RWMutex
+
 	// idMap stores a mapping between between the md5sum of the file path and
 	// the associated policyID. This allows us to keep a consistent PolicyID in
 	// the event of policy changes.
@@ -61,6 +90,7 @@ func NewFileSource(log hclog.Logger, dir string, policyProcessor *policy.Process
 	return &Source{
 		dir:              dir,
 		log:              log.ResetNamed("file_policy_source"),
+		scan:             &scanOptions{},
 		idMap:            make(map[pathMD5Sum]policy.PolicyID),
 		policyMap:        make(map[policy.PolicyID]*filePolicy),
 		reloadCh:         make(chan struct{}),
@@ -69,6 +99,47 @@ func NewFileSource(log hclog.Logger, dir string, policyProcessor *policy.Process
 	}
 }
 
+// SetScanConfig configures how the policy directory is scanned using the
+// policy source config. The "recursive" key enables scanning nested
+// directories, "include" and "exclude" accept comma separated glob patterns,
+// and "scan_interval" enables periodically re-scanning the directory. It must
+// be called before the source is started.
+func (s *Source) SetScanConfig(cfg map[string]string) error {
+	opts, err := newScanOptions(cfg)
+	if err != nil {
+		return err
+	}
+	s.scan = opts
+	return nil
+}
+
+// SetSignatureVerifier configures the source to reject policy files which do
+// not have a valid detached signature, stored alongside the file with the
+// policy.SignatureExtension suffix. It must be called before the source is
+// started.
+func (s *Source) SetSignatureVerifier(v policy.SignatureVerifier) {
+	s.verifier = v
+}
+
+// SetDecrypter configures the source to read encrypted policy files, which
+// are identified by the extension of the Decrypter appended to the name of
+// the policy file, such as "policy.hcl.age". Encrypted files are decrypted in
+// memory and plaintext policy files continue to be read. It must be called
+// before the source is started.
+func (s *Source) SetDecrypter(d policy.Decrypter) {
+	s.decrypter = d
+}
+
+// SetNomadClient sets the Nomad client used to read the Nomad variables
+// referenced by nomad_var() calls within policy files. Policies that use the
+// function are re-evaluated each scan interval, so "scan_interval" should be
+// configured for them to track changes to the variables.
+func (s *Source) SetNomadClient(nomad *api.Client) {
+	s.nomadLock.Lock()
+	defer s.nomadLock.Unlock()
+	s.nomad = nomad
+}
+
 // Name satisfies the Name function of the policy.Source interface.
 func (s *Source) Name() policy.SourceName {
 	return policy.SourceNameFile
@@ -81,7 +152,10 @@ func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
 	// Run the policyID identification method before entering the loop so we do
 	// a first pass on the policies. Otherwise we wouldn't load any until a
 	// reload is triggered.
-	s.identifyPolicyIDs(req.ResultCh, req.ErrCh)
+	s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
+
+	scanCh, stop := s.scanTicker()
+	defer stop()
 
 	for {
 		select {
@@ -89,9 +163,12 @@ func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
 			s.log.Trace("stopping file policy source ID monitor")
 			return
 
+		case <-scanCh:
+			s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
+
 		case <-s.reloadCh:
 			s.log.Info("file policy source ID monitor received reload signal")
-			s.identifyPolicyIDs(req.ResultCh, req.ErrCh)
+			s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
 			s.reloadCompleteCh <- struct{}{}
 		}
 	}
@@ -131,7 +208,7 @@ func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq)
 		file = val.file
 		name = val.name
 
-		p, err := s.handleIndividualPolicyRead(req.ID, file, name)
+		p, err := s.handleIndividualPolicyRead(ctx, req.ID, file, name)
 		if err != nil {
 			policy.HandleSourceError(s.Name(), fmt.Errorf("failed to get policy %s", req.ID), req.ErrCh)
 		}
@@ -148,36 +225,57 @@ func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq)
 	log := s.log.With("policy_id", req.ID, "file", file, "name", name)
 	log.Info("starting file policy monitor")
 
+	scanCh, stop := s.scanTicker()
+	defer stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Debug("stopping file source ID monitor due to context done")
 			return
 
+		case <-scanCh:
 		case <-req.ReloadCh:
 			log.Info("file policy source monitor received reload signal")
+		}
 
-			// Grab a lock as required by the function and the call.
-			s.policyMapLock.Lock()
-			newPolicy, err := s.handleIndividualPolicyRead(req.ID, file, name)
-			s.policyMapLock.Unlock()
+		// Grab a lock as required by the function and the call.
+		s.policyMapLock.Lock()
+		newPolicy, err := s.handleIndividualPolicyRead(ctx, req.ID, file, name)
 
-			// An error indicates the policy failed to be decoded properly. It
-			// isn't a terminal error as the operator can fix the policy and
-			// trigger another reload.
-			if err != nil {
-				policy.HandleSourceError(s.Name(), fmt.Errorf("failed to get policy: %v", err), req.ErrCh)
-				continue
-			}
+		// Store the new policy so that subsequent scans only report further
+		// changes.
+		if err == nil && newPolicy != nil {
+			s.policyMap[req.ID] = &filePolicy{file: file, name: name, policy: newPolicy}
+		}
+		s.policyMapLock.Unlock()
 
-			// A non-nil policy indicates a change, therefore we send this to
-			// the handler.
-			if newPolicy != nil {
-				s.log.Info("file policy content has changed")
-				req.ResultCh <- *newPolicy
-			}
+		// An error indicates the policy failed to be decoded properly. It
+		// isn't a terminal error as the operator can fix the policy and
+		// trigger another reload.
+		if err != nil {
+			policy.HandleSourceError(s.Name(), fmt.Errorf("failed to get policy: %v", err), req.ErrCh)
+			continue
 		}
+
+		// A non-nil policy indicates a change, therefore we send this to
+		// the handler.
+		if newPolicy != nil {
+			log.Info("file policy content has changed")
+			req.ResultCh <- *newPolicy
+		}
+	}
+}
+
+// scanTicker returns a channel which fires each time the policy directory
+// should be re-scanned, along with a function to stop it. The channel never
+// fires if periodic scanning is disabled.
+func (s *Source) scanTicker() (<-chan time.Time, func()) {
+	if s.scan.interval <= 0 {
+		return nil, func() {}
 	}
+	t := time.NewTicker(s.scan.interval)
+	return t.C, t.Stop
 }
 
 // handleIndividualPolicyRead reads the policy from disk and compares it to the
@@ -185,19 +283,22 @@ func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq)
 // be returned, otherwise we return nil to indicate no reload is required. This
 // function is not thread safe, so the caller should obtain at least a read
 // lock on policyMapLock.
-func (s *Source) handleIndividualPolicyRead(ID policy.PolicyID, path, name string) (*sdk.ScalingPolicy, error) {
+func (s *Source) handleIndividualPolicyRead(ctx context.Context, ID policy.PolicyID, path, name string) (*sdk.ScalingPolicy, error) {
 
 	// Decode the file into a new policy to allow comparison to our stored
 	// policy. Make sure to add the ID string and defaults, we are responsible
 	// for managing this and if we don't add it, there will always be a
 	// difference.
-	policies, err := decodeFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode file %s: %v", path, err)
-	}
+	//
+	// Errors decoding other policies within the same file are ignored, so
+	// that a single invalid policy does not affect the rest of the file.
+	policies, err := s.decodeFile(ctx, path)
 
 	newPolicy, ok := policies[name]
 	if !ok {
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode file %s: %v", path, err)
+		}
 		return nil, fmt.Errorf("policy %q doesn't exist in file %s", name, path)
 	}
 
@@ -228,8 +329,8 @@ func (s *Source) handleIndividualPolicyRead(ID policy.PolicyID, path, name strin
 // identifyPolicyIDs iterates the configured directory, identifying the
 // configured policyIDs. The IDs will be wrapped and sent to the resultCh so
 // the policy manager can do its work.
-func (s *Source) identifyPolicyIDs(resultCh chan<- policy.IDMessage, errCh chan<- error) {
-	ids, err := s.handleDir()
+func (s *Source) identifyPolicyIDs(ctx context.Context, resultCh chan<- policy.IDMessage, errCh chan<- error) {
+	ids, err := s.handleDir(ctx)
 	if err != nil {
 		policy.HandleSourceError(s.Name(), err, errCh)
 	}
@@ -243,11 +344,16 @@ func (s *Source) identifyPolicyIDs(resultCh chan<- policy.IDMessage, errCh chan<
 // handleDir iterates through the configured directory, attempting to decode
 // and store all HCL and JSON files as scaling policies. If the policy is not
 // enabled it will be ignored.
-func (s *Source) handleDir() ([]policy.PolicyID, error) {
+func (s *Source) handleDir(ctx context.Context) ([]policy.PolicyID, error) {
 
 	// Obtain a list of all files in the directory which have the suffixes we
 	// can handle as scaling policies.
-	files, err := fileHelper.GetFileListFromDir(s.dir, ".hcl", ".json")
+	var encryptedExt string
+	if s.decrypter != nil {
+		encryptedExt = s.decrypter.Extension()
+	}
+
+	files, err := s.scan.listFiles(s.dir, encryptedExt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files in directory: %v", err)
 	}
@@ -261,11 +367,11 @@ func (s *Source) handleDir() ([]policy.PolicyID, error) {
 		// whether they are enabled or not.
 		// If we cannot decode the file, append an error but do not bail on
 		// the process. A single decode failure shouldn't stop us decoding the
-		// rest of the files in the directory.
-		policies, err := decodeFile(file)
+		// rest of the files in the directory, or the rest of the policies
+		// within the file which did decode.
+		policies, err := s.decodeFile(ctx, file)
 		if err != nil {
 			mErr = multierror.Append(fmt.Errorf("failed to decode file %s: %v", file, err), mErr)
-			continue
 		}
 
 		for name, scalingPolicy := range policies {
@@ -316,6 +422,69 @@ func (s *Source) handleDir() ([]policy.PolicyID, error) {
 	return policyIDs, mErr.ErrorOrNil()
 }
 
+// decodeFile reads and decodes the policy file, verifying its signature
+// first if signature verification is configured. Signatures are verified
+// against the file as stored, so encrypted files are signed after they are
+// encrypted.
+func (s *Source) decodeFile(ctx context.Context, file string) (map[string]*sdk.ScalingPolicy, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.verifier != nil {
+		sig, err := os.ReadFile(file + policy.SignatureExtension)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read signature: %v", err)
+		}
+
+		if err := policy.VerifyPolicySignature(s.Name(), s.verifier, src, sig); err != nil {
+			return nil, err
+		}
+	}
+
+	// Decrypt the file if it is encrypted, using the name without the
+	// encryption extension to determine the document format.
+	filename := file
+	if s.decrypter != nil && strings.HasSuffix(file, s.decrypter.Extension()) {
+		decryptCtx, cancel := context.WithTimeout(ctx, decryptTimeout)
+		src, err = policy.DecryptPolicy(decryptCtx, s.Name(), s.decrypter, src)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		filename = strings.TrimSuffix(file, s.decrypter.Extension())
+	}
+	return decode(filename, src, policyFunctions(filename, s.readNomadVariable))
+}
+
+// readNomadVariable satisfies the variableLookup function type, reading the
+// item from the Nomad variable at path within the default namespace of the
+// Nomad client.
+func (s *Source) readNomadVariable(path, item string) (string, error) {
+	s.nomadLock.RLock()
+	nomad := s.nomad
+	s.nomadLock.RUnlock()
+
+	if nomad == nil {
+		return "", errors.New("nomad_var requires the file policy source to have a Nomad client")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), nomadVariableReadTimeout)
+	defer cancel()
+
+	v, _, err := nomad.Variables().Read(path, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to read Nomad variable %q: %v", path, err)
+	}
+
+	val, ok := v.Items[item]
+	if !ok {
+		return "", fmt.Errorf("item %q not found in Nomad variable %q", item, path)
+	}
+	return val, nil
+}
+
 // getFilePolicyID translates the file into its policyID. This is done by
 // firstly checking our internal state. If it isn't found, we generate and
 // store the ID in our state.
@@ -4,10 +4,17 @@
 package file
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"filippo.io/age"
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad-autoscaler/policy"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSource_getFilePolicyID(t *testing.T) {
@@ -51,3 +58,81 @@ func TestSource_getFilePolicyID(t *testing.T) {
 		})
 	}
 }
+
+func TestSource_decodeFile_encrypted(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key.txt")
+	require.NoError(t, os.WriteFile(keyPath, []byte(identity.String()), 0o600))
+
+	decrypter, err := policy.NewDecrypter(map[string]string{
+		policy.ConfigKeyEncryptionType: policy.EncryptionTypeAge,
+		policy.ConfigKeyEncryptionKey:  keyPath,
+	})
+	require.NoError(t, err)
+
+	// Encrypt a JSON policy, so the format must be detected from the name
+	// without the encryption extension.
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, identity.Recipient())
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`{"scaling": {"web": {"min": 1, "max": 5, "policy": {}}}}`))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "web.json.age"), buf.Bytes(), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api.hcl"), []byte("scaling \"api\" {\n  max = 3\n  policy {}\n}"), 0o600))
+
+	s := NewFileSource(hclog.NewNullLogger(), dir, nil).(*Source)
+
+	// Without a decrypter the encrypted file is not read.
+	files, err := s.scan.listFiles(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "api.hcl")}, files)
+
+	s.SetDecrypter(decrypter)
+	files, err = s.scan.listFiles(dir, decrypter.Extension())
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "api.hcl"), filepath.Join(dir, "web.json.age")}, files)
+
+	policies, err := s.decodeFile(context.Background(), filepath.Join(dir, "web.json.age"))
+	require.NoError(t, err)
+	require.Contains(t, policies, "web")
+	assert.Equal(t, int64(5), policies["web"].Max)
+
+	policies, err = s.decodeFile(context.Background(), filepath.Join(dir, "api.hcl"))
+	require.NoError(t, err)
+	assert.Contains(t, policies, "api")
+}
+
+func TestSource_decodeFile_decryptTimeout(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "web.hcl.test"), []byte("encrypted"), 0o600))
+
+	d := &testBlockingDecrypter{}
+	s := NewFileSource(hclog.NewNullLogger(), dir, nil).(*Source)
+	s.SetDecrypter(d)
+
+	// Decryption must be bounded, and stop when the source is stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.decodeFile(ctx, filepath.Join(dir, "web.hcl.test"))
+	assert.ErrorContains(t, err, context.Canceled.Error())
+	assert.True(t, d.hadDeadline)
+}
+
+// testBlockingDecrypter is a policy.Decrypter which blocks until its context
+// is done, such as a hung remote key management service.
+type testBlockingDecrypter struct {
+	hadDeadline bool
+}
+
+func (d *testBlockingDecrypter) Decrypt(ctx context.Context, _ []byte) ([]byte, error) {
+	_, d.hadDeadline = ctx.Deadline()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (d *testBlockingDecrypter) Extension() string { return ".test" }
@@ -0,0 +1,153 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// fileDecodeVariables is used as the first decode step of a policy file. It
+// extracts the optional variables block, leaving the remaining body to be
+// decoded once the variables are known.
+type fileDecodeVariables struct {
+	Variables *fileDecodeVariablesBlock `hcl:"variables,block"`
+	Remain    hcl.Body                  `hcl:",remain"`
+}
+
+type fileDecodeVariablesBlock struct {
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// variableLookup returns the value of an item within the Nomad variable at
+// the passed path. It backs the nomad_var() policy function.
+type variableLookup func(path, item string) (string, error)
+
+// policyFunctions returns the functions which can be used within policy
+// documents. Relative paths passed to file() are resolved against the
+// directory of the policy document. The nomad_var() function returns an error
+// when lookup is nil.
+func policyFunctions(filename string, lookup variableLookup) map[string]function.Function {
+	funcs := untrustedPolicyFunctions()
+	funcs["env"] = envFunc
+	funcs["file"] = makeFileFunc(filepath.Dir(filename))
+	funcs["nomad_var"] = makeNomadVarFunc(lookup)
+	return funcs
+}
+
+// untrustedPolicyFunctions returns the functions which can be used within
+// policy documents supplied by remote clients. They only operate on their
+// arguments, so cannot be used to read the environment, files or Nomad
+// variables of the agent host.
+func untrustedPolicyFunctions() map[string]function.Function {
+	return map[string]function.Function{
+		"ceil":  stdlib.CeilFunc,
+		"floor": stdlib.FloorFunc,
+		"max":   stdlib.MaxFunc,
+		"min":   stdlib.MinFunc,
+	}
+}
+
+// policyEvalContext builds the evaluation context used to decode a policy
+// document. Variables are exposed as attributes of the "var" object and may
+// themselves use functions, but not other variables.
+func policyEvalContext(vars *fileDecodeVariablesBlock, funcs map[string]function.Function) (*hcl.EvalContext, hcl.Diagnostics) {
+	ctx := &hcl.EvalContext{Functions: funcs}
+
+	if vars == nil {
+		ctx.Variables = map[string]cty.Value{"var": cty.EmptyObjectVal}
+		return ctx, nil
+	}
+
+	attrs, diags := vars.Remain.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	// Evaluate in a stable order so diagnostics are deterministic.
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]cty.Value, len(attrs))
+	for _, name := range names {
+		val, valDiags := attrs[name].Expr.Value(ctx)
+		diags = append(diags, valDiags...)
+		values[name] = val
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	ctx.Variables = map[string]cty.Value{"var": cty.ObjectVal(values)}
+	return ctx, nil
+}
+
+// envFunc returns the value of the named environment variable, or an empty
+// string if it is not set.
+var envFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "name", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		return cty.StringVal(os.Getenv(args[0].AsString())), nil
+	},
+})
+
+// makeFileFunc returns a function which reads the contents of the file at
+// the passed path, resolving relative paths against baseDir.
+func makeFileFunc(baseDir string) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			path := args[0].AsString()
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(baseDir, path)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return cty.NilVal, function.NewArgError(0, err)
+			}
+			return cty.StringVal(string(content)), nil
+		},
+	})
+}
+
+// makeNomadVarFunc returns a function which reads an item from a Nomad
+// variable. The result is a string, which HCL converts when it is used in
+// arithmetic or assigned to a numeric attribute such as min and max.
+func makeNomadVarFunc(lookup variableLookup) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "item", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			if lookup == nil {
+				return cty.NilVal, errors.New("nomad_var is not supported by this policy source")
+			}
+
+			val, err := lookup(args[0].AsString(), args[1].AsString())
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(val), nil
+		},
+	})
+}
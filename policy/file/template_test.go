@@ -0,0 +1,120 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode_templating(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "query.txt"), []byte("sum(rate(http_requests[1m]))"), 0o644))
+
+	src := []byte(`
+scaling "with-file" {
+  enabled = true
+  max     = 1
+
+  policy {
+    check "requests" {
+      source = "prometheus"
+      query  = file("query.txt")
+
+      strategy "target-value" {
+        target = 10
+      }
+    }
+  }
+}`)
+
+	policies, err := Decode(filepath.Join(dir, "policy.hcl"), src)
+	require.NoError(t, err)
+	require.Contains(t, policies, "with-file")
+	assert.Equal(t, "sum(rate(http_requests[1m]))", policies["with-file"].Checks[0].Query)
+
+	// Untrusted documents should not be able to read from the agent host,
+	// but can still use the math functions.
+	_, err = DecodeUntrusted(filepath.Join(dir, "policy.hcl"), src)
+	assert.ErrorContains(t, err, `There is no function named "file"`)
+
+	_, err = DecodeUntrusted("policy.hcl", []byte(`
+scaling "with-env" {
+  max = env("HOME")
+}`))
+	assert.ErrorContains(t, err, `There is no function named "env"`)
+
+	policies, err = DecodeUntrusted("policy.hcl", []byte(`
+scaling "with-math" {
+  max = max(2, ceil(1.5))
+  policy {}
+}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), policies["with-math"].Max)
+
+	// Referencing unknown variables should fail.
+	_, err = Decode(filepath.Join(dir, "policy.hcl"), []byte(`
+scaling "unknown-var" {
+  max = var.missing
+}`))
+	assert.ErrorContains(t, err, "Unsupported attribute")
+
+	// Variables cannot reference other variables.
+	_, err = Decode(filepath.Join(dir, "policy.hcl"), []byte(`
+variables {
+  a = 1
+  b = var.a
+}`))
+	assert.ErrorContains(t, err, "Variables not allowed")
+}
+
+func TestDecode_nomadVar(t *testing.T) {
+	src := []byte(`
+variables {
+  base_capacity = nomad_var("capacity/web", "base")
+}
+
+scaling "dynamic" {
+  enabled = true
+  min     = var.base_capacity
+  max     = 3 * var.base_capacity
+
+  policy {}
+}`)
+
+	values := map[string]string{"capacity/web/base": "4"}
+	lookup := func(path, item string) (string, error) {
+		v, ok := values[path+"/"+item]
+		if !ok {
+			return "", errors.New("not found")
+		}
+		return v, nil
+	}
+
+	policies, err := decode("policy.hcl", src, policyFunctions("policy.hcl", lookup))
+	require.NoError(t, err)
+	require.Contains(t, policies, "dynamic")
+	assert.Equal(t, int64(4), policies["dynamic"].Min)
+	assert.Equal(t, int64(12), policies["dynamic"].Max)
+
+	// Decoding again should pick up the new value.
+	values["capacity/web/base"] = "5"
+	policies, err = decode("policy.hcl", src, policyFunctions("policy.hcl", lookup))
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), policies["dynamic"].Max)
+
+	// Lookup failures should be returned.
+	delete(values, "capacity/web/base")
+	_, err = decode("policy.hcl", src, policyFunctions("policy.hcl", lookup))
+	assert.ErrorContains(t, err, "not found")
+
+	// Sources without a lookup should not support the function.
+	_, err = Decode("policy.hcl", src)
+	assert.ErrorContains(t, err, "nomad_var is not supported")
+}
@@ -10,8 +10,24 @@ scaling "full-cluster-policy" {
   policy {
 
     cooldown            = "10m"
+    cooldown_group      = "hashistack"
     evaluation_interval = "1m"
     on_check_error      = "error"
+    dry_run             = true
+
+    enabled_schedule {
+      timezone = "Europe/London"
+
+      active_window {
+        start    = "0 9 * * 1-5"
+        duration = "8h"
+      }
+
+      inactive_window {
+        start    = "0 12 * * 3"
+        duration = "1h"
+      }
+    }
 
     check "cpu_nomad" {
       source       = "nomad_apm"
@@ -29,6 +45,15 @@ scaling "full-cluster-policy" {
       query    = "nomad_client_allocated_memory*100/(nomad_client_allocated_memory+nomad_client_unallocated_memory)"
       on_error = "ignore"
 
+      query_timeout       = "5s"
+      query_retries       = 2
+      query_retry_backoff = "500ms"
+
+      fallback {
+        source = "aws-cloudwatch"
+        query  = "AWS/EC2/MemoryUtilization"
+      }
+
       strategy "target-value" {
         target = "80"
       }
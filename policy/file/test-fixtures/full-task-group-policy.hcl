@@ -7,6 +7,9 @@ scaling "full-task-group-policy" {
   max     = 10
   type    = "horizontal"
 
+  depends_on = ["full-cluster-policy"]
+  labels     = { team = "payments" }
+
   policy {
 
     cooldown            = "1m"
@@ -0,0 +1,32 @@
+# Copyright (c) HashiCorp, Inc.
+# SPDX-License-Identifier: MPL-2.0
+
+variables {
+  node_class    = "high-memory"
+  base_capacity = 4
+  cpu_target    = 70
+}
+
+scaling "templated-cluster-policy" {
+  enabled = true
+  min     = var.base_capacity
+  max     = max(3 * var.base_capacity, 10)
+
+  policy {
+    cooldown = "${var.base_capacity}m"
+
+    check "cpu_nomad" {
+      source = "nomad_apm"
+      query  = "cpu_${var.node_class}"
+
+      strategy "target-value" {
+        target = min(var.cpu_target + 10, 80)
+      }
+    }
+
+    target "aws-asg" {
+      aws_asg_name = "${env("NOMAD_AUTOSCALER_TEST_ASG_PREFIX")}-asg"
+      node_class   = var.node_class
+    }
+  }
+}
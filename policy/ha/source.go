@@ -71,14 +71,14 @@ func (fs *FilteredSource) MonitorIDs(ctx context.Context, req policy.MonitorIDsR
 			if err == nil {
 				continue
 			}
-			req.ErrCh <- fmt.Errorf("error from upstream policy source monitor: %v", err)
+			req.ErrCh <- fmt.Errorf("error from upstream policy source monitor: %w", err)
 			continue
 
 		case err := <-filterErrCh:
 			if err == nil {
 				continue
 			}
-			req.ErrCh <- fmt.Errorf("error from policy filter monitor: %v", err)
+			req.ErrCh <- fmt.Errorf("error from policy filter monitor: %w", err)
 			continue
 
 		case newUpstreamIDs := <-upstreamPolicyCh:
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"strconv"
 	"sync"
 	"time"
@@ -39,6 +40,33 @@ type Handler struct {
 	// is responsible for.
 	policySource Source
 
+	// conflicts is used to check whether the policy should be skipped because
+	// a policy from a higher priority source targets the same resource.
+	conflicts *ConflictResolver
+
+	// versions records each version of the policy received from the source
+	// and tracks whether an operator has pinned a previous version.
+	versions *VersionStore
+
+	// notifier is used to report changes to the policy.
+	notifier *ChangeNotifier
+
+	// admission is used to approve, reject or mutate each new version of the
+	// policy before it becomes active.
+	admission *AdmissionController
+
+	// admitted caches the last admission decision, so unchanged policies
+	// resent by the source are not submitted to the webhook again.
+	admitted admissionCache
+
+	// cooldowns is used to check whether the cooldown group of the policy is
+	// in cooldown due to another policy in the group scaling its target.
+	cooldowns *CooldownGroups
+
+	// selector restricts evaluation to policies whose labels match the
+	// agent policy_filter.
+	selector LabelSelector
+
 	// mutators is a list of mutations to apply to policies.
 	mutators []Mutator
 
@@ -65,15 +93,73 @@ type Handler struct {
 	// reloadCh is used to communicate to the MonitorPolicy routine that it
 	// should perform a reload.
 	reloadCh chan struct{}
+
+	// pinCh is used to notify the handler that the pinned version of the
+	// policy has changed.
+	pinCh chan struct{}
+
+	// triggerCh is used to request an immediate evaluation of the policy,
+	// outside of its evaluation interval, and carries the number of nodes
+	// required to place blocked allocations.
+	triggerCh chan int64
+
+	// current is a copy of the policy used by the handler, so it can be read
+	// outside of the Run loop.
+	current     *sdk.ScalingPolicy
+	currentLock sync.RWMutex
+}
+
+// HandlerConfig is the configuration used to create a Handler.
+type HandlerConfig struct {
+	// ID is the ID of the policy handled.
+	ID PolicyID
+
+	// Logger is the logger used by the handler.
+	Logger hclog.Logger
+
+	// PluginManager is used to retrieve the plugins used by the policy.
+	PluginManager *manager.PluginManager
+
+	// Source is the policy source the policy is read from.
+	Source Source
+
+	// Conflicts resolves conflicts between policies of different sources
+	// scaling the same target. It is optional.
+	Conflicts *ConflictResolver
+
+	// Versions stores the recent versions of the policy. It is optional.
+	Versions *VersionStore
+
+	// Notifier reports policy changes to the configured webhook. It is
+	// optional.
+	Notifier *ChangeNotifier
+
+	// Admission calls the configured admission webhook before new policy
+	// versions become active. It is optional.
+	Admission *AdmissionController
+
+	// Cooldowns tracks the cooldown shared by policies in the same cooldown
+	// group. It is optional.
+	Cooldowns *CooldownGroups
+
+	// Selector restricts the policies evaluated to those matching its labels.
+	// A nil selector matches every policy.
+	Selector LabelSelector
 }
 
 // NewHandler returns a new handler for a policy.
-func NewHandler(ID PolicyID, log hclog.Logger, pm *manager.PluginManager, ps Source) *Handler {
+func NewHandler(cfg *HandlerConfig) *Handler {
 	return &Handler{
-		policyID:      ID,
-		log:           log.Named("policy_handler").With("policy_id", ID),
-		pluginManager: pm,
-		policySource:  ps,
+		policyID:      cfg.ID,
+		log:           cfg.Logger.Named("policy_handler").With("policy_id", cfg.ID),
+		pluginManager: cfg.PluginManager,
+		policySource:  cfg.Source,
+		conflicts:     cfg.Conflicts,
+		versions:      cfg.Versions,
+		notifier:      cfg.Notifier,
+		admission:     cfg.Admission,
+		cooldowns:     cfg.Cooldowns,
+		selector:      cfg.Selector,
 		mutators: []Mutator{
 			NomadAPMMutator{},
 		},
@@ -82,6 +168,8 @@ func NewHandler(ID PolicyID, log hclog.Logger, pm *manager.PluginManager, ps Sou
 		doneCh:     make(chan struct{}),
 		cooldownCh: make(chan time.Duration),
 		reloadCh:   make(chan struct{}),
+		pinCh:      make(chan struct{}, 1),
+		triggerCh:  make(chan int64, 1),
 	}
 }
 
@@ -93,6 +181,8 @@ func (h *Handler) Run(ctx context.Context, evalCh chan<- *sdk.ScalingEvaluation)
 	h.log.Trace("starting policy handler")
 
 	defer h.Stop()
+	defer h.conflicts.Deregister(h.policyID)
+	defer h.cooldowns.Deregister(h.policyID)
 
 	// Mark the handler as running.
 	h.runningLock.Lock()
@@ -102,6 +192,10 @@ func (h *Handler) Run(ctx context.Context, evalCh chan<- *sdk.ScalingEvaluation)
 	// Store a local copy of the policy so we can compare it for changes.
 	var currentPolicy *sdk.ScalingPolicy
 
+	// Store the latest policy received from the source, which can differ from
+	// currentPolicy when a previous version is pinned.
+	var latestPolicy *sdk.ScalingPolicy
+
 	// Start with a long ticker until we receive the right interval.
 	// TODO(luiz): make this a config param
 	policyReadTimeout := 3 * time.Minute
@@ -151,22 +245,61 @@ func (h *Handler) Run(ctx context.Context, evalCh chan<- *sdk.ScalingEvaluation)
 
 		case p := <-h.ch:
 			h.applyMutators(&p)
-			h.updateHandler(currentPolicy, &p)
-			currentPolicy = &p
 
-		case <-h.ticker.C:
-			eval, err := h.handleTick(ctx, currentPolicy)
+			// Rejected policies are not used, leaving the previously admitted
+			// version of the policy active.
+			admitted, err := h.admitted.admit(ctx, h.admission, h.policyID, h.policySource.Name(), &p)
 			if err != nil {
-				if err == context.Canceled {
-					// Context was canceled, return to stop the handler.
-					return
-				}
-				h.log.Error(err.Error())
+				h.log.Error("policy was not admitted", "error", err)
+				continue
+			}
+			p = *admitted
+
+			// Summarise the changes once, as they are used by the version
+			// history, the log and the change notification.
+			var changes []string
+			updated := latestPolicy != nil && !reflect.DeepEqual(latestPolicy, &p)
+			if updated {
+				changes = policyChanges(latestPolicy, &p)
+			}
+
+			h.versions.Record(h.policyID, h.policySource.Name(), &p, changes)
+
+			if latestPolicy == nil {
+				h.notifier.Notify(PolicyChangeAdd, h.policyID, h.policySource.Name(), nil)
+			} else if updated {
+				h.log.Info("policy updated", "changes", changes)
+				h.notifier.Notify(PolicyChangeUpdate, h.policyID, h.policySource.Name(), changes)
+			}
+			latestPolicy = &p
+
+			effective := h.versions.Effective(h.policyID, latestPolicy)
+			h.updateHandler(currentPolicy, effective)
+			h.register(effective)
+			currentPolicy = effective
+			h.setPolicy(effective)
+
+		case <-h.pinCh:
+			if latestPolicy == nil {
 				continue
 			}
 
-			if eval != nil {
-				evalCh <- eval
+			effective := h.versions.Effective(h.policyID, latestPolicy)
+			h.log.Info("pinned policy version changed, updating policy")
+			h.updateHandler(currentPolicy, effective)
+			h.register(effective)
+			currentPolicy = effective
+			h.setPolicy(effective)
+
+		case <-h.ticker.C:
+			if !h.evaluate(ctx, currentPolicy, 0, evalCh) {
+				return
+			}
+
+		case count := <-h.triggerCh:
+			h.log.Debug("evaluating policy to place blocked allocations", "provision_count", count)
+			if !h.evaluate(ctx, currentPolicy, count, evalCh) {
+				return
 			}
 
 		case ts := <-h.cooldownCh:
@@ -175,8 +308,64 @@ func (h *Handler) Run(ctx context.Context, evalCh chan<- *sdk.ScalingEvaluation)
 				// Context was canceled, return to stop the handler.
 				return
 			}
+
+			// Drop any evaluation requested during the cooldown, as the
+			// blocked allocations may have been placed since.
+			select {
+			case <-h.triggerCh:
+			default:
+			}
+		}
+	}
+}
+
+// register records the policy with the conflict resolver and cooldown groups.
+// Policies filtered out by the label selector are deregistered instead, so
+// they cannot block or share cooldowns with policies this agent evaluates.
+func (h *Handler) register(p *sdk.ScalingPolicy) {
+	if !h.selector.Matches(p.Labels) {
+		h.conflicts.Deregister(h.policyID)
+		h.cooldowns.Deregister(h.policyID)
+		return
+	}
+
+	h.conflicts.Register(h.policyID, h.policySource.Name(), p.Target)
+	h.cooldowns.Register(h.policyID, p.CooldownGroup)
+}
+
+// evaluate sends the policy for evaluation if it passes the handler checks,
+// requesting the target is scaled out by at least the provision count. The
+// boolean return is false if the context was canceled, in which case the
+// handler should stop.
+func (h *Handler) evaluate(ctx context.Context, policy *sdk.ScalingPolicy, provision int64, evalCh chan<- *sdk.ScalingEvaluation) bool {
+	eval, err := h.handleTick(ctx, policy)
+	if err != nil {
+		if err == context.Canceled {
+			return false
 		}
+		h.log.Error(err.Error())
+		return true
 	}
+
+	if eval != nil {
+		eval.ProvisionCount = provision
+		evalCh <- eval
+	}
+	return true
+}
+
+// Policy returns the policy currently used by the handler, or nil if it has
+// not been read from the policy source yet.
+func (h *Handler) Policy() *sdk.ScalingPolicy {
+	h.currentLock.RLock()
+	defer h.currentLock.RUnlock()
+	return h.current
+}
+
+func (h *Handler) setPolicy(p *sdk.ScalingPolicy) {
+	h.currentLock.Lock()
+	defer h.currentLock.Unlock()
+	h.current = p
 }
 
 // Stop stops the handler and the monitoring Go routine.
@@ -220,6 +409,40 @@ func (h *Handler) handleTick(ctx context.Context, policy *sdk.ScalingPolicy) (*s
 		return nil, nil
 	}
 
+	// Exit early if the policy labels do not match the agent policy filter.
+	if !h.selector.Matches(policy.Labels) {
+		h.log.Debug("policy skipped as its labels do not match the policy filter")
+		return nil, nil
+	}
+
+	// Exit early if the policy schedule does not allow it to run now.
+	active, err := policy.EnabledSchedule.IsActive(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check policy enabled_schedule: %v", err)
+	}
+	if !active {
+		h.log.Debug("policy is not active due to its enabled_schedule")
+		return nil, nil
+	}
+
+	// Exit early if a policy from a higher priority source targets the same
+	// resource.
+	if !h.conflicts.Allowed(h.policyID) {
+		h.log.Debug("policy skipped due to conflict with a higher priority source")
+		return nil, nil
+	}
+
+	// Enforce the cooldown of the policy's cooldown group, which is started
+	// when any policy in the group scales its target. Once complete, wait for
+	// the next tick so the evaluation uses fresh data.
+	if cdPeriod := h.cooldowns.Remaining(h.policyID); cdPeriod > cooldownIgnoreTime {
+		h.log.Debug("policy cooldown group is in cooldown", "cooldown_group", policy.CooldownGroup)
+		if !h.enforceCooldown(ctx, cdPeriod) {
+			return nil, context.Canceled
+		}
+		return nil, nil
+	}
+
 	target, err := h.pluginManager.GetTarget(policy.Target)
 	if err != nil {
 		h.log.Warn("failed to get target", "error", err)
@@ -38,7 +38,7 @@ func TestHandler_calculateRemainingCooldown(t *testing.T) {
 		},
 	}
 
-	h := NewHandler("", hclog.NewNullLogger(), nil, nil)
+	h := NewHandler(&HandlerConfig{Logger: hclog.NewNullLogger()})
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -0,0 +1,19 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+// LabelSelector restricts the policies evaluated by the agent to those whose
+// labels match. Every key in the selector must be present in the policy
+// labels with the same value. An empty LabelSelector matches all policies.
+type LabelSelector map[string]string
+
+// Matches returns whether the passed policy labels satisfy the selector.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for k, v := range s {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelSelector_Matches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector LabelSelector
+		labels   map[string]string
+		expected bool
+	}{
+		{
+			name:     "empty selector",
+			selector: nil,
+			labels:   map[string]string{"team": "payments"},
+			expected: true,
+		},
+		{
+			name:     "empty selector and labels",
+			selector: nil,
+			labels:   nil,
+			expected: true,
+		},
+		{
+			name:     "matching labels",
+			selector: LabelSelector{"team": "payments"},
+			labels:   map[string]string{"team": "payments", "tier": "web"},
+			expected: true,
+		},
+		{
+			name:     "different value",
+			selector: LabelSelector{"team": "payments"},
+			labels:   map[string]string{"team": "search"},
+			expected: false,
+		},
+		{
+			name:     "missing key",
+			selector: LabelSelector{"team": "payments", "tier": "web"},
+			labels:   map[string]string{"team": "payments"},
+			expected: false,
+		},
+		{
+			name:     "no labels",
+			selector: LabelSelector{"team": "payments"},
+			labels:   nil,
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.selector.Matches(tc.labels))
+		})
+	}
+}
@@ -21,6 +21,31 @@ type Manager struct {
 	policySource  map[SourceName]Source
 	pluginManager *manager.PluginManager
 
+	// conflicts tracks the targets of active policies to resolve conflicts
+	// between policy sources.
+	conflicts *ConflictResolver
+
+	// versions stores the recent versions of each policy.
+	versions *VersionStore
+
+	// notifier reports policy changes to the configured webhook.
+	notifier *ChangeNotifier
+
+	// admission calls the configured admission webhook before new policy
+	// versions become active.
+	admission *AdmissionController
+
+	// cooldowns tracks the cooldown shared by policies in the same cooldown
+	// group.
+	cooldowns *CooldownGroups
+
+	// selector restricts the policies evaluated to those matching the agent
+	// policy_filter labels.
+	selector LabelSelector
+
+	// sourceStatus tracks the health of each policy source.
+	sourceStatus *sourceStatusTracker
+
 	// lock is used to synchronize parallel access to the maps below.
 	lock sync.RWMutex
 
@@ -43,16 +68,57 @@ type Manager struct {
 	policyIDsErrCh chan error
 }
 
-// NewManager returns a new Manager.
-func NewManager(log hclog.Logger, ps map[SourceName]Source, pm *manager.PluginManager, mInt time.Duration) *Manager {
+// ManagerConfig is the configuration used to create a Manager.
+type ManagerConfig struct {
+	// Logger is the logger used by the manager and its handlers.
+	Logger hclog.Logger
+
+	// Sources are the policy sources the manager reads policies from.
+	Sources map[SourceName]Source
+
+	// PluginManager is used to retrieve the plugins used by policies.
+	PluginManager *manager.PluginManager
+
+	// Conflicts resolves conflicts between policies of different sources
+	// scaling the same target. It is optional.
+	Conflicts *ConflictResolver
+
+	// Versions stores the recent versions of each policy. It is optional.
+	Versions *VersionStore
+
+	// Notifier reports policy changes to the configured webhook. It is
+	// optional.
+	Notifier *ChangeNotifier
+
+	// Admission calls the configured admission webhook before new policy
+	// versions become active. It is optional.
+	Admission *AdmissionController
 
+	// Selector restricts the policies evaluated to those matching the agent
+	// policy_filter labels. A nil selector matches every policy.
+	Selector LabelSelector
+
+	// MetricsInterval is the interval at which the agent is configured to
+	// emit metrics.
+	MetricsInterval time.Duration
+}
+
+// NewManager returns a new Manager.
+func NewManager(cfg *ManagerConfig) *Manager {
 	return &Manager{
-		log:             log.ResetNamed("policy_manager"),
-		policySource:    ps,
-		pluginManager:   pm,
+		log:             cfg.Logger.ResetNamed("policy_manager"),
+		policySource:    cfg.Sources,
+		pluginManager:   cfg.PluginManager,
+		conflicts:       cfg.Conflicts,
+		versions:        cfg.Versions,
+		notifier:        cfg.Notifier,
+		admission:       cfg.Admission,
+		cooldowns:       NewCooldownGroups(),
+		selector:        cfg.Selector,
+		sourceStatus:    newSourceStatusTracker(cfg.Sources),
 		handlers:        make(map[PolicyID]*Handler),
 		keep:            make(map[PolicyID]bool),
-		metricsInterval: mInt,
+		metricsInterval: cfg.MetricsInterval,
 		policyIDsCh:     make(chan IDMessage, 2),
 		policyIDsErrCh:  make(chan error, 2),
 	}
@@ -65,6 +131,9 @@ func (m *Manager) Run(ctx context.Context, evalCh chan<- *sdk.ScalingEvaluation)
 	// Start the metrics reporter.
 	go m.periodicMetricsReporter(ctx, m.metricsInterval)
 
+	// Start sending policy change notifications.
+	go m.notifier.Run(ctx)
+
 	for {
 		// Create a separate context so we can stop the goroutine monitoring the
 		// list of policies independently from the parent context.
@@ -116,6 +185,7 @@ func (m *Manager) monitorPolicies(ctx context.Context, evalCh chan<- *sdk.Scalin
 
 		case err := <-m.policyIDsErrCh:
 			m.log.Error("encountered an error monitoring policy IDs", "error", err)
+			m.sourceStatus.recordError(err)
 			if isUnrecoverableError(err) {
 				return err
 			}
@@ -124,6 +194,7 @@ func (m *Manager) monitorPolicies(ctx context.Context, evalCh chan<- *sdk.Scalin
 		case policyIDs := <-m.policyIDsCh:
 			m.log.Trace("received policy IDs listing",
 				"num", len(policyIDs.IDs), "policy_source", policyIDs.Source)
+			m.sourceStatus.recordSync(policyIDs.Source, len(policyIDs.IDs))
 
 			m.lock.Lock()
 
@@ -149,7 +220,18 @@ func (m *Manager) monitorPolicies(ctx context.Context, evalCh chan<- *sdk.Scalin
 				m.log.Trace("creating new handler",
 					"policy_id", policyID, "policy_source", policyIDs.Source)
 
-				h := NewHandler(policyID, m.log, m.pluginManager, m.policySource[policyIDs.Source])
+				h := NewHandler(&HandlerConfig{
+					ID:            policyID,
+					Logger:        m.log,
+					PluginManager: m.pluginManager,
+					Source:        m.policySource[policyIDs.Source],
+					Conflicts:     m.conflicts,
+					Versions:      m.versions,
+					Notifier:      m.notifier,
+					Admission:     m.admission,
+					Cooldowns:     m.cooldowns,
+					Selector:      m.selector,
+				})
 				m.handlers[policyID] = h
 
 				go func(ID PolicyID) {
@@ -167,6 +249,8 @@ func (m *Manager) monitorPolicies(ctx context.Context, evalCh chan<- *sdk.Scalin
 			for k, h := range m.handlers {
 				if !m.keep[k] && h.policySource.Name() == policyIDs.Source {
 					m.stopHandler(h)
+					m.versions.Remove(k)
+					m.notifier.Notify(PolicyChangeDelete, k, policyIDs.Source, nil)
 				}
 			}
 
@@ -199,8 +283,12 @@ func (m *Manager) stopHandler(h *Handler) {
 }
 
 // EnforceCooldown attempts to enforce cooldown on the policy handler
-// representing the passed ID.
+// representing the passed ID. The cooldown group of the policy, if any, is
+// also placed into cooldown so the other policies in the group skip their
+// evaluations.
 func (m *Manager) EnforceCooldown(id string, t time.Duration) {
+	m.cooldowns.Start(PolicyID(id), t)
+
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
@@ -219,6 +307,88 @@ func (m *Manager) EnforceCooldown(id string, t time.Duration) {
 	}
 }
 
+// SourceStatuses returns the health of each policy source, sorted by name.
+func (m *Manager) SourceStatuses() []*SourceStatus {
+	return m.sourceStatus.list()
+}
+
+// PolicyVersions returns the stored versions of the policy with the passed
+// ID.
+func (m *Manager) PolicyVersions(id PolicyID) (*PolicyVersions, error) {
+	return m.versions.Versions(id)
+}
+
+// PinPolicyVersion pins the policy with the passed ID to a previously stored
+// version. Updates from the policy source are still recorded, but are not
+// used until the policy is unpinned.
+func (m *Manager) PinPolicyVersion(id PolicyID, version uint64) error {
+	if err := m.versions.Pin(id, version); err != nil {
+		return err
+	}
+	m.notifyPinChange(id)
+	return nil
+}
+
+// UnpinPolicy removes the pinned version of the policy with the passed ID, so
+// the latest version from the policy source is used.
+func (m *Manager) UnpinPolicy(id PolicyID) error {
+	if err := m.versions.Unpin(id); err != nil {
+		return err
+	}
+	m.notifyPinChange(id)
+	return nil
+}
+
+// notifyPinChange informs the handler of the passed policy that its pinned
+// version has changed. The notification is dropped if one is already
+// pending, as the handler always reads the current state.
+func (m *Manager) notifyPinChange(id PolicyID) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if h, ok := m.handlers[id]; ok {
+		select {
+		case h.pinCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Policies returns the policies currently used by the running handlers.
+func (m *Manager) Policies() []*sdk.ScalingPolicy {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	policies := make([]*sdk.ScalingPolicy, 0, len(m.handlers))
+	for _, h := range m.handlers {
+		if p := h.Policy(); p != nil {
+			policies = append(policies, p)
+		}
+	}
+	return policies
+}
+
+// TriggerEvaluation requests an immediate evaluation of the policy with the
+// passed ID, scaling its target out by at least the provision count. The
+// evaluation is subject to the same checks as those triggered by the policy
+// evaluation interval, such as cooldown. The request is dropped if one is
+// already pending.
+func (m *Manager) TriggerEvaluation(id PolicyID, provision int64) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	h, ok := m.handlers[id]
+	if !ok {
+		m.log.Debug("attempted to trigger evaluation of non-existent handler", "policy_id", id)
+		return
+	}
+
+	select {
+	case h.triggerCh <- provision:
+	default:
+	}
+}
+
 // ReloadSources triggers a reload of all the policy sources.
 func (m *Manager) ReloadSources() {
 	m.lock.Lock()
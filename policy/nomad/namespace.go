@@ -0,0 +1,84 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package nomad
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// configKeyNamespace is the policy source config key used to select the
+	// namespaces to watch using a glob pattern, such as "prod-*".
+	configKeyNamespace = "namespace"
+
+	// configKeyNamespaceRegex is the policy source config key used to select
+	// the namespaces to watch using a regular expression.
+	configKeyNamespaceRegex = "namespace_regex"
+
+	// wildcardNamespace is used when querying Nomad for policies across all
+	// namespaces.
+	wildcardNamespace = "*"
+)
+
+// namespaceFilter selects the Nomad namespaces the source watches for
+// policies.
+type namespaceFilter struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// newNamespaceFilter builds a namespaceFilter from the policy source config.
+// A nil filter is returned if no namespace selection is configured, in which
+// case the namespace of the Nomad client is used.
+func newNamespaceFilter(cfg map[string]string) (*namespaceFilter, error) {
+	glob, re := cfg[configKeyNamespace], cfg[configKeyNamespaceRegex]
+
+	switch {
+	case glob != "" && re != "":
+		return nil, fmt.Errorf("only one of %q and %q can be set", configKeyNamespace, configKeyNamespaceRegex)
+	case re != "":
+		compiled, err := regexp.Compile(re)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", configKeyNamespaceRegex, err)
+		}
+		return &namespaceFilter{regex: compiled}, nil
+	case glob != "":
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", configKeyNamespace, glob, err)
+		}
+		return &namespaceFilter{glob: glob}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// queryNamespace returns the namespace which should be used when listing
+// policies from Nomad. Filters which can match multiple namespaces list
+// across all namespaces and match the results using match.
+func (f *namespaceFilter) queryNamespace() string {
+	if f == nil {
+		return ""
+	}
+	if f.regex != nil || strings.ContainsAny(f.glob, `*?[\`) {
+		return wildcardNamespace
+	}
+	return f.glob
+}
+
+// match returns whether policies in the passed namespace should be watched.
+func (f *namespaceFilter) match(ns string) bool {
+	if f == nil {
+		return true
+	}
+	if f.regex != nil {
+		return f.regex.MatchString(ns)
+	}
+
+	// The pattern was checked when the filter was built, so ignore the error.
+	ok, _ := path.Match(f.glob, ns)
+	return ok
+}
@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package nomad
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_namespaceFilter(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inputCfg       map[string]string
+		expectedErr    string
+		expectedQuery  string
+		expectedMatch  []string
+		expectedReject []string
+	}{
+		{
+			name:          "no selection",
+			inputCfg:      map[string]string{},
+			expectedQuery: "",
+			expectedMatch: []string{"default", "prod-a"},
+		},
+		{
+			name:           "single namespace",
+			inputCfg:       map[string]string{"namespace": "prod"},
+			expectedQuery:  "prod",
+			expectedMatch:  []string{"prod"},
+			expectedReject: []string{"prod-a", "default"},
+		},
+		{
+			name:           "glob",
+			inputCfg:       map[string]string{"namespace": "prod-*"},
+			expectedQuery:  "*",
+			expectedMatch:  []string{"prod-a", "prod-team-b"},
+			expectedReject: []string{"prod", "dev-a"},
+		},
+		{
+			name:           "regex",
+			inputCfg:       map[string]string{"namespace_regex": "^(prod|staging)-.+$"},
+			expectedQuery:  "*",
+			expectedMatch:  []string{"prod-a", "staging-b"},
+			expectedReject: []string{"prod-", "dev-a"},
+		},
+		{
+			name:        "invalid glob",
+			inputCfg:    map[string]string{"namespace": "prod-["},
+			expectedErr: `invalid namespace "prod-["`,
+		},
+		{
+			name:        "invalid regex",
+			inputCfg:    map[string]string{"namespace_regex": "prod-("},
+			expectedErr: "invalid namespace_regex",
+		},
+		{
+			name:        "both set",
+			inputCfg:    map[string]string{"namespace": "prod-*", "namespace_regex": "prod-.*"},
+			expectedErr: `only one of "namespace" and "namespace_regex" can be set`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := newNamespaceFilter(tc.inputCfg)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedQuery, f.queryNamespace())
+			for _, ns := range tc.expectedMatch {
+				assert.True(t, f.match(ns), ns)
+			}
+			for _, ns := range tc.expectedReject {
+				assert.False(t, f.match(ns), ns)
+			}
+		})
+	}
+}
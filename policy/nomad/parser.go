@@ -5,6 +5,7 @@ package nomad
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/hashicorp/nomad-autoscaler/sdk"
@@ -25,14 +26,22 @@ func parsePolicy(p *api.ScalingPolicy) sdk.ScalingPolicy {
 	to := sdk.ScalingPolicy{
 		ID:      p.ID,
 		Type:    p.Type,
-		Max:     *p.Max, // Nomad always ensures Max is populated.
 		Enabled: true,
 		Checks:  parseChecks(p.Policy[keyChecks]),
 	}
 
-	// Add non-typed values.
+	// Add non-typed values. Nomad ensures Max is populated, but flag missing
+	// values so the policy defaults are applied.
 	if p.Min != nil {
 		to.Min = *p.Min
+	} else {
+		to.MinUnset = true
+	}
+
+	if p.Max != nil {
+		to.Max = *p.Max
+	} else {
+		to.MaxUnset = true
 	}
 
 	if p.Enabled != nil {
@@ -51,11 +60,30 @@ func parsePolicy(p *api.ScalingPolicy) sdk.ScalingPolicy {
 		to.Cooldown, _ = time.ParseDuration(cooldown)
 	}
 
+	// Parse cooldown_group.
+	if group, ok := p.Policy[keyCooldownGroup].(string); ok {
+		to.CooldownGroup = group
+	}
+
 	// Parse on_check_error.
 	if onCheckError, ok := p.Policy[keyOnCheckError].(string); ok {
 		to.OnCheckError = onCheckError
 	}
 
+	// Parse dry_run.
+	if dryRun, ok := p.Policy[keyDryRun].(bool); ok {
+		to.DryRun = dryRun
+	}
+
+	// Parse depends_on.
+	to.DependsOn = parseDependsOn(p.Policy[keyDependsOn])
+
+	// Parse labels.
+	to.Labels = parseLabels(p.Policy[keyLabels])
+
+	// Parse enabled_schedule block.
+	to.EnabledSchedule = parseSchedule(p.Policy[keyEnabledSchedule])
+
 	// Parse target block.
 	var target *sdk.ScalingPolicyTarget
 
@@ -75,9 +103,51 @@ func parsePolicy(p *api.ScalingPolicy) sdk.ScalingPolicy {
 	}
 	to.Target = target
 
+	// Name the policy after the group it scales, so it can be referenced by
+	// the depends_on of other policies.
+	if ns, job, group := p.Target["Namespace"], p.Target["Job"], p.Target["Group"]; job != "" && group != "" {
+		to.Name = fmt.Sprintf("%s/%s/%s", ns, job, group)
+	}
+
 	return to
 }
 
+// parseDependsOn parses the list of policy names in depends_on.
+//
+// It provides best-effort parsing, with any non-string values being skipped.
+func parseDependsOn(d interface{}) []string {
+	list, ok := d.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for _, v := range list {
+		if name, ok := v.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseLabels parses the map of policy labels.
+//
+// It provides best-effort parsing, with any non-string values being skipped.
+func parseLabels(l interface{}) map[string]string {
+	m, ok := l.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil
+	}
+
+	labels := make(map[string]string, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			labels[k] = s
+		}
+	}
+	return labels
+}
+
 // parseChecks parses the list of checks in a scaling policy.
 //
 // It provides best-effort parsing and will return `nil` in case of errors.
@@ -115,7 +185,10 @@ func parseChecks(cs interface{}) []*sdk.ScalingPolicyCheck {
 //	  | check "name" {                 |
 //	  |   source = "source"            |
 //	  |   query = "query"              |
+//	  |   queries = { ... }            |
+//	  |   query_expression = "a / b"   |
 //	  |   query_window = "5m"          |
+//	  |   fallback { ... }             |
 //	  |   strategy "strategy" { ... }  |
 //	  | }                              |
 //	  +--------------------------------+
@@ -147,6 +220,9 @@ func parseCheck(c interface{}) *sdk.ScalingPolicyCheck {
 	source, _ := checkMap[keySource].(string)
 	on_error, _ := checkMap[keyOnError].(string)
 	group, _ := checkMap[keyGroup].(string)
+	queryExpression, _ := checkMap[keyQueryExpression].(string)
+	unit, _ := checkMap[keyUnit].(string)
+	missingData, _ := checkMap[keyMissingData].(string)
 
 	// Parse query_window ignoring errors since we assume policy has been validated.
 	var queryWindow time.Duration
@@ -154,14 +230,138 @@ func parseCheck(c interface{}) *sdk.ScalingPolicyCheck {
 		queryWindow, _ = time.ParseDuration(queryWindowStr)
 	}
 
+	// Parse the query timeout and retry settings, also ignoring errors.
+	parseDuration := func(key string) time.Duration {
+		s, _ := checkMap[key].(string)
+		d, _ := time.ParseDuration(s)
+		return d
+	}
+	queryRetries, _ := parseInt(checkMap[keyQueryRetries])
+
 	return &sdk.ScalingPolicyCheck{
-		Group:       group,
-		Query:       query,
-		QueryWindow: queryWindow,
-		Source:      source,
-		Strategy:    strategy,
-		OnError:     on_error,
+		Group:                   group,
+		Query:                   query,
+		Queries:                 parseQueries(checkMap[keyQueries]),
+		QueryExpression:         queryExpression,
+		QueryWindow:             queryWindow,
+		QueryTimeout:            parseDuration(keyQueryTimeout),
+		QueryRetries:            queryRetries,
+		QueryRetryBackoff:       parseDuration(keyQueryRetryBackoff),
+		QueryRetryMaxBackoff:    parseDuration(keyQueryRetryMaxBackoff),
+		Unit:                    unit,
+		MissingData:             missingData,
+		MissingDataMaxStaleness: parseDuration(keyMissingDataMaxStale),
+		Fallbacks:               parseFallbacks(checkMap[keyFallback]),
+		Source:                  source,
+		Strategy:                strategy,
+		OnError:                 on_error,
+	}
+}
+
+// parseInt parses a whole number decoded from the policy. JSON decoding
+// returns numbers as float64.
+func parseInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, false
+		}
+		return int(n), true
 	}
+	return 0, false
+}
+
+// parseFallbacks parses the list of unlabeled fallback blocks of a check.
+func parseFallbacks(fs interface{}) []*sdk.ScalingPolicyCheckFallback {
+	list, ok := fs.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var fallbacks []*sdk.ScalingPolicyCheckFallback
+	for _, f := range list {
+		fallbackMap, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// Ignore errors since we assume policy has been validated.
+		source, _ := fallbackMap[keySource].(string)
+		query, _ := fallbackMap[keyQuery].(string)
+
+		fallbacks = append(fallbacks, &sdk.ScalingPolicyCheckFallback{Source: source, Query: query})
+	}
+
+	return fallbacks
+}
+
+// parseQueries parses the named queries of a check. They may be defined as a
+// map attribute or as a single block, depending on how the job was submitted.
+func parseQueries(q interface{}) map[string]string {
+	if m := parseBlock(q); m != nil {
+		return parseLabels(m)
+	}
+	return parseLabels(q)
+}
+
+// parseSchedule parses the content of the enabled_schedule block from a
+// policy.
+//
+// It provides best-effort parsing and will return `nil` in case of errors.
+//
+//	scaling {
+//	  policy {
+//	  +-----------------------------+
+//	  | enabled_schedule {          |
+//	  |   timezone = "UTC"          |
+//	  |   active_window { ... }     |
+//	  |   inactive_window { ... }   |
+//	  | }                           |
+//	  +-----------------------------+
+//	  }
+//	}
+func parseSchedule(s interface{}) *sdk.ScalingPolicySchedule {
+	scheduleMap := parseBlock(s)
+	if scheduleMap == nil {
+		return nil
+	}
+
+	timezone, _ := scheduleMap[keyTimezone].(string)
+
+	return &sdk.ScalingPolicySchedule{
+		Timezone:        timezone,
+		ActiveWindows:   parseScheduleWindows(scheduleMap[keyActiveWindow]),
+		InactiveWindows: parseScheduleWindows(scheduleMap[keyInactiveWindow]),
+	}
+}
+
+// parseScheduleWindows parses a list of unlabeled schedule window blocks.
+func parseScheduleWindows(ws interface{}) []*sdk.ScalingPolicyScheduleWindow {
+	list, ok := ws.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var windows []*sdk.ScalingPolicyScheduleWindow
+	for _, w := range list {
+		windowMap, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// Ignore errors since we assume policy has been validated.
+		start, _ := windowMap[keyStart].(string)
+		durationStr, _ := windowMap[keyDuration].(string)
+		duration, _ := time.ParseDuration(durationStr)
+
+		windows = append(windows, &sdk.ScalingPolicyScheduleWindow{Start: start, Duration: duration})
+	}
+
+	return windows
 }
 
 // parseStrategy parses the content of the strategy block from a policy.
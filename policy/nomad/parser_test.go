@@ -24,13 +24,18 @@ func Test_parsePolicy(t *testing.T) {
 			input: "full-scaling",
 			expected: sdk.ScalingPolicy{
 				ID:                 "id",
+				Name:               "default/full-scaling/test",
 				Min:                2,
 				Max:                10,
 				Enabled:            false,
 				EvaluationInterval: 5 * time.Second,
 				Cooldown:           5 * time.Minute,
+				CooldownGroup:      "web",
 				Type:               "horizontal",
 				OnCheckError:       "fail",
+				DryRun:             true,
+				DependsOn:          []string{"cluster"},
+				Labels:             map[string]string{"team": "payments"},
 				Target: &sdk.ScalingPolicyTarget{
 					Name: "target",
 					Config: map[string]string{
@@ -80,6 +85,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "minimum-valid-scaling",
 			expected: sdk.ScalingPolicy{
 				ID:      "id",
+				Name:    "default/minimum-valid-scaling/test",
 				Min:     1,
 				Max:     10,
 				Enabled: true,
@@ -118,6 +124,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "empty-policy",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/empty-policy/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -135,6 +142,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "invalid-evaluation-interval",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/invalid-evaluation-interval/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -152,6 +160,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "invalid-cooldown",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/invalid-cooldown/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -169,6 +178,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "empty-target",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/empty-target/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -186,6 +196,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "invalid-target",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/invalid-target/test",
 				Max:  10,
 				Type: "horizontal",
 			},
@@ -195,6 +206,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "empty-check",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/empty-check/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -215,6 +227,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "single-check",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/single-check/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -242,11 +255,92 @@ func Test_parsePolicy(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "multiple queries",
+			input: "multi-query",
+			expected: sdk.ScalingPolicy{
+				ID:   "id",
+				Name: "default/multi-query/test",
+				Max:  10,
+				Type: "horizontal",
+				Target: &sdk.ScalingPolicyTarget{
+					Name: "",
+					Config: map[string]string{
+						"Namespace": "default",
+						"Job":       "multi-query",
+						"Group":     "test",
+					},
+				},
+				Checks: []*sdk.ScalingPolicyCheck{
+					{
+						Name:   "check",
+						Source: "source",
+						Queries: map[string]string{
+							"requests": "sum(requests)",
+							"healthy":  "count(healthy)",
+						},
+						QueryExpression: "requests / healthy",
+						Strategy: &sdk.ScalingPolicyStrategy{
+							Name: "strategy",
+							Config: map[string]string{
+								"int_config":  "2",
+								"bool_config": "true",
+								"str_config":  "str",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "check fallback",
+			input: "check-fallback",
+			expected: sdk.ScalingPolicy{
+				ID:   "id",
+				Name: "default/check-fallback/test",
+				Max:  10,
+				Type: "horizontal",
+				Target: &sdk.ScalingPolicyTarget{
+					Name: "",
+					Config: map[string]string{
+						"Namespace": "default",
+						"Job":       "check-fallback",
+						"Group":     "test",
+					},
+				},
+				Checks: []*sdk.ScalingPolicyCheck{
+					{
+						Name:                    "check",
+						Source:                  "source",
+						Query:                   "query",
+						QueryTimeout:            5 * time.Second,
+						QueryRetries:            3,
+						QueryRetryBackoff:       time.Second,
+						Unit:                    "bytes_to_mib",
+						MissingData:             "last",
+						MissingDataMaxStaleness: 10 * time.Minute,
+						Fallbacks: []*sdk.ScalingPolicyCheckFallback{
+							{Source: "fallback-1", Query: "query-1"},
+							{Source: "fallback-2", Query: "query-2"},
+						},
+						Strategy: &sdk.ScalingPolicyStrategy{
+							Name: "strategy",
+							Config: map[string]string{
+								"int_config":  "2",
+								"bool_config": "true",
+								"str_config":  "str",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:  "invalid check",
 			input: "invalid-check",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/invalid-check/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -264,6 +358,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "missing-strategy",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/missing-strategy/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -288,6 +383,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "empty-strategy",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/empty-strategy/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -314,6 +410,7 @@ func Test_parsePolicy(t *testing.T) {
 			input: "invalid-strategy",
 			expected: sdk.ScalingPolicy{
 				ID:   "id",
+				Name: "default/invalid-strategy/test",
 				Max:  10,
 				Type: "horizontal",
 				Target: &sdk.ScalingPolicyTarget{
@@ -405,3 +502,39 @@ func Test_parseBlock(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseSchedule(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{
+			keyTimezone: "Europe/London",
+			keyActiveWindow: []interface{}{
+				map[string]interface{}{keyStart: "0 9 * * 1-5", keyDuration: "8h"},
+			},
+			keyInactiveWindow: []interface{}{
+				map[string]interface{}{keyStart: "0 12 * * 3", keyDuration: "1h"},
+			},
+		},
+	}
+
+	expected := &sdk.ScalingPolicySchedule{
+		Timezone: "Europe/London",
+		ActiveWindows: []*sdk.ScalingPolicyScheduleWindow{
+			{Start: "0 9 * * 1-5", Duration: 8 * time.Hour},
+		},
+		InactiveWindows: []*sdk.ScalingPolicyScheduleWindow{
+			{Start: "0 12 * * 3", Duration: time.Hour},
+		},
+	}
+
+	assert.Equal(t, expected, parseSchedule(input))
+	assert.Nil(t, parseSchedule(nil))
+
+	assert.NoError(t, validateBlock(input, "scaling.policy.enabled_schedule", validateSchedule))
+	assert.Error(t, validateBlock([]interface{}{
+		map[string]interface{}{
+			keyActiveWindow: []interface{}{
+				map[string]interface{}{keyStart: 9, keyDuration: "8"},
+			},
+		},
+	}, "scaling.policy.enabled_schedule", validateSchedule))
+}
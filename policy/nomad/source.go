@@ -22,17 +22,37 @@ import (
 // Keys represent the scaling policy document keys and help translate
 // the opaque object into a usable autoscaling policy.
 const (
-	keySource             = "source"
-	keyQuery              = "query"
-	keyQueryWindow        = "query_window"
-	keyEvaluationInterval = "evaluation_interval"
-	keyOnCheckError       = "on_check_error"
-	keyOnError            = "on_error"
-	keyTarget             = "target"
-	keyChecks             = "check"
-	keyGroup              = "group"
-	keyStrategy           = "strategy"
-	keyCooldown           = "cooldown"
+	keySource               = "source"
+	keyQuery                = "query"
+	keyQueries              = "queries"
+	keyQueryExpression      = "query_expression"
+	keyQueryWindow          = "query_window"
+	keyFallback             = "fallback"
+	keyQueryTimeout         = "query_timeout"
+	keyQueryRetries         = "query_retries"
+	keyQueryRetryBackoff    = "query_retry_backoff"
+	keyQueryRetryMaxBackoff = "query_retry_max_backoff"
+	keyUnit                 = "unit"
+	keyMissingData          = "missing_data"
+	keyMissingDataMaxStale  = "missing_data_max_staleness"
+	keyEvaluationInterval   = "evaluation_interval"
+	keyOnCheckError         = "on_check_error"
+	keyOnError              = "on_error"
+	keyTarget               = "target"
+	keyChecks               = "check"
+	keyGroup                = "group"
+	keyStrategy             = "strategy"
+	keyCooldown             = "cooldown"
+	keyCooldownGroup        = "cooldown_group"
+	keyDryRun               = "dry_run"
+	keyDependsOn            = "depends_on"
+	keyLabels               = "labels"
+	keyEnabledSchedule      = "enabled_schedule"
+	keyTimezone             = "timezone"
+	keyActiveWindow         = "active_window"
+	keyInactiveWindow       = "inactive_window"
+	keyStart                = "start"
+	keyDuration             = "duration"
 )
 
 // Ensure NomadSource satisfies the Source interface.
@@ -48,15 +68,27 @@ type Source struct {
 
 	// reloadCh helps coordinate reloading the of the MonitorIDs routine.
 	reloadCh chan struct{}
+
+	// namespaces selects the namespaces watched for policies. When nil, the
+	// namespace of the Nomad client is used.
+	namespaces     *namespaceFilter
+	namespacesLock sync.RWMutex
+
+	// policyNamespaces tracks the namespace of each policy found when
+	// watching multiple namespaces, so it can be used when reading the
+	// policy.
+	policyNamespaces     map[policy.PolicyID]string
+	policyNamespacesLock sync.RWMutex
 }
 
 // NewNomadSource returns a new Nomad policy source.
 func NewNomadSource(log hclog.Logger, nomad *api.Client, policyProcessor *policy.Processor) *Source {
 	return &Source{
-		log:             log.ResetNamed("nomad_policy_source"),
-		nomad:           nomad,
-		policyProcessor: policyProcessor,
-		reloadCh:        make(chan struct{}),
+		log:              log.ResetNamed("nomad_policy_source"),
+		nomad:            nomad,
+		policyProcessor:  policyProcessor,
+		reloadCh:         make(chan struct{}),
+		policyNamespaces: make(map[policy.PolicyID]string),
 	}
 }
 
@@ -66,6 +98,22 @@ func (s *Source) SetNomadClient(nomad *api.Client) {
 	s.nomad = nomad
 }
 
+// SetNamespaceFilter configures the namespaces watched for policies using the
+// policy source config. The "namespace" key accepts a glob pattern, such as
+// "prod-*", while "namespace_regex" accepts a regular expression. When
+// neither is set, the namespace of the Nomad client is used.
+func (s *Source) SetNamespaceFilter(cfg map[string]string) error {
+	f, err := newNamespaceFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	s.namespacesLock.Lock()
+	defer s.namespacesLock.Unlock()
+	s.namespaces = f
+	return nil
+}
+
 // Name satisfies the Name function of the policy.Source interface.
 func (s *Source) Name() policy.SourceName {
 	return policy.SourceNameNomad
@@ -98,6 +146,11 @@ func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
 			err      error
 		)
 
+		s.namespacesLock.RLock()
+		namespaces := s.namespaces
+		s.namespacesLock.RUnlock()
+		q.Namespace = namespaces.queryNamespace()
+
 		// Perform a blocking query on the Nomad API that returns a stub list
 		// of scaling policies. The call is done in a goroutine so we can
 		// still listen for the context closing or a reload request.
@@ -119,6 +172,10 @@ func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
 			return
 		case <-s.reloadCh:
 			s.log.Trace("reloading policies")
+
+			// Reset the index so the namespace selection, which may have
+			// changed, is applied immediately.
+			q.WaitIndex = 1
 			continue
 		case <-blockingQueryCompleteCh:
 		}
@@ -145,17 +202,28 @@ func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
 		}
 
 		var policyIDs []policy.PolicyID
+		policyNamespaces := make(map[policy.PolicyID]string)
 
 		// Iterate over all policies in the list and filter out policies
-		// that are not enabled.
+		// that are not enabled or not in a selected namespace.
 		for _, p := range policies {
+			ns := p.Target["Namespace"]
+			if !namespaces.match(ns) {
+				continue
+			}
+
 			if p.Enabled {
 				policyIDs = append(policyIDs, policy.PolicyID(p.ID))
+				policyNamespaces[policy.PolicyID(p.ID)] = ns
 			} else {
 				s.log.Info("policy not enabled", "policy_id", p.ID)
 			}
 		}
 
+		s.policyNamespacesLock.Lock()
+		s.policyNamespaces = policyNamespaces
+		s.policyNamespacesLock.Unlock()
+
 		// Update the Nomad API wait index to start long polling from the
 		// correct point and update our recorded lastChangeIndex so we have the
 		// correct point to use during the next API return.
@@ -181,6 +249,8 @@ func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq)
 
 	q := &api.QueryOptions{WaitTime: 5 * time.Minute, WaitIndex: 1}
 	for {
+		q.Namespace = s.policyNamespace(req.ID)
+
 		var (
 			p    *api.ScalingPolicy
 			meta *api.QueryMeta
@@ -264,6 +334,23 @@ func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq)
 	}
 }
 
+// policyNamespace returns the namespace to use when reading the passed
+// policy. An empty string is returned, so the Nomad client namespace is used,
+// unless multiple namespaces are being watched.
+func (s *Source) policyNamespace(id policy.PolicyID) string {
+	s.namespacesLock.RLock()
+	ns := s.namespaces.queryNamespace()
+	s.namespacesLock.RUnlock()
+
+	if ns != wildcardNamespace {
+		return ns
+	}
+
+	s.policyNamespacesLock.RLock()
+	defer s.policyNamespacesLock.RUnlock()
+	return s.policyNamespaces[id]
+}
+
 // canonicalizePolicy sets standarized values for missing fields.
 func (s *Source) canonicalizePolicy(p *sdk.ScalingPolicy) {
 	if p == nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/nomad-autoscaler/plugins"
 	"github.com/hashicorp/nomad-autoscaler/policy"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
 	"github.com/hashicorp/nomad/api"
 	"github.com/stretchr/testify/assert"
 )
@@ -323,6 +324,24 @@ func TestSource_canonicalizePolicy(t *testing.T) {
 				sourceConfig.DefaultCooldown = 1 * time.Hour
 			},
 		},
+		{
+			name:  "sets min and max from agent",
+			input: &sdk.ScalingPolicy{MinUnset: true, MaxUnset: true},
+			expected: &sdk.ScalingPolicy{
+				Type:               sdk.ScalingPolicyTypeHorizontal,
+				Min:                2,
+				Max:                8,
+				EvaluationInterval: 10 * time.Second,
+				Target: &sdk.ScalingPolicyTarget{
+					Name:   plugins.InternalTargetNomad,
+					Config: map[string]string{},
+				},
+			},
+			cb: func(_ *api.Config, sourceConfig *policy.ConfigDefaults) {
+				sourceConfig.DefaultMin = 2
+				sourceConfig.DefaultMax = 8
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -333,3 +352,29 @@ func TestSource_canonicalizePolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestSource_canonicalizePolicy_defaultLimits(t *testing.T) {
+	s := TestNomadSource(t, func(_ *api.Config, sourceConfig *policy.ConfigDefaults) {
+		sourceConfig.DefaultMin = 2
+		sourceConfig.DefaultMax = 8
+	})
+
+	// Limits missing from the Nomad policy use the agent defaults.
+	p := parsePolicy(&api.ScalingPolicy{ID: "id", Max: ptr.Int64ToPtr(10)})
+	s.canonicalizePolicy(&p)
+	assert.Equal(t, int64(2), p.Min)
+	assert.Equal(t, int64(10), p.Max)
+	assert.False(t, p.MinUnset)
+
+	// Limits set in the Nomad policy are kept, even if zero.
+	p = parsePolicy(&api.ScalingPolicy{ID: "id", Min: ptr.Int64ToPtr(0), Max: ptr.Int64ToPtr(10)})
+	s.canonicalizePolicy(&p)
+	assert.Equal(t, int64(0), p.Min)
+	assert.Equal(t, int64(10), p.Max)
+
+	p = parsePolicy(&api.ScalingPolicy{ID: "id"})
+	s.canonicalizePolicy(&p)
+	assert.Equal(t, int64(2), p.Min)
+	assert.Equal(t, int64(8), p.Max)
+	assert.False(t, p.MaxUnset)
+}
@@ -0,0 +1,53 @@
+# Copyright (c) HashiCorp, Inc.
+# SPDX-License-Identifier: MPL-2.0
+
+job "check-fallback" {
+  datacenters = ["dc1"]
+  type        = "batch"
+
+  group "test" {
+    scaling {
+      min     = 0
+      max     = 10
+      enabled = false
+
+      policy {
+        check "check" {
+          source = "source"
+          query  = "query"
+
+          query_timeout              = "5s"
+          query_retries              = 3
+          query_retry_backoff        = "1s"
+          unit                       = "bytes_to_mib"
+          missing_data               = "last"
+          missing_data_max_staleness = "10m"
+
+          fallback {
+            source = "fallback-1"
+            query  = "query-1"
+          }
+
+          fallback {
+            source = "fallback-2"
+            query  = "query-2"
+          }
+
+          strategy "strategy" {
+            int_config  = 2
+            bool_config = true
+            str_config  = "str"
+          }
+        }
+      }
+    }
+
+    task "echo" {
+      driver = "raw_exec"
+      config {
+        command = "echo"
+        args    = ["hi"]
+      }
+    }
+  }
+}
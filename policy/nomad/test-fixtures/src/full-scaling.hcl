@@ -14,7 +14,11 @@ job "full-scaling" {
       policy {
         evaluation_interval = "5s"
         cooldown            = "5m"
+        cooldown_group      = "web"
         on_check_error      = "fail"
+        dry_run             = true
+        depends_on          = ["cluster"]
+        labels              = { team = "payments" }
 
         target "target" {
           int_config  = 2
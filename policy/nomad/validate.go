@@ -97,6 +97,57 @@ func validatePolicy(p map[string]interface{}) error {
 		}
 	}
 
+	// Validate CooldownGroup, if present.
+	//   1. CooldownGroup should be a string.
+	if group, ok := p[keyCooldownGroup]; ok {
+		if _, ok := group.(string); !ok {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be string, found %T", path, keyCooldownGroup, group))
+		}
+	}
+
+	// Validate DryRun, if present.
+	//   1. DryRun should be a bool.
+	if dryRun, ok := p[keyDryRun]; ok {
+		if _, ok := dryRun.(bool); !ok {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be bool, found %T", path, keyDryRun, dryRun))
+		}
+	}
+
+	// Validate DependsOn, if present.
+	//   1. DependsOn should be a list of strings.
+	if dependsOn, ok := p[keyDependsOn]; ok {
+		list, ok := dependsOn.([]interface{})
+		if !ok {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be a list, found %T", path, keyDependsOn, dependsOn))
+		}
+		for i, v := range list {
+			if _, ok := v.(string); !ok {
+				result = multierror.Append(result, fmt.Errorf("%s.%s[%d] must be string, found %T", path, keyDependsOn, i, v))
+			}
+		}
+	}
+
+	// Validate Labels, if present.
+	//   1. Labels should be a map of strings.
+	if labels, ok := p[keyLabels]; ok {
+		m, ok := labels.(map[string]interface{})
+		if !ok {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be a map, found %T", path, keyLabels, labels))
+		}
+		for k, v := range m {
+			if _, ok := v.(string); !ok {
+				result = multierror.Append(result, fmt.Errorf("%s.%s.%s must be string, found %T", path, keyLabels, k, v))
+			}
+		}
+	}
+
+	// Validate EnabledSchedule, if present.
+	if schedule, ok := p[keyEnabledSchedule]; ok {
+		if err := validateBlock(schedule, path+"."+keyEnabledSchedule, validateSchedule); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
 	// Validate Target, if present.
 	if targetInterface, ok := p[keyTarget]; ok {
 		err := validateBlocks(targetInterface, path+"."+keyTarget, validateTarget)
@@ -114,6 +165,68 @@ func validatePolicy(p map[string]interface{}) error {
 	return result.ErrorOrNil()
 }
 
+// validateSchedule validates the enabled_schedule block within policy.
+//
+//	scaling {
+//	  policy {
+//	    enabled_schedule {
+//	    +-------------------------+
+//	    | timezone = "UTC"        |
+//	    | active_window { ... }   |
+//	    | inactive_window { ... } |
+//	    +-------------------------+
+//	    }
+//	  }
+//	}
+//
+// Validation rules:
+//  1. Timezone must be a string, if present.
+//  2. Each window must have a string start and a duration.
+//
+// The timezone and cron expressions are validated when the policy is
+// validated by the handler.
+func validateSchedule(s map[string]interface{}, path string) error {
+	var result *multierror.Error
+
+	if tz, ok := s[keyTimezone]; ok {
+		if _, ok := tz.(string); !ok {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be string, found %T", path, keyTimezone, tz))
+		}
+	}
+
+	for _, key := range []string{keyActiveWindow, keyInactiveWindow} {
+		ws, ok := s[key]
+		if !ok {
+			continue
+		}
+
+		list, ok := ws.([]interface{})
+		if !ok {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be []interface{}, found %T", path, key, ws))
+			continue
+		}
+
+		for i, w := range list {
+			windowPath := fmt.Sprintf("%s.%s[%d]", path, key, i)
+
+			windowMap, ok := w.(map[string]interface{})
+			if !ok {
+				result = multierror.Append(result, fmt.Errorf("%s must be map[string]interface{}, found %T", windowPath, w))
+				continue
+			}
+
+			if _, ok := windowMap[keyStart].(string); !ok {
+				result = multierror.Append(result, fmt.Errorf("%s.%s must be string, found %T", windowPath, keyStart, windowMap[keyStart]))
+			}
+			if err := validateDuration(windowMap[keyDuration], windowPath+"."+keyDuration); err != nil {
+				result = multierror.Append(result, err)
+			}
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
 // validateTarget validates target blocks within policy.
 //
 //	scaling {
@@ -203,6 +316,32 @@ func validateCheck(c map[string]interface{}, path string, label string) error {
 		}
 	}
 
+	// Validate Queries, if present.
+	//   1. Queries must be a map of strings.
+	queries, queriesOk := c[keyQueries]
+	if queriesOk {
+		m, ok := queries.(map[string]interface{})
+		if !ok {
+			m = parseBlock(queries)
+		}
+		if m == nil {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be a map, found %T", path, keyQueries, queries))
+		}
+		for k, v := range m {
+			if _, ok := v.(string); !ok {
+				result = multierror.Append(result, fmt.Errorf("%s.%s.%s must be string, found %T", path, keyQueries, k, v))
+			}
+		}
+	}
+
+	// Validate QueryExpression, if present.
+	//   1. QueryExpression must have string value.
+	if expr, ok := c[keyQueryExpression]; ok {
+		if _, ok := expr.(string); !ok {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be string, found %T", path, keyQueryExpression, expr))
+		}
+	}
+
 	// Validate QueryWindow, if present.
 	//   1. QueryWindow should be a valid time duration.
 	queryWindow, ok := c[keyQueryWindow]
@@ -212,9 +351,45 @@ func validateCheck(c map[string]interface{}, path string, label string) error {
 		}
 	}
 
+	// Validate the query timeout, retry and missing data settings, if
+	// present.
+	//   1. Durations should be valid time durations.
+	//   2. QueryRetries should be a whole number.
+	for _, key := range []string{keyQueryTimeout, keyQueryRetryBackoff, keyQueryRetryMaxBackoff, keyMissingDataMaxStale} {
+		if d, ok := c[key]; ok {
+			if err := validateDuration(d, path+"."+key); err != nil {
+				result = multierror.Append(result, err)
+			}
+		}
+	}
+	if retries, ok := c[keyQueryRetries]; ok {
+		if _, ok := parseInt(retries); !ok {
+			result = multierror.Append(result, fmt.Errorf("%s.%s must be a whole number, found %v", path, keyQueryRetries, retries))
+		}
+	}
+
+	// Validate Unit and MissingData, if present.
+	//   1. Unit and MissingData must have string values.
+	for _, key := range []string{keyUnit, keyMissingData} {
+		if v, ok := c[key]; ok {
+			if _, ok := v.(string); !ok {
+				result = multierror.Append(result, fmt.Errorf("%s.%s must be string, found %T", path, key, v))
+			}
+		}
+	}
+
+	// Validate Fallbacks, if present.
+	//   1. Fallbacks must be a list of blocks.
+	//   2. Source and Query must have string values.
+	if fs, ok := c[keyFallback]; ok {
+		if err := validateFallbacks(fs, path+"."+keyFallback); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
 	// Some strategy plugins do not require an APM
 	var strategyValidator validatorWithLabelFunc
-	if !queryOk && !sourceOk {
+	if !queryOk && !queriesOk && !sourceOk {
 		strategyValidator = validateStrategyWithoutMetric
 	}
 
@@ -234,6 +409,47 @@ func validateCheck(c map[string]interface{}, path string, label string) error {
 	return result.ErrorOrNil()
 }
 
+// validateFallbacks validates the fallback blocks within a policy check.
+//
+//	scaling {
+//	  policy {
+//	    check "check" {
+//	    +-----------------------+
+//	    | fallback {            |
+//	    |   source = "source"   |
+//	    |   query  = "query"    |
+//	    | }                     |
+//	    +-----------------------+
+//	    }
+//	  }
+//	}
+func validateFallbacks(fs interface{}, path string) error {
+	var result *multierror.Error
+
+	list, ok := fs.([]interface{})
+	if !ok {
+		return multierror.Append(result, fmt.Errorf("%s must be []interface{}, found %T", path, fs))
+	}
+
+	for i, f := range list {
+		fallbackPath := fmt.Sprintf("%s[%d]", path, i)
+
+		fallbackMap, ok := f.(map[string]interface{})
+		if !ok {
+			result = multierror.Append(result, fmt.Errorf("%s must be map[string]interface{}, found %T", fallbackPath, f))
+			continue
+		}
+
+		for _, key := range []string{keySource, keyQuery} {
+			if _, ok := fallbackMap[key].(string); !ok {
+				result = multierror.Append(result, fmt.Errorf("%s.%s must be string, found %T", fallbackPath, key, fallbackMap[key]))
+			}
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
 // validateStrategy validates strategy blocks within a policy check.
 //
 //	scaling {
@@ -258,7 +474,7 @@ func validateStrategy(s map[string]interface{}, path string, validator validator
 
 // validateStrategyWithoutMetric validates strategy block contents for strategies
 // that do not require an APM.
-// It is called for checks that do not have `source` nor `query`.
+// It is called for checks that do not have `source`, `query` nor `queries`.
 //
 //	scaling {
 //	  policy {
@@ -277,6 +277,11 @@ func Test_validateScalingPolicy(t *testing.T) {
 			inputFile:   "invalid-empty-query",
 			expectError: true,
 		},
+		{
+			name:        "policy.check.queries is valid",
+			inputFile:   "multi-query",
+			expectError: false,
+		},
 		{
 			name:        "policy.check.strategy is missing",
 			inputFile:   "missing-strategy",
@@ -287,6 +292,189 @@ func Test_validateScalingPolicy(t *testing.T) {
 			inputFile:   "strategy-without-metric",
 			expectError: false,
 		},
+		{
+			name:        "policy.check.fallback is valid",
+			inputFile:   "check-fallback",
+			expectError: false,
+		},
+		{
+			name: "policy.check.fallback.query has wrong type",
+			input: &api.ScalingPolicy{
+				ID:   "id",
+				Type: "horizontal",
+				Target: map[string]string{
+					"key": "value",
+				},
+				Min: ptr.Int64ToPtr(1),
+				Max: ptr.Int64ToPtr(5),
+				Policy: map[string]interface{}{
+					keyChecks: []interface{}{
+						map[string]interface{}{
+							"check": []interface{}{
+								map[string]interface{}{
+									keySource: "source",
+									keyQuery:  "query",
+									keyFallback: []interface{}{
+										map[string]interface{}{keySource: "fallback", keyQuery: 2},
+									},
+									keyStrategy: []interface{}{
+										map[string]interface{}{
+											"strategy": []interface{}{
+												map[string]interface{}{
+													"key": "value",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "policy.check.query_retries is not a whole number",
+			input: &api.ScalingPolicy{
+				ID:   "id",
+				Type: "horizontal",
+				Target: map[string]string{
+					"key": "value",
+				},
+				Min: ptr.Int64ToPtr(1),
+				Max: ptr.Int64ToPtr(5),
+				Policy: map[string]interface{}{
+					keyChecks: []interface{}{
+						map[string]interface{}{
+							"check": []interface{}{
+								map[string]interface{}{
+									keySource:       "source",
+									keyQuery:        "query",
+									keyQueryTimeout: "5s",
+									keyQueryRetries: 1.5,
+									keyStrategy: []interface{}{
+										map[string]interface{}{
+											"strategy": []interface{}{
+												map[string]interface{}{
+													"key": "value",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "policy.check.queries has wrong type",
+			input: &api.ScalingPolicy{
+				ID:   "id",
+				Type: "horizontal",
+				Target: map[string]string{
+					"key": "value",
+				},
+				Min: ptr.Int64ToPtr(1),
+				Max: ptr.Int64ToPtr(5),
+				Policy: map[string]interface{}{
+					keyChecks: []interface{}{
+						map[string]interface{}{
+							"check": []interface{}{
+								map[string]interface{}{
+									keySource:          "source",
+									keyQueries:         map[string]interface{}{"requests": 2},
+									keyQueryExpression: "requests",
+									keyStrategy: []interface{}{
+										map[string]interface{}{
+											"strategy": []interface{}{
+												map[string]interface{}{
+													"key": "value",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "policy.check.unit has wrong type",
+			input: &api.ScalingPolicy{
+				ID:   "id",
+				Type: "horizontal",
+				Target: map[string]string{
+					"key": "value",
+				},
+				Min: ptr.Int64ToPtr(1),
+				Max: ptr.Int64ToPtr(5),
+				Policy: map[string]interface{}{
+					keyChecks: []interface{}{
+						map[string]interface{}{
+							"check": []interface{}{
+								map[string]interface{}{
+									keySource: "source",
+									keyQuery:  "query",
+									keyUnit:   1024,
+									keyStrategy: []interface{}{
+										map[string]interface{}{
+											"strategy": []interface{}{
+												map[string]interface{}{
+													"key": "value",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "policy.check.missing_data_max_staleness is invalid",
+			input: &api.ScalingPolicy{
+				ID:   "id",
+				Type: "horizontal",
+				Target: map[string]string{
+					"key": "value",
+				},
+				Min: ptr.Int64ToPtr(1),
+				Max: ptr.Int64ToPtr(5),
+				Policy: map[string]interface{}{
+					keyChecks: []interface{}{
+						map[string]interface{}{
+							"check": []interface{}{
+								map[string]interface{}{
+									keySource:              "source",
+									keyQuery:               "query",
+									keyMissingDataMaxStale: "soon",
+									keyStrategy: []interface{}{
+										map[string]interface{}{
+											"strategy": []interface{}{
+												map[string]interface{}{
+													"key": "value",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
 		{
 			name: "policy.check.strategy.name is empty",
 			input: &api.ScalingPolicy{
@@ -463,6 +651,111 @@ func Test_validateScalingPolicy(t *testing.T) {
 			inputFile:   "invalid-cooldown",
 			expectError: true,
 		},
+		{
+			name: "policy.dry_run has wrong type",
+			input: &api.ScalingPolicy{
+				ID:   "id",
+				Type: "horizontal",
+				Target: map[string]string{
+					"key": "value",
+				},
+				Min: ptr.Int64ToPtr(1),
+				Max: ptr.Int64ToPtr(5),
+				Policy: map[string]interface{}{
+					keyDryRun: "true",
+					keyChecks: []interface{}{
+						map[string]interface{}{
+							"check": []interface{}{
+								map[string]interface{}{
+									keySource: "source",
+									keyQuery:  "query",
+									keyStrategy: []interface{}{
+										map[string]interface{}{
+											"strategy": []interface{}{
+												map[string]interface{}{
+													"key": "value",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "policy.depends_on has wrong element type",
+			input: &api.ScalingPolicy{
+				ID:   "id",
+				Type: "horizontal",
+				Target: map[string]string{
+					"key": "value",
+				},
+				Min: ptr.Int64ToPtr(1),
+				Max: ptr.Int64ToPtr(5),
+				Policy: map[string]interface{}{
+					keyDependsOn: []interface{}{"cluster", 1},
+					keyChecks: []interface{}{
+						map[string]interface{}{
+							"check": []interface{}{
+								map[string]interface{}{
+									keySource: "source",
+									keyQuery:  "query",
+									keyStrategy: []interface{}{
+										map[string]interface{}{
+											"strategy": []interface{}{
+												map[string]interface{}{
+													"key": "value",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "policy.labels has wrong value type",
+			input: &api.ScalingPolicy{
+				ID:   "id",
+				Type: "horizontal",
+				Target: map[string]string{
+					"key": "value",
+				},
+				Min: ptr.Int64ToPtr(1),
+				Max: ptr.Int64ToPtr(5),
+				Policy: map[string]interface{}{
+					keyLabels: map[string]interface{}{"team": 1},
+					keyChecks: []interface{}{
+						map[string]interface{}{
+							"check": []interface{}{
+								map[string]interface{}{
+									keySource: "source",
+									keyQuery:  "query",
+									keyStrategy: []interface{}{
+										map[string]interface{}{
+											"strategy": []interface{}{
+												map[string]interface{}{
+													"key": "value",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,290 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+const (
+	// PolicyChangeAdd is used when a policy is first observed.
+	PolicyChangeAdd = "add"
+
+	// PolicyChangeUpdate is used when a new version of a known policy is
+	// observed.
+	PolicyChangeUpdate = "update"
+
+	// PolicyChangeDelete is used when a policy is removed from its source.
+	PolicyChangeDelete = "delete"
+
+	// defaultWebhookTimeout is the HTTP timeout used when sending webhook
+	// notifications if none is configured.
+	defaultWebhookTimeout = 10 * time.Second
+
+	// webhookQueueSize is the number of notifications that can be pending
+	// before new ones are dropped.
+	webhookQueueSize = 64
+)
+
+// PolicyChangeEvent describes a change to a policy observed by the policy
+// manager.
+type PolicyChangeEvent struct {
+	Type      string     `json:"type"`
+	PolicyID  PolicyID   `json:"policy_id"`
+	Source    SourceName `json:"source"`
+	Timestamp time.Time  `json:"timestamp"`
+
+	// Changes is a summary of the policy fields which changed. It is only
+	// populated for update events.
+	Changes []string `json:"changes,omitempty"`
+}
+
+// ChangeNotifier sends a JSON payload describing each policy change to a
+// configured webhook address. A nil ChangeNotifier is safe to use and does
+// not send notifications.
+type ChangeNotifier struct {
+	log     hclog.Logger
+	address string
+	headers map[string]string
+	client  *http.Client
+	eventCh chan *PolicyChangeEvent
+}
+
+// NewChangeNotifier returns a new ChangeNotifier which sends notifications to
+// address. Notifications are only sent once Run is called.
+func NewChangeNotifier(log hclog.Logger, address string, headers map[string]string, timeout time.Duration) *ChangeNotifier {
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	return &ChangeNotifier{
+		log:     log.Named("policy_change_notifier"),
+		address: address,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+		eventCh: make(chan *PolicyChangeEvent, webhookQueueSize),
+	}
+}
+
+// Run sends queued notifications until the context is canceled.
+func (n *ChangeNotifier) Run(ctx context.Context) {
+	if n == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-n.eventCh:
+			if err := n.send(ctx, e); err != nil {
+				n.log.Warn("failed to send policy change notification",
+					"policy_id", e.PolicyID, "type", e.Type, "error", err)
+				metrics.IncrCounter([]string{"policy", "webhook", "error_count"}, 1)
+				continue
+			}
+			metrics.IncrCounter([]string{"policy", "webhook", "success_count"}, 1)
+		}
+	}
+}
+
+// Notify queues a notification for the passed change. The changes summary,
+// as returned by policyChanges, is only used for update events. The
+// notification is dropped if too many are already pending so policy handling
+// is never blocked by a slow webhook.
+func (n *ChangeNotifier) Notify(changeType string, id PolicyID, source SourceName, changes []string) {
+	if n == nil {
+		return
+	}
+
+	e := &PolicyChangeEvent{
+		Type:      changeType,
+		PolicyID:  id,
+		Source:    source,
+		Timestamp: time.Now().UTC(),
+	}
+	if changeType == PolicyChangeUpdate {
+		e.Changes = changes
+	}
+
+	select {
+	case n.eventCh <- e:
+	default:
+		n.log.Warn("policy change notification queue full, dropping notification",
+			"policy_id", id, "type", changeType)
+		metrics.IncrCounter([]string{"policy", "webhook", "dropped_count"}, 1)
+	}
+}
+
+func (n *ChangeNotifier) send(ctx context.Context, e *PolicyChangeEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// policyChanges returns a summary of the fields that differ between the two
+// passed policies, in a stable order. Changes to the target and checks are
+// reported per field, including each changed key of their config maps, but
+// without the config and query values.
+func policyChanges(old, new *sdk.ScalingPolicy) []string {
+	if old == nil || new == nil {
+		return nil
+	}
+
+	var changes []string
+	add := func(field string, o, n interface{}) {
+		if !reflect.DeepEqual(o, n) {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", field, o, n))
+		}
+	}
+
+	// Queries and plugin config can hold credentials, so only the name of
+	// changed fields is reported.
+	changed := func(field string, o, n string) {
+		if o != n {
+			changes = append(changes, field+" changed")
+		}
+	}
+	addConfig := func(field string, o, n map[string]string) {
+		for _, k := range sortedKeys(o, n) {
+			ov, inOld := o[k]
+			nv, inNew := n[k]
+			switch {
+			case !inOld:
+				changes = append(changes, fmt.Sprintf("%s.%s added", field, k))
+			case !inNew:
+				changes = append(changes, fmt.Sprintf("%s.%s removed", field, k))
+			default:
+				changed(field+"."+k, ov, nv)
+			}
+		}
+	}
+
+	add("enabled", old.Enabled, new.Enabled)
+	add("min", old.Min, new.Min)
+	add("max", old.Max, new.Max)
+	add("cooldown", old.Cooldown, new.Cooldown)
+	add("cooldown_group", old.CooldownGroup, new.CooldownGroup)
+	add("evaluation_interval", old.EvaluationInterval, new.EvaluationInterval)
+	add("on_check_error", old.OnCheckError, new.OnCheckError)
+	add("dry_run", old.DryRun, new.DryRun)
+	add("labels", old.Labels, new.Labels)
+
+	if !reflect.DeepEqual(old.EnabledSchedule, new.EnabledSchedule) {
+		changes = append(changes, "enabled_schedule changed")
+	}
+
+	switch {
+	case reflect.DeepEqual(old.Target, new.Target):
+	case old.Target == nil:
+		changes = append(changes, "target added")
+	case new.Target == nil:
+		changes = append(changes, "target removed")
+	default:
+		add("target.name", old.Target.Name, new.Target.Name)
+		addConfig("target.config", old.Target.Config, new.Target.Config)
+		if !reflect.DeepEqual(old.Target.SubTargets, new.Target.SubTargets) {
+			changes = append(changes, "target.sub_targets changed")
+		}
+	}
+
+	oldChecks := make(map[string]*sdk.ScalingPolicyCheck, len(old.Checks))
+	for _, c := range old.Checks {
+		oldChecks[c.Name] = c
+	}
+	newChecks := make(map[string]*sdk.ScalingPolicyCheck, len(new.Checks))
+	for _, c := range new.Checks {
+		newChecks[c.Name] = c
+	}
+
+	for _, name := range sortedKeys(oldChecks, newChecks) {
+		oc, inOld := oldChecks[name]
+		c, inNew := newChecks[name]
+
+		switch {
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("check %s added", name))
+			continue
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("check %s removed", name))
+			continue
+		case reflect.DeepEqual(oc, c):
+			continue
+		}
+
+		// Report the changed fields, falling back to reporting the check as
+		// a whole if the change is to a field not listed here.
+		n := len(changes)
+		prefix := "check " + name
+		add(prefix+" source", oc.Source, c.Source)
+		changed(prefix+" query", oc.Query, c.Query)
+		addConfig(prefix+" queries", oc.Queries, c.Queries)
+		changed(prefix+" query_expression", oc.QueryExpression, c.QueryExpression)
+
+		switch {
+		case reflect.DeepEqual(oc.Strategy, c.Strategy):
+		case oc.Strategy == nil || c.Strategy == nil:
+			changes = append(changes, prefix+" strategy changed")
+		default:
+			add(prefix+" strategy", oc.Strategy.Name, c.Strategy.Name)
+			addConfig(prefix+" strategy.config", oc.Strategy.Config, c.Strategy.Config)
+		}
+
+		if len(changes) == n {
+			changes = append(changes, fmt.Sprintf("check %s changed", name))
+		}
+	}
+
+	return changes
+}
+
+// sortedKeys returns the union of the keys of the passed maps, sorted so
+// changes are always reported in the same order.
+func sortedKeys[V any](maps ...map[string]V) []string {
+	seen := make(map[string]struct{})
+	for _, m := range maps {
+		for k := range m {
+			seen[k] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,175 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeNotifier(t *testing.T) {
+	eventCh := make(chan *PolicyChangeEvent, 3)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var e PolicyChangeEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		eventCh <- &e
+	}))
+	defer srv.Close()
+
+	n := NewChangeNotifier(hclog.NewNullLogger(), srv.URL, map[string]string{"Authorization": "Bearer secret"}, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go n.Run(ctx)
+
+	n.Notify(PolicyChangeAdd, "a", SourceNameNomad, []string{"ignored"})
+	n.Notify(PolicyChangeUpdate, "a", SourceNameNomad, []string{"min: 1 -> 2"})
+	n.Notify(PolicyChangeDelete, "a", SourceNameNomad, nil)
+
+	expected := []struct {
+		changeType string
+		changes    []string
+	}{
+		{PolicyChangeAdd, nil},
+		{PolicyChangeUpdate, []string{"min: 1 -> 2"}},
+		{PolicyChangeDelete, nil},
+	}
+
+	for _, exp := range expected {
+		select {
+		case e := <-eventCh:
+			assert.Equal(t, exp.changeType, e.Type)
+			assert.Equal(t, PolicyID("a"), e.PolicyID)
+			assert.Equal(t, SourceNameNomad, e.Source)
+			assert.Equal(t, exp.changes, e.Changes)
+			assert.False(t, e.Timestamp.IsZero())
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for notification")
+		}
+	}
+}
+
+func TestChangeNotifier_nil(t *testing.T) {
+	var n *ChangeNotifier
+	assert.NotPanics(t, func() {
+		n.Notify(PolicyChangeAdd, "a", SourceNameNomad, nil)
+		n.Run(context.Background())
+	})
+}
+
+func Test_policyChanges(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old      *sdk.ScalingPolicy
+		new      *sdk.ScalingPolicy
+		expected []string
+	}{
+		{
+			name:     "nil policy",
+			old:      nil,
+			new:      &sdk.ScalingPolicy{},
+			expected: nil,
+		},
+		{
+			name:     "no changes",
+			old:      &sdk.ScalingPolicy{Min: 1, Max: 2},
+			new:      &sdk.ScalingPolicy{Min: 1, Max: 2},
+			expected: nil,
+		},
+		{
+			name: "fields and checks changed",
+			old: &sdk.ScalingPolicy{
+				Enabled:  true,
+				Max:      10,
+				Cooldown: time.Minute,
+				Checks: []*sdk.ScalingPolicyCheck{
+					{Name: "cpu", Query: "avg_cpu"},
+					{Name: "mem", Query: "avg_mem"},
+				},
+			},
+			new: &sdk.ScalingPolicy{
+				Enabled:  false,
+				Max:      20,
+				Cooldown: time.Minute,
+				Target:   &sdk.ScalingPolicyTarget{Name: "nomad-target"},
+				Checks: []*sdk.ScalingPolicyCheck{
+					{Name: "cpu", Query: "max_cpu"},
+					{Name: "queue", Query: "queue_depth"},
+				},
+			},
+			expected: []string{
+				"enabled: true -> false",
+				"max: 10 -> 20",
+				"target added",
+				"check cpu query changed",
+				"check mem removed",
+				"check queue added",
+			},
+		},
+		{
+			name: "target and check fields changed",
+			old: &sdk.ScalingPolicy{
+				Target: &sdk.ScalingPolicyTarget{
+					Name:   "aws-asg",
+					Config: map[string]string{"aws_asg_name": "web", "node_class": "web", "dry-run": "true"},
+				},
+				Checks: []*sdk.ScalingPolicyCheck{
+					{
+						Name:     "cpu",
+						Source:   "nomad-apm",
+						Query:    "avg_cpu",
+						Strategy: &sdk.ScalingPolicyStrategy{Name: "target-value", Config: map[string]string{"target": "70"}},
+					},
+					{Name: "mem", Query: "avg_mem", QueryWindow: time.Minute},
+				},
+			},
+			new: &sdk.ScalingPolicy{
+				Target: &sdk.ScalingPolicyTarget{
+					Name:   "aws-asg",
+					Config: map[string]string{"aws_asg_name": "api", "node_class": "web", "node_drain_deadline": "5m"},
+				},
+				Checks: []*sdk.ScalingPolicyCheck{
+					{Name: "mem", Query: "avg_mem", QueryWindow: 5 * time.Minute},
+					{
+						Name:     "cpu",
+						Source:   "prometheus",
+						Query:    "avg_cpu",
+						Strategy: &sdk.ScalingPolicyStrategy{Name: "target-value", Config: map[string]string{"target": "80"}},
+					},
+				},
+			},
+			expected: []string{
+				"target.config.aws_asg_name changed",
+				"target.config.dry-run removed",
+				"target.config.node_drain_deadline added",
+				"check cpu source: nomad-apm -> prometheus",
+				"check cpu strategy.config.target changed",
+				"check mem changed",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Changes must be reported in the same order regardless of the
+			// map iteration order.
+			for i := 0; i < 10; i++ {
+				assert.Equal(t, tc.expected, policyChanges(tc.old, tc.new))
+			}
+		})
+	}
+}
@@ -39,6 +39,17 @@ func (pr *Processor) ApplyPolicyDefaults(p *sdk.ScalingPolicy) {
 	if p.EvaluationInterval == 0 {
 		p.EvaluationInterval = pr.defaults.DefaultEvaluationInterval
 	}
+	if p.OnCheckError == "" {
+		p.OnCheckError = pr.defaults.DefaultOnCheckError
+	}
+	if p.MinUnset {
+		p.Min = pr.defaults.DefaultMin
+		p.MinUnset = false
+	}
+	if p.MaxUnset && pr.defaults.DefaultMax != 0 {
+		p.Max = pr.defaults.DefaultMax
+		p.MaxUnset = false
+	}
 
 	for i := 0; i < len(p.Checks); i++ {
 		c := p.Checks[i]
@@ -60,11 +71,18 @@ func (pr *Processor) ValidatePolicy(p *sdk.ScalingPolicy) error {
 	if p.Min < 0 {
 		mErr = multierror.Append(mErr, errors.New("policy Min can't be negative"))
 	}
-	if p.Max < 0 {
-		mErr = multierror.Append(mErr, errors.New("policy Max can't be negative"))
-	}
-	if p.Min > p.Max {
-		mErr = multierror.Append(mErr, errors.New("policy Min must not be greater Max"))
+
+	// Max is required unless the agent is configured with a default, as an
+	// omitted value would otherwise scale the target to zero.
+	if p.MaxUnset {
+		mErr = multierror.Append(mErr, errors.New("policy Max is required"))
+	} else {
+		if p.Max < 0 {
+			mErr = multierror.Append(mErr, errors.New("policy Max can't be negative"))
+		}
+		if p.Min > p.Max {
+			mErr = multierror.Append(mErr, errors.New("policy Min must not be greater Max"))
+		}
 	}
 
 	return mErr.ErrorOrNil()
@@ -92,35 +110,59 @@ func (pr *Processor) CanonicalizeAPMQuery(c *sdk.ScalingPolicyCheck, t *sdk.Scal
 		return
 	}
 
+	// Fallbacks may use a Nomad APM even if the primary source does not.
+	for _, f := range c.Fallbacks {
+		if pr.isNomadAPMQuery(f.Source) {
+			f.Query = canonicalizeNomadAPMQuery(f.Query, t)
+		}
+	}
+
 	// If the query source is not a Nomad APM, we do not have any additional
 	// work to perform. The APM canonicalization is specific to the Nomad APM.
 	if !pr.isNomadAPMQuery(c.Source) {
 		return
 	}
 
+	c.Query = canonicalizeNomadAPMQuery(c.Query, t)
+	for name, q := range c.Queries {
+		c.Queries[name] = canonicalizeNomadAPMQuery(q, t)
+	}
+}
+
+// canonicalizeNomadAPMQuery expands a single short styled Nomad APM query
+// using the target configuration.
+func canonicalizeNomadAPMQuery(q string, t *sdk.ScalingPolicyTarget) string {
+
 	// If the query is not formatted in the short manner we do not have any
 	// work to do. Operators can add this if they want/know the autoscaler
 	// internal model.
-	if !isShortQuery(c.Query) {
-		return
+	if !isShortQuery(q) {
+		return q
 	}
 
 	// If the target is a Nomad job task group, format the query in the
 	// expected manner.
 	if t.IsJobTaskGroupTarget() {
-		c.Query = fmt.Sprintf("%s_%s/%s/%s",
-			nomadAPM.QueryTypeTaskGroup, c.Query, t.Config[sdk.TargetConfigKeyTaskGroup], t.Config[sdk.TargetConfigKeyJob])
-		return
+		return fmt.Sprintf("%s_%s/%s/%s",
+			nomadAPM.QueryTypeTaskGroup, q, t.Config[sdk.TargetConfigKeyTaskGroup], t.Config[sdk.TargetConfigKeyJob])
 	}
 
 	// If the target is a Nomad client node pool, format the query in the
-	// expected manner. Once the autoscaler supports more than just class
-	// identification of pools this func and logic will need to be updated. For
-	// now keep it simple.
+	// expected manner, including each of the configured pool identifiers.
 	if t.IsNodePoolTarget() {
-		c.Query = fmt.Sprintf("%s_%s/%s/class",
-			nomadAPM.QueryTypeNode, c.Query, t.Config[sdk.TargetConfigKeyClass])
+		query := fmt.Sprintf("%s_%s", nomadAPM.QueryTypeNode, q)
+		for _, id := range []struct{ configKey, queryKey string }{
+			{sdk.TargetConfigKeyClass, "class"},
+			{sdk.TargetConfigKeyDatacenter, "datacenter"},
+			{sdk.TargetConfigKeyNodePool, "node_pool"},
+		} {
+			if v, ok := t.Config[id.configKey]; ok {
+				query += "/" + v + "/" + id.queryKey
+			}
+		}
+		return query
 	}
+	return q
 }
 
 // isNomadAPMQuery helps identify whether the policy query is aligned with a
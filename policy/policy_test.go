@@ -81,6 +81,19 @@ func TestProcessor_ValidatePolicy(t *testing.T) {
 			},
 			name: "negative maximum value which is lower than minimum",
 		},
+		{
+			inputPolicy: &sdk.ScalingPolicy{
+				ID:       "ce888afe-3dd2-144c-7227-74644434f708",
+				Min:      1,
+				MaxUnset: true,
+			},
+			expectedOutput: &multierror.Error{
+				Errors: []error{
+					errors.New("policy Max is required"),
+				},
+			},
+			name: "maximum not set without default",
+		},
 		{
 			inputPolicy: &sdk.ScalingPolicy{
 				ID:  "ce888afe-3dd2-144c-7227-74644434f708",
@@ -204,6 +217,23 @@ func TestProcessor_CanonicalizeAPMQuery(t *testing.T) {
 			},
 			name: "correctly formatted node target short query",
 		},
+		{
+			inputCheck: &sdk.ScalingPolicyCheck{
+				Name:   "random-check",
+				Source: "nomad-apm",
+				Query:  "percentage-allocated_cpu",
+			},
+			inputAPMNames: []string{"nomad-apm"},
+			inputTarget: &sdk.ScalingPolicyTarget{
+				Config: map[string]string{"node_class": "hashistack", "node_pool": "gpu"},
+			},
+			expectedOutputCheck: &sdk.ScalingPolicyCheck{
+				Name:   "random-check",
+				Source: "nomad-apm",
+				Query:  "node_percentage-allocated_cpu/hashistack/class/gpu/node_pool",
+			},
+			name: "correctly formatted node pool target short query",
+		},
 		{
 			inputCheck: &sdk.ScalingPolicyCheck{
 				Name:   "random-check",
@@ -238,6 +268,56 @@ func TestProcessor_CanonicalizeAPMQuery(t *testing.T) {
 			},
 			name: "incorrectly formatted node target short query",
 		},
+		{
+			inputCheck: &sdk.ScalingPolicyCheck{
+				Name:   "random-check",
+				Source: "nomad-apm",
+				Queries: map[string]string{
+					"cpu":    "avg_cpu",
+					"memory": "taskgroup_avg_memory/cache/example",
+				},
+				QueryExpression: "cpu + memory",
+			},
+			inputAPMNames: []string{"nomad-apm"},
+			inputTarget: &sdk.ScalingPolicyTarget{
+				Config: map[string]string{"Job": "example", "Group": "cache"},
+			},
+			expectedOutputCheck: &sdk.ScalingPolicyCheck{
+				Name:   "random-check",
+				Source: "nomad-apm",
+				Queries: map[string]string{
+					"cpu":    "taskgroup_avg_cpu/cache/example",
+					"memory": "taskgroup_avg_memory/cache/example",
+				},
+				QueryExpression: "cpu + memory",
+			},
+			name: "correctly formatted taskgroup target short named queries",
+		},
+		{
+			inputCheck: &sdk.ScalingPolicyCheck{
+				Name:   "random-check",
+				Source: "prometheus",
+				Query:  "scalar(super-data-point)",
+				Fallbacks: []*sdk.ScalingPolicyCheckFallback{
+					{Source: "nomad-apm", Query: "avg_cpu"},
+					{Source: "cloudwatch", Query: "avg_cpu"},
+				},
+			},
+			inputAPMNames: []string{"nomad-apm"},
+			inputTarget: &sdk.ScalingPolicyTarget{
+				Config: map[string]string{"Job": "example", "Group": "cache"},
+			},
+			expectedOutputCheck: &sdk.ScalingPolicyCheck{
+				Name:   "random-check",
+				Source: "prometheus",
+				Query:  "scalar(super-data-point)",
+				Fallbacks: []*sdk.ScalingPolicyCheckFallback{
+					{Source: "nomad-apm", Query: "taskgroup_avg_cpu/cache/example"},
+					{Source: "cloudwatch", Query: "avg_cpu"},
+				},
+			},
+			name: "nomad apm fallback short query",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -311,6 +391,54 @@ func TestProcessor_ApplyPolicyDefaults(t *testing.T) {
 			},
 			name: "neither set to default",
 		},
+		{
+			inputPolicy: &sdk.ScalingPolicy{
+				Max:      20,
+				MinUnset: true,
+				Checks:   []*sdk.ScalingPolicyCheck{{Name: "cpu"}},
+			},
+			inputDefaults: &ConfigDefaults{
+				DefaultEvaluationInterval: 5 * time.Second,
+				DefaultCooldown:           10 * time.Second,
+				DefaultOnCheckError:       sdk.ScalingPolicyOnErrorIgnore,
+				DefaultMin:                2,
+				DefaultMax:                10,
+			},
+			expectedOutputPolicy: &sdk.ScalingPolicy{
+				Cooldown:           10 * time.Second,
+				EvaluationInterval: 5 * time.Second,
+				OnCheckError:       sdk.ScalingPolicyOnErrorIgnore,
+				Min:                2,
+				Max:                20,
+				Checks:             []*sdk.ScalingPolicyCheck{{Name: "cpu", QueryWindow: DefaultQueryWindow}},
+			},
+			name: "policy defaults merged unless overridden",
+		},
+		{
+			inputPolicy: &sdk.ScalingPolicy{
+				MaxUnset: true,
+			},
+			inputDefaults: &ConfigDefaults{
+				DefaultMin: 2,
+				DefaultMax: 10,
+			},
+			expectedOutputPolicy: &sdk.ScalingPolicy{
+				Min: 0,
+				Max: 10,
+			},
+			name: "explicit zero min not overridden",
+		},
+		{
+			inputPolicy: &sdk.ScalingPolicy{
+				MinUnset: true,
+				MaxUnset: true,
+			},
+			inputDefaults: &ConfigDefaults{},
+			expectedOutputPolicy: &sdk.ScalingPolicy{
+				MaxUnset: true,
+			},
+			name: "no max default configured",
+		},
 	}
 
 	for _, tc := range testCases {
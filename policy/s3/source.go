@@ -0,0 +1,497 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	hclog "github.com/hashicorp/go-hclog"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	filePolicy "github.com/hashicorp/nomad-autoscaler/policy/file"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/uuid"
+	"github.com/mitchellh/copystructure"
+)
+
+const (
+	// configKeys are the keys which can be set within the policy source
+	// config block.
+	configKeyBucket          = "bucket"
+	configKeyPrefix          = "prefix"
+	configKeyRegion          = "region"
+	configKeyEndpoint        = "endpoint"
+	configKeyForcePathStyle  = "force_path_style"
+	configKeySyncInterval    = "sync_interval"
+	configKeyAccessID        = "aws_access_key_id"
+	configKeySecretKey       = "aws_secret_access_key"
+	configKeySessionToken    = "aws_session_token"
+	configValueRegionDefault = "us-east-1"
+
+	// defaultSyncInterval is the interval at which the bucket is listed to
+	// detect policy changes when the operator does not configure one.
+	defaultSyncInterval = time.Minute
+)
+
+// Ensure Source satisfies the Source interface.
+var _ policy.Source = (*Source)(nil)
+
+// objectClient is the subset of the S3 API used by the policy source. It
+// exists so tests can supply a fake implementation.
+type objectClient interface {
+	ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// Source is the S3 implementation of the policy.Source interface. Policies
+// are stored as HCL or JSON objects within a bucket, using the same format as
+// the file policy source.
+type Source struct {
+	bucket          string
+	prefix          string
+	syncInterval    time.Duration
+	client          objectClient
+	log             hclog.Logger
+	policyProcessor *policy.Processor
+
+	// verifier, if set, is used to check the detached signature of each
+	// policy object before it is decoded.
+	verifier policy.SignatureVerifier
+
+	// reloadChannels help coordinate reloading the of the MonitorIDs routine.
+	reloadCh         chan struct{}
+	reloadCompleteCh chan struct{}
+
+	// objects maps an object key to the last seen version of that object.
+	// The ETag is used to avoid downloading and decoding objects which have
+	// not changed since the last sync.
+	objects map[string]*object
+
+	// idMap stores a mapping between the object key and policy name, and the
+	// associated policyID. This allows us to keep a consistent PolicyID in
+	// the event of policy changes.
+	idMap map[string]policy.PolicyID
+
+	// policyMap maps our policyID to the latest policy decoded from the
+	// bucket.
+	policyMap map[policy.PolicyID]*objectPolicy
+
+	// lock protects the maps above, which are written by the MonitorIDs
+	// routine and read by each MonitorPolicy routine.
+	lock sync.RWMutex
+}
+
+// object is the decoded content of a single bucket object.
+type object struct {
+	etag     string
+	policies map[string]*sdk.ScalingPolicy
+}
+
+// objectPolicy is a wrapper around a scaling policy that also provides the
+// object key and name that it came from.
+type objectPolicy struct {
+	key    string
+	name   string
+	policy *sdk.ScalingPolicy
+}
+
+// NewS3Source returns a new S3 policy source using the provided config
+// mapping to build the S3 client.
+func NewS3Source(log hclog.Logger, config map[string]string, policyProcessor *policy.Processor) (policy.Source, error) {
+
+	bucket := config[configKeyBucket]
+	if bucket == "" {
+		return nil, fmt.Errorf("%q config value is required", configKeyBucket)
+	}
+
+	syncInterval := defaultSyncInterval
+	if v, ok := config[configKeySyncInterval]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", configKeySyncInterval, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%q must be greater than 0", configKeySyncInterval)
+		}
+		syncInterval = d
+	}
+
+	verifier, err := policy.NewSignatureVerifier(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSource(log, bucket, config[configKeyPrefix], syncInterval, client, policyProcessor)
+	s.verifier = verifier
+	return s, nil
+}
+
+func newSource(log hclog.Logger, bucket, prefix string, syncInterval time.Duration,
+	client objectClient, policyProcessor *policy.Processor) *Source {
+	return &Source{
+		bucket:           bucket,
+		prefix:           prefix,
+		syncInterval:     syncInterval,
+		client:           client,
+		log:              log.ResetNamed("s3_policy_source"),
+		policyProcessor:  policyProcessor,
+		reloadCh:         make(chan struct{}),
+		reloadCompleteCh: make(chan struct{}, 1),
+		objects:          make(map[string]*object),
+		idMap:            make(map[string]policy.PolicyID),
+		policyMap:        make(map[policy.PolicyID]*objectPolicy),
+	}
+}
+
+// newClient builds the S3 client from the default AWS config, overridden by
+// the values from the passed config mapping.
+func newClient(config map[string]string) (*s3.Client, error) {
+
+	// Load our default AWS config. This handles pulling configuration from
+	// default profiles and environment variables.
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load default AWS config: %v", err)
+	}
+
+	if region, ok := config[configKeyRegion]; ok {
+		cfg.Region = region
+	}
+	if cfg.Region == "" {
+		cfg.Region = configValueRegionDefault
+	}
+
+	// Static credentials require both the access key and secret key; the
+	// session token is optional.
+	keyID := config[configKeyAccessID]
+	secretKey := config[configKeySecretKey]
+	if keyID != "" && secretKey != "" {
+		cfg.Credentials = credentials.NewStaticCredentialsProvider(keyID, secretKey, config[configKeySessionToken])
+	}
+
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := config[configKeyEndpoint]; endpoint != "" {
+			o.EndpointResolver = s3.EndpointResolverFromURL(endpoint)
+		}
+		o.UsePathStyle = config[configKeyForcePathStyle] == "true"
+	}), nil
+}
+
+// Name satisfies the Name function of the policy.Source interface.
+func (s *Source) Name() policy.SourceName {
+	return policy.SourceNameS3
+}
+
+// MonitorIDs satisfies the MonitorIDs function of the policy.Source interface.
+func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
+	s.log.Debug("starting s3 policy source ID monitor")
+
+	// Perform the first sync before entering the loop, otherwise we wouldn't
+	// load any policies until the first tick.
+	s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Trace("stopping s3 policy source ID monitor")
+			return
+
+		case <-ticker.C:
+			s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
+
+		case <-s.reloadCh:
+			s.log.Info("s3 policy source ID monitor received reload signal")
+			s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
+			s.reloadCompleteCh <- struct{}{}
+		}
+	}
+}
+
+// ReloadIDsMonitor satisfies the ReloadIDsMonitor function of the
+// policy.Source interface.
+func (s *Source) ReloadIDsMonitor() {
+	s.reloadCh <- struct{}{}
+	<-s.reloadCompleteCh
+}
+
+// MonitorPolicy satisfies the MonitorPolicy function of the policy.Source
+// interface. The bucket is synced by the MonitorIDs routine, so this only
+// needs to check whether the stored policy has changed since it was last
+// sent.
+func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq) {
+
+	// Close channels when done with the monitoring loop.
+	defer close(req.ResultCh)
+	defer close(req.ErrCh)
+
+	log := s.log.With("policy_id", req.ID)
+
+	var last *sdk.ScalingPolicy
+
+	sendIfChanged := func() {
+		s.lock.RLock()
+		val, ok := s.policyMap[req.ID]
+		s.lock.RUnlock()
+
+		if !ok || val.policy == nil {
+			policy.HandleSourceError(s.Name(), fmt.Errorf("failed to get policy %s", req.ID), req.ErrCh)
+			return
+		}
+		if last != nil && reflect.DeepEqual(last, val.policy) {
+			return
+		}
+		if last != nil {
+			log.Info("s3 policy content has changed", "key", val.key, "name", val.name)
+		}
+		last = val.policy
+		req.ResultCh <- *val.policy
+	}
+
+	sendIfChanged()
+	log.Info("starting s3 policy monitor")
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("stopping s3 policy monitor due to context done")
+			return
+
+		case <-ticker.C:
+			sendIfChanged()
+
+		case <-req.ReloadCh:
+			log.Info("s3 policy source monitor received reload signal")
+			sendIfChanged()
+		}
+	}
+}
+
+// identifyPolicyIDs syncs the bucket and sends the discovered policy IDs to
+// the resultCh so the policy manager can do its work.
+func (s *Source) identifyPolicyIDs(ctx context.Context, resultCh chan<- policy.IDMessage, errCh chan<- error) {
+	ids, err := s.sync(ctx)
+	if err != nil {
+		policy.HandleSourceError(s.Name(), err, errCh)
+	}
+
+	// Even if we receive an error we may have IDs to send. Otherwise it may be
+	// that all policies have been removed so we should even send the empty
+	// list so handlers can be cleaned.
+	resultCh <- policy.IDMessage{IDs: ids, Source: s.Name()}
+}
+
+// sync lists the configured bucket prefix, downloading and decoding any
+// policy object whose ETag has changed since the last sync. If the listing
+// itself fails, the previously known policies are returned so that handlers
+// are not stopped due to a transient error.
+func (s *Source) sync(ctx context.Context) ([]policy.PolicyID, error) {
+
+	keys, err := s.listKeys(ctx)
+	if err != nil {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		return s.knownIDs(), fmt.Errorf("failed to list objects in bucket %s: %v", s.bucket, err)
+	}
+
+	var mErr *multierror.Error
+	objects := make(map[string]*object, len(keys))
+
+	for key, etag := range keys {
+		s.lock.RLock()
+		existing, ok := s.objects[key]
+		s.lock.RUnlock()
+
+		if ok && existing.etag == etag {
+			objects[key] = existing
+			continue
+		}
+
+		policies, err := s.readObject(ctx, key)
+		if err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to decode object %s: %v", key, err))
+
+			// Retain the previous version of the object so that a bad
+			// upload doesn't remove a working policy.
+			if ok {
+				objects[key] = existing
+			}
+			continue
+		}
+		objects[key] = &object{etag: etag, policies: policies}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.objects = objects
+	policyMap := make(map[policy.PolicyID]*objectPolicy)
+
+	for key, obj := range objects {
+		for name, p := range obj.policies {
+			policyID := s.getObjectPolicyID(key, name)
+
+			// Ignore the policy if its disabled.
+			if !p.Enabled {
+				s.log.Trace("policy is disabled therefore ignoring", "policy_id", policyID, "key", key)
+				continue
+			}
+
+			// Work on a copy so the cached object remains untouched by the
+			// processor.
+			cp, err := copystructure.Copy(p)
+			if err != nil {
+				mErr = multierror.Append(mErr, fmt.Errorf("failed to copy policy from object %s: %v", key, err))
+				continue
+			}
+			scalingPolicy := cp.(*sdk.ScalingPolicy)
+			scalingPolicy.ID = policyID.String()
+			s.policyProcessor.ApplyPolicyDefaults(scalingPolicy)
+
+			if err := s.policyProcessor.ValidatePolicy(scalingPolicy); err != nil {
+				mErr = multierror.Append(mErr, fmt.Errorf("failed to validate object %s: %v", key, err))
+				continue
+			}
+
+			for _, c := range scalingPolicy.Checks {
+				s.policyProcessor.CanonicalizeCheck(c, scalingPolicy.Target)
+			}
+
+			policyMap[policyID] = &objectPolicy{key: key, name: name, policy: scalingPolicy}
+		}
+	}
+	s.policyMap = policyMap
+
+	return s.knownIDs(), mErr.ErrorOrNil()
+}
+
+// knownIDs returns the sorted list of IDs currently held within the policy
+// map. The caller must hold at least a read lock.
+func (s *Source) knownIDs() []policy.PolicyID {
+	ids := make([]policy.PolicyID, 0, len(s.policyMap))
+	for id := range s.policyMap {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// listKeys returns all object keys under the configured prefix which have a
+// suffix we can handle as scaling policies, mapped to their ETag. When
+// signature verification is configured, the ETag of the signature object is
+// included so a change to either object causes the policy to be read again.
+func (s *Source) listKeys(ctx context.Context) (map[string]string, error) {
+	keys := make(map[string]string)
+	sigs := make(map[string]string)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	}
+
+	for {
+		out, err := s.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range out.Contents {
+			key := aws.ToString(obj.Key)
+			etag := strings.Trim(aws.ToString(obj.ETag), `"`)
+			switch path.Ext(key) {
+			case ".hcl", ".json":
+				keys[key] = etag
+			case policy.SignatureExtension:
+				sigs[strings.TrimSuffix(key, policy.SignatureExtension)] = etag
+			}
+		}
+
+		if !out.IsTruncated || out.NextContinuationToken == nil {
+			break
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+
+	if s.verifier != nil {
+		for key, etag := range keys {
+			keys[key] = etag + "/" + sigs[key]
+		}
+	}
+	return keys, nil
+}
+
+// readObject downloads and decodes the object identified by key, verifying
+// its signature first if signature verification is configured.
+func (s *Source) readObject(ctx context.Context, key string) (map[string]*sdk.ScalingPolicy, error) {
+	src, err := s.getObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.verifier != nil {
+		sig, err := s.getObject(ctx, key+policy.SignatureExtension)
+		if err != nil {
+			var noSuchKey *types.NoSuchKey
+			if !errors.As(err, &noSuchKey) {
+				return nil, fmt.Errorf("failed to read signature: %v", err)
+			}
+		}
+		if err := policy.VerifyPolicySignature(s.Name(), s.verifier, src, sig); err != nil {
+			return nil, err
+		}
+	}
+	return filePolicy.Decode(key, src)
+}
+
+// getObject downloads the content of the object identified by key.
+func (s *Source) getObject(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(out.Body)
+}
+
+// getObjectPolicyID translates the object key and policy name into its
+// policyID, generating and storing a new ID if this is the first time the
+// policy has been seen. The caller must hold the write lock.
+func (s *Source) getObjectPolicyID(key, name string) policy.PolicyID {
+	mapKey := key + "/" + name
+
+	policyID, ok := s.idMap[mapKey]
+	if !ok {
+		policyID = policy.PolicyID(uuid.Generate())
+		s.idMap[mapKey] = policyID
+	}
+	return policyID
+}
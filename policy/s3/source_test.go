@@ -0,0 +1,217 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+scaling "cluster_policy" {
+  enabled = true
+  min     = 1
+  max     = 10
+
+  policy {
+    check "cpu" {
+      source = "prometheus"
+      query  = "avg(cpu)"
+
+      strategy "target-value" {
+        target = 70
+      }
+    }
+
+    target "aws-asg" {
+      aws_asg_name = "my-asg"
+      node_class   = "hashistack"
+    }
+  }
+}
+`
+
+type fakeObject struct {
+	etag string
+	body string
+}
+
+// fakeClient is an in-memory implementation of the objectClient interface.
+type fakeClient struct {
+	objects  map[string]fakeObject
+	listErr  error
+	getCalls int
+}
+
+func (f *fakeClient) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	out := &s3.ListObjectsV2Output{}
+	for k, v := range f.objects {
+		out.Contents = append(out.Contents, types.Object{Key: aws.String(k), ETag: aws.String(`"` + v.etag + `"`)})
+	}
+	return out, nil
+}
+
+func (f *fakeClient) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.getCalls++
+	obj, ok := f.objects[aws.ToString(in.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewBufferString(obj.body))}, nil
+}
+
+func newTestSource(client objectClient) *Source {
+	processor := policy.NewProcessor(&policy.ConfigDefaults{
+		DefaultEvaluationInterval: 10 * time.Second,
+		DefaultCooldown:           time.Minute,
+	}, nil)
+	return newSource(hclog.NewNullLogger(), "bucket", "policies/", time.Minute, client, processor)
+}
+
+func TestSource_sync(t *testing.T) {
+	client := &fakeClient{objects: map[string]fakeObject{
+		"policies/cluster.hcl": {etag: "v1", body: testPolicy},
+		"policies/README.md":   {etag: "v1", body: "not a policy"},
+	}}
+	s := newTestSource(client)
+
+	// The first sync should download only the policy object.
+	ids, err := s.sync(context.Background())
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	assert.Equal(t, 1, client.getCalls)
+
+	p := s.policyMap[ids[0]]
+	require.NotNil(t, p)
+	assert.Equal(t, "policies/cluster.hcl", p.key)
+	assert.Equal(t, "cluster_policy", p.name)
+	assert.Equal(t, ids[0].String(), p.policy.ID)
+	assert.Equal(t, int64(10), p.policy.Max)
+	assert.Equal(t, time.Minute, p.policy.Cooldown)
+
+	// An unchanged ETag should not trigger a download and must keep the ID.
+	ids2, err := s.sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ids, ids2)
+	assert.Equal(t, 1, client.getCalls)
+
+	// A changed ETag should trigger a download and update the policy.
+	client.objects["policies/cluster.hcl"] = fakeObject{etag: "v2", body: testPolicy}
+	ids3, err := s.sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ids, ids3)
+	assert.Equal(t, 2, client.getCalls)
+
+	// An invalid object should not remove the previously working policy.
+	client.objects["policies/cluster.hcl"] = fakeObject{etag: "v3", body: "scaling {"}
+	ids4, err := s.sync(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, ids, ids4)
+
+	// A listing failure should retain the known policies.
+	client.listErr = errors.New("access denied")
+	ids5, err := s.sync(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, ids, ids5)
+
+	// Removing the object should remove the policy.
+	client.listErr = nil
+	delete(client.objects, "policies/cluster.hcl")
+	ids6, err := s.sync(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, ids6)
+}
+
+// fakeVerifier accepts signatures which match the expected value.
+type fakeVerifier struct {
+	expected string
+}
+
+func (f *fakeVerifier) Verify(_, sig []byte) error {
+	if string(sig) != f.expected {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func TestSource_syncSignature(t *testing.T) {
+	client := &fakeClient{objects: map[string]fakeObject{
+		"policies/cluster.hcl":     {etag: "v1", body: testPolicy},
+		"policies/cluster.hcl.sig": {etag: "s1", body: "valid"},
+		"policies/unsigned.hcl":    {etag: "v1", body: testPolicy},
+	}}
+	s := newTestSource(client)
+	s.verifier = &fakeVerifier{expected: "valid"}
+
+	// Only the signed object should be accepted.
+	ids, err := s.sync(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "policies/unsigned.hcl: signature verification failed: policy is not signed")
+	require.Len(t, ids, 1)
+	assert.Equal(t, "policies/cluster.hcl", s.policyMap[ids[0]].key)
+
+	// Replacing the signature should cause the object to be verified again,
+	// while retaining the previously verified policy.
+	client.objects["policies/cluster.hcl.sig"] = fakeObject{etag: "s2", body: "tampered"}
+	ids2, err := s.sync(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "policies/cluster.hcl: signature verification failed: invalid signature")
+	assert.Equal(t, ids, ids2)
+}
+
+func TestNewS3Source(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      map[string]string
+		expectedErr string
+	}{
+		{
+			name:        "missing bucket",
+			config:      map[string]string{},
+			expectedErr: `"bucket" config value is required`,
+		},
+		{
+			name:        "invalid sync interval",
+			config:      map[string]string{"bucket": "b", "sync_interval": "soon"},
+			expectedErr: `failed to parse "sync_interval"`,
+		},
+		{
+			name:        "non-positive sync interval",
+			config:      map[string]string{"bucket": "b", "sync_interval": "0s"},
+			expectedErr: `"sync_interval" must be greater than 0`,
+		},
+		{
+			name:   "valid",
+			config: map[string]string{"bucket": "b", "region": "eu-west-1", "sync_interval": "30s"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewS3Source(hclog.NewNullLogger(), tc.config, nil)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 30*time.Second, s.(*Source).syncInterval)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	metrics "github.com/armon/go-metrics"
+)
+
+const (
+	// ConfigKeySignatureType is the policy source config key used to enable
+	// signature verification of policy documents. Supported values are
+	// SignatureTypeCosign and SignatureTypeGPG.
+	ConfigKeySignatureType = "signature_type"
+
+	// ConfigKeySignatureKey is the policy source config key which holds the
+	// path to the public key, or GPG keyring, used to verify signatures.
+	ConfigKeySignatureKey = "signature_key"
+
+	// SignatureTypeCosign verifies signatures created using
+	// "cosign sign-blob" with an ECDSA or Ed25519 key pair.
+	SignatureTypeCosign = "cosign"
+
+	// SignatureTypeGPG verifies GPG detached signatures, in either binary or
+	// ASCII armored form.
+	SignatureTypeGPG = "gpg"
+
+	// SignatureExtension is appended to the name of a policy document to
+	// find its detached signature, such as "policy.hcl.sig".
+	SignatureExtension = ".sig"
+)
+
+// SignatureVerifier checks a detached signature of a policy document.
+type SignatureVerifier interface {
+
+	// Verify returns an error if sig is not a valid signature of doc.
+	Verify(doc, sig []byte) error
+}
+
+// NewSignatureVerifier builds a SignatureVerifier from the policy source
+// config. A nil verifier is returned if signature verification is not
+// configured.
+func NewSignatureVerifier(config map[string]string) (SignatureVerifier, error) {
+	sigType, keyPath := config[ConfigKeySignatureType], config[ConfigKeySignatureKey]
+	if sigType == "" {
+		return nil, nil
+	}
+	if keyPath == "" {
+		return nil, fmt.Errorf("%q config value is required when %q is set", ConfigKeySignatureKey, ConfigKeySignatureType)
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature key: %v", err)
+	}
+
+	switch sigType {
+	case SignatureTypeCosign:
+		return newCosignVerifier(key)
+	case SignatureTypeGPG:
+		return newGPGVerifier(key)
+	default:
+		return nil, fmt.Errorf("invalid %s %q, must be one of %q or %q",
+			ConfigKeySignatureType, sigType, SignatureTypeCosign, SignatureTypeGPG)
+	}
+}
+
+// VerifyPolicySignature verifies the signature of a policy document read
+// from the named source, emitting metrics so rejected documents can be
+// alerted on. A nil verifier accepts all documents.
+func VerifyPolicySignature(name SourceName, v SignatureVerifier, doc, sig []byte) error {
+	if v == nil {
+		return nil
+	}
+
+	labels := []metrics.Label{{Name: "policy_source", Value: string(name)}}
+
+	var err error
+	if sig == nil {
+		err = errors.New("policy is not signed")
+	} else {
+		err = v.Verify(doc, sig)
+	}
+
+	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"policy", "signature", "rejected_count"}, 1, labels)
+		return fmt.Errorf("signature verification failed: %v", err)
+	}
+
+	metrics.IncrCounterWithLabels([]string{"policy", "signature", "verified_count"}, 1, labels)
+	return nil
+}
+
+// cosignVerifier verifies the base64 encoded signatures produced by
+// "cosign sign-blob".
+type cosignVerifier struct {
+	key interface{}
+}
+
+func newCosignVerifier(key []byte) (*cosignVerifier, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, errors.New("failed to decode cosign public key PEM")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cosign public key: %v", err)
+	}
+
+	switch pub.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported cosign public key type %T", pub)
+	}
+	return &cosignVerifier{key: pub}, nil
+}
+
+func (c *cosignVerifier) Verify(doc, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %v", err)
+	}
+
+	switch key := c.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(doc)
+		if !ecdsa.VerifyASN1(key, digest[:], raw) {
+			return errors.New("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, doc, raw) {
+			return errors.New("invalid signature")
+		}
+	}
+	return nil
+}
+
+// gpgVerifier verifies GPG detached signatures against a keyring.
+type gpgVerifier struct {
+	keyring openpgp.EntityList
+}
+
+func newGPGVerifier(key []byte) (*gpgVerifier, error) {
+	var (
+		keyring openpgp.EntityList
+		err     error
+	)
+	if isArmored(key) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gpg keyring: %v", err)
+	}
+	return &gpgVerifier{keyring: keyring}, nil
+}
+
+func (g *gpgVerifier) Verify(doc, sig []byte) error {
+	var err error
+	if isArmored(sig) {
+		_, err = openpgp.CheckArmoredDetachedSignature(g.keyring, bytes.NewReader(doc), bytes.NewReader(sig), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(g.keyring, bytes.NewReader(doc), bytes.NewReader(sig), nil)
+	}
+	return err
+}
+
+// isArmored returns whether b holds ASCII armored OpenPGP data.
+func isArmored(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN PGP"))
+}
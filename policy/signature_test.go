@@ -0,0 +1,164 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicyDoc = []byte(`scaling "test" { enabled = true }`)
+
+func writeSignatureKey(t *testing.T, key []byte) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(p, key, 0o600))
+	return p
+}
+
+func pemPublicKey(t *testing.T, pub interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestNewSignatureVerifier(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyPath := writeSignatureKey(t, pemPublicKey(t, &ecKey.PublicKey))
+
+	testCases := []struct {
+		name        string
+		config      map[string]string
+		expectNil   bool
+		expectedErr string
+	}{
+		{
+			name:      "not configured",
+			config:    map[string]string{},
+			expectNil: true,
+		},
+		{
+			name:        "missing key",
+			config:      map[string]string{"signature_type": "cosign"},
+			expectedErr: `"signature_key" config value is required`,
+		},
+		{
+			name:        "unreadable key",
+			config:      map[string]string{"signature_type": "cosign", "signature_key": filepath.Join(t.TempDir(), "missing")},
+			expectedErr: "failed to read signature key",
+		},
+		{
+			name:        "invalid type",
+			config:      map[string]string{"signature_type": "x509", "signature_key": keyPath},
+			expectedErr: `invalid signature_type "x509"`,
+		},
+		{
+			name:        "invalid gpg keyring",
+			config:      map[string]string{"signature_type": "gpg", "signature_key": keyPath},
+			expectedErr: "failed to read gpg keyring",
+		},
+		{
+			name:   "valid cosign",
+			config: map[string]string{"signature_type": "cosign", "signature_key": keyPath},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewSignatureVerifier(tc.config)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectNil, v == nil)
+		})
+	}
+}
+
+func TestCosignVerifier(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(testPolicyDoc)
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	require.NoError(t, err)
+	edSig := ed25519.Sign(edPriv, testPolicyDoc)
+
+	testCases := []struct {
+		name string
+		pub  interface{}
+		sig  []byte
+	}{
+		{name: "ecdsa", pub: &ecKey.PublicKey, sig: ecSig},
+		{name: "ed25519", pub: edPub, sig: edSig},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := NewSignatureVerifier(map[string]string{
+				"signature_type": "cosign",
+				"signature_key":  writeSignatureKey(t, pemPublicKey(t, tc.pub)),
+			})
+			require.NoError(t, err)
+
+			encoded := []byte(base64.StdEncoding.EncodeToString(tc.sig) + "\n")
+			assert.NoError(t, VerifyPolicySignature(SourceNameFile, v, testPolicyDoc, encoded))
+
+			err = VerifyPolicySignature(SourceNameFile, v, append(testPolicyDoc, ' '), encoded)
+			assert.EqualError(t, err, "signature verification failed: invalid signature")
+
+			err = VerifyPolicySignature(SourceNameFile, v, testPolicyDoc, nil)
+			assert.EqualError(t, err, "signature verification failed: policy is not signed")
+
+			err = VerifyPolicySignature(SourceNameFile, v, testPolicyDoc, []byte("%%%"))
+			assert.ErrorContains(t, err, "failed to decode signature")
+		})
+	}
+}
+
+func TestGPGVerifier(t *testing.T) {
+	entity, err := openpgp.NewEntity("autoscaler", "", "autoscaler@example.com", nil)
+	require.NoError(t, err)
+
+	var keyring bytes.Buffer
+	require.NoError(t, entity.Serialize(&keyring))
+
+	v, err := NewSignatureVerifier(map[string]string{
+		"signature_type": "gpg",
+		"signature_key":  writeSignatureKey(t, keyring.Bytes()),
+	})
+	require.NoError(t, err)
+
+	var binarySig, armoredSig bytes.Buffer
+	require.NoError(t, openpgp.DetachSign(&binarySig, entity, bytes.NewReader(testPolicyDoc), nil))
+	require.NoError(t, openpgp.ArmoredDetachSign(&armoredSig, entity, bytes.NewReader(testPolicyDoc), nil))
+
+	assert.NoError(t, VerifyPolicySignature(SourceNameS3, v, testPolicyDoc, binarySig.Bytes()))
+	assert.NoError(t, VerifyPolicySignature(SourceNameS3, v, testPolicyDoc, armoredSig.Bytes()))
+	assert.Error(t, VerifyPolicySignature(SourceNameS3, v, append(testPolicyDoc, ' '), armoredSig.Bytes()))
+}
+
+func TestVerifyPolicySignature_nilVerifier(t *testing.T) {
+	assert.NoError(t, VerifyPolicySignature(SourceNameFile, nil, testPolicyDoc, nil))
+}
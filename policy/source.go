@@ -19,6 +19,16 @@ const DefaultQueryWindow = time.Minute
 type ConfigDefaults struct {
 	DefaultEvaluationInterval time.Duration
 	DefaultCooldown           time.Duration
+
+	// DefaultOnCheckError is applied to policies which do not configure
+	// on_check_error. Checks without on_error inherit the policy value.
+	DefaultOnCheckError string
+
+	// DefaultMin and DefaultMax are applied to policies which do not set
+	// their min and max values. A zero DefaultMax indicates no default is
+	// configured, in which case policies must set max.
+	DefaultMin int64
+	DefaultMax int64
 }
 
 type MonitorIDsReq struct {
@@ -71,6 +81,22 @@ const (
 	// SourceNameFile is the source for policies that are loaded from disk.
 	SourceNameFile SourceName = "file"
 
+	// SourceNameS3 is the source for policies that are loaded from an
+	// S3-compatible object storage bucket.
+	SourceNameS3 SourceName = "s3"
+
+	// SourceNameVault is the source for policies that are loaded from a
+	// Vault KV v2 secrets engine.
+	SourceNameVault SourceName = "vault"
+
+	// SourceNameNomadVariables is the source for policies that are loaded
+	// from Nomad Variables.
+	SourceNameNomadVariables SourceName = "nomad-variables"
+
+	// SourceNameAPI is the source for policies that are pushed to the agent
+	// through its HTTP API.
+	SourceNameAPI SourceName = "api"
+
 	// SourceNameHA is the source for HA policy sources
 	SourceNameHA SourceName = "ha"
 )
@@ -88,8 +114,9 @@ func HandleSourceError(name SourceName, err error, errCha chan<- error) {
 		[]metrics.Label{{Name: "policy_source", Value: string(name)}})
 
 	// Send the error to the channel for the handler/manager can perform the
-	// work it needs to.
-	errCha <- err
+	// work it needs to. The error is wrapped so the manager can attribute it
+	// to the source when reporting source health.
+	errCha <- &SourceError{Source: name, Err: err}
 }
 
 // IDMessage encapsulates the required information that allows the policy
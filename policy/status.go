@@ -0,0 +1,109 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SourceError is the error sent by HandleSourceError. It records the name of
+// the policy source which encountered the error, so it can be attributed when
+// errors from multiple sources are sent to a shared channel.
+type SourceError struct {
+	Source SourceName
+	Err    error
+}
+
+func (e *SourceError) Error() string { return e.Err.Error() }
+
+func (e *SourceError) Unwrap() error { return e.Err }
+
+// SourceStatus describes the health of a single policy source.
+type SourceStatus struct {
+	Name SourceName
+
+	// LastSync is the time the source last sent a list of policies. It is
+	// the zero time, encoded as null, if the source has not completed a sync.
+	LastSync time.Time
+
+	// PolicyCount is the number of policies loaded in the last sync.
+	PolicyCount int
+
+	// LastError is the last error encountered by the source and the time it
+	// happened. Both are empty if the source has not encountered an error. An
+	// error more recent than LastSync indicates the source may be serving
+	// stale policies.
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// sourceStatusTracker records the status of each policy source as observed
+// by the policy manager.
+type sourceStatusTracker struct {
+	lock     sync.RWMutex
+	statuses map[SourceName]*SourceStatus
+}
+
+func newSourceStatusTracker(sources map[SourceName]Source) *sourceStatusTracker {
+	t := &sourceStatusTracker{statuses: make(map[SourceName]*SourceStatus, len(sources))}
+	for name := range sources {
+		t.statuses[name] = &SourceStatus{Name: name}
+	}
+	return t
+}
+
+// recordSync updates the status of the source after it sent a list of
+// policies.
+func (t *sourceStatusTracker) recordSync(name SourceName, count int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := t.get(name)
+	s.LastSync = time.Now().UTC()
+	s.PolicyCount = count
+}
+
+// recordError updates the status of the source which produced err. Errors
+// which cannot be attributed to a source are ignored.
+func (t *sourceStatusTracker) recordError(err error) {
+	var srcErr *SourceError
+	if !errors.As(err, &srcErr) {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	s := t.get(srcErr.Source)
+	s.LastError = srcErr.Err.Error()
+	s.LastErrorTime = time.Now().UTC()
+}
+
+// get returns the status of the named source, creating it if needed. The
+// caller must hold the write lock.
+func (t *sourceStatusTracker) get(name SourceName) *SourceStatus {
+	s, ok := t.statuses[name]
+	if !ok {
+		s = &SourceStatus{Name: name}
+		t.statuses[name] = s
+	}
+	return s
+}
+
+// list returns a copy of the status of each source, sorted by name.
+func (t *sourceStatusTracker) list() []*SourceStatus {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	out := make([]*SourceStatus, 0, len(t.statuses))
+	for _, s := range t.statuses {
+		sCopy := *s
+		out = append(out, &sCopy)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,54 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceStatusTracker(t *testing.T) {
+	tracker := newSourceStatusTracker(map[SourceName]Source{
+		SourceNameNomad: nil,
+		SourceNameFile:  nil,
+	})
+
+	// Sources which have not synced should still be listed.
+	statuses := tracker.list()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, SourceNameFile, statuses[0].Name)
+	assert.Equal(t, SourceNameNomad, statuses[1].Name)
+	assert.True(t, statuses[1].LastSync.IsZero())
+
+	tracker.recordSync(SourceNameNomad, 3)
+
+	// Errors sent by HandleSourceError should be attributed to the source,
+	// while other errors are ignored.
+	errCh := make(chan error, 1)
+	HandleSourceError(SourceNameNomad, errors.New("connection refused"), errCh)
+	err := <-errCh
+	assert.EqualError(t, err, "connection refused")
+	tracker.recordError(err)
+	tracker.recordError(errors.New("unattributed"))
+
+	statuses = tracker.list()
+	require.Len(t, statuses, 2)
+
+	nomad := statuses[1]
+	assert.Equal(t, 3, nomad.PolicyCount)
+	assert.False(t, nomad.LastSync.IsZero())
+	assert.Equal(t, "connection refused", nomad.LastError)
+	assert.False(t, nomad.LastErrorTime.Before(nomad.LastSync))
+
+	file := statuses[0]
+	assert.Empty(t, file.LastError)
+	assert.True(t, file.LastErrorTime.IsZero())
+
+	// The returned statuses should be copies.
+	nomad.PolicyCount = 10
+	assert.Equal(t, 3, tracker.list()[1].PolicyCount)
+}
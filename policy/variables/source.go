@@ -0,0 +1,434 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package variables
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	filePolicy "github.com/hashicorp/nomad-autoscaler/policy/file"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/blocking"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/uuid"
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/copystructure"
+)
+
+const (
+	// configKeys are the keys which can be set within the policy source
+	// config block.
+	configKeyPath      = "path"
+	configKeyNamespace = "namespace"
+	configKeyItem      = "item"
+
+	// configValues are the default values used when the operator does not
+	// set the corresponding config key.
+	configValuePathDefault = "nomad-autoscaler/policies"
+	configValueItemDefault = "policy"
+
+	// blockingQueryWaitTime is the maximum time each blocking query waits for
+	// a change to the variables below the path prefix.
+	blockingQueryWaitTime = 5 * time.Minute
+
+	// retryInterval is the time to wait before retrying a failed list call.
+	retryInterval = 10 * time.Second
+)
+
+// Ensure Source satisfies the Source interface.
+var _ policy.Source = (*Source)(nil)
+
+// varsClient is the subset of the Nomad Variables API used by the policy
+// source. It exists so tests can supply a fake implementation.
+type varsClient interface {
+	// list performs a, possibly blocking, query for the metadata of all
+	// variables below the passed path prefix.
+	list(ctx context.Context, prefix string, q *api.QueryOptions) ([]*api.VariableMetadata, *api.QueryMeta, error)
+
+	// read returns the variable at the passed path within the namespace.
+	read(ctx context.Context, namespace, p string) (*api.Variable, error)
+}
+
+// Source is the Nomad Variables implementation of the policy.Source
+// interface. Each variable below the configured path prefix holds a single
+// policy document, using the same format as the file policy source, within
+// a configurable item.
+type Source struct {
+	log             hclog.Logger
+	prefix          string
+	namespace       string
+	item            string
+	policyProcessor *policy.Processor
+
+	vars     varsClient
+	varsLock sync.RWMutex
+
+	// reloadCh helps coordinate reloading the of the MonitorIDs routine.
+	reloadCh chan struct{}
+
+	// variables maps a variable namespace and path to the last seen version
+	// of that variable. The modify index is used to avoid reading variables
+	// which have not changed since the last sync.
+	variables map[string]*variable
+
+	// idMap stores a mapping between the variable namespace, path and policy
+	// name, and the associated policyID. This allows us to keep a consistent
+	// PolicyID in the event of policy changes.
+	idMap map[string]policy.PolicyID
+
+	// policyMap maps our policyID to the latest policy read from Nomad.
+	policyMap map[policy.PolicyID]*variablePolicy
+
+	// updateCh is closed, and replaced, each time the policies are synced so
+	// that MonitorPolicy routines can check for changes without polling.
+	updateCh chan struct{}
+
+	// lock protects the fields above, which are written by the MonitorIDs
+	// routine and read by each MonitorPolicy routine.
+	lock sync.RWMutex
+}
+
+// variable is the decoded content of a single Nomad variable.
+type variable struct {
+	modifyIndex uint64
+	policies    map[string]*sdk.ScalingPolicy
+}
+
+// variablePolicy is a wrapper around a scaling policy that also provides the
+// variable path and name that it came from.
+type variablePolicy struct {
+	path   string
+	name   string
+	policy *sdk.ScalingPolicy
+}
+
+// NewVariablesSource returns a new Nomad Variables policy source which reads
+// variables using the passed Nomad client.
+func NewVariablesSource(log hclog.Logger, nomad *api.Client, config map[string]string, policyProcessor *policy.Processor) *Source {
+
+	prefix := configValuePathDefault
+	if v := config[configKeyPath]; v != "" {
+		prefix = strings.Trim(v, "/")
+	}
+
+	item := configValueItemDefault
+	if v := config[configKeyItem]; v != "" {
+		item = v
+	}
+
+	return newSource(log, &nomadVars{client: nomad}, prefix, config[configKeyNamespace], item, policyProcessor)
+}
+
+func newSource(log hclog.Logger, vars varsClient, prefix, namespace, item string,
+	policyProcessor *policy.Processor) *Source {
+	return &Source{
+		log:             log.ResetNamed("variables_policy_source"),
+		prefix:          prefix,
+		namespace:       namespace,
+		item:            item,
+		policyProcessor: policyProcessor,
+		vars:            vars,
+		reloadCh:        make(chan struct{}),
+		variables:       make(map[string]*variable),
+		idMap:           make(map[string]policy.PolicyID),
+		policyMap:       make(map[policy.PolicyID]*variablePolicy),
+		updateCh:        make(chan struct{}),
+	}
+}
+
+// SetNomadClient updates the Nomad client used to read variables.
+func (s *Source) SetNomadClient(nomad *api.Client) {
+	s.varsLock.Lock()
+	defer s.varsLock.Unlock()
+	s.vars = &nomadVars{client: nomad}
+}
+
+// Name satisfies the Name function of the policy.Source interface.
+func (s *Source) Name() policy.SourceName {
+	return policy.SourceNameNomadVariables
+}
+
+// ReloadIDsMonitor satisfies the ReloadIDsMonitor function of the
+// policy.Source interface.
+func (s *Source) ReloadIDsMonitor() {
+	s.reloadCh <- struct{}{}
+}
+
+// MonitorIDs performs blocking queries against the Nomad Variables API and
+// sends the policy IDs found below the path prefix in the resultCh channel
+// when a change is detected. Errors are sent through the errCh channel.
+//
+// This function blocks until the context is closed.
+func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
+	s.log.Debug("starting variables blocking query watcher")
+
+	q := &api.QueryOptions{Namespace: s.namespace, WaitTime: blockingQueryWaitTime, WaitIndex: 1}
+
+	for {
+		var (
+			metas []*api.VariableMetadata
+			meta  *api.QueryMeta
+			err   error
+		)
+
+		// Perform the blocking query in a goroutine so we can still listen
+		// for the context closing or a reload request.
+		blockingQueryCompleteCh := make(chan struct{})
+		go func() {
+			s.varsLock.RLock()
+			vars := s.vars
+			s.varsLock.RUnlock()
+
+			metas, meta, err = vars.list(ctx, s.prefix, q)
+			close(blockingQueryCompleteCh)
+		}()
+
+		select {
+		case <-ctx.Done():
+			s.log.Trace("stopping variables ID subscription")
+			return
+		case <-s.reloadCh:
+			s.log.Trace("reloading variables policies")
+			q.WaitIndex = 1
+			continue
+		case <-blockingQueryCompleteCh:
+		}
+
+		if err != nil {
+			policy.HandleSourceError(s.Name(), fmt.Errorf("failed to call the Nomad list variables API: %v", err), req.ErrCh)
+			select {
+			case <-ctx.Done():
+				s.log.Trace("stopping variables ID subscription")
+				return
+			case <-s.reloadCh:
+				s.log.Trace("reloading variables policies")
+				q.WaitIndex = 1
+				continue
+			case <-time.After(retryInterval):
+				continue
+			}
+		}
+
+		// If the index has not changed, the query returned because the timeout
+		// was reached, therefore start the next query loop.
+		if !blocking.IndexHasChanged(meta.LastIndex, q.WaitIndex) {
+			continue
+		}
+
+		ids, err := s.sync(ctx, metas)
+		if err != nil {
+			policy.HandleSourceError(s.Name(), err, req.ErrCh)
+		}
+
+		// Even if we receive an error we may have IDs to send. Otherwise it
+		// may be that all policies have been removed so we should even send
+		// the empty list so handlers can be cleaned.
+		req.ResultCh <- policy.IDMessage{IDs: ids, Source: s.Name()}
+
+		q.WaitIndex = meta.LastIndex
+	}
+}
+
+// MonitorPolicy satisfies the MonitorPolicy function of the policy.Source
+// interface. Variables are synced by the MonitorIDs routine, so this only
+// needs to check whether the stored policy has changed after each sync.
+func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq) {
+
+	// Close channels when done with the monitoring loop.
+	defer close(req.ResultCh)
+	defer close(req.ErrCh)
+
+	log := s.log.With("policy_id", req.ID)
+	log.Info("starting variables policy monitor")
+
+	var last *sdk.ScalingPolicy
+
+	for {
+		s.lock.RLock()
+		val, ok := s.policyMap[req.ID]
+		updateCh := s.updateCh
+		s.lock.RUnlock()
+
+		switch {
+		case !ok || val.policy == nil:
+			policy.HandleSourceError(s.Name(), fmt.Errorf("failed to get policy %s", req.ID), req.ErrCh)
+		case last == nil || !reflect.DeepEqual(last, val.policy):
+			if last != nil {
+				log.Info("variables policy content has changed", "path", val.path, "name", val.name)
+			}
+			last = val.policy
+			req.ResultCh <- *val.policy
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Debug("stopping variables policy monitor due to context done")
+			return
+		case <-updateCh:
+		case <-req.ReloadCh:
+			log.Info("variables policy source monitor received reload signal")
+		}
+	}
+}
+
+// sync reads any variable whose modify index has changed since the last sync
+// and decodes the policies it holds. Variables which fail to be read or
+// decoded retain their previous policies so that a bad write doesn't remove
+// a working policy.
+func (s *Source) sync(ctx context.Context, metas []*api.VariableMetadata) ([]policy.PolicyID, error) {
+	var mErr *multierror.Error
+	variables := make(map[string]*variable, len(metas))
+
+	s.varsLock.RLock()
+	vars := s.vars
+	s.varsLock.RUnlock()
+
+	for _, m := range metas {
+		key := variableKey(m.Namespace, m.Path)
+
+		s.lock.RLock()
+		existing, ok := s.variables[key]
+		s.lock.RUnlock()
+
+		if ok && existing.modifyIndex == m.ModifyIndex {
+			variables[key] = existing
+			continue
+		}
+
+		v, err := vars.read(ctx, m.Namespace, m.Path)
+		if err != nil {
+			// The variable could have been deleted between the list and read
+			// calls which isn't an error.
+			if errors.Is(err, api.ErrVariablePathNotFound) {
+				continue
+			}
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to read variable %s: %v", key, err))
+			if ok {
+				variables[key] = existing
+			}
+			continue
+		}
+
+		policies, err := s.decodeVariable(m.Path, v.Items)
+		if err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to decode variable %s: %v", key, err))
+			if ok {
+				variables[key] = existing
+			}
+			continue
+		}
+		variables[key] = &variable{modifyIndex: v.ModifyIndex, policies: policies}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.variables = variables
+	policyMap := make(map[policy.PolicyID]*variablePolicy)
+
+	for key, v := range variables {
+		for name, vp := range v.policies {
+			policyID := s.getVariablePolicyID(key, name)
+
+			// Ignore the policy if its disabled.
+			if !vp.Enabled {
+				s.log.Trace("policy is disabled therefore ignoring", "policy_id", policyID, "variable", key)
+				continue
+			}
+
+			// Work on a copy so the cached variable remains untouched by the
+			// processor.
+			cp, err := copystructure.Copy(vp)
+			if err != nil {
+				mErr = multierror.Append(mErr, fmt.Errorf("failed to copy policy from variable %s: %v", key, err))
+				continue
+			}
+			scalingPolicy := cp.(*sdk.ScalingPolicy)
+			scalingPolicy.ID = policyID.String()
+			s.policyProcessor.ApplyPolicyDefaults(scalingPolicy)
+
+			if err := s.policyProcessor.ValidatePolicy(scalingPolicy); err != nil {
+				mErr = multierror.Append(mErr, fmt.Errorf("failed to validate variable %s: %v", key, err))
+				continue
+			}
+
+			for _, c := range scalingPolicy.Checks {
+				s.policyProcessor.CanonicalizeCheck(c, scalingPolicy.Target)
+			}
+
+			policyMap[policyID] = &variablePolicy{path: key, name: name, policy: scalingPolicy}
+		}
+	}
+	s.policyMap = policyMap
+
+	// Notify the MonitorPolicy routines that the policies have been synced.
+	close(s.updateCh)
+	s.updateCh = make(chan struct{})
+
+	ids := make([]policy.PolicyID, 0, len(policyMap))
+	for id := range policyMap {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, mErr.ErrorOrNil()
+}
+
+// decodeVariable decodes the policy document held within the configured
+// item of the variable. Documents starting with a brace are treated as JSON,
+// otherwise as HCL.
+func (s *Source) decodeVariable(p string, items api.VariableItems) (map[string]*sdk.ScalingPolicy, error) {
+	doc, ok := items[s.item]
+	if !ok {
+		return nil, fmt.Errorf("item %q not found", s.item)
+	}
+
+	filename := p + ".hcl"
+	if strings.HasPrefix(strings.TrimSpace(doc), "{") {
+		filename = p + ".json"
+	}
+	return filePolicy.Decode(filename, []byte(doc))
+}
+
+// getVariablePolicyID translates the variable key and policy name into its
+// policyID, generating and storing a new ID if this is the first time the
+// policy has been seen. The caller must hold the write lock.
+func (s *Source) getVariablePolicyID(key, name string) policy.PolicyID {
+	mapKey := key + "/" + name
+
+	policyID, ok := s.idMap[mapKey]
+	if !ok {
+		policyID = policy.PolicyID(uuid.Generate())
+		s.idMap[mapKey] = policyID
+	}
+	return policyID
+}
+
+// variableKey returns the key used to identify a variable across namespaces.
+func variableKey(namespace, p string) string {
+	return namespace + "/" + p
+}
+
+// nomadVars implements varsClient using the Nomad API client.
+type nomadVars struct {
+	client *api.Client
+}
+
+func (n *nomadVars) list(ctx context.Context, prefix string, q *api.QueryOptions) ([]*api.VariableMetadata, *api.QueryMeta, error) {
+	return n.client.Variables().PrefixList(prefix, q.WithContext(ctx))
+}
+
+func (n *nomadVars) read(ctx context.Context, namespace, p string) (*api.Variable, error) {
+	q := &api.QueryOptions{Namespace: namespace}
+	v, _, err := n.client.Variables().Read(p, q.WithContext(ctx))
+	return v, err
+}
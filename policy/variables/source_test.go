@@ -0,0 +1,227 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package variables
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+scaling "cluster_policy" {
+  enabled = true
+  min     = 1
+  max     = 10
+
+  policy {
+    check "cpu" {
+      source = "prometheus"
+      query  = "avg(cpu)"
+
+      strategy "target-value" {
+        target = 70
+      }
+    }
+
+    target "aws-asg" {
+      aws_asg_name = "my-asg"
+      node_class   = "hashistack"
+    }
+  }
+}
+`
+
+const testUpdatedPolicy = `
+scaling "cluster_policy" {
+  enabled = true
+  min     = 1
+  max     = 20
+
+  policy {
+    target "aws-asg" {
+      aws_asg_name = "my-asg"
+      node_class   = "hashistack"
+    }
+  }
+}
+`
+
+const testJSONPolicy = `{
+  "scaling": {
+    "json_policy": {
+      "enabled": true,
+      "max": 5,
+      "policy": {
+        "target": {
+          "aws-asg": {
+            "node_class": "worker"
+          }
+        }
+      }
+    }
+  }
+}`
+
+// fakeVars is an in-memory implementation of the varsClient interface.
+type fakeVars struct {
+	vars    map[string]*api.Variable
+	reads   int
+	readErr error
+}
+
+func (f *fakeVars) list(_ context.Context, _ string, _ *api.QueryOptions) ([]*api.VariableMetadata, *api.QueryMeta, error) {
+	var metas []*api.VariableMetadata
+	for _, v := range f.vars {
+		metas = append(metas, v.Metadata())
+	}
+	return metas, &api.QueryMeta{LastIndex: 1}, nil
+}
+
+func (f *fakeVars) read(_ context.Context, namespace, p string) (*api.Variable, error) {
+	f.reads++
+	if f.readErr != nil {
+		return nil, f.readErr
+	}
+	v, ok := f.vars[variableKey(namespace, p)]
+	if !ok {
+		return nil, api.ErrVariablePathNotFound
+	}
+	return v, nil
+}
+
+func (f *fakeVars) put(namespace, p string, index uint64, doc string) {
+	f.vars[variableKey(namespace, p)] = &api.Variable{
+		Namespace:   namespace,
+		Path:        p,
+		ModifyIndex: index,
+		Items:       api.VariableItems{"policy": doc},
+	}
+}
+
+func newTestSource(vars varsClient) *Source {
+	processor := policy.NewProcessor(&policy.ConfigDefaults{
+		DefaultEvaluationInterval: 10 * time.Second,
+		DefaultCooldown:           time.Minute,
+	}, nil)
+	return newSource(hclog.NewNullLogger(), vars, configValuePathDefault, "*", configValueItemDefault, processor)
+}
+
+func TestSource_sync(t *testing.T) {
+	vars := &fakeVars{vars: map[string]*api.Variable{}}
+	vars.put("default", "nomad-autoscaler/policies/cluster", 10, testPolicy)
+	vars.put("team-a", "nomad-autoscaler/policies/queue", 11, testJSONPolicy)
+	s := newTestSource(vars)
+
+	metas, _, _ := vars.list(context.Background(), "", nil)
+
+	// The first sync should find policies across namespaces and in both
+	// formats.
+	ids, err := s.sync(context.Background(), metas)
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+
+	names := map[string]string{}
+	for _, id := range ids {
+		names[s.policyMap[id].name] = s.policyMap[id].path
+	}
+	assert.Equal(t, map[string]string{
+		"cluster_policy": "default/nomad-autoscaler/policies/cluster",
+		"json_policy":    "team-a/nomad-autoscaler/policies/queue",
+	}, names)
+
+	// Unchanged variables should not be read again.
+	ids2, err := s.sync(context.Background(), metas)
+	require.NoError(t, err)
+	assert.Equal(t, ids, ids2)
+	assert.Equal(t, 2, vars.reads)
+
+	// A new version should be read again while keeping the policy ID.
+	vars.put("default", "nomad-autoscaler/policies/cluster", 12, testPolicy)
+	metas, _, _ = vars.list(context.Background(), "", nil)
+	ids3, err := s.sync(context.Background(), metas)
+	require.NoError(t, err)
+	assert.Equal(t, ids, ids3)
+	assert.Equal(t, 3, vars.reads)
+
+	// A variable missing the policy item should not remove the working
+	// policy.
+	vars.vars[variableKey("default", "nomad-autoscaler/policies/cluster")] = &api.Variable{
+		Namespace:   "default",
+		Path:        "nomad-autoscaler/policies/cluster",
+		ModifyIndex: 13,
+		Items:       api.VariableItems{"other": "value"},
+	}
+	metas, _, _ = vars.list(context.Background(), "", nil)
+	ids4, err := s.sync(context.Background(), metas)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `item "policy" not found`)
+	assert.Equal(t, ids, ids4)
+
+	// A read failure should retain the known policies.
+	vars.put("default", "nomad-autoscaler/policies/cluster", 14, testPolicy)
+	vars.readErr = errors.New("permission denied")
+	metas, _, _ = vars.list(context.Background(), "", nil)
+	ids5, err := s.sync(context.Background(), metas)
+	require.Error(t, err)
+	assert.Equal(t, ids, ids5)
+
+	// Deleting a variable should remove its policy.
+	vars.readErr = nil
+	delete(vars.vars, variableKey("team-a", "nomad-autoscaler/policies/queue"))
+	metas, _, _ = vars.list(context.Background(), "", nil)
+	ids6, err := s.sync(context.Background(), metas)
+	require.NoError(t, err)
+	assert.Len(t, ids6, 1)
+}
+
+func TestSource_MonitorPolicy(t *testing.T) {
+	vars := &fakeVars{vars: map[string]*api.Variable{}}
+	vars.put("default", "nomad-autoscaler/policies/cluster", 10, testPolicy)
+	s := newTestSource(vars)
+
+	metas, _, _ := vars.list(context.Background(), "", nil)
+	ids, err := s.sync(context.Background(), metas)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resultCh := make(chan sdk.ScalingPolicy)
+	go s.MonitorPolicy(ctx, policy.MonitorPolicyReq{
+		ID:       ids[0],
+		ErrCh:    make(chan error, 10),
+		ReloadCh: make(chan struct{}),
+		ResultCh: resultCh,
+	})
+
+	select {
+	case p := <-resultCh:
+		assert.Equal(t, 10, int(p.Max))
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for policy")
+	}
+
+	// Updating the variable should send the new policy once synced.
+	vars.put("default", "nomad-autoscaler/policies/cluster", 11, testUpdatedPolicy)
+	metas, _, _ = vars.list(context.Background(), "", nil)
+	_, err = s.sync(context.Background(), metas)
+	require.NoError(t, err)
+
+	select {
+	case p := <-resultCh:
+		assert.Equal(t, 20, int(p.Max))
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for updated policy")
+	}
+}
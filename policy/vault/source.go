@@ -0,0 +1,578 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	filePolicy "github.com/hashicorp/nomad-autoscaler/policy/file"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/uuid"
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/copystructure"
+)
+
+const (
+	// configKeys are the keys which can be set within the policy source
+	// config block.
+	configKeyAddress       = "address"
+	configKeyToken         = "token"
+	configKeyNamespace     = "namespace"
+	configKeyMount         = "mount"
+	configKeyPath          = "path"
+	configKeyField         = "field"
+	configKeySyncInterval  = "sync_interval"
+	configKeyCACert        = "ca_cert"
+	configKeyCAPath        = "ca_path"
+	configKeyClientCert    = "client_cert"
+	configKeyClientKey     = "client_key"
+	configKeyTLSServerName = "tls_server_name"
+	configKeySkipVerify    = "skip_verify"
+
+	// configValues are the default values used when the operator does not
+	// set the corresponding config key.
+	configValueMountDefault = "secret"
+	configValueFieldDefault = "policy"
+
+	// defaultSyncInterval is the interval at which the KV path is listed to
+	// detect policy changes when the operator does not configure one.
+	defaultSyncInterval = time.Minute
+)
+
+// Ensure Source satisfies the Source interface.
+var _ policy.Source = (*Source)(nil)
+
+// errSecretNotFound is returned by a kvClient when the requested secret does
+// not exist or has been deleted.
+var errSecretNotFound = errors.New("secret not found")
+
+// kvClient is the subset of Vault KV v2 functionality used by the policy
+// source. It exists so tests can supply a fake implementation.
+type kvClient interface {
+	// list returns the keys directly under the passed path. Keys which end
+	// with a slash are sub-paths.
+	list(ctx context.Context, p string) ([]string, error)
+
+	// get returns the current version and data of the secret at the passed
+	// path.
+	get(ctx context.Context, p string) (int, map[string]interface{}, error)
+}
+
+// Source is the Vault KV v2 implementation of the policy.Source interface.
+// Each secret below the configured path holds a single policy document,
+// using the same format as the file policy source, within a configurable
+// field.
+type Source struct {
+	client          *api.Client
+	kv              kvClient
+	basePath        string
+	field           string
+	syncInterval    time.Duration
+	log             hclog.Logger
+	policyProcessor *policy.Processor
+
+	// reloadChannels help coordinate reloading the of the MonitorIDs routine.
+	reloadCh         chan struct{}
+	reloadCompleteCh chan struct{}
+
+	// secrets maps a secret path to the last seen version of that secret.
+	// The version is used to avoid decoding secrets which have not changed
+	// since the last sync.
+	secrets map[string]*secret
+
+	// idMap stores a mapping between the secret path and policy name, and the
+	// associated policyID. This allows us to keep a consistent PolicyID in
+	// the event of policy changes.
+	idMap map[string]policy.PolicyID
+
+	// policyMap maps our policyID to the latest policy decoded from Vault.
+	policyMap map[policy.PolicyID]*secretPolicy
+
+	// lock protects the maps above, which are written by the MonitorIDs
+	// routine and read by each MonitorPolicy routine.
+	lock sync.RWMutex
+}
+
+// secret is the decoded content of a single KV secret.
+type secret struct {
+	version  int
+	policies map[string]*sdk.ScalingPolicy
+}
+
+// secretPolicy is a wrapper around a scaling policy that also provides the
+// secret path and name that it came from.
+type secretPolicy struct {
+	path   string
+	name   string
+	policy *sdk.ScalingPolicy
+}
+
+// NewVaultSource returns a new Vault policy source using the provided config
+// mapping to build the Vault client.
+func NewVaultSource(log hclog.Logger, config map[string]string, policyProcessor *policy.Processor) (policy.Source, error) {
+
+	syncInterval := defaultSyncInterval
+	if v, ok := config[configKeySyncInterval]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", configKeySyncInterval, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%q must be greater than 0", configKeySyncInterval)
+		}
+		syncInterval = d
+	}
+
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mount := configValueMountDefault
+	if v := config[configKeyMount]; v != "" {
+		mount = strings.Trim(v, "/")
+	}
+
+	field := configValueFieldDefault
+	if v := config[configKeyField]; v != "" {
+		field = v
+	}
+
+	s := newSource(log, &vaultKV{client: client, mount: mount},
+		strings.Trim(config[configKeyPath], "/"), field, syncInterval, policyProcessor)
+	s.client = client
+	return s, nil
+}
+
+func newSource(log hclog.Logger, kv kvClient, basePath, field string,
+	syncInterval time.Duration, policyProcessor *policy.Processor) *Source {
+	return &Source{
+		kv:               kv,
+		basePath:         basePath,
+		field:            field,
+		syncInterval:     syncInterval,
+		log:              log.ResetNamed("vault_policy_source"),
+		policyProcessor:  policyProcessor,
+		reloadCh:         make(chan struct{}),
+		reloadCompleteCh: make(chan struct{}, 1),
+		secrets:          make(map[string]*secret),
+		idMap:            make(map[string]policy.PolicyID),
+		policyMap:        make(map[policy.PolicyID]*secretPolicy),
+	}
+}
+
+// newClient builds the Vault client from the default config, which reads
+// the standard VAULT_* environment variables, overridden by the values from
+// the passed config mapping.
+func newClient(config map[string]string) (*api.Client, error) {
+	cfg := api.DefaultConfig()
+	if cfg.Error != nil {
+		return nil, fmt.Errorf("failed to load default Vault config: %v", cfg.Error)
+	}
+
+	if v := config[configKeyAddress]; v != "" {
+		cfg.Address = v
+	}
+
+	tlsConfig := &api.TLSConfig{
+		CACert:        config[configKeyCACert],
+		CAPath:        config[configKeyCAPath],
+		ClientCert:    config[configKeyClientCert],
+		ClientKey:     config[configKeyClientKey],
+		TLSServerName: config[configKeyTLSServerName],
+		Insecure:      config[configKeySkipVerify] == "true",
+	}
+	if err := cfg.ConfigureTLS(tlsConfig); err != nil {
+		return nil, fmt.Errorf("failed to configure Vault TLS: %v", err)
+	}
+
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate Vault client: %v", err)
+	}
+
+	if v := config[configKeyToken]; v != "" {
+		client.SetToken(v)
+	}
+	if v := config[configKeyNamespace]; v != "" {
+		client.SetNamespace(v)
+	}
+	return client, nil
+}
+
+// Name satisfies the Name function of the policy.Source interface.
+func (s *Source) Name() policy.SourceName {
+	return policy.SourceNameVault
+}
+
+// MonitorIDs satisfies the MonitorIDs function of the policy.Source interface.
+func (s *Source) MonitorIDs(ctx context.Context, req policy.MonitorIDsReq) {
+	s.log.Debug("starting vault policy source ID monitor")
+
+	// Keep the Vault token alive for as long as we are monitoring.
+	if s.client != nil {
+		go s.renewToken(ctx)
+	}
+
+	// Perform the first sync before entering the loop, otherwise we wouldn't
+	// load any policies until the first tick.
+	s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Trace("stopping vault policy source ID monitor")
+			return
+
+		case <-ticker.C:
+			s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
+
+		case <-s.reloadCh:
+			s.log.Info("vault policy source ID monitor received reload signal")
+			s.identifyPolicyIDs(ctx, req.ResultCh, req.ErrCh)
+			s.reloadCompleteCh <- struct{}{}
+		}
+	}
+}
+
+// ReloadIDsMonitor satisfies the ReloadIDsMonitor function of the
+// policy.Source interface.
+func (s *Source) ReloadIDsMonitor() {
+	s.reloadCh <- struct{}{}
+	<-s.reloadCompleteCh
+}
+
+// MonitorPolicy satisfies the MonitorPolicy function of the policy.Source
+// interface. Vault is synced by the MonitorIDs routine, so this only needs to
+// check whether the stored policy has changed since it was last sent.
+func (s *Source) MonitorPolicy(ctx context.Context, req policy.MonitorPolicyReq) {
+
+	// Close channels when done with the monitoring loop.
+	defer close(req.ResultCh)
+	defer close(req.ErrCh)
+
+	log := s.log.With("policy_id", req.ID)
+
+	var last *sdk.ScalingPolicy
+
+	sendIfChanged := func() {
+		s.lock.RLock()
+		val, ok := s.policyMap[req.ID]
+		s.lock.RUnlock()
+
+		if !ok || val.policy == nil {
+			policy.HandleSourceError(s.Name(), fmt.Errorf("failed to get policy %s", req.ID), req.ErrCh)
+			return
+		}
+		if last != nil && reflect.DeepEqual(last, val.policy) {
+			return
+		}
+		if last != nil {
+			log.Info("vault policy content has changed", "path", val.path, "name", val.name)
+		}
+		last = val.policy
+		req.ResultCh <- *val.policy
+	}
+
+	sendIfChanged()
+	log.Info("starting vault policy monitor")
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("stopping vault policy monitor due to context done")
+			return
+
+		case <-ticker.C:
+			sendIfChanged()
+
+		case <-req.ReloadCh:
+			log.Info("vault policy source monitor received reload signal")
+			sendIfChanged()
+		}
+	}
+}
+
+// renewToken renews the configured Vault token for as long as the context is
+// active, if the token is renewable. Non-renewable tokens, such as root
+// tokens, are left untouched.
+func (s *Source) renewToken(ctx context.Context) {
+	secret, err := s.client.Auth().Token().RenewSelfWithContext(ctx, 0)
+	if err != nil {
+		s.log.Debug("vault token is not renewable", "error", err)
+		return
+	}
+	if secret == nil || secret.Auth == nil || !secret.Auth.Renewable {
+		s.log.Debug("vault token is not renewable")
+		return
+	}
+
+	watcher, err := s.client.NewLifetimeWatcher(&api.LifetimeWatcherInput{Secret: secret})
+	if err != nil {
+		s.log.Error("failed to setup vault token renewal", "error", err)
+		return
+	}
+
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				s.log.Error("failed to renew vault token", "error", err)
+			} else {
+				s.log.Warn("vault token can no longer be renewed")
+			}
+			return
+		case <-watcher.RenewCh():
+			s.log.Trace("renewed vault token")
+		}
+	}
+}
+
+// identifyPolicyIDs syncs the KV path and sends the discovered policy IDs to
+// the resultCh so the policy manager can do its work.
+func (s *Source) identifyPolicyIDs(ctx context.Context, resultCh chan<- policy.IDMessage, errCh chan<- error) {
+	ids, err := s.sync(ctx)
+	if err != nil {
+		policy.HandleSourceError(s.Name(), err, errCh)
+	}
+
+	// Even if we receive an error we may have IDs to send. Otherwise it may be
+	// that all policies have been removed so we should even send the empty
+	// list so handlers can be cleaned.
+	resultCh <- policy.IDMessage{IDs: ids, Source: s.Name()}
+}
+
+// sync lists the configured KV path recursively, decoding any secret whose
+// version has changed since the last sync. If the listing itself fails, the
+// previously known policies are returned so that handlers are not stopped
+// due to a transient error.
+func (s *Source) sync(ctx context.Context) ([]policy.PolicyID, error) {
+
+	paths, err := s.listPaths(ctx, s.basePath)
+	if err != nil {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		return s.knownIDs(), fmt.Errorf("failed to list vault path %q: %v", s.basePath, err)
+	}
+
+	var mErr *multierror.Error
+	secrets := make(map[string]*secret, len(paths))
+
+	for _, p := range paths {
+		s.lock.RLock()
+		existing, ok := s.secrets[p]
+		s.lock.RUnlock()
+
+		version, data, err := s.kv.get(ctx, p)
+		if err != nil {
+			// The secret could have been deleted between the list and get
+			// calls which isn't an error.
+			if errors.Is(err, errSecretNotFound) {
+				continue
+			}
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to read secret %s: %v", p, err))
+			if ok {
+				secrets[p] = existing
+			}
+			continue
+		}
+
+		if ok && existing.version == version {
+			secrets[p] = existing
+			continue
+		}
+
+		policies, err := s.decodeSecret(p, data)
+		if err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to decode secret %s: %v", p, err))
+
+			// Retain the previous version of the secret so that a bad write
+			// doesn't remove a working policy.
+			if ok {
+				secrets[p] = existing
+			}
+			continue
+		}
+		secrets[p] = &secret{version: version, policies: policies}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.secrets = secrets
+	policyMap := make(map[policy.PolicyID]*secretPolicy)
+
+	for p, sec := range secrets {
+		for name, sp := range sec.policies {
+			policyID := s.getSecretPolicyID(p, name)
+
+			// Ignore the policy if its disabled.
+			if !sp.Enabled {
+				s.log.Trace("policy is disabled therefore ignoring", "policy_id", policyID, "path", p)
+				continue
+			}
+
+			// Work on a copy so the cached secret remains untouched by the
+			// processor.
+			cp, err := copystructure.Copy(sp)
+			if err != nil {
+				mErr = multierror.Append(mErr, fmt.Errorf("failed to copy policy from secret %s: %v", p, err))
+				continue
+			}
+			scalingPolicy := cp.(*sdk.ScalingPolicy)
+			scalingPolicy.ID = policyID.String()
+			s.policyProcessor.ApplyPolicyDefaults(scalingPolicy)
+
+			if err := s.policyProcessor.ValidatePolicy(scalingPolicy); err != nil {
+				mErr = multierror.Append(mErr, fmt.Errorf("failed to validate secret %s: %v", p, err))
+				continue
+			}
+
+			for _, c := range scalingPolicy.Checks {
+				s.policyProcessor.CanonicalizeCheck(c, scalingPolicy.Target)
+			}
+
+			policyMap[policyID] = &secretPolicy{path: p, name: name, policy: scalingPolicy}
+		}
+	}
+	s.policyMap = policyMap
+
+	return s.knownIDs(), mErr.ErrorOrNil()
+}
+
+// knownIDs returns the sorted list of IDs currently held within the policy
+// map. The caller must hold at least a read lock.
+func (s *Source) knownIDs() []policy.PolicyID {
+	ids := make([]policy.PolicyID, 0, len(s.policyMap))
+	for id := range s.policyMap {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// listPaths recursively lists all secret paths below the passed path.
+func (s *Source) listPaths(ctx context.Context, p string) ([]string, error) {
+	keys, err := s.kv.list(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, k := range keys {
+		full := path.Join(p, k)
+		if !strings.HasSuffix(k, "/") {
+			paths = append(paths, full)
+			continue
+		}
+
+		sub, err := s.listPaths(ctx, full)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, sub...)
+	}
+	return paths, nil
+}
+
+// decodeSecret decodes the policy document held within the configured field
+// of the secret data. Documents starting with a brace are treated as JSON,
+// otherwise as HCL.
+func (s *Source) decodeSecret(p string, data map[string]interface{}) (map[string]*sdk.ScalingPolicy, error) {
+	raw, ok := data[s.field]
+	if !ok {
+		return nil, fmt.Errorf("field %q not found", s.field)
+	}
+	doc, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("field %q must be a string", s.field)
+	}
+
+	filename := p + ".hcl"
+	if strings.HasPrefix(strings.TrimSpace(doc), "{") {
+		filename = p + ".json"
+	}
+	return filePolicy.Decode(filename, []byte(doc))
+}
+
+// getSecretPolicyID translates the secret path and policy name into its
+// policyID, generating and storing a new ID if this is the first time the
+// policy has been seen. The caller must hold the write lock.
+func (s *Source) getSecretPolicyID(p, name string) policy.PolicyID {
+	mapKey := p + "/" + name
+
+	policyID, ok := s.idMap[mapKey]
+	if !ok {
+		policyID = policy.PolicyID(uuid.Generate())
+		s.idMap[mapKey] = policyID
+	}
+	return policyID
+}
+
+// vaultKV implements kvClient using the Vault API client.
+type vaultKV struct {
+	client *api.Client
+	mount  string
+}
+
+func (v *vaultKV) list(ctx context.Context, p string) ([]string, error) {
+	secret, err := v.client.Logical().ListWithContext(ctx, path.Join(v.mount, "metadata", p))
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	rawKeys, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(rawKeys))
+	for _, k := range rawKeys {
+		if key, ok := k.(string); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (v *vaultKV) get(ctx context.Context, p string) (int, map[string]interface{}, error) {
+	secret, err := v.client.KVv2(v.mount).Get(ctx, p)
+	if err != nil {
+		if errors.Is(err, api.ErrSecretNotFound) {
+			return 0, nil, errSecretNotFound
+		}
+		return 0, nil, err
+	}
+
+	version := 0
+	if secret.VersionMetadata != nil {
+		version = secret.VersionMetadata.Version
+	}
+	return version, secret.Data, nil
+}
@@ -0,0 +1,170 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+scaling "cluster_policy" {
+  enabled = true
+  min     = 1
+  max     = 10
+
+  policy {
+    check "cpu" {
+      source = "prometheus"
+      query  = "avg(cpu)"
+
+      strategy "target-value" {
+        target = 70
+      }
+    }
+
+    target "aws-asg" {
+      aws_asg_name = "my-asg"
+      node_class   = "hashistack"
+    }
+  }
+}
+`
+
+const testJSONPolicy = `{
+  "scaling": {
+    "json_policy": {
+      "enabled": true,
+      "max": 5,
+      "policy": {
+        "target": {
+          "aws-asg": {
+            "node_class": "worker"
+          }
+        }
+      }
+    }
+  }
+}`
+
+type fakeSecret struct {
+	version int
+	data    map[string]interface{}
+}
+
+// fakeKV is an in-memory implementation of the kvClient interface.
+type fakeKV struct {
+	secrets map[string]fakeSecret
+	listErr error
+}
+
+func (f *fakeKV) list(_ context.Context, p string) ([]string, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+
+	seen := map[string]bool{}
+	for k := range f.secrets {
+		rel := strings.TrimPrefix(k, p+"/")
+		if rel == k {
+			continue
+		}
+		if i := strings.Index(rel, "/"); i >= 0 {
+			rel = rel[:i+1]
+		}
+		seen[rel] = true
+	}
+
+	var keys []string
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (f *fakeKV) get(_ context.Context, p string) (int, map[string]interface{}, error) {
+	s, ok := f.secrets[path.Clean(p)]
+	if !ok {
+		return 0, nil, errSecretNotFound
+	}
+	return s.version, s.data, nil
+}
+
+func newTestSource(kv kvClient) *Source {
+	processor := policy.NewProcessor(&policy.ConfigDefaults{
+		DefaultEvaluationInterval: 10 * time.Second,
+		DefaultCooldown:           time.Minute,
+	}, nil)
+	return newSource(hclog.NewNullLogger(), kv, "autoscaler", "policy", time.Minute, processor)
+}
+
+func TestSource_sync(t *testing.T) {
+	kv := &fakeKV{secrets: map[string]fakeSecret{
+		"autoscaler/cluster":      {version: 1, data: map[string]interface{}{"policy": testPolicy}},
+		"autoscaler/team-a/queue": {version: 1, data: map[string]interface{}{"policy": testJSONPolicy}},
+	}}
+	s := newTestSource(kv)
+
+	// The first sync should find policies in nested paths and in both
+	// formats.
+	ids, err := s.sync(context.Background())
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+
+	names := map[string]string{}
+	for _, id := range ids {
+		names[s.policyMap[id].name] = s.policyMap[id].path
+	}
+	assert.Equal(t, map[string]string{
+		"cluster_policy": "autoscaler/cluster",
+		"json_policy":    "autoscaler/team-a/queue",
+	}, names)
+
+	// Record the decoded secret so we can verify it is reused.
+	cached := s.secrets["autoscaler/cluster"]
+
+	ids2, err := s.sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ids, ids2)
+	assert.Same(t, cached, s.secrets["autoscaler/cluster"])
+
+	// A new version should be decoded again while keeping the policy ID.
+	kv.secrets["autoscaler/cluster"] = fakeSecret{version: 2, data: map[string]interface{}{"policy": testPolicy}}
+	ids3, err := s.sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ids, ids3)
+	assert.NotSame(t, cached, s.secrets["autoscaler/cluster"])
+
+	// A secret missing the policy field should not remove the working
+	// policy.
+	kv.secrets["autoscaler/cluster"] = fakeSecret{version: 3, data: map[string]interface{}{"other": "value"}}
+	ids4, err := s.sync(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `field "policy" not found`)
+	assert.Equal(t, ids, ids4)
+
+	// A listing failure should retain the known policies.
+	kv.listErr = errors.New("permission denied")
+	ids5, err := s.sync(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, ids, ids5)
+
+	// Deleting a secret should remove its policy.
+	kv.listErr = nil
+	delete(kv.secrets, "autoscaler/team-a/queue")
+	ids6, err := s.sync(context.Background())
+	require.Error(t, err)
+	assert.Len(t, ids6, 1)
+}
@@ -0,0 +1,244 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/mitchellh/copystructure"
+)
+
+// DefaultVersionHistoryLimit is the number of versions kept for each policy
+// when no limit is configured.
+const DefaultVersionHistoryLimit = 10
+
+var (
+	// ErrPolicyNotFound is returned when the requested policy does not have
+	// any recorded versions.
+	ErrPolicyNotFound = errors.New("policy not found")
+
+	// ErrPolicyVersionNotFound is returned when the requested version of a
+	// policy is not stored.
+	ErrPolicyVersionNotFound = errors.New("policy version not found")
+)
+
+// PolicyVersion is a single version of a policy, as received from its source.
+type PolicyVersion struct {
+
+	// Version is a monotonically increasing number which identifies this
+	// version of the policy.
+	Version uint64
+
+	// Source is the policy source which provided this version.
+	Source SourceName
+
+	// Received is the time the agent received this version.
+	Received time.Time
+
+	// Policy is the policy as received from the source.
+	Policy *sdk.ScalingPolicy
+
+	// Changes summarizes the fields which differ from the previous version,
+	// such as "max: 10 -> 50". It is empty for the first recorded version.
+	Changes []string
+}
+
+// PolicyVersions details the stored versions of a policy.
+type PolicyVersions struct {
+	ID PolicyID
+
+	// PinnedVersion is the version an operator has pinned the policy to. A
+	// value of zero indicates the policy is not pinned and the latest version
+	// is used.
+	PinnedVersion uint64
+
+	// Versions lists the stored versions, ordered from oldest to newest.
+	Versions []*PolicyVersion
+}
+
+// VersionStore keeps the last N versions of each policy so operators can
+// inspect recent changes and pin a policy to a previous version. A nil
+// VersionStore is safe to use and does not record versions.
+type VersionStore struct {
+	limit int
+
+	lock     sync.RWMutex
+	policies map[PolicyID]*policyVersions
+}
+
+type policyVersions struct {
+	next     uint64
+	pinned   uint64
+	versions []*PolicyVersion
+}
+
+// NewVersionStore returns a new VersionStore which keeps up to limit versions
+// of each policy. A limit of zero uses DefaultVersionHistoryLimit.
+func NewVersionStore(limit int) *VersionStore {
+	if limit <= 0 {
+		limit = DefaultVersionHistoryLimit
+	}
+	return &VersionStore{
+		limit:    limit,
+		policies: make(map[PolicyID]*policyVersions),
+	}
+}
+
+// Record stores p as the newest version of the policy, unless it is identical
+// to the current newest version. The changes summarise how p differs from the
+// previous version, as returned by policyChanges. The oldest versions are
+// discarded once the limit is reached, with the exception of the pinned
+// version.
+func (s *VersionStore) Record(id PolicyID, source SourceName, p *sdk.ScalingPolicy, changes []string) {
+	if s == nil || p == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pv, ok := s.policies[id]
+	if !ok {
+		pv = &policyVersions{next: 1}
+		s.policies[id] = pv
+	}
+
+	if n := len(pv.versions); n > 0 && reflect.DeepEqual(pv.versions[n-1].Policy, p) {
+		return
+	}
+
+	// Store a copy so later changes to the handler policy do not modify the
+	// stored history.
+	pCopy, err := copystructure.Copy(p)
+	if err != nil {
+		return
+	}
+
+	pv.versions = append(pv.versions, &PolicyVersion{
+		Version:  pv.next,
+		Source:   source,
+		Received: time.Now().UTC(),
+		Policy:   pCopy.(*sdk.ScalingPolicy),
+		Changes:  changes,
+	})
+	pv.next++
+
+	// The pinned version is retained even when it exceeds the limit, as is
+	// the newest version.
+	for len(pv.versions) > s.limit {
+		i := 0
+		if pv.versions[0].Version == pv.pinned {
+			i = 1
+		}
+		if i == len(pv.versions)-1 {
+			break
+		}
+		pv.versions = append(pv.versions[:i], pv.versions[i+1:]...)
+	}
+}
+
+// Effective returns the policy which should be used for the passed policy
+// ID. This is the pinned version if one is set, otherwise latest.
+func (s *VersionStore) Effective(id PolicyID, latest *sdk.ScalingPolicy) *sdk.ScalingPolicy {
+	if s == nil {
+		return latest
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	pv, ok := s.policies[id]
+	if !ok || pv.pinned == 0 {
+		return latest
+	}
+
+	for _, v := range pv.versions {
+		if v.Version == pv.pinned {
+			pCopy, err := copystructure.Copy(v.Policy)
+			if err != nil {
+				return latest
+			}
+			return pCopy.(*sdk.ScalingPolicy)
+		}
+	}
+	return latest
+}
+
+// Versions returns the stored versions of the passed policy.
+func (s *VersionStore) Versions(id PolicyID) (*PolicyVersions, error) {
+	if s == nil {
+		return nil, ErrPolicyNotFound
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	pv, ok := s.policies[id]
+	if !ok {
+		return nil, ErrPolicyNotFound
+	}
+
+	versions := make([]*PolicyVersion, len(pv.versions))
+	copy(versions, pv.versions)
+
+	return &PolicyVersions{ID: id, PinnedVersion: pv.pinned, Versions: versions}, nil
+}
+
+// Pin instructs the store to use the passed version of a policy instead of
+// the latest until Unpin is called.
+func (s *VersionStore) Pin(id PolicyID, version uint64) error {
+	if s == nil {
+		return ErrPolicyNotFound
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pv, ok := s.policies[id]
+	if !ok {
+		return ErrPolicyNotFound
+	}
+
+	for _, v := range pv.versions {
+		if v.Version == version {
+			pv.pinned = version
+			return nil
+		}
+	}
+	return ErrPolicyVersionNotFound
+}
+
+// Unpin removes any pinned version of the passed policy, so the latest
+// version is used.
+func (s *VersionStore) Unpin(id PolicyID) error {
+	if s == nil {
+		return ErrPolicyNotFound
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pv, ok := s.policies[id]
+	if !ok {
+		return ErrPolicyNotFound
+	}
+	pv.pinned = 0
+	return nil
+}
+
+// Remove deletes all stored versions of the passed policy.
+func (s *VersionStore) Remove(id PolicyID) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.policies, id)
+}
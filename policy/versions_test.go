@@ -0,0 +1,72 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionStore(t *testing.T) {
+	s := NewVersionStore(2)
+
+	p1 := &sdk.ScalingPolicy{ID: "a", Max: 1}
+	p2 := &sdk.ScalingPolicy{ID: "a", Max: 2}
+	p3 := &sdk.ScalingPolicy{ID: "a", Max: 3}
+
+	_, err := s.Versions("a")
+	assert.ErrorIs(t, err, ErrPolicyNotFound)
+
+	// Identical policies should only be recorded once.
+	s.Record("a", SourceNameFile, p1, nil)
+	s.Record("a", SourceNameFile, &sdk.ScalingPolicy{ID: "a", Max: 1}, nil)
+	s.Record("a", SourceNameFile, p2, policyChanges(p1, p2))
+
+	versions, err := s.Versions("a")
+	require.NoError(t, err)
+	require.Len(t, versions.Versions, 2)
+	assert.Equal(t, uint64(1), versions.Versions[0].Version)
+	assert.Equal(t, uint64(2), versions.Versions[1].Version)
+	assert.Equal(t, SourceNameFile, versions.Versions[0].Source)
+	assert.Empty(t, versions.Versions[0].Changes)
+	assert.Equal(t, []string{"max: 1 -> 2"}, versions.Versions[1].Changes)
+	assert.Same(t, p2, s.Effective("a", p2))
+
+	// Pinning should return the pinned version, even after it would have
+	// been discarded due to the limit.
+	require.NoError(t, s.Pin("a", 1))
+	s.Record("a", SourceNameFile, p3, policyChanges(p2, p3))
+	assert.Equal(t, int64(1), s.Effective("a", p3).Max)
+
+	versions, err = s.Versions("a")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), versions.PinnedVersion)
+	require.Len(t, versions.Versions, 2)
+	assert.Equal(t, uint64(1), versions.Versions[0].Version)
+	assert.Equal(t, uint64(3), versions.Versions[1].Version)
+	assert.Equal(t, []string{"max: 2 -> 3"}, versions.Versions[1].Changes)
+
+	assert.ErrorIs(t, s.Pin("a", 2), ErrPolicyVersionNotFound)
+	assert.ErrorIs(t, s.Pin("b", 1), ErrPolicyNotFound)
+
+	// Unpinning should return to the latest version.
+	require.NoError(t, s.Unpin("a"))
+	assert.Same(t, p3, s.Effective("a", p3))
+
+	s.Remove("a")
+	_, err = s.Versions("a")
+	assert.ErrorIs(t, err, ErrPolicyNotFound)
+}
+
+func TestVersionStore_nil(t *testing.T) {
+	var s *VersionStore
+
+	p := &sdk.ScalingPolicy{ID: "a"}
+	s.Record("a", SourceNameFile, p, nil)
+	assert.Same(t, p, s.Effective("a", p))
+	assert.ErrorIs(t, s.Pin("a", 1), ErrPolicyNotFound)
+}
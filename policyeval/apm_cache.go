@@ -0,0 +1,154 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// APMCache caches APM query results for a short period of time, so that
+// identical queries from different policies reuse the same result instead of
+// each querying the APM. Concurrent identical queries are coalesced into a
+// single call. A nil APMCache is safe to use and never caches.
+type APMCache struct {
+	defaultTTL time.Duration
+	sourceTTLs map[string]time.Duration
+
+	lock    sync.Mutex
+	entries map[apmCacheKey]*apmCacheEntry
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+// apmCacheKey identifies a query result. The query window is part of the key
+// since the same query over a different window returns different metrics.
+type apmCacheKey struct {
+	source string
+	query  string
+	window time.Duration
+}
+
+// apmCacheEntry is a cached result. The done channel is closed once the
+// query has completed and the result is available.
+type apmCacheEntry struct {
+	done    chan struct{}
+	metrics sdk.TimestampedMetrics
+	err     error
+	created time.Time
+	expires time.Time
+}
+
+// NewAPMCache returns a new APMCache which caches results for ttl, unless
+// the source has its own TTL in sourceTTLs.
+func NewAPMCache(ttl time.Duration, sourceTTLs map[string]time.Duration) *APMCache {
+	return &APMCache{
+		defaultTTL: ttl,
+		sourceTTLs: sourceTTLs,
+		entries:    make(map[apmCacheKey]*apmCacheEntry),
+		now:        time.Now,
+	}
+}
+
+// ttl returns the cache TTL for the source.
+func (c *APMCache) ttl(source string) time.Duration {
+	if ttl, ok := c.sourceTTLs[source]; ok {
+		return ttl
+	}
+	return c.defaultTTL
+}
+
+// Query returns the cached result for the query, calling fn to perform the
+// query if there is no valid result. Errors are not cached. If maxAge is
+// positive, results older than it are not used even if they have not expired,
+// so policies evaluated more often than the cache TTL still receive fresh
+// metrics.
+func (c *APMCache) Query(source, query string, window, maxAge time.Duration, fn func() (sdk.TimestampedMetrics, error)) (sdk.TimestampedMetrics, error) {
+	if c == nil || c.ttl(source) <= 0 {
+		return fn()
+	}
+
+	key := apmCacheKey{source: source, query: query, window: window}
+	labels := []metrics.Label{{Name: "plugin_name", Value: source}}
+
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		select {
+		case <-entry.done:
+			// Discard expired, stale or failed results.
+			if entry.err != nil || !c.now().Before(entry.expires) ||
+				(maxAge > 0 && c.now().Sub(entry.created) >= maxAge) {
+				ok = false
+			}
+		default:
+			// The query is in flight, so wait for its result.
+		}
+	}
+
+	if ok {
+		c.lock.Unlock()
+		<-entry.done
+		if entry.err == nil {
+			metrics.IncrCounterWithLabels([]string{"plugin", "apm", "query", "cache_hit"}, 1, labels)
+			return copyMetrics(entry.metrics), nil
+		}
+		// The in-flight query failed, so perform our own.
+		return fn()
+	}
+
+	c.pruneLocked()
+	entry = &apmCacheEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.lock.Unlock()
+
+	metrics.IncrCounterWithLabels([]string{"plugin", "apm", "query", "cache_miss"}, 1, labels)
+
+	m, err := fn()
+
+	c.lock.Lock()
+	entry.metrics, entry.err = m, err
+	entry.created = c.now()
+	entry.expires = entry.created.Add(c.ttl(source))
+	if err != nil {
+		delete(c.entries, key)
+	}
+	close(entry.done)
+	c.lock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return copyMetrics(m), nil
+}
+
+// pruneLocked removes expired entries from the cache. It must be called
+// while holding the lock.
+func (c *APMCache) pruneLocked() {
+	now := c.now()
+	for k, e := range c.entries {
+		select {
+		case <-e.done:
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		default:
+		}
+	}
+}
+
+// copyMetrics returns a copy of the input, so that callers sorting or
+// modifying their result do not affect the cached value.
+func copyMetrics(m sdk.TimestampedMetrics) sdk.TimestampedMetrics {
+	if m == nil {
+		return nil
+	}
+	out := make(sdk.TimestampedMetrics, len(m))
+	copy(out, m)
+	return out
+}
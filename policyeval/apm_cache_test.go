@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPMCache_Query(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := NewAPMCache(10*time.Second, map[string]time.Duration{"nomad-apm": 0})
+	cache.now = func() time.Time { return now }
+
+	var calls int
+	fn := func() (sdk.TimestampedMetrics, error) {
+		calls++
+		return sdk.TimestampedMetrics{{Timestamp: now, Value: float64(calls)}}, nil
+	}
+
+	// Identical queries within the TTL reuse the result.
+	m, err := cache.Query("prometheus", "up", time.Minute, 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), m[0].Value)
+
+	m[0].Value = 42
+	m, err = cache.Query("prometheus", "up", time.Minute, 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), m[0].Value, "result should not be modified by callers")
+	assert.Equal(t, 1, calls)
+
+	// A different query window is a different result.
+	_, err = cache.Query("prometheus", "up", 5*time.Minute, 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// Results expire after the TTL.
+	now = now.Add(10 * time.Second)
+	m, err = cache.Query("prometheus", "up", time.Minute, 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), m[0].Value)
+	assert.Len(t, cache.entries, 1, "expired entries should be pruned")
+
+	// Sources with a zero TTL are not cached.
+	_, err = cache.Query("nomad-apm", "up", time.Minute, 0, fn)
+	require.NoError(t, err)
+	_, err = cache.Query("nomad-apm", "up", time.Minute, 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls)
+
+	// Errors are not cached.
+	_, err = cache.Query("prometheus", "down", time.Minute, 0, func() (sdk.TimestampedMetrics, error) {
+		return nil, errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+	_, err = cache.Query("prometheus", "down", time.Minute, 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, 6, calls)
+
+	// A nil cache always performs the query.
+	var nilCache *APMCache
+	_, err = nilCache.Query("prometheus", "up", time.Minute, 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, 7, calls)
+}
+
+func TestAPMCache_Query_concurrent(t *testing.T) {
+	cache := NewAPMCache(time.Minute, nil)
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (sdk.TimestampedMetrics, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return sdk.TimestampedMetrics{{Timestamp: time.Now(), Value: 1}}, nil
+	}
+
+	// Concurrent identical queries wait for the in-flight query.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m, err := cache.Query("prometheus", "up", time.Minute, 0, fn)
+			assert.NoError(t, err)
+			assert.Len(t, m, 1)
+		}()
+	}
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 10*time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestAPMCache_Query_maxAge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := NewAPMCache(time.Minute, nil)
+	cache.now = func() time.Time { return now }
+
+	var calls int
+	fn := func() (sdk.TimestampedMetrics, error) {
+		calls++
+		return sdk.TimestampedMetrics{{Timestamp: now, Value: float64(calls)}}, nil
+	}
+
+	_, err := cache.Query("prometheus", "up", time.Minute, 5*time.Second, fn)
+	require.NoError(t, err)
+
+	// Results younger than the max age are reused.
+	now = now.Add(4 * time.Second)
+	m, err := cache.Query("prometheus", "up", time.Minute, 5*time.Second, fn)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), m[0].Value)
+
+	// Results are refreshed once they reach the max age, even within the TTL.
+	now = now.Add(time.Second)
+	m, err = cache.Query("prometheus", "up", time.Minute, 5*time.Second, fn)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), m[0].Value)
+
+	// Queries without a max age use the TTL.
+	now = now.Add(30 * time.Second)
+	m, err = cache.Query("prometheus", "up", time.Minute, 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), m[0].Value)
+	assert.Equal(t, 2, calls)
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -25,6 +26,16 @@ import (
 // is not ready.
 var errTargetNotReady = errors.New("target not ready")
 
+// Defaults used when a check retries queries without configuring the backoff.
+const (
+	defaultQueryRetryBackoff    = time.Second
+	defaultQueryRetryMaxBackoff = 30 * time.Second
+)
+
+// defaultMissingDataMaxStaleness is the maximum age of the last known result
+// used by checks with the "last" missing data behaviour when not configured.
+const defaultMissingDataMaxStaleness = 5 * time.Minute
+
 // Worker is responsible for executing a policy evaluation request.
 type BaseWorker struct {
 	id            string
@@ -32,20 +43,67 @@ type BaseWorker struct {
 	pluginManager *manager.PluginManager
 	policyManager *policy.Manager
 	broker        *Broker
+	guardrail     *Guardrail
+	apmCache      *APMCache
+	lastMetrics   *LastMetrics
+	activities    *TargetActivities
+	desiredCounts *DesiredCounts
 	queue         string
 }
 
+// BaseWorkerConfig is the configuration used to create a BaseWorker.
+type BaseWorkerConfig struct {
+	// Logger is the logger used by the worker.
+	Logger hclog.Logger
+
+	// PluginManager is used to retrieve the plugins used by policies.
+	PluginManager *manager.PluginManager
+
+	// PolicyManager is used to look up the current state of policies.
+	PolicyManager *policy.Manager
+
+	// Broker is the evaluation broker the worker dequeues evaluations from.
+	Broker *Broker
+
+	// Guardrail enforces global rules on the scaling actions of policies. It
+	// is optional.
+	Guardrail *Guardrail
+
+	// APMCache shares the results of identical APM queries between policies.
+	// It is optional.
+	APMCache *APMCache
+
+	// LastMetrics stores the last metrics returned by each check. It is
+	// optional.
+	LastMetrics *LastMetrics
+
+	// Activities records the scaling activity of each target. It is optional.
+	Activities *TargetActivities
+
+	// DesiredCounts stores the desired count computed for each policy. It is
+	// optional.
+	DesiredCounts *DesiredCounts
+
+	// Queue is the name of the broker queue the worker reads from.
+	Queue string
+}
+
 // NewBaseWorker returns a new BaseWorker instance.
-func NewBaseWorker(l hclog.Logger, pm *manager.PluginManager, m *policy.Manager, b *Broker, queue string) *BaseWorker {
+func NewBaseWorker(cfg *BaseWorkerConfig) *BaseWorker {
 	id := uuid.Generate()
 
 	return &BaseWorker{
 		id:            id,
-		logger:        l.Named("worker").With("id", id, "queue", queue),
-		pluginManager: pm,
-		policyManager: m,
-		broker:        b,
-		queue:         queue,
+		logger:        cfg.Logger.Named("worker").With("id", id, "queue", cfg.Queue),
+		pluginManager: cfg.PluginManager,
+		policyManager: cfg.PolicyManager,
+		broker:        cfg.Broker,
+		guardrail:     cfg.Guardrail,
+		apmCache:      cfg.APMCache,
+		lastMetrics:   cfg.LastMetrics,
+		activities:    cfg.Activities,
+		desiredCounts: cfg.DesiredCounts,
+		queue:         cfg.Queue,
 	}
 }
 
@@ -116,34 +174,28 @@ func (w *BaseWorker) handlePolicy(ctx context.Context, eval *sdk.ScalingEvaluati
 	if err != nil {
 		return fmt.Errorf("failed to get target status: %v", err)
 	}
+	w.reportTargetActivities(logger, eval.Policy, currentStatus, labels)
 
 	if !currentStatus.Ready {
 		return errTargetNotReady
 	}
 
-	// First make sure the target is within the policy limits.
-	// Return early after scaling since we already modified the target.
-	if currentStatus.Count < eval.Policy.Min {
-		reason := fmt.Sprintf("scaling up because current count %d is lower than policy min value of %d",
-			currentStatus.Count, eval.Policy.Min)
-
-		action := sdk.ScalingAction{
-			Count:     eval.Policy.Min,
-			Reason:    reason,
-			Direction: sdk.ScaleDirectionUp,
+	// Scale the target back if its count was changed outside of the
+	// autoscaler and the policy reconciles such changes.
+	if action := w.checkOutOfBand(logger, eval.Policy, currentStatus, labels); action != nil {
+		if ok, err := w.guardrail.Evaluate(ctx, eval.Policy, action, currentStatus.Count); !ok {
+			return err
+		}
+		if w.dryRunAction(logger, target, eval.Policy, *action, currentStatus, labels) {
+			return nil
 		}
-		return w.scaleTarget(logger, target, eval.Policy, action, currentStatus)
+		return w.scaleTarget(logger, target, eval.Policy, *action, currentStatus)
 	}
-	if currentStatus.Count > eval.Policy.Max {
-		reason := fmt.Sprintf("scaling down because current count %d is greater than policy max value of %d",
-			currentStatus.Count, eval.Policy.Max)
 
-		action := sdk.ScalingAction{
-			Count:     eval.Policy.Max,
-			Reason:    reason,
-			Direction: sdk.ScaleDirectionDown,
-		}
-		return w.scaleTarget(logger, target, eval.Policy, action, currentStatus)
+	// First make sure the target is within the policy limits.
+	// Return early after scaling since we already modified the target.
+	if ok, err := w.enforceLimits(ctx, logger, target, eval.Policy, currentStatus, labels); ok {
+		return err
 	}
 
 	// Prepare handlers.
@@ -155,7 +207,7 @@ func (w *BaseWorker) handlePolicy(ctx context.Context, eval *sdk.ScalingEvaluati
 
 	// Start check handlers.
 	for _, checkEval := range eval.CheckEvaluations {
-		checkHandler := newCheckHandler(logger, eval.Policy, checkEval, w.pluginManager)
+		checkHandler := newCheckHandler(logger, eval.Policy, checkEval, w.pluginManager, w.apmCache, w.lastMetrics)
 
 		// Wrap target status call in a goroutine so we can listen for ctx as well.
 		var action *sdk.ScalingAction
@@ -254,13 +306,30 @@ func (w *BaseWorker) handlePolicy(ctx context.Context, eval *sdk.ScalingEvaluati
 	// tracking how long it takes to run all the checks within a policy.
 	metrics.MeasureSinceWithLabels([]string{"scale", "evaluate_ms"}, evalStartTime, labels)
 
-	if winner.handler == nil || winner.action == nil || winner.action.Direction == sdk.ScaleDirectionNone {
+	// Evaluations triggered by blocked allocations scale the target out far
+	// enough to place them, unless the checks already scale it further.
+	if action := provisionAction(eval, currentStatus.Count, winner.action); action != nil {
+		logger.Debug("scaling out to place blocked allocations",
+			"provision_count", eval.ProvisionCount, "count", action.Count)
+		winner = checkResult{action: action}
+	} else if winner.handler == nil || winner.action == nil || winner.action.Direction == sdk.ScaleDirectionNone {
 		logger.Debug("no checks need to be executed")
 		return nil
+	} else {
+		logger.Debug(fmt.Sprintf("check %s selected", winner.handler.checkEval.Check.Name),
+			"direction", winner.action.Direction, "count", winner.action.Count)
 	}
 
-	logger.Debug(fmt.Sprintf("check %s selected", winner.handler.checkEval.Check.Name),
-		"direction", winner.action.Direction, "count", winner.action.Count)
+	// Allow the guardrail to deny or clamp the action before it is taken.
+	if ok, err := w.guardrail.Evaluate(ctx, eval.Policy, winner.action, currentStatus.Count); !ok {
+		return err
+	}
+
+	// If the policy is in dry-run mode, report the action that would have
+	// been taken without calling the target.
+	if w.dryRunAction(logger, target, eval.Policy, *winner.action, currentStatus, labels) {
+		return nil
+	}
 
 	// Measure how long it takes to invoke the scaling actions. This helps
 	// understand the time taken to interact with the remote target and action
@@ -294,6 +363,234 @@ func (w *BaseWorker) handlePolicy(ctx context.Context, eval *sdk.ScalingEvaluati
 	return nil
 }
 
+// checkOutOfBand compares the count of a cluster target with the count the
+// autoscaler last scaled it to, in order to detect capacity changes made
+// outside of the autoscaler. Changes are reported, and the action scaling the
+// target back to the last desired count, within the policy limits, is
+// returned if the policy reconciles out of band changes. Otherwise the new
+// count is adopted.
+func (w *BaseWorker) checkOutOfBand(
+	logger hclog.Logger,
+	policy *sdk.ScalingPolicy,
+	status *sdk.TargetStatus,
+	labels []metrics.Label,
+) *sdk.ScalingAction {
+
+	if policy.Type != sdk.ScalingPolicyTypeCluster {
+		return nil
+	}
+
+	desired, ok := w.desiredCounts.Get(policy.ID)
+	if !ok || desired == status.Count {
+		return nil
+	}
+
+	logger.Warn("detected target count change made outside of the autoscaler",
+		"expected", desired, "actual", status.Count)
+	metrics.IncrCounterWithLabels([]string{"scale", "target", "out_of_band_count"}, 1, labels)
+
+	// Forget the desired count, so the change is only reported once if the
+	// target is not scaled back.
+	w.desiredCounts.Delete(policy.ID)
+
+	reconcile := false
+	if val, ok := policy.Target.Config[sdk.TargetConfigKeyReconcileOutOfBand]; ok {
+		var err error
+		if reconcile, err = strconv.ParseBool(val); err != nil {
+			logger.Warn("failed to parse target config value, not reconciling",
+				"key", sdk.TargetConfigKeyReconcileOutOfBand, "error", err)
+			return nil
+		}
+	}
+	if !reconcile || policy.DryRun {
+		return nil
+	}
+
+	if desired < policy.Min {
+		desired = policy.Min
+	}
+	if desired > policy.Max {
+		desired = policy.Max
+	}
+	if desired == status.Count {
+		return nil
+	}
+
+	action := sdk.ScalingAction{
+		Count:     desired,
+		Direction: sdk.ScaleDirectionDown,
+		Reason: fmt.Sprintf("reconciling out of band change, scaling back to %d after count was changed to %d outside of the autoscaler",
+			desired, status.Count),
+	}
+	if desired > status.Count {
+		action.Direction = sdk.ScaleDirectionUp
+	}
+	return &action
+}
+
+// enforceLimits scales the target to the policy min or max if its current
+// count is outside of them. It returns true if the count was outside of the
+// limits, in which case the evaluation is complete and no checks should be
+// run.
+func (w *BaseWorker) enforceLimits(
+	ctx context.Context,
+	logger hclog.Logger,
+	targetImpl target.Target,
+	policy *sdk.ScalingPolicy,
+	currentStatus *sdk.TargetStatus,
+	labels []metrics.Label,
+) (bool, error) {
+
+	var action sdk.ScalingAction
+
+	switch {
+	case currentStatus.Count < policy.Min:
+		action = sdk.ScalingAction{
+			Count: policy.Min,
+			Reason: fmt.Sprintf("scaling up because current count %d is lower than policy min value of %d",
+				currentStatus.Count, policy.Min),
+			Direction: sdk.ScaleDirectionUp,
+		}
+	case currentStatus.Count > policy.Max:
+		action = sdk.ScalingAction{
+			Count: policy.Max,
+			Reason: fmt.Sprintf("scaling down because current count %d is greater than policy max value of %d",
+				currentStatus.Count, policy.Max),
+			Direction: sdk.ScaleDirectionDown,
+		}
+	default:
+		return false, nil
+	}
+
+	if ok, err := w.guardrail.Evaluate(ctx, policy, &action, currentStatus.Count); !ok {
+		return true, err
+	}
+	if w.dryRunAction(logger, targetImpl, policy, action, currentStatus, labels) {
+		return true, nil
+	}
+	return true, w.scaleTarget(logger, targetImpl, policy, action, currentStatus)
+}
+
+// dryRunAction reports the action that would have been taken if the policy
+// is in dry-run mode, returning true if so. The target is not scaled, so
+// callers must only call scaleTarget when false is returned.
+func (w *BaseWorker) dryRunAction(
+	logger hclog.Logger,
+	targetImpl target.Target,
+	policy *sdk.ScalingPolicy,
+	action sdk.ScalingAction,
+	currentStatus *sdk.TargetStatus,
+	labels []metrics.Label,
+) bool {
+
+	if !policy.DryRun {
+		return false
+	}
+
+	logger.Info("policy dry-run is enabled, skipping scaling action",
+		"from", currentStatus.Count, "to", action.Count,
+		"direction", action.Direction, "reason", action.Reason)
+	w.dryRunTarget(logger, targetImpl, policy, action)
+
+	dryRunLabels := append(labels, metrics.Label{Name: "direction", Value: action.Direction.String()})
+	metrics.IncrCounterWithLabels([]string{"scale", "dry_run", "count"}, 1, dryRunLabels)
+	metrics.SetGaugeWithLabels([]string{"scale", "dry_run", "desired_count"}, float32(action.Count), labels)
+	return true
+}
+
+// provisionAction returns the action scaling a cluster target out by the
+// number of nodes required to place the blocked allocations which triggered
+// the evaluation, within the policy max, replacing the winning check action.
+// A nil action is returned if the evaluation was not triggered by blocked
+// allocations, the target cannot be scaled out, the winning action already
+// scales it further, or the target is in dry-run mode.
+func provisionAction(eval *sdk.ScalingEvaluation, current int64, winner *sdk.ScalingAction) *sdk.ScalingAction {
+	if eval.ProvisionCount <= 0 || eval.Policy.Type != sdk.ScalingPolicyTypeCluster {
+		return nil
+	}
+	if winner != nil && winner.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return nil
+	}
+	if eval.Policy.Target != nil && eval.Policy.Target.Config["dry-run"] == "true" {
+		return nil
+	}
+
+	count := current + eval.ProvisionCount
+	if count > eval.Policy.Max {
+		count = eval.Policy.Max
+	}
+	if count <= current {
+		return nil
+	}
+	if winner != nil && winner.Direction == sdk.ScaleDirectionUp && winner.Count >= count {
+		return nil
+	}
+
+	return &sdk.ScalingAction{
+		Count:     count,
+		Direction: sdk.ScaleDirectionUp,
+		Reason: fmt.Sprintf("scaling up by %d to place blocked allocations, %d nodes required",
+			count-current, eval.ProvisionCount),
+		Meta: map[string]interface{}{"nomad_policy_id": eval.Policy.ID},
+	}
+}
+
+// reportTargetActivities surfaces the scaling activities reported by the
+// target whose status changed since the policy was last evaluated, so failures
+// of the provider to fulfil scaling actions are visible in the agent logs and
+// metrics.
+func (w *BaseWorker) reportTargetActivities(
+	logger hclog.Logger,
+	policy *sdk.ScalingPolicy,
+	status *sdk.TargetStatus,
+	labels []metrics.Label,
+) {
+
+	for _, a := range w.activities.Changed(policy.ID, status.Activities) {
+		activityLogger := logger.With("activity_id", a.ID, "description", a.Description)
+
+		switch a.Status {
+		case sdk.TargetActivityStatusFailed:
+			activityLogger.Warn("target scaling activity failed", "message", a.Message)
+		case sdk.TargetActivityStatusCancelled:
+			activityLogger.Info("target scaling activity cancelled", "message", a.Message)
+		default:
+			activityLogger.Debug("target scaling activity updated", "status", a.Status)
+		}
+
+		activityLabels := append(labels, metrics.Label{Name: "status", Value: string(a.Status)})
+		metrics.IncrCounterWithLabels([]string{"scale", "target", "activity_count"}, 1, activityLabels)
+	}
+}
+
+// dryRunTarget asks the target what it would do to fulfil the action, such
+// as which nodes would be drained, and logs the result. Targets which do not
+// support dry runs are skipped, and errors are only logged as the policy is
+// not scaled either way.
+func (w *BaseWorker) dryRunTarget(
+	logger hclog.Logger,
+	targetImpl target.Target,
+	policy *sdk.ScalingPolicy,
+	action sdk.ScalingAction,
+) {
+
+	result, err := target.DryRun(targetImpl, action, policy.Target.Config)
+	if err != nil {
+		if err != target.ErrDryRunUnsupported {
+			logger.Warn("failed to perform target dry-run", "error", err)
+		}
+		return
+	}
+
+	nodes := make([]string, 0, len(result.Nodes))
+	for _, node := range result.Nodes {
+		nodes = append(nodes, node.NomadNodeID)
+	}
+
+	logger.Info("target dry-run result",
+		"count", result.Count, "nodes", nodes, "meta", result.Meta)
+}
+
 // scaleTarget performs all the necessary checks and actions necessary to scale
 // a target.
 func (w *BaseWorker) scaleTarget(
@@ -320,10 +617,18 @@ func (w *BaseWorker) scaleTarget(
 			return nil
 		}
 
+		// The count of the target is unknown after a failed action, so it
+		// cannot be used to detect out of band changes.
+		w.desiredCounts.Delete(policy.ID)
+
 		metrics.IncrCounter([]string{"scale", "invoke", "error_count"}, 1)
 		return fmt.Errorf("failed to scale target: %v", err)
 	}
 
+	if policy.Type == sdk.ScalingPolicyTypeCluster && action.Count != sdk.StrategyActionMetaValueDryRunCount {
+		w.desiredCounts.Set(policy.ID, action.Count)
+	}
+
 	logger.Debug("successfully submitted scaling action to target",
 		"desired_count", action.Count)
 	metrics.IncrCounter([]string{"scale", "invoke", "success_count"}, 1)
@@ -360,10 +665,12 @@ type checkHandler struct {
 	policy        *sdk.ScalingPolicy
 	checkEval     *sdk.ScalingCheckEvaluation
 	pluginManager *manager.PluginManager
+	apmCache      *APMCache
+	lastMetrics   *LastMetrics
 }
 
 // newCheckHandler returns a new checkHandler instance.
-func newCheckHandler(l hclog.Logger, p *sdk.ScalingPolicy, c *sdk.ScalingCheckEvaluation, pm *manager.PluginManager) *checkHandler {
+func newCheckHandler(l hclog.Logger, p *sdk.ScalingPolicy, c *sdk.ScalingCheckEvaluation, pm *manager.PluginManager, cache *APMCache, lm *LastMetrics) *checkHandler {
 	return &checkHandler{
 		logger: l.Named("check_handler").With(
 			"check", c.Check.Name,
@@ -373,6 +680,8 @@ func newCheckHandler(l hclog.Logger, p *sdk.ScalingPolicy, c *sdk.ScalingCheckEv
 		policy:        p,
 		checkEval:     c,
 		pluginManager: pm,
+		apmCache:      cache,
+		lastMetrics:   lm,
 	}
 }
 
@@ -380,20 +689,15 @@ func newCheckHandler(l hclog.Logger, p *sdk.ScalingPolicy, c *sdk.ScalingCheckEv
 func (h *checkHandler) start(ctx context.Context, currentStatus *sdk.TargetStatus) (*sdk.ScalingAction, error) {
 	h.logger.Debug("received policy check for evaluation")
 
-	var source apm.APM
 	var strategy strategy.Strategy
-
-	source, err := h.pluginManager.GetAPM(h.checkEval.Check.Source)
-	if err != nil {
-		return nil, fmt.Errorf("failed to dispense APM plugin: %v", err)
-	}
+	var err error
 
 	// Query check's APM.
 	// Wrap call in a goroutine so we can listen for ctx as well.
 	apmQueryDoneCh := make(chan interface{})
 	go func() {
 		defer close(apmQueryDoneCh)
-		h.checkEval.Metrics, err = h.runAPMQuery(source)
+		h.checkEval.Metrics, err = h.runAPMQuery(ctx)
 	}()
 
 	select {
@@ -406,15 +710,22 @@ func (h *checkHandler) start(ctx context.Context, currentStatus *sdk.TargetStatu
 		return nil, fmt.Errorf("failed to query source: %v", err)
 	}
 
-	if h.checkEval.Metrics != nil {
-		// Make sure metrics are sorted consistently.
-		sort.Sort(h.checkEval.Metrics)
-
+	if h.checkEval.Check.HasQuery() {
 		if len(h.checkEval.Metrics) == 0 {
-			h.logger.Warn("no metrics available")
-			return &sdk.ScalingAction{Direction: sdk.ScaleDirectionNone}, nil
+			h.checkEval.Metrics, err = h.handleMissingData()
+			if err != nil {
+				return nil, err
+			}
+			if h.checkEval.Metrics == nil {
+				return &sdk.ScalingAction{Direction: sdk.ScaleDirectionNone}, nil
+			}
+		} else if h.checkEval.Check.MissingData == sdk.ScalingPolicyMissingDataLast {
+			h.lastMetrics.Set(h.policy.ID, h.checkEval.Check.Name, h.checkEval.Metrics, h.missingDataMaxStaleness())
 		}
 
+		// Make sure metrics are sorted consistently.
+		sort.Sort(h.checkEval.Metrics)
+
 		if h.logger.IsTrace() {
 			for _, m := range h.checkEval.Metrics {
 				h.logger.Trace("metric result", "ts", m.Timestamp, "value", m.Value)
@@ -483,24 +794,212 @@ func (h *checkHandler) start(ctx context.Context, currentStatus *sdk.TargetStatu
 	return h.checkEval.Action, nil
 }
 
-// runAPMQuery wraps the apm.Query call to provide operational functionality.
-func (h *checkHandler) runAPMQuery(apmImpl apm.APM) (sdk.TimestampedMetrics, error) {
-	if h.checkEval.Check.Query == "" {
+// handleMissingData returns the metrics to evaluate the check with when its
+// query returned no datapoints, according to the check missing data
+// behaviour. Nil metrics are returned if the current count should be held.
+func (h *checkHandler) handleMissingData() (sdk.TimestampedMetrics, error) {
+	switch h.checkEval.Check.MissingData {
+	case sdk.ScalingPolicyMissingDataZero:
+		h.logger.Warn("no metrics available, using zero")
+		return sdk.TimestampedMetrics{{Timestamp: time.Now(), Value: 0}}, nil
+
+	case sdk.ScalingPolicyMissingDataLast:
+		maxStaleness := h.missingDataMaxStaleness()
+		m, age, ok := h.lastMetrics.Get(h.policy.ID, h.checkEval.Check.Name, maxStaleness)
+		if !ok {
+			return nil, fmt.Errorf("no metrics available and no last known metrics within %s", maxStaleness)
+		}
+		h.logger.Warn("no metrics available, using last known metrics", "age", age)
+		return m, nil
+
+	case sdk.ScalingPolicyMissingDataError:
+		return nil, errors.New("no metrics available")
+
+	default:
+		h.logger.Warn("no metrics available")
 		return nil, nil
 	}
+}
 
-	h.logger.Debug("querying source", "query", h.checkEval.Check.Query, "source", h.checkEval.Check.Source)
+// missingDataMaxStaleness returns the maximum age of the last known metrics
+// used by the check.
+func (h *checkHandler) missingDataMaxStaleness() time.Duration {
+	if s := h.checkEval.Check.MissingDataMaxStaleness; s > 0 {
+		return s
+	}
+	return defaultMissingDataMaxStaleness
+}
 
-	// Trigger a metric measure to track latency of the call.
-	labels := []metrics.Label{{Name: "plugin_name", Value: h.checkEval.Check.Source}, {Name: "policy_id", Value: h.policy.ID}}
-	defer metrics.MeasureSinceWithLabels([]string{"plugin", "apm", "query", "invoke_ms"}, time.Now(), labels)
+// runAPMQuery queries the check source, falling back to the next source in
+// the check fallbacks if the query fails or returns no data.
+func (h *checkHandler) runAPMQuery(ctx context.Context) (sdk.TimestampedMetrics, error) {
+	check := h.checkEval.Check
+	if !check.HasQuery() {
+		return nil, nil
+	}
 
 	// Calculate query range from the query window defined in the check.
 	to := time.Now()
-	from := to.Add(-h.checkEval.Check.QueryWindow)
+	from := to.Add(-check.QueryWindow)
 	r := sdk.TimeRange{From: from, To: to}
 
-	return apmImpl.Query(h.checkEval.Check.Query, r)
+	m, err := h.querySource(check.Source, func(apmImpl apm.APM) (sdk.TimestampedMetrics, error) {
+		if len(check.Queries) > 0 {
+			return h.runAPMQueries(ctx, apmImpl, r)
+		}
+		return h.runTemplatedAPMQuery(ctx, apmImpl, check.Source, check.Query, r)
+	})
+
+	source := check.Source
+	for _, f := range check.Fallbacks {
+		if err == nil && len(m) > 0 {
+			break
+		}
+
+		h.logger.Warn("source query failed or returned no data, trying fallback",
+			"failed_source", source, "fallback_source", f.Source, "error", err)
+		source = f.Source
+
+		f := f
+		m, err = h.querySource(f.Source, func(apmImpl apm.APM) (sdk.TimestampedMetrics, error) {
+			return h.runTemplatedAPMQuery(ctx, apmImpl, f.Source, f.Query, r)
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return sdk.ConvertUnit(m, check.Unit)
+}
+
+// querySource dispenses the APM plugin for the source and runs fn with it.
+func (h *checkHandler) querySource(source string, fn func(apm.APM) (sdk.TimestampedMetrics, error)) (sdk.TimestampedMetrics, error) {
+	apmImpl, err := h.pluginManager.GetAPM(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dispense APM plugin: %v", err)
+	}
+	return fn(apmImpl)
+}
+
+// runTemplatedAPMQuery renders the policy variables used in the query before
+// running it.
+func (h *checkHandler) runTemplatedAPMQuery(ctx context.Context, apmImpl apm.APM, source, q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	rendered, err := sdk.RenderQuery(q, h.policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render query: %v", err)
+	}
+	return h.runSingleAPMQuery(ctx, apmImpl, source, rendered, r)
+}
+
+// runSingleAPMQuery performs one query against the APM, tracking the latency
+// of the call. Results may be served from the APM cache, as long as they are
+// not older than the policy evaluation interval.
+func (h *checkHandler) runSingleAPMQuery(ctx context.Context, apmImpl apm.APM, source, q string, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	return h.apmCache.Query(source, q, h.checkEval.Check.QueryWindow, h.policy.EvaluationInterval, func() (sdk.TimestampedMetrics, error) {
+		return h.queryWithRetry(ctx, func() (sdk.TimestampedMetrics, error) {
+			h.logger.Debug("querying source", "query", q, "source", source)
+
+			// Trigger a metric measure to track latency of the call.
+			labels := []metrics.Label{{Name: "plugin_name", Value: source}, {Name: "policy_id", Value: h.policy.ID}}
+			defer metrics.MeasureSinceWithLabels([]string{"plugin", "apm", "query", "invoke_ms"}, time.Now(), labels)
+
+			return apmImpl.Query(q, r)
+		})
+	})
+}
+
+// queryWithRetry runs the query, applying the check query timeout to each
+// attempt and retrying failed attempts with an exponential backoff.
+func (h *checkHandler) queryWithRetry(ctx context.Context, query func() (sdk.TimestampedMetrics, error)) (sdk.TimestampedMetrics, error) {
+	check := h.checkEval.Check
+
+	backoff := check.QueryRetryBackoff
+	if backoff == 0 {
+		backoff = defaultQueryRetryBackoff
+	}
+	maxBackoff := check.QueryRetryMaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = defaultQueryRetryMaxBackoff
+	}
+	if backoff > maxBackoff {
+		maxBackoff = backoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		m, err := runWithTimeout(check.QueryTimeout, query)
+		if err == nil || attempt >= check.QueryRetries {
+			return m, err
+		}
+
+		h.logger.Warn("query failed, retrying",
+			"attempt", attempt+1, "retries", check.QueryRetries, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// runWithTimeout runs the query, returning an error if it doesn't complete
+// within the timeout. APM queries can't be cancelled, so a query which times
+// out keeps running in the background until the plugin returns.
+func runWithTimeout(timeout time.Duration, query func() (sdk.TimestampedMetrics, error)) (sdk.TimestampedMetrics, error) {
+	if timeout <= 0 {
+		return query()
+	}
+
+	type result struct {
+		metrics sdk.TimestampedMetrics
+		err     error
+	}
+
+	resultCh := make(chan result, 1)
+	go func() {
+		m, err := query()
+		resultCh <- result{metrics: m, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-resultCh:
+		return res.metrics, res.err
+	case <-timer.C:
+		return nil, fmt.Errorf("query timed out after %s", timeout)
+	}
+}
+
+// runAPMQueries runs each of the named queries of the check and combines
+// their results using the check query expression.
+func (h *checkHandler) runAPMQueries(ctx context.Context, apmImpl apm.APM, r sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	expr, err := sdk.ParseQueryExpression(h.checkEval.Check.QueryExpression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query expression: %v", err)
+	}
+
+	results := make(map[string]sdk.TimestampedMetrics, len(expr.Variables()))
+	for _, name := range expr.Variables() {
+		q, ok := h.checkEval.Check.Queries[name]
+		if !ok {
+			return nil, fmt.Errorf("query expression references undefined query %q", name)
+		}
+
+		m, err := h.runTemplatedAPMQuery(ctx, apmImpl, h.checkEval.Check.Source, q, r)
+		if err != nil {
+			return nil, fmt.Errorf("query %q failed: %v", name, err)
+		}
+		results[name] = m
+	}
+
+	return expr.Aggregate(results)
 }
 
 // runStrategyRun wraps the strategy.Run call to provide operational functionality.
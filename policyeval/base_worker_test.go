@@ -0,0 +1,441 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHandler_queryWithRetry(t *testing.T) {
+	testCases := []struct {
+		name          string
+		check         *sdk.ScalingPolicyCheck
+		failures      int
+		expectedCalls int
+		expectedError string
+	}{
+		{
+			name:          "no retries",
+			check:         &sdk.ScalingPolicyCheck{},
+			failures:      1,
+			expectedCalls: 1,
+			expectedError: "503 service unavailable",
+		},
+		{
+			name:          "succeeds after retry",
+			check:         &sdk.ScalingPolicyCheck{QueryRetries: 3, QueryRetryBackoff: time.Millisecond},
+			failures:      2,
+			expectedCalls: 3,
+		},
+		{
+			name:          "retries exhausted",
+			check:         &sdk.ScalingPolicyCheck{QueryRetries: 2, QueryRetryBackoff: time.Millisecond},
+			failures:      5,
+			expectedCalls: 3,
+			expectedError: "503 service unavailable",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &checkHandler{
+				logger:    hclog.NewNullLogger(),
+				checkEval: &sdk.ScalingCheckEvaluation{Check: tc.check},
+			}
+
+			var calls int
+			m, err := h.queryWithRetry(context.Background(), func() (sdk.TimestampedMetrics, error) {
+				calls++
+				if calls <= tc.failures {
+					return nil, errors.New("503 service unavailable")
+				}
+				return sdk.TimestampedMetrics{{Timestamp: time.Now(), Value: 1}}, nil
+			})
+
+			assert.Equal(t, tc.expectedCalls, calls)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, m, 1)
+		})
+	}
+}
+
+func TestCheckHandler_queryWithRetry_cancel(t *testing.T) {
+	h := &checkHandler{
+		logger: hclog.NewNullLogger(),
+		checkEval: &sdk.ScalingCheckEvaluation{
+			Check: &sdk.ScalingPolicyCheck{QueryRetries: 10, QueryRetryBackoff: time.Hour},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls int
+	_, err := h.queryWithRetry(ctx, func() (sdk.TimestampedMetrics, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	})
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 1, calls)
+}
+
+func Test_runWithTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err := runWithTimeout(10*time.Millisecond, func() (sdk.TimestampedMetrics, error) {
+		<-release
+		return nil, nil
+	})
+	assert.EqualError(t, err, "query timed out after 10ms")
+
+	m, err := runWithTimeout(time.Second, func() (sdk.TimestampedMetrics, error) {
+		return sdk.TimestampedMetrics{{Value: 1}}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, sdk.TimestampedMetrics{{Value: 1}}, m)
+}
+
+func TestCheckHandler_handleMissingData(t *testing.T) {
+	lm := NewLastMetrics()
+	lm.Set("policy", "stored", sdk.TimestampedMetrics{{Timestamp: time.Now(), Value: 42}}, time.Minute)
+
+	testCases := []struct {
+		name          string
+		check         *sdk.ScalingPolicyCheck
+		expected      []float64
+		expectedError string
+	}{
+		{
+			name:  "hold by default",
+			check: &sdk.ScalingPolicyCheck{Name: "stored"},
+		},
+		{
+			name:     "zero",
+			check:    &sdk.ScalingPolicyCheck{Name: "stored", MissingData: sdk.ScalingPolicyMissingDataZero},
+			expected: []float64{0},
+		},
+		{
+			name:     "last",
+			check:    &sdk.ScalingPolicyCheck{Name: "stored", MissingData: sdk.ScalingPolicyMissingDataLast},
+			expected: []float64{42},
+		},
+		{
+			name:          "last without stored metrics",
+			check:         &sdk.ScalingPolicyCheck{Name: "unknown", MissingData: sdk.ScalingPolicyMissingDataLast},
+			expectedError: "no metrics available and no last known metrics within 5m0s",
+		},
+		{
+			name:          "error",
+			check:         &sdk.ScalingPolicyCheck{Name: "stored", MissingData: sdk.ScalingPolicyMissingDataError},
+			expectedError: "no metrics available",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &checkHandler{
+				logger:      hclog.NewNullLogger(),
+				policy:      &sdk.ScalingPolicy{ID: "policy"},
+				checkEval:   &sdk.ScalingCheckEvaluation{Check: tc.check},
+				lastMetrics: lm,
+			}
+
+			m, err := h.handleMissingData()
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			var values []float64
+			for _, v := range m {
+				values = append(values, v.Value)
+			}
+			assert.Equal(t, tc.expected, values)
+		})
+	}
+}
+
+func TestBaseWorker_checkOutOfBand(t *testing.T) {
+	testCases := []struct {
+		inputType      string
+		inputDesired   *int64
+		inputCount     int64
+		inputConfig    map[string]string
+		expectedOutput *sdk.ScalingAction
+		expectedStored bool
+		name           string
+	}{
+		{
+			inputType:   sdk.ScalingPolicyTypeCluster,
+			inputCount:  3,
+			inputConfig: map[string]string{sdk.TargetConfigKeyReconcileOutOfBand: "true"},
+			name:        "not scaled by the autoscaler",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputDesired:   ptr.Int64ToPtr(3),
+			inputCount:     3,
+			inputConfig:    map[string]string{sdk.TargetConfigKeyReconcileOutOfBand: "true"},
+			expectedStored: true,
+			name:           "count unchanged",
+		},
+		{
+			inputType:    sdk.ScalingPolicyTypeCluster,
+			inputDesired: ptr.Int64ToPtr(3),
+			inputCount:   5,
+			name:         "out of band change kept",
+		},
+		{
+			inputType:    sdk.ScalingPolicyTypeCluster,
+			inputDesired: ptr.Int64ToPtr(3),
+			inputCount:   5,
+			inputConfig:  map[string]string{sdk.TargetConfigKeyReconcileOutOfBand: "true"},
+			expectedOutput: &sdk.ScalingAction{
+				Count:     3,
+				Direction: sdk.ScaleDirectionDown,
+				Reason:    "reconciling out of band change, scaling back to 3 after count was changed to 5 outside of the autoscaler",
+			},
+			name: "out of band scale out reconciled",
+		},
+		{
+			inputType:    sdk.ScalingPolicyTypeCluster,
+			inputDesired: ptr.Int64ToPtr(12),
+			inputCount:   1,
+			inputConfig:  map[string]string{sdk.TargetConfigKeyReconcileOutOfBand: "true"},
+			expectedOutput: &sdk.ScalingAction{
+				Count:     10,
+				Direction: sdk.ScaleDirectionUp,
+				Reason:    "reconciling out of band change, scaling back to 10 after count was changed to 1 outside of the autoscaler",
+			},
+			name: "reconciled count within policy limits",
+		},
+		{
+			inputType:    sdk.ScalingPolicyTypeCluster,
+			inputDesired: ptr.Int64ToPtr(3),
+			inputCount:   5,
+			inputConfig:  map[string]string{sdk.TargetConfigKeyReconcileOutOfBand: "invalid"},
+			name:         "invalid reconcile config",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeHorizontal,
+			inputDesired:   ptr.Int64ToPtr(3),
+			inputCount:     5,
+			inputConfig:    map[string]string{sdk.TargetConfigKeyReconcileOutOfBand: "true"},
+			expectedStored: true,
+			name:           "horizontal policy",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &BaseWorker{desiredCounts: NewDesiredCounts()}
+			if tc.inputDesired != nil {
+				w.desiredCounts.Set("policy", *tc.inputDesired)
+			}
+
+			policy := &sdk.ScalingPolicy{
+				ID:     "policy",
+				Type:   tc.inputType,
+				Min:    1,
+				Max:    10,
+				Target: &sdk.ScalingPolicyTarget{Config: tc.inputConfig},
+			}
+
+			action := w.checkOutOfBand(hclog.NewNullLogger(), policy, &sdk.TargetStatus{Count: tc.inputCount}, nil)
+			assert.Equal(t, tc.expectedOutput, action, tc.name)
+
+			_, ok := w.desiredCounts.Get("policy")
+			assert.Equal(t, tc.expectedStored, ok, tc.name)
+		})
+	}
+}
+
+func Test_provisionAction(t *testing.T) {
+	testCases := []struct {
+		inputType      string
+		inputProvision int64
+		inputCount     int64
+		inputWinner    *sdk.ScalingAction
+		inputDryRun    bool
+		expectedCount  int64
+		name           string
+	}{
+		{
+			inputType:  sdk.ScalingPolicyTypeCluster,
+			inputCount: 3,
+			name:       "not triggered by blocked allocations",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputProvision: 2,
+			inputCount:     3,
+			expectedCount:  5,
+			name:           "scale out by provision count",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputProvision: 4,
+			inputCount:     8,
+			expectedCount:  10,
+			name:           "scale out limited by policy max",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputProvision: 2,
+			inputCount:     10,
+			name:           "target at policy max",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeHorizontal,
+			inputProvision: 2,
+			inputCount:     3,
+			name:           "not a cluster policy",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputProvision: 2,
+			inputCount:     3,
+			inputWinner:    &sdk.ScalingAction{Count: 4, Direction: sdk.ScaleDirectionUp},
+			expectedCount:  5,
+			name:           "checks scale out less",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputProvision: 2,
+			inputCount:     3,
+			inputWinner:    &sdk.ScalingAction{Count: 2, Direction: sdk.ScaleDirectionDown},
+			expectedCount:  5,
+			name:           "checks scale in",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputProvision: 2,
+			inputCount:     3,
+			inputWinner:    &sdk.ScalingAction{Count: 7, Direction: sdk.ScaleDirectionUp},
+			name:           "checks scale out further",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputProvision: 2,
+			inputCount:     3,
+			inputWinner:    &sdk.ScalingAction{Count: sdk.StrategyActionMetaValueDryRunCount, Direction: sdk.ScaleDirectionUp},
+			name:           "dry-run action",
+		},
+		{
+			inputType:      sdk.ScalingPolicyTypeCluster,
+			inputProvision: 2,
+			inputCount:     3,
+			inputWinner:    &sdk.ScalingAction{Count: 4, Direction: sdk.ScaleDirectionUp},
+			inputDryRun:    true,
+			name:           "dry-run target",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eval := &sdk.ScalingEvaluation{
+				Policy: &sdk.ScalingPolicy{
+					ID:     "test",
+					Type:   tc.inputType,
+					Max:    10,
+					Target: &sdk.ScalingPolicyTarget{Config: map[string]string{}},
+				},
+				ProvisionCount: tc.inputProvision,
+			}
+			if tc.inputDryRun {
+				eval.Policy.Target.Config["dry-run"] = "true"
+			}
+
+			action := provisionAction(eval, tc.inputCount, tc.inputWinner)
+			if tc.expectedCount == 0 {
+				assert.Nil(t, action, tc.name)
+				return
+			}
+			require.NotNil(t, action, tc.name)
+			assert.Equal(t, tc.expectedCount, action.Count, tc.name)
+			assert.True(t, action.Direction == sdk.ScaleDirectionUp, tc.name)
+		})
+	}
+}
+
+func TestBaseWorker_enforceLimits(t *testing.T) {
+	testCases := []struct {
+		inputCount     int64
+		expectedOutput bool
+		name           string
+	}{
+		{
+			inputCount:     0,
+			expectedOutput: true,
+			name:           "count lower than min",
+		},
+		{
+			inputCount:     12,
+			expectedOutput: true,
+			name:           "count greater than max",
+		},
+		{
+			inputCount:     5,
+			expectedOutput: false,
+			name:           "count within limits",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &BaseWorker{desiredCounts: NewDesiredCounts()}
+			target := &testScaleTarget{}
+
+			policy := &sdk.ScalingPolicy{
+				ID:     "policy",
+				Type:   sdk.ScalingPolicyTypeCluster,
+				Min:    1,
+				Max:    10,
+				DryRun: true,
+				Target: &sdk.ScalingPolicyTarget{Config: map[string]string{}},
+			}
+
+			ok, err := w.enforceLimits(context.Background(), hclog.NewNullLogger(), target,
+				policy, &sdk.TargetStatus{Count: tc.inputCount}, nil)
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, tc.expectedOutput, ok, tc.name)
+
+			// Dry-run policies must never scale the target.
+			assert.Empty(t, target.actions, tc.name)
+		})
+	}
+}
+
+type testScaleTarget struct {
+	actions []sdk.ScalingAction
+}
+
+func (t *testScaleTarget) Status(_ map[string]string) (*sdk.TargetStatus, error) {
+	return &sdk.TargetStatus{Ready: true}, nil
+}
+
+func (t *testScaleTarget) Scale(action sdk.ScalingAction, _ map[string]string) error {
+	t.actions = append(t.actions, action)
+	return nil
+}
+
+func (t *testScaleTarget) SetConfig(_ map[string]string) error { return nil }
+
+func (t *testScaleTarget) PluginInfo() (*base.PluginInfo, error) { return nil, nil }
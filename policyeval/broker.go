@@ -57,6 +57,10 @@ import (
 //
 //   - the value for the policy ID is updated if a newer eval for the policy is
 //     enqueued.
+//
+//   - evals for policies with `DependsOn` are held in `pendingEvals` while an
+//     eval for any of the named policies is pending or unack'd, until the
+//     dependency window has passed since the eval was created.
 type Broker struct {
 	logger hclog.Logger
 
@@ -70,6 +74,11 @@ type Broker struct {
 	// being considered as failed.
 	deliveryLimit int
 
+	// dependencyWindow is the maximum time an eval is held back while the
+	// policies it depends on are being evaluated. A value of zero disables
+	// the ordering of dependent evals.
+	dependencyWindow time.Duration
+
 	// pendingEvals holds evaluations that are ready to be picked for
 	// further evaluation.
 	// Each key represents a different queue and the value is a heap holding
@@ -86,6 +95,10 @@ type Broker struct {
 
 	// waiting tracks Dequeue requests that are blocked waiting for work.
 	waiting map[string]chan struct{}
+
+	// holdTimers wake blocked Dequeue requests for a queue once the evals
+	// held back by their dependencies reach the end of the dependency window.
+	holdTimers map[string]*time.Timer
 }
 
 // unackEval tracks an unacknowledged evaluation along with the Nack timer
@@ -96,16 +109,18 @@ type unackEval struct {
 }
 
 // NewBroker returns a new Broker object.
-func NewBroker(l hclog.Logger, timeout time.Duration, deliveryLimit int) *Broker {
+func NewBroker(l hclog.Logger, timeout time.Duration, deliveryLimit int, dependencyWindow time.Duration) *Broker {
 	return &Broker{
 		logger:           l.Named("broker"),
 		nackTimeout:      timeout,
 		deliveryLimit:    deliveryLimit,
+		dependencyWindow: dependencyWindow,
 		pendingEvals:     make(map[string]PendingEvaluations),
 		enqueuedEvals:    make(map[string]int),
 		enqueuedPolicies: make(map[string]string),
 		unack:            make(map[string]*unackEval),
 		waiting:          make(map[string]chan struct{}),
+		holdTimers:       make(map[string]*time.Timer),
 	}
 }
 
@@ -159,6 +174,13 @@ func (b *Broker) enqueueLocked(eval *sdk.ScalingEvaluation, token string) {
 		if pendingEval != nil {
 			if eval.CreateTime.After(pendingEval.CreateTime) {
 				logger.Debug("new eval is newer, policy updated")
+
+				// Keep the provisioning request of the replaced eval, so
+				// the blocked allocations which triggered it are placed.
+				if pendingEval.ProvisionCount > eval.ProvisionCount {
+					eval.ProvisionCount = pendingEval.ProvisionCount
+				}
+
 				b.enqueuedPolicies[eval.Policy.ID] = eval.ID
 				delete(b.enqueuedEvals, eval.ID)
 				pending[i] = eval
@@ -172,12 +194,27 @@ func (b *Broker) enqueueLocked(eval *sdk.ScalingEvaluation, token string) {
 	b.pendingEvals[queue] = pending
 
 	// Unblock any blocked dequeues.
+	b.notifyLocked(queue)
+
+	logger.Debug("eval enqueued")
+}
+
+// notifyLocked unblocks a Dequeue request waiting for work on the queue. The
+// caller must hold the lock.
+func (b *Broker) notifyLocked(queue string) {
 	select {
 	case b.waiting[queue] <- struct{}{}:
 	default:
 	}
+}
 
-	logger.Debug("eval enqueued")
+// notifyAllLocked unblocks a Dequeue request waiting for work on each queue,
+// so evals held back by their dependencies are checked again. The caller must
+// hold the lock.
+func (b *Broker) notifyAllLocked() {
+	for queue := range b.waiting {
+		b.notifyLocked(queue)
+	}
 }
 
 // Dequeue is used to retrieve an eval from the broker.
@@ -225,7 +262,8 @@ func (b *Broker) Dequeue(ctx context.Context, queue string) (*sdk.ScalingEvaluat
 	return eval, token, nil
 }
 
-// findWork returns an eval from the queue heap or nil if there's no eval available.
+// findWork returns an eval from the queue heap or nil if there's no eval
+// available. Evals held back by their dependencies are left in the heap.
 func (b *Broker) findWork(queue string) *sdk.ScalingEvaluation {
 	b.l.Lock()
 	defer b.l.Unlock()
@@ -235,15 +273,95 @@ func (b *Broker) findWork(queue string) *sdk.ScalingEvaluation {
 		return nil
 	}
 
-	if pending.Len() == 0 {
-		return nil
+	var (
+		eval    *sdk.ScalingEvaluation
+		held    []*sdk.ScalingEvaluation
+		minHold time.Duration
+	)
+
+	// Pop the heap until an eval which is not held is found.
+	for pending.Len() > 0 {
+		e := heap.Pop(&pending).(*sdk.ScalingEvaluation)
+		b.pendingEvals[queue] = pending
+
+		hold := b.dependencyHoldLocked(e, held)
+		if hold <= 0 {
+			eval = e
+			break
+		}
+
+		b.logger.Debug("holding eval for dependencies",
+			"eval_id", e.ID, "policy_id", e.Policy.ID, "depends_on", e.Policy.DependsOn)
+		held = append(held, e)
+		if minHold == 0 || hold < minHold {
+			minHold = hold
+		}
 	}
 
-	// Pop heap and update reference.
-	raw := heap.Pop(&pending)
+	// Return the held evals to the heap and update reference.
+	for _, e := range held {
+		heap.Push(&pending, e)
+	}
 	b.pendingEvals[queue] = pending
 
-	return raw.(*sdk.ScalingEvaluation)
+	// If only held evals are available make sure a blocked dequeue is woken
+	// once the first of them reaches the end of the dependency window.
+	if eval == nil && len(held) > 0 {
+		if t, ok := b.holdTimers[queue]; ok {
+			t.Stop()
+		}
+		b.holdTimers[queue] = time.AfterFunc(minHold, func() {
+			b.l.Lock()
+			defer b.l.Unlock()
+			b.notifyLocked(queue)
+		})
+	}
+
+	return eval
+}
+
+// dependencyHoldLocked returns how much longer the eval should be held back
+// because an eval for a policy it depends on is pending or unack'd. A zero
+// value indicates the eval can be dequeued. Evals which have been popped from
+// the pending heap, but not yet returned to it, must be passed as popped. The
+// caller must hold the lock.
+func (b *Broker) dependencyHoldLocked(eval *sdk.ScalingEvaluation, popped []*sdk.ScalingEvaluation) time.Duration {
+	if b.dependencyWindow <= 0 || len(eval.Policy.DependsOn) == 0 {
+		return 0
+	}
+
+	remaining := b.dependencyWindow - time.Since(eval.CreateTime)
+	if remaining <= 0 {
+		return 0
+	}
+
+	deps := make(map[string]bool, len(eval.Policy.DependsOn))
+	for _, name := range eval.Policy.DependsOn {
+		deps[name] = true
+	}
+
+	inFlight := func(e *sdk.ScalingEvaluation) bool {
+		return e.Policy.ID != eval.Policy.ID && deps[e.Policy.Name]
+	}
+
+	for _, u := range b.unack {
+		if inFlight(u.Eval) {
+			return remaining
+		}
+	}
+	for _, e := range popped {
+		if inFlight(e) {
+			return remaining
+		}
+	}
+	for _, pending := range b.pendingEvals {
+		for _, e := range pending {
+			if inFlight(e) {
+				return remaining
+			}
+		}
+	}
+	return 0
 }
 
 // waitForWork blocks until queue receives an item or the context is canceled.
@@ -294,6 +412,9 @@ func (b *Broker) Ack(evalID, token string) error {
 	delete(b.enqueuedEvals, evalID)
 	delete(b.enqueuedPolicies, unack.Eval.Policy.ID)
 
+	// Evals which depend on this policy may now be dequeued.
+	b.notifyAllLocked()
+
 	b.logger.Debug("eval ack'd", "policy_id", unack.Eval.Policy.ID)
 	return nil
 }
@@ -330,6 +451,7 @@ func (b *Broker) Nack(evalID, token string) error {
 
 		delete(b.enqueuedEvals, evalID)
 		delete(b.enqueuedPolicies, unack.Eval.Policy.ID)
+		b.notifyAllLocked()
 		return nil
 	}
 
@@ -23,7 +23,7 @@ func TestBroker(t *testing.T) {
 	nackTimeout := 100 * time.Millisecond
 
 	// Setup broker so it only allows dequeueing evals twice before failing.
-	b := NewBroker(l, nackTimeout, 2)
+	b := NewBroker(l, nackTimeout, 2, 0)
 
 	// Create and enqueue some evals.
 	eval1 := &sdk.ScalingEvaluation{
@@ -172,3 +172,115 @@ func TestBroker(t *testing.T) {
 	assert.Empty(token)
 	assert.Nil(err)
 }
+
+func TestBroker_dependsOn(t *testing.T) {
+	l := hclog.NewNullLogger()
+	b := NewBroker(l, time.Minute, 2, 200*time.Millisecond)
+
+	cluster := &sdk.ScalingEvaluation{
+		ID: "cluster-eval",
+		Policy: &sdk.ScalingPolicy{
+			ID:   "cluster-policy",
+			Name: "cluster",
+			Type: "cluster",
+		},
+		CreateTime: time.Now(),
+	}
+	app := &sdk.ScalingEvaluation{
+		ID: "app-eval",
+		Policy: &sdk.ScalingPolicy{
+			ID:        "app-policy",
+			Name:      "default/app/web",
+			Type:      "horizontal",
+			DependsOn: []string{"cluster"},
+			Priority:  10,
+		},
+		CreateTime: time.Now(),
+	}
+	other := &sdk.ScalingEvaluation{
+		ID: "other-eval",
+		Policy: &sdk.ScalingPolicy{
+			ID:   "other-policy",
+			Type: "horizontal",
+		},
+		CreateTime: time.Now(),
+	}
+
+	b.Enqueue(cluster)
+	b.Enqueue(app)
+	b.Enqueue(other)
+
+	// The app eval has a higher priority, but is held back while the cluster
+	// eval is pending.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	eval, _, err := b.Dequeue(ctx, "horizontal")
+	assert.NoError(t, err)
+	assert.Equal(t, other, eval)
+
+	// The app eval is still held while the cluster eval is unack'd.
+	clusterEval, clusterToken, err := b.Dequeue(ctx, "cluster")
+	assert.NoError(t, err)
+	assert.Equal(t, cluster, clusterEval)
+	assert.Nil(t, b.findWork("horizontal"))
+
+	// Acking the cluster eval should release the app eval.
+	appCh := make(chan *sdk.ScalingEvaluation)
+	go func() {
+		e, _, _ := b.Dequeue(ctx, "horizontal")
+		appCh <- e
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, b.Ack(clusterEval.ID, clusterToken))
+
+	select {
+	case e := <-appCh:
+		assert.Equal(t, app, e)
+	case <-time.After(time.Second):
+		assert.FailNow(t, "timed out waiting for app eval")
+	}
+
+	// Once the dependency window has passed the eval is no longer held.
+	cluster2 := &sdk.ScalingEvaluation{ID: "cluster-eval-2", Policy: cluster.Policy, CreateTime: time.Now()}
+	app2 := &sdk.ScalingEvaluation{ID: "app-eval-2", Policy: app.Policy, CreateTime: time.Now()}
+	b.Enqueue(cluster2)
+	b.Enqueue(app2)
+
+	start := time.Now()
+	eval, _, err = b.Dequeue(ctx, "horizontal")
+	assert.NoError(t, err)
+	assert.Equal(t, app2, eval)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestBroker_provisionCount(t *testing.T) {
+	b := NewBroker(hclog.NewNullLogger(), time.Minute, 2, 0)
+
+	p := &sdk.ScalingPolicy{ID: "cluster-policy", Type: "cluster"}
+	provision := &sdk.ScalingEvaluation{
+		ID:             "provision-eval",
+		Policy:         p,
+		CreateTime:     time.Now(),
+		ProvisionCount: 3,
+	}
+	tick := &sdk.ScalingEvaluation{
+		ID:         "tick-eval",
+		Policy:     p,
+		CreateTime: provision.CreateTime.Add(time.Second),
+	}
+
+	// The newer eval replaces the pending eval, but keeps its provisioning
+	// request.
+	b.Enqueue(provision)
+	b.Enqueue(tick)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	eval, _, err := b.Dequeue(ctx, "cluster")
+	assert.NoError(t, err)
+	assert.Equal(t, "tick-eval", eval.ID)
+	assert.Equal(t, int64(3), eval.ProvisionCount)
+}
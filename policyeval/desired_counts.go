@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"sync"
+	"time"
+)
+
+// desiredCountsMaxAge is how long the desired count of a policy is
+// remembered without the policy being evaluated.
+const desiredCountsMaxAge = time.Hour
+
+// DesiredCounts stores the count each cluster policy target was last scaled
+// to by the Nomad Autoscaler, so changes of the target capacity made outside
+// of the autoscaler, such as manual console edits or other automation, can be
+// detected regardless of which worker evaluates the policy. A nil
+// DesiredCounts is safe to use and never stores counts.
+type DesiredCounts struct {
+	lock    sync.Mutex
+	entries map[string]*desiredCountEntry
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+// desiredCountEntry is a stored desired count, which is removed once the
+// policy has not been evaluated for desiredCountsMaxAge.
+type desiredCountEntry struct {
+	count   int64
+	updated time.Time
+}
+
+// NewDesiredCounts returns a new, empty, DesiredCounts instance.
+func NewDesiredCounts() *DesiredCounts {
+	return &DesiredCounts{
+		entries: make(map[string]*desiredCountEntry),
+		now:     time.Now,
+	}
+}
+
+// Set stores the count the target of the policy was scaled to.
+func (d *DesiredCounts) Set(policyID string, count int64) {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.pruneLocked()
+	d.entries[policyID] = &desiredCountEntry{count: count, updated: d.now()}
+}
+
+// Get returns the count the target of the policy was last scaled to. False is
+// returned if the target has not been scaled by the autoscaler, or if the
+// outcome of the last scaling action is unknown.
+func (d *DesiredCounts) Get(policyID string) (int64, bool) {
+	if d == nil {
+		return 0, false
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	e, ok := d.entries[policyID]
+	if !ok {
+		return 0, false
+	}
+	e.updated = d.now()
+	return e.count, true
+}
+
+// Delete removes the desired count of the policy.
+func (d *DesiredCounts) Delete(policyID string) {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.entries, policyID)
+}
+
+// pruneLocked removes the entries of policies which have not been evaluated
+// recently, so counts of removed policies are not kept forever. It must be
+// called while holding the lock.
+func (d *DesiredCounts) pruneLocked() {
+	now := d.now()
+	for k, e := range d.entries {
+		if now.Sub(e.updated) > desiredCountsMaxAge {
+			delete(d.entries, k)
+		}
+	}
+}
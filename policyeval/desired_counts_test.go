@@ -0,0 +1,48 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDesiredCounts(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	dc := NewDesiredCounts()
+	dc.now = func() time.Time { return now }
+
+	_, ok := dc.Get("policy")
+	assert.False(t, ok)
+
+	dc.Set("policy", 3)
+	count, ok := dc.Get("policy")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), count)
+
+	dc.Delete("policy")
+	_, ok = dc.Get("policy")
+	assert.False(t, ok)
+
+	// Counts of policies not evaluated recently are pruned when storing new
+	// ones, while reading a count keeps it.
+	dc.Set("policy", 3)
+	dc.Set("other", 1)
+	now = now.Add(desiredCountsMaxAge / 2)
+	dc.Get("policy")
+	now = now.Add(desiredCountsMaxAge/2 + time.Minute)
+	dc.Set("new", 2)
+	assert.Len(t, dc.entries, 2)
+	_, ok = dc.Get("other")
+	assert.False(t, ok)
+
+	// A nil DesiredCounts never stores counts.
+	var nilDC *DesiredCounts
+	nilDC.Set("policy", 3)
+	nilDC.Delete("policy")
+	_, ok = nilDC.Get("policy")
+	assert.False(t, ok)
+}
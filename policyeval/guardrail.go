@@ -0,0 +1,165 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// DefaultGuardrailQuery is the Rego query evaluated when the guardrail is not
+// configured with one.
+const DefaultGuardrailQuery = "data.nomad_autoscaler.guardrail"
+
+// GuardrailInput is the input document passed to the guardrail Rego policy
+// for each computed scaling action.
+type GuardrailInput struct {
+	PolicyID  string                 `json:"policy_id"`
+	Policy    string                 `json:"policy"`
+	Labels    map[string]string      `json:"labels"`
+	Target    map[string]string      `json:"target"`
+	Direction string                 `json:"direction"`
+	From      int64                  `json:"from"`
+	To        int64                  `json:"to"`
+	Reason    string                 `json:"reason"`
+	Meta      map[string]interface{} `json:"meta"`
+}
+
+// GuardrailDecision is the result of the guardrail Rego query. A nil Allow is
+// treated as allowed, while a non-nil Count replaces the count of the action.
+type GuardrailDecision struct {
+	Allow  *bool  `json:"allow"`
+	Count  *int64 `json:"count"`
+	Reason string `json:"reason"`
+}
+
+// Guardrail evaluates an embedded OPA Rego policy against every computed
+// scaling action before it is submitted to the target. The policy can deny
+// the action or clamp its count, allowing global rules to be enforced
+// independently of individual scaling policies. A nil Guardrail is safe to
+// use and allows all actions.
+type Guardrail struct {
+	log   hclog.Logger
+	query rego.PreparedEvalQuery
+}
+
+// NewGuardrail returns a new Guardrail which evaluates query against the Rego
+// modules loaded from paths. Paths can be files or directories.
+func NewGuardrail(ctx context.Context, log hclog.Logger, paths []string, query string) (*Guardrail, error) {
+	if query == "" {
+		query = DefaultGuardrailQuery
+	}
+
+	pq, err := rego.New(
+		rego.Query(query),
+		rego.Load(paths, nil),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare guardrail policy: %v", err)
+	}
+
+	return &Guardrail{
+		log:   log.Named("guardrail"),
+		query: pq,
+	}, nil
+}
+
+// Evaluate runs the guardrail policy for the action proposed for the policy,
+// updating it in place if the guardrail clamps its count. It returns false if
+// the action was denied or no longer changes the target count.
+//
+// Errors evaluating the guardrail deny the action, so a broken guardrail
+// policy does not allow unchecked scaling. Dry-run actions don't change the
+// target count, so they are allowed without evaluating the guardrail.
+func (g *Guardrail) Evaluate(ctx context.Context, p *sdk.ScalingPolicy, action *sdk.ScalingAction, from int64) (bool, error) {
+	if g == nil || action.Count == sdk.StrategyActionMetaValueDryRunCount {
+		return true, nil
+	}
+
+	input := &GuardrailInput{
+		PolicyID:  p.ID,
+		Policy:    p.Name,
+		Labels:    p.Labels,
+		Direction: action.Direction.String(),
+		From:      from,
+		To:        action.Count,
+		Reason:    action.Reason,
+		Meta:      action.Meta,
+	}
+	if p.Target != nil {
+		input.Target = p.Target.Config
+	}
+
+	decision, err := g.eval(ctx, input)
+	if err != nil {
+		metrics.IncrCounter([]string{"scale", "guardrail", "error_count"}, 1)
+		return false, fmt.Errorf("failed to evaluate guardrail: %v", err)
+	}
+
+	if decision.Allow != nil && !*decision.Allow {
+		metrics.IncrCounter([]string{"scale", "guardrail", "denied_count"}, 1)
+		g.log.Info("scaling action denied by guardrail",
+			"policy_id", p.ID, "from", from, "to", action.Count, "reason", decision.Reason)
+		return false, nil
+	}
+
+	if decision.Count == nil || *decision.Count == action.Count {
+		return true, nil
+	}
+
+	count := *decision.Count
+	if count < 0 {
+		metrics.IncrCounter([]string{"scale", "guardrail", "error_count"}, 1)
+		return false, fmt.Errorf("guardrail returned negative count %d", count)
+	}
+
+	metrics.IncrCounter([]string{"scale", "guardrail", "clamped_count"}, 1)
+	g.log.Info("scaling action clamped by guardrail",
+		"policy_id", p.ID, "from", from, "to", action.Count, "clamped_to", count, "reason", decision.Reason)
+
+	action.Count = count
+	switch {
+	case count > from:
+		action.Direction = sdk.ScaleDirectionUp
+	case count < from:
+		action.Direction = sdk.ScaleDirectionDown
+	default:
+		action.Direction = sdk.ScaleDirectionNone
+	}
+	if decision.Reason != "" {
+		action.Reason = fmt.Sprintf("%s (guardrail: %s)", action.Reason, decision.Reason)
+	}
+
+	return action.Direction != sdk.ScaleDirectionNone, nil
+}
+
+func (g *Guardrail) eval(ctx context.Context, input *GuardrailInput) (*GuardrailDecision, error) {
+	rs, err := g.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, err
+	}
+
+	// An undefined result means the guardrail has no opinion on the action.
+	var decision GuardrailDecision
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return &decision, nil
+	}
+
+	// Round trip the result through JSON so the decision can be decoded
+	// into typed fields.
+	raw, err := json.Marshal(rs[0].Expressions[0].Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &decision); err != nil {
+		return nil, fmt.Errorf("invalid guardrail result: %v", err)
+	}
+	return &decision, nil
+}
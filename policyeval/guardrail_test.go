@@ -0,0 +1,153 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGuardrailPolicy = `
+package nomad_autoscaler
+
+import future.keywords.if
+
+default guardrail := {}
+
+# Never scale production below 3.
+guardrail := {"count": 3, "reason": "prod minimum"} if {
+	input.labels.env == "prod"
+	input.to < 3
+}
+
+# Never scale down the database.
+guardrail := {"allow": false, "reason": "database scale down"} if {
+	input.target.Job == "db"
+	input.direction == "down"
+}
+`
+
+func TestGuardrail_Evaluate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "guardrail.rego"), []byte(testGuardrailPolicy), 0o644))
+
+	g, err := NewGuardrail(context.Background(), hclog.NewNullLogger(), []string{dir}, "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		labels         map[string]string
+		job            string
+		action         sdk.ScalingAction
+		from           int64
+		expectedOK     bool
+		expectedAction sdk.ScalingAction
+	}{
+		{
+			name:           "allowed",
+			labels:         map[string]string{"env": "dev"},
+			job:            "web",
+			action:         sdk.ScalingAction{Count: 1, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+			from:           5,
+			expectedOK:     true,
+			expectedAction: sdk.ScalingAction{Count: 1, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+		},
+		{
+			name:           "clamped",
+			labels:         map[string]string{"env": "prod"},
+			job:            "web",
+			action:         sdk.ScalingAction{Count: 1, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+			from:           5,
+			expectedOK:     true,
+			expectedAction: sdk.ScalingAction{Count: 3, Direction: sdk.ScaleDirectionDown, Reason: "scale (guardrail: prod minimum)"},
+		},
+		{
+			name:           "clamped to current count",
+			labels:         map[string]string{"env": "prod"},
+			job:            "web",
+			action:         sdk.ScalingAction{Count: 1, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+			from:           3,
+			expectedOK:     false,
+			expectedAction: sdk.ScalingAction{Count: 3, Direction: sdk.ScaleDirectionNone, Reason: "scale (guardrail: prod minimum)"},
+		},
+		{
+			name:           "clamped changes direction",
+			labels:         map[string]string{"env": "prod"},
+			job:            "web",
+			action:         sdk.ScalingAction{Count: 1, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+			from:           2,
+			expectedOK:     true,
+			expectedAction: sdk.ScalingAction{Count: 3, Direction: sdk.ScaleDirectionUp, Reason: "scale (guardrail: prod minimum)"},
+		},
+		{
+			name:           "denied",
+			job:            "db",
+			action:         sdk.ScalingAction{Count: 4, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+			from:           5,
+			expectedOK:     false,
+			expectedAction: sdk.ScalingAction{Count: 4, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+		},
+		{
+			name:           "dry-run",
+			labels:         map[string]string{"env": "prod"},
+			job:            "db",
+			action:         sdk.ScalingAction{Count: sdk.StrategyActionMetaValueDryRunCount, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+			from:           5,
+			expectedOK:     true,
+			expectedAction: sdk.ScalingAction{Count: sdk.StrategyActionMetaValueDryRunCount, Direction: sdk.ScaleDirectionDown, Reason: "scale"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &sdk.ScalingPolicy{
+				ID:     "id",
+				Labels: tc.labels,
+				Target: &sdk.ScalingPolicyTarget{Config: map[string]string{"Job": tc.job}},
+			}
+
+			ok, err := g.Evaluate(context.Background(), p, &tc.action, tc.from)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedAction, tc.action)
+		})
+	}
+
+	// A nil guardrail allows all actions.
+	var nilGuardrail *Guardrail
+	action := sdk.ScalingAction{Count: 1, Direction: sdk.ScaleDirectionDown}
+	ok, err := nilGuardrail.Evaluate(context.Background(), &sdk.ScalingPolicy{}, &action, 5)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestGuardrail_Evaluate_error(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "guardrail.rego"), []byte(`
+package nomad_autoscaler
+
+guardrail := "not an object"
+`), 0o644))
+
+	g, err := NewGuardrail(context.Background(), hclog.NewNullLogger(), []string{dir}, "")
+	require.NoError(t, err)
+
+	// Invalid results must deny the action.
+	action := sdk.ScalingAction{Count: 1, Direction: sdk.ScaleDirectionDown}
+	ok, err := g.Evaluate(context.Background(), &sdk.ScalingPolicy{}, &action, 5)
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "invalid guardrail result")
+
+	// Invalid policies must fail on creation.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "guardrail.rego"), []byte("package"), 0o644))
+	_, err = NewGuardrail(context.Background(), hclog.NewNullLogger(), []string{dir}, "")
+	assert.ErrorContains(t, err, "failed to prepare guardrail policy")
+}
@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// LastMetrics stores the last non-empty query result of each policy check,
+// so checks configured with the "last" missing data behaviour can reuse it
+// when a query returns no datapoints. A nil LastMetrics is safe to use and
+// never stores results.
+type LastMetrics struct {
+	lock    sync.Mutex
+	entries map[lastMetricsKey]*lastMetricsEntry
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+// lastMetricsKey identifies the check of a policy.
+type lastMetricsKey struct {
+	policyID string
+	check    string
+}
+
+// lastMetricsEntry is a stored result, which is removed once it is older
+// than maxAge.
+type lastMetricsEntry struct {
+	metrics sdk.TimestampedMetrics
+	stored  time.Time
+	maxAge  time.Duration
+}
+
+// NewLastMetrics returns a new, empty, LastMetrics instance.
+func NewLastMetrics() *LastMetrics {
+	return &LastMetrics{
+		entries: make(map[lastMetricsKey]*lastMetricsEntry),
+		now:     time.Now,
+	}
+}
+
+// Set stores the result of the policy check, which is kept for at most
+// maxAge.
+func (l *LastMetrics) Set(policyID, check string, m sdk.TimestampedMetrics, maxAge time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.pruneLocked()
+	l.entries[lastMetricsKey{policyID: policyID, check: check}] = &lastMetricsEntry{
+		metrics: copyMetrics(m),
+		stored:  l.now(),
+		maxAge:  maxAge,
+	}
+}
+
+// Get returns the last result stored for the policy check, along with its
+// age. False is returned if there is no result or the stored result is older
+// than maxAge.
+func (l *LastMetrics) Get(policyID, check string, maxAge time.Duration) (sdk.TimestampedMetrics, time.Duration, bool) {
+	if l == nil {
+		return nil, 0, false
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	e, ok := l.entries[lastMetricsKey{policyID: policyID, check: check}]
+	if !ok {
+		return nil, 0, false
+	}
+
+	age := l.now().Sub(e.stored)
+	if age > maxAge {
+		return nil, age, false
+	}
+	return copyMetrics(e.metrics), age, true
+}
+
+// pruneLocked removes entries older than their maximum age, so results of
+// removed policies are not kept forever. It must be called while holding the
+// lock.
+func (l *LastMetrics) pruneLocked() {
+	now := l.now()
+	for k, e := range l.entries {
+		if now.Sub(e.stored) > e.maxAge {
+			delete(l.entries, k)
+		}
+	}
+}
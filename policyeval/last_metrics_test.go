@@ -0,0 +1,49 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastMetrics(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	lm := NewLastMetrics()
+	lm.now = func() time.Time { return now }
+
+	_, _, ok := lm.Get("policy", "check", time.Minute)
+	assert.False(t, ok)
+
+	lm.Set("policy", "check", sdk.TimestampedMetrics{{Timestamp: now, Value: 1}}, time.Minute)
+
+	now = now.Add(30 * time.Second)
+	m, age, ok := lm.Get("policy", "check", time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, 30*time.Second, age)
+	assert.Equal(t, float64(1), m[0].Value)
+
+	// Results of other checks are independent.
+	_, _, ok = lm.Get("policy", "other", time.Minute)
+	assert.False(t, ok)
+
+	// Results older than the maximum age are not returned.
+	_, _, ok = lm.Get("policy", "check", 10*time.Second)
+	assert.False(t, ok)
+
+	// Expired results are pruned when storing new ones.
+	now = now.Add(time.Minute)
+	lm.Set("policy", "other", sdk.TimestampedMetrics{{Timestamp: now, Value: 2}}, time.Minute)
+	assert.Len(t, lm.entries, 1)
+
+	// A nil LastMetrics never stores results.
+	var nilLM *LastMetrics
+	nilLM.Set("policy", "check", m, time.Minute)
+	_, _, ok = nilLM.Get("policy", "check", time.Minute)
+	assert.False(t, ok)
+}
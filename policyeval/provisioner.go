@@ -0,0 +1,276 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/blocking"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// defaultProvisionerBatchWindow is the time the provisioner waits after a
+	// new blocked evaluation is detected when no batch window is configured.
+	defaultProvisionerBatchWindow = 2 * time.Second
+
+	// provisionerQuery is the Nomad APM query returning the number of nodes
+	// to add to a node pool in order to place its blocked allocations. The
+	// pool identifier values and keys are appended to it.
+	provisionerQuery = "node_bin-packed_blocked"
+)
+
+// provisionerPolicies is the subset of the policy manager used by the
+// provisioner, allowing it to be replaced within tests.
+type provisionerPolicies interface {
+	Policies() []*sdk.ScalingPolicy
+	TriggerEvaluation(id policy.PolicyID, provision int64)
+}
+
+// provisionerAPMs is the subset of the plugin manager used by the
+// provisioner, allowing it to be replaced within tests.
+type provisionerAPMs interface {
+	GetAPM(source string) (apm.APM, error)
+}
+
+// Provisioner watches the Nomad blocked evaluations and, as soon as new ones
+// are detected, triggers the evaluation of the cluster policies whose node
+// pool could place the blocked allocations. Each evaluation scales the target
+// out by at least the number of nodes required to place them, so clusters
+// react to placement failures within seconds rather than waiting for the
+// policy evaluation interval.
+//
+// The provisioner does not track the nodes being added, so the policy
+// cooldown should cover the time taken for new nodes to join the cluster.
+type Provisioner struct {
+	logger   hclog.Logger
+	policies provisionerPolicies
+	apms     provisionerAPMs
+
+	// apmName is the name of the Nomad APM plugin used to calculate the
+	// number of nodes required.
+	apmName string
+
+	// batchWindow is the time waited after a new blocked evaluation is
+	// detected, so evaluations blocked together are placed by a single
+	// scaling action.
+	batchWindow time.Duration
+
+	nomad     *api.Client
+	nomadLock sync.RWMutex
+}
+
+// NewProvisioner returns a new Provisioner.
+func NewProvisioner(l hclog.Logger, nomad *api.Client, m *policy.Manager, pm provisionerAPMs, apmName string, batchWindow time.Duration) *Provisioner {
+	if batchWindow <= 0 {
+		batchWindow = defaultProvisionerBatchWindow
+	}
+
+	return &Provisioner{
+		logger:      l.Named("provisioner"),
+		policies:    m,
+		apms:        pm,
+		apmName:     apmName,
+		batchWindow: batchWindow,
+		nomad:       nomad,
+	}
+}
+
+// SetNomadClient is used to update the Nomad client used to watch the blocked
+// evaluations.
+func (p *Provisioner) SetNomadClient(nomad *api.Client) {
+	p.nomadLock.Lock()
+	defer p.nomadLock.Unlock()
+	p.nomad = nomad
+}
+
+// Run watches the blocked evaluations until the context is canceled.
+func (p *Provisioner) Run(ctx context.Context) {
+	p.logger.Info("starting blocked evaluations watcher", "batch_window", p.batchWindow)
+
+	q := &api.QueryOptions{
+		Namespace: "*",
+		Filter:    fmt.Sprintf("Status == %q", api.EvalStatusBlocked),
+		WaitTime:  5 * time.Minute,
+		WaitIndex: 1,
+	}
+
+	// seen tracks the blocked evaluations the provisioner has already acted
+	// upon, so only new ones trigger evaluations.
+	seen := make(map[string]struct{})
+
+	for {
+		p.nomadLock.RLock()
+		evalsAPI := p.nomad.Evaluations()
+		p.nomadLock.RUnlock()
+
+		evals, meta, err := evalsAPI.List(q.WithContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				p.logger.Info("context closed, shutting down provisioner")
+				return
+			}
+
+			p.logger.Error("failed to list blocked evaluations", "error", err)
+			select {
+			case <-ctx.Done():
+				p.logger.Info("context closed, shutting down provisioner")
+				return
+			case <-time.After(10 * time.Second):
+				continue
+			}
+		}
+
+		// If the index has not changed, the query returned because the timeout
+		// was reached, therefore start the next query loop.
+		if !blocking.IndexHasChanged(meta.LastIndex, q.WaitIndex) {
+			continue
+		}
+		q.WaitIndex = meta.LastIndex
+
+		if newBlockedEvals(seen, evals) == 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			p.logger.Info("context closed, shutting down provisioner")
+			return
+		case <-time.After(p.batchWindow):
+		}
+
+		p.provision()
+	}
+}
+
+// newBlockedEvals updates the set of seen evaluations with the currently
+// blocked evaluations, returning how many were not seen before. Evaluations
+// which are no longer blocked are removed from the set.
+func newBlockedEvals(seen map[string]struct{}, evals []*api.Evaluation) int {
+	current := make(map[string]struct{}, len(evals))
+	count := 0
+
+	for _, eval := range evals {
+		if eval.Status != api.EvalStatusBlocked {
+			continue
+		}
+		current[eval.ID] = struct{}{}
+		if _, ok := seen[eval.ID]; !ok {
+			count++
+		}
+	}
+
+	for id := range seen {
+		delete(seen, id)
+	}
+	for id := range current {
+		seen[id] = struct{}{}
+	}
+	return count
+}
+
+// provision triggers the evaluation of each enabled cluster policy whose node
+// pool requires nodes in order to place blocked allocations.
+func (p *Provisioner) provision() {
+	apmImpl, err := p.apms.GetAPM(p.apmName)
+	if err != nil {
+		p.logger.Error("failed to get APM plugin", "apm", p.apmName, "error", err)
+		return
+	}
+
+	for _, pol := range p.policies.Policies() {
+		if pol.Type != sdk.ScalingPolicyTypeCluster || !pol.Enabled || pol.Target == nil {
+			continue
+		}
+
+		count, err := p.requiredNodes(apmImpl, pol.Target)
+		if err != nil {
+			p.logger.Warn("failed to calculate nodes required to place blocked allocations",
+				"policy_id", pol.ID, "error", err)
+			continue
+		}
+		if count == 0 {
+			continue
+		}
+
+		p.logger.Info("triggering policy evaluation to place blocked allocations",
+			"policy_id", pol.ID, "provision_count", count)
+
+		labels := []metrics.Label{
+			{Name: "policy_id", Value: pol.ID},
+			{Name: "target_name", Value: pol.Target.Name},
+		}
+		metrics.IncrCounterWithLabels([]string{"scale", "provisioner", "trigger_count"}, 1, labels)
+
+		p.policies.TriggerEvaluation(policy.PolicyID(pol.ID), count)
+	}
+}
+
+// requiredNodes returns the number of nodes to add to the node pool of the
+// target in order to place the blocked allocations. Policies spanning several
+// sub targets use the largest count of their sub targets, as the blocked
+// allocations may be placed on any of them.
+func (p *Provisioner) requiredNodes(apmImpl apm.APM, target *sdk.ScalingPolicyTarget) (int64, error) {
+	configs := []map[string]string{target.Config}
+	if len(target.SubTargets) > 0 {
+		configs = configs[:0]
+		for _, s := range target.SubTargets {
+			configs = append(configs, s.TargetConfig(target.Config))
+		}
+	}
+
+	var required int64
+
+	for _, cfg := range configs {
+		q, err := blockedNodesQuery(cfg)
+		if err != nil {
+			return 0, err
+		}
+
+		result, err := apmImpl.Query(q, sdk.TimeRange{})
+		if err != nil {
+			return 0, fmt.Errorf("failed to query %s: %v", q, err)
+		}
+		if len(result) == 0 {
+			continue
+		}
+
+		if count := int64(result[len(result)-1].Value); count > required {
+			required = count
+		}
+	}
+	return required, nil
+}
+
+// blockedNodesQuery returns the Nomad APM query calculating the number of
+// nodes required to place the blocked allocations within the node pool
+// identified by the target config.
+func blockedNodesQuery(cfg map[string]string) (string, error) {
+	q := provisionerQuery
+
+	for _, key := range []string{sdk.TargetConfigKeyClass, sdk.TargetConfigKeyDatacenter, sdk.TargetConfigKeyNodePool} {
+		val, ok := cfg[key]
+		if !ok {
+			continue
+		}
+		if val == "" || strings.Contains(val, "/") {
+			return "", fmt.Errorf("unsupported %s value %q", key, val)
+		}
+		q += "/" + val + "/" + key
+	}
+
+	if q == provisionerQuery {
+		return "", fmt.Errorf("node pool identification method required")
+	}
+	return q, nil
+}
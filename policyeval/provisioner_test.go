@@ -0,0 +1,173 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-autoscaler/plugins/apm"
+	"github.com/hashicorp/nomad-autoscaler/plugins/base"
+	"github.com/hashicorp/nomad-autoscaler/policy"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newBlockedEvals(t *testing.T) {
+	seen := make(map[string]struct{})
+
+	evals := []*api.Evaluation{
+		{ID: "eval-1", Status: api.EvalStatusBlocked},
+		{ID: "eval-2", Status: api.EvalStatusBlocked},
+		{ID: "eval-3", Status: api.EvalStatusComplete},
+	}
+	assert.Equal(t, 2, newBlockedEvals(seen, evals))
+	assert.Len(t, seen, 2)
+
+	// Evaluations already seen are not counted again, and those no longer
+	// blocked are forgotten.
+	evals = []*api.Evaluation{
+		{ID: "eval-2", Status: api.EvalStatusBlocked},
+		{ID: "eval-4", Status: api.EvalStatusBlocked},
+	}
+	assert.Equal(t, 1, newBlockedEvals(seen, evals))
+	assert.Equal(t, map[string]struct{}{"eval-2": {}, "eval-4": {}}, seen)
+
+	assert.Equal(t, 0, newBlockedEvals(seen, evals))
+}
+
+func Test_blockedNodesQuery(t *testing.T) {
+	testCases := []struct {
+		inputConfig    map[string]string
+		expectedOutput string
+		expectError    bool
+		name           string
+	}{
+		{
+			inputConfig:    map[string]string{"node_class": "high-memory"},
+			expectedOutput: "node_bin-packed_blocked/high-memory/node_class",
+			name:           "node class",
+		},
+		{
+			inputConfig:    map[string]string{"node_pool": "gpu", "datacenter": "dc1", "other": "value"},
+			expectedOutput: "node_bin-packed_blocked/dc1/datacenter/gpu/node_pool",
+			name:           "multiple identifiers",
+		},
+		{
+			inputConfig: map[string]string{"asg_name": "workers"},
+			expectError: true,
+			name:        "no node pool identifier",
+		},
+		{
+			inputConfig: map[string]string{"node_class": "a/b"},
+			expectError: true,
+			name:        "unsupported value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, err := blockedNodesQuery(tc.inputConfig)
+			if tc.expectError {
+				assert.Error(t, err, tc.name)
+				return
+			}
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+		})
+	}
+}
+
+func TestProvisioner_provision(t *testing.T) {
+	policies := &testProvisionerPolicies{
+		policies: []*sdk.ScalingPolicy{
+			{
+				ID:      "blocked",
+				Type:    sdk.ScalingPolicyTypeCluster,
+				Enabled: true,
+				Target:  &sdk.ScalingPolicyTarget{Config: map[string]string{"node_class": "high-memory"}},
+			},
+			{
+				ID:      "sub-targets",
+				Type:    sdk.ScalingPolicyTypeCluster,
+				Enabled: true,
+				Target: &sdk.ScalingPolicyTarget{
+					Config: map[string]string{"datacenter": "dc1"},
+					SubTargets: []*sdk.ScalingPolicySubTarget{
+						{Name: "compute", Config: map[string]string{"node_class": "compute"}},
+						{Name: "high-memory", Config: map[string]string{"node_class": "high-memory"}},
+					},
+				},
+			},
+			{
+				ID:      "not-blocked",
+				Type:    sdk.ScalingPolicyTypeCluster,
+				Enabled: true,
+				Target:  &sdk.ScalingPolicyTarget{Config: map[string]string{"node_class": "compute"}},
+			},
+			{
+				ID:     "disabled",
+				Type:   sdk.ScalingPolicyTypeCluster,
+				Target: &sdk.ScalingPolicyTarget{Config: map[string]string{"node_class": "high-memory"}},
+			},
+			{
+				ID:      "horizontal",
+				Type:    sdk.ScalingPolicyTypeHorizontal,
+				Enabled: true,
+				Target:  &sdk.ScalingPolicyTarget{Config: map[string]string{"Job": "example"}},
+			},
+		},
+		triggered: make(map[policy.PolicyID]int64),
+	}
+
+	p := &Provisioner{
+		logger:   hclog.NewNullLogger(),
+		policies: policies,
+		apms: &testProvisionerAPMs{results: map[string]float64{
+			"node_bin-packed_blocked/high-memory/node_class":                3,
+			"node_bin-packed_blocked/compute/node_class":                    0,
+			"node_bin-packed_blocked/compute/node_class/dc1/datacenter":     1,
+			"node_bin-packed_blocked/high-memory/node_class/dc1/datacenter": 2,
+		}},
+		apmName:     "nomad-apm",
+		batchWindow: time.Millisecond,
+	}
+
+	p.provision()
+	assert.Equal(t, map[policy.PolicyID]int64{
+		"blocked":     3,
+		"sub-targets": 2,
+	}, policies.triggered)
+}
+
+type testProvisionerPolicies struct {
+	policies  []*sdk.ScalingPolicy
+	triggered map[policy.PolicyID]int64
+}
+
+func (t *testProvisionerPolicies) Policies() []*sdk.ScalingPolicy { return t.policies }
+
+func (t *testProvisionerPolicies) TriggerEvaluation(id policy.PolicyID, provision int64) {
+	t.triggered[id] = provision
+}
+
+type testProvisionerAPMs struct {
+	results map[string]float64
+}
+
+func (t *testProvisionerAPMs) GetAPM(_ string) (apm.APM, error) { return t, nil }
+
+func (t *testProvisionerAPMs) Query(q string, _ sdk.TimeRange) (sdk.TimestampedMetrics, error) {
+	return sdk.TimestampedMetrics{{Timestamp: time.Now(), Value: t.results[q]}}, nil
+}
+
+func (t *testProvisionerAPMs) QueryMultiple(_ string, _ sdk.TimeRange) ([]sdk.TimestampedMetrics, error) {
+	return nil, nil
+}
+
+func (t *testProvisionerAPMs) SetConfig(_ map[string]string) error { return nil }
+
+func (t *testProvisionerAPMs) PluginInfo() (*base.PluginInfo, error) { return nil, nil }
@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+)
+
+// targetActivitiesMaxAge is how long the activities reported for a policy
+// are remembered without the policy being evaluated.
+const targetActivitiesMaxAge = time.Hour
+
+// TargetActivities tracks the scaling activities reported by the target of
+// each policy, so each change of an activity status is only surfaced once
+// regardless of which worker evaluates the policy. A nil TargetActivities is
+// safe to use and reports all activities as changed.
+type TargetActivities struct {
+	lock    sync.Mutex
+	entries map[string]*targetActivitiesEntry
+
+	// now is used to allow tests to control time.
+	now func() time.Time
+}
+
+// targetActivitiesEntry is the last reported status of each activity of a
+// policy target.
+type targetActivitiesEntry struct {
+	statuses map[string]sdk.TargetActivityStatus
+	updated  time.Time
+}
+
+// NewTargetActivities returns a new, empty, TargetActivities instance.
+func NewTargetActivities() *TargetActivities {
+	return &TargetActivities{
+		entries: make(map[string]*targetActivitiesEntry),
+		now:     time.Now,
+	}
+}
+
+// Changed records the activities reported by the target of the policy and
+// returns those which are new or whose status changed since they were last
+// reported. Activities no longer reported by the target are forgotten.
+func (t *TargetActivities) Changed(policyID string, activities []sdk.TargetActivity) []sdk.TargetActivity {
+	if t == nil {
+		return activities
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.pruneLocked()
+
+	prev := t.entries[policyID]
+	entry := &targetActivitiesEntry{
+		statuses: make(map[string]sdk.TargetActivityStatus, len(activities)),
+		updated:  t.now(),
+	}
+
+	var changed []sdk.TargetActivity
+	for _, a := range activities {
+		entry.statuses[a.ID] = a.Status
+		if prev != nil {
+			if status, ok := prev.statuses[a.ID]; ok && status == a.Status {
+				continue
+			}
+		}
+		changed = append(changed, a)
+	}
+
+	t.entries[policyID] = entry
+	return changed
+}
+
+// pruneLocked removes the entries of policies which have not been evaluated
+// recently, so activities of removed policies are not kept forever. It must
+// be called while holding the lock.
+func (t *TargetActivities) pruneLocked() {
+	now := t.now()
+	for k, e := range t.entries {
+		if now.Sub(e.updated) > targetActivitiesMaxAge {
+			delete(t.entries, k)
+		}
+	}
+}
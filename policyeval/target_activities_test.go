@@ -0,0 +1,51 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package policyeval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetActivities(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ta := NewTargetActivities()
+	ta.now = func() time.Time { return now }
+
+	running := sdk.TargetActivity{ID: "a-2", Status: sdk.TargetActivityStatusInProgress}
+	failed := sdk.TargetActivity{ID: "a-2", Status: sdk.TargetActivityStatusFailed}
+	done := sdk.TargetActivity{ID: "a-1", Status: sdk.TargetActivityStatusSuccessful}
+
+	// All activities are new when the policy is first evaluated.
+	assert.Equal(t, []sdk.TargetActivity{running, done},
+		ta.Changed("policy", []sdk.TargetActivity{running, done}))
+
+	// Unchanged activities are not reported again.
+	assert.Empty(t, ta.Changed("policy", []sdk.TargetActivity{running, done}))
+
+	// Status changes are reported.
+	assert.Equal(t, []sdk.TargetActivity{failed},
+		ta.Changed("policy", []sdk.TargetActivity{failed, done}))
+
+	// Activities of other policies are independent.
+	assert.Equal(t, []sdk.TargetActivity{failed},
+		ta.Changed("other", []sdk.TargetActivity{failed}))
+
+	// Activities no longer reported by the target are forgotten.
+	assert.Empty(t, ta.Changed("policy", []sdk.TargetActivity{failed}))
+	assert.Equal(t, []sdk.TargetActivity{done},
+		ta.Changed("policy", []sdk.TargetActivity{failed, done}))
+
+	// Policies not evaluated recently are pruned.
+	now = now.Add(targetActivitiesMaxAge + time.Minute)
+	ta.Changed("policy", nil)
+	assert.Len(t, ta.entries, 1)
+
+	// A nil TargetActivities reports all activities.
+	var nilTA *TargetActivities
+	assert.Equal(t, []sdk.TargetActivity{done}, nilTA.Changed("policy", []sdk.TargetActivity{done}))
+}
@@ -0,0 +1,155 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Aggregation reduces metrics to a single value.
+type Aggregation func(TimestampedMetrics) float64
+
+// ParseAggregation returns the Aggregation identified by the input, allowing
+// policies and plugins to select how metrics are reduced by name. Supported
+// values are sum, avg, min, max, stddev and percentiles in the form p<N>,
+// such as p50, p90, p99 or p99.9.
+func ParseAggregation(s string) (Aggregation, error) {
+	switch s {
+	case "sum":
+		return TimestampedMetrics.Sum, nil
+	case "avg":
+		return TimestampedMetrics.Mean, nil
+	case "min":
+		return TimestampedMetrics.Min, nil
+	case "max":
+		return TimestampedMetrics.Max, nil
+	case "stddev":
+		return TimestampedMetrics.StdDev, nil
+	}
+
+	if p, ok := strings.CutPrefix(s, "p"); ok {
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil || math.IsNaN(f) || f < 0 || f > 100 {
+			return nil, fmt.Errorf("invalid percentile %q, must be between p0 and p100", s)
+		}
+		return func(t TimestampedMetrics) float64 { return t.Percentile(f) }, nil
+	}
+
+	return nil, fmt.Errorf("invalid aggregation %q, allowed values are sum, avg, min, max, stddev or p<N>", s)
+}
+
+// Values returns the values of the metrics.
+func (t TimestampedMetrics) Values() []float64 {
+	values := make([]float64, len(t))
+	for i, m := range t {
+		values[i] = m.Value
+	}
+	return values
+}
+
+// Sum returns the sum of the metric values.
+func (t TimestampedMetrics) Sum() float64 {
+	var sum float64
+	for _, m := range t {
+		sum += m.Value
+	}
+	return sum
+}
+
+// Mean returns the average of the metric values, or zero if there are no
+// metrics.
+func (t TimestampedMetrics) Mean() float64 {
+	if len(t) == 0 {
+		return 0
+	}
+	return t.Sum() / float64(len(t))
+}
+
+// Min returns the smallest metric value, or zero if there are no metrics.
+func (t TimestampedMetrics) Min() float64 {
+	if len(t) == 0 {
+		return 0
+	}
+	min := t[0].Value
+	for _, m := range t[1:] {
+		min = math.Min(min, m.Value)
+	}
+	return min
+}
+
+// Max returns the largest metric value, or zero if there are no metrics.
+func (t TimestampedMetrics) Max() float64 {
+	if len(t) == 0 {
+		return 0
+	}
+	max := t[0].Value
+	for _, m := range t[1:] {
+		max = math.Max(max, m.Value)
+	}
+	return max
+}
+
+// StdDev returns the population standard deviation of the metric values, or
+// zero if there are no metrics.
+func (t TimestampedMetrics) StdDev() float64 {
+	if len(t) == 0 {
+		return 0
+	}
+
+	mean := t.Mean()
+	var sum float64
+	for _, m := range t {
+		d := m.Value - mean
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(t)))
+}
+
+// Percentile returns the p-th percentile of the metric values, where p is
+// between 0 and 100. Values between the closest ranks are linearly
+// interpolated. Zero is returned if there are no metrics.
+func (t TimestampedMetrics) Percentile(p float64) float64 {
+	if len(t) == 0 {
+		return 0
+	}
+
+	values := t.Values()
+	sort.Float64s(values)
+
+	p = math.Max(0, math.Min(100, p))
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
+
+// WeightedMean returns the average of the metric values, each multiplied by
+// the weight at the same index. An error is returned if the number of
+// weights does not match the number of metrics, any weight is negative, or
+// the weights sum to zero.
+func (t TimestampedMetrics) WeightedMean(weights []float64) (float64, error) {
+	if len(weights) != len(t) {
+		return 0, fmt.Errorf("expected %d weights, received %d", len(t), len(weights))
+	}
+
+	var sum, total float64
+	for i, m := range t {
+		if weights[i] < 0 {
+			return 0, fmt.Errorf("weight %d cannot be negative", i)
+		}
+		sum += m.Value * weights[i]
+		total += weights[i]
+	}
+
+	if total == 0 {
+		return 0, errors.New("weights must not sum to zero")
+	}
+	return sum / total, nil
+}
@@ -0,0 +1,157 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMetrics(values ...float64) TimestampedMetrics {
+	now := time.Unix(1700000000, 0)
+	metrics := make(TimestampedMetrics, len(values))
+	for i, v := range values {
+		metrics[i] = TimestampedMetric{Timestamp: now.Add(time.Duration(i) * time.Second), Value: v}
+	}
+	return metrics
+}
+
+func TestTimestampedMetrics_aggregations(t *testing.T) {
+	metrics := newTestMetrics(2, 4, 4, 4, 5, 5, 7, 9)
+
+	assert.Equal(t, []float64{2, 4, 4, 4, 5, 5, 7, 9}, metrics.Values())
+	assert.Equal(t, 40.0, metrics.Sum())
+	assert.Equal(t, 5.0, metrics.Mean())
+	assert.Equal(t, 2.0, metrics.Min())
+	assert.Equal(t, 9.0, metrics.Max())
+	assert.Equal(t, 2.0, metrics.StdDev())
+
+	// Empty metrics return zero values.
+	empty := TimestampedMetrics{}
+	assert.Empty(t, empty.Values())
+	assert.Zero(t, empty.Sum())
+	assert.Zero(t, empty.Mean())
+	assert.Zero(t, empty.Min())
+	assert.Zero(t, empty.Max())
+	assert.Zero(t, empty.StdDev())
+	assert.Zero(t, empty.Percentile(99))
+}
+
+func TestTimestampedMetrics_Percentile(t *testing.T) {
+	// Values are unsorted to ensure the calculation does not depend on the
+	// order of the metrics.
+	metrics := newTestMetrics(7, 1, 10, 3, 5, 9, 2, 8, 4, 6)
+
+	testCases := []struct {
+		percentile float64
+		expected   float64
+	}{
+		{percentile: 0, expected: 1},
+		{percentile: 50, expected: 5.5},
+		{percentile: 90, expected: 9.1},
+		{percentile: 99, expected: 9.91},
+		{percentile: 100, expected: 10},
+		{percentile: -10, expected: 1},
+		{percentile: 200, expected: 10},
+	}
+
+	for _, tc := range testCases {
+		assert.InDelta(t, tc.expected, metrics.Percentile(tc.percentile), 0.0001, "p%v", tc.percentile)
+	}
+
+	// The input metrics are not modified.
+	assert.Equal(t, 7.0, metrics[0].Value)
+	assert.Equal(t, 42.0, newTestMetrics(42).Percentile(90))
+}
+
+func TestTimestampedMetrics_WeightedMean(t *testing.T) {
+	metrics := newTestMetrics(10, 20, 30)
+
+	testCases := []struct {
+		name          string
+		weights       []float64
+		expected      float64
+		expectedError string
+	}{
+		{
+			name:     "equal weights",
+			weights:  []float64{1, 1, 1},
+			expected: 20,
+		},
+		{
+			name:     "recent points weighted higher",
+			weights:  []float64{1, 2, 3},
+			expected: 140.0 / 6,
+		},
+		{
+			name:     "zero weight",
+			weights:  []float64{0, 1, 1},
+			expected: 25,
+		},
+		{
+			name:          "mismatched weights",
+			weights:       []float64{1, 1},
+			expectedError: "expected 3 weights, received 2",
+		},
+		{
+			name:          "negative weight",
+			weights:       []float64{1, -1, 1},
+			expectedError: "weight 1 cannot be negative",
+		},
+		{
+			name:          "zero weights",
+			weights:       []float64{0, 0, 0},
+			expectedError: "weights must not sum to zero",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := metrics.WeightedMean(tc.weights)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tc.expected, actual, 0.0001)
+		})
+	}
+}
+
+func TestParseAggregation(t *testing.T) {
+	metrics := newTestMetrics(7, 1, 10, 3, 5, 9, 2, 8, 4, 6)
+
+	testCases := []struct {
+		input         string
+		expected      float64
+		expectedError string
+	}{
+		{input: "sum", expected: 55},
+		{input: "avg", expected: 5.5},
+		{input: "min", expected: 1},
+		{input: "max", expected: 10},
+		{input: "stddev", expected: 2.8723},
+		{input: "p50", expected: 5.5},
+		{input: "p99.9", expected: 9.991},
+		{input: "p101", expectedError: `invalid percentile "p101", must be between p0 and p100`},
+		{input: "pmax", expectedError: `invalid percentile "pmax", must be between p0 and p100`},
+		{input: "pNaN", expectedError: `invalid percentile "pNaN", must be between p0 and p100`},
+		{input: "median", expectedError: `invalid aggregation "median", allowed values are sum, avg, min, max, stddev or p<N>`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			agg, err := ParseAggregation(tc.input)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tc.expected, agg(metrics), 0.0001)
+		})
+	}
+}
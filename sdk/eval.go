@@ -16,6 +16,12 @@ type ScalingEvaluation struct {
 	Policy           *ScalingPolicy
 	CheckEvaluations []*ScalingCheckEvaluation
 	CreateTime       time.Time
+
+	// ProvisionCount is the number of nodes to add to a cluster target in
+	// order to place blocked allocations. It is only set on evaluations
+	// triggered by the blocked evaluations provisioner, and the target is
+	// scaled out by at least this many nodes regardless of the checks.
+	ProvisionCount int64
 }
 
 // NewScalingEvaluation creates a new ScalingEvaluation based off the passed
@@ -0,0 +1,199 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package scaleutils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	errHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/error"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	defaultNodeCleanupVerifyAttempts = 5
+	defaultNodeCleanupVerifyInterval = 10 * time.Second
+
+	// leakReasons are used as the metric label and log value identifying why
+	// a scaled in node is not cleaned up.
+	leakReasonRemoteResource = "remote_resource_exists"
+	leakReasonNomadNode      = "nomad_node_exists"
+)
+
+// ClusterRemoteIDExistsFunc is the callback function signature used to check
+// whether the remote resource of a scaled in node still exists within the
+// target platform.
+type ClusterRemoteIDExistsFunc func(ctx context.Context, remoteID string) (bool, error)
+
+// nodeCleaner is the subset of the Nomad nodes API used to verify scaled in
+// nodes are cleaned up, allowing the calls to be replaced within tests.
+type nodeCleaner interface {
+	Info(nodeID string, q *api.QueryOptions) (*api.Node, *api.QueryMeta, error)
+	Purge(nodeID string, q *api.QueryOptions) (*api.NodePurgeResponse, *api.QueryMeta, error)
+}
+
+// cleanupVerification is the operator configuration of the node cleanup
+// verification.
+type cleanupVerification struct {
+	enabled  bool
+	attempts int
+	interval time.Duration
+}
+
+// cleanupVerifyConfig reads the node cleanup verification config, using the
+// defaults for the values not configured.
+func cleanupVerifyConfig(cfg map[string]string) (cleanupVerification, error) {
+
+	verify := cleanupVerification{
+		attempts: defaultNodeCleanupVerifyAttempts,
+		interval: defaultNodeCleanupVerifyInterval,
+	}
+
+	var mErr *multierror.Error
+
+	if val, ok := cfg[sdk.TargetConfigKeyNodeCleanupVerify]; ok {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			mErr = multierror.Append(mErr, err)
+		}
+		verify.enabled = b
+	}
+
+	if val, ok := cfg[sdk.TargetConfigKeyNodeCleanupVerifyAttempts]; ok {
+		a, err := strconv.Atoi(val)
+		switch {
+		case err != nil:
+			mErr = multierror.Append(mErr, err)
+		case a < 1:
+			mErr = multierror.Append(mErr, fmt.Errorf("%s must be positive", sdk.TargetConfigKeyNodeCleanupVerifyAttempts))
+		default:
+			verify.attempts = a
+		}
+	}
+
+	if val, ok := cfg[sdk.TargetConfigKeyNodeCleanupVerifyInterval]; ok {
+		d, err := time.ParseDuration(val)
+		switch {
+		case err != nil:
+			mErr = multierror.Append(mErr, err)
+		case d < 0:
+			mErr = multierror.Append(mErr, fmt.Errorf("%s must not be negative", sdk.TargetConfigKeyNodeCleanupVerifyInterval))
+		default:
+			verify.interval = d
+		}
+	}
+
+	if mErr != nil {
+		return cleanupVerification{}, errHelper.FormattedMultiError(mErr)
+	}
+	return verify, nil
+}
+
+// verifyCleanup checks the scaled in nodes are cleaned up, retrying until
+// they are or the configured attempts are exhausted. Nodes which are not
+// cleaned up are reported as leaked, as they would otherwise be counted
+// within the node pool and block it from being scaled.
+func (c *ClusterScaleUtils) verifyCleanup(ctx context.Context, ids []NodeResourceID, purge bool, verify cleanupVerification) error {
+
+	pending := ids
+	reasons := make(map[string]string)
+
+	for attempt := 1; ; attempt++ {
+		var remaining []NodeResourceID
+
+		for _, node := range pending {
+			reason, err := c.checkNodeCleanup(ctx, node, purge)
+			if err != nil {
+				c.log.Warn("failed to verify node cleanup", "node_id", node.NomadNodeID, "error", err)
+				reason = err.Error()
+			}
+			if reason != "" {
+				reasons[node.NomadNodeID] = reason
+				remaining = append(remaining, node)
+			}
+		}
+
+		pending = remaining
+		if len(pending) == 0 {
+			c.log.Debug("verified scaled in nodes are cleaned up", "attempts", attempt)
+			return nil
+		}
+		if attempt >= verify.attempts {
+			break
+		}
+
+		c.log.Debug("waiting for scaled in nodes to be cleaned up",
+			"remaining", len(pending), "attempt", attempt, "interval", verify.interval)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("node cleanup verification cancelled with %d nodes remaining: %w", len(pending), ctx.Err())
+		case <-time.After(verify.interval):
+		}
+	}
+
+	var mErr *multierror.Error
+
+	for _, node := range pending {
+		reason := reasons[node.NomadNodeID]
+		c.log.Error("scaled in node was not cleaned up",
+			"node_id", node.NomadNodeID, "remote_id", node.RemoteResourceID, "reason", reason)
+
+		label := leakReasonNomadNode
+		if reason == leakReasonRemoteResource {
+			label = leakReasonRemoteResource
+		}
+		metrics.IncrCounterWithLabels([]string{"scale", "target", "leaked_node_count"}, 1,
+			[]metrics.Label{{Name: "reason", Value: label}})
+
+		mErr = multierror.Append(mErr, fmt.Errorf("node %s was not cleaned up: %s", node.NomadNodeID, reason))
+	}
+
+	return errHelper.FormattedMultiError(mErr)
+}
+
+// checkNodeCleanup returns the reason the scaled in node is not yet cleaned
+// up, or an empty string if it is. If purging is enabled, a Nomad node whose
+// remote resource is gone is purged again, as the previous purge may have
+// failed or raced with a final client heartbeat.
+func (c *ClusterScaleUtils) checkNodeCleanup(ctx context.Context, node NodeResourceID, purge bool) (string, error) {
+
+	if c.ClusterRemoteIDExistsFunc != nil && node.RemoteResourceID != "" {
+		exists, err := c.ClusterRemoteIDExistsFunc(ctx, node.RemoteResourceID)
+		if err != nil {
+			return "", fmt.Errorf("failed to check remote resource %s: %v", node.RemoteResourceID, err)
+		}
+		if exists {
+			return leakReasonRemoteResource, nil
+		}
+	}
+
+	info, _, err := c.cleaner.Info(node.NomadNodeID, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "404") {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read node %s: %v", node.NomadNodeID, err)
+	}
+
+	// Without purging, Nomad removes the node once it has been down for the
+	// node garbage collection threshold.
+	if !purge {
+		if info.Status == api.NodeStatusDown {
+			return "", nil
+		}
+		return leakReasonNomadNode, nil
+	}
+
+	if err := c.purgeNode(node.NomadNodeID); err != nil {
+		return "", err
+	}
+	return "", nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package scaleutils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockCleaner is a mock implementation of the nodeCleaner. Nodes are tracked
+// by their status, and removed once purged.
+type mockCleaner struct {
+	nodes  map[string]string
+	purged []string
+}
+
+func (mc *mockCleaner) Info(nodeID string, _ *api.QueryOptions) (*api.Node, *api.QueryMeta, error) {
+	status, ok := mc.nodes[nodeID]
+	if !ok {
+		return nil, nil, errors.New("Unexpected response code: 404 (node not found)")
+	}
+	return &api.Node{ID: nodeID, Status: status}, nil, nil
+}
+
+func (mc *mockCleaner) Purge(nodeID string, _ *api.QueryOptions) (*api.NodePurgeResponse, *api.QueryMeta, error) {
+	delete(mc.nodes, nodeID)
+	mc.purged = append(mc.purged, nodeID)
+	return &api.NodePurgeResponse{}, nil, nil
+}
+
+func Test_cleanupVerifyConfig(t *testing.T) {
+	testCases := []struct {
+		inputCfg            map[string]string
+		expectedOutput      cleanupVerification
+		expectedOutputError bool
+		name                string
+	}{
+		{
+			inputCfg: map[string]string{},
+			expectedOutput: cleanupVerification{
+				attempts: defaultNodeCleanupVerifyAttempts,
+				interval: defaultNodeCleanupVerifyInterval,
+			},
+			name: "no user parameters set",
+		},
+		{
+			inputCfg: map[string]string{
+				"node_cleanup_verify":          "true",
+				"node_cleanup_verify_attempts": "3",
+				"node_cleanup_verify_interval": "1m",
+			},
+			expectedOutput: cleanupVerification{enabled: true, attempts: 3, interval: time.Minute},
+			name:           "all user parameters set",
+		},
+		{
+			inputCfg: map[string]string{
+				"node_cleanup_verify":          "yes please",
+				"node_cleanup_verify_attempts": "0",
+				"node_cleanup_verify_interval": "-1s",
+			},
+			expectedOutputError: true,
+			name:                "invalid user parameters",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, err := cleanupVerifyConfig(tc.inputCfg)
+			if tc.expectedOutputError {
+				assert.Error(t, err, tc.name)
+				return
+			}
+			assert.NoError(t, err, tc.name)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+		})
+	}
+}
+
+func TestClusterScaleUtils_verifyCleanup(t *testing.T) {
+	testCases := []struct {
+		inputNodes          map[string]string
+		inputRemoteExists   map[string]int
+		inputPurge          bool
+		expectedPurged      []string
+		expectedOutputError bool
+		name                string
+	}{
+		{
+			inputNodes: map[string]string{},
+			name:       "nodes already removed",
+		},
+		{
+			inputNodes:     map[string]string{"node-1": api.NodeStatusDown},
+			inputPurge:     true,
+			expectedPurged: []string{"node-1"},
+			name:           "nodes purged again",
+		},
+		{
+			inputNodes:        map[string]string{"node-1": api.NodeStatusDown},
+			inputRemoteExists: map[string]int{"i-1": 2},
+			inputPurge:        true,
+			expectedPurged:    []string{"node-1"},
+			name:              "purge waits for remote resource removal",
+		},
+		{
+			inputNodes: map[string]string{"node-1": api.NodeStatusDown},
+			name:       "nodes down without purge",
+		},
+		{
+			inputNodes:          map[string]string{"node-1": api.NodeStatusReady},
+			expectedOutputError: true,
+			name:                "nodes not down without purge",
+		},
+		{
+			inputNodes:          map[string]string{"node-1": api.NodeStatusDown},
+			inputRemoteExists:   map[string]int{"i-1": 5},
+			inputPurge:          true,
+			expectedOutputError: true,
+			name:                "remote resource not removed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mc := &mockCleaner{nodes: tc.inputNodes}
+
+			// The remote resource exists for the configured number of checks.
+			remoteExists := func(_ context.Context, id string) (bool, error) {
+				if tc.inputRemoteExists[id] > 0 {
+					tc.inputRemoteExists[id]--
+					return true, nil
+				}
+				return false, nil
+			}
+
+			cu := &ClusterScaleUtils{
+				log:                       hclog.NewNullLogger(),
+				cleaner:                   mc,
+				ClusterRemoteIDExistsFunc: remoteExists,
+			}
+
+			ids := []NodeResourceID{{NomadNodeID: "node-1", RemoteResourceID: "i-1"}}
+			verify := cleanupVerification{enabled: true, attempts: 3, interval: time.Millisecond}
+
+			err := cu.verifyCleanup(context.Background(), ids, tc.inputPurge, verify)
+			if tc.expectedOutputError {
+				assert.Error(t, err, tc.name)
+			} else {
+				assert.NoError(t, err, tc.name)
+			}
+			assert.Equal(t, tc.expectedPurged, mc.purged, tc.name)
+		})
+	}
+}
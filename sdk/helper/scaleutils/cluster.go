@@ -44,7 +44,13 @@ type ClusterScaleUtils struct {
 	// Nomad nodes ID to the remote resource ID used by the target platform.
 	ClusterNodeIDLookupFunc ClusterNodeIDLookupFunc
 
+	// ClusterRemoteIDExistsFunc is the optional callback function used to
+	// verify the remote resource of a scaled in node has been removed from
+	// the target platform. When not set, only the Nomad node is verified.
+	ClusterRemoteIDExistsFunc ClusterRemoteIDExistsFunc
+
 	drainer nodeDrainer
+	cleaner nodeCleaner
 }
 
 // NewClusterScaleUtils instantiates a new ClusterScaleUtils object for use.
@@ -67,6 +73,7 @@ func NewClusterScaleUtils(cfg *api.Config, log hclog.Logger) (*ClusterScaleUtils
 		client:    client,
 		curNodeID: id,
 		drainer:   client.Nodes(),
+		cleaner:   client.Nodes(),
 	}, nil
 }
 
@@ -110,6 +117,28 @@ func (c *ClusterScaleUtils) RunPreScaleInTasks(ctx context.Context, cfg map[stri
 // terminating the nodes in the remote provider.
 func (c *ClusterScaleUtils) RunPreScaleInTasksWithRemoteCheck(ctx context.Context, cfg map[string]string, remoteIDs []string, num int) ([]NodeResourceID, error) {
 
+	selectedResourceIDs, err := c.SelectScaleInNodesWithRemoteCheck(cfg, remoteIDs, num)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drain the nodes.
+	// TODO(jrasell) we should try some reconciliation here, where we identify
+	//  failed nodes and continue with nodes that drained successfully.
+	if err := c.DrainNodes(ctx, cfg, selectedResourceIDs); err != nil {
+		return nil, err
+	}
+	c.log.Debug("pre scale-in tasks now complete")
+
+	return selectedResourceIDs, nil
+}
+
+// SelectScaleInNodesWithRemoteCheck performs node identification, filtering
+// by remote ID, and selection of the nodes to terminate in the remote
+// provider, without draining them. It allows targets to perform their own
+// tasks on the selected nodes before they are drained using DrainNodes.
+func (c *ClusterScaleUtils) SelectScaleInNodesWithRemoteCheck(cfg map[string]string, remoteIDs []string, num int) ([]NodeResourceID, error) {
+
 	// Check that the ClusterNodeIDLookupFunc has been set, otherwise we cannot
 	// attempt to identify nodes and their remote resource IDs.
 	if c.ClusterNodeIDLookupFunc == nil {
@@ -176,14 +205,6 @@ func (c *ClusterScaleUtils) RunPreScaleInTasksWithRemoteCheck(ctx context.Contex
 		selectedResourceIDs = append(selectedResourceIDs, nodesResourceIDsMap[n.ID])
 	}
 
-	// Drain the nodes.
-	// TODO(jrasell) we should try some reconciliation here, where we identify
-	//  failed nodes and continue with nodes that drained successfully.
-	if err := c.DrainNodes(ctx, cfg, selectedResourceIDs); err != nil {
-		return nil, err
-	}
-	c.log.Debug("pre scale-in tasks now complete")
-
 	return selectedResourceIDs, nil
 }
 
@@ -218,6 +239,22 @@ func (c *ClusterScaleUtils) IdentifyScaleInNodes(cfg map[string]string, num int)
 	// Filter out the Nomad node ID where this autoscaler instance is running.
 	filteredNodes = filterOutNodeID(filteredNodes, c.curNodeID)
 
+	// Filter out the nodes which operators have protected from scale in.
+	filteredNodes, err = filterOutProtectedNodes(filteredNodes, c.nodeInfo, c.log)
+	if err != nil {
+		return nil, err
+	}
+
+	// When scaling in only drains nodes, those drained previously are still
+	// ready and must not be selected again.
+	eligibilityOnly, err := IsEligibilityOnly(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if eligibilityOnly {
+		filteredNodes = filterOutDrainedNodes(filteredNodes)
+	}
+
 	if c.log.IsDebug() {
 		for _, n := range filteredNodes {
 			c.log.Debug("node passed filter criteria", "node_id", n.ID)
@@ -240,6 +277,12 @@ func (c *ClusterScaleUtils) IdentifyScaleInNodes(cfg map[string]string, num int)
 	return filteredNodes, nil
 }
 
+// nodeInfo reads the full node object of the node ID from the API.
+func (c *ClusterScaleUtils) nodeInfo(nodeID string) (*api.Node, error) {
+	node, _, err := c.client.Nodes().Info(nodeID, nil)
+	return node, err
+}
+
 func (c *ClusterScaleUtils) IdentifyScaleInRemoteIDs(nodes []*api.NodeListStub) ([]NodeResourceID, error) {
 
 	var (
@@ -278,6 +321,55 @@ func (c *ClusterScaleUtils) IdentifyScaleInRemoteIDs(nodes []*api.NodeListStub)
 	return out, nil
 }
 
+// IdentifyNodesByRemoteIDs returns the Nomad nodes whose remote resource ID
+// is within remoteIDs, regardless of the node pool they are part of. It allows
+// targets to act on nodes when the remote provider signals a change to their
+// resources. Nodes which are down are skipped, as are nodes whose remote ID
+// cannot be identified, which may be running in another provider.
+func (c *ClusterScaleUtils) IdentifyNodesByRemoteIDs(remoteIDs []string) ([]NodeResourceID, error) {
+
+	// Check that the ClusterNodeIDLookupFunc has been set, otherwise we cannot
+	// attempt to identify the remote resource IDs of nodes.
+	if c.ClusterNodeIDLookupFunc == nil {
+		return nil, errors.New("required ClusterNodeIDLookupFunc not set")
+	}
+
+	wanted := make(map[string]struct{}, len(remoteIDs))
+	for _, id := range remoteIDs {
+		wanted[id] = struct{}{}
+	}
+
+	nodes, _, err := c.client.Nodes().List(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Nomad nodes from API: %v", err)
+	}
+
+	var out []NodeResourceID
+
+	for _, node := range nodes {
+		if node.Status == api.NodeStatusDown {
+			continue
+		}
+
+		nodeInfo, err := c.nodeInfo(node.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := c.ClusterNodeIDLookupFunc(nodeInfo)
+		if err != nil {
+			c.log.Trace("failed to identify remote provider ID for node", "node_id", node.ID, "error", err)
+			continue
+		}
+
+		if _, ok := wanted[id]; ok {
+			c.log.Debug("identified node for remote provider ID", "node_id", node.ID, "remote_id", id)
+			out = append(out, NodeResourceID{NomadNodeID: node.ID, RemoteResourceID: id})
+		}
+	}
+	return out, nil
+}
+
 func (c *ClusterScaleUtils) SelectScaleInNodes(nodes []*api.NodeListStub, cfg map[string]string, num int) ([]*api.NodeListStub, error) {
 	// Setup the node selector used to identify suitable nodes for termination.
 	selector, err := nodeselector.NewSelector(cfg, c.client, c.log)
@@ -305,32 +397,11 @@ func (c *ClusterScaleUtils) SelectScaleInNodes(nodes []*api.NodeListStub, cfg ma
 // RunPostScaleInTasks triggers any tasks which should occur after the nodes
 // have been terminated within the remote provider.
 //
-// The context is currently ignored on purpose, pending investigation into
-// plugging this into the Nomad API query meta.
-func (c *ClusterScaleUtils) RunPostScaleInTasks(_ context.Context, cfg map[string]string, ids []NodeResourceID) error {
-
-	// Attempt to read of the node purge config parameter. If it has been set
-	// then check its value, otherwise the default stance is that node purging
-	// is disabled.
-	if val, ok := cfg[sdk.TargetConfigKeyNodePurge]; ok {
+// The context is only used to stop the node cleanup verification, pending
+// investigation into plugging it into the Nomad API query meta.
+func (c *ClusterScaleUtils) RunPostScaleInTasks(ctx context.Context, cfg map[string]string, ids []NodeResourceID) error {
 
-		// Parse the string as a bool. If we get an error return this as the
-		// operator has attempted to configure this value, but it's not worth
-		// breaking the whole pipeline for. Therefore log the error and return
-		// as Nomad will eventually perform this work.
-		boolVal, err := strconv.ParseBool(val)
-		if err != nil {
-			c.log.Error("failed to parse node_purge config param", "error", err)
-			return nil
-		}
-
-		// If the operator has disabled node purging, exit.
-		if !boolVal {
-			return nil
-		}
-	} else {
-		return nil
-	}
+	purge := c.nodePurgeEnabled(cfg)
 
 	// Use a multierror to collect errors from any and all node purge calls
 	// that fail.
@@ -338,19 +409,61 @@ func (c *ClusterScaleUtils) RunPostScaleInTasks(_ context.Context, cfg map[strin
 
 	// Iterate the node list and perform a purge on each node. In the event of
 	// an error, add this to the list. Otherwise log useful information.
-	for _, node := range ids {
-
-		resp, _, err := c.client.Nodes().Purge(node.NomadNodeID, nil)
-		if err != nil {
-			mErr = multierror.Append(mErr, err)
-		} else {
-			c.log.Info("successfully purged Nomad node", "node_id", node.NomadNodeID, "nomad_evals", resp.EvalIDs)
+	if purge {
+		for _, node := range ids {
+			if err := c.purgeNode(node.NomadNodeID); err != nil {
+				mErr = multierror.Append(mErr, err)
+			}
 		}
 	}
 
+	// Verify the nodes were cleaned up, if the operator enabled it. Purge
+	// errors are retried as part of the verification.
+	verify, err := cleanupVerifyConfig(cfg)
+	if err != nil {
+		c.log.Error("failed to parse node cleanup verification config", "error", err)
+		return errHelper.FormattedMultiError(mErr)
+	}
+	if verify.enabled {
+		return c.verifyCleanup(ctx, ids, purge, verify)
+	}
+
 	return errHelper.FormattedMultiError(mErr)
 }
 
+// nodePurgeEnabled returns whether the operator enabled purging Nomad nodes
+// once they have been terminated within the remote provider.
+func (c *ClusterScaleUtils) nodePurgeEnabled(cfg map[string]string) bool {
+
+	// Attempt to read of the node purge config parameter. If it has been set
+	// then check its value, otherwise the default stance is that node purging
+	// is disabled.
+	val, ok := cfg[sdk.TargetConfigKeyNodePurge]
+	if !ok {
+		return false
+	}
+
+	// Parse the string as a bool. If we get an error log it, as the operator
+	// has attempted to configure this value, but it's not worth breaking the
+	// whole pipeline for as Nomad will eventually perform this work.
+	boolVal, err := strconv.ParseBool(val)
+	if err != nil {
+		c.log.Error("failed to parse node_purge config param", "error", err)
+		return false
+	}
+	return boolVal
+}
+
+// purgeNode purges the node from Nomad.
+func (c *ClusterScaleUtils) purgeNode(nodeID string) error {
+	resp, _, err := c.cleaner.Purge(nodeID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to purge node %s: %v", nodeID, err)
+	}
+	c.log.Info("successfully purged Nomad node", "node_id", nodeID, "nomad_evals", resp.EvalIDs)
+	return nil
+}
+
 // IsPoolReady provides a method for understanding whether the node pool is in
 // a state that allows it to be safely scaled. This should be used by target
 // plugins when providing their status response. A non-nil error indicates
@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package scaleutils
+
+import (
+	"fmt"
+	"strconv"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	errHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/error"
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils/nodepool"
+	"github.com/hashicorp/nomad/api"
+)
+
+// IsEligibilityOnly returns whether the target config enables the eligibility
+// only scale in mode, where nodes are drained but not terminated. The mode is
+// disabled by default.
+func IsEligibilityOnly(cfg map[string]string) (bool, error) {
+	val, ok := cfg[sdk.TargetConfigKeyNodeEligibilityOnly]
+	if !ok {
+		return false, nil
+	}
+
+	boolVal, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s config param: %v", sdk.TargetConfigKeyNodeEligibilityOnly, err)
+	}
+	return boolVal, nil
+}
+
+// IdentifyDrainedNodes returns the ready nodes within the pool which have been
+// drained by the Nomad Autoscaler and are still ineligible for scheduling.
+// When using the eligibility only scale in mode, these nodes no longer form
+// part of the pool capacity but have not yet been removed.
+func (c *ClusterScaleUtils) IdentifyDrainedNodes(cfg map[string]string) ([]*api.NodeListStub, error) {
+
+	poolID, err := nodepool.NewClusterNodePoolIdentifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, _, err := c.client.Nodes().List(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Nomad nodes: %v", err)
+	}
+
+	var out []*api.NodeListStub
+	for _, node := range nodes {
+		if poolID.IsPoolMember(node) && isDrainedNode(node) {
+			out = append(out, node)
+		}
+	}
+	return out, nil
+}
+
+// MarkNodesEligible marks the nodes as eligible for scheduling, returning
+// drained nodes to the pool capacity.
+func (c *ClusterScaleUtils) MarkNodesEligible(nodes []*api.NodeListStub) error {
+
+	var mErr *multierror.Error
+
+	for _, node := range nodes {
+		if _, err := c.client.Nodes().ToggleEligibility(node.ID, true, nil); err != nil {
+			mErr = multierror.Append(mErr, fmt.Errorf("failed to mark node %s eligible: %v", node.ID, err))
+			continue
+		}
+		c.log.Info("marked Nomad node eligible", "node_id", node.ID)
+	}
+
+	return errHelper.FormattedMultiError(mErr)
+}
+
+// isDrainedNode returns whether the node is ready and was drained by the Nomad
+// Autoscaler, remaining ineligible for scheduling once the drain completed.
+func isDrainedNode(node *api.NodeListStub) bool {
+	return node.Status == api.NodeStatusReady &&
+		!node.Drain &&
+		node.SchedulingEligibility == api.NodeSchedulingIneligible &&
+		node.LastDrain != nil &&
+		node.LastDrain.Meta[nodeDrainedMetaKey] == nodeDrainedMetaValue
+}
+
+// filterOutDrainedNodes removes the nodes drained by the Nomad Autoscaler from
+// the list.
+func filterOutDrainedNodes(nodes []*api.NodeListStub) []*api.NodeListStub {
+	var out []*api.NodeListStub
+	for _, node := range nodes {
+		if !isDrainedNode(node) {
+			out = append(out, node)
+		}
+	}
+	return out
+}
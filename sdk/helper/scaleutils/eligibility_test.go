@@ -0,0 +1,103 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package scaleutils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsEligibilityOnly(t *testing.T) {
+	testCases := []struct {
+		inputCfg       map[string]string
+		expectedOutput bool
+		expectedError  error
+		name           string
+	}{
+		{
+			inputCfg:       map[string]string{},
+			expectedOutput: false,
+			expectedError:  nil,
+			name:           "not configured",
+		},
+		{
+			inputCfg:       map[string]string{"node_eligibility_only": "true"},
+			expectedOutput: true,
+			expectedError:  nil,
+			name:           "enabled",
+		},
+		{
+			inputCfg:       map[string]string{"node_eligibility_only": "false"},
+			expectedOutput: false,
+			expectedError:  nil,
+			name:           "disabled",
+		},
+		{
+			inputCfg:       map[string]string{"node_eligibility_only": "maybe"},
+			expectedOutput: false,
+			expectedError:  errors.New("failed to parse node_eligibility_only config param: strconv.ParseBool: parsing \"maybe\": invalid syntax"),
+			name:           "invalid value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualError := IsEligibilityOnly(tc.inputCfg)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedError, actualError, tc.name)
+		})
+	}
+}
+
+func Test_filterOutDrainedNodes(t *testing.T) {
+	drainedMeta := &api.DrainMetadata{Meta: map[string]string{nodeDrainedMetaKey: nodeDrainedMetaValue}}
+
+	nodes := []*api.NodeListStub{
+		{
+			ID:                    "eligible",
+			SchedulingEligibility: api.NodeSchedulingEligible,
+			Status:                api.NodeStatusReady,
+			LastDrain:             drainedMeta,
+		},
+		{
+			ID:                    "drained",
+			SchedulingEligibility: api.NodeSchedulingIneligible,
+			Status:                api.NodeStatusReady,
+			LastDrain:             drainedMeta,
+		},
+		{
+			ID:                    "operator-ineligible",
+			SchedulingEligibility: api.NodeSchedulingIneligible,
+			Status:                api.NodeStatusReady,
+		},
+		{
+			ID:                    "operator-drained",
+			SchedulingEligibility: api.NodeSchedulingIneligible,
+			Status:                api.NodeStatusReady,
+			LastDrain:             &api.DrainMetadata{},
+		},
+		{
+			ID:                    "draining",
+			Drain:                 true,
+			SchedulingEligibility: api.NodeSchedulingIneligible,
+			Status:                api.NodeStatusReady,
+			LastDrain:             drainedMeta,
+		},
+		{
+			ID:                    "down",
+			SchedulingEligibility: api.NodeSchedulingIneligible,
+			Status:                api.NodeStatusDown,
+			LastDrain:             drainedMeta,
+		},
+	}
+
+	var ids []string
+	for _, n := range filterOutDrainedNodes(nodes) {
+		ids = append(ids, n.ID)
+	}
+	assert.Equal(t, []string{"eligible", "operator-ineligible", "operator-drained", "draining", "down"}, ids)
+}
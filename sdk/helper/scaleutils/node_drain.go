@@ -5,6 +5,7 @@ package scaleutils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"sync"
@@ -27,6 +28,10 @@ const (
 // DrainNodes iterates the provided nodeID list and performs a drain on each
 // one. Each node drain is monitored and events logged until the context is
 // closed or all drains reach a terminal state.
+//
+// If the operator has limited the drain parallelism, the nodes are drained in
+// batches, waiting for the configured delay between each. A failed batch
+// stops the remaining nodes from being drained.
 func (c *ClusterScaleUtils) DrainNodes(ctx context.Context, cfg map[string]string, nodes []NodeResourceID) error {
 
 	drainSpec, err := drainSpec(cfg)
@@ -34,6 +39,50 @@ func (c *ClusterScaleUtils) DrainNodes(ctx context.Context, cfg map[string]strin
 		return fmt.Errorf("failed to generate node drainspec: %v", err)
 	}
 
+	forceTimeout, err := drainForceTimeout(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to parse node drain force timeout: %v", err)
+	}
+
+	parallelism, batchDelay, err := drainPacing(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to parse node drain pacing: %v", err)
+	}
+	if parallelism <= 0 || parallelism > len(nodes) {
+		parallelism = len(nodes)
+	}
+
+	for start := 0; start < len(nodes); start += parallelism {
+
+		// Wait between batches, but not before the first one.
+		if start > 0 && batchDelay > 0 {
+			c.log.Info("waiting before draining next batch of nodes",
+				"delay", batchDelay, "drained", start, "remaining", len(nodes)-start)
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("node drain cancelled after %d of %d nodes: %w", start, len(nodes), ctx.Err())
+			case <-time.After(batchDelay):
+			}
+		}
+
+		end := start + parallelism
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+
+		if err := c.drainNodeBatch(ctx, nodes[start:end], drainSpec, forceTimeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// drainNodeBatch drains the nodes concurrently, waiting for all the drains to
+// reach a terminal state.
+func (c *ClusterScaleUtils) drainNodeBatch(ctx context.Context, nodes []NodeResourceID, drainSpec *api.DrainSpec, forceTimeout time.Duration) error {
+
 	// Define a WaitGroup. This allows us to trigger each node drain in a go
 	// routine and then wait for them all to complete before exiting.
 	var wg sync.WaitGroup
@@ -59,7 +108,7 @@ func (c *ClusterScaleUtils) DrainNodes(ctx context.Context, cfg map[string]strin
 			// Ensure we call done on the WaitGroup to decrement the count remaining.
 			defer wg.Done()
 
-			if err := c.drainNode(ctx, n.NomadNodeID, drainSpec); err != nil {
+			if err := c.drainNode(ctx, n.NomadNodeID, drainSpec, forceTimeout); err != nil {
 				resultLock.Lock()
 				result = multierror.Append(result, err)
 				resultLock.Unlock()
@@ -98,6 +147,22 @@ func drainSpec(cfg map[string]string) (*api.DrainSpec, error) {
 		}
 	}
 
+	// Attempt to read the operator defined no deadline mode from the config.
+	// Nomad treats a zero deadline as no deadline, which conflicts with an
+	// operator defined deadline.
+	if noDeadlineString, ok := cfg[sdk.TargetConfigKeyDrainNoDeadline]; ok {
+		noDeadline, err := strconv.ParseBool(noDeadlineString)
+		switch {
+		case err != nil:
+			mErr = multierror.Append(mErr, err)
+		case noDeadline && cfg[sdk.TargetConfigKeyDrainDeadline] != "":
+			mErr = multierror.Append(mErr, fmt.Errorf("%s and %s are mutually exclusive",
+				sdk.TargetConfigKeyDrainDeadline, sdk.TargetConfigKeyDrainNoDeadline))
+		case noDeadline:
+			deadline = 0
+		}
+	}
+
 	// Attempt to read the operator defined ignore system jobs from the config.
 	if ignoreSystemJobsString, ok := cfg[sdk.TargetConfigKeyIgnoreSystemJobs]; ok {
 		isj, err := strconv.ParseBool(ignoreSystemJobsString)
@@ -120,35 +185,125 @@ func drainSpec(cfg map[string]string) (*api.DrainSpec, error) {
 	}, nil
 }
 
+// drainPacing reads the optional operator defined drain parallelism and delay
+// between batches from the config. A zero parallelism is returned when it is
+// not configured, meaning all nodes are drained at once.
+func drainPacing(cfg map[string]string) (int, time.Duration, error) {
+
+	var (
+		parallelism int
+		batchDelay  time.Duration
+		mErr        *multierror.Error
+	)
+
+	if parallelismString, ok := cfg[sdk.TargetConfigKeyDrainParallelism]; ok {
+		p, err := strconv.Atoi(parallelismString)
+		switch {
+		case err != nil:
+			mErr = multierror.Append(mErr, err)
+		case p < 1:
+			mErr = multierror.Append(mErr, fmt.Errorf("%s must be positive", sdk.TargetConfigKeyDrainParallelism))
+		default:
+			parallelism = p
+		}
+	}
+
+	if delayString, ok := cfg[sdk.TargetConfigKeyDrainBatchDelay]; ok {
+		d, err := time.ParseDuration(delayString)
+		switch {
+		case err != nil:
+			mErr = multierror.Append(mErr, err)
+		case d < 0:
+			mErr = multierror.Append(mErr, fmt.Errorf("%s must not be negative", sdk.TargetConfigKeyDrainBatchDelay))
+		default:
+			batchDelay = d
+		}
+	}
+
+	if mErr != nil {
+		return 0, 0, errHelper.FormattedMultiError(mErr)
+	}
+	return parallelism, batchDelay, nil
+}
+
+// drainForceTimeout reads the optional operator defined drain force timeout
+// from the config. A zero duration is returned when it is not configured,
+// meaning drains are never forced by the Nomad Autoscaler.
+func drainForceTimeout(cfg map[string]string) (time.Duration, error) {
+	timeoutString, ok := cfg[sdk.TargetConfigKeyDrainForceTimeout]
+	if !ok {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(timeoutString)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("%s must be positive", sdk.TargetConfigKeyDrainForceTimeout)
+	}
+	return timeout, nil
+}
+
 // drainNode triggers a drain on the supplied ID using the DrainSpec. The
 // function handles monitoring the drain and reporting its terminal status to
-// the caller.
-func (c *ClusterScaleUtils) drainNode(ctx context.Context, nodeID string, spec *api.DrainSpec) error {
+// the caller. If forceTimeout is positive and the drain has not completed
+// within it, the drain is forced.
+func (c *ClusterScaleUtils) drainNode(ctx context.Context, nodeID string, spec *api.DrainSpec, forceTimeout time.Duration) error {
 
 	c.log.Info("triggering drain on node", "node_id", nodeID, "deadline", spec.Deadline)
 
-	opts := &api.DrainOptions{
-		DrainSpec:    spec,
-		MarkEligible: false,
-		Meta: map[string]string{
-			nodeDrainedMetaKey: nodeDrainedMetaValue,
-		},
-	}
-
 	// Update the drain on the node.
-	resp, err := c.drainer.UpdateDrainOpts(nodeID, opts, nil)
+	resp, err := c.drainer.UpdateDrainOpts(nodeID, drainOptions(spec), nil)
 	if err != nil {
 		return fmt.Errorf("failed to drain node: %w", err)
 	}
 
+	monitorCtx := ctx
+	if forceTimeout > 0 {
+		var cancel context.CancelFunc
+		monitorCtx, cancel = context.WithTimeout(ctx, forceTimeout)
+		defer cancel()
+	}
+
 	// Monitor the drain so we output the log messages. An error here indicates
 	// the drain failed to complete successfully.
-	if err := c.monitorNodeDrain(ctx, nodeID, resp.LastIndex, spec.IgnoreSystemJobs); err != nil {
+	err = c.monitorNodeDrain(monitorCtx, nodeID, resp.LastIndex, spec.IgnoreSystemJobs)
+
+	// If only the force timeout has been reached, update the drain with a
+	// negative deadline which Nomad uses to stop the remaining allocations
+	// immediately, and continue monitoring it.
+	if forceTimeout > 0 && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		c.log.Warn("node drain did not complete within force timeout, forcing drain",
+			"node_id", nodeID, "force_timeout", forceTimeout)
+
+		forceSpec := &api.DrainSpec{Deadline: -1 * time.Second, IgnoreSystemJobs: spec.IgnoreSystemJobs}
+
+		resp, err = c.drainer.UpdateDrainOpts(nodeID, drainOptions(forceSpec), nil)
+		if err != nil {
+			return fmt.Errorf("failed to force drain node: %w", err)
+		}
+		err = c.monitorNodeDrain(ctx, nodeID, resp.LastIndex, spec.IgnoreSystemJobs)
+	}
+
+	if err != nil {
 		return fmt.Errorf("context done while monitoring node drain: %w", err)
 	}
 	return nil
 }
 
+// drainOptions returns the options used to update the drain of a node using
+// the DrainSpec.
+func drainOptions(spec *api.DrainSpec) *api.DrainOptions {
+	return &api.DrainOptions{
+		DrainSpec:    spec,
+		MarkEligible: false,
+		Meta: map[string]string{
+			nodeDrainedMetaKey: nodeDrainedMetaValue,
+		},
+	}
+}
+
 // monitorNodeDrain follows the drain of a node, logging the messages we
 // receive to their appropriate level.
 func (c *ClusterScaleUtils) monitorNodeDrain(ctx context.Context, nodeID string, index uint64, ignoreSys bool) error {
@@ -6,6 +6,7 @@ package scaleutils
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -107,6 +108,42 @@ func TestNewClusterScaleUtils_drainSpec(t *testing.T) {
 			},
 			name: "multi config params parse error",
 		},
+		{
+			inputCfg: map[string]string{
+				"node_drain_no_deadline":        "true",
+				"node_drain_ignore_system_jobs": "true",
+			},
+			expectedOutputSpec: &api.DrainSpec{
+				Deadline:         0,
+				IgnoreSystemJobs: true,
+			},
+			expectedOutputError: nil,
+			name:                "no deadline set in config",
+		},
+		{
+			inputCfg: map[string]string{
+				"node_drain_deadline":    "10m",
+				"node_drain_no_deadline": "false",
+			},
+			expectedOutputSpec: &api.DrainSpec{
+				Deadline:         10 * time.Minute,
+				IgnoreSystemJobs: false,
+			},
+			expectedOutputError: nil,
+			name:                "no deadline disabled in config",
+		},
+		{
+			inputCfg: map[string]string{
+				"node_drain_deadline":    "10m",
+				"node_drain_no_deadline": "true",
+			},
+			expectedOutputSpec: nil,
+			expectedOutputError: &multierror.Error{
+				Errors:      []error{errors.New("node_drain_deadline and node_drain_no_deadline are mutually exclusive")},
+				ErrorFormat: errHelper.MultiErrorFunc,
+			},
+			name: "config deadline and no deadline conflict",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -153,8 +190,225 @@ func Test_DrainNode(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	err := cu.drainNode(ctx, testNodeID, &api.DrainSpec{})
+	err := cu.drainNode(ctx, testNodeID, &api.DrainSpec{}, 0)
 	must.NoError(t, err)
 	must.True(t, md.monitorFunctionCalled)
 
 }
+
+func Test_drainForceTimeout(t *testing.T) {
+	testCases := []struct {
+		inputCfg            map[string]string
+		expectedOutput      time.Duration
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputCfg:            map[string]string{},
+			expectedOutput:      0,
+			expectedOutputError: nil,
+			name:                "not configured",
+		},
+		{
+			inputCfg:            map[string]string{"node_drain_force_timeout": "1h"},
+			expectedOutput:      time.Hour,
+			expectedOutputError: nil,
+			name:                "configured",
+		},
+		{
+			inputCfg:            map[string]string{"node_drain_force_timeout": "0s"},
+			expectedOutput:      0,
+			expectedOutputError: errors.New("node_drain_force_timeout must be positive"),
+			name:                "zero timeout",
+		},
+		{
+			inputCfg:            map[string]string{"node_drain_force_timeout": "1hh"},
+			expectedOutput:      0,
+			expectedOutputError: errors.New(`time: unknown unit "hh" in duration "1hh"`),
+			name:                "parse error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualError := drainForceTimeout(tc.inputCfg)
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			if tc.expectedOutputError != nil {
+				assert.EqualError(t, actualError, tc.expectedOutputError.Error(), tc.name)
+			} else {
+				assert.NoError(t, actualError, tc.name)
+			}
+		})
+	}
+}
+
+func Test_DrainNode_forceTimeout(t *testing.T) {
+	testNodeID := "nodeID"
+	testLogger := hclog.New(&hclog.LoggerOptions{
+		Level: hclog.LevelFromString("ERROR"),
+	})
+
+	md := newMockDrainer()
+
+	var specs []*api.DrainSpec
+	md.drainerMockFunc = func(nodeID string, opts *api.DrainOptions, _ *api.WriteOptions) (*api.NodeDrainUpdateResponse, error) {
+		must.StrContains(t, testNodeID, nodeID)
+		must.StrContains(t, opts.Meta[nodeDrainedMetaKey], nodeDrainedMetaValue)
+
+		specs = append(specs, opts.DrainSpec)
+		return &api.NodeDrainUpdateResponse{}, nil
+	}
+
+	// The first drain never completes, so only the forced drain closes the
+	// monitor channel.
+	md.monitorMockFunc = func(ctx context.Context, nodeID string, index uint64, ignoreSys bool) <-chan *api.MonitorMessage {
+		outCh := make(chan *api.MonitorMessage, 1)
+		forced := len(specs) > 1
+		go func() {
+			if !forced {
+				<-ctx.Done()
+			}
+			close(outCh)
+		}()
+		return outCh
+	}
+
+	cu := &ClusterScaleUtils{
+		log:     testLogger,
+		drainer: md,
+	}
+
+	err := cu.drainNode(context.Background(), testNodeID, &api.DrainSpec{IgnoreSystemJobs: true}, 50*time.Millisecond)
+	must.NoError(t, err)
+	must.Eq(t, []*api.DrainSpec{
+		{Deadline: 0, IgnoreSystemJobs: true},
+		{Deadline: -1 * time.Second, IgnoreSystemJobs: true},
+	}, specs)
+}
+
+func Test_drainPacing(t *testing.T) {
+	testCases := []struct {
+		inputCfg            map[string]string
+		expectedParallelism int
+		expectedBatchDelay  time.Duration
+		expectedOutputError error
+		name                string
+	}{
+		{
+			inputCfg:            map[string]string{},
+			expectedParallelism: 0,
+			expectedBatchDelay:  0,
+			expectedOutputError: nil,
+			name:                "not configured",
+		},
+		{
+			inputCfg:            map[string]string{"node_drain_parallelism": "5", "node_drain_batch_delay": "2m"},
+			expectedParallelism: 5,
+			expectedBatchDelay:  2 * time.Minute,
+			expectedOutputError: nil,
+			name:                "configured",
+		},
+		{
+			inputCfg:            map[string]string{"node_drain_parallelism": "0", "node_drain_batch_delay": "-1s"},
+			expectedParallelism: 0,
+			expectedBatchDelay:  0,
+			expectedOutputError: errHelper.FormattedMultiError(&multierror.Error{
+				Errors: []error{
+					errors.New("node_drain_parallelism must be positive"),
+					errors.New("node_drain_batch_delay must not be negative"),
+				},
+			}),
+			name: "invalid values",
+		},
+		{
+			inputCfg:            map[string]string{"node_drain_parallelism": "five"},
+			expectedParallelism: 0,
+			expectedBatchDelay:  0,
+			expectedOutputError: errHelper.FormattedMultiError(&multierror.Error{
+				Errors: []error{errors.New(`strconv.Atoi: parsing "five": invalid syntax`)},
+			}),
+			name: "parse error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualParallelism, actualBatchDelay, actualError := drainPacing(tc.inputCfg)
+			assert.Equal(t, tc.expectedParallelism, actualParallelism, tc.name)
+			assert.Equal(t, tc.expectedBatchDelay, actualBatchDelay, tc.name)
+			if tc.expectedOutputError != nil {
+				assert.EqualError(t, actualError, tc.expectedOutputError.Error(), tc.name)
+			} else {
+				assert.NoError(t, actualError, tc.name)
+			}
+		})
+	}
+}
+
+func Test_DrainNodes_parallelism(t *testing.T) {
+	testLogger := hclog.New(&hclog.LoggerOptions{
+		Level: hclog.LevelFromString("ERROR"),
+	})
+
+	md := &concurrencyDrainer{}
+	cu := &ClusterScaleUtils{
+		log:     testLogger,
+		drainer: md,
+	}
+
+	nodes := []NodeResourceID{
+		{NomadNodeID: "a"}, {NomadNodeID: "b"}, {NomadNodeID: "c"}, {NomadNodeID: "d"}, {NomadNodeID: "e"},
+	}
+	cfg := map[string]string{"node_drain_parallelism": "2", "node_drain_batch_delay": "10ms"}
+
+	err := cu.DrainNodes(context.Background(), cfg, nodes)
+	must.NoError(t, err)
+	must.Eq(t, 5, md.drained)
+	must.Eq(t, 2, md.maxConcurrent)
+
+	// A failed batch stops the remaining nodes from being drained.
+	md = &concurrencyDrainer{fail: "b"}
+	cu.drainer = md
+
+	err = cu.DrainNodes(context.Background(), cfg, nodes)
+	must.Error(t, err)
+	must.Eq(t, 2, md.drained)
+}
+
+// concurrencyDrainer is a nodeDrainer tracking the number of drains in
+// progress concurrently.
+type concurrencyDrainer struct {
+	lock          sync.Mutex
+	fail          string
+	current       int
+	maxConcurrent int
+	drained       int
+}
+
+func (d *concurrencyDrainer) UpdateDrainOpts(nodeID string, _ *api.DrainOptions, _ *api.WriteOptions) (*api.NodeDrainUpdateResponse, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.drained++
+	if nodeID == d.fail {
+		return nil, errors.New("drain failed")
+	}
+
+	d.current++
+	if d.current > d.maxConcurrent {
+		d.maxConcurrent = d.current
+	}
+	return &api.NodeDrainUpdateResponse{}, nil
+}
+
+func (d *concurrencyDrainer) MonitorDrain(_ context.Context, _ string, _ uint64, _ bool) <-chan *api.MonitorMessage {
+	outCh := make(chan *api.MonitorMessage)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		d.lock.Lock()
+		d.current--
+		d.lock.Unlock()
+		close(outCh)
+	}()
+	return outCh
+}
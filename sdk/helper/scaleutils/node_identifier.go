@@ -5,8 +5,11 @@ package scaleutils
 
 import (
 	"fmt"
+	"strconv"
 
+	hclog "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad-autoscaler/sdk"
 	errHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/error"
 	"github.com/hashicorp/nomad/api"
 )
@@ -125,3 +128,45 @@ func filterOutNodeID(n []*api.NodeListStub, id string) []*api.NodeListStub {
 	}
 	return n
 }
+
+// filterOutProtectedNodes removes the nodes which are protected from scale in
+// using the sdk.NodeMetaKeyScaleInProtected meta key. The node list stubs do
+// not include the node meta, so the full node is read using the infoFn.
+func filterOutProtectedNodes(n []*api.NodeListStub, infoFn func(string) (*api.Node, error), log hclog.Logger) ([]*api.NodeListStub, error) {
+
+	var out []*api.NodeListStub
+
+	for _, node := range n {
+
+		// If we fail to read a node, it's likely we won't be able to read any
+		// others, therefore just exit rather than collect all the errors.
+		info, err := infoFn(node.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read node %s: %v", node.ID, err)
+		}
+
+		val, ok := info.Meta[sdk.NodeMetaKeyScaleInProtected]
+		if !ok {
+			out = append(out, node)
+			continue
+		}
+
+		// An operator who has set the meta key most likely intended to
+		// protect the node, so a malformed value does not remove the
+		// protection.
+		protected, err := strconv.ParseBool(val)
+		if err != nil {
+			log.Warn("failed to parse node scale in protection meta, treating node as protected",
+				"node_id", node.ID, "key", sdk.NodeMetaKeyScaleInProtected, "value", val, "error", err)
+			protected = true
+		}
+
+		if protected {
+			log.Debug("node is protected from scale in", "node_id", node.ID)
+			continue
+		}
+		out = append(out, node)
+	}
+
+	return out, nil
+}
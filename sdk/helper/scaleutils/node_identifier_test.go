@@ -7,6 +7,7 @@ import (
 	"errors"
 	"testing"
 
+	hclog "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
 	errHelper "github.com/hashicorp/nomad-autoscaler/sdk/helper/error"
 	"github.com/hashicorp/nomad-autoscaler/sdk/helper/scaleutils/nodepool"
@@ -426,3 +427,71 @@ func Test_filterOutNodeID(t *testing.T) {
 		})
 	}
 }
+
+func Test_filterOutProtectedNodes(t *testing.T) {
+
+	nodesMeta := map[string]map[string]string{
+		"foo1": nil,
+		"foo2": {"autoscaler.protected": "true"},
+		"foo3": {"autoscaler.protected": "false"},
+		"foo4": {"autoscaler.protected": "yes please"},
+		"foo5": {"other": "true"},
+	}
+
+	infoFn := func(id string) (*api.Node, error) {
+		meta, ok := nodesMeta[id]
+		if !ok {
+			return nil, errors.New("node not found")
+		}
+		return &api.Node{ID: id, Meta: meta}, nil
+	}
+
+	testCases := []struct {
+		inputNodeList  []*api.NodeListStub
+		expectedOutput []*api.NodeListStub
+		expectedError  error
+		name           string
+	}{
+		{
+			inputNodeList: []*api.NodeListStub{
+				{ID: "foo1"},
+				{ID: "foo2"},
+				{ID: "foo3"},
+				{ID: "foo4"},
+				{ID: "foo5"},
+			},
+			expectedOutput: []*api.NodeListStub{
+				{ID: "foo1"},
+				{ID: "foo3"},
+				{ID: "foo5"},
+			},
+			expectedError: nil,
+			name:          "protected and malformed nodes filtered",
+		},
+		{
+			inputNodeList: []*api.NodeListStub{
+				{ID: "foo2"},
+			},
+			expectedOutput: nil,
+			expectedError:  nil,
+			name:           "all nodes protected",
+		},
+		{
+			inputNodeList: []*api.NodeListStub{
+				{ID: "foo1"},
+				{ID: "bar1"},
+			},
+			expectedOutput: nil,
+			expectedError:  errors.New("failed to read node bar1: node not found"),
+			name:           "node info error",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualOutput, actualError := filterOutProtectedNodes(tc.inputNodeList, infoFn, hclog.NewNullLogger())
+			assert.Equal(t, tc.expectedOutput, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedError, actualError, tc.name)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package nodepool
+
+import (
+	"github.com/hashicorp/nomad-autoscaler/sdk"
+	"github.com/hashicorp/nomad/api"
+)
+
+// defaultNodePool is the Nomad node pool of nodes which do not configure one.
+// Nodes registered with Nomad versions prior to 1.6 do not report a node pool
+// and are considered part of it.
+const defaultNodePool = "default"
+
+// nodePoolClusterPoolIdentifier is the Nomad node pool implementation of the
+// ClusterNodePoolIdentifier interface and filters Nomad nodes by their
+// Node.NodePool parameter.
+type nodePoolClusterPoolIdentifier struct {
+	id string
+}
+
+// NewNodePoolIdentifier returns a new nodePoolClusterPoolIdentifier
+// implementation of the ClusterNodePoolIdentifier interface.
+func NewNodePoolIdentifier(id string) ClusterNodePoolIdentifier {
+	return &nodePoolClusterPoolIdentifier{
+		id: id,
+	}
+}
+
+// IsPoolMember satisfies the IsPoolMember function on the
+// ClusterNodePoolIdentifier interface.
+func (n nodePoolClusterPoolIdentifier) IsPoolMember(node *api.NodeListStub) bool {
+	return node.NodePool != "" && node.NodePool == n.id ||
+		node.NodePool == "" && n.id == defaultNodePool
+}
+
+// Key satisfies the Key function on the ClusterNodePoolIdentifier interface.
+func (n nodePoolClusterPoolIdentifier) Key() string { return sdk.TargetConfigKeyNodePool }
+
+// Value satisfies the Value function on the ClusterNodePoolIdentifier
+// interface.
+func (n nodePoolClusterPoolIdentifier) Value() string { return n.id }
@@ -0,0 +1,57 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package nodepool
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNodePoolIdentifier(t *testing.T) {
+	poolID := NewNodePoolIdentifier("gpu")
+	assert.Equal(t, "node_pool", poolID.Key())
+	assert.Equal(t, "gpu", poolID.Value())
+}
+
+func TestNodePoolClusterPoolIdentifier_NodeIsPoolMember(t *testing.T) {
+	testCases := []struct {
+		inputPI        ClusterNodePoolIdentifier
+		inputNode      *api.NodeListStub
+		expectedOutput bool
+		name           string
+	}{
+		{
+			inputPI:        NewNodePoolIdentifier("gpu"),
+			inputNode:      &api.NodeListStub{NodePool: ""},
+			expectedOutput: false,
+			name:           "non-matched empty node pool",
+		},
+		{
+			inputPI:        NewNodePoolIdentifier("gpu"),
+			inputNode:      &api.NodeListStub{NodePool: "default"},
+			expectedOutput: false,
+			name:           "non-matched non-empty node pool",
+		},
+		{
+			inputPI:        NewNodePoolIdentifier("default"),
+			inputNode:      &api.NodeListStub{NodePool: ""},
+			expectedOutput: true,
+			name:           "matched default node pool on pre-1.6 node",
+		},
+		{
+			inputPI:        NewNodePoolIdentifier("gpu"),
+			inputNode:      &api.NodeListStub{NodePool: "gpu"},
+			expectedOutput: true,
+			name:           "matched node pool",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedOutput, tc.inputPI.IsPoolMember(tc.inputNode), tc.name)
+		})
+	}
+}
@@ -30,24 +30,27 @@ type ClusterNodePoolIdentifier interface {
 // NewClusterNodePoolIdentifier generates a new ClusterNodePoolIdentifier based
 // on the provided configuration. If a valid option is not found, an error will
 // be returned.
+//
+// When multiple options are configured, nodes must match all of them.
 func NewClusterNodePoolIdentifier(cfg map[string]string) (ClusterNodePoolIdentifier, error) {
-	class, hasClass := cfg[sdk.TargetConfigKeyClass]
-	dc, hasDC := cfg[sdk.TargetConfigKeyDatacenter]
-
-	switch {
-	case hasClass && hasDC:
-		return NewCombinedClusterPoolIdentifier(
-			[]ClusterNodePoolIdentifier{
-				NewNodeClassPoolIdentifier(class),
-				NewNodeDatacenterPoolIdentifier(dc),
-			},
-			CombinedClusterPoolIdentifierAnd,
-		), nil
-	case hasClass:
-		return NewNodeClassPoolIdentifier(class), nil
-	case hasDC:
-		return NewNodeDatacenterPoolIdentifier(dc), nil
+	var ids []ClusterNodePoolIdentifier
+
+	if class, ok := cfg[sdk.TargetConfigKeyClass]; ok {
+		ids = append(ids, NewNodeClassPoolIdentifier(class))
+	}
+	if dc, ok := cfg[sdk.TargetConfigKeyDatacenter]; ok {
+		ids = append(ids, NewNodeDatacenterPoolIdentifier(dc))
+	}
+	if pool, ok := cfg[sdk.TargetConfigKeyNodePool]; ok {
+		ids = append(ids, NewNodePoolIdentifier(pool))
 	}
 
-	return nil, fmt.Errorf("node pool identification method required")
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("node pool identification method required")
+	case 1:
+		return ids[0], nil
+	default:
+		return NewCombinedClusterPoolIdentifier(ids, CombinedClusterPoolIdentifierAnd), nil
+	}
 }
@@ -53,6 +53,20 @@ func Test_NewClusterNodePoolIdentifier(t *testing.T) {
 			expectedOutputErr:   nil,
 			name:                "node_class and datacenter are configured in config",
 		},
+		{
+			inputCfg:            map[string]string{"node_pool": "gpu"},
+			expectedOutputKey:   "node_pool",
+			expectedOutputValue: "gpu",
+			expectedOutputErr:   nil,
+			name:                "node_pool configured in config",
+		},
+		{
+			inputCfg:            map[string]string{"node_class": "high-memory", "datacenter": "dc1", "node_pool": "gpu"},
+			expectedOutputKey:   "combined_identifier",
+			expectedOutputValue: "node_class:high-memory and datacenter:dc1 and node_pool:gpu",
+			expectedOutputErr:   nil,
+			name:                "node_class, datacenter and node_pool are configured in config",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -4,7 +4,9 @@
 package nodeselector
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/nomad-autoscaler/sdk"
@@ -26,6 +28,60 @@ type ClusterScaleInNodeSelector interface {
 	Select([]*api.NodeListStub, int) []*api.NodeListStub
 }
 
+// Factory is the function signature used to create a node selector. It is
+// passed the target config, so selectors can read their own config params,
+// along with the Nomad client and logger available to the cluster scaling
+// utilities.
+type Factory func(cfg map[string]string, client *api.Client, log hclog.Logger) (ClusterScaleInNodeSelector, error)
+
+var (
+	// factoriesLock guards factories, as target plugins may register custom
+	// selectors while node selection is being performed.
+	factoriesLock sync.RWMutex
+
+	// factories maps the name of each node selector strategy to the function
+	// used to create it. It is pre-populated with the built-in strategies.
+	factories = map[string]Factory{
+		sdk.TargetNodeSelectorStrategyLeastBusy: func(_ map[string]string, client *api.Client, log hclog.Logger) (ClusterScaleInNodeSelector, error) {
+			return newLeastBusyClusterScaleInNodeSelector(client, log), nil
+		},
+		sdk.TargetNodeSelectorStrategyNewestCreateIndex: func(_ map[string]string, _ *api.Client, _ hclog.Logger) (ClusterScaleInNodeSelector, error) {
+			return newNewestCreateIndexClusterScaleInNodeSelector(), nil
+		},
+		sdk.TargetNodeSelectorStrategyEmpty: func(_ map[string]string, client *api.Client, log hclog.Logger) (ClusterScaleInNodeSelector, error) {
+			return newEmptyClusterScaleInNodeSelector(client, log, false), nil
+		},
+		sdk.TargetNodeSelectorStrategyEmptyIgnoreSystemJobs: func(_ map[string]string, client *api.Client, log hclog.Logger) (ClusterScaleInNodeSelector, error) {
+			return newEmptyClusterScaleInNodeSelector(client, log, true), nil
+		},
+	}
+)
+
+// Register makes a custom node selector strategy available under the name,
+// which operators can then configure using the node_selector_strategy target
+// config param. It is intended to be called by target plugins before any
+// scaling is performed, typically from an init function. An error is returned
+// if the name is empty or already registered, including the names of the
+// built-in strategies, so a plugin cannot change the behaviour operators
+// expect of them.
+func Register(name string, factory Factory) error {
+	if name == "" {
+		return errors.New("node selector strategy name must not be empty")
+	}
+	if factory == nil {
+		return fmt.Errorf("node selector strategy %s factory must not be nil", name)
+	}
+
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	if _, ok := factories[name]; ok {
+		return fmt.Errorf("node selector strategy %s already registered", name)
+	}
+	factories[name] = factory
+	return nil
+}
+
 // NewSelector takes the user configuration and creates a
 // ClusterScaleInNodeSelector for use. In the event the configuration cannot be
 // understood, an error will be returned.
@@ -37,19 +93,20 @@ func NewSelector(cfg map[string]string, client *api.Client, log hclog.Logger) (C
 		val = sdk.TargetNodeSelectorStrategyLeastBusy
 	}
 
+	factoriesLock.RLock()
+	factory, ok := factories[val]
+	factoriesLock.RUnlock()
+
 	// If the user configured a value but we are unable to understand this, an
 	// error is returned rather than defaulting so we do not terminate nodes
 	// that the user wasn't expecting.
-	switch val {
-	case sdk.TargetNodeSelectorStrategyLeastBusy:
-		return newLeastBusyClusterScaleInNodeSelector(client, log), nil
-	case sdk.TargetNodeSelectorStrategyNewestCreateIndex:
-		return newNewestCreateIndexClusterScaleInNodeSelector(), nil
-	case sdk.TargetNodeSelectorStrategyEmpty:
-		return newEmptyClusterScaleInNodeSelector(client, log, false), nil
-	case sdk.TargetNodeSelectorStrategyEmptyIgnoreSystemJobs:
-		return newEmptyClusterScaleInNodeSelector(client, log, true), nil
-	default:
+	if !ok {
 		return nil, fmt.Errorf("unsupported node selector strategy: %v", val)
 	}
+
+	selector, err := factory(cfg, client, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node selector strategy %s: %v", val, err)
+	}
+	return selector, nil
 }
@@ -7,7 +7,10 @@ import (
 	"errors"
 	"testing"
 
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewSelector(t *testing.T) {
@@ -69,3 +72,62 @@ func TestNewSelector(t *testing.T) {
 		})
 	}
 }
+
+// testSelector is a custom ClusterScaleInNodeSelector which selects the nodes
+// configured by ID.
+type testSelector struct {
+	id string
+}
+
+func (s *testSelector) Name() string { return "test_custom" }
+
+func (s *testSelector) Select(nodes []*api.NodeListStub, num int) []*api.NodeListStub {
+	for _, node := range nodes {
+		if node.ID == s.id && num > 0 {
+			return []*api.NodeListStub{node}
+		}
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(cfg map[string]string, _ *api.Client, _ hclog.Logger) (ClusterScaleInNodeSelector, error) {
+		id, ok := cfg["test_node_id"]
+		if !ok {
+			return nil, errors.New("required config param test_node_id not found")
+		}
+		return &testSelector{id: id}, nil
+	}
+
+	require.NoError(t, Register("test_custom", factory))
+	t.Cleanup(func() {
+		factoriesLock.Lock()
+		delete(factories, "test_custom")
+		factoriesLock.Unlock()
+	})
+
+	// Registering the same name twice, or the name of a built-in strategy,
+	// should fail.
+	assert.Equal(t, errors.New("node selector strategy test_custom already registered"),
+		Register("test_custom", factory))
+	assert.Equal(t, errors.New("node selector strategy least_busy already registered"),
+		Register("least_busy", factory))
+	assert.Equal(t, errors.New("node selector strategy name must not be empty"),
+		Register("", factory))
+	assert.Equal(t, errors.New("node selector strategy test_nil factory must not be nil"),
+		Register("test_nil", nil))
+
+	selector, err := NewSelector(map[string]string{
+		"node_selector_strategy": "test_custom",
+		"test_node_id":           "node2",
+	}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "test_custom", selector.Name())
+
+	nodes := []*api.NodeListStub{{ID: "node1"}, {ID: "node2"}, {ID: "node3"}}
+	assert.Equal(t, []*api.NodeListStub{{ID: "node2"}}, selector.Select(nodes, 1))
+
+	// Errors from the factory should be returned to the caller.
+	_, err = NewSelector(map[string]string{"node_selector_strategy": "test_custom"}, nil, nil)
+	assert.Equal(t, errors.New("failed to create node selector strategy test_custom: required config param test_node_id not found"), err)
+}
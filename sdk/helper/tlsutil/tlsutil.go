@@ -0,0 +1,80 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	// ConfigKeyCACert is the path to the CA certificate the client should
+	// use, and ConfigKeySkipVerify disables TLS certificate verification.
+	ConfigKeyCACert     = "ca_cert"
+	ConfigKeySkipVerify = "skip_verify"
+
+	// ConfigKeyTLSEnabled explicitly enables or disables TLS for plugins
+	// whose clients do not use TLS by default.
+	ConfigKeyTLSEnabled = "tls_enabled"
+)
+
+// NewClientConfig builds a client TLS config from the plugin config. A nil
+// config is returned if TLS has not been configured, so the client defaults
+// are used.
+func NewClientConfig(config map[string]string) (*tls.Config, error) {
+	if config[ConfigKeyCACert] == "" && config[ConfigKeySkipVerify] == "" {
+		return nil, nil
+	}
+	return newClientConfig(config)
+}
+
+// NewOptionalClientConfig builds a client TLS config for clients that do not
+// use TLS by default. TLS is enabled when ConfigKeyTLSEnabled is true, or when
+// it is unset and any other TLS option has been configured. A nil config is
+// returned if TLS has not been enabled.
+func NewOptionalClientConfig(config map[string]string) (*tls.Config, error) {
+	v := config[ConfigKeyTLSEnabled]
+	if v == "" {
+		return NewClientConfig(config)
+	}
+
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %v", ConfigKeyTLSEnabled, err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+	return newClientConfig(config)
+}
+
+func newClientConfig(config map[string]string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if v := config[ConfigKeySkipVerify]; v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", ConfigKeySkipVerify, err)
+		}
+		tlsConfig.InsecureSkipVerify = skip
+	}
+
+	if caCert := config[ConfigKeyCACert]; caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA cert: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("failed to parse CA cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
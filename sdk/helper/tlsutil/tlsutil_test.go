@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package tlsutil
+
+import (
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caCert := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: srv.Certificate().Raw,
+	}), 0o600))
+	invalidCert := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidCert, []byte("not a cert"), 0o600))
+
+	testCases := []struct {
+		inputConfig    map[string]string
+		inputOptional  bool
+		expectedNil    bool
+		expectedSkip   bool
+		expectedRootCA bool
+		expectedError  string
+		name           string
+	}{
+		{
+			inputConfig: map[string]string{},
+			expectedNil: true,
+			name:        "not configured",
+		},
+		{
+			inputConfig:  map[string]string{"skip_verify": "true"},
+			expectedSkip: true,
+			name:         "skip verify",
+		},
+		{
+			inputConfig:    map[string]string{"ca_cert": caCert},
+			expectedRootCA: true,
+			name:           "ca cert",
+		},
+		{
+			inputConfig:   map[string]string{"skip_verify": "maybe"},
+			expectedError: `failed to parse "skip_verify"`,
+			name:          "invalid skip verify",
+		},
+		{
+			inputConfig:   map[string]string{"ca_cert": filepath.Join(dir, "missing.pem")},
+			expectedError: "failed to read CA cert",
+			name:          "missing ca cert",
+		},
+		{
+			inputConfig:   map[string]string{"ca_cert": invalidCert},
+			expectedError: "failed to parse CA cert",
+			name:          "invalid ca cert",
+		},
+		{
+			inputConfig: map[string]string{"tls_enabled": "true"},
+			expectedNil: true,
+			name:        "tls enabled ignored",
+		},
+		{
+			inputConfig:   map[string]string{"tls_enabled": "true"},
+			inputOptional: true,
+			name:          "optional enabled",
+		},
+		{
+			inputConfig:   map[string]string{"tls_enabled": "false", "skip_verify": "true"},
+			inputOptional: true,
+			expectedNil:   true,
+			name:          "optional disabled",
+		},
+		{
+			inputConfig:    map[string]string{"ca_cert": caCert},
+			inputOptional:  true,
+			expectedRootCA: true,
+			name:           "optional enabled by ca cert",
+		},
+		{
+			inputConfig:   map[string]string{},
+			inputOptional: true,
+			expectedNil:   true,
+			name:          "optional not configured",
+		},
+		{
+			inputConfig:   map[string]string{"tls_enabled": "maybe"},
+			inputOptional: true,
+			expectedError: `failed to parse "tls_enabled"`,
+			name:          "optional invalid tls enabled",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			newConfig := NewClientConfig
+			if tc.inputOptional {
+				newConfig = NewOptionalClientConfig
+			}
+
+			actualOutput, err := newConfig(tc.inputConfig)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError, tc.name)
+				return
+			}
+			require.NoError(t, err, tc.name)
+
+			if tc.expectedNil {
+				assert.Nil(t, actualOutput, tc.name)
+				return
+			}
+			require.NotNil(t, actualOutput, tc.name)
+			assert.Equal(t, tc.expectedSkip, actualOutput.InsecureSkipVerify, tc.name)
+			assert.Equal(t, tc.expectedRootCA, actualOutput.RootCAs != nil, tc.name)
+		})
+	}
+}
@@ -4,7 +4,9 @@
 package sdk
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,6 +20,13 @@ const (
 
 	ScalingPolicyOnErrorFail   = "fail"
 	ScalingPolicyOnErrorIgnore = "ignore"
+
+	// The values of missing_data define how a check handles queries which
+	// return no datapoints.
+	ScalingPolicyMissingDataHold  = "hold"
+	ScalingPolicyMissingDataZero  = "zero"
+	ScalingPolicyMissingDataLast  = "last"
+	ScalingPolicyMissingDataError = "error"
 )
 
 // ScalingPolicy is the internal representation of a scaling document and
@@ -32,6 +41,22 @@ type ScalingPolicy struct {
 	// Type is the type of scaling this policy will perform.
 	Type string
 
+	// Name is a human readable identifier for the policy which other policies
+	// can reference within DependsOn. File policies use the label of their
+	// scaling block, while Nomad job policies use "<namespace>/<job>/<group>".
+	Name string
+
+	// DependsOn lists the names of the policies that should be evaluated
+	// before this one. While an evaluation of any of these policies is in
+	// progress, evaluations of this policy are held back by the evaluation
+	// broker, up to the configured dependency window.
+	DependsOn []string
+
+	// Labels are arbitrary key/value pairs attached to the policy. Agents
+	// configured with a policy_filter only evaluate policies whose labels
+	// match it, allowing a set of policies to be split across deployments.
+	Labels map[string]string
+
 	// Priority controls the order in which a policy is picked for evaluation.
 	Priority int
 
@@ -45,6 +70,14 @@ type ScalingPolicy struct {
 	// this value is not violated.
 	Max int64
 
+	// MinUnset and MaxUnset indicate the policy did not set Min or Max, so
+	// the agent policy defaults should be applied. They must be set by every
+	// policy source decoder, and allow an explicit zero value to be
+	// distinguished from an omitted one. They are cleared once the defaults
+	// have been applied.
+	MinUnset bool
+	MaxUnset bool
+
 	// Enabled indicates whether the autoscaler should actively evaluate the
 	// policy or not.
 	Enabled bool
@@ -57,10 +90,25 @@ type ScalingPolicy struct {
 	// be taken.
 	OnCheckError string
 
+	// DryRun indicates that the policy should be evaluated as normal, but the
+	// resulting action should not be submitted to the target. This allows
+	// operators to observe the behaviour of a policy before enabling it.
+	DryRun bool
+
+	// EnabledSchedule optionally restricts the times at which an enabled
+	// policy is evaluated.
+	EnabledSchedule *ScalingPolicySchedule
+
 	// Cooldown is the time period after a scaling action if performed, during
 	// which no policy evaluations will be started.
 	Cooldown time.Duration
 
+	// CooldownGroup optionally names a group of policies which share a
+	// cooldown. When any policy within the group scales its target, all
+	// policies in the group are placed into cooldown. This is useful when
+	// multiple policies scale the same underlying capacity.
+	CooldownGroup string
+
 	// EvaluationInterval indicates the frequency at which the policy is
 	// evaluated. A lower value means more frequent evaluation and can result
 	// in a high rate of change in the target.
@@ -91,6 +139,20 @@ func (p *ScalingPolicy) Validate() error {
 		result = multierror.Append(result, err)
 	}
 
+	if err := p.EnabledSchedule.Validate(); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	if p.EvaluationInterval < 0 {
+		result = multierror.Append(result, errors.New("evaluation_interval must not be negative"))
+	}
+
+	if p.Target != nil {
+		for _, err := range p.validateSubTargets() {
+			result = multierror.Append(result, err)
+		}
+	}
+
 	for _, c := range p.Checks {
 		if p.Type == ScalingPolicyTypeCluster || p.Type == ScalingPolicyTypeHorizontal {
 			if strings.HasPrefix(c.Strategy.Name, "app-sizing") {
@@ -106,11 +168,90 @@ func (p *ScalingPolicy) Validate() error {
 				c.Name, ScalingPolicyOnErrorFail, ScalingPolicyOnErrorIgnore)
 			result = multierror.Append(result, err)
 		}
+
+		for _, err := range c.validateQueries() {
+			result = multierror.Append(result, fmt.Errorf("invalid queries in check %s: %v", c.Name, err))
+		}
+
+		for _, err := range c.validateQueryTemplates() {
+			result = multierror.Append(result, fmt.Errorf("invalid query template in check %s: %v", c.Name, err))
+		}
+
+		for _, err := range c.validateQueryRetries() {
+			result = multierror.Append(result, fmt.Errorf("invalid query settings in check %s: %v", c.Name, err))
+		}
+
+		for _, err := range c.validateFallbacks() {
+			result = multierror.Append(result, fmt.Errorf("invalid fallback in check %s: %v", c.Name, err))
+		}
+
+		if err := ValidateUnit(c.Unit); err != nil {
+			result = multierror.Append(result, fmt.Errorf("invalid unit in check %s: %v", c.Name, err))
+		}
+
+		for _, err := range c.validateMissingData() {
+			result = multierror.Append(result, fmt.Errorf("invalid missing data settings in check %s: %v", c.Name, err))
+		}
 	}
 
 	return errHelper.FormattedMultiError(result)
 }
 
+// validateSubTargets ensures the sub targets of the policy are consistent
+// with each other and with the policy limits.
+func (p *ScalingPolicy) validateSubTargets() []error {
+	if len(p.Target.SubTargets) == 0 {
+		return nil
+	}
+
+	if p.Type != ScalingPolicyTypeCluster {
+		return []error{fmt.Errorf("sub targets can only be used with %s policies", ScalingPolicyTypeCluster)}
+	}
+
+	var (
+		errs    []error
+		sumMin  int64
+		sumMax  int64
+		bounded = true
+		seen    = make(map[string]bool, len(p.Target.SubTargets))
+	)
+
+	for _, s := range p.Target.SubTargets {
+		if s.Name == "" {
+			errs = append(errs, errors.New("sub targets must have a name"))
+		} else if seen[s.Name] {
+			errs = append(errs, fmt.Errorf("duplicate sub target name %q", s.Name))
+		}
+		seen[s.Name] = true
+
+		if s.Min < 0 {
+			errs = append(errs, fmt.Errorf("min of sub target %s must not be negative", s.Name))
+		}
+		if s.Max < 0 || (s.Max > 0 && s.Max < s.Min) {
+			errs = append(errs, fmt.Errorf("max of sub target %s must not be lower than its min", s.Name))
+		}
+		if s.Weight < 0 {
+			errs = append(errs, fmt.Errorf("weight of sub target %s must not be negative", s.Name))
+		}
+
+		sumMin += s.Min
+		sumMax += s.Max
+		if s.Max == 0 {
+			bounded = false
+		}
+	}
+
+	// Any count within the policy limits must be achievable by distributing
+	// it across the sub targets.
+	if sumMin > p.Min {
+		errs = append(errs, fmt.Errorf("sum of sub target min values (%d) must not be greater than policy min (%d)", sumMin, p.Min))
+	}
+	if bounded && sumMax < p.Max {
+		errs = append(errs, fmt.Errorf("sum of sub target max values (%d) must not be lower than policy max (%d)", sumMax, p.Max))
+	}
+	return errs
+}
+
 // ScalingPolicyCheck is an individual check within a scaling policy.This check
 // will be executed in isolation alongside other checks within the policy.
 type ScalingPolicyCheck struct {
@@ -130,10 +271,60 @@ type ScalingPolicyCheck struct {
 	// Query is run against the Source in order to receive a metric response.
 	Query string
 
+	// Queries is a map of named queries which are run against the Source and
+	// combined using QueryExpression. It allows the metric to be computed by
+	// the autoscaler when it can't be expressed in the APM query language.
+	// Queries can't be used together with Query.
+	Queries map[string]string
+
+	// QueryExpression is the arithmetic expression used to combine the
+	// results of Queries into a single metric, such as
+	// "requests / healthy_instances".
+	QueryExpression string
+
 	// QueryWindow is used to define how further back in time to query for
 	// metrics.
 	QueryWindow time.Duration
 
+	// QueryTimeout is the maximum duration of each query attempt. A value of
+	// zero means queries do not time out.
+	QueryTimeout time.Duration
+
+	// QueryRetries is the number of times a failed query is retried before
+	// the check fails.
+	QueryRetries int
+
+	// QueryRetryBackoff is the time to wait before the first retry of a
+	// failed query. The wait doubles for each subsequent retry, up to
+	// QueryRetryMaxBackoff.
+	QueryRetryBackoff    time.Duration
+	QueryRetryMaxBackoff time.Duration
+
+	// Unit is the optional conversion applied to the values returned by the
+	// query, such as "bytes_to_mib", before they are passed to the strategy.
+	Unit string
+
+	// MissingData defines what to do when the query returns no datapoints.
+	// Possible values are "hold", "zero", "last" or "error", and if not set
+	// "hold" is used.
+	//
+	// If "hold" the current count is kept and the strategy is not run.
+	// If "zero" the strategy is run with a single datapoint of value zero.
+	// If "last" the last non-empty result of the check is used, as long as
+	// it is not older than MissingDataMaxStaleness, otherwise the check
+	// fails.
+	// If "error" the check fails, which is then handled according to OnError.
+	MissingData string
+
+	// MissingDataMaxStaleness is the maximum age of the last known result
+	// used when MissingData is "last".
+	MissingDataMaxStaleness time.Duration
+
+	// Fallbacks is an ordered list of alternative sources which are queried
+	// when the previous source fails or returns no data, such as a secondary
+	// APM holding the same signal.
+	Fallbacks []*ScalingPolicyCheckFallback
+
 	// Strategy is the ScalingPolicyStrategy to use when performing the
 	// ScalingPolicyCheck evaluation.
 	Strategy *ScalingPolicyStrategy
@@ -149,6 +340,163 @@ type ScalingPolicyCheck struct {
 	OnError string
 }
 
+// ScalingPolicyCheckFallback is an alternative source and query used when the
+// primary source of a check is unavailable.
+type ScalingPolicyCheckFallback struct {
+
+	// Source is the APM plugin used to perform the query.
+	Source string
+
+	// Query is run against the Source in order to receive a metric response.
+	Query string
+}
+
+// HasQuery returns true if the check defines either a single query or a set
+// of named queries.
+func (c *ScalingPolicyCheck) HasQuery() bool {
+	return c.Query != "" || len(c.Queries) > 0
+}
+
+// validateQueries ensures the named queries and their expression are
+// consistent.
+func (c *ScalingPolicyCheck) validateQueries() []error {
+	if len(c.Queries) == 0 {
+		if c.QueryExpression != "" {
+			return []error{errors.New("query_expression requires queries to be set")}
+		}
+		return nil
+	}
+
+	if c.Query != "" {
+		return []error{errors.New("query and queries can't be used together")}
+	}
+	if c.QueryExpression == "" {
+		return []error{errors.New("queries requires query_expression to be set")}
+	}
+
+	var errs []error
+
+	names := make([]string, 0, len(c.Queries))
+	for name := range c.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if c.Queries[name] == "" {
+			errs = append(errs, fmt.Errorf("query %q can't be empty", name))
+		}
+	}
+
+	expr, err := ParseQueryExpression(c.QueryExpression)
+	if err != nil {
+		return append(errs, fmt.Errorf("failed to parse query_expression: %v", err))
+	}
+	for _, v := range expr.Variables() {
+		if _, ok := c.Queries[v]; !ok {
+			errs = append(errs, fmt.Errorf("query_expression references undefined query %q", v))
+		}
+	}
+
+	return errs
+}
+
+// validateQueryTemplates ensures all the queries of the check which use
+// templates can be parsed.
+func (c *ScalingPolicyCheck) validateQueryTemplates() []error {
+	queries := []string{c.Query}
+	for _, q := range c.Queries {
+		queries = append(queries, q)
+	}
+	for _, f := range c.Fallbacks {
+		queries = append(queries, f.Query)
+	}
+
+	var errs []error
+	for _, q := range queries {
+		if !IsQueryTemplate(q) {
+			continue
+		}
+		if err := ParseQueryTemplate(q); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// validateQueryRetries ensures the query window, timeout and retry settings
+// are within range.
+func (c *ScalingPolicyCheck) validateQueryRetries() []error {
+	var errs []error
+
+	if c.QueryWindow < 0 {
+		errs = append(errs, errors.New("query_window must not be negative"))
+	}
+
+	if c.QueryTimeout < 0 {
+		errs = append(errs, errors.New("query_timeout must not be negative"))
+	}
+	if c.QueryRetries < 0 {
+		errs = append(errs, errors.New("query_retries must not be negative"))
+	}
+	if c.QueryRetryBackoff < 0 {
+		errs = append(errs, errors.New("query_retry_backoff must not be negative"))
+	}
+	if c.QueryRetryMaxBackoff < 0 {
+		errs = append(errs, errors.New("query_retry_max_backoff must not be negative"))
+	}
+	if c.QueryRetryMaxBackoff > 0 && c.QueryRetryBackoff > c.QueryRetryMaxBackoff {
+		errs = append(errs, errors.New("query_retry_backoff must not be greater than query_retry_max_backoff"))
+	}
+
+	return errs
+}
+
+// validateMissingData ensures the missing data settings are valid and
+// consistent.
+func (c *ScalingPolicyCheck) validateMissingData() []error {
+	var errs []error
+
+	switch c.MissingData {
+	case "", ScalingPolicyMissingDataHold, ScalingPolicyMissingDataZero,
+		ScalingPolicyMissingDataLast, ScalingPolicyMissingDataError:
+	default:
+		errs = append(errs, fmt.Errorf("invalid missing_data %q, allowed values are %s, %s, %s or %s",
+			c.MissingData, ScalingPolicyMissingDataHold, ScalingPolicyMissingDataZero,
+			ScalingPolicyMissingDataLast, ScalingPolicyMissingDataError))
+	}
+
+	if c.MissingDataMaxStaleness < 0 {
+		errs = append(errs, errors.New("missing_data_max_staleness must not be negative"))
+	}
+	if c.MissingDataMaxStaleness != 0 && c.MissingData != ScalingPolicyMissingDataLast {
+		errs = append(errs, fmt.Errorf("missing_data_max_staleness requires missing_data to be %s", ScalingPolicyMissingDataLast))
+	}
+
+	return errs
+}
+
+// validateFallbacks ensures the fallbacks of the check are complete.
+func (c *ScalingPolicyCheck) validateFallbacks() []error {
+	if len(c.Fallbacks) == 0 {
+		return nil
+	}
+
+	if !c.HasQuery() {
+		return []error{errors.New("fallbacks require the check to have a query")}
+	}
+
+	var errs []error
+	for i, f := range c.Fallbacks {
+		if f.Source == "" {
+			errs = append(errs, fmt.Errorf("fallback %d must have a source", i))
+		}
+		if f.Query == "" {
+			errs = append(errs, fmt.Errorf("fallback %d must have a query", i))
+		}
+	}
+	return errs
+}
+
 // ScalingPolicyStrategy contains the plugin and configuration details for
 // calculating the desired target state from the current state.
 type ScalingPolicyStrategy struct {
@@ -173,6 +521,50 @@ type ScalingPolicyTarget struct {
 	// Config is the mapping of config values used by the target plugin. Each
 	// plugin has a set of potentially uniquely supported keys.
 	Config map[string]string `hcl:",remain"`
+
+	// SubTargets optionally splits a cluster target into several independent
+	// targets, such as one per node class, which are evaluated together. The
+	// policy checks and limits apply to the sum of their counts, and each
+	// scaling action is distributed across the sub targets. In policy files
+	// they are defined using sub_target blocks alongside the target block.
+	SubTargets []*ScalingPolicySubTarget
+}
+
+// ScalingPolicySubTarget is one of the targets of a cluster policy spanning
+// several node classes or pools. It is handled by the target plugin of the
+// policy, using the policy target config merged with its own config.
+type ScalingPolicySubTarget struct {
+
+	// Name uniquely identifies the sub target within the policy.
+	Name string `hcl:"name,label"`
+
+	// Min and Max bound the count of the sub target. A Max of zero means the
+	// count is only bounded by the policy limits.
+	Min int64 `hcl:"min,optional"`
+	Max int64 `hcl:"max,optional"`
+
+	// Weight is the relative share of the policy count the sub target should
+	// hold, within its limits. Sub targets without a weight have a weight of
+	// one.
+	Weight int64 `hcl:"weight,optional"`
+
+	// Config is the mapping of config values which are added to, or
+	// override, the policy target config for this sub target.
+	Config map[string]string `hcl:",remain"`
+}
+
+// TargetConfig returns the config used to call the target plugin for the sub
+// target, which is the policy target config merged with the sub target
+// config.
+func (s *ScalingPolicySubTarget) TargetConfig(config map[string]string) map[string]string {
+	out := make(map[string]string, len(config)+len(s.Config))
+	for k, v := range config {
+		out[k] = v
+	}
+	for k, v := range s.Config {
+		out[k] = v
+	}
+	return out
 }
 
 // IsJobTaskGroupTarget identifies whether the ScalingPolicyTarget relates to a
@@ -191,7 +583,8 @@ func (t *ScalingPolicyTarget) IsNodePoolTarget() bool {
 	}
 	_, classOK := t.Config[TargetConfigKeyClass]
 	_, dcOK := t.Config[TargetConfigKeyDatacenter]
-	return classOK || dcOK
+	_, poolOK := t.Config[TargetConfigKeyNodePool]
+	return classOK || dcOK || poolOK
 }
 
 type FileDecodeScalingPolicies struct {
@@ -203,33 +596,70 @@ type FileDecodeScalingPolicies struct {
 // flattened when compared to the literal HCL version. Therefore we cannot
 // translate into the internal struct but use this.
 type FileDecodeScalingPolicy struct {
-	Name    string               `hcl:"name,label"`
-	Enabled bool                 `hcl:"enabled,optional"`
-	Type    string               `hcl:"type,optional"`
-	Min     int64                `hcl:"min,optional"`
-	Max     int64                `hcl:"max"`
-	Doc     *FileDecodePolicyDoc `hcl:"policy,block"`
+	Name      string               `hcl:"name,label"`
+	Enabled   bool                 `hcl:"enabled,optional"`
+	Type      string               `hcl:"type,optional"`
+	Min       *int64               `hcl:"min,optional"`
+	Max       *int64               `hcl:"max,optional"`
+	DependsOn []string             `hcl:"depends_on,optional"`
+	Labels    map[string]string    `hcl:"labels,optional"`
+	Doc       *FileDecodePolicyDoc `hcl:"policy,block"`
 }
 
 type FileDecodePolicyDoc struct {
 	Cooldown              time.Duration
 	CooldownHCL           string `hcl:"cooldown,optional"`
+	CooldownGroup         string `hcl:"cooldown_group,optional"`
 	EvaluationInterval    time.Duration
 	EvaluationIntervalHCL string                      `hcl:"evaluation_interval,optional"`
 	OnCheckError          string                      `hcl:"on_check_error,optional"`
+	DryRun                bool                        `hcl:"dry_run,optional"`
+	EnabledSchedule       *FileDecodePolicySchedule   `hcl:"enabled_schedule,block"`
 	Checks                []*FileDecodePolicyCheckDoc `hcl:"check,block"`
 	Target                *ScalingPolicyTarget        `hcl:"target,block"`
+	SubTargets            []*ScalingPolicySubTarget   `hcl:"sub_target,block"`
+}
+
+type FileDecodePolicySchedule struct {
+	Timezone        string                            `hcl:"timezone,optional"`
+	ActiveWindows   []*FileDecodePolicyScheduleWindow `hcl:"active_window,block"`
+	InactiveWindows []*FileDecodePolicyScheduleWindow `hcl:"inactive_window,block"`
+}
+
+type FileDecodePolicyScheduleWindow struct {
+	Start       string `hcl:"start"`
+	Duration    time.Duration
+	DurationHCL string `hcl:"duration"`
 }
 
 type FileDecodePolicyCheckDoc struct {
-	Name           string `hcl:"name,label"`
-	Group          string `hcl:"group,optional"`
-	Source         string `hcl:"source,optional"`
-	Query          string `hcl:"query,optional"`
-	QueryWindow    time.Duration
-	QueryWindowHCL string                 `hcl:"query_window,optional"`
-	OnError        string                 `hcl:"on_error,optional"`
-	Strategy       *ScalingPolicyStrategy `hcl:"strategy,block"`
+	Name                    string            `hcl:"name,label"`
+	Group                   string            `hcl:"group,optional"`
+	Source                  string            `hcl:"source,optional"`
+	Query                   string            `hcl:"query,optional"`
+	Queries                 map[string]string `hcl:"queries,optional"`
+	QueryExpression         string            `hcl:"query_expression,optional"`
+	QueryWindow             time.Duration
+	QueryWindowHCL          string `hcl:"query_window,optional"`
+	QueryTimeout            time.Duration
+	QueryTimeoutHCL         string `hcl:"query_timeout,optional"`
+	QueryRetries            int    `hcl:"query_retries,optional"`
+	QueryRetryBackoff       time.Duration
+	QueryRetryBackoffHCL    string `hcl:"query_retry_backoff,optional"`
+	QueryRetryMaxBackoff    time.Duration
+	QueryRetryMaxBackoffHCL string `hcl:"query_retry_max_backoff,optional"`
+	Unit                    string `hcl:"unit,optional"`
+	MissingData             string `hcl:"missing_data,optional"`
+	MissingDataMaxStaleness time.Duration
+	MissingDataMaxStaleHCL  string                           `hcl:"missing_data_max_staleness,optional"`
+	OnError                 string                           `hcl:"on_error,optional"`
+	Fallbacks               []*FileDecodePolicyCheckFallback `hcl:"fallback,block"`
+	Strategy                *ScalingPolicyStrategy           `hcl:"strategy,block"`
+}
+
+type FileDecodePolicyCheckFallback struct {
+	Source string `hcl:"source"`
+	Query  string `hcl:"query"`
 }
 
 // Translate all values from the decoded policy file into our internal policy
@@ -237,15 +667,33 @@ type FileDecodePolicyCheckDoc struct {
 func (fpd *FileDecodeScalingPolicy) Translate() *ScalingPolicy {
 	p := &ScalingPolicy{}
 
-	p.Min = fpd.Min
-	p.Max = fpd.Max
+	p.Name = fpd.Name
+	p.DependsOn = fpd.DependsOn
+	p.Labels = fpd.Labels
+	if fpd.Min != nil {
+		p.Min = *fpd.Min
+	} else {
+		p.MinUnset = true
+	}
+	if fpd.Max != nil {
+		p.Max = *fpd.Max
+	} else {
+		p.MaxUnset = true
+	}
 	p.Enabled = fpd.Enabled
 	p.Type = fpd.Type
 	p.Cooldown = fpd.Doc.Cooldown
+	p.CooldownGroup = fpd.Doc.CooldownGroup
 	p.EvaluationInterval = fpd.Doc.EvaluationInterval
 	p.OnCheckError = fpd.Doc.OnCheckError
+	p.DryRun = fpd.Doc.DryRun
+	p.EnabledSchedule = fpd.Doc.EnabledSchedule.Translate()
 	p.Target = fpd.Doc.Target
 
+	if p.Target != nil && len(fpd.Doc.SubTargets) > 0 {
+		p.Target.SubTargets = fpd.Doc.SubTargets
+	}
+
 	fpd.translateChecks(p)
 
 	return p
@@ -262,6 +710,28 @@ func (fpd *FileDecodeScalingPolicy) translateChecks(p *ScalingPolicy) {
 	p.Checks = checks
 }
 
+// Translate all values from the decoded schedule into our internal policy
+// schedule object.
+func (fds *FileDecodePolicySchedule) Translate() *ScalingPolicySchedule {
+	if fds == nil {
+		return nil
+	}
+
+	translateWindows := func(in []*FileDecodePolicyScheduleWindow) []*ScalingPolicyScheduleWindow {
+		var out []*ScalingPolicyScheduleWindow
+		for _, w := range in {
+			out = append(out, &ScalingPolicyScheduleWindow{Start: w.Start, Duration: w.Duration})
+		}
+		return out
+	}
+
+	return &ScalingPolicySchedule{
+		Timezone:        fds.Timezone,
+		ActiveWindows:   translateWindows(fds.ActiveWindows),
+		InactiveWindows: translateWindows(fds.InactiveWindows),
+	}
+}
+
 // Translate all values from the decoded policy check into our internal policy
 // check object.
 func (fdc *FileDecodePolicyCheckDoc) Translate(c *ScalingPolicyCheck) {
@@ -269,7 +739,20 @@ func (fdc *FileDecodePolicyCheckDoc) Translate(c *ScalingPolicyCheck) {
 	c.Group = fdc.Group
 	c.Source = fdc.Source
 	c.Query = fdc.Query
+	c.Queries = fdc.Queries
+	c.QueryExpression = fdc.QueryExpression
 	c.QueryWindow = fdc.QueryWindow
+	c.QueryTimeout = fdc.QueryTimeout
+	c.QueryRetries = fdc.QueryRetries
+	c.QueryRetryBackoff = fdc.QueryRetryBackoff
+	c.QueryRetryMaxBackoff = fdc.QueryRetryMaxBackoff
+	c.Unit = fdc.Unit
+	c.MissingData = fdc.MissingData
+	c.MissingDataMaxStaleness = fdc.MissingDataMaxStaleness
 	c.OnError = fdc.OnError
 	c.Strategy = fdc.Strategy
+
+	for _, f := range fdc.Fallbacks {
+		c.Fallbacks = append(c.Fallbacks, &ScalingPolicyCheckFallback{Source: f.Source, Query: f.Query})
+	}
 }
@@ -0,0 +1,326 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QueryExpression is an arithmetic expression over the named queries of a
+// check. It supports numbers, query names, the +, -, * and / operators,
+// unary minus and parentheses, such as "requests / healthy_instances".
+type QueryExpression struct {
+	raw  string
+	root exprNode
+	vars []string
+}
+
+// ParseQueryExpression parses the input into a QueryExpression.
+func ParseQueryExpression(s string) (*QueryExpression, error) {
+	p := &exprParser{input: s}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", p.tok, p.tok.pos)
+	}
+
+	vars := make([]string, 0, len(p.vars))
+	for v := range p.vars {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+
+	return &QueryExpression{raw: s, root: root, vars: vars}, nil
+}
+
+// String returns the expression as it was written.
+func (e *QueryExpression) String() string { return e.raw }
+
+// Variables returns the sorted list of query names used in the expression.
+func (e *QueryExpression) Variables() []string { return e.vars }
+
+// Evaluate computes the expression using the input values for each query
+// name. An error is returned if a value is missing or the expression divides
+// by zero.
+func (e *QueryExpression) Evaluate(values map[string]float64) (float64, error) {
+	return e.root.eval(values)
+}
+
+// Aggregate evaluates the expression over the metrics returned by each named
+// query. Metrics are aligned by timestamp: a point is produced for each
+// timestamp returned by any of the queries, using the most recent value of
+// every query at that time. Timestamps before all queries have returned a
+// value are skipped.
+func (e *QueryExpression) Aggregate(results map[string]TimestampedMetrics) (TimestampedMetrics, error) {
+	for _, v := range e.vars {
+		if _, ok := results[v]; !ok {
+			return nil, fmt.Errorf("missing result for query %q", v)
+		}
+	}
+
+	// Collect the sorted, unique timestamps of all the results.
+	var timestamps TimestampedMetrics
+	seen := make(map[int64]bool)
+	series := make(map[string]TimestampedMetrics, len(e.vars))
+	for _, v := range e.vars {
+		sorted := make(TimestampedMetrics, len(results[v]))
+		copy(sorted, results[v])
+		sort.Stable(sorted)
+		series[v] = sorted
+
+		for _, m := range sorted {
+			if !seen[m.Timestamp.UnixNano()] {
+				seen[m.Timestamp.UnixNano()] = true
+				timestamps = append(timestamps, TimestampedMetric{Timestamp: m.Timestamp})
+			}
+		}
+	}
+	sort.Sort(timestamps)
+
+	out := TimestampedMetrics{}
+	idx := make(map[string]int, len(e.vars))
+	values := make(map[string]float64, len(e.vars))
+
+	for _, ts := range timestamps {
+		for _, v := range e.vars {
+			for idx[v] < len(series[v]) && !series[v][idx[v]].Timestamp.After(ts.Timestamp) {
+				values[v] = series[v][idx[v]].Value
+				idx[v]++
+			}
+		}
+		if len(values) < len(e.vars) {
+			continue
+		}
+
+		value, err := e.Evaluate(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %q at %s: %v", e.raw, ts.Timestamp, err)
+		}
+		out = append(out, TimestampedMetric{Timestamp: ts.Timestamp, Value: value})
+	}
+
+	return out, nil
+}
+
+// exprNode is a node in the parsed expression tree.
+type exprNode interface {
+	eval(values map[string]float64) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(_ map[string]float64) (float64, error) { return float64(n), nil }
+
+type varNode string
+
+func (n varNode) eval(values map[string]float64) (float64, error) {
+	v, ok := values[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("no value for query %q", string(n))
+	}
+	return v, nil
+}
+
+type negNode struct{ x exprNode }
+
+func (n negNode) eval(values map[string]float64) (float64, error) {
+	v, err := n.x.eval(values)
+	return -v, err
+}
+
+type binaryNode struct {
+	op   byte
+	l, r exprNode
+}
+
+func (n binaryNode) eval(values map[string]float64) (float64, error) {
+	l, err := n.l.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.r.eval(values)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	default:
+		if r == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return l / r, nil
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// exprParser is a recursive descent parser for query expressions.
+type exprParser struct {
+	input string
+	pos   int
+	tok   token
+	vars  map[string]bool
+}
+
+// next advances the parser to the next token in the input.
+func (p *exprParser) next() error {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n') {
+		p.pos++
+	}
+
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = token{kind: tokenEOF, pos: start}
+		return nil
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case strings.IndexByte("+-*/()", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokenOp, text: string(c), pos: start}
+	case isDigit(c) || c == '.':
+		for p.pos < len(p.input) && (isDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = token{kind: tokenNumber, text: p.input[start:p.pos], pos: start}
+	case isIdentStart(c):
+		for p.pos < len(p.input) && (isIdentStart(p.input[p.pos]) || isDigit(p.input[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokenIdent, text: p.input[start:p.pos], pos: start}
+	default:
+		return fmt.Errorf("unexpected character %q at position %d", c, start)
+	}
+	return nil
+}
+
+// parseSum parses terms separated by + and -.
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokenOp && (p.tok.text == "+" || p.tok.text == "-") {
+		op := p.tok.text[0]
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, l: left, r: right}
+	}
+	return left, nil
+}
+
+// parseProduct parses factors separated by * and /.
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokenOp && (p.tok.text == "*" || p.tok.text == "/") {
+		op := p.tok.text[0]
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, l: left, r: right}
+	}
+	return left, nil
+}
+
+// parseFactor parses numbers, query names, unary minus and parenthesized
+// expressions.
+func (p *exprParser) parseFactor() (exprNode, error) {
+	tok := p.tok
+
+	switch {
+	case tok.kind == tokenNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return numberNode(f), p.next()
+
+	case tok.kind == tokenIdent:
+		if p.vars == nil {
+			p.vars = make(map[string]bool)
+		}
+		p.vars[tok.text] = true
+		return varNode(tok.text), p.next()
+
+	case tok.kind == tokenOp && tok.text == "-":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{x: x}, nil
+
+	case tok.kind == tokenOp && tok.text == "(":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenOp || p.tok.text != ")" {
+			return nil, fmt.Errorf("expected \")\" at position %d, found %s", p.tok.pos, p.tok)
+		}
+		return x, p.next()
+	}
+
+	return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// queryTemplateNamespaceKey is the target config key holding the namespace
+// of Nomad job targets.
+const queryTemplateNamespaceKey = "Namespace"
+
+// IsQueryTemplate returns true if the query uses template actions and must
+// be rendered with RenderQuery before being run.
+func IsQueryTemplate(q string) bool {
+	return strings.Contains(q, "{{")
+}
+
+// ParseQueryTemplate checks the query template syntax without rendering it.
+func ParseQueryTemplate(q string) error {
+	_, err := newQueryTemplate(q, nil)
+	return err
+}
+
+// RenderQuery interpolates the well-known policy variables in the query, so
+// a single check definition can be shared across many targets. Queries which
+// are not templates are returned unchanged. The following functions are
+// available:
+//
+//	{{ job }}, {{ group }}, {{ namespace }}       - Nomad job group target values
+//	{{ class }}, {{ datacenter }}, {{ node_pool }} - Nomad node pool target values
+//	{{ policy_id }}, {{ policy_name }}           - policy identifiers
+//	{{ label "key" }}                            - policy label values
+//	{{ target "key" }}                           - arbitrary target config values
+//
+// An error is returned if a referenced value is not set for the policy.
+func RenderQuery(q string, p *ScalingPolicy) (string, error) {
+	if !IsQueryTemplate(q) {
+		return q, nil
+	}
+
+	tmpl, err := newQueryTemplate(q, p)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, nil); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func newQueryTemplate(q string, p *ScalingPolicy) (*template.Template, error) {
+	if p == nil {
+		p = &ScalingPolicy{}
+	}
+
+	var targetConfig map[string]string
+	if p.Target != nil {
+		targetConfig = p.Target.Config
+	}
+
+	lookup := func(kind string, m map[string]string, key string) (string, error) {
+		v, ok := m[key]
+		if !ok || v == "" {
+			return "", fmt.Errorf("%s %q is not set for policy", kind, key)
+		}
+		return v, nil
+	}
+	targetFunc := func(key string) func() (string, error) {
+		return func() (string, error) { return lookup("target config", targetConfig, key) }
+	}
+
+	funcs := template.FuncMap{
+		"job":         targetFunc(TargetConfigKeyJob),
+		"group":       targetFunc(TargetConfigKeyTaskGroup),
+		"namespace":   targetFunc(queryTemplateNamespaceKey),
+		"class":       targetFunc(TargetConfigKeyClass),
+		"datacenter":  targetFunc(TargetConfigKeyDatacenter),
+		"node_pool":   targetFunc(TargetConfigKeyNodePool),
+		"policy_id":   func() string { return p.ID },
+		"policy_name": func() string { return p.Name },
+		"label": func(key string) (string, error) {
+			return lookup("label", p.Labels, key)
+		},
+		"target": func(key string) (string, error) {
+			return lookup("target config", targetConfig, key)
+		},
+	}
+
+	return template.New("query").Funcs(funcs).Option("missingkey=error").Parse(q)
+}
@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderQuery(t *testing.T) {
+	policy := &ScalingPolicy{
+		ID:     "a6e0c3b1",
+		Name:   "default/web/frontend",
+		Labels: map[string]string{"team": "payments"},
+		Target: &ScalingPolicyTarget{
+			Config: map[string]string{
+				"Namespace": "default",
+				"Job":       "web",
+				"Group":     "frontend",
+				"region":    "eu-west-1",
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "not a template",
+			query:    `sum(rate(http_requests_total{job="web"}[1m]))`,
+			expected: `sum(rate(http_requests_total{job="web"}[1m]))`,
+		},
+		{
+			name:     "job group variables",
+			query:    `sum(rate(http_requests_total{namespace="{{ namespace }}",job="{{ job }}",group="{{ group }}"}[1m]))`,
+			expected: `sum(rate(http_requests_total{namespace="default",job="web",group="frontend"}[1m]))`,
+		},
+		{
+			name:     "policy variables",
+			query:    `up{policy="{{ policy_id }}",name="{{ policy_name }}",team="{{ label "team" }}",region="{{ target "region" }}"}`,
+			expected: `up{policy="a6e0c3b1",name="default/web/frontend",team="payments",region="eu-west-1"}`,
+		},
+		{
+			name:          "missing target value",
+			query:         `nomad_client_allocated_cpu{node_class="{{ class }}"}`,
+			expectedError: `target config "node_class" is not set for policy`,
+		},
+		{
+			name:          "missing label",
+			query:         `up{tier="{{ label "tier" }}"}`,
+			expectedError: `label "tier" is not set for policy`,
+		},
+		{
+			name:          "unknown function",
+			query:         `up{job="{{ service }}"}`,
+			expectedError: `function "service" not defined`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := RenderQuery(tc.query, policy)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestParseQueryTemplate(t *testing.T) {
+	assert.NoError(t, ParseQueryTemplate(`up{job="{{ job }}",team="{{ label "team" }}"}`))
+	assert.ErrorContains(t, ParseQueryTemplate(`up{job="{{ job }"}`), "unexpected")
+}
@@ -0,0 +1,141 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQueryExpression(t *testing.T) {
+	values := map[string]float64{"requests": 120, "healthy_instances": 4, "errors": 6}
+
+	testCases := []struct {
+		expr          string
+		expectedVars  []string
+		expectedValue float64
+		expectedError string
+	}{
+		{
+			expr:          "requests / healthy_instances",
+			expectedVars:  []string{"healthy_instances", "requests"},
+			expectedValue: 30,
+		},
+		{
+			expr:          "(requests - errors) / healthy_instances * 2",
+			expectedVars:  []string{"errors", "healthy_instances", "requests"},
+			expectedValue: 57,
+		},
+		{
+			expr:          "requests - errors * 10 + -healthy_instances",
+			expectedVars:  []string{"errors", "healthy_instances", "requests"},
+			expectedValue: 56,
+		},
+		{
+			expr:          "1.5 * 2",
+			expectedVars:  []string{},
+			expectedValue: 3,
+		},
+		{
+			expr:          "",
+			expectedError: "unexpected end of expression at position 0",
+		},
+		{
+			expr:          "requests /",
+			expectedError: "unexpected end of expression at position 10",
+		},
+		{
+			expr:          "(requests",
+			expectedError: `expected ")" at position 9, found end of expression`,
+		},
+		{
+			expr:          "requests healthy_instances",
+			expectedError: `unexpected "healthy_instances" at position 9`,
+		},
+		{
+			expr:          "requests % 2",
+			expectedError: `unexpected character '%' at position 9`,
+		},
+		{
+			expr:          "1.2.3",
+			expectedError: `invalid number "1.2.3" at position 0`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := ParseQueryExpression(tc.expr)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedVars, expr.Variables())
+
+			value, err := expr.Evaluate(values)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, value)
+		})
+	}
+}
+
+func TestQueryExpression_Evaluate_error(t *testing.T) {
+	expr, err := ParseQueryExpression("requests / healthy")
+	require.NoError(t, err)
+
+	_, err = expr.Evaluate(map[string]float64{"requests": 1})
+	assert.EqualError(t, err, `no value for query "healthy"`)
+
+	_, err = expr.Evaluate(map[string]float64{"requests": 1, "healthy": 0})
+	assert.EqualError(t, err, "division by zero")
+}
+
+func TestQueryExpression_Aggregate(t *testing.T) {
+	expr, err := ParseQueryExpression("requests / healthy")
+	require.NoError(t, err)
+
+	ts := time.Unix(1700000000, 0)
+
+	// Series are aligned by timestamp, using the most recent value of each
+	// query. Points before every query has a value are skipped.
+	actual, err := expr.Aggregate(map[string]TimestampedMetrics{
+		"requests": {
+			{Timestamp: ts.Add(20 * time.Second), Value: 60},
+			{Timestamp: ts, Value: 40},
+			{Timestamp: ts.Add(10 * time.Second), Value: 50},
+		},
+		"healthy": {
+			{Timestamp: ts.Add(5 * time.Second), Value: 2},
+			{Timestamp: ts.Add(20 * time.Second), Value: 3},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TimestampedMetrics{
+		{Timestamp: ts.Add(5 * time.Second), Value: 20},
+		{Timestamp: ts.Add(10 * time.Second), Value: 25},
+		{Timestamp: ts.Add(20 * time.Second), Value: 20},
+	}, actual)
+
+	// Empty results produce no metrics.
+	actual, err = expr.Aggregate(map[string]TimestampedMetrics{
+		"requests": {{Timestamp: ts, Value: 40}},
+		"healthy":  {},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	_, err = expr.Aggregate(map[string]TimestampedMetrics{
+		"requests": {{Timestamp: ts, Value: 40}},
+	})
+	assert.EqualError(t, err, `missing result for query "healthy"`)
+
+	_, err = expr.Aggregate(map[string]TimestampedMetrics{
+		"requests": {{Timestamp: ts, Value: 40}},
+		"healthy":  {{Timestamp: ts, Value: 0}},
+	})
+	assert.ErrorContains(t, err, "division by zero")
+}
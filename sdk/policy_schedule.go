@@ -0,0 +1,123 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/cronexpr"
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// ScalingPolicySchedule controls when a policy is active based on recurring
+// time windows. A policy with a schedule is only evaluated when the current
+// time is within one of the active windows, if any are defined, and outside
+// all the inactive windows.
+type ScalingPolicySchedule struct {
+
+	// Timezone is the IANA timezone name used to evaluate the windows, such
+	// as "Europe/London". An empty value uses UTC.
+	Timezone string
+
+	// ActiveWindows lists the windows during which the policy is active. If
+	// empty, the policy is active at all times outside the inactive windows.
+	ActiveWindows []*ScalingPolicyScheduleWindow
+
+	// InactiveWindows lists the windows during which the policy is not
+	// active, such as deployment freeze periods. These take precedence over
+	// the active windows.
+	InactiveWindows []*ScalingPolicyScheduleWindow
+}
+
+// ScalingPolicyScheduleWindow is a recurring time window.
+type ScalingPolicyScheduleWindow struct {
+
+	// Start is a cron expression which defines when the window opens.
+	Start string
+
+	// Duration is how long the window stays open after each start.
+	Duration time.Duration
+}
+
+// Validate checks the schedule timezone and windows are valid.
+func (s *ScalingPolicySchedule) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	var result *multierror.Error
+
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		result = multierror.Append(result, fmt.Errorf("invalid enabled_schedule timezone %q: %v", s.Timezone, err))
+	}
+
+	validateWindows := func(kind string, windows []*ScalingPolicyScheduleWindow) {
+		for i, w := range windows {
+			if _, err := cronexpr.Parse(w.Start); err != nil {
+				result = multierror.Append(result, fmt.Errorf("invalid enabled_schedule %s[%d] start %q: %v", kind, i, w.Start, err))
+			}
+			if w.Duration <= 0 {
+				result = multierror.Append(result, fmt.Errorf("invalid enabled_schedule %s[%d] duration: must be greater than zero", kind, i))
+			}
+		}
+	}
+	validateWindows("active_window", s.ActiveWindows)
+	validateWindows("inactive_window", s.InactiveWindows)
+
+	return result.ErrorOrNil()
+}
+
+// IsActive returns whether the schedule allows the policy to be evaluated at
+// the passed time. A nil schedule is always active.
+func (s *ScalingPolicySchedule) IsActive(t time.Time) (bool, error) {
+	if s == nil {
+		return true, nil
+	}
+
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false, err
+	}
+	t = t.In(loc)
+
+	for _, w := range s.InactiveWindows {
+		in, err := w.contains(t)
+		if err != nil {
+			return false, err
+		}
+		if in {
+			return false, nil
+		}
+	}
+
+	if len(s.ActiveWindows) == 0 {
+		return true, nil
+	}
+
+	for _, w := range s.ActiveWindows {
+		in, err := w.contains(t)
+		if err != nil {
+			return false, err
+		}
+		if in {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// contains returns whether the passed time is within an occurrence of the
+// window. This is the case if the window started within the last Duration.
+func (w *ScalingPolicyScheduleWindow) contains(t time.Time) (bool, error) {
+	expr, err := cronexpr.Parse(w.Start)
+	if err != nil {
+		return false, err
+	}
+
+	// Any start time after t-Duration, and not after t, means the window is
+	// currently open.
+	next := expr.Next(t.Add(-w.Duration))
+	return !next.IsZero() && !next.After(t), nil
+}
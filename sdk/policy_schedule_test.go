@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScalingPolicySchedule_IsActive(t *testing.T) {
+	businessHours := &ScalingPolicyScheduleWindow{Start: "0 9 * * 1-5", Duration: 8 * time.Hour}
+	freeze := &ScalingPolicyScheduleWindow{Start: "0 12 * * 3", Duration: time.Hour}
+
+	testCases := []struct {
+		name           string
+		inputSchedule  *ScalingPolicySchedule
+		inputTime      string
+		expectedActive bool
+	}{
+		{
+			name:           "nil schedule",
+			inputSchedule:  nil,
+			inputTime:      "2023-06-04T03:00:00Z",
+			expectedActive: true,
+		},
+		{
+			name:           "within active window",
+			inputSchedule:  &ScalingPolicySchedule{ActiveWindows: []*ScalingPolicyScheduleWindow{businessHours}},
+			inputTime:      "2023-06-05T09:00:00Z",
+			expectedActive: true,
+		},
+		{
+			name:           "end of active window",
+			inputSchedule:  &ScalingPolicySchedule{ActiveWindows: []*ScalingPolicyScheduleWindow{businessHours}},
+			inputTime:      "2023-06-05T17:00:00Z",
+			expectedActive: false,
+		},
+		{
+			name:           "weekend outside active window",
+			inputSchedule:  &ScalingPolicySchedule{ActiveWindows: []*ScalingPolicyScheduleWindow{businessHours}},
+			inputTime:      "2023-06-04T10:00:00Z",
+			expectedActive: false,
+		},
+		{
+			name:           "timezone applied",
+			inputSchedule:  &ScalingPolicySchedule{Timezone: "America/New_York", ActiveWindows: []*ScalingPolicyScheduleWindow{businessHours}},
+			inputTime:      "2023-06-05T09:30:00Z",
+			expectedActive: false,
+		},
+		{
+			name:           "only inactive windows",
+			inputSchedule:  &ScalingPolicySchedule{InactiveWindows: []*ScalingPolicyScheduleWindow{freeze}},
+			inputTime:      "2023-06-07T11:59:00Z",
+			expectedActive: true,
+		},
+		{
+			name: "inactive window takes precedence",
+			inputSchedule: &ScalingPolicySchedule{
+				ActiveWindows:   []*ScalingPolicyScheduleWindow{businessHours},
+				InactiveWindows: []*ScalingPolicyScheduleWindow{freeze},
+			},
+			inputTime:      "2023-06-07T12:30:00Z",
+			expectedActive: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tc.inputTime)
+			require.NoError(t, err)
+
+			active, err := tc.inputSchedule.IsActive(now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedActive, active)
+		})
+	}
+}
+
+func TestScalingPolicySchedule_Validate(t *testing.T) {
+	assert.NoError(t, (*ScalingPolicySchedule)(nil).Validate())
+	assert.NoError(t, (&ScalingPolicySchedule{
+		Timezone:      "Europe/London",
+		ActiveWindows: []*ScalingPolicyScheduleWindow{{Start: "0 9 * * 1-5", Duration: time.Hour}},
+	}).Validate())
+
+	err := (&ScalingPolicySchedule{
+		Timezone:        "Mars/Olympus_Mons",
+		ActiveWindows:   []*ScalingPolicyScheduleWindow{{Start: "not a cron", Duration: time.Hour}},
+		InactiveWindows: []*ScalingPolicyScheduleWindow{{Start: "0 9 * * *"}},
+	}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid enabled_schedule timezone "Mars/Olympus_Mons"`)
+	assert.Contains(t, err.Error(), `invalid enabled_schedule active_window[0] start "not a cron"`)
+	assert.Contains(t, err.Error(), "invalid enabled_schedule inactive_window[0] duration")
+}
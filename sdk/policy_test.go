@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/nomad-autoscaler/sdk/helper/ptr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,6 +74,340 @@ func TestScalingPolicy_Validate(t *testing.T) {
 			},
 			expectedError: "can only be used with Dynamic Application Sizing",
 		},
+		{
+			name: "query and queries",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:            "queries",
+						Query:           "total",
+						Queries:         map[string]string{"a": "a"},
+						QueryExpression: "a",
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "query and queries can't be used together",
+		},
+		{
+			name: "queries without expression",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:    "queries",
+						Queries: map[string]string{"a": "a"},
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "queries requires query_expression to be set",
+		},
+		{
+			name: "expression without queries",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:            "queries",
+						QueryExpression: "a",
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "query_expression requires queries to be set",
+		},
+		{
+			name: "invalid expression",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:            "queries",
+						Queries:         map[string]string{"a": "a"},
+						QueryExpression: "a /",
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "failed to parse query_expression",
+		},
+		{
+			name: "undefined query",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:            "queries",
+						Queries:         map[string]string{"a": "a"},
+						QueryExpression: "a / b",
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: `query_expression references undefined query "b"`,
+		},
+		{
+			name: "valid queries",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:            "queries",
+						Queries:         map[string]string{"a": "a", "b": "b"},
+						QueryExpression: "(a + 1) / b",
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "",
+		},
+		{
+			name: "fallback without query",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:      "fallback",
+						Fallbacks: []*ScalingPolicyCheckFallback{{Source: "cloudwatch", Query: "q"}},
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "fallbacks require the check to have a query",
+		},
+		{
+			name: "incomplete fallback",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:      "fallback",
+						Query:     "q",
+						Fallbacks: []*ScalingPolicyCheckFallback{{Source: "cloudwatch", Query: "q"}, {}},
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "fallback 1 must have a source",
+		},
+		{
+			name: "invalid query retry backoff",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:                 "retries",
+						Query:                "q",
+						QueryRetries:         3,
+						QueryRetryBackoff:    time.Minute,
+						QueryRetryMaxBackoff: time.Second,
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "query_retry_backoff must not be greater than query_retry_max_backoff",
+		},
+		{
+			name: "negative query retries",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:         "retries",
+						Query:        "q",
+						QueryTimeout: -time.Second,
+						QueryRetries: -1,
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "query_retries must not be negative",
+		},
+		{
+			name: "negative evaluation interval",
+			policy: &ScalingPolicy{
+				Type:               "horizontal",
+				EvaluationInterval: -time.Second,
+			},
+			expectedError: "evaluation_interval must not be negative",
+		},
+		{
+			name: "negative query window",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:        "window",
+						Query:       "q",
+						QueryWindow: -time.Second,
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "query_window must not be negative",
+		},
+		{
+			name: "invalid query template",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:      "template",
+						Query:     `up{job="{{ job }}"}`,
+						Fallbacks: []*ScalingPolicyCheckFallback{{Source: "cloudwatch", Query: "{{ unknown }}"}},
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: `invalid query template in check template: template: query:1: function "unknown" not defined`,
+		},
+		{
+			name: "invalid unit",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:  "unit",
+						Query: "q",
+						Unit:  "bytes_to_furlongs",
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: `invalid unit in check unit: unsupported unit "bytes_to_furlongs"`,
+		},
+		{
+			name: "invalid missing data",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:        "missing",
+						Query:       "q",
+						MissingData: "guess",
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: `invalid missing data settings in check missing: invalid missing_data "guess", allowed values are hold, zero, last or error`,
+		},
+		{
+			name: "missing data max staleness without last",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Checks: []*ScalingPolicyCheck{
+					{
+						Name:                    "missing",
+						Query:                   "q",
+						MissingData:             "hold",
+						MissingDataMaxStaleness: time.Minute,
+						Strategy: &ScalingPolicyStrategy{
+							Name: "target-value",
+						},
+					},
+				},
+			},
+			expectedError: "invalid missing data settings in check missing: missing_data_max_staleness requires missing_data to be last",
+		},
+		{
+			name: "sub targets on horizontal policy",
+			policy: &ScalingPolicy{
+				Type: "horizontal",
+				Target: &ScalingPolicyTarget{
+					SubTargets: []*ScalingPolicySubTarget{{Name: "a"}},
+				},
+			},
+			expectedError: "sub targets can only be used with cluster policies",
+		},
+		{
+			name: "duplicate sub target",
+			policy: &ScalingPolicy{
+				Type: "cluster",
+				Max:  10,
+				Target: &ScalingPolicyTarget{
+					SubTargets: []*ScalingPolicySubTarget{{Name: "a"}, {Name: "a"}},
+				},
+			},
+			expectedError: `duplicate sub target name "a"`,
+		},
+		{
+			name: "sub target max lower than min",
+			policy: &ScalingPolicy{
+				Type: "cluster",
+				Min:  3,
+				Max:  10,
+				Target: &ScalingPolicyTarget{
+					SubTargets: []*ScalingPolicySubTarget{{Name: "a", Min: 3, Max: 2}},
+				},
+			},
+			expectedError: "max of sub target a must not be lower than its min",
+		},
+		{
+			name: "sub target min values above policy min",
+			policy: &ScalingPolicy{
+				Type: "cluster",
+				Min:  4,
+				Max:  10,
+				Target: &ScalingPolicyTarget{
+					SubTargets: []*ScalingPolicySubTarget{{Name: "a", Min: 3}, {Name: "b", Min: 2}},
+				},
+			},
+			expectedError: "sum of sub target min values (5) must not be greater than policy min (4)",
+		},
+		{
+			name: "sub target max values below policy max",
+			policy: &ScalingPolicy{
+				Type: "cluster",
+				Min:  1,
+				Max:  10,
+				Target: &ScalingPolicyTarget{
+					SubTargets: []*ScalingPolicySubTarget{{Name: "a", Max: 2}, {Name: "b", Max: 2}},
+				},
+			},
+			expectedError: "sum of sub target max values (4) must not be lower than policy max (10)",
+		},
+		{
+			name: "valid sub targets",
+			policy: &ScalingPolicy{
+				Type: "cluster",
+				Min:  1,
+				Max:  10,
+				Target: &ScalingPolicyTarget{
+					SubTargets: []*ScalingPolicySubTarget{{Name: "a", Min: 1, Max: 5}, {Name: "b", Weight: 2}},
+				},
+			},
+			expectedError: "",
+		},
 		{
 			name: "valid policy",
 			policy: &ScalingPolicy{
@@ -132,6 +467,13 @@ func TestScalingPolicyTarget_IsNodePoolTarget(t *testing.T) {
 			expectedOutput: true,
 			name:           "datacenter input target",
 		},
+		{
+			inputScalingPolicyTarget: &ScalingPolicyTarget{
+				Config: map[string]string{"node_pool": "gpu"},
+			},
+			expectedOutput: true,
+			name:           "node_pool input target",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -150,8 +492,8 @@ func TestFileDecodePolicy_Translate(t *testing.T) {
 		{
 			inputFileDecodePolicy: &FileDecodeScalingPolicy{
 				Enabled: true,
-				Min:     1,
-				Max:     3,
+				Min:     ptr.Int64ToPtr(1),
+				Max:     ptr.Int64ToPtr(3),
 				Doc: &FileDecodePolicyDoc{
 					Cooldown:              10 * time.Millisecond,
 					CooldownHCL:           "10ms",
@@ -0,0 +1,61 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// unitConversions are the check unit conversions and the factor metric
+// values are multiplied by.
+var unitConversions = map[string]float64{
+	"bytes_to_kib":             1.0 / (1 << 10),
+	"bytes_to_mib":             1.0 / (1 << 20),
+	"bytes_to_gib":             1.0 / (1 << 30),
+	"ratio_to_percent":         100,
+	"percent_to_ratio":         1.0 / 100,
+	"per_second_to_per_minute": 60,
+	"per_second_to_per_hour":   60 * 60,
+	"per_minute_to_per_second": 1.0 / 60,
+	"milliseconds_to_seconds":  1.0 / 1000,
+}
+
+// ValidateUnit returns an error if the check unit conversion is not
+// supported. An empty unit means no conversion.
+func ValidateUnit(unit string) error {
+	if unit == "" {
+		return nil
+	}
+	if _, ok := unitConversions[unit]; !ok {
+		units := make([]string, 0, len(unitConversions))
+		for u := range unitConversions {
+			units = append(units, u)
+		}
+		sort.Strings(units)
+		return fmt.Errorf("unsupported unit %q, allowed values are: %s", unit, strings.Join(units, ", "))
+	}
+	return nil
+}
+
+// ConvertUnit returns a copy of the metrics with the unit conversion applied
+// to their values, so strategies can rely on consistent units regardless of
+// the APM which returned the metrics. The input is returned unchanged if the
+// unit is empty.
+func ConvertUnit(m TimestampedMetrics, unit string) (TimestampedMetrics, error) {
+	if unit == "" || m == nil {
+		return m, nil
+	}
+	if err := ValidateUnit(unit); err != nil {
+		return nil, err
+	}
+
+	factor := unitConversions[unit]
+	out := make(TimestampedMetrics, len(m))
+	for i, metric := range m {
+		out[i] = TimestampedMetric{Timestamp: metric.Timestamp, Value: metric.Value * factor}
+	}
+	return out, nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertUnit(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+
+	testCases := []struct {
+		unit     string
+		input    float64
+		expected float64
+	}{
+		{unit: "", input: 512, expected: 512},
+		{unit: "bytes_to_kib", input: 2048, expected: 2},
+		{unit: "bytes_to_mib", input: 512 * 1024 * 1024, expected: 512},
+		{unit: "bytes_to_gib", input: 3 * 1024 * 1024 * 1024, expected: 3},
+		{unit: "ratio_to_percent", input: 0.75, expected: 75},
+		{unit: "percent_to_ratio", input: 75, expected: 0.75},
+		{unit: "per_second_to_per_minute", input: 2.5, expected: 150},
+		{unit: "per_second_to_per_hour", input: 2, expected: 7200},
+		{unit: "per_minute_to_per_second", input: 120, expected: 2},
+		{unit: "milliseconds_to_seconds", input: 1500, expected: 1.5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.unit, func(t *testing.T) {
+			input := TimestampedMetrics{{Timestamp: ts, Value: tc.input}}
+
+			actual, err := ConvertUnit(input, tc.unit)
+			require.NoError(t, err)
+			assert.Equal(t, TimestampedMetrics{{Timestamp: ts, Value: tc.expected}}, actual)
+
+			// The input metrics are not modified.
+			assert.Equal(t, tc.input, input[0].Value)
+		})
+	}
+
+	_, err := ConvertUnit(TimestampedMetrics{{Timestamp: ts, Value: 1}}, "invalid")
+	assert.ErrorContains(t, err, `unsupported unit "invalid", allowed values are: bytes_to_gib, bytes_to_kib`)
+
+	actual, err := ConvertUnit(nil, "bytes_to_mib")
+	assert.NoError(t, err)
+	assert.Nil(t, actual)
+}
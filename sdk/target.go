@@ -5,6 +5,7 @@ package sdk
 
 import (
 	"fmt"
+	"time"
 )
 
 // TargetScalingNoOpError is a special error type that can be used by target
@@ -45,6 +46,72 @@ type TargetStatus struct {
 	// that can be used during the policy evaluation to ensure the correct
 	// calculations and logic are applied to the target.
 	Meta map[string]string
+
+	// Activities are the recent scaling activities of the remote target, as
+	// reported by the provider, ordered from newest to oldest. This allows
+	// failures of the provider to fulfil previous scaling actions to be
+	// surfaced by the Nomad Autoscaler. Target plugins are not required to
+	// report activities.
+	Activities []TargetActivity
+}
+
+// TargetActivity is a scaling activity of the remote target as reported by
+// the provider, such as an AWS ASG scaling activity or a GCE MIG operation.
+type TargetActivity struct {
+
+	// ID is the provider identifier of the activity.
+	ID string
+
+	// Status is the current status of the activity.
+	Status TargetActivityStatus
+
+	// Description is a human readable description of the activity.
+	Description string
+
+	// Message details the status of the activity, such as the reason of a
+	// failure.
+	Message string
+
+	// StartTime and EndTime are the times the activity started and ended. The
+	// EndTime is zero while the activity is in progress.
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// TargetActivityStatus is the status of a TargetActivity.
+type TargetActivityStatus string
+
+const (
+	TargetActivityStatusInProgress TargetActivityStatus = "in_progress"
+	TargetActivityStatusSuccessful TargetActivityStatus = "successful"
+	TargetActivityStatusFailed     TargetActivityStatus = "failed"
+	TargetActivityStatusCancelled  TargetActivityStatus = "cancelled"
+)
+
+// TargetDryRunResult is the response object when performing the optional
+// DryRun call of the target plugin interface. It details the changes the
+// target would make to fulfil a scaling action, without performing them.
+type TargetDryRunResult struct {
+
+	// Count is the count the target would be scaled to.
+	Count int64
+
+	// Nodes are the Nomad clients which would be affected by the action, such
+	// as those selected for draining and termination when scaling in a
+	// cluster target.
+	Nodes []TargetDryRunNode
+
+	// Meta is a mapping that provides additional information about the
+	// action, such as the counts of other resources scaled alongside the
+	// target.
+	Meta map[string]string
+}
+
+// TargetDryRunNode identifies a Nomad client affected by a dry run action by
+// its Nomad node ID as well as the ID of the resource within the provider.
+type TargetDryRunNode struct {
+	NomadNodeID      string
+	RemoteResourceID string
 }
 
 const (
@@ -72,6 +139,11 @@ const (
 	// the agents datacenter configuration param.
 	TargetConfigKeyDatacenter = "datacenter"
 
+	// TargetConfigKeyNodePool is the horizontal cluster scaling target
+	// config key which identifies nodes as part of a pool of resources using
+	// the clients Nomad node pool, available since Nomad 1.6.
+	TargetConfigKeyNodePool = "node_pool"
+
 	// TargetConfigKeyDrainDeadline is the config key which defines the
 	// override value to use when draining a Nomad client during the scale in
 	// action of horizontal cluster scaling.
@@ -81,17 +153,81 @@ const (
 	// nomad system jobs are drained during the drain operation
 	TargetConfigKeyIgnoreSystemJobs = "node_drain_ignore_system_jobs"
 
+	// TargetConfigKeyDrainNoDeadline is the config key which defines whether
+	// Nomad clients are drained without a deadline, so allocations are only
+	// migrated once they complete. It cannot be used alongside
+	// TargetConfigKeyDrainDeadline.
+	TargetConfigKeyDrainNoDeadline = "node_drain_no_deadline"
+
+	// TargetConfigKeyDrainForceTimeout is the config key which defines how
+	// long the Nomad Autoscaler waits for a drain to complete before forcing
+	// it, stopping any remaining allocations immediately.
+	TargetConfigKeyDrainForceTimeout = "node_drain_force_timeout"
+
+	// TargetConfigKeyDrainParallelism is the config key which defines the
+	// maximum number of Nomad clients drained concurrently during the scale
+	// in action of horizontal cluster scaling. Larger scale in actions are
+	// performed as a rolling drain of batches. By default, all selected
+	// clients are drained at once.
+	TargetConfigKeyDrainParallelism = "node_drain_parallelism"
+
+	// TargetConfigKeyDrainBatchDelay is the config key which defines how long
+	// the Nomad Autoscaler waits between batches of a rolling drain, allowing
+	// migrated allocations to settle before the next batch is drained.
+	TargetConfigKeyDrainBatchDelay = "node_drain_batch_delay"
+
 	// TargetConfigKeyNodePurge is the config key which defines whether or not
 	// Nomad clients are purged from Nomad once they have been terminated
 	// within their provider.
 	TargetConfigKeyNodePurge = "node_purge"
 
+	// TargetConfigKeyNodeCleanupVerify is the config key which defines
+	// whether the Nomad Autoscaler verifies that scaled in nodes are cleaned
+	// up, meaning their remote resource is gone from the provider and the
+	// Nomad client is purged, or down if node purging is disabled. Nodes
+	// which are not cleaned up within the configured attempts are reported
+	// as leaked.
+	TargetConfigKeyNodeCleanupVerify = "node_cleanup_verify"
+
+	// TargetConfigKeyNodeCleanupVerifyAttempts is the config key which
+	// defines how many times the cleanup of scaled in nodes is checked before
+	// they are reported as leaked.
+	TargetConfigKeyNodeCleanupVerifyAttempts = "node_cleanup_verify_attempts"
+
+	// TargetConfigKeyNodeCleanupVerifyInterval is the config key which
+	// defines how long the Nomad Autoscaler waits between checks of the
+	// cleanup of scaled in nodes.
+	TargetConfigKeyNodeCleanupVerifyInterval = "node_cleanup_verify_interval"
+
+	// TargetConfigKeyNodeEligibilityOnly is the config key which defines
+	// whether scaling in only drains the selected Nomad clients, leaving them
+	// ineligible for scheduling, without terminating them within their
+	// provider. This is useful when an external system handles the lifecycle
+	// of the machines. The target count then excludes the drained clients.
+	TargetConfigKeyNodeEligibilityOnly = "node_eligibility_only"
+
+	// TargetConfigKeyReconcileOutOfBand is the config key which defines
+	// whether the Nomad Autoscaler scales a cluster target back to the count
+	// it last scaled the target to, when the capacity has been changed
+	// outside of the autoscaler. Out of band changes are always reported, but
+	// by default the new count is kept.
+	TargetConfigKeyReconcileOutOfBand = "reconcile_out_of_band"
+
 	// TargetConfigNodeSelectorStrategy is the optional node target config
 	// option which dictates how the Nomad Autoscaler selects nodes when
-	// scaling in.
+	// scaling in. Target plugins may register custom strategies in addition
+	// to the built-in ones using nodeselector.Register.
 	TargetConfigNodeSelectorStrategy = "node_selector_strategy"
 )
 
+const (
+	// NodeMetaKeyScaleInProtected is the Nomad client meta key which, when set
+	// to true, protects the node from being selected for termination during
+	// the scale in action of horizontal cluster scaling, regardless of the
+	// node selector strategy.
+	NodeMetaKeyScaleInProtected = "autoscaler.protected"
+)
+
 const (
 	// TargetNodeSelectorStrategyLeastBusy is the cluster scale-in node
 	// selection strategy that identifies nodes based on their CPU and Memory
@@ -120,4 +256,4 @@ const (
 // TargetConfigConflictingClusterParams is a list containing horizontal cluster
 // scaling target configuration options which conflict. This makes it easier to
 // create error messages.
-var TargetConfigConflictingClusterParams = []string{TargetConfigKeyDatacenter, TargetConfigKeyClass}
+var TargetConfigConflictingClusterParams = []string{TargetConfigKeyDatacenter, TargetConfigKeyClass, TargetConfigKeyNodePool}